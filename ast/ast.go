@@ -42,6 +42,8 @@ type ImportStatement struct {
 	Items     []string // Specific items to import (empty means import all)
 	ImportAll bool     // True for "import everything/all"
 	IsSafe    bool     // True for safe imports (don't run top-level code)
+	Alias     string   // Module name for "Import "file.abc" as m"; empty otherwise
+	Line      int
 }
 
 func (is *ImportStatement) node()          {}
@@ -49,10 +51,20 @@ func (is *ImportStatement) statementNode() {}
 
 // VariableDecl represents a variable declaration
 type VariableDecl struct {
-	Name       string
+	Name string
+	// Names lists the variables of a destructuring declaration, "Declare q
+	// and r to be …", which unpacks a list or structure into them; Name is
+	// empty then. It is nil for an ordinary declaration.
+	Names []string
+	// Rest is set by "Declare first and the rest as others to be …": the
+	// last name collects, as a list, the items the others leave over.
+	Rest       bool
 	IsConstant bool
-	Value      Expression
-	Line       int
+	// IsPrivate is true for "Declare privately x to be …": files that
+	// import this one cannot see the variable.
+	IsPrivate bool
+	Value     Expression
+	Line      int
 }
 
 func (vd *VariableDecl) node()          {}
@@ -60,7 +72,13 @@ func (vd *VariableDecl) statementNode() {}
 
 // Assignment represents a variable assignment
 type Assignment struct {
-	Name  string
+	Name string
+	// Names lists the variables of a destructuring assignment, "Set q and r
+	// to …"; Name is empty then. It is nil for an ordinary assignment.
+	Names []string
+	// Rest is set by "Set first and the rest as others to …", as for
+	// VariableDecl.
+	Rest  bool
 	Value Expression
 	Line  int
 }
@@ -72,8 +90,31 @@ func (a *Assignment) statementNode() {}
 type FunctionDecl struct {
 	Name       string
 	Parameters []string
-	Body       []Statement
-	Line       int
+	// ParamCapabilities gives, for each parameter, the capability its
+	// argument must have ("takes pet as anything that can Speaker"), or "".
+	// It is nil when no parameter requires one.
+	ParamCapabilities []string
+	// ParamTypes gives, for each parameter, the type its argument must have
+	// ("takes width as number"), or "". It is nil when no parameter has one.
+	ParamTypes []string
+	// ReturnType is the type the function says it returns ("and returns a
+	// number"), or "" when it does not say.
+	ReturnType string
+	// Defaults gives, for each parameter, the value it takes when a call
+	// leaves it out ("greeting (defaulting to \"Hello\")"), or nil when the
+	// parameter is required. It is nil when no parameter has a default.
+	Defaults []Expression
+	// Variadic is true when the last parameter is a rest parameter ("takes
+	// any number of values"), which collects the extra arguments into a list.
+	Variadic bool
+	// Generator is true when the body gives back values ("Give back x."), so
+	// calling the function makes a generator instead of running the body.
+	Generator bool
+	// IsPrivate is true for "Declare private function …": files that import
+	// this one cannot call the function.
+	IsPrivate bool
+	Body      []Statement
+	Line      int
 }
 
 func (fd *FunctionDecl) node()          {}
@@ -83,11 +124,37 @@ func (fd *FunctionDecl) statementNode() {}
 type FunctionCall struct {
 	Name      string
 	Arguments []Expression
+	// NamedArguments are the "name as value" arguments, which always follow
+	// the positional ones.
+	NamedArguments []*NamedArgument
 }
 
 func (fc *FunctionCall) node()           {}
 func (fc *FunctionCall) expressionNode() {}
 
+// NamedArgument is one "greeting as \"Hi\"" argument, which gives a value to
+// the parameter called Name whatever its position.
+type NamedArgument struct {
+	Name  string
+	Value Expression
+}
+
+// FunctionLiteral represents an anonymous function used as a value.
+// Syntax: a function that takes x and returns x * 2
+//
+//	a function that takes x and does the following: ... thats it
+//
+// The short "returns" form is stored as a body holding a single
+// ReturnStatement, so both forms are executed the same way.
+type FunctionLiteral struct {
+	Parameters []string
+	Body       []Statement
+	Line       int
+}
+
+func (fl *FunctionLiteral) node()           {}
+func (fl *FunctionLiteral) expressionNode() {}
+
 // CallStatement represents a function call as a statement
 type CallStatement struct {
 	FunctionCall *FunctionCall
@@ -116,11 +183,69 @@ type ElseIfPart struct {
 	Body      []Statement
 }
 
+// WhenStatement represents a multi-way branch on a single value:
+//
+//	When command is "start": … When it is "stop" or "halt": … Otherwise: … thats it.
+//
+// The subject is evaluated once and the first case with a matching pattern runs.
+type WhenStatement struct {
+	Subject   Expression
+	Cases     []*WhenCase
+	Otherwise []Statement
+	Line      int
+}
+
+func (ws *WhenStatement) node()          {}
+func (ws *WhenStatement) statementNode() {}
+
+// WhenCase is one "When it is …:" branch. It matches when any of its
+// patterns matches the subject.
+type WhenCase struct {
+	Patterns []WhenPattern
+	Body     []Statement
+}
+
+// WhenPattern is one alternative in a WhenCase.
+type WhenPattern interface {
+	Node
+	patternNode()
+}
+
+// ValuePattern matches a subject equal to Value.
+type ValuePattern struct {
+	Value Expression
+}
+
+func (vp *ValuePattern) node()        {}
+func (vp *ValuePattern) patternNode() {}
+
+// RangePattern matches a subject between Low and High, inclusive.
+type RangePattern struct {
+	Low  Expression
+	High Expression
+}
+
+func (rp *RangePattern) node()        {}
+func (rp *RangePattern) patternNode() {}
+
+// TypePattern matches a subject of the named type: a built-in type such as
+// "number" or "text", an error type, or a structure.
+type TypePattern struct {
+	TypeName string
+}
+
+func (tp *TypePattern) node()        {}
+func (tp *TypePattern) patternNode() {}
+
 // WhileLoop represents a while loop
 type WhileLoop struct {
 	Condition Expression
 	Body      []Statement
-	Line      int
+	// Label is the name given by "… and call this loop outer:", which
+	// "Break out of outer." and "Continue outer." use from a loop inside
+	// this one. It is empty for a loop without a name.
+	Label string
+	Line  int
 }
 
 func (wl *WhileLoop) node()          {}
@@ -130,6 +255,7 @@ func (wl *WhileLoop) statementNode() {}
 type ForLoop struct {
 	Count Expression
 	Body  []Statement
+	Label string // see WhileLoop
 	Line  int
 }
 
@@ -138,10 +264,11 @@ func (fl *ForLoop) statementNode() {}
 
 // ForEachLoop represents a for-each loop over a collection
 type ForEachLoop struct {
-	Item string
-	List Expression
-	Body []Statement
-	Line int // source line of the loop-variable declaration
+	Item  string
+	List  Expression
+	Body  []Statement
+	Label string // see WhileLoop
+	Line  int    // source line of the loop-variable declaration
 }
 
 func (fel *ForEachLoop) node()          {}
@@ -155,6 +282,17 @@ type NumberLiteral struct {
 func (nl *NumberLiteral) node()           {}
 func (nl *NumberLiteral) expressionNode() {}
 
+// DecimalLiteral represents an arbitrary-precision number written as
+// "the decimal 19.99" or, when Whole is set, "the whole number 2". Digits
+// keeps the literal's source text so no precision is lost to float64.
+type DecimalLiteral struct {
+	Digits string
+	Whole  bool
+}
+
+func (dl *DecimalLiteral) node()           {}
+func (dl *DecimalLiteral) expressionNode() {}
+
 // StringLiteral represents a string literal
 type StringLiteral struct {
 	Value string
@@ -163,6 +301,16 @@ type StringLiteral struct {
 func (sl *StringLiteral) node()           {}
 func (sl *StringLiteral) expressionNode() {}
 
+// InterpolatedString represents text with embedded expressions, written
+// "Hello, {name}!". Parts alternate between *StringLiteral pieces and the
+// embedded expressions, in source order.
+type InterpolatedString struct {
+	Parts []Expression
+}
+
+func (is *InterpolatedString) node()           {}
+func (is *InterpolatedString) expressionNode() {}
+
 // ListLiteral represents a list/array literal
 type ListLiteral struct {
 	Elements []Expression
@@ -240,12 +388,75 @@ func (le *LengthExpression) expressionNode() {}
 // ReturnStatement represents a return statement
 type ReturnStatement struct {
 	Value Expression
-	Line  int
+	// Values holds the values of "Return q and r.", which are returned
+	// together as a list; Value is nil then. It is nil for a single value.
+	Values []Expression
+	Line   int
 }
 
 func (rs *ReturnStatement) node()          {}
 func (rs *ReturnStatement) statementNode() {}
 
+// YieldStatement represents "Give back x.", which hands x to the loop
+// consuming the generator and pauses the function until the next item is
+// wanted.
+type YieldStatement struct {
+	Value Expression
+	Line  int
+}
+
+func (ys *YieldStatement) node()          {}
+func (ys *YieldStatement) statementNode() {}
+
+// BackgroundStatement represents "Do the following in the background and
+// call it job: ... thats it.", which starts Body as a task running alongside
+// the rest of the program and names the task Name.
+type BackgroundStatement struct {
+	Name string
+	Body []Statement
+	Line int
+}
+
+func (bs *BackgroundStatement) node()          {}
+func (bs *BackgroundStatement) statementNode() {}
+
+// TestBlock represents a test written at the top level of a file:
+//
+//	Test "adding works" by doing the following:
+//	    Make sure that 1 + 1 is equal to 2.
+//	thats it.
+//
+// Running the file skips it; "english test" runs each one on its own.
+type TestBlock struct {
+	Name string
+	Body []Statement
+	Line int
+}
+
+func (tb *TestBlock) node()          {}
+func (tb *TestBlock) statementNode() {}
+
+// WaitStatement represents "Wait for job.", which waits until the task has
+// finished and raises any error that ended it.
+type WaitStatement struct {
+	Task Expression
+	Line int
+}
+
+func (ws *WaitStatement) node()          {}
+func (ws *WaitStatement) statementNode() {}
+
+// SendStatement represents "Send x to ch.", which waits until another task
+// receives the value from the channel.
+type SendStatement struct {
+	Value   Expression
+	Channel Expression
+	Line    int
+}
+
+func (ss *SendStatement) node()          {}
+func (ss *SendStatement) statementNode() {}
+
 // OutputStatement represents a print statement
 type OutputStatement struct {
 	Values  []Expression
@@ -265,8 +476,12 @@ type ToggleStatement struct {
 func (ts *ToggleStatement) node()          {}
 func (ts *ToggleStatement) statementNode() {}
 
-// BreakStatement breaks out of a loop
-type BreakStatement struct{}
+// BreakStatement breaks out of a loop: the innermost one, or the one called
+// Label for "Break out of outer."
+type BreakStatement struct {
+	Label string
+	Line  int
+}
 
 func (bs *BreakStatement) node()          {}
 func (bs *BreakStatement) statementNode() {}
@@ -289,9 +504,11 @@ func (le *LocationExpression) expressionNode() {}
 
 // StructDecl represents a struct type declaration
 type StructDecl struct {
-	Name    string
-	Fields  []*StructField
-	Methods []*FunctionDecl
+	Name         string
+	Parent       string   // structure this one is "a kind of"; empty if none
+	Capabilities []string // capabilities it declares it "can"
+	Fields       []*StructField
+	Methods      []*FunctionDecl
 }
 
 func (sd *StructDecl) node()          {}
@@ -357,6 +574,24 @@ type RaiseStatement struct {
 func (rs *RaiseStatement) node()          {}
 func (rs *RaiseStatement) statementNode() {}
 
+// AssertStatement represents an assertion:
+//
+//	Make sure that balance is at least 0, otherwise say "negative balance".
+//
+// It raises an AssertionError when the condition does not hold. Text is the
+// condition as written; when the condition is a comparison, Operands holds
+// its two sides as written, so the error can show what each one was.
+type AssertStatement struct {
+	Condition Expression
+	Message   Expression // nil when there is no "otherwise say"
+	Text      string
+	Operands  []string
+	Line      int
+}
+
+func (as *AssertStatement) node()          {}
+func (as *AssertStatement) statementNode() {}
+
 // ErrorTypeDecl declares a custom error type.
 // Syntax:
 //
@@ -370,6 +605,34 @@ type ErrorTypeDecl struct {
 func (etd *ErrorTypeDecl) node()          {}
 func (etd *ErrorTypeDecl) statementNode() {}
 
+// EnumDecl declares an enumeration type with a fixed set of named members.
+// Syntax:
+//
+//	Declare Color as one of red, green and blue.
+type EnumDecl struct {
+	Name    string
+	Members []string
+	Line    int
+}
+
+func (ed *EnumDecl) node()          {}
+func (ed *EnumDecl) statementNode() {}
+
+// CapabilityDecl declares a capability: a set of functions a structure
+// promises to have when it is declared "that can" the capability.
+// Syntax:
+//
+//	Declare Speaker as a capability requiring a function speak.
+//	Declare Pet as a capability requiring a function speak and a function fetch.
+type CapabilityDecl struct {
+	Name    string
+	Methods []string
+	Line    int
+}
+
+func (cd *CapabilityDecl) node()          {}
+func (cd *CapabilityDecl) statementNode() {}
+
 // TypeExpression gets the type of a value
 type TypeExpression struct {
 	Value Expression
@@ -413,8 +676,23 @@ type SwapStatement struct {
 func (ss *SwapStatement) node()          {}
 func (ss *SwapStatement) statementNode() {}
 
-// ContinueStatement skips the rest of the current loop iteration
-type ContinueStatement struct{}
+// OverflowModeStatement chooses what integer arithmetic does when a result
+// does not fit its kind: "Use wrapping arithmetic." makes it wrap around,
+// "Use checked arithmetic." (the default) raises an OverflowError.
+type OverflowModeStatement struct {
+	Wrap bool
+	Line int
+}
+
+func (om *OverflowModeStatement) node()          {}
+func (om *OverflowModeStatement) statementNode() {}
+
+// ContinueStatement skips the rest of the current loop iteration, or of the
+// iteration of the loop called Label for "Continue outer."
+type ContinueStatement struct {
+	Label string
+	Line  int
+}
 
 func (cs *ContinueStatement) node()          {}
 func (cs *ContinueStatement) statementNode() {}
@@ -448,6 +726,21 @@ type LookupTableLiteral struct{}
 func (lt *LookupTableLiteral) node()           {}
 func (lt *LookupTableLiteral) expressionNode() {}
 
+// ChannelLiteral creates a channel for tasks to pass values through: "a channel"
+type ChannelLiteral struct{}
+
+func (cl *ChannelLiteral) node()           {}
+func (cl *ChannelLiteral) expressionNode() {}
+
+// ReceiveExpression takes the next value sent through a channel, waiting
+// until there is one. "Receive from ch into y." assigns it to y.
+type ReceiveExpression struct {
+	Channel Expression
+}
+
+func (re *ReceiveExpression) node()           {}
+func (re *ReceiveExpression) expressionNode() {}
+
 // LookupKeyAccess reads a value from a lookup table: "TABLE at KEY" or "the entry KEY in TABLE"
 type LookupKeyAccess struct {
 	Table Expression
@@ -493,6 +786,7 @@ type TypedVariableDecl struct {
 	Name       string
 	TypeName   string
 	IsConstant bool
+	IsPrivate  bool // declared with "privately"; see VariableDecl
 	Value      Expression
 	Line       int
 }
@@ -502,17 +796,19 @@ func (tvd *TypedVariableDecl) statementNode() {}
 
 // MethodCall represents calling a method on an object
 type MethodCall struct {
-	Object     Expression
-	MethodName string
-	Arguments  []Expression
+	Object         Expression
+	MethodName     string
+	Arguments      []Expression
+	NamedArguments []*NamedArgument
 }
 
 func (mc *MethodCall) node()           {}
 func (mc *MethodCall) expressionNode() {}
 
 // ErrorTypeCheckExpression checks whether an error value's type matches a named
-// error type (including inherited types).
-// Syntax: error is NetworkError
+// error type, or a struct instance's type matches a named structure (in both
+// cases including inherited types).
+// Syntax: error is NetworkError, pet is a Animal
 type ErrorTypeCheckExpression struct {
 	Value    Expression
 	TypeName string
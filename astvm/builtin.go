@@ -1,8 +1,35 @@
 package vm
 
+import "github.com/Advik-B/english/astvm/types"
+
 func (ev *Evaluator) evalBuiltinFunction(name string, args []Value) (Value, error) {
 	if ev.builtinFn == nil {
 		return nil, ev.runtimeError("no built-in evaluator registered for '" + name + "'")
 	}
+	// Function arguments become callbacks so higher-order built-ins such as
+	// transform can run them in this evaluator.
+	for i, arg := range args {
+		if fn, ok := arg.(*FunctionValue); ok {
+			args[i] = ev.callbackFor(fn)
+		}
+	}
+	if name == "sleep" && ev.tasks != nil {
+		// Let the other tasks run while this one sleeps.
+		var result Value
+		var err error
+		ev.tasks.Release(func() { result, err = ev.builtinFn(name, args) })
+		return result, err
+	}
 	return ev.builtinFn(name, args)
 }
+
+// callbackFor wraps fn so the standard library can call it.
+func (ev *Evaluator) callbackFor(fn *FunctionValue) *types.Callback {
+	return &types.Callback{
+		Name: fn.Name,
+		Fn:   fn,
+		Call: func(args []interface{}) (interface{}, error) {
+			return ev.callFunctionValue(fn.Name, fn, args, nil)
+		},
+	}
+}
@@ -3,6 +3,7 @@ package vm
 import (
 	"github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 	"github.com/Advik-B/english/astvm/types"
 	"fmt"
 	"os"
@@ -56,6 +57,11 @@ var builtinArgTypes = map[string][]types.TypeKind{
 	"product":     {types.TypeList},
 	"sorted_desc": {types.TypeList},
 	"zip_with":    {types.TypeList},
+	"transform":   {types.TypeList, types.TypeFunction},
+	"keep":        {types.TypeList, types.TypeFunction},
+	"combine":     {types.TypeList, types.TypeFunction},
+	"find_first":  {types.TypeList, types.TypeFunction},
+	"count_where": {types.TypeList, types.TypeFunction},
 	"sort":        {types.TypeList},
 	"reverse":     {types.TypeList},
 	"sum":         {types.TypeList},
@@ -112,12 +118,75 @@ type TypeChecker struct {
 	// Duplicate detection is limited to the innermost matching scope.
 	scopeStack  []map[string]int
 	seenImports map[string]bool // guards against duplicate / circular imports
+	// enums maps each declared enum to its members; varEnums maps a variable
+	// to the enum its value was taken from, so comparisons between members of
+	// different enums can be rejected.
+	enums    map[string][]string
+	varEnums map[string]string
+	// capabilities maps each declared capability to its required functions
+	// and structs holds each declared structure. varStructs maps a variable
+	// to the structure it was created from.
+	capabilities map[string][]string
+	structs      map[string]*ast.StructDecl
+	varStructs   map[string]string
+	// funcDecls holds the functions declared in the program, so calls to
+	// them can be checked against their parameters: the number of
+	// arguments, allowing for defaults, the names of named arguments, and
+	// arguments that could never have a capability a parameter needs.
+	funcDecls map[string]*ast.FunctionDecl
+	// returns collects how many values each Return in the function being
+	// checked gives, and returnCounts maps each function whose Returns all
+	// give the same number of values, two or more, to that number, so
+	// unpacking its result into the wrong number of variables is caught.
+	returns      []int
+	returnCounts map[string]int
+	// varElements maps a variable declared with element types to its
+	// declared type, such as "list of number", so what is put into it can
+	// be checked.
+	varElements map[string]string
+	// modules maps each name bound by "Import "file.abc" as m" to what the
+	// file declares at its top level: its functions, by declaration, and
+	// its variables, with no declaration. importedProgs holds the program
+	// of each file imported so far, by path, and is shared with the
+	// checkers of imported files.
+	modules       map[string]*types.Module
+	importedProgs map[string]*ast.Program
+	// function is the declared function whose body is being checked, so
+	// its Returns can be checked against the type it says it returns. It is
+	// nil outside a function and in the body of a function literal.
+	function *ast.FunctionDecl
+	// file is the file being checked, or "" when the program is not a
+	// file; the files it imports are looked for from its directory.
+	file string
+	// loops holds the labels of the loops around the statement being
+	// checked, innermost last, with "" for a loop without one, so "Break
+	// out of outer." can be checked against them. A function body starts
+	// with none.
+	loops []string
+	// errorTypes holds each declared error type. namedTypes holds each type
+	// a function's signature names that is not built in, to be looked for
+	// once the whole file has been checked, since a structure can be
+	// declared after a function that takes one.
+	errorTypes map[string]bool
+	namedTypes []namedType
+}
+
+// namedType is a type named in a function's signature, where it is used.
+type namedType struct {
+	name, function, use string
+	line                int
 }
 
 // Check runs the type checker on a program and returns all type errors found.
 // Provide the names of any stdlib-predefined variables via predefines so the
 // checker can report redeclarations as compile-time errors.
 func Check(program *ast.Program, predefines ...string) []*TypeError {
+	return CheckFile(program, "", predefines...)
+}
+
+// CheckFile is Check for a program read from file, whose imports are looked
+// for from the file's directory.
+func CheckFile(program *ast.Program, file string, predefines ...string) []*TypeError {
 	globalScope := make(map[string]int)
 	for _, name := range predefines {
 		globalScope[name] = 0 // 0 = predefined (no source line)
@@ -127,15 +196,31 @@ func Check(program *ast.Program, predefines ...string) []*TypeError {
 		userFunctions: make(map[string]bool),
 		scopeStack:    []map[string]int{globalScope},
 		seenImports:   make(map[string]bool),
+		enums:         make(map[string][]string),
+		varEnums:      make(map[string]string),
+
+		capabilities: make(map[string][]string),
+		structs:      make(map[string]*ast.StructDecl),
+		varStructs:   make(map[string]string),
+		funcDecls:    make(map[string]*ast.FunctionDecl),
+		returnCounts: make(map[string]int),
+		varElements:  make(map[string]string),
+		errorTypes:   make(map[string]bool),
+
+		modules:       make(map[string]*types.Module),
+		importedProgs: make(map[string]*ast.Program),
+		file:          file,
 	}
 	// Pre-scan top-level function declarations so that user-defined functions
 	// sharing a name with a stdlib function are not falsely type-checked.
 	for _, stmt := range program.Statements {
 		if fn, ok := stmt.(*ast.FunctionDecl); ok {
 			tc.userFunctions[fn.Name] = true
+			tc.funcDecls[fn.Name] = fn
 		}
 	}
 	tc.checkStatements(program.Statements)
+	tc.checkNamedTypes()
 	return tc.errors
 }
 
@@ -180,12 +265,23 @@ func (tc *TypeChecker) exprType(expr ast.Expression) types.TypeKind {
 	switch e := expr.(type) {
 	case *ast.NumberLiteral:
 		return types.TypeF64
-	case *ast.StringLiteral:
+	case *ast.DecimalLiteral:
+		if e.Whole {
+			return types.TypeWhole
+		}
+		return types.TypeDecimal
+	case *ast.StringLiteral, *ast.InterpolatedString:
 		return types.TypeString
 	case *ast.BooleanLiteral:
 		return types.TypeBool
 	case *ast.ListLiteral:
 		return types.TypeList
+	case *ast.FunctionLiteral:
+		return types.TypeFunction
+	case *ast.FunctionCall:
+		if fd, ok := tc.funcDecls[e.Name]; ok && fd.ReturnType != "" {
+			return types.Canonical(types.Parse(fd.ReturnType))
+		}
 	case *ast.Identifier:
 		if tk, ok := tc.varTypes[e.Name]; ok {
 			return tk
@@ -203,20 +299,57 @@ func (tc *TypeChecker) checkStatements(stmts []ast.Statement) {
 func (tc *TypeChecker) checkStatement(stmt ast.Statement) {
 	switch s := stmt.(type) {
 	case *ast.VariableDecl:
+		if s.Names != nil {
+			for _, name := range s.Names {
+				tc.declareVar(name, s.Line)
+				tc.forget(name)
+			}
+			tc.checkUnpack(s.Names, s.Rest, s.Value, s.Line)
+			tc.checkExpression(s.Value)
+			return
+		}
 		tc.declareVar(s.Name, s.Line)
+		if _, ok := s.Value.(*ast.FunctionLiteral); ok {
+			// Calls through this name reach the user's function, not a stdlib
+			// one, and not any function declared under the same name.
+			tc.userFunctions[s.Name] = true
+			delete(tc.funcDecls, s.Name)
+			delete(tc.returnCounts, s.Name)
+		}
 		if s.Value != nil {
 			tk := tc.exprType(s.Value)
 			if tk != types.TypeUnknown {
 				tc.varTypes[s.Name] = tk
 			}
+			if enum := tc.enumOf(s.Value); enum != "" {
+				tc.varEnums[s.Name] = enum
+			}
+			if si, ok := s.Value.(*ast.StructInstantiation); ok {
+				tc.varStructs[s.Name] = si.StructName
+			}
 			tc.checkExpression(s.Value)
 		}
+	case *ast.EnumDecl:
+		tc.declareVar(s.Name, s.Line)
+		tc.enums[s.Name] = s.Members
+	case *ast.CapabilityDecl:
+		tc.declareVar(s.Name, s.Line)
+		tc.capabilities[s.Name] = s.Methods
+	case *ast.StructDecl:
+		tc.structs[s.Name] = s
+		tc.checkStructCapabilities(s)
+	case *ast.ErrorTypeDecl:
+		tc.errorTypes[s.Name] = true
 	case *ast.TypedVariableDecl:
 		tc.declareVar(s.Name, s.Line)
 		declaredKind := types.Parse(s.TypeName)
 		if declaredKind != types.TypeUnknown {
 			tc.varTypes[s.Name] = types.Canonical(declaredKind)
 		}
+		if _, _, elemType := types.ElementTypes(s.TypeName); elemType != "" {
+			tc.varElements[s.Name] = s.TypeName
+			tc.checkListElements(s.Name, s.Value, s.Line)
+		}
 		if s.Value != nil {
 			actualKind := tc.exprType(s.Value)
 			if actualKind != types.TypeUnknown && declaredKind != types.TypeUnknown {
@@ -242,23 +375,55 @@ func (tc *TypeChecker) checkStatement(stmt ast.Statement) {
 			tc.checkStatements(s.Else)
 			tc.popScope()
 		}
+	case *ast.WhenStatement:
+		tc.checkExpression(s.Subject)
+		for _, wc := range s.Cases {
+			for _, pattern := range wc.Patterns {
+				switch p := pattern.(type) {
+				case *ast.ValuePattern:
+					tc.checkEnumComparison(s.Subject, p.Value)
+					tc.checkExpression(p.Value)
+				case *ast.RangePattern:
+					tc.checkExpression(p.Low)
+					tc.checkExpression(p.High)
+				}
+			}
+			tc.pushScope()
+			tc.checkStatements(wc.Body)
+			tc.popScope()
+		}
+		if s.Otherwise != nil {
+			tc.pushScope()
+			tc.checkStatements(s.Otherwise)
+			tc.popScope()
+		}
 	case *ast.WhileLoop:
 		tc.checkExpression(s.Condition)
+		tc.pushLoop(s.Label, s.Line)
 		tc.pushScope()
 		tc.checkStatements(s.Body)
 		tc.popScope()
+		tc.popLoop()
 	case *ast.ForLoop:
 		tc.checkExpression(s.Count)
+		tc.pushLoop(s.Label, s.Line)
 		tc.pushScope()
 		tc.checkStatements(s.Body)
 		tc.popScope()
+		tc.popLoop()
 	case *ast.ForEachLoop:
+		tc.pushLoop(s.Label, s.Line)
 		tc.pushScope()
 		if s.Item != "" {
 			tc.declareVar(s.Item, s.Line)
 		}
 		tc.checkStatements(s.Body)
 		tc.popScope()
+		tc.popLoop()
+	case *ast.BreakStatement:
+		tc.checkLoopLabel(s.Label, s.Line)
+	case *ast.ContinueStatement:
+		tc.checkLoopLabel(s.Label, s.Line)
 	case *ast.TryStatement:
 		tc.pushScope()
 		tc.checkStatements(s.TryBody)
@@ -274,44 +439,253 @@ func (tc *TypeChecker) checkStatement(stmt ast.Statement) {
 			tc.checkExpression(arg)
 		}
 	case *ast.Assignment:
+		if s.Names != nil {
+			for _, name := range s.Names {
+				tc.forget(name)
+			}
+			tc.checkUnpack(s.Names, s.Rest, s.Value, s.Line)
+			tc.checkExpression(s.Value)
+			return
+		}
+		if _, tracked := tc.varEnums[s.Name]; tracked {
+			tc.varEnums[s.Name] = tc.enumOf(s.Value)
+		}
+		if _, tracked := tc.varStructs[s.Name]; tracked {
+			delete(tc.varStructs, s.Name)
+			if si, ok := s.Value.(*ast.StructInstantiation); ok {
+				tc.varStructs[s.Name] = si.StructName
+			}
+		}
+		tc.checkListElements(s.Name, s.Value, s.Line)
 		tc.checkExpression(s.Value)
+	case *ast.IndexAssignment:
+		tc.checkElement(s.ListName, s.Value, s.Line)
+	case *ast.LookupKeyAssignment:
+		tc.checkKey(s.TableName, s.Key, s.Line)
+		tc.checkElement(s.TableName, s.Value, s.Line)
 	case *ast.CallStatement:
 		if s.FunctionCall != nil {
 			tc.checkFunctionCallArgs(s.FunctionCall.Name, s.FunctionCall.Arguments)
+			tc.checkUserCall(s.FunctionCall, s.Line)
+			tc.checkElementCall(s.FunctionCall, s.Line)
 			for _, arg := range s.FunctionCall.Arguments {
 				tc.checkExpression(arg)
 			}
+			for _, na := range s.FunctionCall.NamedArguments {
+				tc.checkExpression(na.Value)
+			}
+		}
+		if s.MethodCall != nil {
+			if mod, ok := tc.moduleNamed(s.MethodCall.Object); ok {
+				tc.checkModuleMember(mod, s.MethodCall.MethodName, s.MethodCall.Arguments, s.MethodCall.NamedArguments, s.Line)
+				for _, arg := range s.MethodCall.Arguments {
+					tc.checkExpression(arg)
+				}
+				for _, na := range s.MethodCall.NamedArguments {
+					tc.checkExpression(na.Value)
+				}
+			}
 		}
 	case *ast.ReturnStatement:
 		if s.Value != nil {
 			tc.checkExpression(s.Value)
 		}
+		for _, v := range s.Values {
+			tc.checkExpression(v)
+		}
+		count := 1
+		if s.Values != nil {
+			count = len(s.Values)
+		}
+		tc.returns = append(tc.returns, count)
+		tc.checkReturnType(s)
+	case *ast.YieldStatement:
+		tc.checkExpression(s.Value)
+	case *ast.BackgroundStatement:
+		// The task's name is set like a variable, so starting the same task
+		// again (in a loop, say) is not a redeclaration.
+		tc.pushScope()
+		tc.checkStatements(s.Body)
+		tc.popScope()
+	case *ast.TestBlock:
+		tc.pushScope()
+		tc.checkStatements(s.Body)
+		tc.popScope()
+	case *ast.AssertStatement:
+		tc.checkExpression(s.Condition)
+		if s.Message != nil {
+			tc.checkExpression(s.Message)
+		}
+	case *ast.WaitStatement:
+		tc.checkExpression(s.Task)
+	case *ast.SendStatement:
+		tc.checkExpression(s.Value)
+		tc.checkExpression(s.Channel)
 	case *ast.FunctionDecl:
+		tc.funcDecls[s.Name] = s
+		tc.checkSignatureTypes(s)
+		// Parameters hide any outer variable or function of the same name,
+		// so what was known about it does not apply in the body.
+		// A parameter declared with a type is known to have it.
+		hidden := make(map[string]string)
+		hiddenFuncs := make(map[string]*ast.FunctionDecl)
+		hiddenTypes := make(map[string]types.TypeKind)
+		hiddenElements := make(map[string]string)
+		for i, param := range s.Parameters {
+			if sn, ok := tc.varStructs[param]; ok {
+				hidden[param] = sn
+				delete(tc.varStructs, param)
+			}
+			if fd, ok := tc.funcDecls[param]; ok {
+				hiddenFuncs[param] = fd
+				delete(tc.funcDecls, param)
+			}
+			if tk, ok := tc.varTypes[param]; ok {
+				hiddenTypes[param] = tk
+				delete(tc.varTypes, param)
+			}
+			if typeName, ok := tc.varElements[param]; ok {
+				hiddenElements[param] = typeName
+				delete(tc.varElements, param)
+			}
+			if i < len(s.ParamTypes) {
+				if tk := types.Parse(s.ParamTypes[i]); tk != types.TypeUnknown {
+					tc.varTypes[param] = types.Canonical(tk)
+				}
+			}
+		}
+		outerReturns, outerFunction, outerLoops := tc.returns, tc.function, tc.loops
+		tc.returns, tc.function, tc.loops = nil, s, nil
 		tc.pushScope()
 		tc.checkStatements(s.Body)
 		tc.popScope()
+		tc.loops = outerLoops
+		if count := sameCount(tc.returns); count > 1 {
+			tc.returnCounts[s.Name] = count
+		} else {
+			delete(tc.returnCounts, s.Name)
+		}
+		tc.returns, tc.function = outerReturns, outerFunction
+		for _, param := range s.Parameters {
+			delete(tc.varTypes, param)
+		}
+		for param, sn := range hidden {
+			tc.varStructs[param] = sn
+		}
+		for param, fd := range hiddenFuncs {
+			tc.funcDecls[param] = fd
+		}
+		for param, tk := range hiddenTypes {
+			tc.varTypes[param] = tk
+		}
+		for param, typeName := range hiddenElements {
+			tc.varElements[param] = typeName
+		}
 	case *ast.ImportStatement:
-		if strings.ToLower(filepath.Ext(s.Path)) == ".abc" {
-			tc.checkImportFile(s.Path)
+		// Imported files are known by where they are, since two files can
+		// each import a different "util.abc" from beside them.
+		file, err := project.ResolveImport(tc.file, s.Path)
+		if err != nil {
+			file = s.Path
+		}
+		// A path without an extension names a library, as in "strings/slugify".
+		if ext := strings.ToLower(filepath.Ext(s.Path)); ext == ".abc" || ext == "" {
+			tc.checkImportFile(s.Path, file)
+		}
+		if prog, ok := tc.importedProgs[file]; ok && len(s.Items) > 0 {
+			// A private name stays inside its file.
+			private := project.PrivateNames(prog)
+			for _, item := range s.Items {
+				if private[item] {
+					tc.error(s.Line, "%s", &project.PrivateError{Name: item, File: s.Path})
+				}
+			}
+		}
+		if s.Alias != "" {
+			tc.declareVar(s.Alias, s.Line)
+			if prog, ok := tc.importedProgs[file]; ok {
+				tc.modules[s.Alias] = moduleOf(s.Alias, s.Path, prog)
+			}
 		}
 	}
 }
 
-// checkImportFile parses and type-checks an imported .abc file, appending any
-// errors found to tc.errors. The current global scope is used as the set of
-// pre-defined names so that shadowing of stdlib constants (and of names already
-// declared in the importing file) is detected at compile time.
-func (tc *TypeChecker) checkImportFile(path string) {
+// moduleOf returns what the checker knows of the module bound to alias by
+// importing the file at path, whose program is prog. Private names are not
+// members.
+func moduleOf(alias, path string, prog *ast.Program) *types.Module {
+	mod := types.NewModule(alias, path)
+	for _, stmt := range prog.Statements {
+		switch d := stmt.(type) {
+		case *ast.FunctionDecl:
+			mod.Members[d.Name] = d
+		case *ast.VariableDecl:
+			if d.Name != "" {
+				mod.Members[d.Name] = nil
+			}
+			for _, name := range d.Names {
+				mod.Members[name] = nil
+			}
+		case *ast.TypedVariableDecl:
+			mod.Members[d.Name] = nil
+		case *ast.EnumDecl:
+			mod.Members[d.Name] = nil
+		case *ast.CapabilityDecl:
+			mod.Members[d.Name] = nil
+		}
+	}
+	for name := range project.PrivateNames(prog) {
+		delete(mod.Members, name)
+	}
+	return mod
+}
+
+// checkModuleMember checks "m's name", with the given arguments, where m is
+// bound to mod: the module must have a member called name, and a call to a
+// function in it must fit the function's parameters.
+func (tc *TypeChecker) checkModuleMember(mod *types.Module, name string, args []ast.Expression, named []*ast.NamedArgument, line int) {
+	member, err := mod.Get(name)
+	if err != nil {
+		tc.error(line, "%s", err)
+		return
+	}
+	fn, isFunc := member.(*ast.FunctionDecl)
+	if !isFunc {
+		if len(args) > 0 || len(named) > 0 {
+			tc.error(line, "'%s' in module '%s' is not a function", name, mod.Name)
+		}
+		return
+	}
+	tc.checkCallTo(fn, name, args, named, line)
+}
+
+// moduleNamed returns the module expr names, when it is a name bound by an
+// import with "as".
+func (tc *TypeChecker) moduleNamed(expr ast.Expression) (*types.Module, bool) {
+	id, ok := expr.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	mod, ok := tc.modules[id.Name]
+	return mod, ok
+}
+
+// checkImportFile parses and type-checks the .abc file an import of path
+// found, appending any errors found to tc.errors. The current global scope is
+// used as the set of pre-defined names so that shadowing of stdlib constants
+// (and of names already declared in the importing file) is detected at
+// compile time.
+func (tc *TypeChecker) checkImportFile(path, file string) {
 	// Mark as seen *before* I/O to guard against circular imports (A→B→A).
 	// If reading fails, the entry stays in seenImports, which is fine: the
 	// same file will fail to read on every subsequent attempt, and the
 	// evaluator will surface the missing-file error at runtime.
-	if tc.seenImports[path] {
+	if tc.seenImports[file] {
 		return // already checked or in progress
 	}
-	tc.seenImports[path] = true
+	tc.seenImports[file] = true
 
-	content, err := os.ReadFile(path)
+	content, err := os.ReadFile(file)
 	if err != nil {
 		// Cannot read the file — let the evaluator surface the error at runtime.
 		return
@@ -345,13 +719,30 @@ func (tc *TypeChecker) checkImportFile(path string) {
 		userFunctions: make(map[string]bool),
 		scopeStack:    []map[string]int{importGlobalScope},
 		seenImports:   tc.seenImports, // shared so nested imports are tracked
+		enums:         make(map[string][]string),
+		varEnums:      make(map[string]string),
+
+		capabilities: make(map[string][]string),
+		structs:      make(map[string]*ast.StructDecl),
+		varStructs:   make(map[string]string),
+		funcDecls:    make(map[string]*ast.FunctionDecl),
+		returnCounts: make(map[string]int),
+		varElements:  make(map[string]string),
+		errorTypes:   make(map[string]bool),
+
+		modules:       make(map[string]*types.Module),
+		importedProgs: tc.importedProgs, // shared so every file is parsed once
+		file:          file,
 	}
 	for _, stmt := range prog.Statements {
 		if fn, ok := stmt.(*ast.FunctionDecl); ok {
 			subChecker.userFunctions[fn.Name] = true
+			subChecker.funcDecls[fn.Name] = fn
 		}
 	}
+	tc.importedProgs[file] = prog
 	subChecker.checkStatements(prog.Statements)
+	subChecker.checkNamedTypes()
 	// Tag every error from the sub-checker with the imported file path so the
 	// renderer can show which file the error came from. We copy each error
 	// rather than mutating the pointer, to avoid surprising side-effects if the
@@ -369,17 +760,53 @@ func (tc *TypeChecker) checkExpression(expr ast.Expression) {
 	switch e := expr.(type) {
 	case *ast.FunctionCall:
 		tc.checkFunctionCallArgs(e.Name, e.Arguments)
+		tc.checkUserCall(e, 0)
+		tc.checkElementCall(e, 0)
 		for _, arg := range e.Arguments {
 			tc.checkExpression(arg)
 		}
+		for _, na := range e.NamedArguments {
+			tc.checkExpression(na.Value)
+		}
 	case *ast.MethodCall:
+		if mod, ok := tc.moduleNamed(e.Object); ok {
+			tc.checkModuleMember(mod, e.MethodName, e.Arguments, e.NamedArguments, 0)
+			for _, arg := range e.Arguments {
+				tc.checkExpression(arg)
+			}
+			for _, na := range e.NamedArguments {
+				tc.checkExpression(na.Value)
+			}
+			return
+		}
+		if id, ok := e.Object.(*ast.Identifier); ok && len(e.Arguments) == 0 {
+			if members, isEnum := tc.enums[id.Name]; isEnum {
+				tc.checkEnumMember(id.Name, members, e.MethodName)
+				return
+			}
+		}
 		allArgs := append([]ast.Expression{e.Object}, e.Arguments...)
 		tc.checkFunctionCallArgs(e.MethodName, allArgs)
 		tc.checkExpression(e.Object)
 		for _, arg := range e.Arguments {
 			tc.checkExpression(arg)
 		}
+	case *ast.FieldAccess:
+		if mod, ok := tc.moduleNamed(e.Object); ok {
+			tc.checkModuleMember(mod, e.Field, nil, nil, 0)
+			return
+		}
+		if id, ok := e.Object.(*ast.Identifier); ok {
+			if members, isEnum := tc.enums[id.Name]; isEnum {
+				tc.checkEnumMember(id.Name, members, e.Field)
+				return
+			}
+		}
+		tc.checkExpression(e.Object)
 	case *ast.BinaryExpression:
+		if strings.HasPrefix(e.Operator, "is ") {
+			tc.checkEnumComparison(e.Left, e.Right)
+		}
 		tc.checkExpression(e.Left)
 		tc.checkExpression(e.Right)
 	case *ast.UnaryExpression:
@@ -395,7 +822,182 @@ func (tc *TypeChecker) checkExpression(expr ast.Expression) {
 		tc.checkExpression(e.Value)
 	case *ast.ErrorTypeCheckExpression:
 		tc.checkExpression(e.Value)
+	case *ast.ReceiveExpression:
+		tc.checkExpression(e.Channel)
+	case *ast.InterpolatedString:
+		for _, part := range e.Parts {
+			tc.checkExpression(part)
+		}
+	case *ast.FunctionLiteral:
+		outerReturns, outerFunction, outerLoops := tc.returns, tc.function, tc.loops
+		tc.function, tc.loops = nil, nil
+		tc.pushScope()
+		tc.checkStatements(e.Body)
+		tc.popScope()
+		tc.returns, tc.function, tc.loops = outerReturns, outerFunction, outerLoops
+	}
+}
+
+// pushLoop records that the statements checked next are inside a loop
+// called label. A loop may not take the name of a loop around it, since a
+// break naming it could then mean either.
+func (tc *TypeChecker) pushLoop(label string, line int) {
+	if label != "" && tc.loopCalled(label) {
+		tc.error(line, "a loop around this one is already called '%s'", label)
+	}
+	tc.loops = append(tc.loops, label)
+}
+
+// popLoop closes the innermost loop opened with pushLoop.
+func (tc *TypeChecker) popLoop() {
+	tc.loops = tc.loops[:len(tc.loops)-1]
+}
+
+// loopCalled reports whether one of the loops around the current statement
+// is called label.
+func (tc *TypeChecker) loopCalled(label string) bool {
+	for _, l := range tc.loops {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLoopLabel checks that a break or continue naming a loop is inside a
+// loop of that name.
+func (tc *TypeChecker) checkLoopLabel(label string, line int) {
+	if label != "" && !tc.loopCalled(label) {
+		tc.error(line, "there is no loop called '%s' around this statement", label)
+	}
+}
+
+// forget drops what is known about a variable's value when a destructuring
+// declaration or assignment gives it one the checker cannot follow.
+func (tc *TypeChecker) forget(name string) {
+	delete(tc.varTypes, name)
+	delete(tc.varEnums, name)
+	delete(tc.varStructs, name)
+}
+
+// checkUnpack reports a destructuring whose names cannot fit its value: a
+// list written out with a different number of items, a call to a function
+// that always returns a different number of values, or a structure without
+// a field of one of the names. When rest is set the last name collects the
+// leftovers, so there need only be enough for the names before it, and a
+// structure, which has nothing left over, cannot be unpacked at all.
+func (tc *TypeChecker) checkUnpack(names []string, rest bool, value ast.Expression, line int) {
+	fits := func(count int) bool {
+		if rest {
+			return count >= len(names)-1
+		}
+		return count == len(names)
+	}
+	into := fmt.Sprintf("%d variables", len(names))
+	if rest {
+		into = fmt.Sprintf("%d variables and the rest", len(names)-1)
+	}
+	switch v := value.(type) {
+	case *ast.ListLiteral:
+		if !fits(len(v.Elements)) {
+			tc.error(line, "cannot unpack a list of %d item(s) into %s", len(v.Elements), into)
+		}
+	case *ast.FunctionCall:
+		if _, declared := tc.funcDecls[v.Name]; !declared {
+			return
+		}
+		if count, ok := tc.returnCounts[v.Name]; ok && !fits(count) {
+			tc.error(line, "cannot unpack the %d values '%s' returns into %s", count, v.Name, into)
+		}
+	case *ast.StructInstantiation:
+		tc.checkUnpackFields(names, rest, v.StructName, line)
+	case *ast.Identifier:
+		if structName, ok := tc.varStructs[v.Name]; ok {
+			tc.checkUnpackFields(names, rest, structName, line)
+		}
+	}
+}
+
+// checkUnpackFields reports a name with no field in the structure called
+// structName or the ones it inherits from. Structures the checker has not
+// seen are left to the runtime.
+func (tc *TypeChecker) checkUnpackFields(names []string, rest bool, structName string, line int) {
+	if rest {
+		tc.error(line, "cannot collect the rest of a %s; only a list has items left over", structName)
+		return
+	}
+	fields := make(map[string]bool)
+	for name, seen := structName, 0; name != "" && seen <= len(tc.structs); seen++ {
+		sd, ok := tc.structs[name]
+		if !ok {
+			return
+		}
+		for _, f := range sd.Fields {
+			fields[f.Name] = true
+		}
+		name = sd.Parent
+	}
+	for _, name := range names {
+		if !fields[name] {
+			tc.error(line, "cannot unpack '%s' from a %s, which has no field called '%s'", name, structName, name)
+			return
+		}
+	}
+}
+
+// sameCount returns the number every count in counts equals, or 0 when they
+// differ or there are none.
+func sameCount(counts []int) int {
+	if len(counts) == 0 {
+		return 0
+	}
+	for _, c := range counts[1:] {
+		if c != counts[0] {
+			return 0
+		}
+	}
+	return counts[0]
+}
+
+// enumOf returns the name of the enum an expression's value is a member of,
+// as in "Color's red" or a variable declared from one, or "" if unknown.
+func (tc *TypeChecker) enumOf(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.MethodCall:
+		if id, ok := e.Object.(*ast.Identifier); ok && len(e.Arguments) == 0 {
+			if _, isEnum := tc.enums[id.Name]; isEnum {
+				return id.Name
+			}
+		}
+	case *ast.FieldAccess:
+		if id, ok := e.Object.(*ast.Identifier); ok {
+			if _, isEnum := tc.enums[id.Name]; isEnum {
+				return id.Name
+			}
+		}
+	case *ast.Identifier:
+		return tc.varEnums[e.Name]
+	}
+	return ""
+}
+
+// checkEnumComparison reports a comparison between members of two different
+// enums, which could never be equal.
+func (tc *TypeChecker) checkEnumComparison(left, right ast.Expression) {
+	l, r := tc.enumOf(left), tc.enumOf(right)
+	if l != "" && r != "" && l != r {
+		tc.error(0, "cannot compare a %s with a %s; they are different enums", l, r)
+	}
+}
+
+// checkEnumMember reports a reference to a member the enum does not have.
+func (tc *TypeChecker) checkEnumMember(enum string, members []string, name string) {
+	for _, m := range members {
+		if m == name {
+			return
+		}
 	}
+	tc.error(0, "%s has no member '%s'", enum, name)
 }
 
 func (tc *TypeChecker) checkFunctionCallArgs(name string, args []ast.Expression) {
@@ -423,3 +1025,333 @@ func (tc *TypeChecker) checkFunctionCallArgs(name string, args []ast.Expression)
 		}
 	}
 }
+
+// structCan reports whether the structure called name, or one it inherits
+// from, says it can capability. known is false when the answer depends on a
+// structure the checker has not seen, such as one from an import.
+func (tc *TypeChecker) structCan(name, capability string) (can, known bool) {
+	for seen := 0; name != "" && seen <= len(tc.structs); seen++ {
+		sd, ok := tc.structs[name]
+		if !ok {
+			return false, false
+		}
+		for _, c := range sd.Capabilities {
+			if c == capability {
+				return true, true
+			}
+		}
+		name = sd.Parent
+	}
+	return false, true
+}
+
+// checkStructCapabilities reports a structure that says it can do something
+// without having every function the capability requires. Capabilities and
+// parents the checker has not seen are left to the runtime.
+func (tc *TypeChecker) checkStructCapabilities(s *ast.StructDecl) {
+	for _, capability := range s.Capabilities {
+		required, ok := tc.capabilities[capability]
+		if !ok {
+			continue
+		}
+		for _, fn := range required {
+			has, known := tc.structHasMethod(s, fn)
+			if known && !has {
+				tc.error(0, "%s says it can %s, but it has no function '%s'", s.Name, capability, fn)
+				break
+			}
+		}
+	}
+}
+
+// structHasMethod reports whether s declares or inherits the function name.
+// known is false when an ancestor is a structure the checker has not seen.
+func (tc *TypeChecker) structHasMethod(s *ast.StructDecl, name string) (has, known bool) {
+	for seen := 0; s != nil && seen <= len(tc.structs); seen++ {
+		for _, m := range s.Methods {
+			if m.Name == name {
+				return true, true
+			}
+		}
+		if s.Parent == "" {
+			return false, true
+		}
+		s = tc.structs[s.Parent]
+	}
+	return false, false
+}
+
+// checkUserCall checks a call to a function declared in the program against
+// its parameters. The arguments must fit them the way the call will bind
+// them at runtime, allowing for defaults and named arguments. An argument
+// passed for a parameter declared "as anything that can X" must not be known
+// to be an instance of a structure that never said it can X, and one passed
+// for a parameter declared with a type ("takes width as number") must not be
+// known to be of another type.
+func (tc *TypeChecker) checkUserCall(fc *ast.FunctionCall, line int) {
+	fn, ok := tc.funcDecls[fc.Name]
+	if !ok {
+		return
+	}
+	tc.checkCallTo(fn, fc.Name, fc.Arguments, fc.NamedArguments, line)
+}
+
+// checkCallTo checks a call, under the given name, to the declared function
+// fn, as checkUserCall describes.
+func (tc *TypeChecker) checkCallTo(fn *ast.FunctionDecl, name string, arguments []ast.Expression, namedArguments []*ast.NamedArgument, line int) {
+	positional := make([]interface{}, len(arguments))
+	for i, arg := range arguments {
+		positional[i] = arg
+	}
+	names := make([]string, len(namedArguments))
+	named := make([]interface{}, len(namedArguments))
+	for i, na := range namedArguments {
+		names[i], named[i] = na.Name, na.Value
+	}
+	hasDefault := func(i int) bool { return i < len(fn.Defaults) && fn.Defaults[i] != nil }
+	args, _, err := types.BindArguments(name, fn.Parameters, fn.Variadic, hasDefault, positional, names, named)
+	if err != nil {
+		tc.error(line, "%s", err)
+		return
+	}
+
+	for i, capability := range fn.ParamCapabilities {
+		if capability == "" || i >= len(args) {
+			continue
+		}
+		arg, _ := args[i].(ast.Expression)
+		structName := tc.structOf(arg)
+		if structName == "" {
+			continue
+		}
+		if can, known := tc.structCan(structName, capability); known && !can {
+			tc.error(line, "function '%s' needs '%s' to be something that can %s, but %s does not say it can %s",
+				name, fn.Parameters[i], capability, structName, capability)
+		}
+	}
+
+	for i, typeName := range fn.ParamTypes {
+		if typeName == "" || i >= len(args) {
+			continue
+		}
+		arg, _ := args[i].(ast.Expression)
+		if got, mismatch := tc.typeMismatch(arg, typeName); mismatch {
+			tc.error(line, "function '%s' needs '%s' to be %s, but got %s",
+				name, fn.Parameters[i], types.Article(typeName), got)
+		}
+	}
+}
+
+// builtinErrorTypes are the error types the language raises itself.
+var builtinErrorTypes = map[string]bool{
+	"RuntimeError": true, "TypeError": true, "OverflowError": true, "AssertionError": true,
+}
+
+// checkSignatureTypes notes each type fn's signature names that is not built
+// in, for checkNamedTypes, and reports a function that gives back values but
+// says what it returns: calling it makes a generator, whatever it says.
+func (tc *TypeChecker) checkSignatureTypes(fn *ast.FunctionDecl) {
+	for i, typeName := range fn.ParamTypes {
+		if _, builtin := types.MatchesName(types.TypeNull, typeName); typeName != "" && !builtin {
+			tc.namedTypes = append(tc.namedTypes, namedType{
+				name: typeName, function: fn.Name, use: "takes " + fn.Parameters[i] + " as", line: fn.Line,
+			})
+		}
+	}
+	if fn.ReturnType == "" {
+		return
+	}
+	if fn.Generator {
+		tc.error(fn.Line, "function '%s' gives back values, so calling it makes a generator; it cannot say it returns %s",
+			fn.Name, types.Article(fn.ReturnType))
+		return
+	}
+	if _, builtin := types.MatchesName(types.TypeNull, fn.ReturnType); !builtin {
+		tc.namedTypes = append(tc.namedTypes, namedType{
+			name: fn.ReturnType, function: fn.Name, use: "returns", line: fn.Line,
+		})
+	}
+}
+
+// checkNamedTypes reports each type a signature names that is neither built
+// in nor declared, here or in a file imported.
+func (tc *TypeChecker) checkNamedTypes() {
+	for _, nt := range tc.namedTypes {
+		if !tc.isDeclaredType(nt.name) {
+			tc.error(nt.line, "function '%s' %s %s, but there is no structure, enum or error type called '%s'",
+				nt.function, nt.use, types.Article(nt.name), nt.name)
+		}
+	}
+}
+
+// isDeclaredType reports whether name is a structure, enum, capability or
+// error type declared in this file or in one it imports.
+func (tc *TypeChecker) isDeclaredType(name string) bool {
+	_, isStruct := tc.structs[name]
+	_, isEnum := tc.enums[name]
+	_, isCapability := tc.capabilities[name]
+	if isStruct || isEnum || isCapability || tc.errorTypes[name] || builtinErrorTypes[name] {
+		return true
+	}
+	for _, prog := range tc.importedProgs {
+		for _, stmt := range prog.Statements {
+			switch d := stmt.(type) {
+			case *ast.StructDecl:
+				if d.Name == name {
+					return true
+				}
+			case *ast.EnumDecl:
+				if d.Name == name {
+					return true
+				}
+			case *ast.CapabilityDecl:
+				if d.Name == name {
+					return true
+				}
+			case *ast.ErrorTypeDecl:
+				if d.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// checkReturnType reports a Return whose value is known not to be of the
+// type the function being checked says it returns. A generator's Return
+// only ends it, so it is not checked.
+func (tc *TypeChecker) checkReturnType(s *ast.ReturnStatement) {
+	fn := tc.function
+	if fn == nil || fn.ReturnType == "" || fn.Generator {
+		return
+	}
+	var got string
+	var mismatch bool
+	_, isNothing := s.Value.(*ast.NothingLiteral)
+	switch {
+	case s.Values != nil:
+		got, mismatch = tc.typeMismatch(&ast.ListLiteral{Elements: s.Values}, fn.ReturnType)
+	case s.Value == nil || isNothing:
+		matches, _ := types.MatchesName(types.TypeNull, fn.ReturnType)
+		got, mismatch = "nothing", !matches
+	default:
+		got, mismatch = tc.typeMismatch(s.Value, fn.ReturnType)
+	}
+	if mismatch {
+		tc.error(s.Line, "function '%s' should return %s, but this returns %s",
+			fn.Name, types.Article(fn.ReturnType), types.Article(got))
+	}
+}
+
+// structOf returns the name of the structure expr is known to be an
+// instance of, or "".
+func (tc *TypeChecker) structOf(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case *ast.StructInstantiation:
+		return e.StructName
+	case *ast.Identifier:
+		return tc.varStructs[e.Name]
+	}
+	return ""
+}
+
+// typeMismatch reports whether expr is known never to be of the type called
+// want, as written in a function's signature, and if so describes what it
+// is instead. Values the checker cannot follow are left to the runtime.
+func (tc *TypeChecker) typeMismatch(expr ast.Expression, want string) (got string, mismatch bool) {
+	if structName := tc.structOf(expr); structName != "" {
+		if _, builtin := types.MatchesName(types.TypeStruct, want); builtin {
+			return structName, true
+		}
+		if isA, known := tc.structIsA(structName, want); known && !isA {
+			return structName, true
+		}
+		return "", false
+	}
+	tk := tc.exprType(expr)
+	if tk == types.TypeUnknown {
+		return "", false
+	}
+	if matches, builtin := types.MatchesName(tk, want); builtin {
+		return types.Name(tk), !matches
+	}
+	// A value of a built-in type is never a structure or an enum member.
+	_, isStruct := tc.structs[want]
+	_, isEnum := tc.enums[want]
+	return types.Name(tk), isStruct || isEnum
+}
+
+// checkElement reports a value put into a list or lookup table declared
+// with an element type that value could never have.
+func (tc *TypeChecker) checkElement(name string, value ast.Expression, line int) {
+	typeName, ok := tc.varElements[name]
+	if !ok {
+		return
+	}
+	_, _, elemType := types.ElementTypes(typeName)
+	if got, mismatch := tc.typeMismatch(value, elemType); mismatch {
+		tc.error(line, "'%s' is a %s, so it cannot hold %s", name, typeName, types.Article(got))
+	}
+}
+
+// checkKey reports a key of the wrong type for a lookup table declared with
+// a key type.
+func (tc *TypeChecker) checkKey(name string, key ast.Expression, line int) {
+	typeName, ok := tc.varElements[name]
+	if !ok {
+		return
+	}
+	_, keyType, _ := types.ElementTypes(typeName)
+	if keyType == "" {
+		return
+	}
+	if got, mismatch := tc.typeMismatch(key, keyType); mismatch {
+		tc.error(line, "'%s' is a %s, so its keys cannot be %s", name, typeName, types.Article(got))
+	}
+}
+
+// checkListElements checks each item of a list written out as the value of
+// a variable declared with an element type.
+func (tc *TypeChecker) checkListElements(name string, value ast.Expression, line int) {
+	if list, ok := value.(*ast.ListLiteral); ok {
+		for _, elem := range list.Elements {
+			tc.checkElement(name, elem, line)
+		}
+	}
+}
+
+// checkElementCall checks the item given to append or insert when the list
+// is a variable declared with an element type, as in append(scores, "ten").
+func (tc *TypeChecker) checkElementCall(call *ast.FunctionCall, line int) {
+	if tc.userFunctions[call.Name] || len(call.Arguments) == 0 {
+		return
+	}
+	list, ok := call.Arguments[0].(*ast.Identifier)
+	if !ok {
+		return
+	}
+	switch {
+	case call.Name == "append" && len(call.Arguments) == 2:
+		tc.checkElement(list.Name, call.Arguments[1], line)
+	case call.Name == "insert" && len(call.Arguments) == 3:
+		tc.checkElement(list.Name, call.Arguments[2], line)
+	}
+}
+
+// structIsA reports whether the structure called name is want or inherits
+// from it. known is false when the answer depends on a structure the checker
+// has not seen.
+func (tc *TypeChecker) structIsA(name, want string) (isA, known bool) {
+	for seen := 0; name != "" && seen <= len(tc.structs); seen++ {
+		if name == want {
+			return true, true
+		}
+		sd, ok := tc.structs[name]
+		if !ok {
+			return false, false
+		}
+		name = sd.Parent
+	}
+	return false, true
+}
@@ -3,6 +3,7 @@ package vm
 import (
 	"github.com/Advik-B/english/astvm/types"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -27,6 +28,10 @@ func ToString(v Value) string {
 		return strconv.FormatUint(val, 10)
 	case float32:
 		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case *types.Decimal:
+		return val.String()
+	case *big.Int:
+		return val.String()
 	case string:
 		return val
 	case bool:
@@ -62,8 +67,22 @@ func ToString(v Value) string {
 		return "{" + strings.Join(parts, ", ") + "}"
 	case *FunctionValue:
 		return fmt.Sprintf("<function %s>", val.Name)
+	case *Generator:
+		return val.String()
+	case *types.Task:
+		return val.String()
+	case *types.Channel:
+		return val.String()
 	case *StructInstance:
 		return fmt.Sprintf("<%s instance>", val.Definition.Name)
+	case *types.EnumValue:
+		return val.Name
+	case *types.EnumType:
+		return val.String()
+	case *types.Module:
+		return val.String()
+	case *types.Capability:
+		return val.String()
 	case *types.ErrorValue:
 		return fmt.Sprintf("<error: %s>", val.Message)
 	case *ReferenceValue:
@@ -92,6 +111,11 @@ func ToNumber(v Value) (float64, error) {
 		return float64(val), nil
 	case float32:
 		return float64(val), nil
+	case *types.Decimal:
+		return val.Float64(), nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(val).Float64()
+		return f, nil
 	default:
 		return 0, fmt.Errorf(
 			"TypeError: expected number, got %s\n  Hint: use 'cast to number' to convert explicitly",
@@ -10,6 +10,7 @@ import (
 type Environment struct {
 	variables        map[string]Value
 	variableTypes    map[string]types.TypeKind // declared type; fixed at first Define
+	elementTypes     map[string]string         // declared type with element types, e.g. "list of number"
 	constants        map[string]bool
 	functions        map[string]*FunctionValue
 	structs          map[string]*StructDefinition
@@ -22,6 +23,7 @@ func NewEnvironment() *Environment {
 	return &Environment{
 		variables:        make(map[string]Value),
 		variableTypes:    make(map[string]types.TypeKind),
+		elementTypes:     make(map[string]string),
 		constants:        make(map[string]bool),
 		functions:        make(map[string]*FunctionValue),
 		structs:          make(map[string]*StructDefinition),
@@ -34,6 +36,7 @@ func (e *Environment) NewChild() *Environment {
 	return &Environment{
 		variables:        make(map[string]Value),
 		variableTypes:    make(map[string]types.TypeKind),
+		elementTypes:     make(map[string]string),
 		constants:        make(map[string]bool),
 		functions:        make(map[string]*FunctionValue),
 		structs:          make(map[string]*StructDefinition),
@@ -85,6 +88,9 @@ func (e *Environment) Set(name string, value Value) error {
 					types.Name(actual), name, types.Name(declared),
 				)
 			}
+			if err := types.CheckElements(name, e.elementTypes[name], value, e); err != nil {
+				return err
+			}
 		}
 		e.variables[name] = value
 		return nil
@@ -120,6 +126,9 @@ func (e *Environment) DefineTyped(name string, typeName string, value Value, isC
 		return fmt.Errorf("TypeError: unknown type '%s'\n  Hint: valid types are %s",
 			typeName, strings.Join(types.UserTypeNames(), ", "))
 	}
+	if err := types.CheckElementTypes(typeName, e); err != nil {
+		return err
+	}
 	// Type-check the initial value if provided
 	if value != nil {
 		actual := inferTypeKind(value)
@@ -129,13 +138,32 @@ func (e *Environment) DefineTyped(name string, typeName string, value Value, isC
 				types.Name(targetType), name, types.Name(actual),
 			)
 		}
+		if err := types.CheckElements(name, typeName, value, e); err != nil {
+			return err
+		}
 	}
 	e.variables[name] = value
 	e.constants[name] = isConstant
 	e.variableTypes[name] = targetType
+	if _, _, elemType := types.ElementTypes(typeName); elemType != "" {
+		e.elementTypes[name] = typeName
+	}
 	return nil
 }
 
+// GetElementType returns the declared type, such as "list of number", of a
+// variable in the scope chain that was declared with element types.
+func (e *Environment) GetElementType(name string) (string, bool) {
+	if _, ok := e.variables[name]; ok {
+		typeName, ok := e.elementTypes[name]
+		return typeName, ok
+	}
+	if e.parent != nil {
+		return e.parent.GetElementType(name)
+	}
+	return "", false
+}
+
 // DefineErrorType registers a custom error type in the root environment.
 // parent is the parent type name; pass "" for a root error type.
 func (e *Environment) DefineErrorType(name, parent string) {
@@ -178,6 +206,37 @@ func (e *Environment) IsSubtypeOf(childType, parentType string) bool {
 	return false
 }
 
+// IsOfType reports whether v is of the named type: a built-in type, an error
+// type (including inherited types), a structure or an enum.
+func (e *Environment) IsOfType(v Value, name string) bool {
+	if matches, known := types.MatchesName(inferTypeKind(v), name); known {
+		return matches
+	}
+	switch val := v.(type) {
+	case *types.ErrorValue:
+		return e.IsSubtypeOf(val.ErrorType, name)
+	case *StructInstance:
+		return val.Definition.IsA(name)
+	case *types.EnumValue:
+		return val.Type.Name == name
+	}
+	return false
+}
+
+// IsDeclaredType reports whether name is a structure, enum or error type
+// declared in scope.
+func (e *Environment) IsDeclaredType(name string) bool {
+	if _, ok := e.GetStruct(name); ok {
+		return true
+	}
+	if v, ok := e.Get(name); ok {
+		if _, isEnum := v.(*types.EnumType); isEnum {
+			return true
+		}
+	}
+	return e.IsKnownErrorType(name)
+}
+
 // GetFunction retrieves a function searching up the scope chain.
 func (e *Environment) GetFunction(name string) (*FunctionValue, bool) {
 	if fn, ok := e.functions[name]; ok {
@@ -189,6 +248,28 @@ func (e *Environment) GetFunction(name string) (*FunctionValue, bool) {
 	return nil, false
 }
 
+// GetCallable resolves a name used in a call, searching up the scope chain.
+// At each level a declared function wins over a variable, and a variable only
+// matches when it holds a function value (e.g. "Declare double to be a function ...").
+// Built-ins registered in the same scope come last, so a variable can shadow
+// a stdlib name like "first".
+func (e *Environment) GetCallable(name string) (*FunctionValue, bool) {
+	fn, isFunc := e.functions[name]
+	if isFunc && fn.Body != nil {
+		return fn, true
+	}
+	if v, ok := e.variables[name].(*FunctionValue); ok {
+		return v, true
+	}
+	if isFunc {
+		return fn, true
+	}
+	if e.parent != nil {
+		return e.parent.GetCallable(name)
+	}
+	return nil, false
+}
+
 // DefineFunction registers a function in the current scope.
 func (e *Environment) DefineFunction(name string, fn *FunctionValue) {
 	e.functions[name] = fn
@@ -238,3 +319,40 @@ func (e *Environment) GetStruct(name string) (*StructDefinition, bool) {
 func (e *Environment) DefineStruct(name string, def *StructDefinition) {
 	e.structs[name] = def
 }
+
+// importFrom copies everything declared in from's own scope into this one,
+// replacing names it already has, as importing a whole file does with the
+// scope the file ran in. Names in private are left out.
+func (e *Environment) importFrom(from *Environment, private map[string]bool) {
+	for k, v := range from.variables {
+		if !private[k] {
+			e.variables[k] = v
+		}
+	}
+	for k, v := range from.variableTypes {
+		if !private[k] {
+			e.variableTypes[k] = v
+		}
+	}
+	for k, v := range from.elementTypes {
+		if !private[k] {
+			e.elementTypes[k] = v
+		}
+	}
+	for k, v := range from.constants {
+		if !private[k] {
+			e.constants[k] = v
+		}
+	}
+	for k, v := range from.functions {
+		if !private[k] {
+			e.functions[k] = v
+		}
+	}
+	for k, v := range from.structs {
+		e.structs[k] = v
+	}
+	for k, v := range from.customErrorTypes {
+		e.customErrorTypes[k] = v
+	}
+}
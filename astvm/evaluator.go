@@ -5,6 +5,7 @@ import (
 	"github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/bytecode"
 	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 	"github.com/Advik-B/english/astvm/types"
 	"fmt"
 	"io"
@@ -19,6 +20,27 @@ type Evaluator struct {
 	builtinFn   BuiltinFunc // injected stdlib evaluator
 	currentLine int         // source line of the statement currently being evaluated
 	out         io.Writer   // destination for Print/output statements (default: os.Stdout)
+
+	wrapIntegers bool // set by "Use wrapping arithmetic."; integer overflow wraps instead of raising
+	noAssertions bool // set by DisableAssertions; "Make sure that" checks are skipped
+
+	// yield hands a value given back by "Give back" to the loop consuming
+	// the generator this evaluator runs the body of. It is nil elsewhere.
+	yield func(Value)
+
+	// tasks runs the program's background tasks; it is nil until the first
+	// one starts or a channel is used. task is the one this evaluator runs.
+	tasks *types.Scheduler
+	task  *types.Task
+
+	// modules runs every imported file at most once and catches files that
+	// import each other in a circle. It is shared with the evaluators of
+	// generators and background tasks.
+	modules *project.Registry
+
+	// file is the source file of the code being run, or "" when that is not
+	// a file; the files it imports are looked for from its directory.
+	file string
 }
 
 // NewEvaluator creates a new evaluator with the given environment and optional builtin function.
@@ -28,9 +50,24 @@ func NewEvaluator(env *Environment, builtinFn BuiltinFunc) *Evaluator {
 		callStack: []string{"<main>"},
 		builtinFn: builtinFn,
 		out:       os.Stdout,
+		modules:   project.NewRegistry(""),
 	}
 }
 
+// SetFile tells the evaluator that the program it runs was read from file,
+// so its imports are looked for from the file's directory and a file
+// importing it back is reported as a circular import.
+func (ev *Evaluator) SetFile(file string) {
+	ev.file = file
+	ev.modules = project.NewRegistry(file)
+}
+
+// DisableAssertions makes every "Make sure that" check a no-op, as
+// "english run --no-assertions" does for production runs.
+func (ev *Evaluator) DisableAssertions() {
+	ev.noAssertions = true
+}
+
 // SetOutput redirects all Print and output statements to w instead of os.Stdout.
 // This is used by the REPL so that program output goes to the same writer as
 // prompts and error messages, making the REPL fully testable without OS-level
@@ -47,6 +84,16 @@ func (ev *Evaluator) runtimeError(message string) error {
 	}
 }
 
+// catchable attaches the current call stack to a typed error value raised by
+// the types package (an OverflowError, say), so that it can be caught by type
+// like an error raised with Raise.
+func (ev *Evaluator) catchable(err error) error {
+	if errVal, ok := err.(*types.ErrorValue); ok {
+		errVal.CallStack = append([]string{}, ev.callStack...)
+	}
+	return err
+}
+
 // getStatementLine extracts the source line from an AST statement node.
 // Returns 0 if the node type does not carry line information.
 func getStatementLine(stmt ast.Statement) int {
@@ -67,8 +114,20 @@ func getStatementLine(stmt ast.Statement) int {
 		return s.Line
 	case *ast.ReturnStatement:
 		return s.Line
+	case *ast.YieldStatement:
+		return s.Line
+	case *ast.BackgroundStatement:
+		return s.Line
+	case *ast.TestBlock:
+		return s.Line
+	case *ast.WaitStatement:
+		return s.Line
+	case *ast.SendStatement:
+		return s.Line
 	case *ast.IfStatement:
 		return s.Line
+	case *ast.WhenStatement:
+		return s.Line
 	case *ast.WhileLoop:
 		return s.Line
 	case *ast.ForLoop:
@@ -79,10 +138,20 @@ func getStatementLine(stmt ast.Statement) int {
 		return s.Line
 	case *ast.RaiseStatement:
 		return s.Line
+	case *ast.AssertStatement:
+		return s.Line
 	case *ast.TryStatement:
 		return s.Line
 	case *ast.SwapStatement:
 		return s.Line
+	case *ast.OverflowModeStatement:
+		return s.Line
+	case *ast.ImportStatement:
+		return s.Line
+	case *ast.BreakStatement:
+		return s.Line
+	case *ast.ContinueStatement:
+		return s.Line
 	}
 	return 0
 }
@@ -91,7 +160,12 @@ func getStatementLine(stmt ast.Statement) int {
 func (ev *Evaluator) Eval(node interface{}) (Value, error) {
 	switch node := node.(type) {
 	case *ast.Program:
-		return ev.evalProgram(node)
+		result, err := ev.evalProgram(node)
+		if err == nil && ev.tasks != nil {
+			// The program is not over until its background tasks are.
+			err = ev.tasks.Finish(ev.task)
+		}
+		return result, err
 	case *ast.ImportStatement:
 		return ev.evalImport(node)
 	case *ast.VariableDecl:
@@ -100,6 +174,10 @@ func (ev *Evaluator) Eval(node interface{}) (Value, error) {
 		return ev.evalTypedVariableDecl(node)
 	case *ast.ErrorTypeDecl:
 		return ev.evalErrorTypeDecl(node)
+	case *ast.EnumDecl:
+		return ev.evalEnumDecl(node)
+	case *ast.CapabilityDecl:
+		return ev.evalCapabilityDecl(node)
 	case *ast.Assignment:
 		return ev.evalAssignment(node)
 	case *ast.IndexAssignment:
@@ -112,8 +190,21 @@ func (ev *Evaluator) Eval(node interface{}) (Value, error) {
 		return ev.evalOutput(node)
 	case *ast.ReturnStatement:
 		return ev.evalReturn(node)
+	case *ast.YieldStatement:
+		return ev.evalYield(node)
+	case *ast.BackgroundStatement:
+		return ev.evalBackground(node)
+	case *ast.TestBlock:
+		// Tests run only under "english test", each in a fresh environment.
+		return nil, nil
+	case *ast.WaitStatement:
+		return ev.evalWait(node)
+	case *ast.SendStatement:
+		return ev.evalSend(node)
 	case *ast.IfStatement:
 		return ev.evalIfStatement(node)
+	case *ast.WhenStatement:
+		return ev.evalWhenStatement(node)
 	case *ast.WhileLoop:
 		return ev.evalWhileLoop(node)
 	case *ast.ForLoop:
@@ -123,17 +214,25 @@ func (ev *Evaluator) Eval(node interface{}) (Value, error) {
 	case *ast.ToggleStatement:
 		return ev.evalToggle(node)
 	case *ast.BreakStatement:
-		return &BreakValue{}, nil
+		return &BreakValue{Label: node.Label}, nil
 	case *ast.ContinueStatement:
-		return &ContinueValue{}, nil
+		return &ContinueValue{Label: node.Label}, nil
 	case *ast.NothingLiteral:
 		return nil, nil
 	case *ast.AskExpression:
 		return ev.evalAskExpression(node)
 	case *ast.NumberLiteral:
 		return node.Value, nil
+	case *ast.DecimalLiteral:
+		value, err := types.ParseExact(node.Digits, node.Whole)
+		if err != nil {
+			return nil, ev.runtimeError(err.Error())
+		}
+		return value, nil
 	case *ast.StringLiteral:
 		return node.Value, nil
+	case *ast.InterpolatedString:
+		return ev.evalInterpolatedString(node)
 	case *ast.BooleanLiteral:
 		return node.Value, nil
 	case *ast.ListLiteral:
@@ -148,6 +247,8 @@ func (ev *Evaluator) Eval(node interface{}) (Value, error) {
 		return ev.evalUnaryExpression(node)
 	case *ast.FunctionCall:
 		return ev.evalFunctionCall(node)
+	case *ast.FunctionLiteral:
+		return ev.evalFunctionLiteral(node)
 	case *ast.MethodCall:
 		return ev.evalMethodCall(node)
 	case *ast.IndexExpression:
@@ -161,6 +262,10 @@ func (ev *Evaluator) Eval(node interface{}) (Value, error) {
 		return ev.evalArrayLiteral(node)
 	case *ast.LookupTableLiteral:
 		return types.NewLookupTable(), nil
+	case *ast.ChannelLiteral:
+		return &types.Channel{}, nil
+	case *ast.ReceiveExpression:
+		return ev.evalReceive(node)
 	case *ast.LookupKeyAccess:
 		return ev.evalLookupKeyAccess(node)
 	case *ast.LookupKeyAssignment:
@@ -184,8 +289,13 @@ func (ev *Evaluator) Eval(node interface{}) (Value, error) {
 		return ev.evalTryStatement(node)
 	case *ast.RaiseStatement:
 		return ev.evalRaiseStatement(node)
+	case *ast.AssertStatement:
+		return ev.evalAssertStatement(node)
 	case *ast.SwapStatement:
 		return ev.evalSwapStatement(node)
+	case *ast.OverflowModeStatement:
+		ev.wrapIntegers = node.Wrap
+		return nil, nil
 	case *ast.CommentStatement:
 		// Comments are no-ops at runtime.
 		return nil, nil
@@ -208,6 +318,9 @@ func (ev *Evaluator) evalProgram(prog *ast.Program) (Value, error) {
 		if line := getStatementLine(stmt); line > 0 {
 			ev.currentLine = line
 		}
+		if ev.tasks != nil {
+			ev.tasks.Checkpoint()
+		}
 		val, err := ev.Eval(stmt)
 		if err != nil {
 			return nil, err
@@ -225,7 +338,55 @@ func (ev *Evaluator) evalImport(is *ast.ImportStatement) (Value, error) {
 	// Supports selective imports, import all, and safe imports.
 	// Uses bytecode caching (__engcache__) for faster loading.
 
-	// Try to load from cache or parse the source file
+	path, err := project.ResolveImport(ev.file, is.Path)
+	if err != nil {
+		return nil, ev.runtimeError(fmt.Sprintf("failed to import '%s': %v", is.Path, err))
+	}
+
+	var mod *importedFile
+	if is.IsSafe {
+		// A safe import only runs the file's declarations, so nothing it
+		// does is worth remembering for the next import of the same file.
+		program, err := ev.loadImport(path, is)
+		if err != nil {
+			return nil, err
+		}
+		// The declarations still run in a scope of their own, so the
+		// file's public functions can call its private ones.
+		mod = &importedFile{env: ev.env.NewChild(), private: project.PrivateNames(program)}
+		oldEnv, oldFile := ev.env, ev.file
+		ev.env, ev.file = mod.env, path
+		_, err = ev.evalSafeImport(program, is)
+		ev.env, ev.file = oldEnv, oldFile
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		mod, err = ev.runModule(path, is)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Handle different import modes
+	if is.Alias != "" {
+		// Namespaced import: bind the file's declarations to a module
+		return ev.bindModule(mod, is)
+	} else if len(is.Items) > 0 {
+		// Selective import: import specific items
+		return ev.evalSelectiveImport(mod, is)
+	}
+	// Import all: everything the file declared publicly joins the current
+	// scope
+	ev.env.importFrom(mod.env, mod.private)
+
+	// Import statements don't produce a value
+	return nil, nil
+}
+
+// loadImport loads the program of the file at path, from the bytecode cache
+// when it is up to date.
+func (ev *Evaluator) loadImport(path string, is *ast.ImportStatement) (*ast.Program, error) {
 	parseFunc := func(path string) (*ast.Program, error) {
 		content, err := os.ReadFile(path)
 		if err != nil {
@@ -238,28 +399,47 @@ func (ev *Evaluator) evalImport(is *ast.ImportStatement) (Value, error) {
 		return p.Parse()
 	}
 
-	program, _, err := bytecode.LoadCachedOrParse(is.Path, parseFunc)
+	program, _, err := bytecode.LoadCachedOrParse(path, parseFunc)
 	if err != nil {
 		return nil, ev.runtimeError(fmt.Sprintf("failed to import '%s': %v", is.Path, err))
 	}
+	return program, nil
+}
 
-	// Handle different import modes
-	if is.IsSafe {
-		// Safe import: only execute declarations, skip top-level statements
-		return ev.evalSafeImport(program, is)
-	} else if len(is.Items) > 0 {
-		// Selective import: import specific items
-		return ev.evalSelectiveImport(program, is)
-	} else {
-		// Import all: execute everything in current scope
-		_, err = ev.evalProgram(program)
-		if err != nil {
-			return nil, err
-		}
-	}
+// importedFile is an imported file once it has run: the scope it ran in
+// and the names it declared privately, which importers cannot see.
+type importedFile struct {
+	env     *Environment
+	private map[string]bool
+}
 
-	// Import statements don't produce a value
-	return nil, nil
+// runModule runs the file at path in a scope of its own and returns that
+// scope. A file runs once per run: later imports of it get the same scope
+// back, and a file that imports itself, directly or not, is an
+// *project.ImportCycleError.
+func (ev *Evaluator) runModule(path string, is *ast.ImportStatement) (*importedFile, error) {
+	if mod, ok := ev.modules.Lookup(path); ok {
+		return mod.(*importedFile), nil
+	}
+	program, err := ev.loadImport(path, is)
+	if err != nil {
+		return nil, err
+	}
+	if err := ev.modules.Begin(path, is.Line); err != nil {
+		return nil, err
+	}
+	modEnv := ev.env.NewChild()
+	oldEnv, oldFile := ev.env, ev.file
+	ev.env, ev.file = modEnv, path
+	_, err = ev.evalProgram(program)
+	ev.env, ev.file = oldEnv, oldFile
+	ev.modules.End()
+	if err != nil {
+		return nil, err
+	}
+	mod := &importedFile{env: modEnv, private: project.PrivateNames(program)}
+	ev.modules.Store(path, mod)
+	return mod, nil
 }
 
 // evalSafeImport executes only declarations (functions, variables) and skips top-level statements
@@ -277,35 +457,69 @@ func (ev *Evaluator) evalSafeImport(program *ast.Program, is *ast.ImportStatemen
 			if err != nil {
 				return nil, err
 			}
+		case *ast.EnumDecl:
+			_, err := ev.evalEnumDecl(s)
+			if err != nil {
+				return nil, err
+			}
+		case *ast.CapabilityDecl:
+			_, err := ev.evalCapabilityDecl(s)
+			if err != nil {
+				return nil, err
+			}
 			// Skip all other statement types (Print, Call, etc.)
 		}
 	}
 	return nil, nil
 }
 
-// evalSelectiveImport imports only specific items from the file
-func (ev *Evaluator) evalSelectiveImport(program *ast.Program, is *ast.ImportStatement) (Value, error) {
-	// Create a temporary environment for the imported file
-	tempEnv := NewEnvironment()
-	tempEval := NewEvaluator(tempEnv, ev.builtinFn)
-
-	// Execute in temporary environment
-	_, err := tempEval.evalProgram(program)
-	if err != nil {
-		return nil, err
+// bindModule binds is.Alias to a module holding the public variables and
+// functions of file, so none of them clash with the importer's names.
+func (ev *Evaluator) bindModule(file *importedFile, is *ast.ImportStatement) (Value, error) {
+	mod := types.NewModule(is.Alias, is.Path)
+	for name, val := range file.env.variables {
+		if !file.private[name] {
+			mod.Members[name] = val
+		}
+	}
+	for name, fn := range file.env.functions {
+		if !file.private[name] {
+			mod.Members[name] = fn
+		}
+	}
+	mod.Current = func(name string) (interface{}, bool) {
+		if val, ok := file.env.variables[name]; ok {
+			return val, true
+		}
+		if fn, ok := file.env.functions[name]; ok {
+			return fn, true
+		}
+		return nil, false
+	}
+	if err := ev.env.Define(is.Alias, mod, true); err != nil {
+		return nil, ev.runtimeError(fmt.Sprintf("failed to import '%s' as '%s': %v", is.Path, is.Alias, err))
 	}
+	return nil, nil
+}
 
+// evalSelectiveImport imports only specific items from file, none of
+// which may be private
+func (ev *Evaluator) evalSelectiveImport(file *importedFile, is *ast.ImportStatement) (Value, error) {
+	modEnv := file.env
 	// Import only the requested items
 	for _, itemName := range is.Items {
+		if file.private[itemName] {
+			return nil, ev.runtimeError((&project.PrivateError{Name: itemName, File: is.Path}).Error())
+		}
 		// Try to get as variable
-		if val, ok := tempEnv.Get(itemName); ok {
+		if val, ok := modEnv.variables[itemName]; ok {
 			// Check if it's constant
-			isConst := tempEnv.IsConstant(itemName)
+			isConst := modEnv.constants[itemName]
 			err := ev.env.Define(itemName, val, isConst)
 			if err != nil {
 				return nil, ev.runtimeError(fmt.Sprintf("failed to import '%s' from '%s': %v", itemName, is.Path, err))
 			}
-		} else if fn, ok := tempEnv.GetFunction(itemName); ok {
+		} else if fn, ok := modEnv.functions[itemName]; ok {
 			// Import as function
 			ev.env.DefineFunction(itemName, fn)
 		} else {
@@ -321,6 +535,24 @@ func (ev *Evaluator) evalVariableDecl(vd *ast.VariableDecl) (Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	if vd.Names != nil {
+		values, err := ev.unpack(value, vd.Names, vd.Rest)
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range vd.Names {
+			if err := ev.env.Define(name, values[i], vd.IsConstant); err != nil {
+				return nil, &TypeError{Line: vd.Line, Message: err.Error()}
+			}
+		}
+		return nil, nil
+	}
+	if fn, ok := value.(*FunctionValue); ok {
+		if _, isLiteral := vd.Value.(*ast.FunctionLiteral); isLiteral {
+			// Name the function after its variable for errors and stack traces.
+			fn.Name = vd.Name
+		}
+	}
 
 	if err := ev.env.Define(vd.Name, value, vd.IsConstant); err != nil {
 		// Variable redefinition is a compile-time error regardless of when it
@@ -337,10 +569,40 @@ func (ev *Evaluator) evalAssignment(a *ast.Assignment) (Value, error) {
 		return nil, err
 	}
 
+	if a.Names != nil {
+		values, err := ev.unpack(value, a.Names, a.Rest)
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range a.Names {
+			if err := ev.env.Set(name, values[i]); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
 	err = ev.env.Set(a.Name, value)
 	return nil, err
 }
 
+// unpack splits value into one value per name for a destructuring
+// declaration or assignment, the last name collecting the leftover items
+// when rest is set.
+func (ev *Evaluator) unpack(value Value, names []string, rest bool) ([]Value, error) {
+	var values []Value
+	var err error
+	if si, ok := value.(*StructInstance); ok {
+		values, err = types.Unpack(value, names, rest, si.Definition.Name, si.Fields)
+	} else {
+		values, err = types.Unpack(value, names, rest, "", nil)
+	}
+	if err != nil {
+		return nil, ev.catchable(err)
+	}
+	return values, nil
+}
+
 func (ev *Evaluator) evalIndexAssignment(ia *ast.IndexAssignment) (Value, error) {
 	list, ok := ev.env.Get(ia.ListName)
 	if !ok {
@@ -367,6 +629,11 @@ func (ev *Evaluator) evalIndexAssignment(ia *ast.IndexAssignment) (Value, error)
 		if idx < 0 || idx >= len(items) {
 			return nil, ev.runtimeError(fmt.Sprintf("index %d out of range for list of length %d", idx, len(items)))
 		}
+		if typeName, ok := ev.env.GetElementType(ia.ListName); ok {
+			if err := types.CheckElement(ia.ListName, typeName, value, ev.env); err != nil {
+				return nil, ev.catchable(err)
+			}
+		}
 		items[idx] = value
 	case *ArrayValue:
 		if idx < 0 || idx >= len(items.Elements) {
@@ -444,6 +711,8 @@ func (ev *Evaluator) evalLengthExpression(le *ast.LengthExpression) (Value, erro
 		return float64(len(v.Entries)), nil
 	case *RangeValue:
 		return float64(v.Length()), nil
+	case *types.EnumType:
+		return float64(len(v.Members)), nil
 	case string:
 		return float64(len(v)), nil
 	default:
@@ -505,15 +774,46 @@ func (ev *Evaluator) evalToggle(ts *ast.ToggleStatement) (Value, error) {
 
 func (ev *Evaluator) evalFunctionDecl(fd *ast.FunctionDecl) (Value, error) {
 	fn := &FunctionValue{
-		Name:       fd.Name,
-		Parameters: fd.Parameters,
-		Body:       fd.Body,
-		Closure:    ev.env,
+		Name:              fd.Name,
+		Parameters:        fd.Parameters,
+		ParamCapabilities: fd.ParamCapabilities,
+		ParamTypes:        fd.ParamTypes,
+		ReturnType:        fd.ReturnType,
+		Defaults:          fd.Defaults,
+		Variadic:          fd.Variadic,
+		Generator:         fd.Generator,
+		Body:              fd.Body,
+		Closure:           ev.env,
 	}
 	ev.env.DefineFunction(fd.Name, fn)
 	return nil, nil
 }
 
+// evalFunctionLiteral turns an anonymous function expression into a function
+// value that captures the current scope.
+func (ev *Evaluator) evalFunctionLiteral(fl *ast.FunctionLiteral) (Value, error) {
+	return &FunctionValue{
+		Name:       anonymousFunctionName,
+		Parameters: fl.Parameters,
+		Body:       fl.Body,
+		Closure:    ev.env,
+	}, nil
+}
+
+// evalInterpolatedString evaluates each part of an interpolated text literal
+// and joins their textual forms.
+func (ev *Evaluator) evalInterpolatedString(is *ast.InterpolatedString) (Value, error) {
+	var sb strings.Builder
+	for _, part := range is.Parts {
+		val, err := ev.Eval(part)
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(ToString(val))
+	}
+	return sb.String(), nil
+}
+
 func (ev *Evaluator) evalCallStatement(cs *ast.CallStatement) (Value, error) {
 	if cs.MethodCall != nil {
 		_, err := ev.evalMethodCall(cs.MethodCall)
@@ -542,6 +842,17 @@ func (ev *Evaluator) evalOutput(os *ast.OutputStatement) (Value, error) {
 }
 
 func (ev *Evaluator) evalReturn(rs *ast.ReturnStatement) (Value, error) {
+	if rs.Values != nil {
+		values := make([]interface{}, len(rs.Values))
+		for i, expr := range rs.Values {
+			val, err := ev.Eval(expr)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = val
+		}
+		return &ReturnValue{Value: values}, nil
+	}
 	value, err := ev.Eval(rs.Value)
 	if err != nil {
 		return nil, err
@@ -594,6 +905,73 @@ func (ev *Evaluator) evalIfStatement(is *ast.IfStatement) (Value, error) {
 	return nil, nil
 }
 
+// evalWhenStatement evaluates the subject once and runs the body of the first
+// case with a matching pattern, or the Otherwise body when none match.
+func (ev *Evaluator) evalWhenStatement(ws *ast.WhenStatement) (Value, error) {
+	subject, err := ev.Eval(ws.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	body := ws.Otherwise
+	for _, wc := range ws.Cases {
+		matched, err := ev.matchesAnyPattern(subject, wc.Patterns)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			body = wc.Body
+			break
+		}
+	}
+	if body == nil {
+		return nil, nil
+	}
+
+	oldEnv := ev.env
+	ev.env = oldEnv.NewChild()
+	result, err := ev.evalStatements(body)
+	ev.env = oldEnv
+	return result, err
+}
+
+func (ev *Evaluator) matchesAnyPattern(subject Value, patterns []ast.WhenPattern) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := ev.matchesPattern(subject, pattern)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// matchesPattern reports whether subject matches a single When pattern.
+// Values compare with "is equal to", so the same type rules apply as in an If
+// condition. A range only matches numbers, but its bounds must be numbers.
+func (ev *Evaluator) matchesPattern(subject Value, pattern ast.WhenPattern) (bool, error) {
+	switch p := pattern.(type) {
+	case *ast.ValuePattern:
+		value, err := ev.Eval(p.Value)
+		if err != nil {
+			return false, err
+		}
+		return Compare("is equal to", subject, value)
+	case *ast.RangePattern:
+		low, err := ev.Eval(p.Low)
+		if err != nil {
+			return false, err
+		}
+		high, err := ev.Eval(p.High)
+		if err != nil {
+			return false, err
+		}
+		return inRange(subject, low, high)
+	case *ast.TypePattern:
+		return ev.env.IsOfType(subject, p.TypeName), nil
+	}
+	return false, fmt.Errorf("unknown pattern type: %T", pattern)
+}
+
 func (ev *Evaluator) evalWhileLoop(wl *ast.WhileLoop) (Value, error) {
 	var result Value
 	for {
@@ -621,10 +999,16 @@ func (ev *Evaluator) evalWhileLoop(wl *ast.WhileLoop) (Value, error) {
 		if _, ok := val.(*ReturnValue); ok {
 			return val, nil
 		}
-		if _, ok := val.(*BreakValue); ok {
+		if brk, ok := val.(*BreakValue); ok {
+			if !brk.stops(wl.Label) {
+				return val, nil // leaves a loop around this one
+			}
 			break
 		}
-		if _, ok := val.(*ContinueValue); ok {
+		if cont, ok := val.(*ContinueValue); ok {
+			if !cont.continues(wl.Label) {
+				return val, nil
+			}
 			continue
 		}
 		result = val
@@ -659,10 +1043,16 @@ func (ev *Evaluator) evalForLoop(fl *ast.ForLoop) (Value, error) {
 		if _, ok := val.(*ReturnValue); ok {
 			return val, nil
 		}
-		if _, ok := val.(*BreakValue); ok {
+		if brk, ok := val.(*BreakValue); ok {
+			if !brk.stops(fl.Label) {
+				return val, nil // leaves a loop around this one
+			}
 			break
 		}
-		if _, ok := val.(*ContinueValue); ok {
+		if cont, ok := val.(*ContinueValue); ok {
+			if !cont.continues(fl.Label) {
+				return val, nil
+			}
 			continue
 		}
 		result = val
@@ -676,104 +1066,56 @@ func (ev *Evaluator) evalForEachLoop(fel *ast.ForEachLoop) (Value, error) {
 		return nil, err
 	}
 
+	// Every iterable value, a generator included, hands out its items
+	// through the same protocol; a range computes each one as it is reached.
+	it, ok := types.Iterate(list)
+	if !ok {
+		return nil, fmt.Errorf("TypeError: 'for each' requires list, array, or lookup table; got %s",
+			typeKindName(inferTypeKind(list)))
+	}
+	// A generator made by calling a function right here is the loop's
+	// alone, so once the loop is over, however it ends, it is let go.
+	if g, ok := list.(*Generator); ok {
+		switch fel.List.(type) {
+		case *ast.FunctionCall, *ast.MethodCall:
+			defer g.Close()
+		}
+	}
+
 	oldEnv := ev.env
 	var result Value
-
-	switch col := list.(type) {
-	case []interface{}:
-		for _, item := range col {
-			childEnv := oldEnv.NewChild()
-			ev.env = childEnv
-			ev.env.Define(fel.Item, item, false)
-			val, err := ev.evalStatements(fel.Body)
-			ev.env = oldEnv
-			if err != nil {
-				return nil, err
-			}
-			if _, ok := val.(*ReturnValue); ok {
-				return val, nil
-			}
-			if _, ok := val.(*BreakValue); ok {
-				break
-			}
-			if _, ok := val.(*ContinueValue); ok {
-				continue
-			}
-			result = val
+	for {
+		item, more, err := it.Next()
+		if err != nil {
+			return nil, err
 		}
-	case *ArrayValue:
-		for _, item := range col.Elements {
-			childEnv := oldEnv.NewChild()
-			ev.env = childEnv
-			ev.env.Define(fel.Item, item, false)
-			val, err := ev.evalStatements(fel.Body)
-			ev.env = oldEnv
-			if err != nil {
-				return nil, err
-			}
-			if _, ok := val.(*ReturnValue); ok {
-				return val, nil
-			}
-			if _, ok := val.(*BreakValue); ok {
-				break
-			}
-			if _, ok := val.(*ContinueValue); ok {
-				continue
-			}
-			result = val
+		if !more {
+			break
 		}
-	case *LookupTableValue:
-		// Iterating over a lookup table yields the keys in insertion order
-		for _, serialKey := range col.KeyOrder {
-			origKey, _, ok := types.DeserializeKey(serialKey)
-			if !ok {
-				origKey = serialKey
-			}
-			childEnv := oldEnv.NewChild()
-			ev.env = childEnv
-			ev.env.Define(fel.Item, origKey, false)
-			val, err := ev.evalStatements(fel.Body)
-			ev.env = oldEnv
-			if err != nil {
-				return nil, err
-			}
-			if _, ok := val.(*ReturnValue); ok {
-				return val, nil
-			}
-			if _, ok := val.(*BreakValue); ok {
-				break
-			}
-			if _, ok := val.(*ContinueValue); ok {
-				continue
-			}
-			result = val
+		childEnv := oldEnv.NewChild()
+		ev.env = childEnv
+		ev.env.Define(fel.Item, item, false)
+		val, err := ev.evalStatements(fel.Body)
+		ev.env = oldEnv
+		if err != nil {
+			return nil, err
 		}
-	case *RangeValue:
-		// For ranges, we iterate using ToSlice() to materialize the values
-		// This ensures lazy evaluation is respected while still allowing iteration
-		for _, item := range col.ToSlice() {
-			childEnv := oldEnv.NewChild()
-			ev.env = childEnv
-			ev.env.Define(fel.Item, item, false)
-			val, err := ev.evalStatements(fel.Body)
-			ev.env = oldEnv
-			if err != nil {
-				return nil, err
+		if _, ok := val.(*ReturnValue); ok {
+			return val, nil
+		}
+		if brk, ok := val.(*BreakValue); ok {
+			if !brk.stops(fel.Label) {
+				return val, nil // leaves a loop around this one
 			}
-			if _, ok := val.(*ReturnValue); ok {
+			break
+		}
+		if cont, ok := val.(*ContinueValue); ok {
+			if !cont.continues(fel.Label) {
 				return val, nil
 			}
-			if _, ok := val.(*BreakValue); ok {
-				break
-			}
-			if _, ok := val.(*ContinueValue); ok {
-				continue
-			}
-			result = val
+			continue
 		}
-	default:
-		return nil, fmt.Errorf("TypeError: 'for each' requires list, array, or lookup table; got %s",
-			typeKindName(inferTypeKind(list)))
+		result = val
 	}
 
 	return result, nil
@@ -782,6 +1124,9 @@ func (ev *Evaluator) evalForEachLoop(fel *ast.ForEachLoop) (Value, error) {
 func (ev *Evaluator) evalStatements(stmts []ast.Statement) (Value, error) {
 	var result Value
 	for _, stmt := range stmts {
+		if ev.tasks != nil {
+			ev.tasks.Checkpoint()
+		}
 		val, err := ev.Eval(stmt)
 		if err != nil {
 			return nil, err
@@ -856,6 +1201,12 @@ func (ev *Evaluator) evalRangeLiteral(rl *ast.RangeLiteral) (Value, error) {
 func (ev *Evaluator) evalIdentifier(id *ast.Identifier) (Value, error) {
 	val, ok := ev.env.Get(id.Name)
 	if !ok {
+		// A declared function named as a value ("transform(numbers, double)")
+		// evaluates to the function itself. Built-ins have no body and stay
+		// call-only.
+		if fn, isFn := ev.env.GetFunction(id.Name); isFn && fn.Body != nil {
+			return fn, nil
+		}
 		suggestion := ev.findSimilarVariable(id.Name)
 		if suggestion != "" {
 			return nil, ev.runtimeError(fmt.Sprintf("undefined variable '%s'\n  Perhaps you meant: '%s'", id.Name, suggestion))
@@ -936,8 +1287,29 @@ func (ev *Evaluator) evalBinaryExpression(be *ast.BinaryExpression) (Value, erro
 	if err != nil {
 		return nil, err
 	}
+	return ev.applyBinary(be.Operator, left, right)
+}
 
-	switch be.Operator {
+// applyBinary applies a binary operator other than "and" and "or" to the
+// values of its two sides.
+func (ev *Evaluator) applyBinary(operator string, left, right Value) (Value, error) {
+	// Arithmetic on an integer struct field keeps its kind and is range
+	// checked; everything else is ordinary number arithmetic below.
+	if result, handled, err := types.IntegerArithmetic(operator, left, right, ev.wrapIntegers); handled {
+		if err != nil {
+			return nil, ev.catchable(err)
+		}
+		return result, nil
+	}
+	// Decimals and whole numbers are exact; see types.ExactArithmetic.
+	if result, handled, err := types.ExactArithmetic(operator, left, right); handled {
+		if err != nil {
+			return nil, ev.catchable(err)
+		}
+		return result, nil
+	}
+
+	switch operator {
 	case "+":
 		return Add(left, right)
 	case "-":
@@ -956,11 +1328,32 @@ func (ev *Evaluator) evalBinaryExpression(be *ast.BinaryExpression) (Value, erro
 			return nil, ev.runtimeError(err.Error())
 		}
 		return result, nil
+	case "**":
+		result, err := Power(left, right)
+		if err != nil {
+			return nil, ev.runtimeError(err.Error())
+		}
+		return result, nil
+	case "//":
+		result, err := FloorDivide(left, right)
+		if err != nil {
+			return nil, ev.runtimeError(err.Error())
+		}
+		return result, nil
+	case "&", "|", "^", "<<", ">>":
+		result, err := Bitwise(operator, left, right)
+		if _, ok := err.(*types.ErrorValue); ok {
+			return nil, ev.catchable(err)
+		}
+		if err != nil {
+			return nil, ev.runtimeError(err.Error())
+		}
+		return result, nil
 	case "is equal to", "is less than", "is greater than", "is less than or equal to", "is greater than or equal to", "is not equal to":
-		result, err := Compare(be.Operator, left, right)
+		result, err := Compare(operator, left, right)
 		return result, err
 	default:
-		return nil, fmt.Errorf("unknown operator: %s", be.Operator)
+		return nil, fmt.Errorf("unknown operator: %s", operator)
 	}
 }
 
@@ -972,6 +1365,15 @@ func (ev *Evaluator) evalUnaryExpression(ue *ast.UnaryExpression) (Value, error)
 
 	switch ue.Operator {
 	case "-":
+		if result, handled, err := types.IntegerNegate(right, ev.wrapIntegers); handled {
+			if err != nil {
+				return nil, ev.catchable(err)
+			}
+			return result, nil
+		}
+		if result, handled := types.ExactNegate(right); handled {
+			return result, nil
+		}
 		num, err := ToNumber(right)
 		if err != nil {
 			return nil, err
@@ -989,7 +1391,7 @@ func (ev *Evaluator) evalUnaryExpression(ue *ast.UnaryExpression) (Value, error)
 }
 
 func (ev *Evaluator) evalFunctionCall(fc *ast.FunctionCall) (Value, error) {
-	fn, ok := ev.env.GetFunction(fc.Name)
+	fn, ok := ev.env.GetCallable(fc.Name)
 	if !ok {
 		suggestion := ev.findSimilarFunction(fc.Name)
 		if suggestion != "" {
@@ -1011,11 +1413,20 @@ func (ev *Evaluator) evalFunctionCall(fc *ast.FunctionCall) (Value, error) {
 	// Check if it's a built-in function (stdlib)
 	if fn.Body == nil {
 		// This is a built-in function, delegate to stdlib
+		if len(fc.NamedArguments) > 0 {
+			return nil, ev.runtimeError(types.NoNamedArguments(fc.Name).Error())
+		}
 		return ev.evalBuiltinFunction(fc.Name, args)
 	}
 
-	// Check parameter count
-	if len(args) != len(fn.Parameters) {
+	// Check parameter count, or line the arguments up with the parameters
+	// when some are named, have defaults or collect the rest
+	if fn.Defaults != nil || fn.Variadic || len(fc.NamedArguments) > 0 {
+		var err error
+		if args, err = ev.bindArguments(fc.Name, fn, args, fc.NamedArguments); err != nil {
+			return nil, err
+		}
+	} else if len(args) != len(fn.Parameters) {
 		expected := "no arguments"
 		if len(fn.Parameters) == 1 {
 			expected = "1 argument"
@@ -1038,7 +1449,73 @@ func (ev *Evaluator) evalFunctionCall(fc *ast.FunctionCall) (Value, error) {
 		return nil, ev.runtimeError(fmt.Sprintf("function '%s' expects %s, got %s%s", fc.Name, expected, got, paramList))
 	}
 
-	// Create new environment for function execution
+	return ev.runFunctionBody(fn, args, fmt.Sprintf("%s(%s)", fc.Name, strings.Join(fn.Parameters, ", ")))
+}
+
+// bindArguments lines a call's arguments up with fn's parameters when the
+// call names some of them or fn has defaults or a rest parameter. Named
+// argument values are evaluated in the caller's scope, in the order written;
+// a parameter the call leaves out gets its default, evaluated afresh in the
+// scope fn was defined in, so "(defaulting to [])" gives every call its own
+// list. A default can use the parameters before it.
+func (ev *Evaluator) bindArguments(name string, fn *FunctionValue, args []Value, named []*ast.NamedArgument) ([]Value, error) {
+	names := make([]string, len(named))
+	values := make([]Value, len(named))
+	for i, na := range named {
+		val, err := ev.Eval(na.Value)
+		if err != nil {
+			return nil, err
+		}
+		names[i], values[i] = na.Name, val
+	}
+
+	hasDefault := func(i int) bool { return i < len(fn.Defaults) && fn.Defaults[i] != nil }
+	slots, given, err := types.BindArguments(name, fn.Parameters, fn.Variadic, hasDefault, args, names, values)
+	if err != nil {
+		return nil, ev.runtimeError(err.Error())
+	}
+	scope := fn.Closure.NewChild()
+	for i := range slots {
+		if !given[i] {
+			oldEnv := ev.env
+			ev.env = scope
+			slots[i], err = ev.Eval(fn.Defaults[i])
+			ev.env = oldEnv
+			if err != nil {
+				return nil, err
+			}
+		}
+		scope.Define(fn.Parameters[i], slots[i], false)
+	}
+	return slots, nil
+}
+
+// runFunctionBody executes a user-defined function whose arity has already
+// been checked. The body runs in a fresh child of fn.Closure — the scope the
+// function was defined in — so nested functions keep access to the variables
+// of the call that created them, even after that call has returned.
+func (ev *Evaluator) runFunctionBody(fn *FunctionValue, args []Value, frame string) (Value, error) {
+	if err := ev.checkParamCapabilities(fn, args); err != nil {
+		return nil, err
+	}
+	if err := ev.checkParamTypes(fn, args); err != nil {
+		return nil, err
+	}
+	if fn.Generator {
+		return ev.newGenerator(fn, args, frame), nil
+	}
+	result, err := ev.execFunctionBody(fn, args, frame)
+	if err != nil {
+		return nil, err
+	}
+	if err := ev.checkReturnType(fn, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// execFunctionBody runs fn's body with its parameters bound to args.
+func (ev *Evaluator) execFunctionBody(fn *FunctionValue, args []Value, frame string) (Value, error) {
 	funcEnv := fn.Closure.NewChild()
 
 	// Bind parameters
@@ -1052,9 +1529,9 @@ func (ev *Evaluator) evalFunctionCall(fc *ast.FunctionCall) (Value, error) {
 
 	// Add to call stack, recording the call-site line
 	callSiteLine := ev.currentLine
-	frameLabel := fmt.Sprintf("%s(%s)", fc.Name, strings.Join(fn.Parameters, ", "))
+	frameLabel := frame
 	if callSiteLine > 0 {
-		frameLabel = fmt.Sprintf("%s(%s) (line %d)", fc.Name, strings.Join(fn.Parameters, ", "), callSiteLine)
+		frameLabel = fmt.Sprintf("%s (line %d)", frame, callSiteLine)
 	}
 	ev.callStack = append(ev.callStack, frameLabel)
 
@@ -1080,10 +1557,11 @@ func (ev *Evaluator) evalFunctionCall(fc *ast.FunctionCall) (Value, error) {
 	return nil, nil
 }
 
-// callFunction invokes a named function with pre-evaluated argument values.
+// callFunction invokes a named function with pre-evaluated argument values
+// and any named arguments, which are evaluated when the call binds them.
 // This is used by evalMethodCall for the stdlib-fallback path.
-func (ev *Evaluator) callFunction(name string, args []Value) (Value, error) {
-	fn, ok := ev.env.GetFunction(name)
+func (ev *Evaluator) callFunction(name string, args []Value, named []*ast.NamedArgument) (Value, error) {
+	fn, ok := ev.env.GetCallable(name)
 	if !ok {
 		suggestion := ev.findSimilarFunction(name)
 		if suggestion != "" {
@@ -1092,48 +1570,30 @@ func (ev *Evaluator) callFunction(name string, args []Value) (Value, error) {
 		return nil, ev.runtimeError(fmt.Sprintf("undefined function '%s'", name))
 	}
 
+	return ev.callFunctionValue(name, fn, args, named)
+}
+
+// callFunctionValue invokes fn, already resolved, under the given name.
+func (ev *Evaluator) callFunctionValue(name string, fn *FunctionValue, args []Value, named []*ast.NamedArgument) (Value, error) {
 	// Built-in (stdlib) path
 	if fn.Body == nil {
+		if len(named) > 0 {
+			return nil, ev.runtimeError(types.NoNamedArguments(name).Error())
+		}
 		return ev.evalBuiltinFunction(name, args)
 	}
 
 	// User-defined function path
-	if len(args) != len(fn.Parameters) {
-		return nil, ev.runtimeError(fmt.Sprintf("function '%s' expects %d argument(s), got %d", name, len(fn.Parameters), len(args)))
-	}
-
-	funcEnv := fn.Closure.NewChild()
-	for i, param := range fn.Parameters {
-		funcEnv.Define(param, args[i], false)
-	}
-
-	oldEnv := ev.env
-	ev.env = funcEnv
-	callSiteLine := ev.currentLine
-	frameLabel := fmt.Sprintf("%s(...)", name)
-	if callSiteLine > 0 {
-		frameLabel = fmt.Sprintf("%s(...) (line %d)", name, callSiteLine)
-	}
-	ev.callStack = append(ev.callStack, frameLabel)
-	defer func() {
-		ev.env = oldEnv
-		ev.callStack = ev.callStack[:len(ev.callStack)-1]
-		ev.currentLine = callSiteLine
-	}()
-
-	for _, stmt := range fn.Body {
-		if line := getStatementLine(stmt); line > 0 {
-			ev.currentLine = line
-		}
-		val, err := ev.Eval(stmt)
-		if err != nil {
+	if fn.Defaults != nil || fn.Variadic || len(named) > 0 {
+		var err error
+		if args, err = ev.bindArguments(name, fn, args, named); err != nil {
 			return nil, err
 		}
-		if retVal, ok := val.(*ReturnValue); ok {
-			return retVal.Value, nil
-		}
+	} else if len(args) != len(fn.Parameters) {
+		return nil, ev.runtimeError(fmt.Sprintf("function '%s' expects %d argument(s), got %d", name, len(fn.Parameters), len(args)))
 	}
-	return nil, nil
+
+	return ev.runFunctionBody(fn, args, fmt.Sprintf("%s(...)", name))
 }
 
 func (ev *Evaluator) findSimilarFunction(name string) string {
@@ -1241,6 +1701,14 @@ func (ev *Evaluator) evalLookupKeyAssignment(la *ast.LookupKeyAssignment) (Value
 		return nil, err
 	}
 
+	if typeName, ok := ev.env.GetElementType(la.TableName); ok {
+		if err := types.CheckKey(la.TableName, typeName, keyVal, ev.env); err != nil {
+			return nil, ev.catchable(err)
+		}
+		if err := types.CheckElement(la.TableName, typeName, value, ev.env); err != nil {
+			return nil, ev.catchable(err)
+		}
+	}
 	lt.Set(serialKey, value)
 	return nil, nil
 }
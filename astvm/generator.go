@@ -0,0 +1,120 @@
+package vm
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/Advik-B/english/ast"
+)
+
+// Generator is the value of calling a function that gives back values
+// ("Give back x."). Its body runs on a goroutine of its own, but only ever
+// while Next waits for it: Next resumes the body and blocks until it gives
+// back the next value or finishes, so the body and the loop consuming it
+// never run at the same time and share the environment safely.
+type Generator struct {
+	Name    string
+	start   func()
+	resume  chan struct{}
+	results chan generatorStep
+	started bool
+	done    bool
+}
+
+// generatorStep is what the body hands back each time it stops: a value,
+// or done with the error that ended it, if any.
+type generatorStep struct {
+	value Value
+	err   error
+	done  bool
+}
+
+func (g *Generator) String() string {
+	return fmt.Sprintf("<generator %s>", g.Name)
+}
+
+// Next runs the body until it gives back its next value. Once the body has
+// finished, Next keeps reporting that there are no more.
+func (g *Generator) Next() (interface{}, bool, error) {
+	if g.done {
+		return nil, false, nil
+	}
+	if !g.started {
+		g.started = true
+		go g.start()
+	} else {
+		g.resume <- struct{}{}
+	}
+	step := <-g.results
+	if step.done {
+		g.done = true
+		return nil, false, step.err
+	}
+	return step.value, true, nil
+}
+
+// Close lets go of a generator nothing will ask for more values: a body
+// waiting to be resumed ends, and so does its goroutine. A closed generator
+// has no more values.
+func (g *Generator) Close() {
+	if g.started && !g.done {
+		close(g.resume)
+	}
+	g.done = true
+}
+
+// newGenerator makes the generator for a call to fn with args. The body
+// does not start until the first value is asked for, and it runs on an
+// evaluator of its own whose yield passes each value back to Next.
+//
+// The body holds on to the channels but not to the generator, nor to the
+// scope of the caller that may, so once the program has no way left to reach
+// the generator its finalizer closes it, ending a body left waiting.
+func (ev *Evaluator) newGenerator(fn *FunctionValue, args []Value, frame string) *Generator {
+	resume := make(chan struct{})
+	results := make(chan generatorStep)
+	g := &Generator{
+		Name:    fn.Name,
+		resume:  resume,
+		results: results,
+	}
+	body := &Evaluator{
+		env:          fn.Closure,
+		callStack:    append([]string(nil), ev.callStack...),
+		builtinFn:    ev.builtinFn,
+		currentLine:  ev.currentLine,
+		out:          ev.out,
+		wrapIntegers: ev.wrapIntegers,
+		noAssertions: ev.noAssertions,
+		modules:      ev.modules,
+		file:         ev.file,
+		tasks:        ev.tasks,
+		task:         ev.task,
+	}
+	body.yield = func(v Value) {
+		results <- generatorStep{value: v}
+		if _, open := <-resume; !open {
+			runtime.Goexit() // closed; see Close
+		}
+	}
+	g.start = func() {
+		_, err := body.execFunctionBody(fn, args, frame)
+		results <- generatorStep{done: true, err: err}
+	}
+	runtime.SetFinalizer(g, (*Generator).Close)
+	return g
+}
+
+// evalYield hands the value of "Give back x." to the loop consuming the
+// generator and waits until it wants the next one.
+func (ev *Evaluator) evalYield(ys *ast.YieldStatement) (Value, error) {
+	if ev.yield == nil {
+		return nil, ev.runtimeError("'Give back' can only be used in a function that gives back values")
+	}
+	value, err := ev.Eval(ys.Value)
+	if err != nil {
+		return nil, err
+	}
+	ev.yield(value)
+	return nil, nil
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/Advik-B/english/astvm/types"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 )
 
 // errDivisionByZero and errRemainderByZero are sentinel errors returned by
@@ -22,6 +24,13 @@ var (
 //   - array  + array    → array  (concatenation, same element type required)
 //   - any other combination is a TypeError
 func Add(left, right Value) (Value, error) {
+	if types.IsNumeric(inferTypeKind(left)) && types.IsNumeric(inferTypeKind(right)) {
+		// Any mix of number kinds; integer-only sums never get here because
+		// types.IntegerArithmetic handles them first.
+		l, _ := requireNumber(left, "+")
+		r, _ := requireNumber(right, "+")
+		return l + r, nil
+	}
 	switch l := left.(type) {
 	case float64:
 		r, ok := right.(float64)
@@ -116,6 +125,91 @@ func Modulo(left, right Value) (Value, error) {
 	return float64(int64(l) % int64(r)), nil
 }
 
+// Power raises left to the power of right.
+func Power(left, right Value) (Value, error) {
+	l, err := requireNumber(left, "to the power of")
+	if err != nil {
+		return nil, err
+	}
+	r, err := requireNumber(right, "to the power of")
+	if err != nil {
+		return nil, err
+	}
+	return math.Pow(l, r), nil
+}
+
+// FloorDivide divides two numbers and rounds the result down.  Division by
+// zero is an error.
+func FloorDivide(left, right Value) (Value, error) {
+	l, err := requireNumber(left, "divided evenly by")
+	if err != nil {
+		return nil, err
+	}
+	r, err := requireNumber(right, "divided evenly by")
+	if err != nil {
+		return nil, err
+	}
+	if r == 0 {
+		return nil, errDivisionByZero
+	}
+	return math.Floor(l / r), nil
+}
+
+// Bitwise applies one of the bitwise operators &, |, ^, << or >> to two
+// whole numbers.
+func Bitwise(op string, left, right Value) (Value, error) {
+	name := bitwiseOpName(op)
+	l, err := requireWholeNumber(left, name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := requireWholeNumber(right, name)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "&":
+		return float64(l & r), nil
+	case "|":
+		return float64(l | r), nil
+	case "^":
+		return float64(l ^ r), nil
+	case "<<", ">>":
+		if r < 0 {
+			return nil, fmt.Errorf("ValueError: cannot shift by a negative amount (%d)", r)
+		}
+		if op == "<<" {
+			if r >= 63 || (l<<uint64(r))>>uint64(r) != l {
+				return nil, &types.ErrorValue{
+					ErrorType: "OverflowError",
+					Message:   fmt.Sprintf("%d shifted left by %d is too large for a number; shift a whole number instead", l, r),
+				}
+			}
+			return float64(l << uint64(r)), nil
+		}
+		return float64(l >> uint64(r)), nil
+	}
+	return nil, fmt.Errorf("unknown operator: %s", op)
+}
+
+// bitwiseOpName returns the English phrase for a bitwise operator, for use in
+// error messages.
+func bitwiseOpName(op string) string {
+	switch op {
+	case "&":
+		return "bitwise and"
+	case "|":
+		return "bitwise or"
+	case "^":
+		return "bitwise xor"
+	case "<<":
+		return "shifted left by"
+	case ">>":
+		return "shifted right by"
+	}
+	return op
+}
+
 // ─── Comparison ───────────────────────────────────────────────────────────────
 
 // Compare evaluates a comparison expression and returns a boolean.
@@ -142,6 +236,24 @@ func Compare(op string, left, right Value) (bool, error) {
 	}
 }
 
+// inRange reports whether v lies between low and high inclusive, as in the
+// pattern "is between 1 and 10". A v that is not a number is never in range.
+func inRange(v, low, high Value) (bool, error) {
+	lo, err := requireNumber(low, "between")
+	if err != nil {
+		return false, err
+	}
+	hi, err := requireNumber(high, "between")
+	if err != nil {
+		return false, err
+	}
+	n, err := requireNumber(v, "between")
+	if err != nil {
+		return false, nil
+	}
+	return n >= lo && n <= hi, nil
+}
+
 // strictEquals checks equality without any implicit conversion.
 // Two values are equal only if they are the same type AND the same value.
 // Exception: nil == nil is always true (nothing == nothing).
@@ -152,6 +264,9 @@ func strictEquals(left, right Value) (bool, error) {
 	if left == nil || right == nil {
 		return false, nil // nothing ≠ any concrete value
 	}
+	if eq, ok := types.NumbersEqual(left, right); ok {
+		return eq, nil // numbers compare by value across kinds
+	}
 	lk := types.Canonical(inferTypeKind(left))
 	rk := types.Canonical(inferTypeKind(right))
 	if lk != rk {
@@ -168,6 +283,9 @@ func Equals(left, right Value) bool {
 	if left == nil || right == nil {
 		return false
 	}
+	if eq, ok := types.NumbersEqual(left, right); ok {
+		return eq
+	}
 	switch l := left.(type) {
 	case float64:
 		if r, ok := right.(float64); ok {
@@ -181,12 +299,19 @@ func Equals(left, right Value) bool {
 		if r, ok := right.(bool); ok {
 			return l == r
 		}
+	case *types.EnumValue:
+		if r, ok := right.(*types.EnumValue); ok {
+			return l.Equal(r)
+		}
 	}
 	return false
 }
 
 // strictOrderCompare applies an ordering predicate to two numbers.
 func strictOrderCompare(left, right Value, pred func(float64, float64) bool) (bool, error) {
+	if cmp, ok := types.CompareNumbers(left, right); ok {
+		return pred(float64(cmp), 0), nil
+	}
 	l, err := requireNumber(left, "comparison")
 	if err != nil {
 		return false, err
@@ -215,6 +340,11 @@ func requireNumber(v Value, op string) (float64, error) {
 		return float64(val), nil
 	case float32:
 		return float64(val), nil
+	case *types.Decimal:
+		return val.Float64(), nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(val).Float64()
+		return f, nil
 	default:
 		return 0, fmt.Errorf(
 			"TypeError: '%s' requires number, got %s",
@@ -223,6 +353,19 @@ func requireNumber(v Value, op string) (float64, error) {
 	}
 }
 
+// requireWholeNumber unwraps a numeric Value with no fractional part to int64
+// or returns a TypeError.
+func requireWholeNumber(v Value, op string) (int64, error) {
+	n, err := requireNumber(v, op)
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("TypeError: '%s' requires whole numbers, got %v", op, n)
+	}
+	return int64(n), nil
+}
+
 // typeMismatchError builds a type mismatch error (kept for compatibility).
 func typeMismatchError(left, right Value, op string) error {
 	return fmt.Errorf(
@@ -4,6 +4,7 @@ import (
 	"github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/astvm/types"
 	"fmt"
+	"math/big"
 )
 
 // evalStructDecl evaluates a struct declaration
@@ -11,6 +12,24 @@ func (ev *Evaluator) evalStructDecl(node *ast.StructDecl) (Value, error) {
 	// Create field definitions
 	fields := make(map[string]*FieldDefinition)
 	fieldOrder := make([]string, 0, len(node.Fields))
+	methods := make(map[string]*FunctionValue)
+
+	// A structure declared "a kind of" another starts with a copy of the
+	// parent's fields and methods; its own declarations add to or replace them.
+	var parent *StructDefinition
+	if node.Parent != "" {
+		var ok bool
+		if parent, ok = ev.env.GetStruct(node.Parent); !ok {
+			return nil, ev.runtimeError(fmt.Sprintf("undefined struct type '%s'", node.Parent))
+		}
+		for _, name := range parent.FieldOrder {
+			fields[name] = parent.Fields[name]
+			fieldOrder = append(fieldOrder, name)
+		}
+		for name, method := range parent.Methods {
+			methods[name] = method
+		}
+	}
 
 	for _, field := range node.Fields {
 		// Parse type name
@@ -27,6 +46,11 @@ func (ev *Evaluator) evalStructDecl(node *ast.StructDecl) (Value, error) {
 			if err != nil {
 				return nil, err
 			}
+			if types.IsInteger(typeKind) {
+				if val, err = types.CheckIntegerField(field.Name, val, typeKind); err != nil {
+					return nil, ev.catchable(err)
+				}
+			}
 			defaultValue = val
 		} else {
 			// Set default values based on type
@@ -43,6 +67,10 @@ func (ev *Evaluator) evalStructDecl(node *ast.StructDecl) (Value, error) {
 				defaultValue = float32(0.0)
 			case types.TypeF64:
 				defaultValue = float64(0.0)
+			case types.TypeDecimal:
+				defaultValue = types.DecimalFromInt(big.NewInt(0))
+			case types.TypeWhole:
+				defaultValue = big.NewInt(0)
 			case types.TypeString:
 				defaultValue = ""
 			case types.TypeBool:
@@ -54,23 +82,46 @@ func (ev *Evaluator) evalStructDecl(node *ast.StructDecl) (Value, error) {
 			}
 		}
 
+		if _, inherited := fields[field.Name]; !inherited {
+			fieldOrder = append(fieldOrder, field.Name)
+		}
 		fields[field.Name] = &FieldDefinition{
 			Name:         field.Name,
 			TypeInfo:     typeInfo,
 			DefaultValue: defaultValue,
 		}
-		fieldOrder = append(fieldOrder, field.Name)
 	}
 
 	// Create method definitions
-	methods := make(map[string]*FunctionValue)
 	for _, method := range node.Methods {
 		// Create function value for the method
 		methods[method.Name] = &FunctionValue{
-			Name:       method.Name,
-			Parameters: method.Parameters,
-			Body:       method.Body,
-			Closure:    ev.env, // Methods capture the struct definition environment
+			Name:              method.Name,
+			Parameters:        method.Parameters,
+			ParamCapabilities: method.ParamCapabilities,
+			ParamTypes:        method.ParamTypes,
+			ReturnType:        method.ReturnType,
+			Defaults:          method.Defaults,
+			Variadic:          method.Variadic,
+			Body:              method.Body,
+			Closure:           ev.env, // Methods capture the struct definition environment
+		}
+	}
+
+	// A structure that says it "can" something must have every function the
+	// capability requires, whether declared here or inherited.
+	for _, name := range node.Capabilities {
+		val, _ := ev.env.Get(name)
+		capability, ok := val.(*types.Capability)
+		if !ok {
+			return nil, ev.runtimeError(fmt.Sprintf("undefined capability '%s'", name))
+		}
+		missing := capability.Missing(func(m string) bool {
+			_, ok := methods[m]
+			return ok
+		})
+		if missing != "" {
+			return nil, ev.runtimeError(fmt.Sprintf("%s says it can %s, but it has no function '%s'", node.Name, name, missing))
 		}
 	}
 
@@ -79,7 +130,9 @@ func (ev *Evaluator) evalStructDecl(node *ast.StructDecl) (Value, error) {
 		Name:       node.Name,
 		Fields:     fields,
 		Methods:    methods,
-		FieldOrder: fieldOrder,
+		FieldOrder:   fieldOrder,
+		Parent:       parent,
+		Capabilities: node.Capabilities,
 	}
 
 	// Register struct in environment
@@ -88,6 +141,61 @@ func (ev *Evaluator) evalStructDecl(node *ast.StructDecl) (Value, error) {
 	return nil, nil
 }
 
+// checkParamCapabilities raises a catchable TypeError when an argument passed
+// for a parameter declared "as anything that can X" is not an instance of a
+// structure that declared X.
+func (ev *Evaluator) checkParamCapabilities(fn *FunctionValue, args []Value) error {
+	for i, capability := range fn.ParamCapabilities {
+		if capability == "" || i >= len(args) {
+			continue
+		}
+		got := types.Name(types.Infer(args[i]))
+		if inst, ok := args[i].(*StructInstance); ok {
+			if inst.Definition.Can(capability) {
+				continue
+			}
+			got = inst.Definition.Name
+		}
+		return ev.catchable(types.CapabilityMismatch(fn.Name, fn.Parameters[i], capability, got))
+	}
+	return nil
+}
+
+// checkParamTypes raises a catchable TypeError when an argument is not of the
+// type its parameter was declared as ("takes width as number").
+func (ev *Evaluator) checkParamTypes(fn *FunctionValue, args []Value) error {
+	for i, typeName := range fn.ParamTypes {
+		if typeName == "" || i >= len(args) || ev.env.IsOfType(args[i], typeName) {
+			continue
+		}
+		return ev.catchable(types.ArgumentTypeMismatch(fn.Name, fn.Parameters[i], typeName, signatureTypeName(args[i])))
+	}
+	return nil
+}
+
+// checkReturnType raises a catchable TypeError when a function that says what
+// it returns ("and returns a number") gives back something else.
+func (ev *Evaluator) checkReturnType(fn *FunctionValue, result Value) error {
+	if fn.ReturnType == "" || ev.env.IsOfType(result, fn.ReturnType) {
+		return nil
+	}
+	return ev.catchable(types.ReturnTypeMismatch(fn.Name, fn.ReturnType, signatureTypeName(result)))
+}
+
+// signatureTypeName describes a value's type for a signature TypeError:
+// instances and enum members by the name of their structure or enum.
+func signatureTypeName(v Value) string {
+	switch val := v.(type) {
+	case *StructInstance:
+		return val.Definition.Name
+	case *types.EnumValue:
+		return val.Type.Name
+	case *types.ErrorValue:
+		return val.ErrorType
+	}
+	return types.Name(inferTypeKind(v))
+}
+
 // evalStructInstantiation evaluates creating a new struct instance
 func (ev *Evaluator) evalStructInstantiation(node *ast.StructInstantiation) (Value, error) {
 	// Get struct definition
@@ -111,10 +219,14 @@ func (ev *Evaluator) evalStructInstantiation(node *ast.StructInstantiation) (Val
 		}
 
 		// Check if field exists
-		if _, ok := structDef.Fields[fieldName]; !ok {
+		fieldDef, ok := structDef.Fields[fieldName]
+		if !ok {
 			return nil, ev.runtimeError(fmt.Sprintf("struct '%s' has no field '%s'", node.StructName, fieldName))
 		}
 
+		if val, err = ev.checkField(fieldDef, val); err != nil {
+			return nil, err
+		}
 		fields[fieldName] = val
 	}
 
@@ -132,6 +244,20 @@ func (ev *Evaluator) evalFieldAccess(node *ast.FieldAccess) (Value, error) {
 		return nil, err
 	}
 
+	// "the pi of geo" reads a member of a module.
+	if mod, ok := obj.(*types.Module); ok {
+		return ev.evalModuleMember(mod, node.Field, nil, nil)
+	}
+
+	// "the red of Color" reads a member of an enum.
+	if et, ok := obj.(*types.EnumType); ok {
+		member, err := et.Get(node.Field)
+		if err != nil {
+			return nil, ev.runtimeError(err.Error())
+		}
+		return member, nil
+	}
+
 	// Check if it's a struct instance
 	structInst, ok := obj.(*StructInstance)
 	if !ok {
@@ -171,12 +297,43 @@ func (ev *Evaluator) evalFieldAssignment(node *ast.FieldAssignment) (Value, erro
 		return nil, err
 	}
 
+	if fieldDef, ok := structInst.Definition.Fields[node.Field]; ok {
+		if value, err = ev.checkField(fieldDef, value); err != nil {
+			return nil, err
+		}
+	}
+
 	// Assign to field
 	structInst.Fields[node.Field] = value
 
 	return nil, nil
 }
 
+// evalModuleMember reads the member called name from mod, or calls it with
+// the given arguments when it is a function.
+func (ev *Evaluator) evalModuleMember(mod *types.Module, name string, argExprs []ast.Expression, named []*ast.NamedArgument) (Value, error) {
+	member, err := mod.Get(name)
+	if err != nil {
+		return nil, ev.runtimeError(err.Error())
+	}
+	fn, isFunc := member.(*FunctionValue)
+	if !isFunc {
+		if len(argExprs) > 0 || len(named) > 0 {
+			return nil, ev.runtimeError(fmt.Sprintf("'%s' in module '%s' is not a function", name, mod.Name))
+		}
+		return member, nil
+	}
+	args := make([]Value, len(argExprs))
+	for i, arg := range argExprs {
+		v, err := ev.Eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return ev.callFunctionValue(name, fn, args, named)
+}
+
 // evalMethodCall evaluates calling a method on an object
 func (ev *Evaluator) evalMethodCall(node *ast.MethodCall) (Value, error) {
 	// Evaluate the object
@@ -185,6 +342,20 @@ func (ev *Evaluator) evalMethodCall(node *ast.MethodCall) (Value, error) {
 		return nil, err
 	}
 
+	// "geo's area with 3" calls a member of a module; "geo's pi" reads one.
+	if mod, ok := obj.(*types.Module); ok {
+		return ev.evalModuleMember(mod, node.MethodName, node.Arguments, node.NamedArguments)
+	}
+
+	// "Color's red" reads a member of an enum.
+	if et, ok := obj.(*types.EnumType); ok && len(node.Arguments) == 0 {
+		member, err := et.Get(node.MethodName)
+		if err != nil {
+			return nil, ev.runtimeError(err.Error())
+		}
+		return member, nil
+	}
+
 	// Check if it's a struct instance
 	structInst, ok := obj.(*StructInstance)
 	if !ok {
@@ -199,12 +370,16 @@ func (ev *Evaluator) evalMethodCall(node *ast.MethodCall) (Value, error) {
 			}
 			extraArgs[i] = v
 		}
-		return ev.callFunction(node.MethodName, append([]Value{obj}, extraArgs...))
+		return ev.callFunction(node.MethodName, append([]Value{obj}, extraArgs...), node.NamedArguments)
 	}
 
 	// Get method from struct definition
 	method, ok := structInst.Definition.Methods[node.MethodName]
 	if !ok {
+		// "p's x" with no method named x reads the field x.
+		if value, isField := structInst.Fields[node.MethodName]; isField && len(node.Arguments) == 0 {
+			return value, nil
+		}
 		return nil, ev.runtimeError(fmt.Sprintf("struct '%s' has no method '%s'", structInst.Definition.Name, node.MethodName))
 	}
 
@@ -218,11 +393,23 @@ func (ev *Evaluator) evalMethodCall(node *ast.MethodCall) (Value, error) {
 		args[i] = val
 	}
 
-	// Check parameter count
-	if len(args) != len(method.Parameters) {
+	// Check parameter count, or line the arguments up with the parameters
+	// when some are named, have defaults or collect the rest
+	if method.Defaults != nil || method.Variadic || len(node.NamedArguments) > 0 {
+		if args, err = ev.bindArguments(node.MethodName, method, args, node.NamedArguments); err != nil {
+			return nil, err
+		}
+	} else if len(args) != len(method.Parameters) {
 		return nil, ev.runtimeError(fmt.Sprintf("method '%s' expects %d arguments, got %d", node.MethodName, len(method.Parameters), len(args)))
 	}
 
+	if err := ev.checkParamCapabilities(method, args); err != nil {
+		return nil, err
+	}
+	if err := ev.checkParamTypes(method, args); err != nil {
+		return nil, err
+	}
+
 	// Create new environment for method execution
 	// The method has access to struct fields as well as parameters
 	methodEnv := method.Closure.NewChild()
@@ -266,11 +453,32 @@ func (ev *Evaluator) evalMethodCall(node *ast.MethodCall) (Value, error) {
 	ev.callStack = ev.callStack[:len(ev.callStack)-1]
 
 	// Update struct fields from method environment (in case method modified them)
-	for fieldName := range structInst.Fields {
+	for _, fieldName := range structInst.Definition.FieldOrder {
 		if val, ok := methodEnv.Get(fieldName); ok {
+			val, err := ev.checkField(structInst.Definition.Fields[fieldName], val)
+			if err != nil {
+				return nil, err
+			}
 			structInst.Fields[fieldName] = val
 		}
 	}
 
+	if err := ev.checkReturnType(method, result); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
+
+// checkField checks a value being stored in a struct field against the
+// field's declared type. Integer fields are the only ones enforced at runtime:
+// the value must be a whole number within the field's range.
+func (ev *Evaluator) checkField(def *FieldDefinition, v Value) (Value, error) {
+	if !types.IsInteger(def.TypeInfo.Kind) {
+		return v, nil
+	}
+	val, err := types.CheckIntegerField(def.Name, v, def.TypeInfo.Kind)
+	if err != nil {
+		return nil, ev.catchable(err)
+	}
+	return val, nil
+}
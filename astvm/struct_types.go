@@ -9,6 +9,35 @@ type StructDefinition struct {
 	Fields     map[string]*FieldDefinition
 	Methods    map[string]*FunctionValue
 	FieldOrder []string // preserves declaration order
+	// Parent is the structure this one was declared "a kind of", or nil.
+	// Its fields and methods have already been copied into this definition.
+	Parent *StructDefinition
+	// Capabilities are the capabilities this structure declared with
+	// "that can"; those of its ancestors are found through Parent.
+	Capabilities []string
+}
+
+// IsA reports whether sd is the structure called name or inherits from it.
+func (sd *StructDefinition) IsA(name string) bool {
+	for d := sd; d != nil; d = d.Parent {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether sd, or a structure it inherits from, declared the
+// capability called name.
+func (sd *StructDefinition) Can(name string) bool {
+	for d := sd; d != nil; d = d.Parent {
+		for _, c := range d.Capabilities {
+			if c == name {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // FieldDefinition describes a single field in a struct.
@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/astvm/types"
+)
+
+// scheduler returns the scheduler running the program's background tasks,
+// making it on first use with this evaluator as the main program.
+func (ev *Evaluator) scheduler() *types.Scheduler {
+	if ev.tasks == nil {
+		ev.tasks, ev.task = types.NewScheduler()
+	}
+	return ev.tasks
+}
+
+// evalBackground starts the body of "Do the following in the background and
+// call it job:" as a task on an evaluator of its own, which shares the
+// enclosing scope, and stores the task in the named variable.
+func (ev *Evaluator) evalBackground(bs *ast.BackgroundStatement) (Value, error) {
+	sched := ev.scheduler()
+	body := &Evaluator{
+		env:          ev.env.NewChild(),
+		callStack:    append(append([]string(nil), ev.callStack...), bs.Name),
+		builtinFn:    ev.builtinFn,
+		currentLine:  ev.currentLine,
+		out:          ev.out,
+		wrapIntegers: ev.wrapIntegers,
+		noAssertions: ev.noAssertions,
+		modules:      ev.modules,
+		file:         ev.file,
+		tasks:        sched,
+	}
+	task := sched.Start(bs.Name, func(t *types.Task) error {
+		body.task = t
+		_, err := body.evalStatements(bs.Body)
+		return err
+	})
+	return nil, ev.env.Set(bs.Name, task)
+}
+
+// evalWait waits for the task named in "Wait for job." and raises the error
+// that ended it, if any.
+func (ev *Evaluator) evalWait(ws *ast.WaitStatement) (Value, error) {
+	value, err := ev.Eval(ws.Task)
+	if err != nil {
+		return nil, err
+	}
+	task, ok := value.(*types.Task)
+	if !ok {
+		return nil, ev.runtimeError(fmt.Sprintf("TypeError: 'Wait for' needs a task started in the background; got %s", GetType(value).Name))
+	}
+	return nil, ev.scheduler().Wait(ev.task, task)
+}
+
+func (ev *Evaluator) evalSend(ss *ast.SendStatement) (Value, error) {
+	value, err := ev.Eval(ss.Value)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := ev.evalChannel(ss.Channel, "Send")
+	if err != nil {
+		return nil, err
+	}
+	return nil, ev.scheduler().Send(ev.task, ch, value)
+}
+
+func (ev *Evaluator) evalReceive(re *ast.ReceiveExpression) (Value, error) {
+	ch, err := ev.evalChannel(re.Channel, "Receive")
+	if err != nil {
+		return nil, err
+	}
+	return ev.scheduler().Receive(ev.task, ch)
+}
+
+// evalChannel evaluates the channel a Send or Receive uses.
+func (ev *Evaluator) evalChannel(expr ast.Expression, verb string) (*types.Channel, error) {
+	value, err := ev.Eval(expr)
+	if err != nil {
+		return nil, err
+	}
+	ch, ok := value.(*types.Channel)
+	if !ok {
+		return nil, ev.runtimeError(fmt.Sprintf("TypeError: '%s' needs a channel; got %s", verb, GetType(value).Name))
+	}
+	return ch, nil
+}
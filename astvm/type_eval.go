@@ -134,6 +134,60 @@ func (ev *Evaluator) evalRaiseStatement(node *ast.RaiseStatement) (Value, error)
 	}
 }
 
+// evalAssertStatement evaluates "Make sure that <condition>, otherwise say
+// <message>.". A comparison's two sides are worked out first, so the
+// AssertionError can show what each one was.
+func (ev *Evaluator) evalAssertStatement(node *ast.AssertStatement) (Value, error) {
+	if ev.noAssertions {
+		return nil, nil
+	}
+	check := &types.Assertion{Condition: node.Text, Line: node.Line}
+	var result Value
+	if be, ok := node.Condition.(*ast.BinaryExpression); ok && len(node.Operands) == 2 {
+		left, err := ev.Eval(be.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := ev.Eval(be.Right)
+		if err != nil {
+			return nil, err
+		}
+		result, err = ev.applyBinary(be.Operator, left, right)
+		if err != nil {
+			return nil, err
+		}
+		check.Operands = []types.Operand{
+			{Text: node.Operands[0], Value: ToString(left)},
+			{Text: node.Operands[1], Value: ToString(right)},
+		}
+	} else {
+		var err error
+		result, err = ev.Eval(node.Condition)
+		if err != nil {
+			return nil, err
+		}
+	}
+	holds, err := ToBool(result)
+	if err != nil {
+		return nil, err
+	}
+	if holds {
+		return nil, nil
+	}
+
+	message := ""
+	if node.Message != nil {
+		msgVal, err := ev.Eval(node.Message)
+		if err != nil {
+			return nil, err
+		}
+		message = ToString(msgVal)
+	}
+	failure := types.NewAssertionError(message, check)
+	failure.CallStack = append([]string{}, ev.callStack...)
+	return nil, failure
+}
+
 // evalTypedVariableDecl evaluates a variable declaration with an explicit type annotation.
 // Syntax: Declare x as number to be 5.
 func (ev *Evaluator) evalTypedVariableDecl(node *ast.TypedVariableDecl) (Value, error) {
@@ -162,6 +216,28 @@ func (ev *Evaluator) evalErrorTypeDecl(node *ast.ErrorTypeDecl) (Value, error) {
 	return nil, nil
 }
 
+// evalEnumDecl evaluates an enumeration declaration. The enum is bound to its
+// name as a constant, so it is visible wherever a variable would be,
+// including to files that import this one.
+// Syntax: Declare Color as one of red, green and blue.
+func (ev *Evaluator) evalEnumDecl(node *ast.EnumDecl) (Value, error) {
+	if err := ev.env.Define(node.Name, types.NewEnumType(node.Name, node.Members), true); err != nil {
+		return nil, &TypeError{Line: node.Line, Message: err.Error()}
+	}
+	return nil, nil
+}
+
+// evalCapabilityDecl evaluates a capability declaration. Like an enum, the
+// capability is bound to its name as a constant.
+// Syntax: Declare Speaker as a capability requiring a function speak.
+func (ev *Evaluator) evalCapabilityDecl(node *ast.CapabilityDecl) (Value, error) {
+	capability := &types.Capability{Name: node.Name, Methods: node.Methods}
+	if err := ev.env.Define(node.Name, capability, true); err != nil {
+		return nil, &TypeError{Line: node.Line, Message: err.Error()}
+	}
+	return nil, nil
+}
+
 // evalErrorTypeCheckExpression evaluates "error is TypeName" — returns true if
 // the value is an ErrorValue whose type is TypeName or a subtype of TypeName.
 func (ev *Evaluator) evalErrorTypeCheckExpression(node *ast.ErrorTypeCheckExpression) (Value, error) {
@@ -169,6 +245,9 @@ func (ev *Evaluator) evalErrorTypeCheckExpression(node *ast.ErrorTypeCheckExpres
 	if err != nil {
 		return nil, err
 	}
+	if si, ok := val.(*StructInstance); ok {
+		return si.Definition.IsA(node.TypeName), nil
+	}
 	errVal, ok := val.(*types.ErrorValue)
 	if !ok {
 		return false, nil
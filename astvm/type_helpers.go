@@ -3,6 +3,7 @@ package vm
 import (
 	"github.com/Advik-B/english/astvm/types"
 	"fmt"
+	"math/big"
 )
 
 // inferTypeKind determines the TypeKind of a runtime value.
@@ -15,6 +16,8 @@ func inferTypeKind(v Value) types.TypeKind {
 		return types.TypeStruct
 	case *ReferenceValue:
 		return types.TypeRef
+	case *Generator:
+		return types.TypeGenerator
 	default:
 		return types.Infer(v)
 	}
@@ -43,6 +46,10 @@ func GetType(v Value) *types.TypeInfo {
 		return &types.TypeInfo{Kind: types.TypeU64, Name: "u64"}
 	case float32:
 		return &types.TypeInfo{Kind: types.TypeF32, Name: "f32"}
+	case *types.Decimal:
+		return &types.TypeInfo{Kind: types.TypeDecimal, Name: "decimal"}
+	case *big.Int:
+		return &types.TypeInfo{Kind: types.TypeWhole, Name: "whole number"}
 	case string:
 		return &types.TypeInfo{Kind: types.TypeString, Name: "text"}
 	case bool:
@@ -60,6 +67,20 @@ func GetType(v Value) *types.TypeInfo {
 		return &types.TypeInfo{Kind: types.TypeFunction, Name: "function"}
 	case *StructInstance:
 		return &types.TypeInfo{Kind: types.TypeStruct, Name: val.Definition.Name}
+	case *types.EnumValue:
+		return &types.TypeInfo{Kind: types.TypeEnum, Name: val.Type.Name}
+	case *types.EnumType:
+		return &types.TypeInfo{Kind: types.TypeEnum, Name: "enum"}
+	case *types.Capability:
+		return &types.TypeInfo{Kind: types.TypeUnknown, Name: "capability"}
+	case *types.Module:
+		return &types.TypeInfo{Kind: types.TypeUnknown, Name: "module"}
+	case *Generator:
+		return &types.TypeInfo{Kind: types.TypeUnknown, Name: "generator"}
+	case *types.Task:
+		return &types.TypeInfo{Kind: types.TypeUnknown, Name: "task"}
+	case *types.Channel:
+		return &types.TypeInfo{Kind: types.TypeUnknown, Name: "channel"}
 	case *types.ErrorValue:
 		return &types.TypeInfo{Kind: types.TypeError, Name: "error"}
 	case *ReferenceValue:
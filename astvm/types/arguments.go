@@ -0,0 +1,99 @@
+package types
+
+import "fmt"
+
+// BindArguments matches a call's arguments to the parameters of the function
+// called function. The positional arguments fill parameters from the left,
+// and each named argument, names[i] with value named[i], fills the parameter
+// of that name. When variadic is true the last parameter is a rest parameter
+// ("takes any number of values"): it collects the positional arguments left
+// over into a list, which is empty when there are none, and cannot be named.
+// The result has one slot per parameter; given[i] is false when the call left
+// parameter i out, which is only allowed when hasDefault(i).
+//
+// The errors are plain errors rather than ErrorValues: calling a function the
+// wrong way is a mistake in the program, not something to catch.
+func BindArguments(function string, params []string, variadic bool, hasDefault func(i int) bool,
+	positional []interface{}, names []string, named []interface{}) (slots []interface{}, given []bool, err error) {
+	fixed := len(params)
+	if variadic {
+		fixed--
+	}
+	required := 0
+	for i := 0; i < fixed; i++ {
+		if !hasDefault(i) {
+			required = i + 1
+		}
+	}
+	expects := func() error {
+		count := ArgumentCount(required, fixed)
+		if variadic {
+			count = fmt.Sprintf("at least %d argument(s)", required)
+		}
+		return fmt.Errorf("function '%s' expects %s, got %d", function, count, len(positional))
+	}
+	if !variadic && len(positional) > len(params) {
+		return nil, nil, expects()
+	}
+
+	slots = make([]interface{}, len(params))
+	given = make([]bool, len(params))
+	n := copy(slots[:fixed], positional)
+	for i := 0; i < n; i++ {
+		given[i] = true
+	}
+	if variadic {
+		rest := make([]interface{}, len(positional)-n)
+		copy(rest, positional[n:])
+		slots[fixed], given[fixed] = rest, true
+	}
+	for j, name := range names {
+		i := indexOf(params, name)
+		if i < 0 {
+			return nil, nil, fmt.Errorf("function '%s' has no parameter called '%s'", function, name)
+		}
+		if i == fixed {
+			return nil, nil, fmt.Errorf("function '%s' collects its extra arguments in '%s', so it cannot be given by name", function, name)
+		}
+		if given[i] {
+			return nil, nil, fmt.Errorf("function '%s' was given '%s' twice", function, name)
+		}
+		slots[i], given[i] = named[j], true
+	}
+
+	for i, param := range params {
+		if given[i] || hasDefault(i) {
+			continue
+		}
+		if len(names) == 0 {
+			return nil, nil, expects()
+		}
+		return nil, nil, fmt.Errorf("function '%s' needs a value for '%s'", function, param)
+	}
+	return slots, given, nil
+}
+
+// ArgumentCount describes how many arguments a function with min required
+// and max total parameters accepts, as in "2 argument(s)" or "1 to 3
+// arguments".
+func ArgumentCount(min, max int) string {
+	if min == max {
+		return fmt.Sprintf("%d argument(s)", max)
+	}
+	return fmt.Sprintf("%d to %d arguments", min, max)
+}
+
+// NoNamedArguments is the error for passing "name as value" arguments to a
+// built-in function, whose parameters have no names a call can use.
+func NoNamedArguments(function string) error {
+	return fmt.Errorf("'%s' is a built-in function and does not take named arguments", function)
+}
+
+func indexOf(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,38 @@
+package types
+
+import "fmt"
+
+// Capability is a contract declared with "Declare Speaker as a capability
+// requiring a function speak." A structure promises it with "that can
+// Speaker", and a parameter declared "as anything that can Speaker" only
+// accepts instances of structures that made that promise.
+type Capability struct {
+	Name    string
+	Methods []string // required function names, in declaration order
+}
+
+func (c *Capability) String() string {
+	return fmt.Sprintf("<capability %s>", c.Name)
+}
+
+// Missing returns the first required function that has is false for, or ""
+// when every required function is present.
+func (c *Capability) Missing(has func(name string) bool) string {
+	for _, m := range c.Methods {
+		if !has(m) {
+			return m
+		}
+	}
+	return ""
+}
+
+// CapabilityMismatch is the TypeError raised when the argument passed for
+// param, described by got, belongs to a structure that does not declare the
+// capability the parameter asks for.
+func CapabilityMismatch(function, param, capability, got string) error {
+	return &ErrorValue{
+		ErrorType: "TypeError",
+		Message: fmt.Sprintf("function '%s' needs '%s' to be something that can %s, but got %s",
+			function, param, capability, got),
+	}
+}
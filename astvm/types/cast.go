@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -16,72 +18,22 @@ func Cast(v interface{}, target TypeKind) (interface{}, error) {
 	}
 
 	switch target {
-	case TypeI32:
-		switch val := v.(type) {
-		case float64:
-			return int32(val), nil
-		case int64:
-			return int32(val), nil
-		case uint32:
-			return int32(val), nil
-		case uint64:
-			return int32(val), nil
-		case string:
-			var i int32
-			if _, err := fmt.Sscanf(val, "%d", &i); err != nil {
-				return nil, fmt.Errorf("TypeError: cannot cast text %q to number", val)
-			}
-			return i, nil
-		default:
-			return nil, fmt.Errorf("TypeError: cannot cast %s to number", Name(Infer(v)))
-		}
+	case TypeI32, TypeI64, TypeU32, TypeU64:
+		return castInteger(v, target)
 
-	case TypeI64:
-		switch val := v.(type) {
-		case float64:
-			return int64(val), nil
-		case int32:
-			return int64(val), nil
-		case uint32:
-			return int64(val), nil
-		case uint64:
-			return int64(val), nil
-		case string:
-			var i int64
-			if _, err := fmt.Sscanf(val, "%d", &i); err != nil {
-				return nil, fmt.Errorf("TypeError: cannot cast text %q to number", val)
-			}
-			return i, nil
-		default:
-			return nil, fmt.Errorf("TypeError: cannot cast %s to number", Name(Infer(v)))
+	case TypeDecimal:
+		d, err := castDecimal(v)
+		if err != nil {
+			return nil, err
 		}
+		return d, nil
 
-	case TypeU32:
-		switch val := v.(type) {
-		case float64:
-			if val < 0 {
-				return nil, fmt.Errorf("TypeError: cannot cast negative number to unsigned integer")
-			}
-			return uint32(val), nil
-		case int32:
-			if val < 0 {
-				return nil, fmt.Errorf("TypeError: cannot cast negative number to unsigned integer")
-			}
-			return uint32(val), nil
-		case int64:
-			if val < 0 {
-				return nil, fmt.Errorf("TypeError: cannot cast negative number to unsigned integer")
-			}
-			return uint32(val), nil
-		case string:
-			var i uint32
-			if _, err := fmt.Sscanf(val, "%d", &i); err != nil {
-				return nil, fmt.Errorf("TypeError: cannot cast text %q to number", val)
-			}
-			return i, nil
-		default:
-			return nil, fmt.Errorf("TypeError: cannot cast %s to number", Name(Infer(v)))
+	case TypeWhole:
+		n, err := castWhole(v)
+		if err != nil {
+			return nil, err
 		}
+		return n, nil
 
 	case TypeF64:
 		switch val := v.(type) {
@@ -97,6 +49,11 @@ func Cast(v interface{}, target TypeKind) (interface{}, error) {
 			return float64(val), nil
 		case float32:
 			return float64(val), nil
+		case *Decimal:
+			return val.Float64(), nil
+		case *big.Int:
+			f, _ := new(big.Float).SetInt(val).Float64()
+			return f, nil
 		case bool:
 			if val {
 				return float64(1), nil
@@ -125,6 +82,14 @@ func Cast(v interface{}, target TypeKind) (interface{}, error) {
 			return val != 0, nil
 		case int64:
 			return val != 0, nil
+		case uint32:
+			return val != 0, nil
+		case uint64:
+			return val != 0, nil
+		case *Decimal:
+			return val.Sign() != 0, nil
+		case *big.Int:
+			return val.Sign() != 0, nil
 		case string:
 			normalized := strings.ToLower(val)
 			switch normalized {
@@ -145,6 +110,112 @@ func Cast(v interface{}, target TypeKind) (interface{}, error) {
 	}
 }
 
+// castInteger converts v to the integer kind target. Unlike a store into an
+// integer field, an explicit cast truncates fractions toward zero, but it
+// still refuses values the kind cannot represent.
+func castInteger(v interface{}, target TypeKind) (interface{}, error) {
+	var n *big.Int
+	switch val := v.(type) {
+	case int32, int64, uint32, uint64:
+		n, _ = exactInteger(val)
+	case *big.Int:
+		n = val
+	case *Decimal:
+		n = val.Trunc()
+	case float32:
+		return castInteger(float64(val), target)
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) {
+			return nil, fmt.Errorf("TypeError: cannot cast %s to %s", basicString(val), IntegerName(target))
+		}
+		n, _ = exactInteger(math.Trunc(val))
+	case bool:
+		n = big.NewInt(0)
+		if val {
+			n.SetInt64(1)
+		}
+	case string:
+		var ok bool
+		if n, ok = new(big.Int).SetString(strings.TrimSpace(val), 10); !ok {
+			return nil, fmt.Errorf("TypeError: cannot cast text %q to number", val)
+		}
+	default:
+		return nil, fmt.Errorf("TypeError: cannot cast %s to number", Name(Infer(v)))
+	}
+	if _, signed := integerBits(target); !signed && n.Sign() < 0 {
+		return nil, fmt.Errorf("TypeError: cannot cast negative number to unsigned integer")
+	}
+	lo, hi := integerRange(target)
+	if n.Cmp(lo) < 0 || n.Cmp(hi) > 0 {
+		return nil, fmt.Errorf("TypeError: %s does not fit in %s (%s to %s)", n, withArticle(target), lo, hi)
+	}
+	return fromBig(n, target), nil
+}
+
+// castDecimal converts v to a decimal. Numbers go through their shortest
+// decimal representation, so 0.1 cast to decimal is exactly 0.1.
+func castDecimal(v interface{}) (*Decimal, error) {
+	switch val := v.(type) {
+	case *Decimal:
+		return val, nil
+	case float32:
+		return castDecimal(float64(val))
+	case float64:
+		d, err := decimalFromFloat(val)
+		if err != nil {
+			return nil, fmt.Errorf("TypeError: cannot cast %s to decimal", basicString(val))
+		}
+		return d, nil
+	case bool:
+		if val {
+			return DecimalFromInt(big.NewInt(1)), nil
+		}
+		return DecimalFromInt(big.NewInt(0)), nil
+	case string:
+		d, err := ParseDecimal(val)
+		if err != nil {
+			return nil, fmt.Errorf("TypeError: cannot cast text %q to decimal", val)
+		}
+		return d, nil
+	}
+	if n, ok := exactWhole(v); ok {
+		return DecimalFromInt(n), nil
+	}
+	return nil, fmt.Errorf("TypeError: cannot cast %s to decimal", Name(Infer(v)))
+}
+
+// castWhole converts v to a whole number, truncating any fraction toward
+// zero like the fixed-width integer casts.
+func castWhole(v interface{}) (*big.Int, error) {
+	switch val := v.(type) {
+	case *Decimal:
+		return val.Trunc(), nil
+	case float32:
+		return castWhole(float64(val))
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) {
+			return nil, fmt.Errorf("TypeError: cannot cast %s to whole number", basicString(val))
+		}
+		n, _ := exactInteger(math.Trunc(val))
+		return n, nil
+	case bool:
+		if val {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	case string:
+		n, ok := new(big.Int).SetString(strings.TrimSpace(val), 10)
+		if !ok {
+			return nil, fmt.Errorf("TypeError: cannot cast text %q to whole number", val)
+		}
+		return n, nil
+	}
+	if n, ok := exactWhole(v); ok {
+		return n, nil
+	}
+	return nil, fmt.Errorf("TypeError: cannot cast %s to whole number", Name(Infer(v)))
+}
+
 // Infer determines the TypeKind of a runtime value without importing the vm package.
 // It handles all primitive and composite types known to vm/types/.
 // Types defined only in vm/ (FunctionValue, StructInstance, ReferenceValue) are
@@ -173,6 +244,12 @@ func Infer(v interface{}) TypeKind {
 		return TypeArray
 	case *LookupTableValue:
 		return TypeLookup
+	case *Decimal:
+		return TypeDecimal
+	case *big.Int:
+		return TypeWhole
+	case *EnumValue:
+		return TypeEnum
 	case *ErrorValue:
 		return TypeError
 	case *TypedValue:
@@ -208,6 +285,16 @@ func basicString(v interface{}) string {
 		return strconv.FormatUint(val, 10)
 	case float32:
 		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case *Decimal:
+		return val.String()
+	case *big.Int:
+		return val.String()
+	case *EnumValue:
+		return val.String()
+	case *EnumType:
+		return val.String()
+	case *Capability:
+		return val.String()
 	case string:
 		return val
 	case bool:
@@ -48,6 +48,16 @@ func (lt *LookupTableValue) Delete(serialKey string) bool {
 	return true
 }
 
+// Callback is a user function handed to a built-in. Before calling the
+// standard library, each VM wraps its own function values in a Callback bound
+// to itself, so higher-order built-ins such as transform can call back into
+// user code without knowing which VM is running them.
+type Callback struct {
+	Name string                                        // function name, for error messages
+	Fn   interface{}                                   // the VM's own function value
+	Call func(args []interface{}) (interface{}, error) // invokes Fn with args
+}
+
 // RangeValue represents an immutable range with lazy evaluation.
 // For ranges with more than 20 elements, values are generated on-demand.
 type RangeValue struct {
@@ -0,0 +1,480 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DecimalPrecision is the number of significant digits kept when a decimal
+// division does not terminate. It matches the default context of Python's
+// decimal module.
+const DecimalPrecision = 28
+
+// Decimal is an exact base-10 number: coef × 10^-scale. Addition, subtraction
+// and multiplication are exact; division is exact when the quotient
+// terminates and is otherwise rounded half-to-even to DecimalPrecision
+// significant digits. A Decimal is immutable once created.
+type Decimal struct {
+	coef  *big.Int
+	scale int32 // digits after the decimal point, never negative
+}
+
+// NewDecimal returns coef × 10^-scale. A negative scale is folded into coef.
+func NewDecimal(coef *big.Int, scale int32) *Decimal {
+	c := new(big.Int).Set(coef)
+	if scale < 0 {
+		c.Mul(c, pow10(-scale))
+		scale = 0
+	}
+	return &Decimal{coef: c, scale: scale}
+}
+
+// DecimalFromInt returns n as a decimal with no fractional digits.
+func DecimalFromInt(n *big.Int) *Decimal {
+	return NewDecimal(n, 0)
+}
+
+// ParseDecimal reads text such as "19.99", "-0.5" or "+12". Trailing zeros are
+// significant and kept, so "1.50" prints back as 1.50.
+func ParseDecimal(s string) (*Decimal, error) {
+	text := strings.TrimSpace(s)
+	digits := strings.TrimLeft(text, "+-")
+	if len(text)-len(digits) > 1 {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole+frac == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	coef, _ := new(big.Int).SetString(whole+frac, 10)
+	if strings.HasPrefix(text, "-") {
+		coef.Neg(coef)
+	}
+	return &Decimal{coef: coef, scale: int32(len(frac))}, nil
+}
+
+// ParseExact returns the value of a decimal literal's digits: a *Decimal, or
+// a *big.Int when whole is set.
+func ParseExact(digits string, whole bool) (interface{}, error) {
+	if !whole {
+		return ParseDecimal(digits)
+	}
+	n, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid whole number %q", digits)
+	}
+	return n, nil
+}
+
+// decimalFromFloat converts f through its shortest decimal representation, so
+// the number 0.1 becomes the decimal 0.1 rather than the binary value nearest
+// to it.
+func decimalFromFloat(f float64) (*Decimal, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("%s has no decimal value", basicString(f))
+	}
+	return ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Coefficient returns the unscaled digits of d.
+func (d *Decimal) Coefficient() *big.Int { return new(big.Int).Set(d.coef) }
+
+// Scale returns the number of digits after the decimal point.
+func (d *Decimal) Scale() int32 { return d.scale }
+
+// Sign returns -1, 0 or +1.
+func (d *Decimal) Sign() int { return d.coef.Sign() }
+
+// String formats d in plain notation, keeping its trailing zeros.
+func (d *Decimal) String() string {
+	s := new(big.Int).Abs(d.coef).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(s); pad > 0 {
+			s = strings.Repeat("0", pad) + s
+		}
+		s = s[:len(s)-int(d.scale)] + "." + s[len(s)-int(d.scale):]
+	}
+	if d.coef.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Float64 returns the float64 nearest to d.
+func (d *Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+// Trunc returns the whole-number part of d, rounding toward zero.
+func (d *Decimal) Trunc() *big.Int {
+	return new(big.Int).Quo(d.coef, pow10(d.scale))
+}
+
+// Cmp compares d and o, returning -1, 0 or +1.
+func (d *Decimal) Cmp(o *Decimal) int {
+	a, b := align(d, o)
+	return a.Cmp(b)
+}
+
+// Neg returns -d.
+func (d *Decimal) Neg() *Decimal {
+	return &Decimal{coef: new(big.Int).Neg(d.coef), scale: d.scale}
+}
+
+// Add returns d + o.
+func (d *Decimal) Add(o *Decimal) *Decimal {
+	a, b := align(d, o)
+	return &Decimal{coef: a.Add(a, b), scale: max(d.scale, o.scale)}
+}
+
+// Sub returns d - o.
+func (d *Decimal) Sub(o *Decimal) *Decimal {
+	return d.Add(o.Neg())
+}
+
+// Mul returns d × o.
+func (d *Decimal) Mul(o *Decimal) *Decimal {
+	return &Decimal{coef: new(big.Int).Mul(d.coef, o.coef), scale: d.scale + o.scale}
+}
+
+// Quo returns d ÷ o, which must not be zero. An exact quotient keeps the
+// fewest digits after the point that still represent it (but at least the
+// difference of the operands' scales); an inexact one is rounded half-to-even
+// to DecimalPrecision significant digits.
+func (d *Decimal) Quo(o *Decimal) *Decimal {
+	num := new(big.Int).Mul(new(big.Int).Abs(d.coef), pow10(o.scale))
+	den := new(big.Int).Mul(new(big.Int).Abs(o.coef), pow10(d.scale))
+
+	// Pick the scale that leaves DecimalPrecision digits in the quotient. The
+	// digit-count estimate can be one too high, which the loop corrects.
+	scale := max(int32(DecimalPrecision-numDigits(num)+numDigits(den)), 0)
+	q, r := new(big.Int), new(big.Int)
+	for {
+		q.QuoRem(new(big.Int).Mul(num, pow10(scale)), den, r)
+		if scale == 0 || numDigits(q) <= DecimalPrecision {
+			break
+		}
+		scale--
+	}
+
+	if r.Sign() != 0 {
+		// Round half to even.
+		switch new(big.Int).Lsh(r, 1).Cmp(den) {
+		case 1:
+			q.Add(q, big.NewInt(1))
+		case 0:
+			if q.Bit(0) == 1 {
+				q.Add(q, big.NewInt(1))
+			}
+		}
+	} else {
+		ideal := max(d.scale-o.scale, 0)
+		ten := big.NewInt(10)
+		for scale > ideal {
+			quo, rem := new(big.Int).QuoRem(q, ten, new(big.Int))
+			if rem.Sign() != 0 {
+				break
+			}
+			q, scale = quo, scale-1
+		}
+	}
+	if d.coef.Sign()*o.coef.Sign() < 0 {
+		q.Neg(q)
+	}
+	return &Decimal{coef: q, scale: scale}
+}
+
+// floorQuo returns ⌊d ÷ o⌋ as a whole number; o must not be zero.
+func (d *Decimal) floorQuo(o *Decimal) *big.Int {
+	a, b := align(d, o)
+	q, m := new(big.Int).DivMod(a, b, new(big.Int))
+	// DivMod rounds toward -∞ only for a positive divisor.
+	if b.Sign() < 0 && m.Sign() != 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// rem returns d minus o times the quotient truncated toward zero, so the
+// result has the sign of d, as for every other number kind.
+func (d *Decimal) rem(o *Decimal) *Decimal {
+	a, b := align(d, o)
+	return &Decimal{coef: a.Rem(a, b), scale: max(d.scale, o.scale)}
+}
+
+// rat returns d as an exact fraction.
+func (d *Decimal) rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.coef, pow10(d.scale))
+}
+
+// align returns the coefficients of a and b rescaled to their common scale.
+func align(a, b *Decimal) (*big.Int, *big.Int) {
+	x, y := new(big.Int).Set(a.coef), new(big.Int).Set(b.coef)
+	switch {
+	case a.scale < b.scale:
+		x.Mul(x, pow10(b.scale-a.scale))
+	case b.scale < a.scale:
+		y.Mul(y, pow10(a.scale-b.scale))
+	}
+	return x, y
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// numDigits returns the number of decimal digits in |n|; zero has one.
+func numDigits(n *big.Int) int {
+	if n.Sign() == 0 {
+		return 1
+	}
+	return len(new(big.Int).Abs(n).String())
+}
+
+// ─── Mixed arithmetic ────────────────────────────────────────────────────────
+
+// IsExact reports whether tk is one of the arbitrary-precision kinds, decimal
+// or whole number.
+func IsExact(tk TypeKind) bool {
+	return tk == TypeDecimal || tk == TypeWhole
+}
+
+// exactRat returns any number as an exact fraction. Floats convert exactly,
+// so the number 0.1 is not equal to the decimal 0.1, just as in Python.
+func exactRat(v interface{}) (*big.Rat, bool) {
+	switch val := v.(type) {
+	case *Decimal:
+		return val.rat(), true
+	case *big.Int:
+		return new(big.Rat).SetInt(val), true
+	case float32:
+		return exactRat(float64(val))
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(val), true
+	}
+	if n, ok := exactInteger(v); ok {
+		return new(big.Rat).SetInt(n), true
+	}
+	return nil, false
+}
+
+// exactWhole returns v as a big.Int when it is a whole number of any kind
+// other than decimal: a whole number, a fixed-width integer or a float with no
+// fractional part.
+func exactWhole(v interface{}) (*big.Int, bool) {
+	if n, ok := v.(*big.Int); ok {
+		return n, true
+	}
+	return exactInteger(v)
+}
+
+// CompareNumbers orders two numbers exactly when at least one of them is a
+// decimal or a whole number. ok is false when neither is, or when either is
+// not a number, leaving the comparison to the ordinary number path.
+func CompareNumbers(a, b interface{}) (cmp int, ok bool) {
+	if !IsExact(Infer(a)) && !IsExact(Infer(b)) {
+		return 0, false
+	}
+	x, xok := exactRat(a)
+	y, yok := exactRat(b)
+	if !xok || !yok {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+// ExactArithmetic applies op ("+", "-", "*", "/", "//", "%", "**", "&", "|",
+// "^", "<<" or ">>") when at least one operand is a decimal or a whole number.
+//
+// A decimal combines with another decimal or with any whole number and gives
+// a decimal. Mixing a decimal with a fractional number is a TypeError, since
+// the binary fraction would silently spoil the exact result; cast it to
+// decimal first. A whole number combines with any whole number and gives a
+// whole number, except that "/" gives an ordinary number, as it does in
+// Python.
+//
+// handled is false when the operation is not exact arithmetic, when the
+// divisor is zero, or when the result is an ordinary number; the caller then
+// falls back to float arithmetic, which also reports those errors.
+func ExactArithmetic(op string, left, right interface{}) (result interface{}, handled bool, err error) {
+	switch op {
+	case "+", "-", "*", "/", "//", "%", "**", "&", "|", "^", "<<", ">>":
+	default:
+		return nil, false, nil
+	}
+	lk, rk := Infer(left), Infer(right)
+	if !IsExact(lk) && !IsExact(rk) {
+		return nil, false, nil
+	}
+	if !IsNumeric(lk) || !IsNumeric(rk) {
+		return nil, false, nil
+	}
+	if lk == TypeDecimal || rk == TypeDecimal {
+		return decimalArithmetic(op, left, right)
+	}
+	l, lok := exactWhole(left)
+	r, rok := exactWhole(right)
+	if !lok || !rok {
+		// A whole number mixed with a fraction is ordinary arithmetic.
+		return nil, false, nil
+	}
+	return wholeArithmetic(op, l, r)
+}
+
+// toDecimal converts a decimal or any whole number to a decimal.
+func toDecimal(v interface{}) (*Decimal, bool) {
+	if d, ok := v.(*Decimal); ok {
+		return d, true
+	}
+	if n, ok := exactWhole(v); ok {
+		return DecimalFromInt(n), true
+	}
+	return nil, false
+}
+
+func decimalArithmetic(op string, left, right interface{}) (interface{}, bool, error) {
+	l, lok := toDecimal(left)
+	r, rok := toDecimal(right)
+	if !lok || !rok {
+		other := left
+		if lok {
+			other = right
+		}
+		return nil, true, &ErrorValue{
+			ErrorType: "TypeError",
+			Message: fmt.Sprintf("cannot mix a decimal with the number %s; use 'cast to decimal' first",
+				basicString(other)),
+		}
+	}
+	switch op {
+	case "+":
+		return l.Add(r), true, nil
+	case "-":
+		return l.Sub(r), true, nil
+	case "*":
+		return l.Mul(r), true, nil
+	case "/", "//", "%":
+		if r.Sign() == 0 {
+			return nil, false, nil
+		}
+		switch op {
+		case "/":
+			return l.Quo(r), true, nil
+		case "//":
+			return DecimalFromInt(l.floorQuo(r)), true, nil
+		}
+		return l.rem(r), true, nil
+	case "**":
+		return decimalPower(l, right)
+	case "&", "|", "^", "<<", ">>":
+		return nil, true, &ErrorValue{
+			ErrorType: "TypeError",
+			Message:   fmt.Sprintf("'%s' needs whole numbers, not decimals", op),
+		}
+	}
+	return nil, false, nil
+}
+
+// decimalPower raises base to a whole-number exponent. A negative exponent
+// divides, and so is rounded like any other inexact division.
+func decimalPower(base *Decimal, exponent interface{}) (interface{}, bool, error) {
+	if d, ok := exponent.(*Decimal); ok && new(big.Int).Rem(d.coef, pow10(d.scale)).Sign() == 0 {
+		exponent = d.Trunc()
+	}
+	n, ok := exactWhole(exponent)
+	if !ok {
+		return nil, true, &ErrorValue{
+			ErrorType: "TypeError",
+			Message:   fmt.Sprintf("a decimal can only be raised to a whole-number power, not %s", basicString(exponent)),
+		}
+	}
+	// The result's scale is base.scale × |n|, which must fit the scale field.
+	if !n.IsInt64() || abs64(n.Int64()) > math.MaxInt32/int64(max(base.scale, 1)) {
+		return nil, true, &ErrorValue{
+			ErrorType: "OverflowError",
+			Message:   fmt.Sprintf("the power %s is too large for a decimal", n),
+		}
+	}
+	e := n.Int64()
+	if e < 0 && base.Sign() == 0 {
+		return nil, false, nil
+	}
+	p := &Decimal{
+		coef:  new(big.Int).Exp(base.coef, big.NewInt(abs64(e)), nil),
+		scale: base.scale * int32(abs64(e)),
+	}
+	if e < 0 {
+		return DecimalFromInt(big.NewInt(1)).Quo(p), true, nil
+	}
+	return p, true, nil
+}
+
+func wholeArithmetic(op string, l, r *big.Int) (interface{}, bool, error) {
+	n := new(big.Int)
+	switch op {
+	case "+":
+		return n.Add(l, r), true, nil
+	case "-":
+		return n.Sub(l, r), true, nil
+	case "*":
+		return n.Mul(l, r), true, nil
+	case "//", "%":
+		if r.Sign() == 0 {
+			return nil, false, nil
+		}
+		if op == "%" {
+			return n.Rem(l, r), true, nil
+		}
+		rem := new(big.Int)
+		n.QuoRem(l, r, rem)
+		if rem.Sign() != 0 && (rem.Sign() < 0) != (r.Sign() < 0) {
+			n.Sub(n, big.NewInt(1))
+		}
+		return n, true, nil
+	case "**":
+		if r.Sign() < 0 {
+			return nil, false, nil
+		}
+		return n.Exp(l, r, nil), true, nil
+	case "&":
+		return n.And(l, r), true, nil
+	case "|":
+		return n.Or(l, r), true, nil
+	case "^":
+		return n.Xor(l, r), true, nil
+	case "<<", ">>":
+		if r.Sign() < 0 || !r.IsInt64() {
+			return nil, false, nil
+		}
+		if op == ">>" {
+			return n.Rsh(l, uint(r.Int64())), true, nil
+		}
+		return n.Lsh(l, uint(r.Int64())), true, nil
+	}
+	return nil, false, nil
+}
+
+// ExactNegate negates a decimal or a whole number.
+func ExactNegate(v interface{}) (result interface{}, handled bool) {
+	switch val := v.(type) {
+	case *Decimal:
+		return val.Neg(), true
+	case *big.Int:
+		return new(big.Int).Neg(val), true
+	}
+	return nil, false
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package types
+
+import "fmt"
+
+// EnumType is a type declared with "Declare Color as one of red, green and
+// blue." It is bound to its name like a constant, and its members are read
+// with "Color's red".
+type EnumType struct {
+	Name    string
+	Members []*EnumValue // in declaration order
+}
+
+// NewEnumType returns an enum whose members are named by members, in order.
+func NewEnumType(name string, members []string) *EnumType {
+	et := &EnumType{Name: name, Members: make([]*EnumValue, len(members))}
+	enumKeysMu.Lock()
+	defer enumKeysMu.Unlock()
+	for i, m := range members {
+		et.Members[i] = &EnumValue{Type: et, Name: m, Ordinal: i}
+		enumKeys[et.Members[i].key()] = et.Members[i]
+	}
+	return et
+}
+
+// Member returns the member called name, or nil if the enum has none.
+func (et *EnumType) Member(name string) *EnumValue {
+	for _, m := range et.Members {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Get returns the member called name, or an error listing the enum's members
+// when there is no such member.
+func (et *EnumType) Get(name string) (*EnumValue, error) {
+	if m := et.Member(name); m != nil {
+		return m, nil
+	}
+	return nil, fmt.Errorf("%s has no member '%s' (its members are %s)", et.Name, name, et.memberList())
+}
+
+// Values returns the members as a list, for iteration with "For each".
+func (et *EnumType) Values() []interface{} {
+	out := make([]interface{}, len(et.Members))
+	for i, m := range et.Members {
+		out[i] = m
+	}
+	return out
+}
+
+func (et *EnumType) String() string {
+	return fmt.Sprintf("<enum %s>", et.Name)
+}
+
+func (et *EnumType) memberList() string {
+	s := ""
+	for i, m := range et.Members {
+		switch {
+		case i == 0:
+		case i == len(et.Members)-1:
+			s += " and "
+		default:
+			s += ", "
+		}
+		s += m.Name
+	}
+	return s
+}
+
+// EnumValue is one member of an EnumType. Two members are equal only when
+// they belong to the same enum and have the same name; members of different
+// enums are never equal.
+type EnumValue struct {
+	Type    *EnumType
+	Name    string
+	Ordinal int // position in the declaration, from 0
+}
+
+// String returns the member's name, which is also what "cast to text" gives.
+func (ev *EnumValue) String() string {
+	return ev.Name
+}
+
+// Equal reports whether ev and o are the same member of the same enum.
+func (ev *EnumValue) Equal(o *EnumValue) bool {
+	return ev.Type.Name == o.Type.Name && ev.Ordinal == o.Ordinal
+}
@@ -7,10 +7,19 @@ type ErrorValue struct {
 	Message   string
 	ErrorType string   // e.g. "TypeError", "RuntimeError"
 	CallStack []string // most-recent first
+	// Assertion is set on the AssertionError a failed "Make sure that"
+	// raises; see NewAssertionError.
+	Assertion *Assertion
 }
 
 func (e *ErrorValue) Error() string {
 	result := fmt.Sprintf("%s: %s\n", e.ErrorType, e.Message)
+	if e.Assertion != nil {
+		result += "  Make sure that " + e.Assertion.Condition + "\n"
+		for _, operand := range e.AssertionOperands() {
+			result += "    " + operand + "\n"
+		}
+	}
 	if len(e.CallStack) > 0 {
 		result += "\nCall Stack (most recent first):\n"
 		for i, frame := range e.CallStack {
@@ -20,6 +29,67 @@ func (e *ErrorValue) Error() string {
 	return result
 }
 
+// Assertion describes the check behind an AssertionError: the condition as
+// written, the line it is on and, for a comparison, what each side was.
+type Assertion struct {
+	Condition string
+	Operands  []Operand
+	Line      int
+}
+
+// Operand is one side of a failed comparison: its source text and its value.
+type Operand struct {
+	Text  string
+	Value string
+}
+
+// NewAssertionError returns the AssertionError raised when a "Make sure
+// that" check fails. message is what follows "otherwise say"; when it is
+// empty the message names the condition instead.
+func NewAssertionError(message string, check *Assertion) *ErrorValue {
+	if message == "" {
+		message = "failed to make sure that " + check.Condition
+	}
+	return &ErrorValue{Message: message, ErrorType: "AssertionError", Assertion: check}
+}
+
+// The methods below satisfy stacktraces.AssertionError, which renders a
+// failed assertion with its condition and operands. AssertionCondition is
+// empty for every other error value.
+
+func (e *ErrorValue) AssertionMessage() string { return e.Message }
+
+func (e *ErrorValue) AssertionCondition() string {
+	if e.Assertion == nil {
+		return ""
+	}
+	return e.Assertion.Condition
+}
+
+// AssertionOperands returns a line per side of the failed comparison, such
+// as "balance was -5". A side written as its own value, like 0, is left out.
+func (e *ErrorValue) AssertionOperands() []string {
+	if e.Assertion == nil {
+		return nil
+	}
+	var lines []string
+	for _, operand := range e.Assertion.Operands {
+		if operand.Text != operand.Value {
+			lines = append(lines, operand.Text+" was "+operand.Value)
+		}
+	}
+	return lines
+}
+
+func (e *ErrorValue) AssertionLine() int {
+	if e.Assertion == nil {
+		return 0
+	}
+	return e.Assertion.Line
+}
+
+func (e *ErrorValue) AssertionCallStack() []string { return e.CallStack }
+
 // NewTypeError creates a clear, consistent type mismatch error.
 func NewTypeError(operation, expected, got string) error {
 	return fmt.Errorf("TypeError: '%s' requires %s, but got %s", operation, expected, got)
@@ -0,0 +1,126 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A list or lookup table can be declared with the type of what it holds:
+//
+//	Declare scores as a list of numbers.
+//	Declare ages as a lookup table from text to number.
+//
+// The parser records these as a single type name, "list of number" or
+// "lookup table from text to number", so they travel wherever a plain type
+// name does. Parse maps them to TypeList and TypeLookup; the helpers below
+// check what goes into them.
+
+// ElementTypes splits a declared type name into the container's own name and
+// the types of its keys and elements. keyType is only set for a lookup table;
+// both are "" when name has no element type. A structure, enum or error type
+// keeps its case, as in "list of Point".
+func ElementTypes(name string) (container, keyType, elemType string) {
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "list of ") {
+		return "list", "", name[len("list of "):]
+	}
+	if strings.HasPrefix(lower, "lookup table from ") {
+		rest := name[len("lookup table from "):]
+		if i := strings.Index(strings.ToLower(rest), " to "); i >= 0 {
+			return "lookup table", rest[:i], rest[i+len(" to "):]
+		}
+	}
+	return name, "", ""
+}
+
+// UserTypes is what a VM knows about the structures, enums and error types a
+// program declares, which the element checks below fall back to for a type
+// name that is not built in.
+type UserTypes interface {
+	// IsDeclaredType reports whether name is a declared structure, enum or
+	// error type.
+	IsDeclaredType(name string) bool
+	// IsOfType reports whether v belongs to the type called name.
+	IsOfType(v interface{}, name string) bool
+}
+
+// CheckElementTypes reports an error when the key or element type named in
+// typeName is neither a built-in type nor one of user's. Lookup table keys
+// must be number, text or boolean.
+func CheckElementTypes(typeName string, user UserTypes) error {
+	_, keyType, elemType := ElementTypes(typeName)
+	if elemType == "" {
+		return nil
+	}
+	if Parse(elemType) == TypeUnknown && !user.IsDeclaredType(elemType) {
+		return fmt.Errorf("TypeError: unknown element type '%s' in '%s'\n  Hint: valid types are %s, or a structure, enum or error type",
+			elemType, typeName, strings.Join(UserTypeNames(), ", "))
+	}
+	if keyType != "" {
+		switch Canonical(Parse(keyType)) {
+		case TypeF64, TypeString, TypeBool:
+		default:
+			return fmt.Errorf("TypeError: lookup table keys must be number, text, or boolean; got '%s'", keyType)
+		}
+	}
+	return nil
+}
+
+// CheckElements checks every element of value, and every key when it is a
+// lookup table, against the types declared for variable by typeName.
+func CheckElements(variable, typeName string, value interface{}, user UserTypes) error {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, elem := range v {
+			if err := CheckElement(variable, typeName, elem, user); err != nil {
+				return err
+			}
+		}
+	case *LookupTableValue:
+		for _, k := range v.KeyOrder {
+			key, _, _ := DeserializeKey(k)
+			if err := CheckKey(variable, typeName, key, user); err != nil {
+				return err
+			}
+			if err := CheckElement(variable, typeName, v.Entries[k], user); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckElement reports a TypeError when elem may not be stored in variable,
+// whose declared type is typeName. nothing fits any element type.
+func CheckElement(variable, typeName string, elem interface{}, user UserTypes) error {
+	_, _, elemType := ElementTypes(typeName)
+	if elemType == "" || elem == nil || fitsType(elem, elemType, user) {
+		return nil
+	}
+	return &ErrorValue{
+		ErrorType: "TypeError",
+		Message:   fmt.Sprintf("'%s' is a %s, so it cannot hold %s", variable, typeName, Article(Name(Infer(elem)))),
+	}
+}
+
+// CheckKey reports a TypeError when key may not be used as a key of the
+// lookup table variable, whose declared type is typeName.
+func CheckKey(variable, typeName string, key interface{}, user UserTypes) error {
+	_, keyType, _ := ElementTypes(typeName)
+	if keyType == "" || fitsType(key, keyType, user) {
+		return nil
+	}
+	return &ErrorValue{
+		ErrorType: "TypeError",
+		Message:   fmt.Sprintf("'%s' is a %s, so its keys cannot be %s", variable, typeName, Article(Name(Infer(key)))),
+	}
+}
+
+// fitsType reports whether v belongs to the type called name, asking user
+// when name is not a built-in type.
+func fitsType(v interface{}, name string, user UserTypes) bool {
+	if matches, known := MatchesName(Infer(v), name); known {
+		return matches
+	}
+	return user.IsOfType(v, name)
+}
@@ -37,7 +37,9 @@ func (t *TypeInfo) String() string {
 		return "array"
 	case TypeLookup:
 		return "lookup table"
-	case TypeStruct:
+	case TypeDecimal, TypeWhole:
+		return Name(t.Kind)
+	case TypeStruct, TypeEnum:
 		return t.Name
 	case TypeFunction:
 		return "function"
@@ -48,6 +50,10 @@ func (t *TypeInfo) String() string {
 	case TypeRef:
 		return "reference"
 	default:
+		// Values with no kind of their own, like modules, carry a name.
+		if t.Name != "" {
+			return t.Name
+		}
 		return "unknown"
 	}
 }
@@ -0,0 +1,304 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// IsInteger reports whether tk is one of the fixed-width integer kinds used by
+// struct fields declared as integer, unsigned integer, i32, i64, u32 or u64.
+func IsInteger(tk TypeKind) bool {
+	switch tk {
+	case TypeI32, TypeI64, TypeU32, TypeU64:
+		return true
+	}
+	return false
+}
+
+// integerBits returns the width and signedness of an integer kind.
+func integerBits(tk TypeKind) (bits uint, signed bool) {
+	switch tk {
+	case TypeI32:
+		return 32, true
+	case TypeI64:
+		return 64, true
+	case TypeU32:
+		return 32, false
+	}
+	return 64, false
+}
+
+// integerRange returns the smallest and largest values an integer kind holds.
+func integerRange(tk TypeKind) (lo, hi *big.Int) {
+	bits, signed := integerBits(tk)
+	if signed {
+		hi = new(big.Int).Lsh(big.NewInt(1), bits-1)
+		lo = new(big.Int).Neg(hi)
+		return lo, hi.Sub(hi, big.NewInt(1))
+	}
+	hi = new(big.Int).Lsh(big.NewInt(1), bits)
+	return big.NewInt(0), hi.Sub(hi, big.NewInt(1))
+}
+
+// IntegerName returns the user-facing name of an integer kind.
+func IntegerName(tk TypeKind) string {
+	switch tk {
+	case TypeI32:
+		return "integer"
+	case TypeI64:
+		return "64-bit integer"
+	case TypeU32:
+		return "unsigned integer"
+	}
+	return "64-bit unsigned integer"
+}
+
+// withArticle prefixes an integer kind's name with "a" or "an".
+func withArticle(tk TypeKind) string {
+	name := IntegerName(tk)
+	if name[0] == 'i' || name[0] == 'u' {
+		return "an " + name
+	}
+	return "a " + name
+}
+
+// exactInteger returns v as a big.Int when it is an integer kind or a whole
+// float. ok is false for fractions, infinities, NaN and non-numbers.
+func exactInteger(v interface{}) (n *big.Int, ok bool) {
+	switch val := v.(type) {
+	case int32:
+		return big.NewInt(int64(val)), true
+	case int64:
+		return big.NewInt(val), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(val)), true
+	case uint64:
+		return new(big.Int).SetUint64(val), true
+	case float32:
+		return exactInteger(float64(val))
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) || val != math.Trunc(val) {
+			return nil, false
+		}
+		n, _ = big.NewFloat(val).Int(nil)
+		return n, true
+	}
+	return nil, false
+}
+
+// fromBig converts n, which must already be in range, to the Go type that
+// represents tk at runtime.
+func fromBig(n *big.Int, tk TypeKind) interface{} {
+	switch tk {
+	case TypeI32:
+		return int32(n.Int64())
+	case TypeI64:
+		return n.Int64()
+	case TypeU32:
+		return uint32(n.Uint64())
+	}
+	return n.Uint64()
+}
+
+// wrapInteger reduces n into the range of tk the way a fixed-width machine
+// integer would: modulo 2^bits, reinterpreted as two's complement when signed.
+func wrapInteger(n *big.Int, tk TypeKind) *big.Int {
+	bits, signed := integerBits(tk)
+	modulus := new(big.Int).Lsh(big.NewInt(1), bits)
+	r := new(big.Int).Mod(n, modulus)
+	if signed && r.Bit(int(bits-1)) == 1 {
+		r.Sub(r, modulus)
+	}
+	return r
+}
+
+// fitInteger stores n in tk. Out-of-range values wrap around when wrap is set
+// and raise an OverflowError otherwise; what describes n in that error.
+func fitInteger(n *big.Int, tk TypeKind, wrap bool, what string) (interface{}, error) {
+	lo, hi := integerRange(tk)
+	if n.Cmp(lo) >= 0 && n.Cmp(hi) <= 0 {
+		return fromBig(n, tk), nil
+	}
+	if wrap {
+		return fromBig(wrapInteger(n, tk), tk), nil
+	}
+	return nil, &ErrorValue{
+		ErrorType: "OverflowError",
+		Message:   fmt.Sprintf("%s does not fit in %s (%s to %s)", what, withArticle(tk), lo, hi),
+	}
+}
+
+// CheckIntegerField converts a value that is being stored in the struct field
+// named field, declared with integer kind tk. Whole numbers of any numeric
+// kind are accepted. A fraction is a TypeError instead of being silently
+// truncated, and a value outside the field's range — including any negative
+// value in an unsigned field — is an OverflowError. Stores never wrap,
+// whatever the program's overflow mode.
+func CheckIntegerField(field string, v interface{}, tk TypeKind) (interface{}, error) {
+	if tv, ok := v.(*TypedValue); ok {
+		v = tv.Value
+	}
+	prefix := fmt.Sprintf("field '%s' is %s and cannot hold", field, withArticle(tk))
+	if !IsNumeric(Infer(v)) {
+		return nil, &ErrorValue{ErrorType: "TypeError", Message: fmt.Sprintf("%s %s", prefix, Name(Infer(v)))}
+	}
+	n, ok := exactInteger(v)
+	if !ok {
+		return nil, &ErrorValue{
+			ErrorType: "TypeError",
+			Message:   fmt.Sprintf("%s %s because it is not a whole number", prefix, basicString(v)),
+		}
+	}
+	if _, signed := integerBits(tk); !signed && n.Sign() < 0 {
+		return nil, &ErrorValue{
+			ErrorType: "OverflowError",
+			Message:   fmt.Sprintf("%s the negative value %s", prefix, n),
+		}
+	}
+	if lo, hi := integerRange(tk); n.Cmp(lo) < 0 || n.Cmp(hi) > 0 {
+		return nil, &ErrorValue{
+			ErrorType: "OverflowError",
+			Message:   fmt.Sprintf("%s %s (%s to %s)", prefix, n, lo, hi),
+		}
+	}
+	return fromBig(n, tk), nil
+}
+
+// IntegerArithmetic applies op ("+", "-", "*", "//", "%", "**", "&", "|",
+// "^", "<<" or ">>") when at least one operand is an integer kind. The result
+// keeps the integer kind of the left operand, or of the right one when the
+// left is a plain number, so "count + 1" on an unsigned counter stays
+// unsigned. A result outside that kind's range raises an OverflowError, or
+// wraps around when wrap is set.
+//
+// handled is false when the operation is not integer arithmetic: neither
+// operand is an integer, an operand is fractional or not a number, the result
+// would be fractional, or the right operand is one the ordinary number path
+// rejects (a zero divisor, a negative shift). The caller then falls back to
+// float arithmetic, which also reports those errors.
+func IntegerArithmetic(op string, left, right interface{}, wrap bool) (result interface{}, handled bool, err error) {
+	kind := Infer(left)
+	if !IsInteger(kind) {
+		kind = Infer(right)
+		if !IsInteger(kind) {
+			return nil, false, nil
+		}
+	}
+	l, lok := exactInteger(left)
+	r, rok := exactInteger(right)
+	if !lok || !rok {
+		return nil, false, nil
+	}
+
+	n := new(big.Int)
+	switch op {
+	case "+":
+		n.Add(l, r)
+	case "-":
+		n.Sub(l, r)
+	case "*":
+		n.Mul(l, r)
+	case "//":
+		if r.Sign() == 0 {
+			return nil, false, nil
+		}
+		rem := new(big.Int)
+		n.QuoRem(l, r, rem)
+		if rem.Sign() != 0 && (rem.Sign() < 0) != (r.Sign() < 0) {
+			n.Sub(n, big.NewInt(1))
+		}
+	case "%":
+		if r.Sign() == 0 {
+			return nil, false, nil
+		}
+		n.Rem(l, r)
+	case "**":
+		if r.Sign() < 0 {
+			return nil, false, nil
+		}
+		if n, err = integerPower(l, r, kind, wrap); err != nil {
+			return nil, true, err
+		}
+	case "&":
+		n.And(l, r)
+	case "|":
+		n.Or(l, r)
+	case "^":
+		n.Xor(l, r)
+	case "<<", ">>":
+		if r.Sign() < 0 {
+			return nil, false, nil
+		}
+		// Anything shifted by more than 128 places has left every
+		// fixed-width kind, so clamp before asking big.Int to allocate.
+		shift := uint(128)
+		if r.IsInt64() && r.Int64() < 128 {
+			shift = uint(r.Int64())
+		}
+		if op == ">>" {
+			n.Rsh(l, shift)
+		} else {
+			n.Lsh(l, shift)
+		}
+	default:
+		return nil, false, nil
+	}
+	res, err := fitInteger(n, kind, wrap, fmt.Sprintf("the result of %s %s %s", basicString(left), op, basicString(right)))
+	return res, true, err
+}
+
+// integerPower raises base to a non-negative exponent. In wrap mode it uses
+// modular exponentiation so huge exponents stay cheap; otherwise an exponent
+// too large for any fixed-width result is reported as an overflow up front.
+func integerPower(base, exp *big.Int, tk TypeKind, wrap bool) (*big.Int, error) {
+	if wrap {
+		bits, _ := integerBits(tk)
+		modulus := new(big.Int).Lsh(big.NewInt(1), bits)
+		return new(big.Int).Exp(base, exp, modulus), nil
+	}
+	if base.CmpAbs(big.NewInt(1)) <= 0 || exp.Cmp(big.NewInt(128)) <= 0 {
+		return new(big.Int).Exp(base, exp, nil), nil
+	}
+	lo, hi := integerRange(tk)
+	return nil, &ErrorValue{
+		ErrorType: "OverflowError",
+		Message: fmt.Sprintf("the result of %s ** %s does not fit in %s (%s to %s)",
+			base, exp, withArticle(tk), lo, hi),
+	}
+}
+
+// IntegerNegate negates v when it is an integer kind, keeping its kind. The
+// only unsigned value that can be negated without overflow is zero.
+func IntegerNegate(v interface{}, wrap bool) (result interface{}, handled bool, err error) {
+	kind := Infer(v)
+	if !IsInteger(kind) {
+		return nil, false, nil
+	}
+	n, _ := exactInteger(v)
+	res, err := fitInteger(n.Neg(n), kind, wrap, "-"+basicString(v))
+	return res, true, err
+}
+
+// NumbersEqual compares two numbers of any numeric kind by value, exactly, so
+// an unsigned 64-bit counter is never rounded through float64. ok is false
+// when either value is not a number.
+func NumbersEqual(a, b interface{}) (equal, ok bool) {
+	if cmp, exact := CompareNumbers(a, b); exact {
+		return cmp == 0, true
+	}
+	if !IsNumeric(Infer(a)) || !IsNumeric(Infer(b)) {
+		return false, false
+	}
+	ai, aWhole := exactInteger(a)
+	bi, bWhole := exactInteger(b)
+	switch {
+	case aWhole && bWhole:
+		return ai.Cmp(bi) == 0, true
+	case aWhole != bWhole:
+		return false, true
+	}
+	af, _ := Cast(a, TypeF64)
+	bf, _ := Cast(b, TypeF64)
+	return af == bf, true
+}
@@ -0,0 +1,78 @@
+package types
+
+// Iterator hands out the items of a sequence one at a time. "For each" walks
+// every kind of collection through one, and a generator — the value of
+// calling a function that gives back values — is one itself, running its
+// body only as far as the next "Give back" each time Next is called.
+type Iterator interface {
+	// Next returns the next item, or ok false once there are none left.
+	Next() (item interface{}, ok bool, err error)
+}
+
+// Iterate returns an Iterator over value, or ok false when value cannot be
+// walked by "For each". Lists and arrays give their items, ranges their
+// numbers (computed as they are needed), lookup tables their keys in
+// insertion order and enums their members in declaration order.
+func Iterate(value interface{}) (it Iterator, ok bool) {
+	switch v := value.(type) {
+	case Iterator:
+		return v, true
+	case []interface{}:
+		return &sliceIterator{items: v}, true
+	case *ArrayValue:
+		return &sliceIterator{items: v.Elements}, true
+	case *RangeValue:
+		return &rangeIterator{r: v}, true
+	case *LookupTableValue:
+		return &keyIterator{table: v}, true
+	case *EnumType:
+		return &sliceIterator{items: v.Values()}, true
+	}
+	return nil, false
+}
+
+type sliceIterator struct {
+	items []interface{}
+	next  int
+}
+
+func (it *sliceIterator) Next() (interface{}, bool, error) {
+	if it.next >= len(it.items) {
+		return nil, false, nil
+	}
+	it.next++
+	return it.items[it.next-1], true, nil
+}
+
+type rangeIterator struct {
+	r    *RangeValue
+	next int
+}
+
+func (it *rangeIterator) Next() (interface{}, bool, error) {
+	item, ok := it.r.Get(it.next)
+	if ok {
+		it.next++
+	}
+	return item, ok, nil
+}
+
+// keyIterator reads the table's key order afresh each time, so keys added
+// by the loop body are visited too.
+type keyIterator struct {
+	table *LookupTableValue
+	next  int
+}
+
+func (it *keyIterator) Next() (interface{}, bool, error) {
+	if it.next >= len(it.table.KeyOrder) {
+		return nil, false, nil
+	}
+	serialKey := it.table.KeyOrder[it.next]
+	it.next++
+	key, _, ok := DeserializeKey(serialKey)
+	if !ok {
+		key = serialKey
+	}
+	return key, true, nil
+}
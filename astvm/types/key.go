@@ -1,13 +1,16 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // SerializeKey converts a hashable value into a string map key for use inside
 // a LookupTableValue.  The type prefix prevents collisions between e.g. the
 // number 5 and the text "5".
 //
-// Valid key types are: float64 (number), string (text), bool (boolean).
-// Any other type returns a non-nil error.
+// Valid key types are: float64 (number), string (text), bool (boolean) and
+// enum members. Any other type returns a non-nil error.
 func SerializeKey(v interface{}) (string, error) {
 	switch val := v.(type) {
 	case float64:
@@ -19,9 +22,11 @@ func SerializeKey(v interface{}) (string, error) {
 			return "b:true", nil
 		}
 		return "b:false", nil
+	case *EnumValue:
+		return "e:" + val.key(), nil
 	default:
 		return "", fmt.Errorf(
-			"TypeError: lookup table keys must be number, text, or boolean; got %T", v,
+			"TypeError: lookup table keys must be number, text, boolean or an enum member; got %s", Article(Name(Infer(v))),
 		)
 	}
 }
@@ -45,7 +50,26 @@ func DeserializeKey(s string) (interface{}, TypeKind, bool) {
 		return payload, TypeString, true
 	case "b:":
 		return payload == "true", TypeBool, true
+	case "e:":
+		enumKeysMu.Lock()
+		member, ok := enumKeys[payload]
+		enumKeysMu.Unlock()
+		return member, TypeEnum, ok
 	default:
 		return nil, TypeUnknown, false
 	}
 }
+
+// enumKeys maps the key of every enum member declared so far back to the
+// member, so that a lookup table can give back the members it is keyed by.
+// Members that are Equal share a key.
+var (
+	enumKeysMu sync.Mutex
+	enumKeys   = map[string]*EnumValue{}
+)
+
+// key identifies ev the way Equal compares it: by its enum's name and its
+// position in it.
+func (ev *EnumValue) key() string {
+	return fmt.Sprintf("%s#%d", ev.Type.Name, ev.Ordinal)
+}
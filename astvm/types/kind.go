@@ -29,11 +29,25 @@ const (
 	// Composite types introduced by the static type system
 	TypeArray  // homogeneous array   (*ArrayValue)
 	TypeLookup // lookup table / dict (*LookupTableValue)
+
+	// Arbitrary-precision numbers
+	TypeDecimal // exact base-10 number (*Decimal)
+	TypeWhole   // unbounded integer    (*big.Int)
+
+	// User-declared enumerations
+	TypeEnum // member of an enum (*EnumValue)
+
+	// The result of calling a function that gives back values
+	TypeGenerator
 )
 
 // Name returns the user-facing type name for a TypeKind.
 func Name(tk TypeKind) string {
 	switch tk {
+	case TypeDecimal:
+		return "decimal"
+	case TypeWhole:
+		return "whole number"
 	case TypeI32, TypeI64, TypeU32, TypeU64, TypeF32, TypeF64:
 		return "number"
 	case TypeString:
@@ -56,6 +70,10 @@ func Name(tk TypeKind) string {
 		return "error"
 	case TypeRef:
 		return "reference"
+	case TypeEnum:
+		return "enum"
+	case TypeGenerator:
+		return "generator"
 	default:
 		return "unknown"
 	}
@@ -64,17 +82,18 @@ func Name(tk TypeKind) string {
 // IsNumeric returns true for all numeric TypeKinds.
 func IsNumeric(tk TypeKind) bool {
 	switch tk {
-	case TypeI32, TypeI64, TypeU32, TypeU64, TypeF32, TypeF64:
+	case TypeI32, TypeI64, TypeU32, TypeU64, TypeF32, TypeF64, TypeDecimal, TypeWhole:
 		return true
 	}
 	return false
 }
 
-// Canonical maps every numeric TypeKind to TypeF64 (the single "number" type
-// visible to users), leaving all other kinds unchanged.  Used for assignment
-// compatibility checks.
+// Canonical maps every fixed-width numeric TypeKind to TypeF64 (the single
+// "number" type visible to users), leaving all other kinds — including
+// decimal and whole number — unchanged.  Used for assignment compatibility
+// checks.
 func Canonical(tk TypeKind) TypeKind {
-	if IsNumeric(tk) {
+	if IsNumeric(tk) && !IsExact(tk) {
 		return TypeF64
 	}
 	return tk
@@ -105,7 +124,14 @@ func Parse(s string) TypeKind {
 		return TypeArray
 	case "lookup", "table", "lookup table":
 		return TypeLookup
+	case "decimal":
+		return TypeDecimal
+	case "whole number", "whole":
+		return TypeWhole
 	default:
+		if container, _, elemType := ElementTypes(s); elemType != "" {
+			return Parse(container)
+		}
 		return TypeUnknown
 	}
 }
@@ -113,5 +139,29 @@ func Parse(s string) TypeKind {
 // UserTypeNames returns the canonical user-facing type names that are valid
 // for explicit type annotations.  Used in error messages.
 func UserTypeNames() []string {
-	return []string{"number", "text", "boolean", "list", "array", "lookup table"}
+	return []string{"number", "decimal", "whole number", "text", "boolean", "list", "array", "lookup table"}
+}
+
+// MatchesName reports whether a value of kind tk belongs to the built-in type
+// called name, as in the pattern "is a number". Every numeric kind matches any
+// numeric type name, while decimal and whole number match only themselves.
+// known is false when name is not a built-in type, leaving
+// error types and structures to the caller.
+func MatchesName(tk TypeKind, name string) (matches, known bool) {
+	switch strings.ToLower(name) {
+	case "function":
+		return tk == TypeFunction, true
+	case "error":
+		return tk == TypeError, true
+	case "nothing":
+		return tk == TypeNull, true
+	}
+	want := Parse(name)
+	if want == TypeUnknown {
+		return false, false
+	}
+	if IsNumeric(want) && !IsExact(want) {
+		return IsNumeric(tk), true
+	}
+	return tk == want, true
 }
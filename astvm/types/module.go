@@ -0,0 +1,56 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Module is the value bound by "Import "geometry.abc" as geo." It holds what
+// the imported file declared, its functions and variables, reached with
+// "geo's area". Names in one module never clash with the importer's own or
+// with another module's.
+type Module struct {
+	Name    string // the name given after "as"
+	Path    string // the file it was imported from
+	Members map[string]interface{}
+	// Current, when set, reads a member as it is now, so that a variable
+	// the module's own functions change is seen changed. Members then only
+	// says which names there are.
+	Current func(name string) (interface{}, bool)
+}
+
+// NewModule returns an empty module bound to name.
+func NewModule(name, path string) *Module {
+	return &Module{Name: name, Path: path, Members: make(map[string]interface{})}
+}
+
+// Get returns the member called name, or an error listing the module's
+// members when there is no such member.
+func (m *Module) Get(name string) (interface{}, error) {
+	if v, ok := m.Members[name]; ok {
+		if m.Current != nil {
+			if now, ok := m.Current(name); ok {
+				return now, nil
+			}
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("module '%s' has no member '%s' (its members are %s)", m.Name, name, m.memberList())
+}
+
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %s>", m.Name)
+}
+
+func (m *Module) memberList() string {
+	if len(m.Members) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(m.Members))
+	for name := range m.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A function's signature can say what type each parameter takes and what
+// type the function returns:
+//
+//	Declare function area that takes width as number and height as number
+//	and returns a number and does the following:
+//
+// ArgumentTypeMismatch and ReturnTypeMismatch are the TypeErrors both VMs
+// raise when a call breaks that promise.
+
+// ArgumentTypeMismatch is the TypeError raised when the argument passed for
+// param, described by got, is not of the type the parameter was declared as.
+func ArgumentTypeMismatch(function, param, want, got string) error {
+	return &ErrorValue{
+		ErrorType: "TypeError",
+		Message: fmt.Sprintf("function '%s' needs '%s' to be %s, but got %s",
+			function, param, Article(want), got),
+	}
+}
+
+// ReturnTypeMismatch is the TypeError raised when a function gives back a
+// value, described by got, that is not of the type it says it returns.
+func ReturnTypeMismatch(function, want, got string) error {
+	return &ErrorValue{
+		ErrorType: "TypeError",
+		Message: fmt.Sprintf("function '%s' should return %s, but returned %s",
+			function, Article(want), Article(got)),
+	}
+}
+
+// Article prefixes a type name with "a" or "an", as in "a number" or "an
+// array". "text" and "nothing" are left as they are.
+func Article(name string) string {
+	if strings.EqualFold(name, "text") || strings.EqualFold(name, "nothing") {
+		return name
+	}
+	if name != "" && strings.ContainsRune("aeiouAEIOU", rune(name[0])) {
+		return "an " + name
+	}
+	return "a " + name
+}
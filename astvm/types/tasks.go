@@ -0,0 +1,196 @@
+package types
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// checkpointSteps is how many statements or instructions a task runs between
+// chances for the others to take a turn.
+const checkpointSteps = 1000
+
+// Scheduler runs a program's background tasks ("Do the following in the
+// background and call it job:"). Each task runs on a goroutine of its own,
+// but tasks share the program's variables, so only the task holding the turn
+// runs. A task gives up the turn while it waits — for another task, on a
+// channel or in sleep — and now and then at a Checkpoint, always between two
+// statements, so no statement ever sees another half done.
+type Scheduler struct {
+	turn    sync.Mutex
+	changed *sync.Cond // broadcast whenever a task finishes or a channel moves
+
+	main  *Task
+	tasks []*Task
+
+	alive    int              // tasks not yet finished, the main program included
+	waiting  int              // tasks waiting since the last broadcast
+	blocked  map[*Task]string // what each waiting task is waiting for
+	steps    int
+	deadlock error // set once every task is waiting; each wait then fails
+}
+
+// Task is a piece of work started in the background, or the main program.
+type Task struct {
+	Name   string
+	done   bool
+	err    error
+	waited bool // someone has waited for the task and seen its error
+}
+
+func (t *Task) String() string {
+	return fmt.Sprintf("<task %s>", t.Name)
+}
+
+// Channel passes values from one task to another ("a channel"). It holds
+// none of its own: Send waits until a Receive has taken what it sent.
+type Channel struct {
+	queue []interface{}
+	sent  int
+	taken int
+}
+
+func (c *Channel) String() string {
+	return "<channel>"
+}
+
+// NewScheduler returns a scheduler and the Task standing for the main
+// program, which is the caller. The caller holds the turn from then on.
+func NewScheduler() (*Scheduler, *Task) {
+	s := &Scheduler{blocked: make(map[*Task]string), alive: 1}
+	s.changed = sync.NewCond(&s.turn)
+	s.main = &Task{Name: "the main program"}
+	s.turn.Lock()
+	return s, s.main
+}
+
+// Start starts body as a new task called name. body runs on a goroutine of
+// its own once the caller gives up the turn, and is handed its Task.
+func (s *Scheduler) Start(name string, body func(*Task) error) *Task {
+	t := &Task{Name: name}
+	s.tasks = append(s.tasks, t)
+	s.alive++
+	go func() {
+		s.turn.Lock()
+		defer s.turn.Unlock()
+		err := body(t)
+		t.done, t.err = true, err
+		s.alive--
+		s.wake()
+	}()
+	return t
+}
+
+// Wait waits, as the task cur, until t has finished and returns the error
+// that ended it, if any.
+func (s *Scheduler) Wait(cur, t *Task) error {
+	if cur == t {
+		return &ErrorValue{ErrorType: "RuntimeError", Message: fmt.Sprintf("task '%s' cannot wait for itself", t.Name)}
+	}
+	if err := s.block(cur, "waiting for "+t.Name, func() bool { return t.done }); err != nil {
+		return err
+	}
+	t.waited = true
+	return t.err
+}
+
+// Send passes value through ch, waiting as the task cur until another task
+// has received it.
+func (s *Scheduler) Send(cur *Task, ch *Channel, value interface{}) error {
+	ch.queue = append(ch.queue, value)
+	ch.sent++
+	ticket := ch.sent
+	s.wake()
+	return s.block(cur, "waiting to send on a channel", func() bool { return ch.taken >= ticket })
+}
+
+// Receive takes the next value sent through ch, waiting as the task cur
+// until there is one.
+func (s *Scheduler) Receive(cur *Task, ch *Channel) (interface{}, error) {
+	if err := s.block(cur, "waiting to receive from a channel", func() bool { return len(ch.queue) > 0 }); err != nil {
+		return nil, err
+	}
+	value := ch.queue[0]
+	ch.queue = ch.queue[1:]
+	ch.taken++
+	s.wake()
+	return value, nil
+}
+
+// Release runs f without the turn, so the other tasks can run meanwhile. f
+// must not touch anything the tasks share; sleeping is what it is for.
+func (s *Scheduler) Release(f func()) {
+	s.turn.Unlock()
+	defer s.turn.Lock()
+	f()
+}
+
+// Checkpoint is called between statements. Every so often it gives the
+// other tasks a turn, so a task that never waits cannot keep them all from
+// running.
+func (s *Scheduler) Checkpoint() {
+	s.steps++
+	if s.steps%checkpointSteps != 0 || s.alive < 2 {
+		return
+	}
+	s.turn.Unlock()
+	runtime.Gosched()
+	s.turn.Lock()
+}
+
+// Finish is called, as the main program cur, when the program ends. It waits
+// for every task still running and returns the error of the first task that
+// failed without anybody waiting for it.
+func (s *Scheduler) Finish(cur *Task) error {
+	if err := s.block(cur, "waiting for its tasks to finish", func() bool { return s.alive == 1 }); err != nil {
+		return err
+	}
+	for _, t := range s.tasks {
+		if t.err != nil && !t.waited {
+			t.waited = true
+			return t.err
+		}
+	}
+	return nil
+}
+
+// block waits, as the task cur, until ready reports true. When every task
+// that has not finished is waiting, none of them ever will be ready: that is
+// a deadlock, and every one of the waits fails with the same error.
+func (s *Scheduler) block(cur *Task, what string, ready func() bool) error {
+	defer delete(s.blocked, cur)
+	for !ready() {
+		if s.deadlock != nil {
+			return s.deadlock
+		}
+		s.blocked[cur] = what
+		s.waiting++
+		if s.waiting >= s.alive {
+			s.deadlock = s.deadlockError()
+			s.wake()
+			return s.deadlock
+		}
+		s.changed.Wait()
+	}
+	return nil
+}
+
+// wake lets every waiting task look again at what it is waiting for.
+func (s *Scheduler) wake() {
+	s.waiting = 0
+	s.changed.Broadcast()
+}
+
+func (s *Scheduler) deadlockError() error {
+	var parts []string
+	for _, t := range append([]*Task{s.main}, s.tasks...) {
+		if what, ok := s.blocked[t]; ok {
+			parts = append(parts, t.Name+" is "+what)
+		}
+	}
+	return &ErrorValue{
+		ErrorType: "RuntimeError",
+		Message:   "deadlock: every task is waiting and none can go on (" + strings.Join(parts, "; ") + ")",
+	}
+}
@@ -0,0 +1,66 @@
+package types
+
+import "fmt"
+
+// Unpack splits value into one value per name for a destructuring
+// declaration or assignment ("Declare q and r to be …"). A list gives its
+// items in order and must have exactly one per name, unless rest is set
+// ("Declare first and the rest as others to be …"): then the last name
+// collects, as a list, whatever items the names before it leave over. A
+// structure instance, passed as its type name and fields (fields is nil for
+// anything else), gives the field each name names, so "Declare name and age
+// to be person." picks person's name and age whatever order they were
+// declared in.
+func Unpack(value interface{}, names []string, rest bool, structName string, fields map[string]interface{}) ([]interface{}, error) {
+	if fields != nil {
+		if rest {
+			return nil, &ErrorValue{
+				ErrorType: "TypeError",
+				Message:   fmt.Sprintf("cannot collect the rest of a %s; only a list has items left over", structName),
+			}
+		}
+		values := make([]interface{}, len(names))
+		for i, name := range names {
+			v, ok := fields[name]
+			if !ok {
+				return nil, &ErrorValue{
+					ErrorType: "TypeError",
+					Message:   fmt.Sprintf("cannot unpack '%s' from a %s, which has no field called '%s'", name, structName, name),
+				}
+			}
+			values[i] = v
+		}
+		return values, nil
+	}
+
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case *ArrayValue:
+		items = v.Elements
+	default:
+		return nil, &ErrorValue{
+			ErrorType: "TypeError",
+			Message:   fmt.Sprintf("cannot unpack a value of type %s into %d variables; only lists and structures can be unpacked", Name(Infer(value)), len(names)),
+		}
+	}
+	if !rest {
+		if len(items) != len(names) {
+			return nil, &ErrorValue{
+				ErrorType: "RuntimeError",
+				Message:   fmt.Sprintf("cannot unpack %d value(s) into %d variables", len(items), len(names)),
+			}
+		}
+		return append([]interface{}(nil), items...), nil
+	}
+	last := len(names) - 1
+	if len(items) < last {
+		return nil, &ErrorValue{
+			ErrorType: "RuntimeError",
+			Message:   fmt.Sprintf("cannot unpack %d value(s) into %d variables and the rest", len(items), last),
+		}
+	}
+	values := append([]interface{}(nil), items[:last]...)
+	return append(values, append([]interface{}{}, items[last:]...)), nil
+}
@@ -19,10 +19,32 @@ type BuiltinFunc func(name string, args []Value) (Value, error)
 type FunctionValue struct {
 	Name       string
 	Parameters []string
-	Body       []ast.Statement
-	Closure    *Environment
+	// ParamCapabilities holds, per parameter, the capability its argument
+	// must declare ("" for none). It is nil when no parameter asks for one.
+	ParamCapabilities []string
+	// ParamTypes holds, per parameter, the type its argument must have (""
+	// for any). It is nil when no parameter is typed.
+	ParamTypes []string
+	// ReturnType is the type the function's result must have, or "".
+	ReturnType string
+	// Defaults holds, per parameter, the expression giving its value when a
+	// call leaves it out (nil for a required parameter). It is nil when no
+	// parameter has a default.
+	Defaults []ast.Expression
+	// Variadic is true when the last parameter collects any extra
+	// arguments into a list.
+	Variadic bool
+	// Generator is true when the body gives back values, so calling the
+	// function makes a Generator rather than running it.
+	Generator bool
+	Body      []ast.Statement
+	Closure   *Environment
 }
 
+// anonymousFunctionName is the Name given to functions created from a
+// function literal ("a function that takes x and returns x * 2").
+const anonymousFunctionName = "anonymous"
+
 func (f *FunctionValue) String() string {
 	return fmt.Sprintf("<function %s>", f.Name)
 }
@@ -32,11 +54,25 @@ func (f *FunctionValue) String() string {
 // ReturnValue wraps a function's return payload.
 type ReturnValue struct{ Value Value }
 
-// BreakValue signals a loop break.
-type BreakValue struct{}
+// BreakValue signals a loop break. Label names the loop to leave; it is
+// empty for the innermost one.
+type BreakValue struct{ Label string }
+
+// ContinueValue signals a loop continue, of the loop called Label or, when
+// it is empty, of the innermost one.
+type ContinueValue struct{ Label string }
 
-// ContinueValue signals a loop continue.
-type ContinueValue struct{}
+// stops reports whether the break ends the loop called label, rather than
+// passing through it to a loop around it.
+func (b *BreakValue) stops(label string) bool {
+	return b.Label == "" || b.Label == label
+}
+
+// continues reports whether the continue moves the loop called label on to
+// its next iteration, rather than passing through it to a loop around it.
+func (c *ContinueValue) continues(label string) bool {
+	return c.Label == "" || c.Label == label
+}
 
 // ─── Runtime error (non-catchable) ───────────────────────────────────────────
 
@@ -9,8 +9,11 @@ import (
 	"github.com/Advik-B/english/stdlib"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 // Helper function to evaluate code
@@ -1221,6 +1224,20 @@ func TestRuntimeErrorFormat(t *testing.T) {
 	}
 }
 
+func TestOperatorErrorsCarryLine(t *testing.T) {
+	for _, expr := range []string{`"a" to the power of 2`, `1.5 bitwise and 1`} {
+		_, err := evaluate("Declare x to be 1.\nPrint " + expr + ".")
+		rtErr, ok := err.(*vm.RuntimeError)
+		if !ok {
+			t.Errorf("%s: expected a *vm.RuntimeError, got %T (%v)", expr, err, err)
+			continue
+		}
+		if rtErr.Line != 2 {
+			t.Errorf("%s: error should be on line 2, got line %d", expr, rtErr.Line)
+		}
+	}
+}
+
 func TestEvaluatorImport(t *testing.T) {
 	// Create a temporary file for import testing
 	tempDir := t.TempDir()
@@ -2974,3 +2991,546 @@ func TestChecker_FollowsImports(t *testing.T) {
 		t.Errorf("expected error File to be %q, got %q", libFile.Name(), e.File)
 	}
 }
+
+// ============================================
+// ENUMERATIONS
+// ============================================
+
+func TestEnum_MembersAndIteration(t *testing.T) {
+	got := captureOutput(func() {
+		evaluate(`Declare Color as one of red, green and blue.
+Declare c to be Color's green.
+Print c, the type of c.
+For each m in Color, do the following:
+    Print m cast to text.
+thats it.`)
+	})
+	if want := "green Color\nred\ngreen\nblue\n"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestChecker_CompareDifferentEnums(t *testing.T) {
+	errs := checkCode(`Declare Color as one of red, green and blue.
+Declare Size as one of small and large.
+Declare c to be Color's red.
+If c is equal to Size's small, then
+    Print "never".
+thats it.`)
+	if len(errs) == 0 {
+		t.Fatal("expected an error comparing members of different enums, got none")
+	}
+	if msg := errs[0].Error(); !strings.Contains(msg, "Color") || !strings.Contains(msg, "Size") {
+		t.Errorf("error should name both enums, got: %s", msg)
+	}
+}
+
+func TestChecker_UnknownEnumMember(t *testing.T) {
+	errs := checkCode(`Declare Color as one of red, green and blue.
+Print Color's purple.`)
+	if len(errs) == 0 {
+		t.Fatal("expected an error for an unknown enum member, got none")
+	}
+	if msg := errs[0].Error(); !strings.Contains(msg, "purple") {
+		t.Errorf("error should mention 'purple', got: %s", msg)
+	}
+}
+
+func TestChecker_CapabilityMissingFunction(t *testing.T) {
+	errs := checkCode(`Declare Speaker as a capability requiring a function speak.
+declare Animal as a structure with the following fields:
+    name is a string.
+thats it.
+declare Dog as a kind of Animal that can Speaker with the following fields:
+    breed is a string.
+thats it.`)
+	if len(errs) == 0 {
+		t.Fatal("expected an error for a structure missing a required function, got none")
+	}
+	if msg := errs[0].Error(); !strings.Contains(msg, "Dog") || !strings.Contains(msg, "'speak'") {
+		t.Errorf("error should name Dog and 'speak', got: %s", msg)
+	}
+}
+
+func TestChecker_CapabilityInheritedFunction(t *testing.T) {
+	errs := checkCode(`Declare Speaker as a capability requiring a function speak.
+declare Animal as a structure with the following fields:
+    name is a string.
+    let speak be a function that does the following:
+        Print name.
+    thats it.
+thats it.
+declare Dog as a kind of Animal that can Speaker with the following fields:
+    breed is a string.
+thats it.`)
+	if len(errs) != 0 {
+		t.Errorf("an inherited function should satisfy the capability, got: %v", errs)
+	}
+}
+
+func TestChecker_CapabilityWrongArgument(t *testing.T) {
+	errs := checkCode(`Declare Speaker as a capability requiring a function speak.
+declare Cat as a structure with the following fields:
+    let speak be a function that does the following:
+        Print "meow".
+    thats it.
+thats it.
+Declare function greet that takes pet as anything that can Speaker and does the following:
+    Call pet's speak.
+thats it.
+Declare c to be a new instance of Cat.
+Call greet with c.`)
+	if len(errs) == 0 {
+		t.Fatal("expected an error passing a Cat where a Speaker is needed, got none")
+	}
+	if msg := errs[0].Error(); !strings.Contains(msg, "Cat") || !strings.Contains(msg, "Speaker") {
+		t.Errorf("error should name Cat and Speaker, got: %s", msg)
+	}
+}
+
+const checkerGreet = `Declare function greet that takes name and greeting (defaulting to "Hello") and does the following:
+    Print greeting, name.
+thats it.
+`
+
+func TestChecker_CallWithDefaultsAndNamedArguments(t *testing.T) {
+	errs := checkCode(checkerGreet + `Call greet with "Ann".
+Call greet with "Bo" and "Hi".
+Call greet with greeting as "Yo" and name as "Cy".
+Set x to be the result of calling greet with name as "Di".`)
+	if len(errs) != 0 {
+		t.Errorf("expected no errors, got: %v", errs)
+	}
+}
+
+func TestChecker_CallArgumentMismatch(t *testing.T) {
+	tests := []struct {
+		call string
+		want string
+	}{
+		{`Call greet.`, "expects 1 to 2 arguments, got 0"},
+		{`Call greet with "a" and "b" and "c".`, "expects 1 to 2 arguments, got 3"},
+		{`Call greet with nmae as "x".`, "has no parameter called 'nmae'"},
+		{`Call greet with "x" and name as "y".`, "was given 'name' twice"},
+		{`Call greet with greeting as "Hi".`, "needs a value for 'name'"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(checkerGreet + tt.call)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.call)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.call, tt.want, msg)
+		}
+	}
+}
+
+func TestChecker_RestParameter(t *testing.T) {
+	const label = `Declare function label that takes prefix and any number of items and does the following:
+    Print prefix, items.
+thats it.
+`
+	if errs := checkCode(label + `Call label with "a".
+Call label with "a" and 1 and 2 and 3.`); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	tests := []struct {
+		call string
+		want string
+	}{
+		{`Call label.`, "expects at least 1 argument(s), got 0"},
+		{`Call label with "a" and items as 1.`, "cannot be given by name"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(label + tt.call)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.call)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.call, tt.want, msg)
+		}
+	}
+}
+
+func TestChecker_TypedSignature(t *testing.T) {
+	const area = `Declare function area that takes width as number and height as number and returns a number and does the following:
+    Return width * height.
+thats it.
+declare Point as a structure with the following fields:
+    x is a number.
+thats it.
+Declare function show that takes p as a Point and does the following:
+    Print p's x.
+thats it.
+`
+	if errs := checkCode(area + `Print area(2, 3).
+Declare width to be "wide".
+Declare n to be the result of calling area with height as 2 and width as 1.
+Declare m as number to be area(n, 2).
+Declare p to be a new instance of Point.
+Call show with p.
+Declare function name that returns text and does the following:
+    Return "Ann".
+thats it.
+Declare function paint that takes c as Color and e as Oops and does the following:
+    Print c, e.
+thats it.
+Declare Color as one of red and green.
+Declare Oops as an error type.
+Declare function fail that takes e as TypeError and does the following:
+    Print e.
+thats it.`); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`Print area("2", 3).`, "function 'area' needs 'width' to be a number, but got text"},
+		{`Call area with 2 and height as true.`, "needs 'height' to be a number, but got boolean"},
+		{`Call show with 5.`, "needs 'p' to be a Point, but got number"},
+		{`Declare s as text to be area(1, 2).`, "cannot initialize text with number"},
+		{`Declare function name that returns text and does the following:
+    Return 5.
+thats it.`, "function 'name' should return text, but this returns a number"},
+		{`Declare function half that takes n as number and returns a list and does the following:
+    Return n.
+thats it.`, "should return a list, but this returns a number"},
+		{`Declare function origin that returns a Point and does the following:
+    Return nothing.
+thats it.`, "should return a Point, but this returns nothing"},
+		{`Declare function draw that takes s as a Shape and does the following:
+    Print s.
+thats it.`, "function 'draw' takes s as a Shape, but there is no structure, enum or error type called 'Shape'"},
+		{`Declare function origin that returns a Place and does the following:
+    Print "nowhere".
+thats it.`, "there is no structure, enum or error type called 'Place'"},
+		{`Declare function counting that returns a number and does the following:
+    Give back 1.
+thats it.`, "function 'counting' gives back values, so calling it makes a generator; it cannot say it returns a number"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(area + tt.src)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.src)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.src, tt.want, msg)
+		}
+	}
+}
+
+func TestChecker_ElementTypes(t *testing.T) {
+	const decls = `Declare scores as a list of numbers to be [1, 2].
+Declare ages as a lookup table from text to number.
+`
+	if errs := checkCode(decls + `Set scores to be append(scores, 3).
+Set scores to be insert(scores, 0, 4).
+Set the item at position 0 in scores to be 5.
+Set ages at "Ann" to be 30.
+Set scores to be [6, 7].`); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`Declare names as a list of text to be ["a", 1].`, "'names' is a list of text, so it cannot hold a number"},
+		{`Set scores to be append(scores, "x").`, "'scores' is a list of number, so it cannot hold text"},
+		{`Call insert with scores, 0 and true.`, "'scores' is a list of number, so it cannot hold a boolean"},
+		{`Set the item at position 0 in scores to be "y".`, "'scores' is a list of number, so it cannot hold text"},
+		{`Set scores to be [1, "two"].`, "cannot hold text"},
+		{`Set ages at 5 to be 3.`, "'ages' is a lookup table from text to number, so its keys cannot be a number"},
+		{`Set ages at "Bo" to be "old".`, "'ages' is a lookup table from text to number, so it cannot hold text"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(decls + tt.src)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.src)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.src, tt.want, msg)
+		}
+	}
+}
+
+func TestChecker_NamespacedImports(t *testing.T) {
+	lib := filepath.Join(t.TempDir(), "geometry.abc")
+	if err := os.WriteFile(lib, []byte(`Declare tau to always be 6.28.
+Declare function area that takes r as number and does the following:
+    Return tau * r * r / 2.
+thats it.
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	imp := `Import "` + lib + `" as geo.
+`
+	if errs := checkCode(imp + `Declare a to be the result of calling geo's area with 3.
+Call geo's area with 1.
+Print geo's tau.
+Print the tau of geo.`); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`Print geo's radius.`, "module 'geo' has no member 'radius' (its members are area, tau)"},
+		{`Call geo's area with 1 and 2.`, "function 'area' expects 1 argument(s), got 2"},
+		{`Declare a to be the result of calling geo's area with "x".`, "function 'area' needs 'r' to be a number, but got text"},
+		{`Call geo's tau with 1.`, "'tau' in module 'geo' is not a function"},
+		{`Declare geo to be 1.`, "variable 'geo' is already declared at line 1"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(imp + tt.src)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.src)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.src, tt.want, msg)
+		}
+	}
+}
+
+func TestChecker_LibrarySearchPath(t *testing.T) {
+	libs := t.TempDir()
+	if err := os.WriteFile(filepath.Join(libs, "shapes.abc"), []byte(`Declare function area that takes r as number and does the following:
+    Return r * r.
+thats it.
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENGLISH_PATH", libs)
+
+	errs := checkCode(`Import "shapes" as shapes.
+Declare a to be the result of calling shapes's area with "x".`)
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "function 'area' needs 'r' to be a number, but got text") {
+		t.Errorf("expected the library found on ENGLISH_PATH to be checked, got %v", errs)
+	}
+}
+
+func TestChecker_PrivateImports(t *testing.T) {
+	lib := filepath.Join(t.TempDir(), "cache.abc")
+	if err := os.WriteFile(lib, []byte(`Declare privately store to be [].
+Declare private function remember that takes x and does the following:
+    Set store to be append(store, x).
+thats it.
+Declare function keep that takes x and does the following:
+    Call remember with x.
+thats it.
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if errs := checkCode(`Import keep from "` + lib + `".
+Import "` + lib + `" as cache.
+Call cache's keep with 1.`); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`Import keep and remember from "` + lib + `".`, "'remember' is private to '" + lib + "' and cannot be imported"},
+		{`Import store from "` + lib + `".`, "'store' is private to '" + lib + "' and cannot be imported"},
+		{`Import "` + lib + `" as cache.
+Call cache's remember with 1.`, "module 'cache' has no member 'remember' (its members are keep)"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(tt.src)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.src)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.src, tt.want, msg)
+		}
+	}
+}
+
+func TestEvaluatorNamespacedImport(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"a.abc": "Declare function helper that does the following:\n    Return \"from a\".\nthats it.\n",
+		"b.abc": "Declare function helper that does the following:\n    Return \"from b\".\nthats it.\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := captureOutput(func() {
+		evaluate(`Import "` + filepath.Join(dir, "a.abc") + `" as a.
+Import "` + filepath.Join(dir, "b.abc") + `" as b.
+Declare x to be the result of calling a's helper.
+Declare y to be the result of calling b's helper.
+Print x, y.`)
+	})
+	if !strings.Contains(got, "from a from b") {
+		t.Errorf("expected each module's own helper, got %q", got)
+	}
+}
+
+func TestChecker_Destructuring(t *testing.T) {
+	const divide = `Declare function divide that takes a and b and does the following:
+    Return a divided evenly by b and the remainder of a divided by b.
+thats it.
+declare Person as a structure with the following fields:
+    name is a string.
+thats it.
+Declare p to be a new instance of Person with the following fields:
+    name is "Ann".
+thats it.
+`
+	if errs := checkCode(divide + `Declare q and r to be the result of calling divide with 7 and 2.
+Declare x and y to be [1, 2].
+Declare name to be "".
+Set name to p's name.
+Declare n to be 0.
+Set name and n to ["Bo", 3].
+Declare h and the rest as t to be [1, 2, 3].
+Declare d and the rest as m to be the result of calling divide with 7 and 2.
+Print q, r, x, y, name, n, h, t, d, m.`); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	tests := []struct {
+		decl string
+		want string
+	}{
+		{`Declare q, r and s to be the result of calling divide with 7 and 2.`, "cannot unpack the 2 values 'divide' returns into 3 variables"},
+		{`Declare x, y and z to be [1, 2].`, "cannot unpack a list of 2 item(s) into 3 variables"},
+		{`Declare name and age to be p.`, "no field called 'age'"},
+		{`Declare x and y to be [1, 2, 3].`, "cannot unpack a list of 3 item(s) into 2 variables"},
+		{`Declare function triple that takes a and does the following:
+    Return a and a and a.
+thats it.
+Declare q and r to be the result of calling triple with 1.`, "cannot unpack the 3 values 'triple' returns into 2 variables"},
+		{`Declare x, y and the rest as z to be [1].`, "cannot unpack a list of 1 item(s) into 2 variables and the rest"},
+		{`Declare name and the rest as others to be p.`, "cannot collect the rest of a Person"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(divide + tt.decl)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.decl)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.decl, tt.want, msg)
+		}
+	}
+}
+
+func TestChecker_LoopLabels(t *testing.T) {
+	if errs := checkCode(`Repeat the following 2 times and call this loop outer:
+    For each x in [1, 2], do the following and call this loop inner:
+        If x is equal to 1, then
+            Continue outer.
+        thats it.
+        Break out of inner.
+    thats it.
+    Break out of outer.
+thats it.
+Repeat the following 2 times and call this loop outer:
+    Continue outer.
+thats it.`); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`Repeat the following 2 times:
+    Break out of outer.
+thats it.`, "there is no loop called 'outer' around this statement"},
+		{`Repeat the following 2 times and call this loop outer:
+    Print 1.
+thats it.
+Repeat the following 2 times:
+    Continue outer.
+thats it.`, "there is no loop called 'outer' around this statement"},
+		{`Repeat the following 2 times and call this loop outer:
+    Declare function f that does the following:
+        Repeat the following 2 times:
+            Break out of outer.
+        thats it.
+    thats it.
+thats it.`, "there is no loop called 'outer' around this statement"},
+		{`Repeat the following 2 times and call this loop outer:
+    Repeat the following 2 times and call this loop outer:
+        Break out of outer.
+    thats it.
+thats it.`, "a loop around this one is already called 'outer'"},
+	}
+	for _, tt := range tests {
+		errs := checkCode(tt.src)
+		if len(errs) == 0 {
+			t.Errorf("%s: expected an error, got none", tt.src)
+			continue
+		}
+		if msg := errs[0].Error(); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error should contain %q, got: %s", tt.src, tt.want, msg)
+		}
+	}
+}
+
+func TestGeneratorLetGoWhenLoopEnds(t *testing.T) {
+	// A loop over a generator it called for itself lets go of it on the way
+	// out, so breaking early does not leave its body waiting forever.
+	before := runtime.NumGoroutine()
+	_, err := evaluate(`Declare function naturals that does the following:
+    Declare n to be 0.
+    Repeat forever:
+        Give back n.
+        Set n to be n + 1.
+    thats it.
+thats it.
+Repeat the following 50 times:
+    For each x in naturals(), do the following:
+        Break out of this loop.
+    thats it.
+thats it.`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before+5; i++ {
+		runtime.Gosched()
+	}
+	if after := runtime.NumGoroutine(); after > before+5 {
+		t.Errorf("%d goroutines left running after the loops ended", after-before)
+	}
+}
+
+func TestGeneratorLetGoWhenUnreachable(t *testing.T) {
+	// A generator kept in a variable can be resumed later, so breaking out of
+	// a loop over it leaves it waiting; it is let go once nothing can reach it.
+	before := runtime.NumGoroutine()
+	_, err := evaluate(`Declare function naturals that does the following:
+    Declare n to be 0.
+    Repeat forever:
+        Give back n.
+        Set n to be n + 1.
+    thats it.
+thats it.
+Declare function firstOf that does the following:
+    Declare g to be naturals().
+    For each x in g, do the following:
+        Break out of this loop.
+    thats it.
+thats it.
+Repeat the following 50 times:
+    Call firstOf.
+thats it.`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before+5; i++ {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before+5 {
+		t.Errorf("%d goroutines left running after their generators became unreachable", after-before)
+	}
+}
@@ -21,7 +21,7 @@ import (
 var MagicBytes = []byte{0x10, 0x1E, 0x4E, 0x47}
 
 // Version of the bytecode format
-const FormatVersion uint8 = 1
+const FormatVersion uint8 = 15
 
 // Cache configuration
 const (
@@ -64,6 +64,24 @@ const (
 	NodeTypedVariableDecl
 	NodeErrorTypeDecl
 	NodeErrorTypeCheckExpression
+	NodeFunctionLiteral
+	NodeInterpolatedString
+	NodeWhenStatement
+	NodeValuePattern
+	NodeRangePattern
+	NodeTypePattern
+	NodeDecimalLiteral
+	NodeEnumDecl
+	NodeCapabilityDecl
+	NodeYieldStatement
+	NodeBackgroundStatement
+	NodeWaitStatement
+	NodeSendStatement
+	NodeChannelLiteral
+	NodeReceiveExpression
+	NodeAssertStatement
+	NodeTestBlock
+	NodeContinueStatement
 )
 
 // Encoder serializes AST to binary format
@@ -147,12 +165,24 @@ func (e *Encoder) writeBool(v bool) {
 	}
 }
 
+// writeNames writes the names of a destructuring declaration or assignment,
+// which is an empty list for the ordinary single-name form.
+func (e *Encoder) writeNames(names []string) {
+	e.writeUint32(uint32(len(names)))
+	for _, name := range names {
+		e.writeString(name)
+	}
+}
+
 func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 	switch s := stmt.(type) {
 	case *ast.VariableDecl:
 		e.buf.WriteByte(NodeVariableDecl)
 		e.writeString(s.Name)
+		e.writeNames(s.Names)
+		e.writeBool(s.Rest)
 		e.writeBool(s.IsConstant)
+		e.writeBool(s.IsPrivate)
 		return e.encodeExpression(s.Value)
 
 	case *ast.TypedVariableDecl:
@@ -160,6 +190,7 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 		e.writeString(s.Name)
 		e.writeString(s.TypeName)
 		e.writeBool(s.IsConstant)
+		e.writeBool(s.IsPrivate)
 		return e.encodeExpression(s.Value)
 
 	case *ast.ErrorTypeDecl:
@@ -168,9 +199,29 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 		e.writeString(s.ParentType)
 		return nil
 
+	case *ast.EnumDecl:
+		e.buf.WriteByte(NodeEnumDecl)
+		e.writeString(s.Name)
+		e.writeUint32(uint32(len(s.Members)))
+		for _, m := range s.Members {
+			e.writeString(m)
+		}
+		return nil
+
+	case *ast.CapabilityDecl:
+		e.buf.WriteByte(NodeCapabilityDecl)
+		e.writeString(s.Name)
+		e.writeUint32(uint32(len(s.Methods)))
+		for _, m := range s.Methods {
+			e.writeString(m)
+		}
+		return nil
+
 	case *ast.Assignment:
 		e.buf.WriteByte(NodeAssignment)
 		e.writeString(s.Name)
+		e.writeNames(s.Names)
+		e.writeBool(s.Rest)
 		return e.encodeExpression(s.Value)
 
 	case *ast.FunctionDecl:
@@ -180,6 +231,28 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 		for _, param := range s.Parameters {
 			e.writeString(param)
 		}
+		// ParamCapabilities is either empty or one entry per parameter.
+		e.writeUint32(uint32(len(s.ParamCapabilities)))
+		for _, c := range s.ParamCapabilities {
+			e.writeString(c)
+		}
+		// ParamTypes too, followed by the return type ("" when not given).
+		e.writeNames(s.ParamTypes)
+		e.writeString(s.ReturnType)
+		// Defaults is likewise either empty or one entry per parameter, each
+		// a presence flag followed by the default expression.
+		e.writeUint32(uint32(len(s.Defaults)))
+		for _, def := range s.Defaults {
+			e.writeBool(def != nil)
+			if def != nil {
+				if err := e.encodeExpression(def); err != nil {
+					return err
+				}
+			}
+		}
+		e.writeBool(s.Variadic)
+		e.writeBool(s.Generator)
+		e.writeBool(s.IsPrivate)
 		body := filterComments(s.Body)
 		e.writeUint32(uint32(len(body)))
 		for _, bodyStmt := range body {
@@ -220,8 +293,39 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 		}
 		return nil
 
+	case *ast.WhenStatement:
+		e.buf.WriteByte(NodeWhenStatement)
+		if err := e.encodeExpression(s.Subject); err != nil {
+			return err
+		}
+		e.writeUint32(uint32(len(s.Cases)))
+		for _, wc := range s.Cases {
+			e.writeUint32(uint32(len(wc.Patterns)))
+			for _, pattern := range wc.Patterns {
+				if err := e.encodeWhenPattern(pattern); err != nil {
+					return err
+				}
+			}
+			body := filterComments(wc.Body)
+			e.writeUint32(uint32(len(body)))
+			for _, bodyStmt := range body {
+				if err := e.encodeStatement(bodyStmt); err != nil {
+					return err
+				}
+			}
+		}
+		otherwise := filterComments(s.Otherwise)
+		e.writeUint32(uint32(len(otherwise)))
+		for _, stmt := range otherwise {
+			if err := e.encodeStatement(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+
 	case *ast.WhileLoop:
 		e.buf.WriteByte(NodeWhileLoop)
+		e.writeString(s.Label)
 		if err := e.encodeExpression(s.Condition); err != nil {
 			return err
 		}
@@ -236,6 +340,7 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 
 	case *ast.ForLoop:
 		e.buf.WriteByte(NodeForLoop)
+		e.writeString(s.Label)
 		if err := e.encodeExpression(s.Count); err != nil {
 			return err
 		}
@@ -250,6 +355,7 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 
 	case *ast.ForEachLoop:
 		e.buf.WriteByte(NodeForEachLoop)
+		e.writeString(s.Label)
 		e.writeString(s.Item)
 		if err := e.encodeExpression(s.List); err != nil {
 			return err
@@ -273,8 +379,74 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 
 	case *ast.ReturnStatement:
 		e.buf.WriteByte(NodeReturnStatement)
+		// A count of zero means a single value follows; otherwise that many.
+		e.writeUint32(uint32(len(s.Values)))
+		if len(s.Values) == 0 {
+			return e.encodeExpression(s.Value)
+		}
+		for _, v := range s.Values {
+			if err := e.encodeExpression(v); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ast.YieldStatement:
+		e.buf.WriteByte(NodeYieldStatement)
 		return e.encodeExpression(s.Value)
 
+	case *ast.BackgroundStatement:
+		e.buf.WriteByte(NodeBackgroundStatement)
+		e.writeString(s.Name)
+		body := filterComments(s.Body)
+		e.writeUint32(uint32(len(body)))
+		for _, bodyStmt := range body {
+			if err := e.encodeStatement(bodyStmt); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ast.TestBlock:
+		e.buf.WriteByte(NodeTestBlock)
+		e.writeString(s.Name)
+		body := filterComments(s.Body)
+		e.writeUint32(uint32(len(body)))
+		for _, bodyStmt := range body {
+			if err := e.encodeStatement(bodyStmt); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ast.WaitStatement:
+		e.buf.WriteByte(NodeWaitStatement)
+		return e.encodeExpression(s.Task)
+
+	case *ast.SendStatement:
+		e.buf.WriteByte(NodeSendStatement)
+		if err := e.encodeExpression(s.Value); err != nil {
+			return err
+		}
+		return e.encodeExpression(s.Channel)
+
+	case *ast.AssertStatement:
+		e.buf.WriteByte(NodeAssertStatement)
+		if err := e.encodeExpression(s.Condition); err != nil {
+			return err
+		}
+		e.writeBool(s.Message != nil)
+		if s.Message != nil {
+			if err := e.encodeExpression(s.Message); err != nil {
+				return err
+			}
+		}
+		// The source text of the condition and of each side of a comparison,
+		// for the AssertionError.
+		e.writeString(s.Text)
+		e.writeNames(s.Operands)
+		return nil
+
 	case *ast.OutputStatement:
 		e.buf.WriteByte(NodeOutputStatement)
 		// Write number of values
@@ -299,6 +471,12 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 
 	case *ast.BreakStatement:
 		e.buf.WriteByte(NodeBreakStatement)
+		e.writeString(s.Label)
+		return nil
+
+	case *ast.ContinueStatement:
+		e.buf.WriteByte(NodeContinueStatement)
+		e.writeString(s.Label)
 		return nil
 
 	case *ast.ImportStatement:
@@ -311,6 +489,9 @@ func (e *Encoder) encodeStatement(stmt ast.Statement) error {
 		}
 		e.writeBool(s.ImportAll)
 		e.writeBool(s.IsSafe)
+		e.writeString(s.Alias)
+		// The line is kept so a circular import can say where it happens.
+		e.writeUint32(uint32(s.Line))
 		return nil
 
 	case *ast.CommentStatement:
@@ -337,6 +518,26 @@ func (e *Encoder) encodeElseIfPart(part *ast.ElseIfPart) error {
 	return nil
 }
 
+func (e *Encoder) encodeWhenPattern(pattern ast.WhenPattern) error {
+	switch p := pattern.(type) {
+	case *ast.ValuePattern:
+		e.buf.WriteByte(NodeValuePattern)
+		return e.encodeExpression(p.Value)
+	case *ast.RangePattern:
+		e.buf.WriteByte(NodeRangePattern)
+		if err := e.encodeExpression(p.Low); err != nil {
+			return err
+		}
+		return e.encodeExpression(p.High)
+	case *ast.TypePattern:
+		e.buf.WriteByte(NodeTypePattern)
+		e.writeString(p.TypeName)
+		return nil
+	default:
+		return fmt.Errorf("unknown pattern type: %T", pattern)
+	}
+}
+
 func (e *Encoder) encodeFunctionCall(fc *ast.FunctionCall) error {
 	e.buf.WriteByte(NodeFunctionCall)
 	e.writeString(fc.Name)
@@ -346,6 +547,13 @@ func (e *Encoder) encodeFunctionCall(fc *ast.FunctionCall) error {
 			return err
 		}
 	}
+	e.writeUint32(uint32(len(fc.NamedArguments)))
+	for _, na := range fc.NamedArguments {
+		e.writeString(na.Name)
+		if err := e.encodeExpression(na.Value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -418,6 +626,45 @@ func (e *Encoder) encodeExpression(expr ast.Expression) error {
 		e.writeString(ex.TypeName)
 		return e.encodeExpression(ex.Value)
 
+	case *ast.ChannelLiteral:
+		e.buf.WriteByte(NodeChannelLiteral)
+		return nil
+
+	case *ast.ReceiveExpression:
+		e.buf.WriteByte(NodeReceiveExpression)
+		return e.encodeExpression(ex.Channel)
+
+	case *ast.FunctionLiteral:
+		e.buf.WriteByte(NodeFunctionLiteral)
+		e.writeUint32(uint32(len(ex.Parameters)))
+		for _, param := range ex.Parameters {
+			e.writeString(param)
+		}
+		body := filterComments(ex.Body)
+		e.writeUint32(uint32(len(body)))
+		for _, bodyStmt := range body {
+			if err := e.encodeStatement(bodyStmt); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ast.InterpolatedString:
+		e.buf.WriteByte(NodeInterpolatedString)
+		e.writeUint32(uint32(len(ex.Parts)))
+		for _, part := range ex.Parts {
+			if err := e.encodeExpression(part); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ast.DecimalLiteral:
+		e.buf.WriteByte(NodeDecimalLiteral)
+		e.writeString(ex.Digits)
+		e.writeBool(ex.Whole)
+		return nil
+
 	default:
 		return fmt.Errorf("unknown expression type: %T", expr)
 	}
@@ -494,6 +741,22 @@ func (d *Decoder) readFloat64() (float64, error) {
 	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
 }
 
+// readNames reads what writeNames wrote, giving nil for an empty list.
+func (d *Decoder) readNames() ([]string, error) {
+	count, err := d.readUint32()
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	names := make([]string, count)
+	for i := range names {
+		names[i], err = d.readString()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return names, nil
+}
+
 func (d *Decoder) readBool() (bool, error) {
 	b, err := d.readByte()
 	if err != nil {
@@ -540,15 +803,27 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 		if err != nil {
 			return nil, err
 		}
+		names, err := d.readNames()
+		if err != nil {
+			return nil, err
+		}
+		rest, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
 		isConstant, err := d.readBool()
 		if err != nil {
 			return nil, err
 		}
+		isPrivate, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
 		value, err := d.decodeExpression()
 		if err != nil {
 			return nil, err
 		}
-		return &ast.VariableDecl{Name: name, IsConstant: isConstant, Value: value}, nil
+		return &ast.VariableDecl{Name: name, Names: names, Rest: rest, IsConstant: isConstant, IsPrivate: isPrivate, Value: value}, nil
 
 	case NodeTypedVariableDecl:
 		name, err := d.readString()
@@ -563,11 +838,15 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 		if err != nil {
 			return nil, err
 		}
+		isPrivate, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
 		value, err := d.decodeExpression()
 		if err != nil {
 			return nil, err
 		}
-		return &ast.TypedVariableDecl{Name: name, TypeName: typeName, IsConstant: isConstant, Value: value}, nil
+		return &ast.TypedVariableDecl{Name: name, TypeName: typeName, IsConstant: isConstant, IsPrivate: isPrivate, Value: value}, nil
 
 	case NodeErrorTypeDecl:
 		name, err := d.readString()
@@ -580,16 +859,60 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 		}
 		return &ast.ErrorTypeDecl{Name: name, ParentType: parentType}, nil
 
+	case NodeEnumDecl:
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		memberCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		members := make([]string, memberCount)
+		for i := uint32(0); i < memberCount; i++ {
+			members[i], err = d.readString()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &ast.EnumDecl{Name: name, Members: members}, nil
+
+	case NodeCapabilityDecl:
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		methodCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		methods := make([]string, methodCount)
+		for i := uint32(0); i < methodCount; i++ {
+			methods[i], err = d.readString()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &ast.CapabilityDecl{Name: name, Methods: methods}, nil
+
 	case NodeAssignment:
 		name, err := d.readString()
 		if err != nil {
 			return nil, err
 		}
+		names, err := d.readNames()
+		if err != nil {
+			return nil, err
+		}
+		rest, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
 		value, err := d.decodeExpression()
 		if err != nil {
 			return nil, err
 		}
-		return &ast.Assignment{Name: name, Value: value}, nil
+		return &ast.Assignment{Name: name, Names: names, Rest: rest, Value: value}, nil
 
 	case NodeFunctionDecl:
 		name, err := d.readString()
@@ -607,6 +930,59 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 				return nil, err
 			}
 		}
+		capCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		var capabilities []string
+		if capCount > 0 {
+			capabilities = make([]string, capCount)
+			for i := range capabilities {
+				capabilities[i], err = d.readString()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		paramTypes, err := d.readNames()
+		if err != nil {
+			return nil, err
+		}
+		returnType, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		defCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		var defaults []ast.Expression
+		if defCount > 0 {
+			defaults = make([]ast.Expression, defCount)
+			for i := range defaults {
+				hasDefault, err := d.readBool()
+				if err != nil {
+					return nil, err
+				}
+				if hasDefault {
+					if defaults[i], err = d.decodeExpression(); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+		variadic, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
+		generator, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
+		isPrivate, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
 		bodyCount, err := d.readUint32()
 		if err != nil {
 			return nil, err
@@ -618,7 +994,7 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 				return nil, err
 			}
 		}
-		return &ast.FunctionDecl{Name: name, Parameters: params, Body: body}, nil
+		return &ast.FunctionDecl{Name: name, Parameters: params, ParamCapabilities: capabilities, ParamTypes: paramTypes, ReturnType: returnType, Defaults: defaults, Variadic: variadic, Generator: generator, IsPrivate: isPrivate, Body: body}, nil
 
 	case NodeCallStatement:
 		fc, err := d.decodeFunctionCall()
@@ -667,7 +1043,59 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 		}
 		return &ast.IfStatement{Condition: condition, Then: thenBody, ElseIf: elseIfParts, Else: elseBody}, nil
 
+	case NodeWhenStatement:
+		subject, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		caseCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		cases := make([]*ast.WhenCase, caseCount)
+		for i := uint32(0); i < caseCount; i++ {
+			patternCount, err := d.readUint32()
+			if err != nil {
+				return nil, err
+			}
+			patterns := make([]ast.WhenPattern, patternCount)
+			for j := uint32(0); j < patternCount; j++ {
+				patterns[j], err = d.decodeWhenPattern()
+				if err != nil {
+					return nil, err
+				}
+			}
+			bodyCount, err := d.readUint32()
+			if err != nil {
+				return nil, err
+			}
+			body := make([]ast.Statement, bodyCount)
+			for j := uint32(0); j < bodyCount; j++ {
+				body[j], err = d.decodeStatement()
+				if err != nil {
+					return nil, err
+				}
+			}
+			cases[i] = &ast.WhenCase{Patterns: patterns, Body: body}
+		}
+		otherwiseCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		otherwise := make([]ast.Statement, otherwiseCount)
+		for i := uint32(0); i < otherwiseCount; i++ {
+			otherwise[i], err = d.decodeStatement()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &ast.WhenStatement{Subject: subject, Cases: cases, Otherwise: otherwise}, nil
+
 	case NodeWhileLoop:
+		label, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
 		condition, err := d.decodeExpression()
 		if err != nil {
 			return nil, err
@@ -683,9 +1111,13 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 				return nil, err
 			}
 		}
-		return &ast.WhileLoop{Condition: condition, Body: body}, nil
+		return &ast.WhileLoop{Condition: condition, Label: label, Body: body}, nil
 
 	case NodeForLoop:
+		label, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
 		count, err := d.decodeExpression()
 		if err != nil {
 			return nil, err
@@ -701,9 +1133,13 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 				return nil, err
 			}
 		}
-		return &ast.ForLoop{Count: count, Body: body}, nil
+		return &ast.ForLoop{Count: count, Label: label, Body: body}, nil
 
 	case NodeForEachLoop:
+		label, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
 		item, err := d.readString()
 		if err != nil {
 			return nil, err
@@ -723,7 +1159,7 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 				return nil, err
 			}
 		}
-		return &ast.ForEachLoop{Item: item, List: list, Body: body}, nil
+		return &ast.ForEachLoop{Item: item, List: list, Label: label, Body: body}, nil
 
 	case NodeIndexAssignment:
 		listName, err := d.readString()
@@ -741,12 +1177,112 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 		return &ast.IndexAssignment{ListName: listName, Index: index, Value: value}, nil
 
 	case NodeReturnStatement:
+		count, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			values := make([]ast.Expression, count)
+			for i := range values {
+				values[i], err = d.decodeExpression()
+				if err != nil {
+					return nil, err
+				}
+			}
+			return &ast.ReturnStatement{Values: values}, nil
+		}
 		value, err := d.decodeExpression()
 		if err != nil {
 			return nil, err
 		}
 		return &ast.ReturnStatement{Value: value}, nil
 
+	case NodeYieldStatement:
+		value, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.YieldStatement{Value: value}, nil
+
+	case NodeBackgroundStatement:
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		bodyCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		body := make([]ast.Statement, bodyCount)
+		for i := uint32(0); i < bodyCount; i++ {
+			body[i], err = d.decodeStatement()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &ast.BackgroundStatement{Name: name, Body: body}, nil
+
+	case NodeTestBlock:
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		bodyCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		body := make([]ast.Statement, bodyCount)
+		for i := uint32(0); i < bodyCount; i++ {
+			body[i], err = d.decodeStatement()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &ast.TestBlock{Name: name, Body: body}, nil
+
+	case NodeWaitStatement:
+		task, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.WaitStatement{Task: task}, nil
+
+	case NodeSendStatement:
+		value, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		channel, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.SendStatement{Value: value, Channel: channel}, nil
+
+	case NodeAssertStatement:
+		cond, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		hasMessage, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
+		var message ast.Expression
+		if hasMessage {
+			if message, err = d.decodeExpression(); err != nil {
+				return nil, err
+			}
+		}
+		text, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		operands, err := d.readNames()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.AssertStatement{Condition: cond, Message: message, Text: text, Operands: operands}, nil
+
 	case NodeOutputStatement:
 		// Read number of values
 		count, err := d.readUint32()
@@ -776,7 +1312,18 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 		return &ast.ToggleStatement{Name: name}, nil
 
 	case NodeBreakStatement:
-		return &ast.BreakStatement{}, nil
+		label, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.BreakStatement{Label: label}, nil
+
+	case NodeContinueStatement:
+		label, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ContinueStatement{Label: label}, nil
 
 	case NodeImportStatement:
 		path, err := d.readString()
@@ -802,11 +1349,21 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 		if err != nil {
 			return nil, err
 		}
+		alias, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		line, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
 		return &ast.ImportStatement{
 			Path:      path,
 			Items:     items,
 			ImportAll: importAll,
 			IsSafe:    isSafe,
+			Alias:     alias,
+			Line:      int(line),
 		}, nil
 
 	default:
@@ -814,6 +1371,39 @@ func (d *Decoder) decodeStatement() (ast.Statement, error) {
 	}
 }
 
+func (d *Decoder) decodeWhenPattern() (ast.WhenPattern, error) {
+	nodeType, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	switch nodeType {
+	case NodeValuePattern:
+		value, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ValuePattern{Value: value}, nil
+	case NodeRangePattern:
+		low, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		high, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.RangePattern{Low: low, High: high}, nil
+	case NodeTypePattern:
+		typeName, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.TypePattern{TypeName: typeName}, nil
+	default:
+		return nil, fmt.Errorf("unknown pattern node type: %d", nodeType)
+	}
+}
+
 func (d *Decoder) decodeElseIfPart() (*ast.ElseIfPart, error) {
 	nodeType, err := d.readByte()
 	if err != nil {
@@ -849,7 +1439,12 @@ func (d *Decoder) decodeFunctionCall() (*ast.FunctionCall, error) {
 	if nodeType != NodeFunctionCall {
 		return nil, fmt.Errorf("expected FunctionCall node, got %d", nodeType)
 	}
+	return d.decodeFunctionCallBody()
+}
 
+// decodeFunctionCallBody decodes a function call whose node type byte has
+// already been read.
+func (d *Decoder) decodeFunctionCallBody() (*ast.FunctionCall, error) {
 	name, err := d.readString()
 	if err != nil {
 		return nil, err
@@ -865,7 +1460,23 @@ func (d *Decoder) decodeFunctionCall() (*ast.FunctionCall, error) {
 			return nil, err
 		}
 	}
-	return &ast.FunctionCall{Name: name, Arguments: args}, nil
+	namedCount, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	var named []*ast.NamedArgument
+	for i := uint32(0); i < namedCount; i++ {
+		argName, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		named = append(named, &ast.NamedArgument{Name: argName, Value: value})
+	}
+	return &ast.FunctionCall{Name: name, Arguments: args, NamedArguments: named}, nil
 }
 
 func (d *Decoder) decodeExpression() (ast.Expression, error) {
@@ -944,23 +1555,9 @@ func (d *Decoder) decodeExpression() (ast.Expression, error) {
 		return &ast.UnaryExpression{Operator: operator, Right: right}, nil
 
 	case NodeFunctionCall:
-		// Re-read because we already consumed the type byte
-		name, err := d.readString()
-		if err != nil {
-			return nil, err
-		}
-		argCount, err := d.readUint32()
-		if err != nil {
-			return nil, err
-		}
-		args := make([]ast.Expression, argCount)
-		for i := uint32(0); i < argCount; i++ {
-			args[i], err = d.decodeExpression()
-			if err != nil {
-				return nil, err
-			}
-		}
-		return &ast.FunctionCall{Name: name, Arguments: args}, nil
+		// The type byte is already consumed; named arguments follow the
+		// positional ones just as in a call statement.
+		return d.decodeFunctionCallBody()
 
 	case NodeIndexExpression:
 		list, err := d.decodeExpression()
@@ -998,6 +1595,66 @@ func (d *Decoder) decodeExpression() (ast.Expression, error) {
 		}
 		return &ast.ErrorTypeCheckExpression{TypeName: typeName, Value: value}, nil
 
+	case NodeChannelLiteral:
+		return &ast.ChannelLiteral{}, nil
+
+	case NodeReceiveExpression:
+		channel, err := d.decodeExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ReceiveExpression{Channel: channel}, nil
+
+	case NodeFunctionLiteral:
+		paramCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		params := make([]string, paramCount)
+		for i := uint32(0); i < paramCount; i++ {
+			params[i], err = d.readString()
+			if err != nil {
+				return nil, err
+			}
+		}
+		bodyCount, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		body := make([]ast.Statement, bodyCount)
+		for i := uint32(0); i < bodyCount; i++ {
+			body[i], err = d.decodeStatement()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &ast.FunctionLiteral{Parameters: params, Body: body}, nil
+
+	case NodeInterpolatedString:
+		count, err := d.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		parts := make([]ast.Expression, count)
+		for i := uint32(0); i < count; i++ {
+			parts[i], err = d.decodeExpression()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &ast.InterpolatedString{Parts: parts}, nil
+
+	case NodeDecimalLiteral:
+		digits, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		whole, err := d.readBool()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.DecimalLiteral{Digits: digits, Whole: whole}, nil
+
 	default:
 		return nil, fmt.Errorf("unknown expression node type: %d", nodeType)
 	}
@@ -260,6 +260,336 @@ func TestEncodeDecodeFunctionDecl(t *testing.T) {
 	}
 }
 
+func TestEncodeDecodeFunctionLiteral(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.VariableDecl{
+				Name: "double",
+				Value: &ast.FunctionLiteral{
+					Parameters: []string{"x"},
+					Body: []ast.Statement{
+						&ast.ReturnStatement{
+							Value: &ast.BinaryExpression{
+								Left:     &ast.Identifier{Name: "x"},
+								Operator: "*",
+								Right:    &ast.NumberLiteral{Value: 2},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	varDecl := decoded.Statements[0].(*ast.VariableDecl)
+	fl, ok := varDecl.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("Expected FunctionLiteral, got %T", varDecl.Value)
+	}
+	if len(fl.Parameters) != 1 || fl.Parameters[0] != "x" {
+		t.Errorf("Expected parameters [x], got %v", fl.Parameters)
+	}
+	if len(fl.Body) != 1 {
+		t.Errorf("Expected 1 body statement, got %d", len(fl.Body))
+	}
+}
+
+func TestEncodeDecodeInterpolatedString(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.OutputStatement{
+				Values: []ast.Expression{&ast.InterpolatedString{Parts: []ast.Expression{
+					&ast.StringLiteral{Value: "Hello, "},
+					&ast.Identifier{Name: "name"},
+				}}},
+				Newline: true,
+			},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	output := decoded.Statements[0].(*ast.OutputStatement)
+	is, ok := output.Values[0].(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("Expected InterpolatedString, got %T", output.Values[0])
+	}
+	if len(is.Parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d", len(is.Parts))
+	}
+	if lit, ok := is.Parts[0].(*ast.StringLiteral); !ok || lit.Value != "Hello, " {
+		t.Errorf("Expected literal 'Hello, ', got %#v", is.Parts[0])
+	}
+	if id, ok := is.Parts[1].(*ast.Identifier); !ok || id.Name != "name" {
+		t.Errorf("Expected identifier 'name', got %#v", is.Parts[1])
+	}
+}
+
+func TestEncodeDecodeDecimalLiteral(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.OutputStatement{
+				Values: []ast.Expression{
+					&ast.DecimalLiteral{Digits: "0.10"},
+					&ast.DecimalLiteral{Digits: "-12345678901234567890", Whole: true},
+				},
+				Newline: true,
+			},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	output := decoded.Statements[0].(*ast.OutputStatement)
+	dec, ok := output.Values[0].(*ast.DecimalLiteral)
+	if !ok || dec.Digits != "0.10" || dec.Whole {
+		t.Errorf("Expected decimal 0.10, got %#v", output.Values[0])
+	}
+	whole, ok := output.Values[1].(*ast.DecimalLiteral)
+	if !ok || whole.Digits != "-12345678901234567890" || !whole.Whole {
+		t.Errorf("Expected whole number -12345678901234567890, got %#v", output.Values[1])
+	}
+}
+
+func TestEncodeDecodeEnumDecl(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.EnumDecl{Name: "Color", Members: []string{"red", "green", "blue"}},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	decl, ok := decoded.Statements[0].(*ast.EnumDecl)
+	if !ok {
+		t.Fatalf("Expected EnumDecl, got %T", decoded.Statements[0])
+	}
+	if decl.Name != "Color" || len(decl.Members) != 3 || decl.Members[2] != "blue" {
+		t.Errorf("Expected enum Color of red, green and blue, got %#v", decl)
+	}
+}
+
+func TestEncodeDecodeCapabilityDecl(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.CapabilityDecl{Name: "Speaker", Methods: []string{"speak", "listen"}},
+			&ast.FunctionDecl{
+				Name:              "greet",
+				Parameters:        []string{"pet", "times"},
+				ParamCapabilities: []string{"Speaker", ""},
+			},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	decl, ok := decoded.Statements[0].(*ast.CapabilityDecl)
+	if !ok {
+		t.Fatalf("Expected CapabilityDecl, got %T", decoded.Statements[0])
+	}
+	if decl.Name != "Speaker" || len(decl.Methods) != 2 || decl.Methods[1] != "listen" {
+		t.Errorf("Expected capability Speaker requiring speak and listen, got %#v", decl)
+	}
+	fn, ok := decoded.Statements[1].(*ast.FunctionDecl)
+	if !ok {
+		t.Fatalf("Expected FunctionDecl, got %T", decoded.Statements[1])
+	}
+	if len(fn.ParamCapabilities) != 2 || fn.ParamCapabilities[0] != "Speaker" || fn.ParamCapabilities[1] != "" {
+		t.Errorf("Expected parameter capabilities [Speaker \"\"], got %q", fn.ParamCapabilities)
+	}
+}
+
+func TestEncodeDecodeTypedSignature(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.FunctionDecl{
+				Name:       "area",
+				Parameters: []string{"width", "height"},
+				ParamTypes: []string{"number", ""},
+				ReturnType: "number",
+			},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	fn, ok := decoded.Statements[0].(*ast.FunctionDecl)
+	if !ok {
+		t.Fatalf("Expected FunctionDecl, got %T", decoded.Statements[0])
+	}
+	if len(fn.ParamTypes) != 2 || fn.ParamTypes[0] != "number" || fn.ParamTypes[1] != "" {
+		t.Errorf("Expected parameter types [number \"\"], got %q", fn.ParamTypes)
+	}
+	if fn.ReturnType != "number" {
+		t.Errorf("Expected return type number, got %q", fn.ReturnType)
+	}
+}
+
+func TestEncodeDecodeDefaultsAndNamedArguments(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.FunctionDecl{
+				Name:       "greet",
+				Parameters: []string{"name", "greeting"},
+				Defaults:   []ast.Expression{nil, &ast.StringLiteral{Value: "Hello"}},
+			},
+			&ast.CallStatement{
+				FunctionCall: &ast.FunctionCall{
+					Name:           "greet",
+					Arguments:      []ast.Expression{&ast.StringLiteral{Value: "Bo"}},
+					NamedArguments: []*ast.NamedArgument{{Name: "greeting", Value: &ast.StringLiteral{Value: "Hi"}}},
+				},
+			},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	fn, ok := decoded.Statements[0].(*ast.FunctionDecl)
+	if !ok {
+		t.Fatalf("Expected FunctionDecl, got %T", decoded.Statements[0])
+	}
+	if len(fn.Defaults) != 2 || fn.Defaults[0] != nil {
+		t.Fatalf("Expected defaults [nil \"Hello\"], got %v", fn.Defaults)
+	}
+	if lit, ok := fn.Defaults[1].(*ast.StringLiteral); !ok || lit.Value != "Hello" {
+		t.Errorf("Expected greeting to default to \"Hello\", got %#v", fn.Defaults[1])
+	}
+	call, ok := decoded.Statements[1].(*ast.CallStatement)
+	if !ok {
+		t.Fatalf("Expected CallStatement, got %T", decoded.Statements[1])
+	}
+	named := call.FunctionCall.NamedArguments
+	if len(named) != 1 || named[0].Name != "greeting" {
+		t.Errorf("Expected the named argument greeting, got %v", named)
+	}
+}
+
+func TestEncodeDecodeWhenStatement(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.WhenStatement{
+				Subject: &ast.Identifier{Name: "x"},
+				Cases: []*ast.WhenCase{
+					{
+						Patterns: []ast.WhenPattern{
+							&ast.ValuePattern{Value: &ast.StringLiteral{Value: "stop"}},
+							&ast.RangePattern{Low: &ast.NumberLiteral{Value: 1}, High: &ast.NumberLiteral{Value: 10}},
+						},
+						Body: []ast.Statement{&ast.BreakStatement{}},
+					},
+					{
+						Patterns: []ast.WhenPattern{&ast.TypePattern{TypeName: "number"}},
+					},
+				},
+				Otherwise: []ast.Statement{&ast.BreakStatement{}},
+			},
+		},
+	}
+
+	encoder := NewEncoder()
+	data, err := encoder.Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+
+	decoder := NewDecoder(data)
+	decoded, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	when, ok := decoded.Statements[0].(*ast.WhenStatement)
+	if !ok {
+		t.Fatalf("Expected WhenStatement, got %T", decoded.Statements[0])
+	}
+	if len(when.Cases) != 2 || len(when.Otherwise) != 1 {
+		t.Fatalf("Expected 2 cases and an otherwise, got %d and %d", len(when.Cases), len(when.Otherwise))
+	}
+	first := when.Cases[0]
+	if len(first.Patterns) != 2 || len(first.Body) != 1 {
+		t.Fatalf("Unexpected first case: %#v", first)
+	}
+	if _, ok := first.Patterns[1].(*ast.RangePattern); !ok {
+		t.Errorf("Expected RangePattern, got %T", first.Patterns[1])
+	}
+	if tp, ok := when.Cases[1].Patterns[0].(*ast.TypePattern); !ok || tp.TypeName != "number" {
+		t.Errorf("Expected type pattern 'number', got %#v", when.Cases[1].Patterns[0])
+	}
+}
+
 func TestEncodeDecodeIfStatement(t *testing.T) {
 	program := &ast.Program{
 		Statements: []ast.Statement{
@@ -732,3 +1062,316 @@ func TestEncodeDecodeImportStatement(t *testing.T) {
 		t.Errorf("Expected path 'library.abc', got %q", importStmt.Path)
 	}
 }
+
+func TestEncodeDecodeNamespacedImport(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.ImportStatement{Path: "geometry.abc", Alias: "geo", IsSafe: true, Line: 3},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	importStmt, ok := decoded.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("Expected ImportStatement, got %T", decoded.Statements[0])
+	}
+	if importStmt.Alias != "geo" || !importStmt.IsSafe {
+		t.Errorf("Expected a safe import as 'geo', got alias %q, safe %v", importStmt.Alias, importStmt.IsSafe)
+	}
+	if importStmt.Line != 3 {
+		t.Errorf("Expected the import's line to be kept, got %d", importStmt.Line)
+	}
+}
+
+func TestEncodeDecodeDestructuring(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.FunctionDecl{
+				Name:       "divide",
+				Parameters: []string{"a", "b"},
+				Body: []ast.Statement{
+					&ast.ReturnStatement{Values: []ast.Expression{
+						&ast.Identifier{Name: "a"},
+						&ast.Identifier{Name: "b"},
+					}},
+				},
+			},
+			&ast.VariableDecl{
+				Names: []string{"q", "r"},
+				Value: &ast.FunctionCall{Name: "divide", Arguments: []ast.Expression{
+					&ast.NumberLiteral{Value: 7},
+					&ast.NumberLiteral{Value: 2},
+				}},
+			},
+			&ast.Assignment{
+				Names: []string{"r", "q"},
+				Value: &ast.Identifier{Name: "pair"},
+			},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	fn := decoded.Statements[0].(*ast.FunctionDecl)
+	ret, ok := fn.Body[0].(*ast.ReturnStatement)
+	if !ok || len(ret.Values) != 2 || ret.Value != nil {
+		t.Fatalf("Expected a return of two values, got %#v", fn.Body[0])
+	}
+	decl := decoded.Statements[1].(*ast.VariableDecl)
+	if len(decl.Names) != 2 || decl.Names[0] != "q" || decl.Names[1] != "r" {
+		t.Errorf("Expected names [q r], got %v", decl.Names)
+	}
+	assign := decoded.Statements[2].(*ast.Assignment)
+	if len(assign.Names) != 2 || assign.Names[0] != "r" {
+		t.Errorf("Expected names [r q], got %v", assign.Names)
+	}
+}
+
+func TestEncodeDecodeGenerator(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.FunctionDecl{
+				Name:      "numbers",
+				Generator: true,
+				Body: []ast.Statement{
+					&ast.YieldStatement{Value: &ast.NumberLiteral{Value: 1}},
+				},
+			},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	fn := decoded.Statements[0].(*ast.FunctionDecl)
+	if !fn.Generator {
+		t.Errorf("Expected numbers to decode as a generator")
+	}
+	if _, ok := fn.Body[0].(*ast.YieldStatement); !ok {
+		t.Errorf("Expected a YieldStatement, got %#v", fn.Body[0])
+	}
+}
+
+func TestEncodeDecodeBackgroundTasks(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.VariableDecl{Name: "ch", Value: &ast.ChannelLiteral{}},
+			&ast.BackgroundStatement{
+				Name: "job",
+				Body: []ast.Statement{
+					&ast.SendStatement{Value: &ast.NumberLiteral{Value: 1}, Channel: &ast.Identifier{Name: "ch"}},
+				},
+			},
+			&ast.Assignment{Name: "x", Value: &ast.ReceiveExpression{Channel: &ast.Identifier{Name: "ch"}}},
+			&ast.WaitStatement{Task: &ast.Identifier{Name: "job"}},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	if _, ok := decoded.Statements[0].(*ast.VariableDecl).Value.(*ast.ChannelLiteral); !ok {
+		t.Errorf("Expected a ChannelLiteral, got %#v", decoded.Statements[0])
+	}
+	bg := decoded.Statements[1].(*ast.BackgroundStatement)
+	if bg.Name != "job" || len(bg.Body) != 1 {
+		t.Fatalf("Expected background task job with one statement, got %#v", bg)
+	}
+	if _, ok := bg.Body[0].(*ast.SendStatement); !ok {
+		t.Errorf("Expected a SendStatement, got %#v", bg.Body[0])
+	}
+	if _, ok := decoded.Statements[2].(*ast.Assignment).Value.(*ast.ReceiveExpression); !ok {
+		t.Errorf("Expected a ReceiveExpression, got %#v", decoded.Statements[2])
+	}
+	if _, ok := decoded.Statements[3].(*ast.WaitStatement); !ok {
+		t.Errorf("Expected a WaitStatement, got %#v", decoded.Statements[3])
+	}
+}
+
+func TestEncodeDecodeAssertion(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.AssertStatement{
+				Condition: &ast.BinaryExpression{Left: &ast.Identifier{Name: "balance"}, Operator: "is greater than or equal to", Right: &ast.NumberLiteral{Value: 0}},
+				Message:   &ast.StringLiteral{Value: "negative balance"},
+				Text:      "balance is at least 0",
+				Operands:  []string{"balance", "0"},
+			},
+			&ast.AssertStatement{Condition: &ast.Identifier{Name: "ready"}, Text: "ready"},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	check := decoded.Statements[0].(*ast.AssertStatement)
+	if check.Text != "balance is at least 0" || len(check.Operands) != 2 || check.Operands[0] != "balance" {
+		t.Errorf("Expected the check's source text to survive, got %#v", check)
+	}
+	if msg, ok := check.Message.(*ast.StringLiteral); !ok || msg.Value != "negative balance" {
+		t.Errorf("Expected the message to survive, got %#v", check.Message)
+	}
+	if check := decoded.Statements[1].(*ast.AssertStatement); check.Message != nil || len(check.Operands) != 0 {
+		t.Errorf("Expected a check with no message, got %#v", check)
+	}
+}
+
+func TestEncodeDecodeTestBlock(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.TestBlock{
+				Name: "adding works",
+				Body: []ast.Statement{
+					&ast.AssertStatement{Condition: &ast.BooleanLiteral{Value: true}, Text: "true"},
+				},
+			},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	tb, ok := decoded.Statements[0].(*ast.TestBlock)
+	if !ok || tb.Name != "adding works" || len(tb.Body) != 1 {
+		t.Fatalf("Expected test \"adding works\" with one statement, got %#v", decoded.Statements[0])
+	}
+	if _, ok := tb.Body[0].(*ast.AssertStatement); !ok {
+		t.Errorf("Expected an AssertStatement, got %#v", tb.Body[0])
+	}
+}
+
+func TestEncodeDecodePrivateDeclarations(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.VariableDecl{Name: "cache", IsPrivate: true, Value: &ast.NumberLiteral{Value: 0}},
+			&ast.TypedVariableDecl{Name: "limit", TypeName: "number", IsPrivate: true, Value: &ast.NumberLiteral{Value: 3}},
+			&ast.FunctionDecl{Name: "helper", IsPrivate: true},
+			&ast.FunctionDecl{Name: "run"},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	if !decoded.Statements[0].(*ast.VariableDecl).IsPrivate {
+		t.Errorf("Expected cache to stay private")
+	}
+	if !decoded.Statements[1].(*ast.TypedVariableDecl).IsPrivate {
+		t.Errorf("Expected limit to stay private")
+	}
+	if !decoded.Statements[2].(*ast.FunctionDecl).IsPrivate {
+		t.Errorf("Expected helper to stay private")
+	}
+	if decoded.Statements[3].(*ast.FunctionDecl).IsPrivate {
+		t.Errorf("Expected run to stay public")
+	}
+}
+
+func TestEncodeDecodeLoopLabels(t *testing.T) {
+	program := &ast.Program{
+		Statements: []ast.Statement{
+			&ast.WhileLoop{
+				Condition: &ast.BooleanLiteral{Value: true},
+				Label:     "outer",
+				Body: []ast.Statement{
+					&ast.ForLoop{
+						Count: &ast.NumberLiteral{Value: 2},
+						Label: "middle",
+						Body: []ast.Statement{
+							&ast.ForEachLoop{
+								Item:  "x",
+								List:  &ast.Identifier{Name: "items"},
+								Label: "inner",
+								Body: []ast.Statement{
+									&ast.ContinueStatement{Label: "middle"},
+									&ast.ContinueStatement{},
+									&ast.BreakStatement{Label: "outer"},
+									&ast.BreakStatement{},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := NewEncoder().Encode(program)
+	if err != nil {
+		t.Fatalf("Encode error: %v", err)
+	}
+	decoded, err := NewDecoder(data).Decode()
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+
+	outer := decoded.Statements[0].(*ast.WhileLoop)
+	if outer.Label != "outer" {
+		t.Errorf("Expected the while loop to be called outer, got %q", outer.Label)
+	}
+	middle := outer.Body[0].(*ast.ForLoop)
+	if middle.Label != "middle" {
+		t.Errorf("Expected the counted loop to be called middle, got %q", middle.Label)
+	}
+	inner := middle.Body[0].(*ast.ForEachLoop)
+	if inner.Label != "inner" || inner.Item != "x" {
+		t.Errorf("Expected the for-each loop over x to be called inner, got %#v", inner)
+	}
+	if cont := inner.Body[0].(*ast.ContinueStatement); cont.Label != "middle" {
+		t.Errorf("Expected a continue of middle, got %#v", cont)
+	}
+	if cont := inner.Body[1].(*ast.ContinueStatement); cont.Label != "" {
+		t.Errorf("Expected a plain continue, got %#v", cont)
+	}
+	if brk := inner.Body[2].(*ast.BreakStatement); brk.Label != "outer" {
+		t.Errorf("Expected a break out of outer, got %#v", brk)
+	}
+	if brk := inner.Body[3].(*ast.BreakStatement); brk.Label != "" {
+		t.Errorf("Expected a plain break, got %#v", brk)
+	}
+}
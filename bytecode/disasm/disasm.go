@@ -238,20 +238,34 @@ func (d *disassembler) stmtMaybeUnroll(node ast.Statement) {
 		for _, tmp := range extras {
 			d.stmt(tmp)
 		}
-		d.stmt(&ast.VariableDecl{Name: s.Name, Value: newVal, IsConstant: s.IsConstant})
+		d.stmt(&ast.VariableDecl{Name: s.Name, Names: s.Names, Rest: s.Rest, Value: newVal, IsConstant: s.IsConstant, IsPrivate: s.IsPrivate})
 	case *ast.TypedVariableDecl:
 		extras, newVal := d.unrollTopExpr(s.Value)
 		for _, tmp := range extras {
 			d.stmt(tmp)
 		}
-		d.stmt(&ast.TypedVariableDecl{Name: s.Name, TypeName: s.TypeName, Value: newVal, IsConstant: s.IsConstant})
+		d.stmt(&ast.TypedVariableDecl{Name: s.Name, TypeName: s.TypeName, Value: newVal, IsConstant: s.IsConstant, IsPrivate: s.IsPrivate})
 	case *ast.Assignment:
 		extras, newVal := d.unrollTopExpr(s.Value)
 		for _, tmp := range extras {
 			d.stmt(tmp)
 		}
-		d.stmt(&ast.Assignment{Name: s.Name, Value: newVal})
+		d.stmt(&ast.Assignment{Name: s.Name, Names: s.Names, Rest: s.Rest, Value: newVal})
 	case *ast.ReturnStatement:
+		if len(s.Values) > 0 {
+			var allExtras []ast.Statement
+			newVals := make([]ast.Expression, len(s.Values))
+			for i, v := range s.Values {
+				extras, newV := d.unrollTopExpr(v)
+				allExtras = append(allExtras, extras...)
+				newVals[i] = newV
+			}
+			for _, tmp := range allExtras {
+				d.stmt(tmp)
+			}
+			d.stmt(&ast.ReturnStatement{Values: newVals})
+			break
+		}
 		extras, newVal := d.unrollTopExpr(s.Value)
 		for _, tmp := range extras {
 			d.stmt(tmp)
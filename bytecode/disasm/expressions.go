@@ -27,9 +27,28 @@ func (d *disassembler) expr(node ast.Expression) string {
 	case *ast.NumberLiteral:
 		return d.s(styleNum, fmt.Sprintf("%g", ex.Value))
 
+	case *ast.DecimalLiteral:
+		if ex.Whole {
+			return d.s(styleNum, ex.Digits)
+		}
+		return d.s(styleNum, ex.Digits+"d")
+
 	case *ast.StringLiteral:
 		return d.s(styleStr, `"`+ex.Value+`"`)
 
+	case *ast.InterpolatedString:
+		var sb strings.Builder
+		sb.WriteString(d.s(styleStr, `"`))
+		for _, part := range ex.Parts {
+			if lit, ok := part.(*ast.StringLiteral); ok {
+				sb.WriteString(d.s(styleStr, lit.Value))
+				continue
+			}
+			sb.WriteString(d.s(stylePunct, "{") + d.expr(part) + d.s(stylePunct, "}"))
+		}
+		sb.WriteString(d.s(styleStr, `"`))
+		return sb.String()
+
 	case *ast.BooleanLiteral:
 		if ex.Value {
 			return d.s(styleBool, "true")
@@ -61,7 +80,7 @@ func (d *disassembler) expr(node ast.Expression) string {
 		return d.s(styleOp, d.opStr(ex.Operator)) + d.expr(ex.Right)
 
 	case *ast.FunctionCall:
-		return d.s(styleLabel, ex.Name) + d.argList(ex.Arguments)
+		return d.s(styleLabel, ex.Name) + d.argList(ex.Arguments, ex.NamedArguments)
 
 	case *ast.IndexExpression:
 		return d.expr(ex.List) +
@@ -107,6 +126,13 @@ func (d *disassembler) expr(node ast.Expression) string {
 	case *ast.LookupTableLiteral:
 		return d.s(stylePunct, "{}")
 
+	case *ast.ChannelLiteral:
+		return d.s(styleOpcodeIO, "channel") + d.s(stylePunct, "()")
+
+	case *ast.ReceiveExpression:
+		return d.s(styleOpcodeIO, "receive") +
+			d.s(stylePunct, "(") + d.expr(ex.Channel) + d.s(stylePunct, ")")
+
 	case *ast.LookupKeyAccess:
 		return d.expr(ex.Table) +
 			d.s(stylePunct, "[") + d.expr(ex.Key) + d.s(stylePunct, "]")
@@ -124,7 +150,7 @@ func (d *disassembler) expr(node ast.Expression) string {
 		return d.expr(ex.Object) +
 			d.s(stylePunct, ".") +
 			d.s(styleLabel, ex.MethodName) +
-			d.argList(ex.Arguments)
+			d.argList(ex.Arguments, ex.NamedArguments)
 
 	case *ast.FieldAccess:
 		return d.expr(ex.Object) +
@@ -155,19 +181,60 @@ func (d *disassembler) expr(node ast.Expression) string {
 }
 
 // argList renders a function-call argument list as "(a, b, c)".
-func (d *disassembler) argList(args []ast.Expression) string {
-	if len(args) == 0 {
+func (d *disassembler) argList(args []ast.Expression, named []*ast.NamedArgument) string {
+	if len(args) == 0 && len(named) == 0 {
 		return d.s(stylePunct, "()")
 	}
-	parts := make([]string, len(args))
-	for i, a := range args {
-		parts[i] = d.expr(a)
+	parts := make([]string, 0, len(args)+len(named))
+	for _, a := range args {
+		parts = append(parts, d.expr(a))
+	}
+	for _, na := range named {
+		parts = append(parts, d.s(styleIdent, na.Name)+d.s(styleOp, "=")+d.expr(na.Value))
 	}
 	return d.s(stylePunct, "(") +
 		strings.Join(parts, d.s(stylePunct, ", ")) +
 		d.s(stylePunct, ")")
 }
 
+// paramList renders a function's parameters as "(a, b=default, *rest)".
+func (d *disassembler) paramList(fd *ast.FunctionDecl) string {
+	params := make([]string, len(fd.Parameters))
+	for i, p := range fd.Parameters {
+		params[i] = d.s(styleIdent, p)
+		if i < len(fd.ParamTypes) && fd.ParamTypes[i] != "" {
+			params[i] += d.s(styleType, ":"+fd.ParamTypes[i])
+		}
+		if i < len(fd.Defaults) && fd.Defaults[i] != nil {
+			params[i] += d.s(styleOp, "=") + d.expr(fd.Defaults[i])
+		}
+	}
+	if fd.Variadic && len(params) > 0 {
+		params[len(params)-1] = d.s(styleOp, "*") + params[len(params)-1]
+	}
+	list := d.s(stylePunct, "(") +
+		strings.Join(params, d.s(stylePunct, ", ")) +
+		d.s(stylePunct, ")")
+	if fd.ReturnType != "" {
+		list += d.s(styleOp, " -> ") + d.s(styleType, fd.ReturnType)
+	}
+	return list
+}
+
+// pattern renders a When case pattern: a value, "lo..hi" or ":type".
+func (d *disassembler) pattern(node ast.WhenPattern) string {
+	switch p := node.(type) {
+	case *ast.ValuePattern:
+		return d.expr(p.Value)
+	case *ast.RangePattern:
+		return d.expr(p.Low) + d.s(styleOp, "..") + d.expr(p.High)
+	case *ast.TypePattern:
+		return d.s(styleType, ":"+p.TypeName)
+	default:
+		return d.s(styleNull, "?")
+	}
+}
+
 // ─── Operator symbol mapping ──────────────────────────────────────────────────
 
 // symOp converts English-phrased operator names (as stored in the AST) to their
@@ -18,11 +18,14 @@ func (d *disassembler) stmt(node ast.Statement) {
 	switch s := node.(type) {
 
 	case *ast.VariableDecl:
-		name := d.s(styleIdent, s.Name)
+		name := d.targets(s.Name, s.Names, s.Rest)
 		constTag := ""
 		if s.IsConstant {
 			constTag = " " + d.s(styleConst, "[const]")
 		}
+		if s.IsPrivate {
+			constTag += " " + d.s(styleConst, "[private]")
+		}
 		arrow := d.s(styleArrow, "←")
 		d.emit(styleOpcodeDecl, "DECLARE_VAR",
 			name+constTag+"  "+arrow+"  "+d.expr(s.Value))
@@ -34,6 +37,9 @@ func (d *disassembler) stmt(node ast.Statement) {
 		if s.IsConstant {
 			constTag = " " + d.s(styleConst, "[const]")
 		}
+		if s.IsPrivate {
+			constTag += " " + d.s(styleConst, "[private]")
+		}
 		arrow := d.s(styleArrow, "←")
 		d.emit(styleOpcodeDecl, "DECLARE_VAR",
 			name+typeTag+constTag+"  "+arrow+"  "+d.expr(s.Value))
@@ -46,21 +52,34 @@ func (d *disassembler) stmt(node ast.Statement) {
 		}
 		d.emit(styleOpcodeDecl, "DECL_ERROR_TYPE", name+parentPart)
 
+	case *ast.EnumDecl:
+		members := make([]string, len(s.Members))
+		for i, m := range s.Members {
+			members[i] = d.s(styleIdent, m)
+		}
+		d.emit(styleOpcodeDecl, "DECL_ENUM",
+			d.s(styleIdent, s.Name)+"  "+d.s(styleOp, "of")+"  "+strings.Join(members, ", "))
+
+	case *ast.CapabilityDecl:
+		methods := make([]string, len(s.Methods))
+		for i, m := range s.Methods {
+			methods[i] = d.s(styleIdent, m)
+		}
+		d.emit(styleOpcodeDecl, "DECL_CAPABILITY",
+			d.s(styleIdent, s.Name)+"  "+d.s(styleOp, "requiring")+"  "+strings.Join(methods, ", "))
+
 	case *ast.Assignment:
-		name := d.s(styleIdent, s.Name)
+		name := d.targets(s.Name, s.Names, s.Rest)
 		arrow := d.s(styleArrow, "←")
 		d.emit(styleOpcodeAssign, "ASSIGN", name+"  "+arrow+"  "+d.expr(s.Value))
 
 	case *ast.FunctionDecl:
-		params := make([]string, len(s.Parameters))
-		for i, p := range s.Parameters {
-			params[i] = d.s(styleIdent, p)
+		privateTag := ""
+		if s.IsPrivate {
+			privateTag = "  " + d.s(styleConst, "[private]")
 		}
-		paramStr := d.s(stylePunct, "(") +
-			strings.Join(params, d.s(stylePunct, ", ")) +
-			d.s(stylePunct, ")")
 		d.emit(styleOpcodeDecl, "FUNC_DECL",
-			d.s(styleLabel, s.Name)+"  "+paramStr)
+			d.s(styleLabel, s.Name)+"  "+d.paramList(s)+privateTag)
 		d.depth++
 		for _, child := range s.Body {
 			d.stmt(child)
@@ -73,7 +92,7 @@ func (d *disassembler) stmt(node ast.Statement) {
 	case *ast.CallStatement:
 		if s.FunctionCall != nil {
 			d.emit(styleOpcodeCall, "CALL",
-				d.s(styleLabel, s.FunctionCall.Name)+d.argList(s.FunctionCall.Arguments))
+				d.s(styleLabel, s.FunctionCall.Name)+d.argList(s.FunctionCall.Arguments, s.FunctionCall.NamedArguments))
 		}
 
 	case *ast.IfStatement:
@@ -101,8 +120,34 @@ func (d *disassembler) stmt(node ast.Statement) {
 		}
 		d.emitLabel(styleOpcodeEnd, fmt.Sprintf("%-18s", "END_IF"), "")
 
+	case *ast.WhenStatement:
+		d.emit(styleOpcodeControl, "WHEN", d.expr(s.Subject))
+		d.depth++
+		for _, wc := range s.Cases {
+			patterns := make([]string, len(wc.Patterns))
+			for i, pattern := range wc.Patterns {
+				patterns[i] = d.pattern(pattern)
+			}
+			d.emitLabel(styleOpcodeControl, fmt.Sprintf("%-18s", "CASE"), strings.Join(patterns, d.s(styleMeta, " | ")))
+			d.depth++
+			for _, child := range wc.Body {
+				d.stmt(child)
+			}
+			d.depth--
+		}
+		if len(s.Otherwise) > 0 {
+			d.emitLabel(styleOpcodeControl, fmt.Sprintf("%-18s", "OTHERWISE"), "")
+			d.depth++
+			for _, child := range s.Otherwise {
+				d.stmt(child)
+			}
+			d.depth--
+		}
+		d.depth--
+		d.emitLabel(styleOpcodeEnd, fmt.Sprintf("%-18s", "END_WHEN"), "")
+
 	case *ast.WhileLoop:
-		d.emit(styleOpcodeControl, "WHILE", d.expr(s.Condition))
+		d.emit(styleOpcodeControl, "WHILE", d.expr(s.Condition)+d.loopLabel(s.Label))
 		d.depth++
 		for _, child := range s.Body {
 			d.stmt(child)
@@ -111,7 +156,7 @@ func (d *disassembler) stmt(node ast.Statement) {
 		d.emitLabel(styleOpcodeEnd, fmt.Sprintf("%-18s", "END_WHILE"), "")
 
 	case *ast.ForLoop:
-		d.emit(styleOpcodeControl, "FOR_LOOP", d.expr(s.Count)+"  "+d.s(styleMeta, "times"))
+		d.emit(styleOpcodeControl, "FOR_LOOP", d.expr(s.Count)+"  "+d.s(styleMeta, "times")+d.loopLabel(s.Label))
 		d.depth++
 		for _, child := range s.Body {
 			d.stmt(child)
@@ -123,7 +168,7 @@ func (d *disassembler) stmt(node ast.Statement) {
 		item := d.s(styleIdent, s.Item)
 		list := d.expr(s.List)
 		d.emit(styleOpcodeControl, "FOR_EACH",
-			item+"  "+d.s(styleOp, "in")+"  "+list)
+			item+"  "+d.s(styleOp, "in")+"  "+list+d.loopLabel(s.Label))
 		d.depth++
 		for _, child := range s.Body {
 			d.stmt(child)
@@ -139,7 +184,44 @@ func (d *disassembler) stmt(node ast.Statement) {
 			listName+idxPart+"  "+arrow+"  "+d.expr(s.Value))
 
 	case *ast.ReturnStatement:
-		d.emit(styleOpcodeControl, "RETURN", d.expr(s.Value))
+		if len(s.Values) == 0 {
+			d.emit(styleOpcodeControl, "RETURN", d.expr(s.Value))
+			break
+		}
+		vals := make([]string, len(s.Values))
+		for i, v := range s.Values {
+			vals[i] = d.expr(v)
+		}
+		d.emit(styleOpcodeControl, "RETURN",
+			strings.Join(vals, d.s(stylePunct, ", ")))
+
+	case *ast.YieldStatement:
+		d.emit(styleOpcodeControl, "YIELD", d.expr(s.Value))
+
+	case *ast.BackgroundStatement:
+		d.emit(styleOpcodeControl, "BACKGROUND", d.s(styleIdent, s.Name))
+		d.depth++
+		for _, child := range s.Body {
+			d.stmt(child)
+		}
+		d.depth--
+		d.emitLabel(styleOpcodeEnd, fmt.Sprintf("%-18s", "END_BACKGROUND"), "")
+
+	case *ast.TestBlock:
+		d.emit(styleOpcodeControl, "TEST", d.s(styleStr, fmt.Sprintf("%q", s.Name)))
+		d.depth++
+		for _, child := range s.Body {
+			d.stmt(child)
+		}
+		d.depth--
+		d.emitLabel(styleOpcodeEnd, fmt.Sprintf("%-18s", "END_TEST"), "")
+
+	case *ast.WaitStatement:
+		d.emit(styleOpcodeControl, "WAIT", d.expr(s.Task))
+
+	case *ast.SendStatement:
+		arrow := d.s(styleArrow, "→")
+		d.emit(styleOpcodeIO, "SEND", d.expr(s.Value)+"  "+arrow+"  "+d.expr(s.Channel))
 
 	case *ast.OutputStatement:
 		opcode := "OUTPUT_PRINT"
@@ -157,10 +239,10 @@ func (d *disassembler) stmt(node ast.Statement) {
 		d.emit(styleOpcodeAssign, "TOGGLE", d.s(styleIdent, s.Name))
 
 	case *ast.BreakStatement:
-		d.emit(styleOpcodeControl, "BREAK", "")
+		d.emit(styleOpcodeControl, "BREAK", d.loopName(s.Label))
 
 	case *ast.ContinueStatement:
-		d.emit(styleOpcodeControl, "CONTINUE", "")
+		d.emit(styleOpcodeControl, "CONTINUE", d.loopName(s.Label))
 
 	case *ast.SwapStatement:
 		d.emit(styleOpcodeAssign, "SWAP",
@@ -173,6 +255,13 @@ func (d *disassembler) stmt(node ast.Statement) {
 		}
 		d.emit(styleOpcodeControl, "RAISE", d.expr(s.Message)+errType)
 
+	case *ast.AssertStatement:
+		msg := ""
+		if s.Message != nil {
+			msg = "  " + d.s(styleOp, "otherwise") + "  " + d.expr(s.Message)
+		}
+		d.emit(styleOpcodeControl, "ASSERT", d.expr(s.Condition)+msg)
+
 	case *ast.TryStatement:
 		d.emitLabel(styleOpcodeControl, fmt.Sprintf("%-18s", "TRY"), "")
 		d.depth++
@@ -210,7 +299,11 @@ func (d *disassembler) stmt(node ast.Statement) {
 		d.emitLabel(styleOpcodeEnd, fmt.Sprintf("%-18s", "END_TRY"), "")
 
 	case *ast.StructDecl:
-		d.emit(styleOpcodeDecl, "STRUCT_DECL", d.s(styleLabel, s.Name))
+		parentPart := ""
+		if s.Parent != "" {
+			parentPart = "  " + d.s(styleOp, "extends") + "  " + d.s(styleIdent, s.Parent)
+		}
+		d.emit(styleOpcodeDecl, "STRUCT_DECL", d.s(styleLabel, s.Name)+parentPart)
 		d.depth++
 		for _, f := range s.Fields {
 			typeTag := d.s(styleType, ":"+f.TypeName)
@@ -223,16 +316,9 @@ func (d *disassembler) stmt(node ast.Statement) {
 				d.s(styleIdent, f.Name)+typeTag+defPart)
 		}
 		for _, m := range s.Methods {
-			params := make([]string, len(m.Parameters))
-			for i, p := range m.Parameters {
-				params[i] = d.s(styleIdent, p)
-			}
-			paramStr := d.s(stylePunct, "(") +
-				strings.Join(params, d.s(stylePunct, ", ")) +
-				d.s(stylePunct, ")")
 			d.emitLabel(styleOpcodeDecl,
 				fmt.Sprintf("%-18s", "METHOD"),
-				d.s(styleLabel, m.Name)+paramStr)
+				d.s(styleLabel, m.Name)+d.paramList(m))
 		}
 		d.depth--
 		d.emitLabel(styleOpcodeEnd, fmt.Sprintf("%-18s", "END_STRUCT"), d.s(styleMeta, s.Name))
@@ -265,9 +351,46 @@ func (d *disassembler) stmt(node ast.Statement) {
 				strings.Join(items, d.s(stylePunct, ", ")) +
 				d.s(stylePunct, ")")
 		}
+		if s.Alias != "" {
+			detail += "  " + d.s(styleMeta, "as") + " " + d.s(styleIdent, s.Alias)
+		}
 		if s.IsSafe {
 			detail += "  " + d.s(styleConst, "[safe]")
 		}
 		d.emit(styleOpcodeDecl, "IMPORT", path+detail)
 	}
 }
+
+// targets renders the variable a declaration or assignment stores into, or
+// the comma-separated names of a destructuring one, the last marked with
+// "..." when it collects the rest.
+func (d *disassembler) targets(name string, names []string, rest bool) string {
+	if len(names) == 0 {
+		return d.s(styleIdent, name)
+	}
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = d.s(styleIdent, n)
+	}
+	if rest {
+		parts[len(parts)-1] = d.s(stylePunct, "...") + parts[len(parts)-1]
+	}
+	return strings.Join(parts, d.s(stylePunct, ", "))
+}
+
+// loopLabel renders the name a loop was given with "and call this loop",
+// if any.
+func (d *disassembler) loopLabel(label string) string {
+	if label == "" {
+		return ""
+	}
+	return "  " + d.s(styleMeta, "called") + "  " + d.loopName(label)
+}
+
+// loopName renders the loop a break or continue names, if any.
+func (d *disassembler) loopName(label string) string {
+	if label == "" {
+		return ""
+	}
+	return d.s(styleLabel, label)
+}
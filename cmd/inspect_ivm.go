@@ -74,7 +74,7 @@ displayed; the listing is always derived from the opcode stream.`,
 				stacktraces.Print(parseErr)
 				os.Exit(1)
 			}
-			typeErrs := vm.Check(program, stdlib.PredefinedNames()...)
+			typeErrs := vm.CheckFile(program, filename, stdlib.PredefinedNames()...)
 			if len(typeErrs) > 0 {
 				for _, e := range typeErrs {
 					stacktraces.Print(e)
@@ -7,6 +7,7 @@ import (
 	"github.com/Advik-B/english/highlight"
 	"github.com/Advik-B/english/ivm"
 	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 	"github.com/Advik-B/english/stacktraces"
 	"github.com/Advik-B/english/transpiler"
 	"github.com/Advik-B/english/astvm"
@@ -52,6 +53,7 @@ var runCmd = &cobra.Command{
 		vmFlag, _ := cmd.Flags().GetString("vm")
 		minPoliteness, _ := cmd.Flags().GetFloat64("minimum-politeness")
 		politeFlag, _ := cmd.Flags().GetBool("polite")
+		noAssertions, _ := cmd.Flags().GetBool("no-assertions")
 		// --polite is a convenience shorthand for --minimum-politeness 100.
 		// --minimum-politeness takes precedence when both are provided.
 		if politeFlag && !cmd.Flags().Changed("minimum-politeness") {
@@ -60,12 +62,12 @@ var runCmd = &cobra.Command{
 		ext := strings.ToLower(filepath.Ext(filename))
 		if ext == ".101" {
 			// Politeness only applies to .abc source files.
-			RunBytecode(filename)
+			RunBytecode(filename, noAssertions)
 		} else {
 			if strings.EqualFold(vmFlag, "ast") {
-				RunFileAST(filename, minPoliteness)
+				RunFileAST(filename, minPoliteness, noAssertions)
 			} else {
-				RunFileIVM(filename, minPoliteness)
+				RunFileIVM(filename, minPoliteness, noAssertions)
 			}
 		}
 	},
@@ -246,19 +248,22 @@ func init() {
 	runCmd.Flags().Bool("polite", false,
 		"Require all statements to be polite (equivalent to --minimum-politeness 100). "+
 			"Only applies to .abc source files.")
+	runCmd.Flags().Bool("no-assertions", false,
+		"Skip every 'Make sure that' check, as for a production run.")
 }
 
 // RunFile executes an English source file using the instruction VM (ivm) by default.
 // This is a convenience wrapper for RunFileIVM.
 func RunFile(filename string) {
-	RunFileIVM(filename, -1)
+	RunFileIVM(filename, -1, false)
 }
 
 // RunFileIVM parses and executes an English source file via the instruction-based VM.
 // It is the default execution path for .abc source files.
 // minPoliteness is the minimum required politeness percentage (0–100); pass a
-// negative value to disable the check.
-func RunFileIVM(filename string, minPoliteness float64) {
+// negative value to disable the check. noAssertions skips every "Make sure
+// that" check.
+func RunFileIVM(filename string, minPoliteness float64, noAssertions bool) {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
@@ -284,7 +289,7 @@ func RunFileIVM(filename string, minPoliteness float64) {
 		}
 	}
 
-	typeErrs := vm.Check(program, stdlib.PredefinedNames()...)
+	typeErrs := vm.CheckFile(program, filename, stdlib.PredefinedNames()...)
 	if len(typeErrs) > 0 {
 		for _, e := range typeErrs {
 			stacktraces.Print(e)
@@ -298,18 +303,26 @@ func RunFileIVM(filename string, minPoliteness float64) {
 		os.Exit(1)
 	}
 
-	_, execErr := ivm.Execute(chunk, stdlib.Eval, stdlib.PredefinedValues())
+	_, execErr := executeChunk(chunk, filename, noAssertions)
 	if execErr != nil {
 		stacktraces.Print(execErr)
 		os.Exit(1)
 	}
 }
 
+// executeChunk runs a compiled program on the instruction VM, with or without
+// its assertions. file is the source file it was compiled from, or "" when
+// there is none.
+func executeChunk(chunk *ivm.Chunk, file string, noAssertions bool) (interface{}, error) {
+	return ivm.ExecuteFile(chunk, file, stdlib.Eval, stdlib.PredefinedValues(), noAssertions)
+}
+
 // RunFileAST parses and executes an English source file via the tree-walk evaluator.
 // Use the --vm=ast flag on the run command to select this path.
 // minPoliteness is the minimum required politeness percentage (0–100); pass a
-// negative value to disable the check.
-func RunFileAST(filename string, minPoliteness float64) {
+// negative value to disable the check. noAssertions skips every "Make sure
+// that" check.
+func RunFileAST(filename string, minPoliteness float64, noAssertions bool) {
 	content, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
@@ -337,7 +350,7 @@ func RunFileAST(filename string, minPoliteness float64) {
 		}
 	}
 
-	typeErrs := vm.Check(program, stdlib.PredefinedNames()...)
+	typeErrs := vm.CheckFile(program, filename, stdlib.PredefinedNames()...)
 	if len(typeErrs) > 0 {
 		for _, e := range typeErrs {
 			stacktraces.Print(e)
@@ -346,6 +359,10 @@ func RunFileAST(filename string, minPoliteness float64) {
 	}
 
 	evaluator := vm.NewEvaluator(env, stdlib.Eval)
+	evaluator.SetFile(filename)
+	if noAssertions {
+		evaluator.DisableAssertions()
+	}
 	_, err = evaluator.Eval(program)
 	if err != nil {
 		stacktraces.Print(err)
@@ -380,7 +397,7 @@ func CompileFileOptions(filename string, output string, stripSource bool) {
 		os.Exit(1)
 	}
 
-	typeErrs := vm.Check(program, stdlib.PredefinedNames()...)
+	typeErrs := vm.CheckFile(program, filename, stdlib.PredefinedNames()...)
 	if len(typeErrs) > 0 {
 		for _, e := range typeErrs {
 			stacktraces.Print(e)
@@ -439,10 +456,14 @@ func TranspileFile(filename string) {
 // transpileWithOptions is the recursive worker for TranspileFile.
 // 'seen' prevents duplicate transpilation and infinite loops for circular imports.
 func transpileWithOptions(filename string, inline bool, seen map[string]bool) {
-	if seen[filename] {
+	key := filename
+	if abs, err := filepath.Abs(filename); err == nil {
+		key = abs
+	}
+	if seen[key] {
 		return
 	}
-	seen[filename] = true
+	seen[key] = true
 
 	ext := strings.ToLower(filepath.Ext(filename))
 
@@ -481,7 +502,7 @@ func transpileWithOptions(filename string, inline bool, seen map[string]bool) {
 					stacktraces.Print(parseErr)
 					os.Exit(1)
 				}
-				typeErrs := vm.Check(prog, stdlib.PredefinedNames()...)
+				typeErrs := vm.CheckFile(prog, filename, stdlib.PredefinedNames()...)
 				if len(typeErrs) > 0 {
 					for _, e := range typeErrs {
 						stacktraces.Print(e)
@@ -489,15 +510,19 @@ func transpileWithOptions(filename string, inline bool, seen map[string]bool) {
 					os.Exit(1)
 				}
 				if inline {
-					pySource = transpiler.NewTranspilerInlined().Transpile(prog)
+					tr := transpiler.NewTranspilerInlined().WithSourceFile(compiledFrom(filename))
+					pySource = tr.Transpile(prog)
+					if err := tr.Err(); err != nil {
+						stacktraces.Print(err)
+						os.Exit(1)
+					}
 				} else {
-					sourceDir := filepath.Dir(filename)
-					pySource = transpiler.NewTranspiler().WithSourceDir(sourceDir).Transpile(prog)
+					pySource = transpiler.NewTranspiler().WithSourceFile(filename).Transpile(prog)
 				}
 			} else {
 				// Fallback: decompile from pure opcode stream.
 				// Comments are lost but all logic is preserved.
-				pySource = ivm.Decompile(chunk)
+				pySource = ivm.DecompileFile(chunk, filename)
 			}
 			if writeErr := os.WriteFile(output, []byte(pySource), 0644); writeErr != nil {
 				fmt.Fprintf(os.Stderr, "Error writing Python file: %v\n", writeErr)
@@ -514,7 +539,7 @@ func transpileWithOptions(filename string, inline bool, seen map[string]bool) {
 			os.Exit(1)
 		}
 
-		typeErrs := vm.Check(prog, stdlib.PredefinedNames()...)
+		typeErrs := vm.CheckFile(prog, filename, stdlib.PredefinedNames()...)
 		if len(typeErrs) > 0 {
 			for _, e := range typeErrs {
 				stacktraces.Print(e)
@@ -540,7 +565,7 @@ func transpileWithOptions(filename string, inline bool, seen map[string]bool) {
 			os.Exit(1)
 		}
 
-		typeErrs := vm.Check(prog, stdlib.PredefinedNames()...)
+		typeErrs := vm.CheckFile(prog, filename, stdlib.PredefinedNames()...)
 		if len(typeErrs) > 0 {
 			for _, e := range typeErrs {
 				stacktraces.Print(e)
@@ -550,23 +575,33 @@ func transpileWithOptions(filename string, inline bool, seen map[string]bool) {
 
 		if inline {
 			// --inline: resolve all imports by inlining their ASTs into a single file.
-			pySource = transpiler.NewTranspilerInlined().Transpile(prog)
+			tr := transpiler.NewTranspilerInlined().WithSourceFile(filename)
+			pySource = tr.Transpile(prog)
+			if err := tr.Err(); err != nil {
+				stacktraces.Print(err)
+				os.Exit(1)
+			}
 		} else {
-			// Default: recursively transpile each imported .abc file to its own .py
+			// Default: recursively transpile each imported file to its own .py
 			// file, then emit "from module import *" statements in the main output.
-			// Only files with an explicit ".abc" extension are transpiled; other
-			// import paths (e.g. bare "math") are left for Python to resolve.
+			// Imports are resolved as the VMs resolve them, so a library named
+			// without its extension ("strings/slugify") is transpiled too; paths
+			// that resolve to no file (e.g. bare "math") are left for Python.
 			for _, stmt := range prog.Statements {
 				imp, ok := stmt.(*ast.ImportStatement)
 				if !ok {
 					continue
 				}
-				if strings.ToLower(filepath.Ext(imp.Path)) == ".abc" {
-					transpileWithOptions(imp.Path, false, seen)
+				file, err := project.ResolveImport(filename, imp.Path)
+				if err != nil {
+					if strings.ToLower(filepath.Ext(imp.Path)) != ".abc" {
+						continue
+					}
+					file = imp.Path
 				}
+				transpileWithOptions(file, false, seen)
 			}
-			sourceDir := filepath.Dir(filename)
-			pySource = transpiler.NewTranspiler().WithSourceDir(sourceDir).Transpile(prog)
+			pySource = transpiler.NewTranspiler().WithSourceFile(filename).Transpile(prog)
 		}
 	}
 
@@ -578,7 +613,10 @@ func transpileWithOptions(filename string, inline bool, seen map[string]bool) {
 
 	fmt.Printf("Transpiled %s -> %s\n", filename, output)
 }
-func RunBytecode(filename string) {
+
+// RunBytecode executes a compiled .101 file. noAssertions skips every "Make
+// sure that" check.
+func RunBytecode(filename string, noAssertions bool) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading file: %v\n", err)
@@ -592,7 +630,7 @@ func RunBytecode(filename string) {
 			fmt.Fprintf(os.Stderr, "Bytecode error: %v\n", decodeErr)
 			os.Exit(1)
 		}
-		_, execErr := ivm.Execute(chunk, stdlib.Eval, stdlib.PredefinedValues())
+		_, execErr := executeChunk(chunk, compiledFrom(filename), noAssertions)
 		if execErr != nil {
 			stacktraces.Print(execErr)
 			os.Exit(1)
@@ -611,6 +649,10 @@ func RunBytecode(filename string) {
 	env := vm.NewEnvironment()
 	stdlib.Register(env)
 	evaluator := vm.NewEvaluator(env, stdlib.Eval)
+	evaluator.SetFile(compiledFrom(filename))
+	if noAssertions {
+		evaluator.DisableAssertions()
+	}
 	_, err = evaluator.Eval(program)
 	if err != nil {
 		stacktraces.Print(err)
@@ -618,6 +660,13 @@ func RunBytecode(filename string) {
 	}
 }
 
+// compiledFrom returns the source file the .101 file filename was compiled
+// from. A program run from bytecode is that file as far as its imports are
+// concerned: a file importing it back is a circular import, not a second run.
+func compiledFrom(filename string) string {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + ".abc"
+}
+
 // checkPoliteness verifies that the program meets the minimum politeness
 // percentage.  It returns one *parser.SyntaxError per impolite statement when
 // the threshold is not met, or nil when the program is sufficiently polite.
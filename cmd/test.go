@@ -0,0 +1,346 @@
+package cmd
+
+// test.go – "english test [paths]" command
+//
+// Finds the test blocks in English source files and runs each one on its own:
+//
+//	Test "adding works" by doing the following:
+//	    Make sure that 1 + 1 is equal to 2.
+//	thats it.
+//
+// A test passes when its body runs to the end, fails when a "Make sure that"
+// check in it does not hold (an AssertionError), and is in error when
+// anything else goes wrong.
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/astvm"
+	"github.com/Advik-B/english/astvm/types"
+	"github.com/Advik-B/english/ivm"
+	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/stacktraces"
+	"github.com/Advik-B/english/stdlib"
+
+	"github.com/spf13/cobra"
+)
+
+var testRun string
+var testJUnit string
+
+var testCmd = &cobra.Command{
+	Use:   "test [paths]",
+	Short: "Run the test blocks in English source files",
+	Long: `Find every 'Test "name" by doing the following:' block in the given .abc
+files, or in the .abc files under the given directories (the current directory
+by default), and run each one.
+
+Every test runs in a fresh environment: the file's declarations and imports
+run first, then the test's body. The rest of the file's top-level code does not
+run, and nothing one test changes is seen by another.
+
+A test fails when a 'Make sure that' check in it does not hold, and is in error
+when anything else goes wrong. The command exits with status 1 if any test
+failed or was in error.
+
+  --run PATTERN   only run tests whose name matches the regular expression
+  --junit FILE    also write the results as JUnit XML, for CI servers`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		var filter *regexp.Regexp
+		if testRun != "" {
+			re, err := regexp.Compile(testRun)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid --run pattern: %v\n", err)
+				os.Exit(1)
+			}
+			filter = re
+		}
+
+		files, err := findTestFiles(args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		start := time.Now()
+		var suites []testSuite
+		for _, file := range files {
+			suites = append(suites, runTestFile(file, filter))
+		}
+		passed, failed, errored := printTestSummary(suites, time.Since(start))
+
+		if testJUnit != "" {
+			if err := writeJUnit(testJUnit, suites); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JUnit report: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if passed+failed+errored == 0 {
+			fmt.Println("No tests found.")
+		}
+		if failed+errored > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// testOutcome is how a test ended.
+type testOutcome int
+
+const (
+	testPassed testOutcome = iota
+	testFailed
+	testErrored
+)
+
+type testResult struct {
+	name     string
+	outcome  testOutcome
+	err      error
+	duration time.Duration
+}
+
+// testSuite holds the results of the tests in one file. err is set when the
+// file could not be read, parsed or checked, and none of its tests ran.
+type testSuite struct {
+	file     string
+	results  []testResult
+	err      error
+	duration time.Duration
+}
+
+// findTestFiles expands paths into the .abc files to test: a file is taken
+// as it is, and a directory is searched recursively.
+func findTestFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".abc") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// runTestFile parses and checks file, then runs each of its tests whose name
+// matches filter (every test when filter is nil), printing the outcome of
+// each as it goes.
+func runTestFile(file string, filter *regexp.Regexp) testSuite {
+	suite := testSuite{file: file}
+	start := time.Now()
+	defer func() { suite.duration = time.Since(start) }()
+
+	prog, err := loadTestFile(file)
+	if err != nil {
+		suite.err = err
+		fmt.Printf("ERROR %s\n", file)
+		printIndented(stacktraces.Render(err))
+		return suite
+	}
+
+	for _, test := range ivm.Tests(prog) {
+		if filter != nil && !filter.MatchString(test.Name) {
+			continue
+		}
+		began := time.Now()
+		err := ivm.RunTestFile(prog, file, test, stdlib.Eval, stdlib.PredefinedValues())
+		result := testResult{name: test.Name, err: err, duration: time.Since(began)}
+		label := "PASS "
+		switch {
+		case err == nil:
+			result.outcome = testPassed
+		case isAssertionError(err):
+			result.outcome = testFailed
+			label = "FAIL "
+		default:
+			result.outcome = testErrored
+			label = "ERROR"
+		}
+		suite.results = append(suite.results, result)
+
+		fmt.Printf("%s %s: %s (%s)\n", label, file, test.Name, result.duration.Round(time.Microsecond))
+		if err != nil {
+			printIndented(stacktraces.Render(err))
+		}
+	}
+	return suite
+}
+
+// loadTestFile reads, parses and checks an English source file.
+func loadTestFile(file string) (*ast.Program, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := parser.NewParser(parser.NewLexer(string(content)).TokenizeAll()).Parse()
+	if err != nil {
+		return nil, err
+	}
+	if errs := vm.CheckFile(prog, file, stdlib.PredefinedNames()...); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return prog, nil
+}
+
+func isAssertionError(err error) bool {
+	var ev *types.ErrorValue
+	return errors.As(err, &ev) && ev.ErrorType == "AssertionError"
+}
+
+func printIndented(text string) {
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		fmt.Println("    " + line)
+	}
+}
+
+// printTestSummary prints the totals and returns them.
+func printTestSummary(suites []testSuite, elapsed time.Duration) (passed, failed, errored int) {
+	for _, s := range suites {
+		if s.err != nil {
+			errored++
+		}
+		for _, r := range s.results {
+			switch r.outcome {
+			case testPassed:
+				passed++
+			case testFailed:
+				failed++
+			case testErrored:
+				errored++
+			}
+		}
+	}
+	if passed+failed+errored > 0 {
+		fmt.Printf("\n%d passed, %d failed, %d in error (%s)\n", passed, failed, errored, elapsed.Round(time.Microsecond))
+	}
+	return passed, failed, errored
+}
+
+// ─── JUnit XML ────────────────────────────────────────────────────────────────
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Details string `xml:",chardata"`
+}
+
+// writeJUnit writes the results as JUnit XML: a testsuite per file and a
+// testcase per test. A file that could not be loaded becomes a single
+// testcase in error.
+func writeJUnit(path string, suites []testSuite) error {
+	report := junitTestSuites{}
+	for _, s := range suites {
+		js := junitTestSuite{Name: s.file, Time: junitSeconds(s.duration)}
+		if s.err != nil {
+			js.Cases = append(js.Cases, junitTestCase{
+				Name:      filepath.Base(s.file),
+				Classname: s.file,
+				Time:      junitSeconds(s.duration),
+				Error:     junitProblemFor(s.err),
+			})
+			js.Errors++
+		}
+		for _, r := range s.results {
+			tc := junitTestCase{Name: r.name, Classname: s.file, Time: junitSeconds(r.duration)}
+			switch r.outcome {
+			case testFailed:
+				tc.Failure = junitProblemFor(r.err)
+				js.Failures++
+			case testErrored:
+				tc.Error = junitProblemFor(r.err)
+				js.Errors++
+			}
+			js.Cases = append(js.Cases, tc)
+		}
+		js.Tests = len(js.Cases)
+		report.Tests += js.Tests
+		report.Failures += js.Failures
+		report.Errors += js.Errors
+		report.Suites = append(report.Suites, js)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
+
+func junitProblemFor(err error) *junitProblem {
+	errType := "Error"
+	var ev *types.ErrorValue
+	if errors.As(err, &ev) {
+		errType = ev.ErrorType
+	}
+	details := stacktraces.RenderWithColor(err, false)
+	message := strings.SplitN(strings.TrimSpace(details), "\n", 2)[0]
+	return &junitProblem{Message: message, Type: errType, Details: details}
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func init() {
+	testCmd.Flags().StringVarP(&testRun, "run", "r", "",
+		"Only run tests whose name matches this regular expression")
+	testCmd.Flags().StringVar(&testJUnit, "junit", "",
+		"Also write the results to this file as JUnit XML")
+	rootCmd.AddCommand(testCmd)
+}
@@ -15,12 +15,16 @@ package feature_parity_test
 
 import (
 	"bytes"
+	"errors"
 	"github.com/Advik-B/english/astvm"
+	"github.com/Advik-B/english/astvm/types"
 	"github.com/Advik-B/english/stdlib"
 	"github.com/Advik-B/english/ivm"
 	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -197,6 +201,46 @@ func TestParityModulo(t *testing.T) {
 	assertParity(t, `Print the remainder of 17 divided by 5.`)
 }
 
+func TestParityPowerAndFloorDivision(t *testing.T) {
+	assertParity(t, `Print 2 to the power of 10.
+Print 2 to the power of 3 to the power of 2.
+Print 3 * 2 to the power of 2.
+Print 4 to the power of 0.5.
+Print 7 divided evenly by 2.
+Print -7 divided evenly by 2.
+Print 7.5 divided evenly by 2.`)
+}
+
+func TestParityBitwise(t *testing.T) {
+	assertParity(t, `Declare flags to be 12.
+Print flags bitwise and 10.
+Print flags bitwise or 3.
+Print flags bitwise xor 10.
+Print 1 shifted left by 4.
+Print 256 shifted right by 2 bitwise or 1.
+Print 1 + 1 shifted left by 2.`)
+}
+
+func TestParityFloorDivisionByZero(t *testing.T) {
+	assertParityError(t, `Print 1 divided evenly by 0.`)
+}
+
+func TestParityBitwiseRequiresWholeNumbers(t *testing.T) {
+	assertParityError(t, `Print 1.5 bitwise and 1.`)
+}
+
+func TestParityShiftOverflow(t *testing.T) {
+	assertParityError(t, `Print 1 shifted left by 70.`)
+	assertParityError(t, `Print 3 shifted left by 62.`)
+	assertParity(t, `Print 1 shifted left by 62.
+Print -1 shifted left by 62.
+Try doing the following:
+    Print 1 shifted left by 64.
+on OverflowError:
+    Print "caught", error.
+thats it.`)
+}
+
 func TestParityCompoundArithmetic(t *testing.T) {
 	assertParity(t, `Declare x to be (3 + 4) * 2.
 Print x.`)
@@ -392,6 +436,85 @@ thats it.
 Print total.`)
 }
 
+func TestParityLoopLabels(t *testing.T) {
+	assertOutputContains(t, `Declare i to be 0.
+Repeat the following while i is less than 3 and call this loop outer:
+    Set i to be i + 1.
+    Declare j to be 0.
+    Repeat the following while j is less than 3:
+        Set j to be j + 1.
+        If j is equal to 2, then
+            Continue outer.
+        thats it.
+        If i is equal to 3, then
+            Break out of outer.
+        thats it.
+        Print i, j.
+    thats it.
+    Print "unreachable".
+thats it.
+Print "done".`, "1 1\n2 1\ndone\n")
+
+	assertOutputContains(t, `Declare found to be 0.
+For each a in [1, 2, 3], do the following and call this loop first:
+    Repeat the following 2 times and call this loop second:
+        For each b in [1, 2, 3], do the following:
+            If b is equal to a, then
+                Continue first.
+            thats it.
+            If a is equal to 3, then
+                Break out of first.
+            thats it.
+            Set found to be found + 1.
+        thats it.
+        Set found to be found + 100.
+    thats it.
+    Set found to be found + 100.
+thats it.
+Print "found", found.`, "found 1\n")
+
+	// A label naming the innermost loop acts like a plain break or continue.
+	assertOutputContains(t, `Declare total to be 0.
+Repeat the following 5 times and call this loop count:
+    Set total to be total + 1.
+    If total is equal to 2, then
+        Continue count.
+    thats it.
+    If total is equal to 4, then
+        Break out of count.
+    thats it.
+    Print total.
+thats it.`, "1\n3\n")
+}
+
+func TestParityLoopLabelsLeavingTry(t *testing.T) {
+	// Jumping out of a Try must drop its handler, so the error raised after
+	// the loops reaches the outer handler rather than the one left behind.
+	assertOutputContains(t, `Try doing the following:
+    For each i in [1, 2], do the following and call this loop outer:
+        For each j in [1, 2], do the following:
+            Try doing the following:
+                Declare k to be j.
+                Continue outer.
+            on error:
+                Print "inner".
+            thats it.
+        thats it.
+    thats it.
+    Repeat the following 2 times:
+        Try doing the following:
+            Break out of this loop.
+        on error:
+            Print "inner".
+        thats it.
+    thats it.
+    Raise "boom".
+on error:
+    Print "outer".
+thats it.
+Print "done".`, "outer\ndone\n")
+}
+
 // ─── Functions ───────────────────────────────────────────────────────────────
 
 func TestParityFunctionDeclaration(t *testing.T) {
@@ -430,6 +553,87 @@ done.
 Print fib(10).`)
 }
 
+func TestParityFunctionLiteral(t *testing.T) {
+	assertParity(t, `Declare double to be a function that takes x and returns x * 2.
+Print double(21).
+Print double.`)
+}
+
+func TestParityFunctionLiteralBlock(t *testing.T) {
+	assertParity(t, `Declare shout to be a function that takes word and does the following:
+    Print "shouting".
+    Return word + "!".
+thats it.
+Print shout("hey").`)
+}
+
+func TestParityFunctionsInList(t *testing.T) {
+	assertParity(t, `Declare function triple that takes n and does the following:
+    Return n * 3.
+thats it.
+Declare ops to be [a function that takes n and returns n + 1, triple].
+For each op in ops, do the following:
+    Print op(5).
+thats it.`)
+}
+
+func TestParityFunctionLiteralArity(t *testing.T) {
+	assertParityError(t, `Declare add to be a function that takes a and b and returns a + b.
+Print add(1).`)
+}
+
+// ─── Closures ────────────────────────────────────────────────────────────────
+
+func TestParityClosureCounter(t *testing.T) {
+	assertOutputContains(t, `Declare function make_counter that does the following:
+    Declare count to be 0.
+    Declare function increment that does the following:
+        Set count to be count + 1.
+        Return count.
+    thats it.
+    Return increment.
+thats it.
+Declare first to be make_counter().
+Declare second to be make_counter().
+Print first().
+Print first().
+Print second().`, "1\n2\n1\n")
+}
+
+func TestParityClosureOverParameter(t *testing.T) {
+	assertOutputContains(t, `Declare function make_adder that takes n and does the following:
+    Return a function that takes x and returns x + n.
+thats it.
+Declare add5 to be make_adder(5).
+Declare add10 to be make_adder(10).
+Print add5(1).
+Print add10(1).`, "6\n11\n")
+}
+
+func TestParityClosureOutlivesCall(t *testing.T) {
+	assertOutputContains(t, `Declare function outer that does the following:
+    Declare secret to be "kept".
+    Declare function reveal that does the following:
+        Print secret.
+    thats it.
+    Return reveal.
+thats it.
+Declare r to be outer().
+Call r.`, "kept\n")
+}
+
+func TestParityClosureIsLexical(t *testing.T) {
+	// A function sees the scope it was defined in, not its caller's locals.
+	assertParityError(t, `Declare function show that does the following:
+    Print hidden.
+thats it.
+Declare function caller that does the following:
+    Declare hidden to be "caller local".
+    Call show.
+thats it.
+Call caller.`)
+}
+
 // ─── Lists (Arrays) ──────────────────────────────────────────────────────────
 
 func TestParityListAccess(t *testing.T) {
@@ -522,6 +726,925 @@ increment(c).
 Print c's value.`)
 }
 
+func TestParityIntegerFieldInstantiation(t *testing.T) {
+	assertParity(t, `declare Counter as a structure with the following fields:
+    count is an unsigned integer.
+    total is an integer with 10 being the default.
+thats it.
+Declare c to be a new instance of Counter with the following fields:
+    total is 7.
+    count is 3.
+thats it.
+Print c's count.
+Print c's total.
+Set c's count to be (c's count) + 1.
+If c's count is equal to 4, then
+    Print "equal".
+thats it.`)
+}
+
+func TestParityIntegerFieldRejectsFraction(t *testing.T) {
+	assertParity(t, `declare Meter as a structure with the following fields:
+    reading is an unsigned integer.
+thats it.
+Declare m to be a new instance of Meter.
+Try doing the following:
+    Set m's reading to be 2.5.
+on TypeError:
+    Print error.
+thats it.`)
+}
+
+func TestParityIntegerFieldOverflow(t *testing.T) {
+	assertParity(t, `declare Meter as a structure with the following fields:
+    reading is an unsigned integer.
+thats it.
+Declare m to be a new instance of Meter.
+Try doing the following:
+    Set m's reading to be -1.
+on OverflowError:
+    Print error.
+thats it.
+Try doing the following:
+    Set m's reading to be (m's reading) - 5.
+on OverflowError:
+    Print error.
+thats it.`)
+}
+
+func TestParityIntegerFieldWrapping(t *testing.T) {
+	assertParity(t, `declare Meter as a structure with the following fields:
+    reading is an unsigned integer.
+thats it.
+Declare m to be a new instance of Meter.
+Use wrapping arithmetic.
+Set m's reading to be (m's reading) - 1.
+Print m's reading.`)
+}
+
+func TestParityIntegerFieldMethodWriteBack(t *testing.T) {
+	assertParity(t, `declare Meter as a structure with the following fields:
+    reading is an unsigned integer.
+
+    let rewind be a function that does the following:
+        Set reading to be 0.5.
+    thats it.
+thats it.
+Declare m to be a new instance of Meter.
+Try doing the following:
+    Call m's rewind.
+on TypeError:
+    Print "rejected".
+thats it.`)
+}
+
+func TestParityStructInheritance(t *testing.T) {
+	assertParity(t, `declare Animal as a structure with the following fields:
+    name is a string.
+    sound is a string with "..." being the default.
+
+    let speak be a function that does the following:
+        Print name, "says", sound.
+    thats it.
+
+    let describe be a function that does the following:
+        Print "an animal called", name.
+    thats it.
+thats it.
+declare Dog as a kind of Animal with the following fields:
+    sound is a string with "woof" being the default.
+    tricks is a number.
+
+    let describe be a function that does the following:
+        Print name, "knows", tricks, "tricks".
+    thats it.
+thats it.
+Declare d to be a new instance of Dog with the following fields:
+    name is "Rex".
+    tricks is 3.
+thats it.
+Call d's speak.
+Call d's describe.
+Set d's name to be "Max".
+Call d's speak.
+Print the type of d.
+Declare a to be a new instance of Animal.
+Call a's speak.`)
+}
+
+func TestParityStructInheritanceIsA(t *testing.T) {
+	assertParity(t, `declare Animal as a structure with the following fields:
+    name is a string.
+thats it.
+declare Dog as a kind of Animal with the following fields:
+    breed is a string.
+thats it.
+declare Puppy as a kind of Dog with the following fields:
+    age is an unsigned integer.
+thats it.
+Declare p to be a new instance of Puppy.
+Declare a to be a new instance of Animal.
+If p is a Animal, then
+    Print "puppy is an animal".
+thats it.
+If p is a Dog, then
+    Print "puppy is a dog".
+thats it.
+If a is a Dog, then
+    Print "wrong".
+thats it.
+When p is a Dog:
+    Print "matched Dog".
+thats it.
+Try doing the following:
+    Set p's age to be -1.
+on OverflowError:
+    Print "age is still checked".
+thats it.`)
+}
+
+func TestParityStructInheritanceUnknownParent(t *testing.T) {
+	assertParity(t, `declare Dog as a kind of Animal with the following fields:
+    breed is a string.
+thats it.`)
+}
+
+// ─── Capabilities ────────────────────────────────────────────────────────────
+
+const capabilityPrelude = `Declare Speaker as a capability requiring a function speak.
+declare Dog as a structure that can Speaker with the following fields:
+    name is a string with "Rex" being the default.
+    let speak be a function that does the following:
+        Print name, "says woof".
+    thats it.
+thats it.
+declare Puppy as a kind of Dog with the following fields:
+    age is a number.
+thats it.
+declare Cat as a structure with the following fields:
+    let speak be a function that does the following:
+        Print "meow".
+    thats it.
+thats it.
+Declare function greet that takes pet as anything that can Speaker and does the following:
+    Call pet's speak.
+thats it.
+`
+
+func TestParityCapabilityArgument(t *testing.T) {
+	assertParity(t, capabilityPrelude+`Declare d to be a new instance of Dog.
+Call greet with d.
+Declare p to be a new instance of Puppy.
+Call greet with p.`)
+}
+
+func TestParityCapabilityMismatch(t *testing.T) {
+	assertParity(t, capabilityPrelude+`Declare c to be a new instance of Cat.
+Try doing the following:
+    Call greet with c.
+on TypeError:
+    Print error.
+thats it.
+Try doing the following:
+    Call greet with "Rex".
+on TypeError:
+    Print error.
+thats it.`)
+}
+
+func TestParityCapabilityMissingFunction(t *testing.T) {
+	assertParityError(t, `Declare Speaker as a capability requiring a function speak and a function listen.
+declare Dog as a structure that can Speaker with the following fields:
+    let speak be a function that does the following:
+        Print "woof".
+    thats it.
+thats it.`)
+}
+
+// ─── Default Parameters and Named Arguments ──────────────────────────────────
+
+const greetPrelude = `Declare function greet that takes name and greeting (defaulting to "Hello") and does the following:
+    Print greeting, name.
+thats it.
+`
+
+func TestParityDefaultParameters(t *testing.T) {
+	assertOutputContains(t, greetPrelude+`Call greet with "Ann".
+Call greet with "Bo" and "Hi".
+Declare base to be 10.
+Declare function shift that takes x and amount (defaulting to base) and does the following:
+    Return x + amount.
+thats it.
+Set base to be 20.
+Print shift(1).`, "Hello Ann\nHi Bo\n21\n")
+}
+
+func TestParityDefaultUsesEarlierParameter(t *testing.T) {
+	assertOutputContains(t, `Declare function pair that takes a and b (defaulting to a * 2) and does the following:
+    Print a, b.
+thats it.
+Call pair with 3.
+Call pair with 3 and 4.
+Call pair with b as 1 and a as 5.`, "3 6\n3 4\n5 1\n")
+}
+
+func TestParityNamedArguments(t *testing.T) {
+	assertOutputContains(t, greetPrelude+`Call greet with name as "Cy".
+Call greet with greeting as "Yo" and name as "Di".
+Call greet with "Ed" and greeting as "Hey".
+Set x to be the result of calling greet with name as "Flo".
+declare Dog as a structure with the following fields:
+    name is a string with "Rex" being the default.
+    let bark be a function that takes count and loud (defaulting to false) and does the following:
+        If loud, then
+            Print "WOOF", count.
+        otherwise
+            Print "woof", count.
+        thats it.
+    thats it.
+thats it.
+Declare d to be a new instance of Dog.
+Call bark from d with 1.
+Call bark from d with loud as true and count as 2.`, "Hello Cy\nYo Di\nHey Ed\nHello Flo\nwoof 1\nWOOF 2\n")
+}
+
+func TestParityNamedArgumentErrors(t *testing.T) {
+	for _, call := range []string{
+		`Call greet with nmae as "x".`,
+		`Call greet with "x" and name as "y".`,
+		`Call greet with greeting as "Hi".`,
+		`Call greet with "a" and "b" and "c".`,
+		`Set x to be the result of calling uppercase with text as "a".`,
+	} {
+		assertParityError(t, greetPrelude+call)
+	}
+}
+
+const totalPrelude = `Declare function total that takes start and any number of values and does the following:
+    Declare sum to be start.
+    For each v in values, do the following:
+        Set sum to sum + v.
+    thats it.
+    Return sum.
+thats it.
+`
+
+func TestParityRestParameters(t *testing.T) {
+	assertOutputContains(t, totalPrelude+`Print total(1).
+Print total(1, 2, 3).
+Call total with 10 and 20.
+Set x to be the result of calling total with start as 5.
+Print x.
+declare Bag as a structure with the following fields:
+    name is a string with "bag" being the default.
+    let show be a function that takes any number of things and does the following:
+        Print name, things, the length of things.
+    thats it.
+thats it.
+Declare b to be a new instance of Bag.
+Call show from b.
+Call show from b with 1 and "x".`, "1\n6\n5\nbag [] 0\nbag [1 x] 2\n")
+}
+
+func TestParityRestParameterErrors(t *testing.T) {
+	for _, call := range []string{
+		`Print total().`,
+		`Call total with 1 and values as 2.`,
+	} {
+		assertParityError(t, totalPrelude+call)
+	}
+}
+
+// ─── Typed Signatures ─────────────────────────────────────────────────────────
+
+const signaturePrelude = `Declare function area that takes width as number and height as number and returns a number and does the following:
+    Return width * height.
+thats it.
+declare Point as a structure with the following fields:
+    x is a number.
+    let moved be a function that takes amount as number and returns a Point and does the following:
+        Return a new instance of Point with the following fields:
+            x is x + amount.
+        thats it.
+    thats it.
+thats it.
+Declare function describe that takes p as a Point and returns text and does the following:
+    Return "at {p's x}".
+thats it.
+Declare function label that takes n and returns text and does the following:
+    Return n.
+thats it.
+`
+
+func TestParityTypedSignatures(t *testing.T) {
+	assertOutputContains(t, signaturePrelude+`Print area(2, 3).
+Declare p to be a new instance of Point.
+Print describe(p).
+Declare q to be p's moved with 4.
+Print describe(q).
+Print label("ok").`, "6\nat 0\nat 4\nok\n")
+}
+
+func TestParityTypedSignatureErrors(t *testing.T) {
+	assertOutputContains(t, signaturePrelude+`Declare values to be ["2", 5].
+Try doing the following:
+    Print area(values[0], 3).
+on TypeError:
+    Print error.
+thats it.
+Try doing the following:
+    Print describe(values[1]).
+on TypeError:
+    Print error.
+thats it.
+Try doing the following:
+    Print label(values[1]).
+on TypeError:
+    Print error.
+thats it.
+Declare p to be a new instance of Point.
+Try doing the following:
+    Declare q to be p's moved with values[0].
+on TypeError:
+    Print error.
+thats it.`, "<error: function 'area' needs 'width' to be a number, but got text>\n"+
+		"<error: function 'describe' needs 'p' to be a Point, but got number>\n"+
+		"<error: function 'label' should return text, but returned a number>\n"+
+		"<error: function 'moved' needs 'amount' to be a number, but got text>\n")
+	for _, call := range []string{
+		`Print area("2", 3).`,
+		`Print label(1).`,
+	} {
+		assertParityError(t, signaturePrelude+call)
+	}
+}
+
+const elementPrelude = `Declare scores as a list of numbers to be [1, 2].
+Declare ages as a lookup table from text to number to be a lookup table.
+Declare words to be ["ten", 5].
+`
+
+func TestParityElementTypes(t *testing.T) {
+	assertOutputContains(t, elementPrelude+`Set scores to be append(scores, 3).
+Set scores to be insert(scores, 0, 0).
+Set the item at position 1 in scores to be 9.
+Set ages at "Ann" to be 30.
+Print scores.
+Print ages at "Ann".`, "[0 9 2 3]\n30\n")
+}
+
+func TestParityElementTypesStartEmpty(t *testing.T) {
+	assertOutputContains(t, `Declare ages as a lookup table from text to number.
+Set ages at "bo" to be 3.
+Declare scores as a list of numbers.
+Set scores to be append(scores, 90).
+Print ages at "bo".
+Print scores.`, "3\n[90]\n")
+}
+
+func TestParityElementTypeErrors(t *testing.T) {
+	for _, stmt := range []string{
+		`Declare names as a list of text to be words.`,
+		`Set scores to be append(scores, words[0]).`,
+		`Set scores to be insert(scores, 0, words[0]).`,
+		`Set the item at position 0 in scores to be words[0].`,
+		`Set ages at words[1] to be 3.`,
+		`Set ages at "Ann" to be words[0].`,
+		`Declare odd as a list of gadgets.`,
+	} {
+		assertParityError(t, elementPrelude+stmt)
+	}
+}
+
+func TestParityElementTypesUserDeclared(t *testing.T) {
+	assertOutputContains(t, `Declare Point as a structure with the following fields:
+    x is a number with 0 being the default.
+thats it.
+Declare Color as one of red and green.
+Declare points as a list of Point.
+Declare shades as a lookup table from text to Color.
+Set points to be append(points, a new instance of Point).
+Set shades at "sky" to be Color's green.
+Declare mixed to be [1, "two"].
+Try doing the following:
+    Set points to be append(points, mixed[0]).
+on TypeError:
+    Print error.
+thats it.
+Try doing the following:
+    Set shades at "sea" to be mixed[1].
+on TypeError:
+    Print error.
+thats it.
+Print the length of points, shades at "sky".`, "<error: 'points' is a list of Point, so it cannot hold a number>\n"+
+		"<error: 'shades' is a lookup table from text to Color, so it cannot hold text>\n1 green\n")
+}
+
+func TestParityElementTypeErrorsCaught(t *testing.T) {
+	var src strings.Builder
+	src.WriteString(elementPrelude)
+	for _, stmt := range []string{
+		`Set scores to be append(scores, words[0]).`,
+		`Set scores to be insert(scores, 0, words[0]).`,
+		`Set the item at position 0 in scores to be words[0].`,
+		`Set ages at words[1] to be 3.`,
+		`Set ages at "Ann" to be words[0].`,
+	} {
+		src.WriteString("Try doing the following:\n    " + stmt + "\non TypeError:\n    Print \"caught\".\nthats it.\n")
+	}
+	src.WriteString("Print scores.")
+	assertOutputContains(t, src.String(), strings.Repeat("caught\n", 5)+"[1 2]\n")
+}
+
+// writeModules writes two libraries that both declare helper, and returns
+// an import of each "as" a module.
+func writeModules(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	geometry := filepath.Join(dir, "geometry.abc")
+	text := filepath.Join(dir, "text.abc")
+	files := map[string]string{
+		geometry: `Declare tau to always be 6.28.
+Declare function area that takes r and does the following:
+    Return tau * r * r / 2.
+thats it.
+Declare function helper that does the following:
+    Return "geometry helper".
+thats it.
+Print "geometry loaded".
+`,
+		text: `Declare function helper that takes s and does the following:
+    Return "text helper for " + s.
+thats it.
+`,
+	}
+	for path, src := range files {
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return `Import "` + geometry + `" as geo.
+Import "` + text + `" as txt safely.
+`
+}
+
+func TestParityNamespacedImports(t *testing.T) {
+	assertOutputContains(t, writeModules(t)+`Declare a to be the result of calling geo's area with 2.
+Print a.
+Print geo's tau.
+Declare h to be the result of calling geo's helper.
+Print h.
+Set h to be the result of calling txt's helper with "x".
+Print h.
+Call txt's helper with "y".
+Print geo.
+Print the type of geo.`, "geometry loaded\n12.56\n6.28\ngeometry helper\ntext helper for x\n<module geo>\nmodule\n")
+}
+
+func TestParityModuleVariablesStayCurrent(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter.abc")
+	src := `Declare count to be 0.
+Declare function bump that does the following:
+    Set count to count + 1.
+thats it.
+`
+	if err := os.WriteFile(counter, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// c's count is read when it is used, not when the import ran.
+	assertOutputContains(t, `Import "`+counter+`" as c.
+Call c's bump.
+Call c's bump.
+Print c's count.`, "2\n")
+}
+
+func TestParityNamespacedImportErrors(t *testing.T) {
+	imports := writeModules(t)
+	for _, stmt := range []string{
+		`Print geo's radius.`,
+		`Print geo's tau with 2.`,
+		`Print helper.`,
+		`Declare geo to be 1.`,
+	} {
+		assertParityError(t, imports+stmt)
+	}
+}
+
+func TestParityLibrarySearchPath(t *testing.T) {
+	libs := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(libs, "strings"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	slugify := `Declare function slugify that takes s and does the following:
+    Return "slug-" + s.
+thats it.
+`
+	if err := os.WriteFile(filepath.Join(libs, "strings", "slugify.abc"), []byte(slugify), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ENGLISH_PATH", libs)
+
+	assertOutputContains(t, `Import "strings/slugify".
+Import "strings/slugify" as s.
+Declare a to be the result of calling slugify with "a".
+Declare b to be the result of calling s's slugify with "b".
+Print a, b.`, "slug-a slug-b\n")
+	assertParityError(t, `Import "strings/missing".`)
+}
+
+func TestParityCircularImport(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.abc")
+	b := filepath.Join(dir, "b.abc")
+	files := map[string]string{
+		a: "Print \"a\".\nImport \"" + b + "\".\n",
+		b: "\nImport \"" + a + "\".\n",
+	}
+	for path, src := range files {
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := `Import "` + a + `".`
+	_, astErr := runAST(src)
+	_, ivmErr := runIVM(src)
+	for vmName, err := range map[string]error{"astvm": astErr, "ivm": ivmErr} {
+		var cycle *project.ImportCycleError
+		if !errors.As(err, &cycle) {
+			t.Errorf("%s: expected a circular import error, got %v", vmName, err)
+			continue
+		}
+		if want := "circular import: " + a + " → " + b + " → " + a; cycle.ImportCycleMessage() != want {
+			t.Errorf("%s: got %q, want %q", vmName, cycle.ImportCycleMessage(), want)
+		}
+		want := []string{a + ", line 2 imports " + b, b + ", line 2 imports " + a}
+		if got := cycle.ImportChain(); strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("%s: got chain %q, want %q", vmName, got, want)
+		}
+	}
+}
+
+func TestParityModuleRunsOnce(t *testing.T) {
+	dir := t.TempDir()
+	d := filepath.Join(dir, "d.abc")
+	b := filepath.Join(dir, "b.abc")
+	c := filepath.Join(dir, "c.abc")
+	files := map[string]string{
+		d: "Print \"d runs\".\nDeclare shared to always be 4.\n",
+		b: "Import \"" + d + "\".\n",
+		c: "Import shared from \"" + d + "\".\n",
+	}
+	for path, src := range files {
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// b and c both import d, which prints only the first time.
+	assertParity(t, `Import "`+b+`".
+Import "`+c+`".
+Import "`+d+`" as dd.
+Print shared, dd's shared.`)
+	out, _ := runIVM(`Import "` + b + `".
+Import "` + c + `".`)
+	if strings.Count(out, "d runs") != 1 {
+		t.Errorf("expected d.abc to run once, got %q", out)
+	}
+}
+
+// writePrivateLibrary writes a library whose public function keeps values
+// through a private helper and a private list, and returns its path.
+func writePrivateLibrary(t *testing.T) string {
+	t.Helper()
+	lib := filepath.Join(t.TempDir(), "cache.abc")
+	src := `Declare privately store to be [].
+Declare private function remember that takes x and does the following:
+    Set store to be append(store, x).
+    Return the length of store.
+thats it.
+Declare function keep that takes x and does the following:
+    Return remember(x).
+thats it.
+Declare limit to always be 3.
+`
+	if err := os.WriteFile(lib, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return lib
+}
+
+func TestParityPrivateDeclarations(t *testing.T) {
+	lib := writePrivateLibrary(t)
+	for _, imp := range []string{
+		`Import everything from "` + lib + `".`,
+		`Import everything from "` + lib + `" safely.`,
+		`Import keep and limit from "` + lib + `".`,
+	} {
+		assertOutputContains(t, imp+`
+Print keep("a"), keep("b"), limit.`, "1 2 3\n")
+	}
+	assertOutputContains(t, `Import "`+lib+`" as c.
+Declare n to be the result of calling c's keep with "a".
+Print n, c's limit.`, "1 3\n")
+}
+
+func TestParityPrivateDeclarationErrors(t *testing.T) {
+	lib := writePrivateLibrary(t)
+	for _, src := range []string{
+		`Import everything from "` + lib + `".
+Print store.`,
+		`Import everything from "` + lib + `" safely.
+Print remember(1).`,
+		`Import keep and remember from "` + lib + `".`,
+		`Import store from "` + lib + `" safely.`,
+		`Import "` + lib + `" as c.
+Print c's store.`,
+	} {
+		assertParityError(t, src)
+	}
+}
+
+const dividePrelude = `Declare function divide that takes a and b and does the following:
+    Return a divided evenly by b and the remainder of a divided by b.
+thats it.
+`
+
+func TestParityDestructuring(t *testing.T) {
+	assertOutputContains(t, dividePrelude+`Declare q and r to be the result of calling divide with 7 and 2.
+Print q, r.
+Declare first, second and third to be [1, "two", 3].
+Print first, second, third.
+Set q and r to divide(20, 6).
+Print q, r.
+declare Person as a structure with the following fields:
+    name is a string.
+    age is a number.
+thats it.
+Declare p to be a new instance of Person with the following fields:
+    name is "Ann".
+    age is 30.
+thats it.
+Declare age and name to be p.
+Print name, age.
+Print divide(9, 4).
+Try doing the following:
+    Declare x, y and z to be [1, 2].
+on error:
+    Print "caught", error.
+thats it.`, "3 1\n1 two 3\n3 2\nAnn 30\n[2 1]\ncaught <error: cannot unpack 2 value(s) into 3 variables>\n")
+}
+
+func TestParityDestructuringRest(t *testing.T) {
+	assertOutputContains(t, `Declare first and the rest as rest to be [1, 2, 3].
+Print first, rest.
+Declare four to be [4, 5, 6, 7].
+Declare a, b and the rest as others to be four.
+Print a, b, others.
+Set first and the rest as rest to [8, 9, 10].
+Print first, rest.
+Declare last and the rest as leftover to be [11].
+Print last, leftover.
+Try doing the following:
+    Declare x and y to be four.
+on error:
+    Print "caught", error.
+thats it.`, "1 [2 3]\n4 5 [6 7]\n8 [9 10]\n11 []\ncaught <error: cannot unpack 4 value(s) into 2 variables>\n")
+}
+
+func TestParityDestructuringErrors(t *testing.T) {
+	for _, src := range []string{
+		`Declare x and y to be 5.`,
+		`Declare two to be [1, 2].
+Declare x, y and z to be two.`,
+		`Declare one to be [1].
+Declare x, y and the rest as z to be one.`,
+		`declare Point as a structure with the following fields:
+    x is a number.
+thats it.
+Declare p to be a new instance of Point.
+Declare x and z to be p.`,
+	} {
+		assertParityError(t, src)
+	}
+}
+
+func TestParityGenerators(t *testing.T) {
+	assertOutputContains(t, `Declare function evens that takes limit and does the following:
+    Declare i to be 0.
+    repeat the following while i is less than limit:
+        Give back i.
+        Set i to i + 2.
+    thats it.
+thats it.
+Declare function numbers that does the following:
+    Print "start".
+    Give back 1.
+    Print "between".
+    Give back 2.
+    Print "end".
+thats it.
+For each n in evens(7), do the following:
+    Print n.
+thats it.
+Declare g to be numbers().
+Print g.
+For each n in g, do the following:
+    Print "got", n.
+thats it.
+For each n in g, do the following:
+    Print "again", n.
+thats it.`, "0\n2\n4\n6\n<generator numbers>\nstart\ngot 1\nbetween\ngot 2\nend\n")
+}
+
+func TestParityGeneratorTypeName(t *testing.T) {
+	assertOutputContains(t, `Declare function numbers that does the following:
+    Give back 1.
+thats it.
+Declare g to be numbers().
+Try doing the following:
+    Print the length of g.
+on error:
+    Print error.
+thats it.
+Try doing the following:
+    Print g at 0.
+on error:
+    Print error.
+thats it.`, "<error: cannot get length of generator>\n"+
+		"<error: TypeError: cannot index generator with a key; expected lookup table>\n")
+}
+
+func TestParityGeneratorResumes(t *testing.T) {
+	// Breaking out of a loop leaves the generator where it stopped, and an
+	// error in its body reaches the loop consuming it.
+	assertOutputContains(t, `Declare function naturals that does the following:
+    Declare i to be 1.
+    Repeat forever:
+        Give back i.
+        Set i to i + 1.
+    thats it.
+thats it.
+Declare s to be naturals().
+For each n in s, do the following:
+    If n is greater than 2, then
+        Break out of this loop.
+    thats it.
+    Print n.
+thats it.
+For each n in s, do the following:
+    Print "resumed", n.
+    Break out of this loop.
+thats it.
+Declare function failing that does the following:
+    Give back 1.
+    Raise "boom".
+thats it.
+Try doing the following:
+    For each n in failing(), do the following:
+        Print n.
+    thats it.
+on error:
+    Print "caught", error.
+thats it.`, "1\n2\nresumed 4\n1\ncaught <error: boom>\n")
+}
+
+// ─── Background tasks and channels ───────────────────────────────────────────
+
+func TestParityBackgroundTasks(t *testing.T) {
+	assertOutputContains(t, `Declare results to be a channel.
+Print results.
+Do the following in the background and call it producer:
+    Declare i to be 1.
+    repeat the following while i is less than 4:
+        Send i * 10 to results.
+        Set i to i + 1.
+    thats it.
+thats it.
+Print producer.
+Declare total to be 0.
+Declare count to be 0.
+repeat the following while count is less than 3:
+    Receive from results into n.
+    Print "got", n.
+    Set total to total + n.
+    Set count to count + 1.
+thats it.
+Wait for producer.
+Print "total", total.
+Do the following in the background and call it failing:
+    Raise "task broke".
+thats it.
+Try doing the following:
+    Wait for failing.
+on error:
+    Print "caught", error.
+thats it.
+Do the following in the background and call it sleeper:
+    Wait for 10ms.
+    Print "slept".
+thats it.
+Print "before".
+Wait for sleeper.`, "<channel>\n<task producer>\ngot 10\ngot 20\ngot 30\ntotal 60\ncaught <error: task broke>\nbefore\nslept\n")
+}
+
+func TestParityBackgroundSharedState(t *testing.T) {
+	// Only one task runs at a time, so no update to counter is lost, and the
+	// program does not end before its tasks do.
+	assertOutputContains(t, `Declare counter to be 0.
+Declare function bump that does the following:
+    repeat the following 2000 times:
+        Set counter to counter + 1.
+    thats it.
+thats it.
+Do the following in the background and call it first:
+    Call bump.
+thats it.
+Do the following in the background and call it second:
+    Call bump.
+thats it.
+Call bump.
+Wait for first.
+Wait for second.
+Print counter.
+Do the following in the background and call it last:
+    Print "last".
+thats it.`, "6000\nlast\n")
+}
+
+func TestParityBackgroundErrors(t *testing.T) {
+	for _, src := range []string{
+		// Every task is waiting on a channel nobody sends to.
+		`Declare ch to be a channel.
+Do the following in the background and call it job:
+    Receive from ch into x.
+thats it.
+Print "main done".`,
+		`Declare ch to be a channel.
+Receive from ch into x.`,
+		// A task that failed without being waited for fails the program.
+		`Do the following in the background and call it job:
+    Raise "unseen".
+thats it.`,
+		`Declare x to be 5.
+Wait for x.`,
+		`Send 1 to 2.`,
+	} {
+		assertParityError(t, src)
+	}
+
+	_, astErr := runAST(`Declare ch to be a channel.
+Receive from ch into x.`)
+	_, ivmErr := runIVM(`Declare ch to be a channel.
+Receive from ch into x.`)
+	for _, err := range []error{astErr, ivmErr} {
+		if err == nil || !strings.Contains(err.Error(), "deadlock") {
+			t.Errorf("expected a deadlock error, got %v", err)
+		}
+	}
+}
+
+func TestParityNestedImportBesideImporter(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "lib")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	helper := filepath.Join(dir, "helper.abc")
+	files := map[string]string{
+		helper: `Import "inner.abc".
+Declare function twice that takes n and does the following:
+    Return n * factor.
+thats it.
+`,
+		filepath.Join(dir, "inner.abc"): "Declare factor to be 2.\n",
+	}
+	for path, src := range files {
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertOutputContains(t, `Import "`+helper+`".
+Print twice(4).`, "8\n")
+}
+
+func TestParityBackgroundTaskInImport(t *testing.T) {
+	// A task an imported file starts runs alongside the importing program.
+	lib := filepath.Join(t.TempDir(), "bglib.abc")
+	if err := os.WriteFile(lib, []byte(`Declare results to be a channel.
+Do the following in the background and call it libjob:
+    Send 42 to results.
+    Print "libjob done".
+thats it.
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertOutputContains(t, `Import everything from "`+lib+`".
+Receive from results into n.
+Wait for libjob.
+Print "got", n.`, "libjob done\ngot 42\n")
+}
+
 // ─── Try / Catch ─────────────────────────────────────────────────────────────
 
 func TestParityTryCatch(t *testing.T) {
@@ -592,6 +1715,91 @@ on AppError:
 thats it.`)
 }
 
+// ─── Assertions ──────────────────────────────────────────────────────────────
+
+func TestParityAssertions(t *testing.T) {
+	assertOutputContains(t, `Declare balance to be 5.
+Make sure that balance is at least 0, otherwise say "negative balance".
+Make sure that balance is at most 10.
+Print "ok".
+Set balance to -5.
+Try doing the following:
+    Make sure that balance is at least 0, otherwise say "negative balance: {balance}".
+on AssertionError:
+    Print "caught", error.
+thats it.
+Try doing the following:
+    Make sure that balance is greater than 0 and balance is less than 10.
+on error:
+    Print error.
+thats it.`, "ok\ncaught <error: negative balance: -5>\n<error: failed to make sure that balance is greater than 0 and balance is less than 10>\n")
+}
+
+func TestParityAssertionErrors(t *testing.T) {
+	const src = `Declare balance to be -5.
+Make sure that balance is at least 0, otherwise say "negative balance".`
+	assertParityError(t, src)
+
+	_, astErr := runAST(src)
+	_, ivmErr := runIVM(src)
+	for _, err := range []error{astErr, ivmErr} {
+		ev, ok := err.(*types.ErrorValue)
+		if !ok || ev.ErrorType != "AssertionError" {
+			t.Fatalf("expected an AssertionError, got %#v", err)
+		}
+		if got := ev.AssertionCondition(); got != "balance is at least 0" {
+			t.Errorf("condition = %q", got)
+		}
+		if got := ev.AssertionOperands(); len(got) != 1 || got[0] != "balance was -5" {
+			t.Errorf("operands = %q", got)
+		}
+		if ev.AssertionLine() != 2 {
+			t.Errorf("line = %d, want 2", ev.AssertionLine())
+		}
+	}
+}
+
+func TestParityAssertionsDisabled(t *testing.T) {
+	const src = `Make sure that 1 is greater than 2, otherwise say "unreachable".
+Print "skipped".`
+	prog, err := parser.NewParser(parser.NewLexer(src).TokenizeAll()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var astErr, ivmErr error
+	astOut := captureStdout(func() {
+		env := vm.NewEnvironment()
+		stdlib.Register(env)
+		evaluator := vm.NewEvaluator(env, stdlib.Eval)
+		evaluator.DisableAssertions()
+		_, astErr = evaluator.Eval(prog)
+	})
+	ivmOut := captureStdout(func() {
+		chunk, err := ivm.Compile(prog)
+		if err != nil {
+			ivmErr = err
+			return
+		}
+		_, ivmErr = ivm.ExecuteWithoutAssertions(chunk, stdlib.Eval, stdlib.PredefinedValues())
+	})
+	if astErr != nil || ivmErr != nil {
+		t.Fatalf("astvm err: %v, ivm err: %v", astErr, ivmErr)
+	}
+	if astOut != "skipped\n" || ivmOut != "skipped\n" {
+		t.Errorf("astvm output %q, ivm output %q", astOut, ivmOut)
+	}
+}
+
+func TestParityTestBlocksSkipped(t *testing.T) {
+	assertOutputContains(t, `Declare total to be 1.
+Test "not run" by doing the following:
+    Print "inside the test".
+    Make sure that total is equal to 2.
+thats it.
+Print total.`, "1\n")
+}
+
 // ─── Cast ────────────────────────────────────────────────────────────────────
 
 func TestParityCastNumberToText(t *testing.T) {
@@ -606,12 +1814,165 @@ Declare n to be cast s to number.
 Print n.`)
 }
 
+func TestParityCastToInteger(t *testing.T) {
+	assertParity(t, `Print 3.9 cast to integer.
+Print -3.9 cast to integer.
+Print "42" cast to unsigned integer.`)
+}
+
+func TestParityCastNegativeToUnsigned(t *testing.T) {
+	assertParityError(t, `Print -2 cast to unsigned integer.`)
+}
+
 func TestParityCastNumberToBoolean(t *testing.T) {
 	assertParity(t, `Declare n to be 1.
 Declare b to be cast n to boolean.
 Print b.`)
 }
 
+// ─── Decimal and Whole Numbers ───────────────────────────────────────────────
+
+func TestParityDecimalArithmetic(t *testing.T) {
+	assertParity(t, `Declare a to be the decimal 0.1.
+Declare b to be the decimal 0.2.
+Print a + b.
+Declare price as decimal to be the decimal 19.99.
+Print price * 3.
+Print -a.
+Print the type of a.`)
+}
+
+func TestParityDecimalDivision(t *testing.T) {
+	assertParity(t, `Print (the decimal 1) / 3.
+Print (the decimal 2) / 3.
+Print (the decimal 10) / 4.
+Print (the decimal -7.5) divided evenly by 2.
+Print the remainder of (the decimal -7.5) divided by 2.
+Print (the decimal 2) to the power of -2.`)
+}
+
+func TestParityWholeNumberFactorial(t *testing.T) {
+	assertParity(t, `Declare f to be the whole number 1.
+Declare i to be 1.
+repeat the following while i is less than or equal to 30:
+    Set f to be f * i.
+    Set i to be i + 1.
+thats it.
+Print f.
+Print the type of f.
+Print (the whole number 1) shifted left by 100.`)
+}
+
+func TestParityDecimalCasts(t *testing.T) {
+	assertParity(t, `Print 0.1 cast to decimal.
+Print "3.14159" cast to decimal.
+Print (the decimal 2.75) cast to whole number.
+Print (the decimal 2.75) cast to number.`)
+}
+
+func TestParityDecimalComparison(t *testing.T) {
+	assertParity(t, `Declare a to be the decimal 0.1.
+If (the decimal 0.30) is equal to a + the decimal 0.2, then
+    Print "equal".
+thats it.
+If a is equal to 0.1, then
+    Print "float equal".
+thats it.
+If a is less than 1, then
+    Print "less".
+thats it.`)
+}
+
+func TestParityDecimalMixError(t *testing.T) {
+	assertParity(t, `Try doing the following:
+    Print (the decimal 0.1) + 0.5.
+on TypeError:
+    Print "caught".
+thats it.`)
+}
+
+func TestParityDecimalStructField(t *testing.T) {
+	assertParity(t, `Declare Product as a structure with the following fields:
+    price is a decimal.
+    stock is a whole number.
+thats it.
+Declare p to be a new instance of Product.
+Print p's price.
+Set p's price to be the decimal 2.50.
+Print p's price * 2.`)
+}
+
+// ─── Enumerations ────────────────────────────────────────────────────────────
+
+func TestParityEnumMembers(t *testing.T) {
+	assertParity(t, `Declare Color as one of red, green and blue.
+Declare c to be Color's green.
+Print c.
+Print the blue of Color.
+Print c cast to text.
+Print the type of c.
+Print the length of Color.
+If c is equal to Color's green, then
+    Print "green".
+thats it.
+If c is not equal to Color's red, then
+    Print "not red".
+thats it.`)
+}
+
+func TestParityEnumIteration(t *testing.T) {
+	assertParity(t, `Declare Size as one of small, medium, and large.
+For each s in Size, do the following:
+    Print s.
+thats it.`)
+}
+
+func TestParityEnumWhen(t *testing.T) {
+	assertParity(t, `Declare Color as one of red, green and blue.
+Declare c to be Color's blue.
+When c is Color's red:
+    Print "red".
+When it is Color's blue:
+    Print "blue".
+thats it.
+When c is a Color:
+    Print "a Color".
+thats it.`)
+}
+
+func TestParityEnumDistinctTypes(t *testing.T) {
+	// Members of different enums are never equal, even with the same name.
+	assertParity(t, `Declare Light as one of red, amber and green.
+Declare Color as one of red, green and blue.
+Declare function check that takes v and does the following:
+    If v is equal to Color's red, then
+        Print "same".
+    thats it.
+    If v is not equal to Color's red, then
+        Print "different".
+    thats it.
+thats it.
+Call check with Color's red.
+Call check with Light's red.`)
+}
+
+func TestParityEnumKeys(t *testing.T) {
+	assertOutputContains(t, `Declare Light as one of red, amber and green.
+Declare Color as one of red, green and blue.
+Declare counts to be a lookup table.
+Set counts at Color's blue to be 3.
+Set counts at Color's red to be 1.
+Set counts at Light's red to be 2.
+Set counts at Color's red to be (counts at Color's red) + 10.
+Print counts at Color's red, counts at Light's red.
+For each c in counts, do the following:
+    If c is equal to Color's blue, then
+        Print "blue is a key".
+    thats it.
+thats it.
+Print counts.`, "11 2\nblue is a key\n{blue: 3, red: 11, red: 2}\n")
+}
+
 // ─── Standard Library ────────────────────────────────────────────────────────
 
 func TestParityStdlibSqrt(t *testing.T) {
@@ -675,6 +2036,41 @@ Print first(nums).
 Print last(nums).`)
 }
 
+func TestParityStdlibHigherOrder(t *testing.T) {
+	assertOutputContains(t, `Declare numbers to be [1, 2, 3, 4, 5].
+Declare function double that takes x and does the following:
+    Return x * 2.
+thats it.
+Declare is_even to be a function that takes n and returns the remainder of n divided by 2 is equal to 0.
+Declare add to be a function that takes a and b and returns a + b.
+Print transform(numbers, double).
+Print keep(numbers, is_even).
+Print combine(numbers, add, 0).
+Print find_first(numbers, is_even).
+Print count_where(numbers, is_even).`, "15\n2\n2\n")
+}
+
+func TestParityStdlibHigherOrderClosure(t *testing.T) {
+	assertParity(t, `Declare limit to be 3.
+Declare numbers to be [1, 2, 3, 4, 5].
+Print keep(numbers, a function that takes n and returns n is greater than limit).`)
+}
+
+func TestParityStdlibHigherOrderErrors(t *testing.T) {
+	assertParity(t, `Declare numbers to be [1, 2, 3].
+Declare add to be a function that takes a and b and returns a + b.
+Try doing the following:
+    Print transform(numbers, add).
+on error:
+    Print "caught".
+thats it.
+Try doing the following:
+    Print keep(numbers, a function that takes n and returns n).
+on error:
+    Print "caught again".
+thats it.`)
+}
+
 // ─── Predefined Constants ────────────────────────────────────────────────────
 
 func TestParityPiConstant(t *testing.T) {
@@ -816,6 +2212,138 @@ func TestParityStringLength(t *testing.T) {
 Print the length of s.`)
 }
 
+func TestParityStringInterpolation(t *testing.T) {
+	assertParity(t, `Declare name to be "Bo".
+Declare age to be 4.
+Declare greet to be a function that takes who and returns "Hello, {who}!".
+Print "{name} is {age} and will be {age + 1} next year.".
+Print greet(name).
+Print "nested: {greet('{name} jr')}".
+Print "literal \{braces\} and {} stay as written".
+Print "done: {true}, {nothing}, {1.5}".`)
+}
+
+func TestParityStringInterpolationError(t *testing.T) {
+	assertParityError(t, `Print "Hello, {missing}".`)
+}
+
+// ─── When Statements ─────────────────────────────────────────────────────────
+
+func TestParityWhenValues(t *testing.T) {
+	assertParity(t, `Declare function describe that takes x and does the following:
+    When x is "start":
+        Return "starting".
+    When it is "stop" or "halt":
+        Return "stopping".
+    When it is between 1 and 10:
+        Return "small number".
+    When it is one of 11, 12, 13:
+        Return "teen-ish".
+    When it is a number:
+        Return "number".
+    When it is nothing:
+        Return "nothing".
+    When it is true:
+        Return "yes".
+    Otherwise:
+        Return "something else".
+    thats it.
+thats it.
+Print describe("start").
+Print describe("halt").
+Print describe(5).
+Print describe(12).
+Print describe(42).
+Print describe(nothing).
+Print describe(true).
+Print describe(false).
+Print describe("other").
+Print describe(1 + 1).`)
+}
+
+func TestParityWhenTypesAndLoops(t *testing.T) {
+	assertParity(t, `Declare AppError as an error type.
+Declare NetworkError as a type of AppError.
+declare Point as a structure with the following fields:
+    x is a number.
+thats it.
+Declare function classify that takes thing and does the following:
+    When thing is a Point:
+        Return "point".
+    When it is a NetworkError:
+        Return "network error".
+    When it is an AppError:
+        Return "app error".
+    When it is an error:
+        Return "some error".
+    When it is a list:
+        Return "list".
+    When it is a text:
+        Return "text".
+    When it is a function:
+        Return "function".
+    thats it.
+    Return "unmatched".
+thats it.
+let p be a new instance of Point with the following fields:
+    x is 1.
+thats it.
+Print classify(p).
+Print classify([1, 2]).
+Print classify("hi").
+Print classify(classify).
+Print classify(5).
+Try doing the following:
+    Raise "timeout" as NetworkError.
+on error:
+    Print classify(error).
+thats it.
+Try doing the following:
+    Raise "boom" as AppError.
+on error:
+    Print classify(error).
+thats it.
+Try doing the following:
+    Raise "plain".
+on error:
+    Print classify(error).
+thats it.
+Declare total to be 0.
+for each n in [1, 2, 3, 4, 5, 6], do the following:
+    When n is 2 or 4:
+        Continue.
+    When it is 6:
+        Break out of this loop.
+    Otherwise:
+        Set total to be total + n.
+    thats it.
+thats it.
+Print total.`)
+}
+
+func TestParityWhenExactSubject(t *testing.T) {
+	assertOutputContains(t, `Declare function name that takes x and does the following:
+    When x is 1:
+        Return "one".
+    When it is 2:
+        Return "two".
+    When it is "three":
+        Return "three".
+    Otherwise:
+        Return "other".
+    thats it.
+thats it.
+Print name(the whole number 2).
+Print name(the decimal 1).
+Print name(the decimal 1.5).`, "two\none\nother\n")
+}
+
+func TestParityWhenRangeError(t *testing.T) {
+	assertParityError(t, `When 5 is between "a" and 10:
+    Print "never".
+thats it.`)
+}
+
 // ─── Boolean Expressions ─────────────────────────────────────────────────────
 
 func TestParityComplexBooleans(t *testing.T) {
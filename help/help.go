@@ -66,9 +66,17 @@ func (r *Registry) Search(query string) []SearchResult {
 		}
 	}
 
-	// Sort by score (descending)
+	// Sort by score (descending); among equal scores the shorter, then the
+	// alphabetically first, name wins so the order does not follow the map.
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		a, b := results[i].Entry.Name, results[j].Entry.Name
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
 	})
 
 	return results
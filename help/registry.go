@@ -115,6 +115,20 @@ func (r *Registry) loadDefaultEntries() {
 		SeeAlso:  []string{"if"},
 	})
 
+	r.Register(&HelpEntry{
+		Name:        "when",
+		Description: "Match one value against several patterns",
+		Category:    "keyword",
+		LongDesc:    "Use 'When <value> is <pattern>:' to branch on a single value. Later clauses start with 'When it is', patterns can be values, 'one of' lists, ranges ('between 1 and 10'), types ('a number'), error types and structure types, and 'Otherwise:' catches everything else. End with 'thats it.'",
+		Examples: []string{
+			"When command is \"start\":\n    Print \"go\".\nWhen it is \"stop\" or \"halt\":\n    Print \"stop\".\nOtherwise:\n    Print \"unknown\".\nthats it.",
+			"When score is between 90 and 100:\n    Print \"A\".\nWhen it is a number:\n    Print \"other\".\nthats it.",
+		},
+		Keywords: []string{"match", "switch", "case", "pattern", "branch"},
+		Aliases:  []string{"match", "switch"},
+		SeeAlso:  []string{"if", "otherwise"},
+	})
+
 	r.Register(&HelpEntry{
 		Name:        "then",
 		Description: "Introduces the consequence of a conditional",
@@ -178,12 +192,13 @@ func (r *Registry) loadDefaultEntries() {
 		Name:        "break",
 		Description: "Exit a loop early",
 		Category:    "keyword",
-		LongDesc:    "Use 'break' or 'break out of this loop' to immediately exit the innermost loop.",
+		LongDesc:    "Use 'break' or 'break out of this loop' to immediately exit the innermost loop. A loop named with 'and call this loop <name>' before its colon can be left from inside loops within it with 'break out of <name>'.",
 		Examples: []string{
 			"For each n in [1 .. 100], do the following:\n    If n is 50, then break out of this loop.\n    Print the value of n.\nthats it.",
 			"Repeat forever:\n    If done, then break.\nthats it.",
+			"For each row in grid, do the following and call this loop rows:\n    For each cell in row, do the following:\n        If cell is equal to 0, then\n            Break out of rows.\n        thats it.\n    thats it.\nthats it.",
 		},
-		Keywords: []string{"exit", "terminate", "stop", "loop control"},
+		Keywords: []string{"exit", "terminate", "stop", "loop control", "label", "outer loop", "call this loop"},
 		SeeAlso:  []string{"continue", "repeat"},
 	})
 
@@ -191,11 +206,12 @@ func (r *Registry) loadDefaultEntries() {
 		Name:        "continue",
 		Description: "Skip to the next iteration of a loop",
 		Category:    "keyword",
-		LongDesc:    "Use 'continue' or 'skip' to skip the rest of the current iteration and move to the next one.",
+		LongDesc:    "Use 'continue' or 'skip' to skip the rest of the current iteration and move to the next one. 'Continue <name>' moves on to the next iteration of the loop named with 'and call this loop <name>', even from inside loops within it.",
 		Examples: []string{
 			"For each n in [1 .. 10], do the following:\n    If n is 5, then continue.\n    Print the value of n.\nthats it.",
+			"For each row in grid, do the following and call this loop rows:\n    For each cell in row, do the following:\n        If cell is equal to 0, then\n            Continue rows.\n        thats it.\n    thats it.\nthats it.",
 		},
-		Keywords: []string{"skip", "next", "loop control"},
+		Keywords: []string{"skip", "next", "loop control", "label", "outer loop"},
 		Aliases:  []string{"skip"},
 		SeeAlso:  []string{"break", "repeat"},
 	})
@@ -208,13 +224,14 @@ func (r *Registry) loadDefaultEntries() {
 		Name:        "function",
 		Description: "Define a reusable function",
 		Category:    "keyword",
-		LongDesc:    "Declare functions using 'Declare function <name> that takes <params> and does the following:'. Functions can return values using 'return'.",
+		LongDesc:    "Declare functions using 'Declare function <name> that takes <params> and does the following:'. Functions can return values using 'return'. A parameter can be given a type with 'as', and the function can say what it returns with 'and returns a <type>'; calls and returns that break the signature are errors.",
 		Examples: []string{
 			"Declare function greet that takes name and does the following:\n    Print \"Hello, \" + name + \"!\".\nthats it.",
 			"Declare function add that takes a and b and does the following:\n    Return a + b.\nthats it.",
 			"Declare function say_hello that does the following:\n    Print \"Hello!\".\nthats it.",
+			"Declare function area that takes width as number and height as number and returns a number and does the following:\n    Return width * height.\nthats it.",
 		},
-		Keywords: []string{"procedure", "subroutine", "method", "def", "define"},
+		Keywords: []string{"procedure", "subroutine", "method", "def", "define", "signature", "returns"},
 		SeeAlso:  []string{"return", "call"},
 	})
 
@@ -313,10 +330,11 @@ func (r *Registry) loadDefaultEntries() {
 		Name:        "text",
 		Description: "String/text data type",
 		Category:    "type",
-		LongDesc:    "Text values are strings enclosed in double quotes. Supports concatenation with + and various string operations. Escape sequences like \\n for newline are supported.",
+		LongDesc:    "Text values are strings enclosed in double quotes. Supports concatenation with + and various string operations. Escape sequences like \\n for newline are supported. Expressions in braces are replaced by their value: \"Hi {name}\". Write \\{ for a literal brace.",
 		Examples: []string{
 			"Declare name as text to be \"Alice\".",
 			"Print \"Hello, \" + name + \"!\".",
+			"Print \"{name} will be {age + 1} next year.\".",
 			"Declare message to be \"Line 1\\nLine 2\".",
 		},
 		Keywords: []string{"string", "character", "word", "str"},
@@ -363,6 +381,7 @@ func (r *Registry) loadDefaultEntries() {
 			"Print the value of items[0].",
 			"For each item in items, print the value of item.",
 			"Declare names to be an array of text.",
+			"Declare scores as a list of numbers to be [90, 85].",
 		},
 		Keywords: []string{"array", "collection", "sequence", "vector"},
 		Aliases:  []string{"array"},
@@ -379,6 +398,7 @@ func (r *Registry) loadDefaultEntries() {
 			"Set scores at \"Alice\" to 95.",
 			"Print scores at \"Alice\".",
 			"If scores has \"Bob\", then print \"Found\". thats it.",
+			"Declare ages as a lookup table from text to number.",
 		},
 		Keywords: []string{"dictionary", "map", "hash", "object", "dict", "hashmap"},
 		Aliases:  []string{"dictionary", "map"},
@@ -393,16 +413,34 @@ func (r *Registry) loadDefaultEntries() {
 		Name:        "arithmetic",
 		Description: "Mathematical operators",
 		Category:    "operator",
-		LongDesc:    "Arithmetic operators: + (addition), - (subtraction), * (multiplication), / (division). For modulo, use 'remainder of X divided by Y'.",
+		LongDesc:    "Arithmetic operators: + (addition), - (subtraction), * (multiplication), / (division), 'to the power of' (exponent) and 'divided evenly by' (floor division). For modulo, use 'remainder of X divided by Y'.",
 		Examples: []string{
 			"Print the result of 5 + 3.",
 			"Print the result of 10 - 4.",
 			"Print the result of 6 * 7.",
 			"Print the result of 15 / 3.",
 			"Print the remainder of 10 divided by 3.",
+			"Print 2 to the power of 10.",
+			"Print 7 divided evenly by 2.",
 		},
-		Keywords: []string{"math", "addition", "subtraction", "multiplication", "division", "modulo", "plus", "minus"},
-		SeeAlso:  []string{"number", "math functions"},
+		Keywords: []string{"math", "addition", "subtraction", "multiplication", "division", "modulo", "plus", "minus", "power", "exponent", "floor"},
+		SeeAlso:  []string{"number", "math functions", "bitwise operators"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "bitwise operators",
+		Description: "Operate on the bits of whole numbers",
+		Category:    "operator",
+		LongDesc:    "Bitwise operators work on whole numbers: 'bitwise and', 'bitwise or', 'bitwise xor', 'shifted left by' and 'shifted right by'. Shifts bind tighter than and/or/xor, and all of them bind more loosely than + and -.",
+		Examples: []string{
+			"Print 12 bitwise and 10.",
+			"Print 12 bitwise or 3.",
+			"Print 12 bitwise xor 10.",
+			"Print 1 shifted left by 4.",
+			"Print 256 shifted right by 2.",
+		},
+		Keywords: []string{"bits", "and", "or", "xor", "shift", "mask", "binary"},
+		SeeAlso:  []string{"arithmetic"},
 	})
 
 	r.Register(&HelpEntry{
@@ -553,6 +591,34 @@ func (r *Registry) loadDefaultEntries() {
 		SeeAlso:  []string{"try catch", "error types"},
 	})
 
+	r.Register(&HelpEntry{
+		Name:        "make sure",
+		Description: "Check that something holds",
+		Category:    "keyword",
+		LongDesc:    "Use 'Make sure that' to check a condition the program relies on. If it is false, an AssertionError is raised with the 'otherwise say' message, or the condition itself when there is none; an uncaught one shows what each side of the comparison was. Run with --no-assertions to skip every check.",
+		Examples: []string{
+			"Make sure that balance is at least 0, otherwise say \"negative balance\".",
+			"Make sure that the length of items is greater than 0.",
+			"Try the following:\n    Make sure that age is at most 150.\non AssertionError:\n    Print \"Bad age\".\nthats it.",
+		},
+		Keywords: []string{"assert", "assertion", "check", "AssertionError", "at least", "at most"},
+		Aliases:  []string{"assert", "make sure that"},
+		SeeAlso:  []string{"try catch", "raise"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "test",
+		Description: "Write a test block",
+		Category:    "keyword",
+		LongDesc:    "Use 'Test \"name\" by doing the following:' at the top level of a file to write a test. 'english run' skips tests; 'english test' runs each one in a fresh environment after the file's declarations and imports. A test fails when a 'Make sure that' check in it does not hold. Use --run to pick tests by name and --junit to write a JUnit XML report.",
+		Examples: []string{
+			"Test \"adding works\" by doing the following:\n    Make sure that add(1, 2) is equal to 3.\nthats it.",
+		},
+		Keywords: []string{"testing", "unit test", "junit"},
+		Aliases:  []string{"tests", "test block"},
+		SeeAlso:  []string{"make sure"},
+	})
+
 	r.Register(&HelpEntry{
 		Name:        "error types",
 		Description: "Define custom error hierarchies",
@@ -583,7 +649,161 @@ func (r *Registry) loadDefaultEntries() {
 			"Set the age of john to 31.",
 		},
 		Keywords: []string{"structure", "object", "type", "custom type", "class", "record"},
-		SeeAlso:  []string{"types"},
+		SeeAlso:  []string{"types", "integer fields", "inheritance"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "inheritance",
+		Description: "Build a structure on top of another",
+		Category:    "concept",
+		LongDesc:    "'declare Dog as a kind of Animal with the following fields:' makes Dog start with all of Animal's fields, defaults and methods. Dog can add fields, give an inherited field a new default, and replace methods by declaring them again. 'rex is a Animal' and 'When rex is a Animal' are true for a Dog.",
+		Examples: []string{
+			"declare Dog as a kind of Animal with the following fields:\n    tricks is a number.\nthats it.",
+			"If rex is a Animal, then\n    Print \"an animal\".\nthats it.",
+		},
+		Keywords: []string{"inherit", "inheritance", "kind of", "subclass", "parent", "extends", "override"},
+		SeeAlso:  []string{"struct", "capability"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "capability",
+		Description: "A set of functions a structure promises to have",
+		Category:    "concept",
+		LongDesc:    "'Declare Speaker as a capability requiring a function speak.' names a set of required functions. A structure promises them with 'that can Speaker' after 'a structure' or 'a kind of X', and it is a compile-time error if one is missing; a structure inherits its parent's capabilities. A function parameter declared 'as anything that can Speaker' only accepts instances of structures that made the promise, and anything else raises a TypeError.",
+		Examples: []string{
+			"Declare Speaker as a capability requiring a function speak.",
+			"declare Dog as a structure that can Speaker with the following fields:\n    let speak be a function that does the following:\n        Print \"woof\".\n    thats it.\nthats it.",
+			"Declare function greet that takes pet as anything that can Speaker and does the following:\n    Call pet's speak.\nthats it.",
+		},
+		Keywords: []string{"capability", "interface", "protocol", "trait", "that can", "anything that can", "contract"},
+		SeeAlso:  []string{"struct", "inheritance"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "defaults",
+		Description: "Default parameter values and named arguments",
+		Category:    "concept",
+		LongDesc:    "A parameter written 'greeting (defaulting to \"Hello\")' takes that value when a call leaves it out; every parameter after one with a default needs a default too. The default is worked out again on each call. A call can name an argument with 'greeting as \"Hi\"' to fill that parameter whatever its position; named arguments come after the positional ones. An unknown name, a name given twice, or a missing parameter without a default is an error. Built-in functions only take positional arguments.",
+		Examples: []string{
+			"Declare function greet that takes name and greeting (defaulting to \"Hello\") and does the following:\n    Print greeting, \", \", name.\nthats it.",
+			"Call greet with \"Alice\".",
+			"Call greet with name as \"Bo\" and greeting as \"Hi\".",
+		},
+		Keywords: []string{"default", "defaulting to", "optional", "named argument", "keyword argument", "kwargs"},
+		SeeAlso:  []string{"function", "call"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "any number of",
+		Description: "A parameter that collects any number of arguments",
+		Category:    "concept",
+		LongDesc:    "'takes any number of values' makes the last parameter collect the arguments left over once the others are filled, as a list that is empty when there are none. It has to come last, and it cannot have a default or be given by name.",
+		Examples: []string{
+			"Declare function total that takes any number of values and does the following:\n    Return the length of values.\nthats it.",
+			"Declare function label that takes prefix and any number of items and does the following:\n    Print prefix, items.\nthats it.",
+			"Print total(1, 2, 3).",
+		},
+		Keywords: []string{"variadic", "rest parameter", "varargs", "args", "any number"},
+		SeeAlso:  []string{"function", "defaults"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "destructuring",
+		Description: "Returning several values and taking them apart",
+		Category:    "concept",
+		LongDesc:    "'Return a and b.' gives back both values as a list. 'Declare q and r to be ...' or 'Set q and r to ...' takes a list apart into one variable per item, and the list must have exactly one item per name, unless the last is written 'the rest as others', which collects whatever the names before it leave over as a list. A structure is taken apart by field name instead, so each name has to be one of its fields.",
+		Examples: []string{
+			"Declare function divide that takes a and b and does the following:\n    Return a divided evenly by b and the remainder of a divided by b.\nthats it.",
+			"Declare q and r to be the result of calling divide with 7 and 2.",
+			"Declare first, second and third to be [1, 2, 3].",
+			"Declare first and the rest as others to be [1, 2, 3].",
+			"Declare name and age to be person.",
+		},
+		Keywords: []string{"destructure", "unpack", "multiple return", "several values", "tuple"},
+		SeeAlso:  []string{"function", "return"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "give back",
+		Description: "Hand out values one at a time from a function",
+		Category:    "keyword",
+		LongDesc:    "A function whose body says 'Give back x.' is a generator: calling it runs nothing yet, and 'For each' over the result runs the body only as far as the next 'Give back' for each item. Breaking out of the loop leaves the generator where it stopped, so a later loop carries on from there.",
+		Examples: []string{
+			"Declare function evens that takes limit and does the following:\n    Declare i to be 0.\n    repeat the following while i is less than limit:\n        Give back i.\n        Set i to i + 2.\n    thats it.\nthats it.",
+			"For each n in evens(10), do the following:\n    Print n.\nthats it.",
+		},
+		Keywords: []string{"generator", "yield", "lazy", "stream", "iterator"},
+		SeeAlso:  []string{"function", "for each", "return"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "background",
+		Description: "Run work in a background task",
+		Category:    "keyword",
+		LongDesc:    "'Do the following in the background and call it job:' starts the block as a task and carries straight on; 'Wait for job.' waits until it has finished and raises the error that ended it, if any. Tasks share the program's variables, but only one runs at a time and they only take turns between statements or while waiting, so no statement sees another half done. The program waits for all its tasks before it ends, and fails if one of them failed without anybody waiting for it. When every task is waiting and none can go on, the program stops with a deadlock error saying what each one was waiting for.",
+		Examples: []string{
+			"Do the following in the background and call it job:\n    Wait for 1 second.\n    Print \"done\".\nthats it.",
+			"Wait for job.",
+		},
+		Keywords: []string{"task", "thread", "concurrency", "parallel", "wait", "deadlock"},
+		SeeAlso:  []string{"channel", "sleep"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "channel",
+		Description: "Pass values between background tasks",
+		Category:    "keyword",
+		LongDesc:    "'a channel' makes a new channel. 'Send x to ch.' waits until another task has received x, and 'Receive from ch into y.' waits for the next value sent and stores it in y. A channel holds nothing of its own, so sending and receiving tasks meet at each value.",
+		Examples: []string{
+			"Declare results to be a channel.",
+			"Do the following in the background and call it producer:\n    Send 42 to results.\nthats it.",
+			"Receive from results into answer.",
+		},
+		Keywords: []string{"send", "receive", "message", "queue", "task"},
+		SeeAlso:  []string{"background"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "integer fields",
+		Description: "Struct fields that only hold whole numbers",
+		Category:    "concept",
+		LongDesc:    "A field declared as 'integer', 'unsigned integer', i32, i64, u32 or u64 only holds whole numbers within its range. Storing a fraction is a TypeError; storing a value that does not fit, or a negative value in an unsigned field, is an OverflowError. Arithmetic on such a field keeps its kind and raises OverflowError when the result does not fit. 'Use wrapping arithmetic.' makes overflowing results wrap around instead; 'Use checked arithmetic.' switches back.",
+		Examples: []string{
+			"declare Meter as a structure with the following fields:\n    reading is an unsigned integer.\nthats it.",
+			"Set m's reading to m's reading + 1.",
+			"Use wrapping arithmetic.",
+		},
+		Keywords: []string{"integer", "unsigned", "overflow", "wrapping", "checked", "u32", "i64"},
+		SeeAlso:  []string{"struct", "cast"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "decimal",
+		Description: "Exact decimal and whole-number arithmetic",
+		Category:    "concept",
+		LongDesc:    "'the decimal 19.99' is an exact base-10 number and 'the whole number 12' an integer of any size. Decimals are exact for +, - and *, keep their trailing zeros, and round a non-terminating division to 28 significant digits. A decimal mixes with whole numbers but not with fractional numbers; use 'cast to decimal' to convert. Whole numbers never overflow.",
+		Examples: []string{
+			"Declare price to be the decimal 19.99.",
+			"Print price * 3.",
+			"Declare big to be the whole number 2.",
+			"Print 0.1 cast to decimal.",
+		},
+		Keywords: []string{"decimal", "whole number", "money", "precision", "bignum", "exact"},
+		SeeAlso:  []string{"cast", "integer fields"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "enum",
+		Description: "A type whose values are a fixed set of names",
+		Category:    "concept",
+		LongDesc:    "'Declare Color as one of red, green and blue.' declares an enum. Read a member with \"Color's red\", loop over every member with 'For each', and match members in a When statement. Members are only equal to themselves, and 'cast to text' gives the member's name. Comparing members of different enums, or naming a member that does not exist, is a compile-time error.",
+		Examples: []string{
+			"Declare Color as one of red, green and blue.",
+			"Declare c to be Color's green.",
+			"For each shade in Color, do the following:\n    Print shade.\nthats it.",
+		},
+		Keywords: []string{"enum", "enumeration", "one of", "members", "choices"},
+		SeeAlso:  []string{"struct", "when"},
 	})
 
 	// ═══════════════════════════════════════════════════════════════════════════
@@ -594,15 +814,33 @@ func (r *Registry) loadDefaultEntries() {
 		Name:        "import",
 		Description: "Import code from other files",
 		Category:    "keyword",
-		LongDesc:    "Use 'import' to include code from other .abc files. Can import everything or specific functions. Use 'safely' to prevent side effects.",
+		LongDesc:    "Use 'import' to include code from other .abc files. Can import everything or specific functions. Use 'safely' to prevent side effects. Use 'as' to keep the file's names in a module of their own, reached with a possessive. Imports not found in the current directory are looked for in the project root (the directory holding english.project), its libraries/ folder, the directories the manifest lists and those in ENGLISH_PATH; a path without an extension, like \"strings/slugify\", names a library.",
 		Examples: []string{
 			"Import \"utils.abc\".",
 			"Import add and subtract from \"math.abc\".",
 			"Import everything from \"lib.abc\".",
 			"Import all from \"lib.abc\" safely.",
+			"Import \"geometry.abc\" as geo.",
+			"Declare a to be the result of calling geo's area with 3.",
+			"Import \"strings/slugify\".",
 		},
-		Keywords: []string{"include", "require", "module", "load"},
-		SeeAlso:  []string{"function"},
+		Keywords: []string{"include", "require", "module", "load", "library", "project"},
+		SeeAlso:  []string{"function", "private"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "private",
+		Description: "Hide a declaration from files that import this one",
+		Category:    "keyword",
+		LongDesc:    "Put 'privately' after 'Declare', or 'private' before 'function', to keep a variable or function inside its file. Importing everything, importing safely and importing with 'as' leave it out, and naming it in a selective import is a compile-time error. Functions of the same file can still use it.",
+		Examples: []string{
+			"Declare privately cache to be [].",
+			"Declare private function helper that takes x and does the following:",
+			"Import keep from \"cache.abc\".",
+		},
+		Keywords: []string{"privately", "hidden", "export", "public", "library", "helper"},
+		Aliases:  []string{"privately"},
+		SeeAlso:  []string{"import", "declare"},
 	})
 
 	// ═══════════════════════════════════════════════════════════════════════════
@@ -632,7 +870,7 @@ func (r *Registry) loadDefaultEntries() {
 			"Declare result to be pow(3, 4).",
 		},
 		Keywords: []string{"math", "power", "exponent", "exponential"},
-		SeeAlso:  []string{"sqrt"},
+		SeeAlso:  []string{"sqrt", "arithmetic"},
 	})
 
 	r.Register(&HelpEntry{
@@ -1577,6 +1815,67 @@ func (r *Registry) loadDefaultEntries() {
 		SeeAlso:  []string{"flatten"},
 	})
 
+	r.Register(&HelpEntry{
+		Name:        "transform",
+		Description: "Apply a function to every element",
+		Category:    "function",
+		LongDesc:    "Returns a new list holding the result of calling the function on each element of the list.",
+		Examples: []string{
+			"Declare doubled to be transform(numbers, double).",
+			"Declare squares to be transform(numbers, a function that takes x and returns x * x).",
+		},
+		Keywords: []string{"list", "map", "apply", "function"},
+		SeeAlso:  []string{"keep", "combine"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "keep",
+		Description: "Keep elements that pass a test",
+		Category:    "function",
+		LongDesc:    "Returns a new list with only the elements for which the function returns true.",
+		Examples: []string{
+			"Declare evens to be keep(numbers, is_even).",
+		},
+		Keywords: []string{"list", "filter", "select", "function"},
+		SeeAlso:  []string{"transform", "find_first", "count_where"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "combine",
+		Description: "Reduce a list to a single value",
+		Category:    "function",
+		LongDesc:    "Starts from the given value and calls the function with the running result and each element in turn, returning the final result.",
+		Examples: []string{
+			"Declare total to be combine(numbers, add, 0).",
+		},
+		Keywords: []string{"list", "reduce", "fold", "function"},
+		SeeAlso:  []string{"transform", "sum"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "find_first",
+		Description: "Find the first element that passes a test",
+		Category:    "function",
+		LongDesc:    "Returns the first element for which the function returns true, or nothing if none does.",
+		Examples: []string{
+			"Declare first_even to be find_first(numbers, is_even).",
+		},
+		Keywords: []string{"list", "find", "search", "function"},
+		SeeAlso:  []string{"keep", "count_where"},
+	})
+
+	r.Register(&HelpEntry{
+		Name:        "count_where",
+		Description: "Count elements that pass a test",
+		Category:    "function",
+		LongDesc:    "Returns how many elements of the list the function returns true for.",
+		Examples: []string{
+			"Declare even_count to be count_where(numbers, is_even).",
+		},
+		Keywords: []string{"list", "count", "filter", "function"},
+		SeeAlso:  []string{"keep", "find_first"},
+	})
+
 	// ═══════════════════════════════════════════════════════════════════════════
 	// LOOKUP TABLE FUNCTIONS
 	// ═══════════════════════════════════════════════════════════════════════════
@@ -121,7 +121,7 @@ func mapTokenKind(t token.Type) tokenKind {
 		return kindNewline
 
 	// ── Literals ──────────────────────────────────────────────────────────────
-	case token.STRING:
+	case token.STRING, token.INTERPOLATED_STRING:
 		return kindString
 	case token.NUMBER:
 		return kindNumber
@@ -131,7 +131,9 @@ func mapTokenKind(t token.Type) tokenKind {
 		return kindNull
 
 	// ── Operators ─────────────────────────────────────────────────────────────
-	case token.PLUS, token.MINUS, token.STAR, token.SLASH, token.ASSIGN:
+	case token.PLUS, token.MINUS, token.STAR, token.SLASH, token.ASSIGN,
+		token.POWER, token.FLOOR_DIVIDE, token.BIT_AND, token.BIT_OR, token.BIT_XOR,
+		token.SHIFT_LEFT, token.SHIFT_RIGHT:
 		return kindOperator
 
 	// ── Comparison operators ──────────────────────────────────────────────────
@@ -168,7 +170,7 @@ func mapTokenKind(t token.Type) tokenKind {
 		token.BREAK, token.OUT, token.LOOP, token.TIMES,
 		token.FOR, token.EACH, token.DO,
 		token.RETURN, token.CONTINUE, token.SKIP,
-		token.SLEEP:
+		token.SLEEP, token.WHEN:
 		return kindControlFlow
 
 	// ── Declaration keywords ──────────────────────────────────────────────────
@@ -189,11 +191,41 @@ func tokenize(source string) []chunk {
 	tokens := tokeniser.TokenizeForHighlight(source)
 	chunks := make([]chunk, 0, len(tokens))
 	for _, tok := range tokens {
+		if tok.Type == token.INTERPOLATED_STRING {
+			chunks = append(chunks, interpolatedChunks(tok.Value)...)
+			continue
+		}
 		chunks = append(chunks, chunk{kind: mapTokenKind(tok.Type), text: tok.Value})
 	}
 	return chunks
 }
 
+// interpolatedChunks splits an interpolated text literal (quotes included) so
+// that embedded "{expression}" parts are coloured as code rather than text.
+// Malformed or unterminated literals are coloured as plain text.
+func interpolatedChunks(text string) []chunk {
+	whole := []chunk{{kind: kindString, text: text}}
+	if len(text) < 2 || text[len(text)-1] != text[0] {
+		return whole
+	}
+	parts, err := tokeniser.SplitInterpolation(text[1 : len(text)-1])
+	if err != nil {
+		return whole
+	}
+	quote := text[:1]
+	chunks := []chunk{{kind: kindString, text: quote}}
+	for _, part := range parts {
+		if !part.IsExpr {
+			chunks = append(chunks, chunk{kind: kindString, text: part.Raw})
+			continue
+		}
+		chunks = append(chunks, chunk{kind: kindPunctuation, text: "{"})
+		chunks = append(chunks, tokenize(part.Raw)...)
+		chunks = append(chunks, chunk{kind: kindPunctuation, text: "}"})
+	}
+	return append(chunks, chunk{kind: kindString, text: quote})
+}
+
 // ─── Colour rendering ─────────────────────────────────────────────────────────
 
 // applyStyle colours text using the lipgloss style corresponding to kind.
@@ -67,6 +67,18 @@ func TestHighlight_Color_StringLiteral(t *testing.T) {
 	}
 }
 
+func TestHighlight_Color_InterpolatedString(t *testing.T) {
+	source := `Print "Hi {name}, next is {age + 1}".`
+	got := highlight.Highlight(source, true)
+	if stripANSI(got) != source {
+		t.Errorf("expected text preserved, got:\n%s", stripANSI(got))
+	}
+	// The embedded expression is coloured separately from the surrounding text.
+	if strings.Contains(got, "{name}") {
+		t.Errorf("expected embedded expression to be highlighted as code, got:\n%q", got)
+	}
+}
+
 func TestHighlight_Color_NumberLiteral(t *testing.T) {
 	source := `Declare x to be 3.14.`
 	got := stripANSI(highlight.Highlight(source, true))
@@ -1,5 +1,7 @@
 package ivm
 
+import "github.com/Advik-B/english/astvm/types"
+
 // Instruction is a single VM instruction: an opcode plus a 32-bit operand.
 type Instruction struct {
 	Op      Opcode
@@ -13,6 +15,10 @@ type Chunk struct {
 	Code       []Instruction
 	Funcs      []*FuncChunk // user-defined function sub-chunks
 	StructDefs []*StructDef // struct type definitions
+	JumpTables []*JumpTable // constant-case dispatch tables for When statements
+	EnumDefs   []*EnumDef   // enumeration type definitions
+
+	CapabilityDefs []*CapabilityDef // capability declarations
 }
 
 // FuncChunk is the compiled representation of a user-defined function.
@@ -20,13 +26,130 @@ type FuncChunk struct {
 	Name   string
 	Params []string
 	Body   *Chunk
+	// ParamCapabilities holds, per parameter, the capability its argument
+	// must declare ("" for none). It is nil when no parameter asks for one.
+	ParamCapabilities []string
+	// ParamTypes holds, per parameter, the type its argument must have (""
+	// for any). It is nil when no parameter is typed.
+	ParamTypes []string
+	// ReturnType is the type the function's result must have, or "".
+	ReturnType string
+	// Defaults holds, per parameter, a mini-chunk computing the value it
+	// takes when a call leaves it out (nil for a required parameter). It is
+	// nil when no parameter has a default.
+	Defaults []*Chunk
+	// Variadic is true when the last parameter collects any extra
+	// arguments into a list; callFuncChunk packs them at the call.
+	Variadic bool
+	// Generator is true when the body gives back values; calling the
+	// function makes a Generator that runs the body a step at a time.
+	Generator bool
+
+	// env is the scope the function was defined in. It is set on the runtime
+	// copy made by OP_DEFINE_FUNC / OP_MAKE_FUNC (see withEnv) and is never
+	// encoded; calls use it as the parent of the function's own scope.
+	env *ivmEnv
+}
+
+// withEnv returns a copy of fc that closes over env.
+func (fc *FuncChunk) withEnv(env *ivmEnv) *FuncChunk {
+	closure := *fc
+	closure.env = env
+	return &closure
 }
 
 // StructDef is the compiled representation of a struct type declaration.
 type StructDef struct {
 	Name    string
+	Parent  string // structure this one is "a kind of"; empty if none
 	Fields  []*FieldDef
 	Methods []*FuncChunk
+	// Capabilities are the capabilities this structure declared with
+	// "that can"; those of its ancestors are found through parent.
+	Capabilities []string
+
+	// parent is the definition Parent named when OP_DEFINE_STRUCT ran. It is
+	// set only on the runtime copy made by inherit and is never encoded.
+	parent *StructDef
+}
+
+// inherit returns a copy of sd that also has parent's fields and methods.
+// Inherited fields come first, in the parent's order; a field or method that
+// sd declares again replaces the parent's in place.
+func (sd *StructDef) inherit(parent *StructDef) *StructDef {
+	merged := &StructDef{Name: sd.Name, Parent: sd.Parent, Capabilities: sd.Capabilities, parent: parent}
+	merged.Fields = append(merged.Fields, parent.Fields...)
+	for _, fd := range sd.Fields {
+		if i := indexOfField(merged.Fields, fd.Name); i >= 0 {
+			merged.Fields[i] = fd
+		} else {
+			merged.Fields = append(merged.Fields, fd)
+		}
+	}
+	merged.Methods = append(merged.Methods, parent.Methods...)
+	for _, m := range sd.Methods {
+		if i := indexOfMethod(merged.Methods, m.Name); i >= 0 {
+			merged.Methods[i] = m
+		} else {
+			merged.Methods = append(merged.Methods, m)
+		}
+	}
+	return merged
+}
+
+// isA reports whether sd is the structure called name or inherits from it.
+func (sd *StructDef) isA(name string) bool {
+	for d := sd; d != nil; d = d.parent {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// can reports whether sd, or a structure it inherits from, declared the
+// capability called name.
+func (sd *StructDef) can(name string) bool {
+	for d := sd; d != nil; d = d.parent {
+		for _, c := range d.Capabilities {
+			if c == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func indexOfField(fields []*FieldDef, name string) int {
+	for i, fd := range fields {
+		if fd.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOfMethod(methods []*FuncChunk, name string) int {
+	for i, m := range methods {
+		if m.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// field returns the definition of the named field, or nil if sd has no such
+// field (or sd itself is nil).
+func (sd *StructDef) field(name string) *FieldDef {
+	if sd == nil {
+		return nil
+	}
+	for _, fd := range sd.Fields {
+		if fd.Name == name {
+			return fd
+		}
+	}
+	return nil
 }
 
 // FieldDef describes a single struct field.
@@ -36,6 +159,60 @@ type FieldDef struct {
 	DefaultExprChunk *Chunk // compiled default-value expression, or nil
 }
 
+// EnumDef is the compiled representation of an enumeration declaration.
+type EnumDef struct {
+	Name    string
+	Members []string
+}
+
+// CapabilityDef is the compiled representation of a capability declaration.
+type CapabilityDef struct {
+	Name    string
+	Methods []string
+}
+
+// JumpTable maps the constant case values of a When statement to the offset
+// of the matching case body. OP_JUMP_TABLE falls through when the subject is
+// not one of the keys.
+type JumpTable struct {
+	Keys    []interface{} // float64, string, bool or nil
+	Targets []uint32      // Targets[i] is the body offset for Keys[i]
+
+	index map[interface{}]int // key → position in Keys; first occurrence wins
+}
+
+// NewJumpTable builds a table over keys. Targets start at zero and are
+// patched once the case bodies have been emitted.
+func NewJumpTable(keys []interface{}) *JumpTable {
+	jt := &JumpTable{Keys: keys, Targets: make([]uint32, len(keys)), index: map[interface{}]int{}}
+	for i, k := range keys {
+		if _, dup := jt.index[k]; !dup {
+			jt.index[k] = i
+		}
+	}
+	return jt
+}
+
+// Lookup returns the body offset for v. Only the constant kinds can be keys,
+// so any other value misses without being hashed. An integer struct field, a
+// decimal or a whole number matches the number key it equals exactly.
+func (jt *JumpTable) Lookup(v interface{}) (uint32, bool) {
+	if kind := types.Infer(v); types.IsInteger(kind) || types.IsExact(kind) {
+		f, _ := types.Cast(v, types.TypeF64)
+		if eq, _ := types.NumbersEqual(v, f); !eq {
+			return 0, false
+		}
+		v = f
+	}
+	switch v.(type) {
+	case float64, string, bool, nil:
+		if i, ok := jt.index[v]; ok {
+			return jt.Targets[i], true
+		}
+	}
+	return 0, false
+}
+
 // NewChunk allocates an empty Chunk.
 func NewChunk() *Chunk {
 	return &Chunk{
@@ -44,6 +221,10 @@ func NewChunk() *Chunk {
 		Code:       []Instruction{},
 		Funcs:      []*FuncChunk{},
 		StructDefs: []*StructDef{},
+		JumpTables: []*JumpTable{},
+		EnumDefs:   []*EnumDef{},
+
+		CapabilityDefs: []*CapabilityDef{},
 	}
 }
 
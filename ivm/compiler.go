@@ -2,6 +2,7 @@ package ivm
 
 import (
 	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/astvm/types"
 	"fmt"
 )
 
@@ -12,7 +13,10 @@ type Compiler struct {
 	loopContinues    [][]int // like loopEnds: positions of continue JUMPs to patch (for for/for-each)
 	loopEnds         [][]int // positions of break JUMPs to patch to loop end
 	loopScopeDepths  []int   // scope depth at the start of each loop's body
+	loopLabels       []string // name given with "and call this loop …", or ""
+	loopTryDepths    []int   // tryDepth at the start of each loop's body
 	scopeDepth       int     // current number of active scopes (each PUSH_SCOPE increments)
+	tryDepth         int     // number of Try bodies enclosing the code being compiled
 	funcName         string  // name of the function being compiled (for error messages)
 	counter          int     // for generating unique hidden variable names
 }
@@ -46,7 +50,20 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		// nothing
 
 	case *ast.VariableDecl:
-		if s.Value != nil {
+		if s.Names != nil {
+			op := OP_DEFINE_VAR
+			if s.IsConstant {
+				op = OP_DEFINE_CONST
+			}
+			return c.compileUnpack(s.Names, s.Rest, s.Value, op)
+		}
+		if fl, ok := s.Value.(*ast.FunctionLiteral); ok {
+			// Name the function after its variable so errors and the
+			// decompiler can refer to it.
+			if err := c.compileFunctionLiteral(s.Name, fl); err != nil {
+				return err
+			}
+		} else if s.Value != nil {
 			if err := c.compileExpression(s.Value); err != nil {
 				return err
 			}
@@ -82,6 +99,9 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		if s.Line > 0 {
 			c.chunk.Emit(OP_SET_LINE, uint32(s.Line))
 		}
+		if s.Names != nil {
+			return c.compileUnpack(s.Names, s.Rest, s.Value, OP_STORE_VAR)
+		}
 		if err := c.compileExpression(s.Value); err != nil {
 			return err
 		}
@@ -90,6 +110,9 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 
 	case *ast.IndexAssignment:
 		// compile index, compile value
+		if s.Line > 0 {
+			c.chunk.Emit(OP_SET_LINE, uint32(s.Line))
+		}
 		if err := c.compileExpression(s.Index); err != nil {
 			return err
 		}
@@ -101,6 +124,9 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 
 	case *ast.LookupKeyAssignment:
 		// compile key, compile value
+		if s.Line > 0 {
+			c.chunk.Emit(OP_SET_LINE, uint32(s.Line))
+		}
 		if err := c.compileExpression(s.Key); err != nil {
 			return err
 		}
@@ -123,7 +149,7 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 
 	case *ast.FunctionDecl:
 		// Compile function body as a child FuncChunk
-		bodyChunk, err := c.compileFuncBody(s.Name, s.Parameters, s.Body)
+		bodyChunk, err := c.compileFunctionDecl(s)
 		if err != nil {
 			return err
 		}
@@ -144,7 +170,15 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		c.chunk.Emit(OP_POP, 0)
 
 	case *ast.ReturnStatement:
-		if s.Value != nil {
+		if s.Values != nil {
+			// "Return q and r." returns the values together as a list.
+			for _, v := range s.Values {
+				if err := c.compileExpression(v); err != nil {
+					return err
+				}
+			}
+			c.chunk.Emit(OP_BUILD_LIST, uint32(len(s.Values)))
+		} else if s.Value != nil {
 			if err := c.compileExpression(s.Value); err != nil {
 				return err
 			}
@@ -153,6 +187,41 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		}
 		c.chunk.Emit(OP_RETURN, 0)
 
+	case *ast.YieldStatement:
+		if err := c.compileExpression(s.Value); err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_YIELD, 0)
+
+	case *ast.TestBlock:
+		// Tests run only under "english test"; see RunTest.
+
+	case *ast.BackgroundStatement:
+		// The body becomes a function with no parameters that the task runs.
+		fc, err := c.compileFuncBody(s.Name, nil, s.Body)
+		if err != nil {
+			return err
+		}
+		funcIdx := uint32(len(c.chunk.Funcs))
+		c.chunk.Funcs = append(c.chunk.Funcs, fc)
+		c.chunk.Emit(OP_SPAWN, funcIdx)
+		c.chunk.Emit(OP_STORE_VAR, c.chunk.AddName(s.Name))
+
+	case *ast.WaitStatement:
+		if err := c.compileExpression(s.Task); err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_WAIT, 0)
+
+	case *ast.SendStatement:
+		if err := c.compileExpression(s.Value); err != nil {
+			return err
+		}
+		if err := c.compileExpression(s.Channel); err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_SEND, 0)
+
 	case *ast.OutputStatement:
 		count := uint32(len(s.Values))
 		for _, v := range s.Values {
@@ -171,6 +240,11 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 			return err
 		}
 
+	case *ast.WhenStatement:
+		if err := c.compileWhenStatement(s); err != nil {
+			return err
+		}
+
 	case *ast.WhileLoop:
 		if err := c.compileWhileLoop(s); err != nil {
 			return err
@@ -195,42 +269,46 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		n2 := c.chunk.AddName(s.Name2)
 		c.chunk.Emit(OP_SWAP_VARS, n1<<16|n2)
 
+	case *ast.OverflowModeStatement:
+		var wrap uint32
+		if s.Wrap {
+			wrap = 1
+		}
+		c.chunk.Emit(OP_SET_OVERFLOW_MODE, wrap)
+
 	case *ast.BreakStatement:
 		if len(c.loopEnds) == 0 {
 			return fmt.Errorf("break outside loop")
 		}
-		// pop ALL scopes including the loop body scope (exit the loop entirely)
-		loopBodyDepth := c.loopScopeDepths[len(c.loopScopeDepths)-1]
-		for c.scopeDepth >= loopBodyDepth {
-			c.chunk.Emit(OP_POP_SCOPE, 0)
-			c.scopeDepth--
+		loop, err := c.loopNamed(s.Label)
+		if err != nil {
+			return err
 		}
+		// pop ALL scopes including the loop body scope (exit the loop entirely)
+		c.emitEscapePops(loop)
 		pos := c.chunk.CurrentPos()
 		c.chunk.Emit(OP_JUMP, 0) // placeholder
-		last := len(c.loopEnds) - 1
-		c.loopEnds[last] = append(c.loopEnds[last], pos)
+		c.loopEnds[loop] = append(c.loopEnds[loop], pos)
 
 	case *ast.ContinueStatement:
 		if len(c.loopStarts) == 0 {
 			return fmt.Errorf("continue outside loop")
 		}
+		loop, err := c.loopNamed(s.Label)
+		if err != nil {
+			return err
+		}
 		// pop ALL scopes including the loop body scope, then jump to the continue target.
 		// The loop will re-push a fresh scope for the next iteration.
-		loopBodyDepth := c.loopScopeDepths[len(c.loopScopeDepths)-1]
-		for c.scopeDepth >= loopBodyDepth {
-			c.chunk.Emit(OP_POP_SCOPE, 0)
-			c.scopeDepth--
-		}
-		last := len(c.loopContinues) - 1
-		if last >= 0 && c.loopContinues[last] != nil {
+		c.emitEscapePops(loop)
+		if c.loopContinues[loop] != nil {
 			// for/for-each loop: emit patchable JUMP (patched to increment/decrement pos after body)
 			pos := c.chunk.CurrentPos()
 			c.chunk.Emit(OP_JUMP, 0) // placeholder
-			c.loopContinues[last] = append(c.loopContinues[last], pos)
+			c.loopContinues[loop] = append(c.loopContinues[loop], pos)
 		} else {
 			// while loop: continue target is loopStart (known at compile time)
-			start := c.loopStarts[len(c.loopStarts)-1]
-			c.chunk.Emit(OP_JUMP, uint32(start))
+			c.chunk.Emit(OP_JUMP, uint32(c.loopStarts[loop]))
 		}
 
 	case *ast.TryStatement:
@@ -248,6 +326,9 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		}
 		c.chunk.Emit(OP_RAISE, typeIdx)
 
+	case *ast.AssertStatement:
+		return c.compileAssert(s)
+
 	case *ast.ErrorTypeDecl:
 		nIdx := c.chunk.AddName(s.Name)
 		var pIdx uint32
@@ -256,15 +337,31 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 		}
 		c.chunk.Emit(OP_DEFINE_ERROR_TYPE, nIdx<<16|pIdx)
 
+	case *ast.EnumDecl:
+		enumIdx := uint32(len(c.chunk.EnumDefs))
+		c.chunk.EnumDefs = append(c.chunk.EnumDefs, &EnumDef{Name: s.Name, Members: s.Members})
+		c.chunk.Emit(OP_DEFINE_ENUM, enumIdx)
+
+	case *ast.CapabilityDecl:
+		capIdx := uint32(len(c.chunk.CapabilityDefs))
+		c.chunk.CapabilityDefs = append(c.chunk.CapabilityDefs, &CapabilityDef{Name: s.Name, Methods: s.Methods})
+		c.chunk.Emit(OP_DEFINE_CAPABILITY, capIdx)
+
 	case *ast.StructDecl:
 		if err := c.compileStructDecl(s); err != nil {
 			return err
 		}
 
 	case *ast.ImportStatement:
+		if s.Line > 0 {
+			c.chunk.Emit(OP_SET_LINE, uint32(s.Line))
+		}
 		// Push the items count constant and path
-		// operand = importAll<<2 | isSafe<<1 | hasItems
+		// operand = hasAlias<<3 | importAll<<2 | isSafe<<1 | hasItems
 		flags := uint32(0)
+		if s.Alias != "" {
+			flags |= 8
+		}
 		if s.ImportAll {
 			flags |= 4
 		}
@@ -286,6 +383,11 @@ func (c *Compiler) compileStatement(stmt ast.Statement) error {
 			itemsIdx := c.chunk.AddConst(items)
 			c.chunk.Emit(OP_LOAD_CONST, itemsIdx)
 		}
+		// Push the module name for "as"
+		if s.Alias != "" {
+			aliasIdx := c.chunk.AddConst(s.Alias)
+			c.chunk.Emit(OP_LOAD_CONST, aliasIdx)
+		}
 		c.chunk.Emit(OP_IMPORT, flags)
 
 	default:
@@ -300,6 +402,14 @@ func (c *Compiler) compileExpression(expr ast.Expression) error {
 		idx := c.chunk.AddConst(e.Value)
 		c.chunk.Emit(OP_LOAD_CONST, idx)
 
+	case *ast.DecimalLiteral:
+		value, err := types.ParseExact(e.Digits, e.Whole)
+		if err != nil {
+			return fmt.Errorf("ivm compiler: %v", err)
+		}
+		idx := c.chunk.AddConst(value)
+		c.chunk.Emit(OP_LOAD_CONST, idx)
+
 	case *ast.StringLiteral:
 		idx := c.chunk.AddConst(e.Value)
 		c.chunk.Emit(OP_LOAD_CONST, idx)
@@ -333,6 +443,26 @@ func (c *Compiler) compileExpression(expr ast.Expression) error {
 			return fmt.Errorf("unknown unary operator: %s", e.Operator)
 		}
 
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral("anonymous", e)
+
+	case *ast.ChannelLiteral:
+		c.chunk.Emit(OP_NEW_CHANNEL, 0)
+
+	case *ast.ReceiveExpression:
+		if err := c.compileExpression(e.Channel); err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_RECEIVE, 0)
+
+	case *ast.InterpolatedString:
+		for _, part := range e.Parts {
+			if err := c.compileExpression(part); err != nil {
+				return err
+			}
+		}
+		c.chunk.Emit(OP_BUILD_STRING, uint32(len(e.Parts)))
+
 	case *ast.ListLiteral:
 		for _, elem := range e.Elements {
 			if err := c.compileExpression(elem); err != nil {
@@ -389,27 +519,23 @@ func (c *Compiler) compileExpression(expr ast.Expression) error {
 		c.chunk.Emit(OP_LENGTH, 0)
 
 	case *ast.FunctionCall:
-		for _, arg := range e.Arguments {
-			if err := c.compileExpression(arg); err != nil {
-				return err
-			}
+		counts, err := c.compileArguments(e.Name, e.Arguments, e.NamedArguments)
+		if err != nil {
+			return err
 		}
 		nIdx := c.chunk.AddName(e.Name)
-		argc := uint32(len(e.Arguments))
-		c.chunk.Emit(OP_CALL, argc<<16|nIdx)
+		c.chunk.Emit(OP_CALL, counts|nIdx)
 
 	case *ast.MethodCall:
 		if err := c.compileExpression(e.Object); err != nil {
 			return err
 		}
-		for _, arg := range e.Arguments {
-			if err := c.compileExpression(arg); err != nil {
-				return err
-			}
+		counts, err := c.compileArguments(e.MethodName, e.Arguments, e.NamedArguments)
+		if err != nil {
+			return err
 		}
 		mIdx := c.chunk.AddName(e.MethodName)
-		argc := uint32(len(e.Arguments))
-		c.chunk.Emit(OP_CALL_METHOD, argc<<16|mIdx)
+		c.chunk.Emit(OP_CALL_METHOD, counts|mIdx)
 
 	case *ast.LocationExpression:
 		nIdx := c.chunk.AddName(e.Name)
@@ -559,6 +685,64 @@ func (c *Compiler) compileBinaryExpr(e *ast.BinaryExpression) error {
 	return nil
 }
 
+// compileAssert compiles "Make sure that <condition>, otherwise say
+// <message>." to:
+//
+//	ASSERT -> end
+//	LOAD_CONST text
+//	<condition>                                 ASSERT_CHECK 0 -> end
+//	  or, for a comparison:
+//	LOAD_CONST left text; <left>; LOAD_CONST right text; <right>; ASSERT_CHECK op -> end
+//	<message> or LOAD_NOTHING
+//	ASSERT_FAILED
+//	end:
+//
+// A comparison's sides are kept on the stack so the AssertionError can show
+// what each one was, and the message is only worked out when the check fails.
+func (c *Compiler) compileAssert(s *ast.AssertStatement) error {
+	if s.Line > 0 {
+		c.chunk.Emit(OP_SET_LINE, uint32(s.Line))
+	}
+	skip := c.chunk.CurrentPos()
+	c.chunk.Emit(OP_ASSERT, 0)
+	c.chunk.Emit(OP_LOAD_CONST, c.chunk.AddConst(s.Text))
+
+	var kind uint32
+	if be, ok := s.Condition.(*ast.BinaryExpression); ok && len(s.Operands) == 2 {
+		binOp, err := parseBinOp(be.Operator)
+		if err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_LOAD_CONST, c.chunk.AddConst(s.Operands[0]))
+		if err := c.compileExpression(be.Left); err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_LOAD_CONST, c.chunk.AddConst(s.Operands[1]))
+		if err := c.compileExpression(be.Right); err != nil {
+			return err
+		}
+		kind = (uint32(binOp) + 1) << 24
+	} else if err := c.compileExpression(s.Condition); err != nil {
+		return err
+	}
+	check := c.chunk.CurrentPos()
+	c.chunk.Emit(OP_ASSERT_CHECK, kind)
+
+	if s.Message != nil {
+		if err := c.compileExpression(s.Message); err != nil {
+			return err
+		}
+	} else {
+		c.chunk.Emit(OP_LOAD_NOTHING, 0)
+	}
+	c.chunk.Emit(OP_ASSERT_FAILED, 0)
+
+	end := uint32(c.chunk.CurrentPos())
+	c.chunk.PatchJump(skip, end)
+	c.chunk.PatchJump(check, kind|end)
+	return nil
+}
+
 func parseBinOp(op string) (BinOp, error) {
 	switch op {
 	case "+":
@@ -571,6 +755,20 @@ func parseBinOp(op string) (BinOp, error) {
 		return BinDiv, nil
 	case "%":
 		return BinMod, nil
+	case "**":
+		return BinPow, nil
+	case "//":
+		return BinFloorDiv, nil
+	case "&":
+		return BinBitAnd, nil
+	case "|":
+		return BinBitOr, nil
+	case "^":
+		return BinBitXor, nil
+	case "<<":
+		return BinShl, nil
+	case ">>":
+		return BinShr, nil
 	case "is equal to":
 		return BinEq, nil
 	case "is not equal to":
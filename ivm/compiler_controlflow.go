@@ -1,6 +1,9 @@
 package ivm
 
-import "github.com/Advik-B/english/ast"
+import (
+	"fmt"
+	"github.com/Advik-B/english/ast"
+)
 
 func (c *Compiler) compileIfStatement(s *ast.IfStatement) error {
 	if err := c.compileExpression(s.Condition); err != nil {
@@ -82,6 +85,8 @@ func (c *Compiler) compileWhileLoop(s *ast.WhileLoop) error {
 	c.loopContinues = append(c.loopContinues, nil) // nil = while loop: continue uses loopStarts
 	c.loopEnds = append(c.loopEnds, []int{})
 	c.loopScopeDepths = append(c.loopScopeDepths, loopBodyDepth)
+	c.loopLabels = append(c.loopLabels, s.Label)
+	c.loopTryDepths = append(c.loopTryDepths, c.tryDepth)
 
 	if err := c.compileStatements(s.Body); err != nil {
 		return err
@@ -106,6 +111,8 @@ func (c *Compiler) compileWhileLoop(s *ast.WhileLoop) error {
 	c.loopContinues = c.loopContinues[:len(c.loopContinues)-1]
 	c.loopEnds = c.loopEnds[:len(c.loopEnds)-1]
 	c.loopScopeDepths = c.loopScopeDepths[:len(c.loopScopeDepths)-1]
+	c.loopLabels = c.loopLabels[:len(c.loopLabels)-1]
+	c.loopTryDepths = c.loopTryDepths[:len(c.loopTryDepths)-1]
 	return nil
 }
 
@@ -141,6 +148,8 @@ func (c *Compiler) compileForLoop(s *ast.ForLoop) error {
 	c.loopContinues = append(c.loopContinues, []int{}) // for loop: continue needs patchable JUMP
 	c.loopEnds = append(c.loopEnds, []int{})
 	c.loopScopeDepths = append(c.loopScopeDepths, loopBodyDepth)
+	c.loopLabels = append(c.loopLabels, s.Label)
+	c.loopTryDepths = append(c.loopTryDepths, c.tryDepth)
 
 	if err := c.compileStatements(s.Body); err != nil {
 		return err
@@ -176,38 +185,31 @@ func (c *Compiler) compileForLoop(s *ast.ForLoop) error {
 	c.loopContinues = c.loopContinues[:len(c.loopContinues)-1]
 	c.loopEnds = c.loopEnds[:len(c.loopEnds)-1]
 	c.loopScopeDepths = c.loopScopeDepths[:len(c.loopScopeDepths)-1]
+	c.loopLabels = c.loopLabels[:len(c.loopLabels)-1]
+	c.loopTryDepths = c.loopTryDepths[:len(c.loopTryDepths)-1]
 	return nil
 }
 
 func (c *Compiler) compileForEachLoop(s *ast.ForEachLoop) error {
 	// for each item in list:
-	// compile list; define __each_list; define __each_idx = 0
-	// LOOP_START: LOAD __each_idx; LOAD __each_list; LENGTH; LT; JUMP_IF_FALSE -> LOOP_END
-	// PUSH_SCOPE; define item = __each_list[__each_idx]; ...body...; POP_SCOPE
-	// LOAD __each_idx; LOAD 1; ADD; STORE __each_idx; JUMP -> LOOP_START; LOOP_END:
-	listName := c.nextHidden()
-	idxName := c.nextHidden()
+	// compile list; ITER; define __each_iter
+	// LOOP_START: LOAD __each_iter; ITER_NEXT -> LOOP_END
+	// PUSH_SCOPE; define item; ...body...; POP_SCOPE
+	// JUMP -> LOOP_START; LOOP_END:
+	iterName := c.nextHidden()
 
 	if err := c.compileExpression(s.List); err != nil {
 		return err
 	}
-	listIdx := c.chunk.AddName(listName)
-	c.chunk.Emit(OP_DEFINE_VAR, listIdx)
-
-	startIdx := c.chunk.AddConst(float64(0)) // 0-based indexing
-	c.chunk.Emit(OP_LOAD_CONST, startIdx)
-	idxIdx := c.chunk.AddName(idxName)
-	c.chunk.Emit(OP_DEFINE_VAR, idxIdx)
+	c.chunk.Emit(OP_ITER, 0)
+	iterIdx := c.chunk.AddName(iterName)
+	c.chunk.Emit(OP_DEFINE_VAR, iterIdx)
 
 	loopStart := c.chunk.CurrentPos()
 
-	c.chunk.Emit(OP_LOAD_VAR, idxIdx)
-	c.chunk.Emit(OP_LOAD_VAR, listIdx)
-	c.chunk.Emit(OP_LENGTH, 0)
-	c.chunk.Emit(OP_BINARY_OP, uint32(BinLt)) // idx < len (0-based: stop when idx == len)
-
+	c.chunk.Emit(OP_LOAD_VAR, iterIdx)
 	exitJump := c.chunk.CurrentPos()
-	c.chunk.Emit(OP_JUMP_IF_FALSE, 0)
+	c.chunk.Emit(OP_ITER_NEXT, 0)
 
 	c.chunk.Emit(OP_PUSH_SCOPE, 0)
 	c.scopeDepth++
@@ -217,11 +219,10 @@ func (c *Compiler) compileForEachLoop(s *ast.ForEachLoop) error {
 	c.loopContinues = append(c.loopContinues, []int{}) // for-each: continue needs patchable JUMP
 	c.loopEnds = append(c.loopEnds, []int{})
 	c.loopScopeDepths = append(c.loopScopeDepths, loopBodyDepth)
+	c.loopLabels = append(c.loopLabels, s.Label)
+	c.loopTryDepths = append(c.loopTryDepths, c.tryDepth)
 
-	// define loop variable
-	c.chunk.Emit(OP_LOAD_VAR, listIdx)
-	c.chunk.Emit(OP_LOAD_VAR, idxIdx)
-	c.chunk.Emit(OP_INDEX_GET, 0)
+	// define loop variable from the item ITER_NEXT pushed
 	itemIdx := c.chunk.AddName(s.Item)
 	c.chunk.Emit(OP_DEFINE_VAR, itemIdx)
 
@@ -233,35 +234,58 @@ func (c *Compiler) compileForEachLoop(s *ast.ForEachLoop) error {
 	c.chunk.Emit(OP_POP_SCOPE, 0)
 	c.scopeDepth--
 
-	// increment index — continue jumps land here
-	incrementPos := uint32(c.chunk.CurrentPos())
-	c.chunk.Emit(OP_LOAD_VAR, idxIdx)
-	oneIdx := c.chunk.AddConst(float64(1))
-	c.chunk.Emit(OP_LOAD_CONST, oneIdx)
-	c.chunk.Emit(OP_BINARY_OP, uint32(BinAdd))
-	c.chunk.Emit(OP_STORE_VAR, idxIdx)
-
 	c.chunk.Emit(OP_JUMP, uint32(loopStart))
 
 	loopEnd := uint32(c.chunk.CurrentPos())
 	c.chunk.PatchJump(exitJump, loopEnd)
 
-	// Patch break and continue jumps
+	// Patch break and continue jumps; continue asks for the next item
 	breaks := c.loopEnds[len(c.loopEnds)-1]
 	for _, pos := range breaks {
 		c.chunk.PatchJump(pos, loopEnd)
 	}
 	conts := c.loopContinues[len(c.loopContinues)-1]
 	for _, pos := range conts {
-		c.chunk.PatchJump(pos, incrementPos)
+		c.chunk.PatchJump(pos, uint32(loopStart))
 	}
 	c.loopStarts = c.loopStarts[:len(c.loopStarts)-1]
 	c.loopContinues = c.loopContinues[:len(c.loopContinues)-1]
 	c.loopEnds = c.loopEnds[:len(c.loopEnds)-1]
 	c.loopScopeDepths = c.loopScopeDepths[:len(c.loopScopeDepths)-1]
+	c.loopLabels = c.loopLabels[:len(c.loopLabels)-1]
+	c.loopTryDepths = c.loopTryDepths[:len(c.loopTryDepths)-1]
 	return nil
 }
 
+// loopNamed returns the index, in the loop stacks, of the loop a break or
+// continue leaves: the innermost one, or the one called label.
+func (c *Compiler) loopNamed(label string) (int, error) {
+	if label == "" {
+		return len(c.loopLabels) - 1, nil
+	}
+	for i := len(c.loopLabels) - 1; i >= 0; i-- {
+		if c.loopLabels[i] == label {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("there is no loop called '%s' around this statement", label)
+}
+
+// emitEscapePops closes every scope down to and including the body scope
+// of the given loop, and drops the try frame of every Try between here and
+// that loop, for a break or continue jumping out of them. The scopes stay
+// open for the code after the jump, so scopeDepth is left alone. Each scope
+// pop has operand 1, marking it as an early exit rather than the end of a
+// block.
+func (c *Compiler) emitEscapePops(loop int) {
+	if n := c.tryDepth - c.loopTryDepths[loop]; n > 0 {
+		c.chunk.Emit(OP_TRY_POP, uint32(n))
+	}
+	for d := c.scopeDepth; d >= c.loopScopeDepths[loop]; d-- {
+		c.chunk.Emit(OP_POP_SCOPE, 1)
+	}
+}
+
 func (c *Compiler) compileTryStatement(s *ast.TryStatement) error {
 	// Layout (no finally):
 	//   TRY_BEGIN(catch_offset)
@@ -311,9 +335,11 @@ func (c *Compiler) compileTryStatement(s *ast.TryStatement) error {
 		c.chunk.Emit(OP_TRY_SET_FINALLY, 0) // placeholder; patched below
 	}
 
+	c.tryDepth++
 	if err := c.compileStatements(s.TryBody); err != nil {
 		return err
 	}
+	c.tryDepth--
 
 	tryEndPos := c.chunk.CurrentPos()
 	c.chunk.Emit(OP_TRY_END, 0) // placeholder for end_offset (past catch body, before finally)
@@ -366,3 +392,179 @@ func (c *Compiler) compileTryStatement(s *ast.TryStatement) error {
 
 	return nil
 }
+
+// compileWhenStatement compiles a When statement. The subject is stored in a
+// hidden variable. Leading cases whose patterns are all constants dispatch
+// through a jump table; the remaining cases are tested in order, laid out
+// like an if/otherwise-if chain:
+//
+//	<subject>; DEFINE_VAR h
+//	LOAD_VAR h; JUMP_TABLE t               (only with constant cases)
+//	<tests and bodies of the remaining cases, then Otherwise>
+//	JUMP end                               (only with constant cases)
+//	<constant case bodies, each followed by JUMP end>
+//	end:
+func (c *Compiler) compileWhenStatement(s *ast.WhenStatement) error {
+	if err := c.compileExpression(s.Subject); err != nil {
+		return err
+	}
+	subj := c.chunk.AddName(c.nextHidden())
+	c.chunk.Emit(OP_DEFINE_VAR, subj)
+
+	var keys []interface{}
+	var keyCases []int
+	split := 0
+	for ; split < len(s.Cases); split++ {
+		caseKeys, ok := constantPatternKeys(s.Cases[split].Patterns)
+		if !ok {
+			break
+		}
+		for _, k := range caseKeys {
+			keys = append(keys, k)
+			keyCases = append(keyCases, split)
+		}
+	}
+	var table *JumpTable
+	if split > 0 {
+		table = NewJumpTable(keys)
+		c.chunk.JumpTables = append(c.chunk.JumpTables, table)
+		c.chunk.Emit(OP_LOAD_VAR, subj)
+		c.chunk.Emit(OP_JUMP_TABLE, uint32(len(c.chunk.JumpTables)-1))
+	}
+
+	rest := s.Cases[split:]
+	var chainJumps []int
+	for i, wc := range rest {
+		if err := c.compileWhenTest(subj, wc.Patterns); err != nil {
+			return err
+		}
+		skipPos := c.chunk.CurrentPos()
+		c.chunk.Emit(OP_JUMP_IF_FALSE, 0)
+		if err := c.compileScopedBlock(wc.Body); err != nil {
+			return err
+		}
+		if i < len(rest)-1 || len(s.Otherwise) > 0 {
+			chainJumps = append(chainJumps, c.chunk.CurrentPos())
+			c.chunk.Emit(OP_JUMP, 0)
+		}
+		c.chunk.PatchJump(skipPos, uint32(c.chunk.CurrentPos()))
+	}
+	if len(s.Otherwise) > 0 {
+		if err := c.compileScopedBlock(s.Otherwise); err != nil {
+			return err
+		}
+	}
+	chainEnd := uint32(c.chunk.CurrentPos())
+	for _, pos := range chainJumps {
+		c.chunk.PatchJump(pos, chainEnd)
+	}
+	if table == nil {
+		return nil
+	}
+
+	endJumps := []int{c.chunk.CurrentPos()}
+	c.chunk.Emit(OP_JUMP, 0)
+	for ci := 0; ci < split; ci++ {
+		start := uint32(c.chunk.CurrentPos())
+		for i, kc := range keyCases {
+			if kc == ci {
+				table.Targets[i] = start
+			}
+		}
+		if err := c.compileScopedBlock(s.Cases[ci].Body); err != nil {
+			return err
+		}
+		if ci < split-1 {
+			endJumps = append(endJumps, c.chunk.CurrentPos())
+			c.chunk.Emit(OP_JUMP, 0)
+		}
+	}
+	endPos := uint32(c.chunk.CurrentPos())
+	for _, pos := range endJumps {
+		c.chunk.PatchJump(pos, endPos)
+	}
+	return nil
+}
+
+// compileScopedBlock compiles body inside its own scope.
+func (c *Compiler) compileScopedBlock(body []ast.Statement) error {
+	c.chunk.Emit(OP_PUSH_SCOPE, 0)
+	c.scopeDepth++
+	if err := c.compileStatements(body); err != nil {
+		return err
+	}
+	c.chunk.Emit(OP_POP_SCOPE, 0)
+	c.scopeDepth--
+	return nil
+}
+
+// constantPatternKeys returns the jump-table keys for a case whose patterns
+// are all literal values, or false if any pattern needs a runtime test.
+func constantPatternKeys(patterns []ast.WhenPattern) ([]interface{}, bool) {
+	keys := make([]interface{}, 0, len(patterns))
+	for _, pattern := range patterns {
+		vp, ok := pattern.(*ast.ValuePattern)
+		if !ok {
+			return nil, false
+		}
+		switch v := vp.Value.(type) {
+		case *ast.NumberLiteral:
+			keys = append(keys, v.Value)
+		case *ast.StringLiteral:
+			keys = append(keys, v.Value)
+		case *ast.BooleanLiteral:
+			keys = append(keys, v.Value)
+		case *ast.NothingLiteral:
+			keys = append(keys, nil)
+		default:
+			return nil, false
+		}
+	}
+	return keys, true
+}
+
+// compileWhenTest leaves true on the stack when the subject in hidden
+// variable subj matches any of patterns. Alternatives short-circuit with the
+// same layout as "or".
+func (c *Compiler) compileWhenTest(subj uint32, patterns []ast.WhenPattern) error {
+	if err := c.compileWhenPattern(subj, patterns[0]); err != nil {
+		return err
+	}
+	for _, pattern := range patterns[1:] {
+		trueJump := c.chunk.CurrentPos()
+		c.chunk.Emit(OP_JUMP_IF_TRUE, 0)
+		if err := c.compileWhenPattern(subj, pattern); err != nil {
+			return err
+		}
+		endJump := c.chunk.CurrentPos()
+		c.chunk.Emit(OP_JUMP, 0)
+		c.chunk.PatchJump(trueJump, uint32(c.chunk.CurrentPos()))
+		c.chunk.Emit(OP_LOAD_CONST, c.chunk.AddConst(true))
+		c.chunk.PatchJump(endJump, uint32(c.chunk.CurrentPos()))
+	}
+	return nil
+}
+
+func (c *Compiler) compileWhenPattern(subj uint32, pattern ast.WhenPattern) error {
+	c.chunk.Emit(OP_LOAD_VAR, subj)
+	switch p := pattern.(type) {
+	case *ast.ValuePattern:
+		if err := c.compileExpression(p.Value); err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_BINARY_OP, uint32(BinEq))
+	case *ast.RangePattern:
+		if err := c.compileExpression(p.Low); err != nil {
+			return err
+		}
+		if err := c.compileExpression(p.High); err != nil {
+			return err
+		}
+		c.chunk.Emit(OP_IN_RANGE, 0)
+	case *ast.TypePattern:
+		c.chunk.Emit(OP_IS_TYPE, c.chunk.AddName(p.TypeName))
+	default:
+		return fmt.Errorf("unsupported pattern type %T", pattern)
+	}
+	return nil
+}
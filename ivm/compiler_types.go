@@ -1,9 +1,13 @@
 package ivm
 
-import "github.com/Advik-B/english/ast"
+import (
+	"fmt"
+
+	"github.com/Advik-B/english/ast"
+)
 
 func (c *Compiler) compileStructDecl(s *ast.StructDecl) error {
-	sd := &StructDef{Name: s.Name}
+	sd := &StructDef{Name: s.Name, Parent: s.Parent, Capabilities: s.Capabilities}
 
 	// Compile fields
 	for _, field := range s.Fields {
@@ -25,7 +29,7 @@ func (c *Compiler) compileStructDecl(s *ast.StructDecl) error {
 
 	// Compile methods
 	for _, method := range s.Methods {
-		fc, err := c.compileFuncBody(method.Name, method.Parameters, method.Body)
+		fc, err := c.compileFunctionDecl(method)
 		if err != nil {
 			return err
 		}
@@ -39,18 +43,14 @@ func (c *Compiler) compileStructDecl(s *ast.StructDecl) error {
 }
 
 func (c *Compiler) compileStructInstantiation(e *ast.StructInstantiation) error {
-	// We need the StructDef to know field order and defaults.
-	// Since we don't have access to the struct definition at compile time,
-	// we compile the provided fields in the order they appear in FieldOrder,
-	// and let the machine look up defaults for missing fields.
-	// Stack: [field_value1, field_value2, ...] for each field in FieldOrder
-	// Then the machine uses NEW_STRUCT to construct the instance.
-
-	// Push the struct name so the machine can look up the def
+	// The struct definition may come from an import, so its field order is
+	// not known here. Each given field is pushed as a name constant followed
+	// by its value, and OP_NEW_STRUCT matches the pairs to the definition by
+	// name, filling in defaults for the rest.
 	snIdx := c.chunk.AddName(e.StructName)
 
-	// Push each specified field in order
 	for _, fieldName := range e.FieldOrder {
+		c.chunk.Emit(OP_LOAD_CONST, c.chunk.AddConst(fieldName))
 		val, ok := e.FieldValues[fieldName]
 		if !ok {
 			c.chunk.Emit(OP_LOAD_NOTHING, 0)
@@ -61,12 +61,102 @@ func (c *Compiler) compileStructInstantiation(e *ast.StructInstantiation) error
 		}
 	}
 
-	// Encode field_count<<16 | struct_name_idx
+	// Encode pair_count<<16 | struct_name_idx
 	fieldCount := uint32(len(e.FieldOrder))
 	c.chunk.Emit(OP_NEW_STRUCT, fieldCount<<16|snIdx)
 	return nil
 }
 
+// compileFunctionLiteral compiles an anonymous function into a child FuncChunk
+// and emits OP_MAKE_FUNC, leaving the function value on the stack.
+func (c *Compiler) compileFunctionLiteral(name string, fl *ast.FunctionLiteral) error {
+	bodyChunk, err := c.compileFuncBody(name, fl.Parameters, fl.Body)
+	if err != nil {
+		return err
+	}
+	funcIdx := uint32(len(c.chunk.Funcs))
+	c.chunk.Funcs = append(c.chunk.Funcs, bodyChunk)
+	c.chunk.Emit(OP_MAKE_FUNC, funcIdx)
+	return nil
+}
+
+// compileFunctionDecl compiles a declared function or method, including what
+// its parameters require, the types in its signature, the mini-chunks
+// computing their defaults, whether the last one collects the rest and
+// whether it is a generator.
+func (c *Compiler) compileFunctionDecl(fd *ast.FunctionDecl) (*FuncChunk, error) {
+	fc, err := c.compileFuncBody(fd.Name, fd.Parameters, fd.Body)
+	if err != nil {
+		return nil, err
+	}
+	fc.ParamCapabilities = fd.ParamCapabilities
+	fc.ParamTypes = fd.ParamTypes
+	fc.ReturnType = fd.ReturnType
+	fc.Variadic = fd.Variadic
+	fc.Generator = fd.Generator
+	if fd.Defaults != nil {
+		fc.Defaults = make([]*Chunk, len(fd.Defaults))
+		for i, def := range fd.Defaults {
+			if def == nil {
+				continue
+			}
+			subComp := &Compiler{chunk: NewChunk()}
+			if err := subComp.compileExpression(def); err != nil {
+				return nil, err
+			}
+			subComp.chunk.Emit(OP_RETURN, 0)
+			fc.Defaults[i] = subComp.chunk
+		}
+	}
+	return fc, nil
+}
+
+// compileArguments pushes a call's positional arguments, then each named one
+// as a name constant followed by its value, and returns the call operand's
+// counts: argc<<16 | kwc<<24.
+func (c *Compiler) compileArguments(name string, args []ast.Expression, named []*ast.NamedArgument) (uint32, error) {
+	if len(args) > 0xFF || len(named) > 0xFF {
+		return 0, fmt.Errorf("too many arguments in the call to '%s' (at most 255 of each kind)", name)
+	}
+	for _, arg := range args {
+		if err := c.compileExpression(arg); err != nil {
+			return 0, err
+		}
+	}
+	for _, na := range named {
+		c.chunk.Emit(OP_LOAD_CONST, c.chunk.AddConst(na.Name))
+		if err := c.compileExpression(na.Value); err != nil {
+			return 0, err
+		}
+	}
+	return uint32(len(named))<<24 | uint32(len(args))<<16, nil
+}
+
+// unpackRest is set in OP_UNPACK's operand, above the count, when the last
+// name collects the items left over ("and the rest as others").
+const unpackRest = 1 << 31
+
+// compileUnpack compiles a destructuring declaration or assignment: the
+// value, the names as constants, OP_UNPACK, then store, which is
+// OP_DEFINE_VAR, OP_DEFINE_CONST or OP_STORE_VAR, once per name.
+func (c *Compiler) compileUnpack(names []string, rest bool, value ast.Expression, store Opcode) error {
+	if err := c.compileExpression(value); err != nil {
+		return err
+	}
+	for _, name := range names {
+		c.chunk.Emit(OP_LOAD_CONST, c.chunk.AddConst(name))
+	}
+	operand := uint32(len(names))
+	if rest {
+		operand |= unpackRest
+	}
+	c.chunk.Emit(OP_UNPACK, operand)
+	for _, name := range names {
+		c.chunk.Emit(store, c.chunk.AddName(name))
+	}
+	return nil
+}
+
 func (c *Compiler) compileFuncBody(name string, params []string, body []ast.Statement) (*FuncChunk, error) {
 	subComp := &Compiler{
 		chunk:    NewChunk(),
@@ -9,14 +9,23 @@ package ivm
 // would produce from the original source (modulo comment loss).
 
 import (
+	"github.com/Advik-B/english/astvm/types"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 )
 
 // Decompile decompiles chunk and returns Python source code.
 func Decompile(chunk *Chunk) string {
+	return DecompileFile(chunk, "")
+}
+
+// DecompileFile is Decompile for the chunk compiled from file, whose imports
+// are looked for from the file's directory.
+func DecompileFile(chunk *Chunk, file string) string {
 	d := newDecompiler(chunk)
+	d.file = file
 	d.decode(0, len(chunk.Code))
 	return d.finish()
 }
@@ -47,15 +56,30 @@ type decompiler struct {
 	indent int
 
 	// tracking which Python modules / helpers are needed
-	needsMath   bool
-	needsRandom bool
-	needsCopy   bool
-	helpers     map[string]bool // helperDef keys from transpiler/helpers.go
+	needsMath    bool
+	needsRandom  bool
+	needsCopy    bool
+	needsDecimal bool
+	needsEnum    bool
+	// needsProtocol is set once a capability becomes a typing.Protocol.
+	needsProtocol bool
+	// needsCallable is set by a signature naming the type "function".
+	needsCallable bool
+	// needsThreading and needsQueue are set by background tasks and channels.
+	needsThreading bool
+	needsQueue     bool
+	helpers        map[string]bool // helperDef keys from transpiler/helpers.go
 	// user-defined function names (to distinguish from stdlib)
 	userFuncs map[string]bool
 	// userImports collects "from X import Y" lines for PEP8 E402: all imports
 	// are hoisted to the top of the generated file in finish().
 	userImports []string
+	// modules maps each name bound by "Import "file.abc" as m" to the
+	// functions the file declares, so a zero-argument "m's f" becomes the
+	// call m.f() and "m's x" the attribute m.x.
+	modules map[string]map[string]bool
+	// file is the file the chunk was compiled from, or "".
+	file string
 
 	// Per-chunk metadata cache: keyed by chunk pointer, computed lazily.
 	// This is correct across sub-chunks (functions/methods) because each
@@ -64,6 +88,11 @@ type decompiler struct {
 	// Whether the last thing emitted at indent==0 was a def/class body
 	// (tracked to emit required E302/E305 blank lines).
 	lastWasTopDef bool
+	// counter for naming anonymous functions that need a full def
+	anonCount int
+	// loops holds the loops being decoded, innermost last, so the jump of
+	// a break or continue can be matched to the loop it leaves.
+	loops []*loopFrame
 }
 
 func newDecompiler(root *Chunk) *decompiler {
@@ -111,6 +140,9 @@ func (d *decompiler) getChunkMeta(chunk *Chunk) *chunkMeta {
 		case OP_PUSH_SCOPE:
 			scopeStack = append(scopeStack, i)
 		case OP_POP_SCOPE:
+			if instr.Operand == 1 {
+				break // a break or continue leaving early
+			}
 			if len(scopeStack) > 0 {
 				top := scopeStack[len(scopeStack)-1]
 				scopeStack = scopeStack[:len(scopeStack)-1]
@@ -160,6 +192,28 @@ func (d *decompiler) finish() string {
 	if d.needsCopy {
 		out.WriteString("import copy\n")
 	}
+	if d.needsEnum {
+		out.WriteString("import enum\n")
+	}
+	if d.needsThreading {
+		out.WriteString("import threading\n")
+	}
+	if d.needsQueue {
+		out.WriteString("import queue\n")
+	}
+	if d.needsDecimal {
+		out.WriteString("from decimal import Decimal\n")
+	}
+	var typingNames []string
+	if d.needsCallable {
+		typingNames = append(typingNames, "Callable")
+	}
+	if d.needsProtocol {
+		typingNames = append(typingNames, "Protocol")
+	}
+	if len(typingNames) > 0 {
+		out.WriteString("from typing import " + strings.Join(typingNames, ", ") + "\n")
+	}
 
 	// User-module imports (hoisted to top to satisfy PEP8 E402).
 	// Deduplicate while preserving order.
@@ -171,7 +225,7 @@ func (d *decompiler) finish() string {
 		}
 	}
 
-	hasMod := d.needsMath || d.needsRandom || d.needsCopy || len(d.userImports) > 0
+	hasMod := d.needsMath || d.needsRandom || d.needsCopy || d.needsEnum || d.needsThreading || d.needsQueue || d.needsDecimal || len(typingNames) > 0 || len(d.userImports) > 0
 	if hasMod && len(d.helpers) > 0 {
 		out.WriteByte('\n')
 	}
@@ -322,6 +376,10 @@ func fmtValue(v interface{}) string {
 		return "False"
 	case nil:
 		return "None"
+	case *types.Decimal:
+		return "Decimal(" + strconv.Quote(val.String()) + ")"
+	case *big.Int:
+		return val.String()
 	case []interface{}:
 		parts := make([]string, len(val))
 		for i, item := range val {
@@ -368,6 +426,14 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 	case OP_SET_LINE:
 		// no output
 
+	case OP_SET_OVERFLOW_MODE:
+		// Python integers never overflow, so the mode only survives as a note.
+		if operand == 1 {
+			d.emit("# Use wrapping arithmetic.")
+		} else {
+			d.emit("# Use checked arithmetic.")
+		}
+
 	// ── Constants / values ───────────────────────────────────────────────────
 	case OP_LOAD_CONST:
 		cv := d.fmtConst(operand)
@@ -375,6 +441,9 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 		if strings.Contains(cv, "math.inf") || strings.Contains(cv, "math.nan") {
 			d.needsMath = true
 		}
+		if strings.HasPrefix(cv, "Decimal(") {
+			d.needsDecimal = true
+		}
 		d.push(cv)
 
 	case OP_LOAD_NOTHING:
@@ -403,9 +472,11 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 
 	case OP_DEFINE_TYPED, OP_DEFINE_TYPED_CONST:
 		val := d.pop()
-		_ = d.pop() // type name string – Python is dynamically typed
-		raw := d.rawName(operand)
-		d.emit(sanitizeDecompIdent(raw) + " = " + val)
+		target := sanitizeDecompIdent(d.rawName(operand))
+		if typeName, err := strconv.Unquote(d.pop()); err == nil {
+			target += ": " + d.typeAnnotation(typeName)
+		}
+		d.emit(target + " = " + val)
 
 	// ── Assignment ────────────────────────────────────────────────────────────
 	case OP_STORE_VAR:
@@ -421,6 +492,31 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 		n2 := d.pyName(operand & 0xFFFF)
 		d.emit(n1 + ", " + n2 + " = " + n2 + ", " + n1)
 
+	case OP_UNPACK:
+		// LOAD_CONST "a"; LOAD_CONST "b"; UNPACK 2; then one DEFINE_VAR,
+		// DEFINE_CONST or STORE_VAR per name, which become a tuple target.
+		// Only a list literal with one item per name is certain to unpack by
+		// position; anything else may be a structure, which _unpack reads
+		// field by field. Collecting the rest only works on a list, so it
+		// becomes a starred target.
+		count := int(operand &^ unpackRest)
+		rest := operand&unpackRest != 0
+		names := d.popN(count)
+		val := d.pop()
+		if built := d.ip - 2 - count; !rest && (built < 0 || code[built].Op != OP_BUILD_LIST || int(code[built].Operand) != count) {
+			d.helpers["_unpack"] = true
+			val = "_unpack(" + val + ", " + strings.Join(names, ", ") + ")"
+		}
+		targets := make([]string, 0, count)
+		for i := 0; i < count && d.ip < len(code); i++ {
+			targets = append(targets, d.pyName(code[d.ip].Operand))
+			d.ip++
+		}
+		if rest && len(targets) > 0 {
+			targets[len(targets)-1] = "*" + targets[len(targets)-1]
+		}
+		d.emit(strings.Join(targets, ", ") + " = " + val)
+
 	// ── Arithmetic / comparison ───────────────────────────────────────────────
 	case OP_BINARY_OP:
 		right := d.pop()
@@ -458,12 +554,19 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 		}
 		// else: unusual pattern – discard
 
+	case OP_JUMP_TABLE:
+		d.decodeJumpTable(d.pop(), d.chunk.JumpTables[operand])
+
 	case OP_JUMP:
 		// Standalone JUMP in the middle of a range = break/continue.
-		// Emit Python break/continue based on direction.
+		// Emit Python break/continue for the loop it targets, or based on
+		// direction when it targets none being decoded.
 		// (Jumps that are part of if/while/for structure are consumed by the
 		// structure handlers and never seen here.)
 		target := int(operand)
+		if d.decodeLoopJump(target) {
+			break
+		}
 		if target < d.ip-1 {
 			d.emit("continue")
 		} else {
@@ -472,7 +575,7 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 
 	// Scope delimiters are consumed by structural handlers; if we see one
 	// unexpectedly, just skip it.
-	case OP_PUSH_SCOPE, OP_POP_SCOPE:
+	case OP_PUSH_SCOPE, OP_POP_SCOPE, OP_TRY_POP:
 		// handled structurally
 
 	// ── Functions ─────────────────────────────────────────────────────────────
@@ -481,22 +584,99 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 			d.decodeFunc(d.chunk.Funcs[operand])
 		}
 
+	case OP_MAKE_FUNC:
+		if int(operand) < len(d.chunk.Funcs) {
+			d.decodeFuncValue(d.chunk.Funcs[operand])
+		}
+
 	case OP_CALL:
-		argc := operand >> 16
 		nameIdx := operand & 0xFFFF
-		args := d.popN(int(argc))
+		kwargs := d.popKeywordArgs(operand >> 24)
+		args := d.popN(int(operand >> 16 & 0xFF))
 		funcName := d.rawName(nameIdx)
+		if len(kwargs) > 0 {
+			d.push(sanitizeDecompIdent(funcName) + "(" + strings.Join(append(args, kwargs...), ", ") + ")")
+			break
+		}
 		d.push(d.fmtFuncCall(funcName, args))
 
 	case OP_CALL_METHOD:
-		argc := operand >> 16
 		methIdx := operand & 0xFFFF
-		args := d.popN(int(argc))
+		kwargs := d.popKeywordArgs(operand >> 24)
+		args := append(d.popN(int(operand>>16&0xFF)), kwargs...)
 		obj := d.pop()
 		meth := d.rawName(methIdx)
+		if funcs, isModule := d.modules[obj]; isModule && len(args) == 0 && !funcs[meth] {
+			d.push(obj + "." + sanitizeDecompIdent(meth))
+			break
+		}
+		if len(args) == 0 && (d.isFieldName(meth) || d.isEnumName(obj)) {
+			d.push(obj + "." + sanitizeDecompIdent(meth))
+			break
+		}
 		argStr := strings.Join(args, ", ")
 		d.push(obj + "." + meth + "(" + argStr + ")")
 
+	case OP_YIELD:
+		d.emit("yield " + d.pop())
+
+	// SPAWN's body becomes a function run on a thread by the _Task helper;
+	// the STORE_VAR that follows names the task.
+	case OP_SPAWN:
+		if int(operand) < len(d.chunk.Funcs) {
+			body := *d.chunk.Funcs[operand]
+			body.Name = "_background_" + body.Name
+			d.decodeFunc(&body)
+			d.needsThreading = true
+			d.helpers["_Task"] = true
+			d.push("_Task(" + sanitizeDecompIdent(body.Name) + ")")
+		}
+
+	case OP_WAIT:
+		d.emit(d.pop() + ".wait()")
+
+	case OP_NEW_CHANNEL:
+		d.needsQueue = true
+		d.push("queue.Queue()")
+
+	case OP_SEND:
+		ch := d.pop()
+		d.emit(ch + ".put(" + d.pop() + ")")
+
+	case OP_RECEIVE:
+		d.push(d.pop() + ".get()")
+
+	// ── Assertions ────────────────────────────────────────────────────────────
+	// ASSERT only matters when assertions are switched off; Python has -O.
+	case OP_ASSERT:
+
+	case OP_ASSERT_CHECK:
+		if kind := operand >> 24; kind == 0 {
+			cond := d.pop()
+			d.pop() // source text
+			d.push(cond)
+		} else {
+			right := d.pop()
+			d.pop()
+			left := d.pop()
+			d.pop()
+			d.pop()
+			d.push(d.fmtBinOp(left, BinOp(kind-1), right))
+		}
+
+	case OP_ASSERT_FAILED:
+		msg := d.pop()
+		cond := d.pop()
+		if msg == "None" {
+			d.emit("assert " + cond)
+		} else {
+			d.emit("assert " + cond + ", " + msg)
+		}
+
+	// ITER leaves what is walked on the stack; the DEFINE_VAR of the hidden
+	// iterator that follows decodes the whole for-each loop.
+	case OP_ITER:
+
 	case OP_RETURN:
 		val := d.pop()
 		if val == "None" {
@@ -548,6 +728,9 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 	case OP_BUILD_LOOKUP:
 		d.push("{}")
 
+	case OP_BUILD_STRING:
+		d.push(fString(d.popN(int(operand))))
+
 	case OP_INDEX_GET:
 		idx := d.pop()
 		list := d.pop()
@@ -603,6 +786,16 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 		typeName := d.pyName(operand)
 		d.push("isinstance(" + val + ", " + typeName + ")")
 
+	case OP_IS_TYPE:
+		val := d.pop()
+		d.push(d.fmtTypeTest(d.rawName(operand), val))
+
+	case OP_IN_RANGE:
+		hi := d.pop()
+		lo := d.pop()
+		val := d.pop()
+		d.push("(isinstance(" + val + ", (int, float)) and not isinstance(" + val + ", bool) and " + lo + " <= " + val + " <= " + hi + ")")
+
 	// ── Input ─────────────────────────────────────────────────────────────────
 	case OP_ASK:
 		if operand == 1 {
@@ -623,20 +816,29 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 			d.decodeStruct(d.chunk.StructDefs[operand])
 		}
 
+	case OP_DEFINE_ENUM:
+		if int(operand) < len(d.chunk.EnumDefs) {
+			d.decodeEnum(d.chunk.EnumDefs[operand])
+		}
+
+	case OP_DEFINE_CAPABILITY:
+		if int(operand) < len(d.chunk.CapabilityDefs) {
+			d.decodeCapability(d.chunk.CapabilityDefs[operand])
+		}
+
 	case OP_NEW_STRUCT:
 		fieldCount := operand >> 16
 		snIdx := operand & 0xFFFF
 		structName := d.rawName(snIdx)
-		fieldVals := d.popN(int(fieldCount))
-		// Look up field names from the struct def
-		sd := d.findStructDef(structName)
+		// The stack holds a quoted field name before each value.
+		pairs := d.popN(int(fieldCount) * 2)
 		var parts []string
-		if sd != nil && len(sd.Fields) == len(fieldVals) {
-			for i, fv := range fieldVals {
-				parts = append(parts, sd.Fields[i].Name+"="+fv)
+		for i := 0; i < len(pairs); i += 2 {
+			name, err := strconv.Unquote(pairs[i])
+			if err != nil {
+				name = pairs[i]
 			}
-		} else {
-			parts = fieldVals
+			parts = append(parts, sanitizeDecompIdent(name)+"="+pairs[i+1])
 		}
 		d.push(structName + "(" + strings.Join(parts, ", ") + ")")
 
@@ -704,6 +906,10 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 		_ = isSafe
 		importAll := (flags & 4) != 0
 
+		var alias string
+		if flags&8 != 0 {
+			alias = strings.Trim(d.pop(), "\"")
+		}
 		var items []string
 		if hasItems {
 			raw := d.pop()
@@ -717,7 +923,13 @@ func (d *decompiler) processInstr(instr Instruction) { //nolint:gocyclo
 
 		// Collect imports for hoisting to the top of the file (PEP8 E402).
 		var stmt string
-		if importAll || len(items) == 0 {
+		if alias != "" {
+			stmt = "import " + moduleName + " as " + sanitizeDecompIdent(alias)
+			if d.modules == nil {
+				d.modules = make(map[string]map[string]bool)
+			}
+			d.modules[alias] = moduleFunctions(d.file, path)
+		} else if importAll || len(items) == 0 {
 			stmt = "from " + moduleName + " import *"
 		} else {
 			stmt = "from " + moduleName + " import " + strings.Join(items, ", ")
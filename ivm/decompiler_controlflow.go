@@ -1,6 +1,7 @@
 package ivm
 
 import (
+	"strconv"
 	"strings"
 )
 
@@ -218,7 +219,9 @@ func (d *decompiler) decodeElseChain(endTarget int) {
 // the loop (JUMP_IF_FALSE operand).
 func (d *decompiler) decodeWhileBody(cond string, exitTarget int) {
 	code := d.chunk.Code
-	d.emit("while " + stripParens(cond) + ":")
+	// The backward JUMP closing the body goes to the condition, which is
+	// also where a continue goes.
+	header := d.openLoop("while "+stripParens(cond)+":", exitTarget, int(code[exitTarget-1].Operand))
 	d.indent++
 
 	// Consume PUSH_SCOPE
@@ -233,6 +236,7 @@ func (d *decompiler) decodeWhileBody(cond string, exitTarget int) {
 		d.emit("pass")
 	}
 	d.indent--
+	d.closeLoop(header)
 
 	// Consume POP_SCOPE
 	if d.ip < len(code) && code[d.ip].Op == OP_POP_SCOPE {
@@ -255,27 +259,17 @@ func (d *decompiler) tryDecodeForLoop(hiddenName string, initExpr string) bool {
 	code := d.chunk.Code
 
 	// FOR-EACH pattern:
-	//   DEFINE_VAR __hidden_list = listExpr
-	//   LOAD_CONST 0
-	//   DEFINE_VAR __hidden_idx
-	//   loopStart: LOAD_VAR __hidden_idx; LOAD_VAR __hidden_list; LENGTH; BINARY_OP BinLt; JUMP_IF_FALSE
-	//   PUSH_SCOPE
-	//   LOAD_VAR __hidden_list; LOAD_VAR __hidden_idx; INDEX_GET; DEFINE_VAR itemName
+	//   ITER; DEFINE_VAR __hidden_iter = listExpr
+	//   loopStart: LOAD_VAR __hidden_iter; ITER_NEXT -> loopEnd
+	//   PUSH_SCOPE; DEFINE_VAR itemName
 	//   body
 	//   POP_SCOPE
-	//   INCREMENT; JUMP -> loopStart; loopEnd:
-	if d.ip+2 < len(code) &&
-		code[d.ip].Op == OP_LOAD_CONST &&
-		fmtValue(d.chunk.Constants[code[d.ip].Operand]) == "0" &&
-		code[d.ip+1].Op == OP_DEFINE_VAR {
-		idxHidden := d.rawName(code[d.ip+1].Operand)
-		if strings.HasPrefix(idxHidden, "__hidden_") {
-			// Also verify the loop-start pattern: LOAD_VAR idxHidden
-			if d.ip+2 < len(code) && code[d.ip+2].Op == OP_LOAD_VAR &&
-				d.rawName(code[d.ip+2].Operand) == idxHidden {
-				return d.decodeForEach(hiddenName, initExpr, idxHidden, d.ip+2)
-			}
-		}
+	//   JUMP -> loopStart; loopEnd:
+	if d.ip+1 < len(code) &&
+		code[d.ip].Op == OP_LOAD_VAR &&
+		d.rawName(code[d.ip].Operand) == hiddenName &&
+		code[d.ip+1].Op == OP_ITER_NEXT {
+		return d.decodeForEach(initExpr, d.ip)
 	}
 
 	// FOR-LOOP (repeat N times) pattern:
@@ -293,42 +287,30 @@ func (d *decompiler) tryDecodeForLoop(hiddenName string, initExpr string) bool {
 }
 
 // decodeForEach decodes a for-each loop.
-// listHidden: name of hidden list variable; listExpr: its Python expression.
-// idxHidden: name of hidden index variable; loopStart: ip of loop-start instruction.
-func (d *decompiler) decodeForEach(listHidden, listExpr, idxHidden string, loopStart int) bool {
+// listExpr: Python expression for what is walked; loopStart: ip of the
+// LOAD_VAR of the hidden iterator variable.
+func (d *decompiler) decodeForEach(listExpr string, loopStart int) bool {
 	code := d.chunk.Code
-	// Consume LOAD_CONST 0 and DEFINE_VAR __hidden_idx
-	d.ip += 2
-
-	// Consume loop-start check: LOAD_VAR idx; LOAD_VAR list; LENGTH; BINARY_OP BinLt; JUMP_IF_FALSE
-	// That's 5 instructions. Advance to JUMP_IF_FALSE.
-	condEnd := d.ip + 4 // d.ip is at LOAD_VAR idx (index 0), +4 = JUMP_IF_FALSE
-	if condEnd >= len(code) {
-		return false
-	}
-	if code[condEnd].Op != OP_JUMP_IF_FALSE {
-		return false
-	}
-	exitTarget := int(code[condEnd].Operand)
-	d.ip = condEnd + 1 // past JUMP_IF_FALSE
+	// Consume loop-start: LOAD_VAR iter; ITER_NEXT
+	exitTarget := int(code[loopStart+1].Operand)
+	d.ip = loopStart + 2
 
 	// Consume PUSH_SCOPE
 	if d.ip < len(code) && code[d.ip].Op == OP_PUSH_SCOPE {
 		d.ip++
 	}
 
-	// The next 4 instructions define the loop variable: LOAD_VAR list; LOAD_VAR idx; INDEX_GET; DEFINE_VAR itemName
-	if d.ip+3 >= len(code) {
+	// DEFINE_VAR itemName stores the item ITER_NEXT pushed
+	if d.ip >= len(code) || code[d.ip].Op != OP_DEFINE_VAR {
 		return false
 	}
-	itemNameIdx := code[d.ip+3].Operand
-	itemName := sanitizeDecompIdent(d.rawName(itemNameIdx))
-	d.ip += 4 // consume those 4 instructions
+	itemName := sanitizeDecompIdent(d.rawName(code[d.ip].Operand))
+	d.ip++
 
 	// Find the POP_SCOPE for the body
 	bodyEnd := d.findMatchingPopScope(d.ip)
 
-	d.emit("for " + itemName + " in " + listExpr + ":")
+	header := d.openLoop("for "+itemName+" in "+listExpr+":", exitTarget, loopStart)
 	d.indent++
 	forEachStart := d.buf.Len()
 	d.decodeRange(bodyEnd)
@@ -336,17 +318,8 @@ func (d *decompiler) decodeForEach(listHidden, listExpr, idxHidden string, loopS
 		d.emit("pass")
 	}
 	d.indent--
+	d.closeLoop(header)
 
-	// Consume POP_SCOPE
-	if d.ip < len(code) && code[d.ip].Op == OP_POP_SCOPE {
-		d.ip++
-	}
-	// Consume increment: LOAD_VAR idx; LOAD_CONST 1; BINARY_OP BinAdd; STORE_VAR idx
-	d.ip += 4
-	// Consume JUMP -> loopStart
-	if d.ip < len(code) && code[d.ip].Op == OP_JUMP {
-		d.ip++
-	}
 	d.ip = exitTarget
 	return true
 }
@@ -372,7 +345,8 @@ func (d *decompiler) decodeRepeatN(counterHidden, countExpr string, loopStart in
 
 	bodyEnd := d.findMatchingPopScope(d.ip)
 
-	d.emit("for _ in range(int(" + countExpr + ")):")
+	// A continue goes to the decrement just after the body's POP_SCOPE.
+	header := d.openLoop("for _ in range(int("+countExpr+")):", exitTarget, bodyEnd+1)
 	d.indent++
 	repeatStart := d.buf.Len()
 	d.decodeRange(bodyEnd)
@@ -380,6 +354,7 @@ func (d *decompiler) decodeRepeatN(counterHidden, countExpr string, loopStart in
 		d.emit("pass")
 	}
 	d.indent--
+	d.closeLoop(header)
 
 	// Consume POP_SCOPE
 	if d.ip < len(code) && code[d.ip].Op == OP_POP_SCOPE {
@@ -395,6 +370,111 @@ func (d *decompiler) decodeRepeatN(counterHidden, countExpr string, loopStart in
 	return true
 }
 
+// ─── break / continue ─────────────────────────────────────────────────────────
+
+// loopFrame is a loop being decoded: where a break and a continue of it
+// jump to, and the breaks and continues in its body that leave it for a
+// loop around it.
+type loopFrame struct {
+	chunk   *Chunk
+	exit    int
+	next    int
+	escapes []loopEscape
+}
+
+// loopEscape is a break or continue of an outer loop, which Python cannot
+// express directly: the jump sets flag and breaks, and each loop it passes
+// through breaks again when flag is set. target is the index of the outer
+// loop in decompiler.loops.
+type loopEscape struct {
+	flag   string
+	cont   bool
+	target int
+}
+
+// openLoop emits a loop's header and starts matching jumps to exit and
+// next as its breaks and continues. It returns where the header starts in
+// the output, for closeLoop.
+func (d *decompiler) openLoop(header string, exit, next int) int {
+	d.emit(header)
+	d.loops = append(d.loops, &loopFrame{chunk: d.chunk, exit: exit, next: next})
+	return d.buf.Len() - len(header) - 1 - 4*d.indent
+}
+
+// closeLoop ends the innermost loop, whose header starts at header in the
+// output. Flags of breaks and continues leaving it are cleared just before
+// it, when it is directly inside their loop, and checked just after it.
+func (d *decompiler) closeLoop(header int) {
+	frame := d.loops[len(d.loops)-1]
+	d.loops = d.loops[:len(d.loops)-1]
+	parent := len(d.loops) - 1
+	indent := strings.Repeat("    ", d.indent)
+	var inits strings.Builder
+	for _, e := range frame.escapes {
+		if e.target == parent {
+			inits.WriteString(indent + e.flag + " = False\n")
+		}
+	}
+	if inits.Len() > 0 {
+		out := d.buf.String()
+		d.buf.Reset()
+		d.buf.WriteString(out[:header] + inits.String() + out[header:])
+	}
+	for _, e := range frame.escapes {
+		d.emit("if " + e.flag + ":")
+		d.indent++
+		if e.cont && e.target == parent {
+			d.emit("continue")
+		} else {
+			d.emit("break")
+		}
+		d.indent--
+	}
+}
+
+// decodeLoopJump emits the break or continue a JUMP to target stands for,
+// if it goes to the exit or continue point of a loop being decoded, and
+// reports whether it did.
+func (d *decompiler) decodeLoopJump(target int) bool {
+	top := len(d.loops) - 1
+	for i := top; i >= 0 && d.loops[i].chunk == d.chunk; i-- {
+		loop := d.loops[i]
+		if target != loop.exit && target != loop.next {
+			continue
+		}
+		cont := target == loop.next
+		if i == top {
+			if cont {
+				d.emit("continue")
+			} else {
+				d.emit("break")
+			}
+			return true
+		}
+		flag := "_break_" + strconv.Itoa(i+1)
+		if cont {
+			flag = "_continue_" + strconv.Itoa(i+1)
+		}
+		d.emit(flag + " = True")
+		d.emit("break")
+		for j := i + 1; j <= top; j++ {
+			d.loops[j].addEscape(loopEscape{flag: flag, cont: cont, target: i})
+		}
+		return true
+	}
+	return false
+}
+
+// addEscape records e on the loop, once per flag.
+func (f *loopFrame) addEscape(e loopEscape) {
+	for _, have := range f.escapes {
+		if have.flag == e.flag {
+			return
+		}
+	}
+	f.escapes = append(f.escapes, e)
+}
+
 // ─── try / except ─────────────────────────────────────────────────────────────
 
 // decodeTry decodes a try/except/finally block.
@@ -482,3 +562,75 @@ func (d *decompiler) decodeTry(catchOffset int) {
 		// implementation detail and has no Python equivalent).
 	}
 }
+
+// ─── When statement (jump table) ─────────────────────────────────────────────
+
+// decodeJumpTable decodes a When statement compiled with a jump table into a
+// Python match statement. Layout:
+//
+//	LOAD_VAR subj; JUMP_TABLE t; [if-chain for non-constant cases]; JUMP end;
+//	case bodies (PUSH_SCOPE … POP_SCOPE [JUMP end])…; end:
+//
+// The if-chain, when present, becomes the body of a trailing "case _:".
+func (d *decompiler) decodeJumpTable(subject string, table *JumpTable) {
+	code := d.chunk.Code
+	var targets []uint32
+	keysByTarget := map[uint32][]string{}
+	numeric := map[uint32]bool{}
+	booleans := map[uint32][]string{}
+	for i, key := range table.Keys {
+		target := table.Targets[i]
+		if _, seen := keysByTarget[target]; !seen {
+			targets = append(targets, target)
+		}
+		keysByTarget[target] = append(keysByTarget[target], fmtValue(key))
+		switch key.(type) {
+		case float64:
+			numeric[target] = true
+		case bool:
+			booleans[target] = append(booleans[target], subject+" is "+fmtValue(key))
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	first := int(targets[0])
+	for _, t := range targets {
+		if int(t) < first {
+			first = int(t)
+		}
+	}
+	chainStart := d.ip
+	end := int(code[first-1].Operand)
+
+	d.emit("match " + subject + ":")
+	d.indent++
+	for _, target := range targets {
+		pattern := strings.Join(keysByTarget[target], " | ")
+		if numeric[target] {
+			// Python's True is the int 1, so a number key would match a
+			// boolean subject that the jump table would not.
+			guard := append([]string{"not isinstance(" + subject + ", bool)"}, booleans[target]...)
+			pattern += " if " + strings.Join(guard, " or ")
+		}
+		d.emit("case " + pattern + ":")
+		d.indent++
+		d.ip = int(target) + 1 // skip PUSH_SCOPE
+		bodyEnd := d.findMatchingPopScope(d.ip)
+		bodyStart := d.buf.Len()
+		d.decodeRange(bodyEnd)
+		if d.bodyEmpty(bodyStart) {
+			d.emit("pass")
+		}
+		d.indent--
+	}
+	if chainStart < first-1 {
+		d.emit("case _:")
+		d.indent++
+		d.ip = chainStart
+		d.decodeRange(first - 1)
+		d.indent--
+	}
+	d.indent--
+	d.ip = end
+}
@@ -1,6 +1,8 @@
 package ivm
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -8,12 +10,13 @@ import (
 
 func (d *decompiler) decodeFunc(fc *FuncChunk) {
 	params := make([]string, len(fc.Params))
-	for i, p := range fc.Params {
-		params[i] = sanitizeDecompIdent(p)
+	for i := range fc.Params {
+		params[i] = d.param(fc, i)
 	}
 	// PEP8 E302 blank lines are handled automatically by emit().
-	d.emit("def " + sanitizeDecompIdent(fc.Name) + "(" + strings.Join(params, ", ") + "):")
+	d.emit("def " + sanitizeDecompIdent(fc.Name) + "(" + strings.Join(params, ", ") + ")" + d.returnAnnotation(fc) + ":")
 	d.indent++
+	d.emitParamPrelude(fc)
 
 	saved := d.chunk
 	savedIP := d.ip
@@ -50,15 +53,228 @@ func (d *decompiler) decodeFunc(fc *FuncChunk) {
 	}
 }
 
+// param renders parameter i of fc for a def line, with its capability or
+// type annotation and its default value, if it has them. A rest parameter becomes
+// "*values".
+func (d *decompiler) param(fc *FuncChunk, i int) string {
+	if fc.Variadic && i == len(fc.Params)-1 {
+		return "*" + sanitizeDecompIdent(fc.Params[i])
+	}
+	param := d.annotatedParam(fc, i, sanitizeDecompIdent(fc.Params[i]))
+	if i >= len(fc.Defaults) || fc.Defaults[i] == nil {
+		return param
+	}
+	value := "None" // filled in by emitParamPrelude
+	if isConstantDefault(fc.Defaults[i]) {
+		value = d.evalDefaultExpr(fc.Defaults[i])
+	}
+	// PEP8 E251/E252: no spaces around a bare default, spaces around an
+	// annotated one.
+	if strings.Contains(param, ":") {
+		return param + " = " + value
+	}
+	return param + "=" + value
+}
+
+// emitParamPrelude starts a function body by giving its parameters their
+// defaults. English works a default out on every call without one, and it
+// may use the parameters before it, so unless it is a constant param leaves
+// None in the def line and the body fills it in. Python collects a rest
+// parameter into a tuple, so it is made a list, as English gives it.
+func (d *decompiler) emitParamPrelude(fc *FuncChunk) {
+	for i, def := range fc.Defaults {
+		if def == nil || isConstantDefault(def) {
+			continue
+		}
+		name := sanitizeDecompIdent(fc.Params[i])
+		d.emit("if " + name + " is None:")
+		d.indent++
+		d.emit(name + " = " + d.evalDefaultExpr(def))
+		d.indent--
+	}
+	if fc.Variadic && len(fc.Params) > 0 {
+		name := sanitizeDecompIdent(fc.Params[len(fc.Params)-1])
+		d.emit(name + " = list(" + name + ")")
+	}
+}
+
+// isConstantDefault reports whether a default's chunk just loads a
+// constant, which Python can safely evaluate once and share between calls.
+func isConstantDefault(chunk *Chunk) bool {
+	code := chunk.Code
+	if len(code) > 0 && code[len(code)-1].Op == OP_RETURN {
+		code = code[:len(code)-1]
+	}
+	return len(code) == 1 && (code[0].Op == OP_LOAD_CONST || code[0].Op == OP_LOAD_NOTHING)
+}
+
+// popKeywordArgs pops the kwc name/value pairs of a call and renders them as
+// Python keyword arguments.
+func (d *decompiler) popKeywordArgs(kwc uint32) []string {
+	pairs := d.popN(int(kwc) * 2)
+	var kwargs []string
+	for i := 0; i < len(pairs); i += 2 {
+		name, err := strconv.Unquote(pairs[i])
+		if err != nil {
+			name = pairs[i]
+		}
+		kwargs = append(kwargs, sanitizeDecompIdent(name)+"="+pairs[i+1])
+	}
+	return kwargs
+}
+
+// annotatedParam annotates parameter i with the capability it was declared
+// to need, which the decompiled Protocol class stands for, or with the
+// Python type matching the type it was declared as.
+func (d *decompiler) annotatedParam(fc *FuncChunk, i int, param string) string {
+	if i < len(fc.ParamCapabilities) && fc.ParamCapabilities[i] != "" {
+		return param + ": " + fc.ParamCapabilities[i]
+	}
+	if i < len(fc.ParamTypes) && fc.ParamTypes[i] != "" {
+		return param + ": " + d.typeAnnotation(fc.ParamTypes[i])
+	}
+	return param
+}
+
+// returnAnnotation renders the type fc says it returns as " -> T", or ""
+// when it does not say.
+func (d *decompiler) returnAnnotation(fc *FuncChunk) string {
+	if fc.ReturnType == "" {
+		return ""
+	}
+	return " -> " + d.typeAnnotation(fc.ReturnType)
+}
+
+// typeAnnotation converts a type named in a signature or a typed
+// declaration to a Python annotation. Structures, enums and error types are
+// quoted, since their classes may be defined after the function. Element
+// types become type arguments, as in list[float] and dict[str, float].
+func (d *decompiler) typeAnnotation(typeName string) string {
+	lower := strings.ToLower(typeName)
+	if elem, ok := strings.CutPrefix(lower, "list of "); ok {
+		return "list[" + d.typeAnnotation(elem) + "]"
+	}
+	if rest, ok := strings.CutPrefix(lower, "lookup table from "); ok {
+		if key, value, ok := strings.Cut(rest, " to "); ok {
+			return "dict[" + d.typeAnnotation(key) + ", " + d.typeAnnotation(value) + "]"
+		}
+	}
+	switch lower {
+	case "number":
+		return "float"
+	case "integer", "unsigned integer", "whole number":
+		return "int"
+	case "decimal":
+		d.needsDecimal = true
+		return "Decimal"
+	case "text":
+		return "str"
+	case "boolean":
+		return "bool"
+	case "list", "array":
+		return "list"
+	case "lookup table", "table":
+		return "dict"
+	case "function":
+		d.needsCallable = true
+		return "Callable"
+	case "error":
+		return "Exception"
+	case "nothing":
+		return "None"
+	default:
+		return strconv.Quote(typeName)
+	}
+}
+
+// decodeFuncValue handles OP_MAKE_FUNC. A function literal stored straight
+// into a variable of the same name becomes a plain def; one whose body is a
+// single return becomes a lambda; anything else is hoisted into a named def
+// emitted just before the statement that uses it.
+func (d *decompiler) decodeFuncValue(fc *FuncChunk) {
+	code := d.chunk.Code
+	if d.ip < len(code) &&
+		(code[d.ip].Op == OP_DEFINE_VAR || code[d.ip].Op == OP_DEFINE_CONST) &&
+		d.rawName(code[d.ip].Operand) == fc.Name {
+		d.ip++
+		d.decodeFunc(fc)
+		return
+	}
+	if lambda, ok := d.tryLambda(fc); ok {
+		d.push(lambda)
+		return
+	}
+	d.anonCount++
+	named := *fc
+	named.Name = fmt.Sprintf("_anonymous_%d", d.anonCount)
+	d.decodeFunc(&named)
+	d.push(named.Name)
+}
+
+// tryLambda decompiles fc as "lambda params: expr" when its body is nothing
+// but a single returned expression.
+func (d *decompiler) tryLambda(fc *FuncChunk) (string, bool) {
+	body := fc.Body.Code
+	end := len(body)
+	if end >= 2 && body[end-2].Op == OP_LOAD_NOTHING && body[end-1].Op == OP_RETURN {
+		end -= 2
+	}
+	if end == 0 || body[end-1].Op != OP_RETURN {
+		return "", false
+	}
+
+	savedChunk, savedIP, savedStack := d.chunk, d.ip, d.exprStack
+	savedBuf, savedTopDef := d.buf, d.lastWasTopDef
+	var scratch strings.Builder
+	d.chunk, d.exprStack, d.buf = fc.Body, nil, &scratch
+	d.decode(0, end-1)
+	expr, ok := "", scratch.Len() == 0 && len(d.exprStack) == 1
+	if ok {
+		expr = d.exprStack[0]
+	}
+	d.chunk, d.ip, d.exprStack = savedChunk, savedIP, savedStack
+	d.buf, d.lastWasTopDef = savedBuf, savedTopDef
+	if !ok {
+		return "", false
+	}
+
+	params := make([]string, len(fc.Params))
+	for i, p := range fc.Params {
+		params[i] = sanitizeDecompIdent(p)
+	}
+	if len(params) == 0 {
+		return "lambda: " + expr, true
+	}
+	return "lambda " + strings.Join(params, ", ") + ": " + expr, true
+}
+
 // ─── structs ──────────────────────────────────────────────────────────────────
 
 func (d *decompiler) decodeStruct(sd *StructDef) {
 	// PEP8 E302 blank lines are handled automatically by emit().
-	d.emit("class " + sd.Name + ":")
+	initParams := d.structInitParams(sd)
+	var bases []string
+	if sd.Parent != "" {
+		// Fields the subclass does not declare go to the parent's __init__.
+		bases = append(bases, sd.Parent)
+		if initParams != "" {
+			initParams += ", "
+		}
+		initParams += "**inherited"
+	}
+	bases = append(bases, sd.Capabilities...)
+	if len(bases) > 0 {
+		d.emit("class " + sd.Name + "(" + strings.Join(bases, ", ") + "):")
+	} else {
+		d.emit("class " + sd.Name + ":")
+	}
 	d.indent++
-	d.emit("def __init__(self, " + d.structInitParams(sd) + "):")
+	d.emit("def __init__(self, " + initParams + "):")
 	d.indent++
-	if len(sd.Fields) == 0 {
+	if sd.Parent != "" {
+		d.emit("super().__init__(**inherited)")
+	}
+	if len(sd.Fields) == 0 && sd.Parent == "" {
 		d.emit("pass")
 	} else {
 		for _, f := range sd.Fields {
@@ -81,6 +297,48 @@ func (d *decompiler) decodeStruct(sd *StructDef) {
 	}
 }
 
+// decodeEnum emits an enum.Enum subclass. Members print as their bare name,
+// as they do in English.
+func (d *decompiler) decodeEnum(ed *EnumDef) {
+	d.needsEnum = true
+	d.emit("class " + ed.Name + "(enum.Enum):")
+	d.indent++
+	for i, m := range ed.Members {
+		d.emit(sanitizeDecompIdent(m) + " = " + strconv.Itoa(i+1))
+	}
+	d.buf.WriteByte('\n')
+	d.emit("def __str__(self):")
+	d.indent++
+	d.emit("return self.name")
+	d.indent -= 2
+
+	if d.indent == 0 {
+		d.lastWasTopDef = true
+	}
+}
+
+// decodeCapability emits a typing.Protocol class with a stub for each
+// required function.
+func (d *decompiler) decodeCapability(cd *CapabilityDef) {
+	d.needsProtocol = true
+	d.emit("class " + cd.Name + "(Protocol):")
+	d.indent++
+	for i, m := range cd.Methods {
+		if i > 0 {
+			d.buf.WriteByte('\n')
+		}
+		d.emit("def " + sanitizeDecompIdent(m) + "(self): ...")
+	}
+	if len(cd.Methods) == 0 {
+		d.emit("pass")
+	}
+	d.indent--
+
+	if d.indent == 0 {
+		d.lastWasTopDef = true
+	}
+}
+
 func (d *decompiler) structInitParams(sd *StructDef) string {
 	parts := make([]string, len(sd.Fields))
 	for i, f := range sd.Fields {
@@ -98,11 +356,12 @@ func (d *decompiler) structInitParams(sd *StructDef) string {
 func (d *decompiler) decodeMethod(fc *FuncChunk) {
 	params := make([]string, len(fc.Params)+1)
 	params[0] = "self"
-	for i, p := range fc.Params {
-		params[i+1] = sanitizeDecompIdent(p)
+	for i := range fc.Params {
+		params[i+1] = d.param(fc, i)
 	}
-	d.emit("def " + sanitizeDecompIdent(fc.Name) + "(" + strings.Join(params, ", ") + "):")
+	d.emit("def " + sanitizeDecompIdent(fc.Name) + "(" + strings.Join(params, ", ") + ")" + d.returnAnnotation(fc) + ":")
 	d.indent++
+	d.emitParamPrelude(fc)
 
 	saved := d.chunk
 	savedIP := d.ip
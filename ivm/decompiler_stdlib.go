@@ -207,6 +207,17 @@ func (d *decompiler) fmtFuncCall(name string, args []string) string {
 	case "zip_with":
 		d.helpers["_zip_with"] = true
 		return fmt.Sprintf("_zip_with(%s, %s)", a(0), a(1))
+	case "transform":
+		return fmt.Sprintf("list(map(%s, %s))", a(1), a(0))
+	case "keep":
+		return fmt.Sprintf("list(filter(%s, %s))", a(1), a(0))
+	case "combine":
+		d.helpers["_combine"] = true
+		return fmt.Sprintf("_combine(%s, %s, %s)", a(0), a(1), a(2))
+	case "find_first":
+		return fmt.Sprintf("next(filter(%s, %s), None)", a(1), a(0))
+	case "count_where":
+		return fmt.Sprintf("len(list(filter(%s, %s)))", a(1), a(0))
 	// Lookup table
 	case "keys":
 		return fmt.Sprintf("list(%s.keys())", a(0))
@@ -1,7 +1,13 @@
 package ivm
 
 import (
+	"os"
+	"strconv"
 	"strings"
+
+	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 )
 
 // ─── helper utilities ─────────────────────────────────────────────────────────
@@ -26,6 +32,9 @@ func (d *decompiler) findMatchingPopScope(start int) int {
 		case OP_PUSH_SCOPE:
 			depth++
 		case OP_POP_SCOPE:
+			if code[i].Operand == 1 {
+				continue // a break or continue leaving early
+			}
 			if depth == 0 {
 				return i
 			}
@@ -80,23 +89,51 @@ func (d *decompiler) findNextOp(pos int, op Opcode) int {
 	return -1
 }
 
-// findStructDef searches root and all sub-chunks for a struct def with the given name.
-func (d *decompiler) findStructDef(name string) *StructDef {
-	return findStructInChunk(d.root, name)
+// isFieldName reports whether name is a field of some struct declared
+// anywhere in the program and not a method of any, so that a zero-argument
+// CALL_METHOD on it decompiles to attribute access.
+func (d *decompiler) isFieldName(name string) bool {
+	field, method := structMember(d.root, name)
+	return field && !method
 }
 
-func findStructInChunk(chunk *Chunk, name string) *StructDef {
+func structMember(chunk *Chunk, name string) (field, method bool) {
 	for _, sd := range chunk.StructDefs {
-		if sd.Name == name {
-			return sd
+		if sd.field(name) != nil {
+			field = true
+		}
+		for _, fc := range sd.Methods {
+			if fc.Name == name {
+				method = true
+			}
 		}
 	}
 	for _, fc := range chunk.Funcs {
-		if sd := findStructInChunk(fc.Body, name); sd != nil {
-			return sd
+		f, m := structMember(fc.Body, name)
+		field, method = field || f, method || m
+	}
+	return field, method
+}
+
+// isEnumName reports whether name is an enum declared anywhere in the
+// program, so that a zero-argument CALL_METHOD on it decompiles to member
+// access ("Color's red" → Color.red).
+func (d *decompiler) isEnumName(name string) bool {
+	return enumDeclared(d.root, name)
+}
+
+func enumDeclared(chunk *Chunk, name string) bool {
+	for _, ed := range chunk.EnumDefs {
+		if ed.Name == name {
+			return true
 		}
 	}
-	return nil
+	for _, fc := range chunk.Funcs {
+		if enumDeclared(fc.Body, name) {
+			return true
+		}
+	}
+	return false
 }
 
 // bodyEmpty returns true if nothing was written to the output buffer since
@@ -133,6 +170,20 @@ func (d *decompiler) fmtBinOp(left string, op BinOp, right string) string {
 		sym = " > "
 	case BinGte:
 		sym = " >= "
+	case BinPow:
+		sym = " ** "
+	case BinFloorDiv:
+		sym = " // "
+	case BinBitAnd:
+		sym = " & "
+	case BinBitOr:
+		sym = " | "
+	case BinBitXor:
+		sym = " ^ "
+	case BinShl:
+		sym = " << "
+	case BinShr:
+		sym = " >> "
 	default:
 		sym = " ? "
 	}
@@ -167,8 +218,13 @@ func (d *decompiler) fmtCast(typeName, val string) string {
 	switch strings.ToLower(typeName) {
 	case "number", "float":
 		return "float(" + val + ")"
-	case "integer", "int":
+	case "integer", "int", "whole number":
 		return "int(" + val + ")"
+	case "decimal":
+		// str() first so a number converts through its shortest form, as it
+		// does in the VMs.
+		d.needsDecimal = true
+		return "Decimal(str(" + val + "))"
 	case "text", "string", "str":
 		return "str(" + val + ")"
 	case "boolean", "bool":
@@ -178,6 +234,34 @@ func (d *decompiler) fmtCast(typeName, val string) string {
 	}
 }
 
+// fmtTypeTest renders a When type pattern ("is a number") as a Python test.
+// Number tests rule out booleans, which Python counts as ints.
+func (d *decompiler) fmtTypeTest(typeName, val string) string {
+	switch strings.ToLower(typeName) {
+	case "number":
+		return "(isinstance(" + val + ", (int, float)) and not isinstance(" + val + ", bool))"
+	case "decimal":
+		d.needsDecimal = true
+		return "isinstance(" + val + ", Decimal)"
+	case "whole number":
+		return "(isinstance(" + val + ", int) and not isinstance(" + val + ", bool))"
+	case "text":
+		return "isinstance(" + val + ", str)"
+	case "boolean":
+		return "isinstance(" + val + ", bool)"
+	case "list", "array":
+		return "isinstance(" + val + ", list)"
+	case "lookup table", "table":
+		return "isinstance(" + val + ", dict)"
+	case "function":
+		return "callable(" + val + ")"
+	case "nothing":
+		return val + " is None"
+	default:
+		return "isinstance(" + val + ", " + sanitizeDecompIdent(typeName) + ")"
+	}
+}
+
 // ─── import path utilities ────────────────────────────────────────────────────
 
 func pathToModuleName(path string) string {
@@ -193,6 +277,31 @@ func pathToModuleName(path string) string {
 	return base
 }
 
+// moduleFunctions returns the names of the functions declared at the top
+// level of the English file an import of path in the file importer names, or
+// none when it cannot be read.
+func moduleFunctions(importer, path string) map[string]bool {
+	funcs := make(map[string]bool)
+	file, err := project.ResolveImport(importer, path)
+	if err != nil {
+		return funcs
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return funcs
+	}
+	prog, err := parser.NewParser(parser.NewLexer(string(src)).TokenizeAll()).Parse()
+	if err != nil {
+		return funcs
+	}
+	for _, stmt := range prog.Statements {
+		if fd, ok := stmt.(*ast.FunctionDecl); ok {
+			funcs[fd.Name] = true
+		}
+	}
+	return funcs
+}
+
 // extractListLiteral parses a Python list-literal string like ["a", "b"] and
 // returns the items.
 func extractListLiteral(s string) []string {
@@ -216,6 +325,42 @@ func extractListLiteral(s string) []string {
 	return items
 }
 
+// fString renders the parts of an OP_BUILD_STRING as a Python f-string.
+// Parts that are plain string constants become literal text; everything else
+// becomes a {replacement field}. Expressions that cannot sit inside an
+// f-string (quotes, backslashes, a leading brace) fall back to concatenation.
+func fString(parts []string) string {
+	var sb strings.Builder
+	for _, p := range parts {
+		if text, err := strconv.Unquote(p); err == nil && strings.HasPrefix(p, "\"") {
+			quoted := strconv.Quote(text)
+			quoted = quoted[1 : len(quoted)-1]
+			quoted = strings.ReplaceAll(quoted, "{", "{{")
+			sb.WriteString(strings.ReplaceAll(quoted, "}", "}}"))
+			continue
+		}
+		if strings.ContainsAny(p, "\"\\") || strings.HasPrefix(p, "{") {
+			return concatString(parts)
+		}
+		sb.WriteString("{" + p + "}")
+	}
+	return `f"` + sb.String() + `"`
+}
+
+// concatString joins OP_BUILD_STRING parts with "+", converting non-literal
+// parts with str().
+func concatString(parts []string) string {
+	terms := make([]string, len(parts))
+	for i, p := range parts {
+		if _, err := strconv.Unquote(p); err == nil && strings.HasPrefix(p, "\"") {
+			terms[i] = p
+		} else {
+			terms[i] = "str(" + p + ")"
+		}
+	}
+	return "(" + strings.Join(terms, " + ") + ")"
+}
+
 // helperDefs mirrors the definitions in transpiler/helpers.go so the decompiler
 // can inject the same helper functions when needed.
 var helperDefs = map[string]string{
@@ -269,4 +414,30 @@ var helperDefs = map[string]string{
 
 	"_zip_with": `def _zip_with(a, b):
     return [[x, y] for x, y in zip(a, b)]`,
+	"_combine": `def _combine(lst, fn, start):
+    result = start
+    for item in lst:
+        result = fn(result, item)
+    return result`,
+	"_unpack": `def _unpack(value, *names):
+    if isinstance(value, (list, tuple)):
+        return value
+    return tuple(getattr(value, name) for name in names)`,
+	"_Task": `class _Task(threading.Thread):
+    def __init__(self, body):
+        super().__init__()
+        self.body = body
+        self.error = None
+        self.start()
+
+    def run(self):
+        try:
+            self.body()
+        except Exception as e:
+            self.error = e
+
+    def wait(self):
+        self.join()
+        if self.error is not None:
+            raise self.error`,
 }
@@ -4,14 +4,16 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"github.com/Advik-B/english/astvm/types"
 	"math"
+	"math/big"
 )
 
 // Magic bytes and version for .101 instruction-format files.
 var MagicBytes = []byte{0x10, 0x1E, 0x4E, 0x47}
 
 // InstructionFormatVersion is the bytecode format version for instruction-based .101 files.
-const InstructionFormatVersion uint8 = 3
+const InstructionFormatVersion uint8 = 13
 
 // EncodeFile serialises chunk with magic header + version byte.
 func EncodeFile(chunk *Chunk) ([]byte, error) {
@@ -160,6 +162,32 @@ func (e *encoder) writeChunk(c *Chunk) error {
 			return err
 		}
 	}
+	// JumpTables
+	e.writeUint32(uint32(len(c.JumpTables)))
+	for _, jt := range c.JumpTables {
+		e.writeUint32(uint32(len(jt.Keys)))
+		for i, k := range jt.Keys {
+			if err := e.writeConstant(k); err != nil {
+				return err
+			}
+			e.writeUint32(jt.Targets[i])
+		}
+	}
+	// EnumDefs
+	e.writeUint32(uint32(len(c.EnumDefs)))
+	for _, ed := range c.EnumDefs {
+		e.writeString(ed.Name)
+		e.writeUint32(uint32(len(ed.Members)))
+		for _, m := range ed.Members {
+			e.writeString(m)
+		}
+	}
+	// CapabilityDefs
+	e.writeUint32(uint32(len(c.CapabilityDefs)))
+	for _, cd := range c.CapabilityDefs {
+		e.writeString(cd.Name)
+		e.writeStrings(cd.Methods)
+	}
 	return nil
 }
 
@@ -188,6 +216,14 @@ func (e *encoder) writeConstant(v interface{}) error {
 			s, _ := item.(string)
 			e.writeString(s)
 		}
+	case *big.Int:
+		// Whole numbers and decimals are stored as their decimal text, which
+		// round-trips exactly and keeps a decimal's trailing zeros.
+		e.writeByte(5)
+		e.writeString(val.String())
+	case *types.Decimal:
+		e.writeByte(6)
+		e.writeString(val.String())
 	default:
 		return fmt.Errorf("ivm encoding: unsupported constant type %T", v)
 	}
@@ -200,11 +236,49 @@ func (e *encoder) writeFuncChunk(fc *FuncChunk) error {
 	for _, p := range fc.Params {
 		e.writeString(p)
 	}
+	e.writeStrings(fc.ParamCapabilities)
+	e.writeStrings(fc.ParamTypes)
+	e.writeString(fc.ReturnType)
+	// Defaults: a count (0 when no parameter has one), then per parameter a
+	// flag byte and, when set, the mini-chunk computing the default.
+	e.writeUint32(uint32(len(fc.Defaults)))
+	for _, def := range fc.Defaults {
+		if def == nil {
+			e.writeByte(0)
+			continue
+		}
+		e.writeByte(1)
+		if err := e.writeChunk(def); err != nil {
+			return err
+		}
+	}
+	// Variadic: 1 when the last parameter collects the extra arguments.
+	if fc.Variadic {
+		e.writeByte(1)
+	} else {
+		e.writeByte(0)
+	}
+	// Generator: 1 when the body gives back values.
+	if fc.Generator {
+		e.writeByte(1)
+	} else {
+		e.writeByte(0)
+	}
 	return e.writeChunk(fc.Body)
 }
 
+// writeStrings writes a count followed by each string.
+func (e *encoder) writeStrings(ss []string) {
+	e.writeUint32(uint32(len(ss)))
+	for _, s := range ss {
+		e.writeString(s)
+	}
+}
+
 func (e *encoder) writeStructDef(sd *StructDef) error {
 	e.writeString(sd.Name)
+	e.writeString(sd.Parent)
+	e.writeStrings(sd.Capabilities)
 	// Fields
 	e.writeUint32(uint32(len(sd.Fields)))
 	for _, fd := range sd.Fields {
@@ -353,9 +427,109 @@ func (d *decoder) readChunk() (*Chunk, error) {
 		c.StructDefs[i] = sd
 	}
 
+	// JumpTables
+	jCount, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	c.JumpTables = make([]*JumpTable, jCount)
+	for i := uint32(0); i < jCount; i++ {
+		jt, err := d.readJumpTable()
+		if err != nil {
+			return nil, err
+		}
+		c.JumpTables[i] = jt
+	}
+
+	// EnumDefs
+	eCount, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	c.EnumDefs = make([]*EnumDef, eCount)
+	for i := uint32(0); i < eCount; i++ {
+		ed, err := d.readEnumDef()
+		if err != nil {
+			return nil, err
+		}
+		c.EnumDefs[i] = ed
+	}
+
+	// CapabilityDefs
+	capCount, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	c.CapabilityDefs = make([]*CapabilityDef, capCount)
+	for i := uint32(0); i < capCount; i++ {
+		name, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		methods, err := d.readStrings()
+		if err != nil {
+			return nil, err
+		}
+		c.CapabilityDefs[i] = &CapabilityDef{Name: name, Methods: methods}
+	}
+
 	return c, nil
 }
 
+// readStrings reads a list written by writeStrings. An empty list reads
+// back as nil.
+func (d *decoder) readStrings() ([]string, error) {
+	n, err := d.readUint32()
+	if err != nil || n == 0 {
+		return nil, err
+	}
+	ss := make([]string, n)
+	for i := range ss {
+		if ss[i], err = d.readString(); err != nil {
+			return nil, err
+		}
+	}
+	return ss, nil
+}
+
+func (d *decoder) readEnumDef() (*EnumDef, error) {
+	name, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	ed := &EnumDef{Name: name, Members: make([]string, n)}
+	for i := uint32(0); i < n; i++ {
+		if ed.Members[i], err = d.readString(); err != nil {
+			return nil, err
+		}
+	}
+	return ed, nil
+}
+
+func (d *decoder) readJumpTable() (*JumpTable, error) {
+	n, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]interface{}, n)
+	targets := make([]uint32, n)
+	for i := uint32(0); i < n; i++ {
+		if keys[i], err = d.readConstant(); err != nil {
+			return nil, err
+		}
+		if targets[i], err = d.readUint32(); err != nil {
+			return nil, err
+		}
+	}
+	jt := NewJumpTable(keys)
+	copy(jt.Targets, targets)
+	return jt, nil
+}
+
 func (d *decoder) readConstant() (interface{}, error) {
 	tag, err := d.readByte()
 	if err != nil {
@@ -388,6 +562,12 @@ func (d *decoder) readConstant() (interface{}, error) {
 			items[i] = s
 		}
 		return items, nil
+	case 5, 6: // whole number, decimal
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		return types.ParseExact(s, tag == 5)
 	default:
 		return nil, fmt.Errorf("ivm decode: unknown constant tag %d", tag)
 	}
@@ -410,11 +590,50 @@ func (d *decoder) readFuncChunk() (*FuncChunk, error) {
 		}
 		params[i] = p
 	}
+	capabilities, err := d.readStrings()
+	if err != nil {
+		return nil, err
+	}
+	paramTypes, err := d.readStrings()
+	if err != nil {
+		return nil, err
+	}
+	returnType, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	dCount, err := d.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	var defaults []*Chunk
+	if dCount > 0 {
+		defaults = make([]*Chunk, dCount)
+	}
+	for i := range defaults {
+		hasDefault, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if hasDefault == 1 {
+			if defaults[i], err = d.readChunk(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	variadic, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	generator, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
 	body, err := d.readChunk()
 	if err != nil {
 		return nil, err
 	}
-	return &FuncChunk{Name: name, Params: params, Body: body}, nil
+	return &FuncChunk{Name: name, Params: params, ParamCapabilities: capabilities, ParamTypes: paramTypes, ReturnType: returnType, Defaults: defaults, Variadic: variadic == 1, Generator: generator == 1, Body: body}, nil
 }
 
 func (d *decoder) readStructDef() (*StructDef, error) {
@@ -422,7 +641,15 @@ func (d *decoder) readStructDef() (*StructDef, error) {
 	if err != nil {
 		return nil, err
 	}
-	sd := &StructDef{Name: name}
+	parent, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	capabilities, err := d.readStrings()
+	if err != nil {
+		return nil, err
+	}
+	sd := &StructDef{Name: name, Parent: parent, Capabilities: capabilities}
 
 	// Fields
 	fCount, err := d.readUint32()
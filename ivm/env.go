@@ -82,6 +82,9 @@ func (e *ivmEnv) setVar(name string, value interface{}) error {
 				types.Canonical(actualKind) != types.Canonical(declared) {
 				return fmt.Errorf("TypeError: cannot assign %s to variable '%s' (declared as %s)\n  Hint: use 'cast to' for explicit conversion", actual, name, en.typeName)
 			}
+			if err := types.CheckElements(name, en.typeName, value, e); err != nil {
+				return err
+			}
 		}
 		en.value = value
 		return nil
@@ -94,6 +97,18 @@ func (e *ivmEnv) setVar(name string, value interface{}) error {
 	return nil
 }
 
+// varType returns the declared type name of a variable in the scope chain,
+// or "" when its type was inferred.
+func (e *ivmEnv) varType(name string) string {
+	if en, ok := e.vars[name]; ok {
+		return en.typeName
+	}
+	if e.parent != nil {
+		return e.parent.varType(name)
+	}
+	return ""
+}
+
 func (e *ivmEnv) defineVar(name string, value interface{}, isConst bool) error {
 	if _, ok := e.vars[name]; ok {
 		return fmt.Errorf("variable '%s' is already defined in this scope", name)
@@ -110,11 +125,17 @@ func (e *ivmEnv) defineTypedVar(name string, typeName string, value interface{},
 	if target == types.TypeUnknown {
 		return fmt.Errorf("TypeError: unknown type '%s'", typeName)
 	}
+	if err := types.CheckElementTypes(typeName, e); err != nil {
+		return err
+	}
 	if value != nil {
 		actual := types.Infer(value)
 		if types.Canonical(actual) != types.Canonical(target) {
 			return fmt.Errorf("TypeError: cannot initialize %s variable '%s' with %s value\n  Hint: use 'cast to' for explicit conversion", typeName, name, types.Name(actual))
 		}
+		if err := types.CheckElements(name, typeName, value, e); err != nil {
+			return err
+		}
 	}
 	e.vars[name] = &envEntry{value: value, typeName: typeName, isConst: isConst}
 	return nil
@@ -130,6 +151,24 @@ func (e *ivmEnv) getFunc(name string) (*FuncChunk, bool) {
 	return nil, false
 }
 
+// getCallable resolves a name used in a call. At each scope level a declared
+// function wins over a variable, and a variable only matches when it holds a
+// function value.
+func (e *ivmEnv) getCallable(name string) (*FuncChunk, bool) {
+	if fn, ok := e.funcs[name]; ok {
+		return fn, true
+	}
+	if en, ok := e.vars[name]; ok {
+		if fn, ok := en.value.(*FuncChunk); ok {
+			return fn, true
+		}
+	}
+	if e.parent != nil {
+		return e.parent.getCallable(name)
+	}
+	return nil, false
+}
+
 func (e *ivmEnv) defineFunc(name string, fn *FuncChunk) {
 	e.funcs[name] = fn
 }
@@ -181,3 +220,37 @@ func (e *ivmEnv) isSubtypeOf(child, parent string) bool {
 	}
 	return false
 }
+
+// IsOfType reports whether v is of the named type: a built-in type, an error
+// type (including inherited types), a structure or an enum.
+func (e *ivmEnv) IsOfType(v interface{}, name string) bool {
+	if matches, known := types.MatchesName(ivmKind(v), name); known {
+		return matches
+	}
+	switch val := v.(type) {
+	case *types.ErrorValue:
+		return e.isSubtypeOf(val.ErrorType, name)
+	case *StructInstance:
+		if val.DefRef != nil {
+			return val.DefRef.isA(name)
+		}
+		return val.DefName == name
+	case *types.EnumValue:
+		return val.Type.Name == name
+	}
+	return false
+}
+
+// IsDeclaredType reports whether name is a structure, enum or error type
+// declared in scope.
+func (e *ivmEnv) IsDeclaredType(name string) bool {
+	if _, ok := e.getStructDef(name); ok {
+		return true
+	}
+	if v, ok := e.getVar(name); ok {
+		if _, isEnum := v.(*types.EnumType); isEnum {
+			return true
+		}
+	}
+	return e.isKnownErrorType(name)
+}
@@ -0,0 +1,22 @@
+package ivm
+
+// Generator is the value of calling a function that gives back values. It
+// holds the function's call frame, suspended at its last OP_YIELD, and runs
+// it a little further each time "For each" asks for another item.
+type Generator struct {
+	Name  string
+	frame *callFrame
+	// m is the machine that created the generator, used when something other
+	// than OP_ITER_NEXT asks it for a value.
+	m    *Machine
+	done bool
+}
+
+// Next implements types.Iterator.
+func (g *Generator) Next() (interface{}, bool, error) {
+	return g.m.resume(g)
+}
+
+func (g *Generator) String() string {
+	return "<generator " + g.Name + ">"
+}
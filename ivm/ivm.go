@@ -18,8 +18,11 @@
 package ivm
 
 import (
+	"fmt"
 	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/astvm/types"
 	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 	"os"
 )
 
@@ -27,7 +30,26 @@ import (
 // builtin is the stdlib function dispatcher.
 // predefined is a map of pre-defined constant values (e.g. math.Pi).
 func Execute(chunk *Chunk, builtin BuiltinFunc, predefined map[string]interface{}) (interface{}, error) {
+	return execute(chunk, "", builtin, predefined, false)
+}
+
+// ExecuteWithoutAssertions is Execute with every "Make sure that" check
+// skipped, in the program and in everything it imports.
+func ExecuteWithoutAssertions(chunk *Chunk, builtin BuiltinFunc, predefined map[string]interface{}) (interface{}, error) {
+	return execute(chunk, "", builtin, predefined, true)
+}
+
+// ExecuteFile is Execute for the program compiled from file, so a file that
+// imports it back is reported as a circular import rather than run again.
+// noAssertions skips every "Make sure that" check.
+func ExecuteFile(chunk *Chunk, file string, builtin BuiltinFunc, predefined map[string]interface{}, noAssertions bool) (interface{}, error) {
+	return execute(chunk, file, builtin, predefined, noAssertions)
+}
+
+func execute(chunk *Chunk, file string, builtin BuiltinFunc, predefined map[string]interface{}, noAssertions bool) (interface{}, error) {
 	m := newMachine(builtin)
+	m.noAssertions = noAssertions
+	m.file = file
 
 	root := newIvmEnv()
 	// Install predefined constants
@@ -35,58 +57,102 @@ func Execute(chunk *Chunk, builtin BuiltinFunc, predefined map[string]interface{
 		root.defineVar(k, v, true)
 	}
 
-	// Set up import handler that reads, compiles, and executes source files
-	m.importHandler = func(path string, items []interface{}, importAll, isSafe bool, env *ivmEnv) error {
-		src, err := os.ReadFile(path)
+	// runIn runs prog, read from file, on a machine of its own, defining what
+	// it declares in env. It runs as part of the task caller runs, so
+	// background tasks it starts share the caller's scheduler.
+	runIn := func(caller *Machine, prog *ast.Program, file string, env *ivmEnv) error {
+		subChunk, err := Compile(prog)
 		if err != nil {
 			return err
 		}
-		lexer := parser.NewLexer(string(src))
-		tokens := lexer.TokenizeAll()
-		p := parser.NewParser(tokens)
-		prog, err := p.Parse()
+		subMachine := newMachine(builtin)
+		subMachine.importHandler = m.importHandler
+		subMachine.noAssertions = noAssertions
+		subMachine.file = file
+		subMachine.sched = caller.scheduler()
+		subMachine.task = caller.task
+		subMachine.cur = &callFrame{
+			chunk: subChunk,
+			ip:    0,
+			stack: []interface{}{},
+			env:   env,
+		}
+		_, err = subMachine.execute(env)
+		return err
+	}
+
+	// Every file runs at most once; later imports of it reuse its scope.
+	modules := project.NewRegistry(file)
+
+	// Set up import handler that reads, compiles, and executes source files
+	m.importHandler = func(caller *Machine, path string, items []interface{}, importAll, isSafe bool, alias string, line int, env *ivmEnv) error {
+		resolved, err := project.ResolveImport(caller.file, path)
 		if err != nil {
 			return err
 		}
 
+		var file *importedFile
 		if isSafe {
 			// Safe import: execute only declarations (VariableDecl, FunctionDecl,
-			// StructDecl, typed declarations, error type declarations) in the current
-			// environment, skipping any top-level side-effectful statements (Print, Call,
-			// etc.). This mirrors the tree-walk evalSafeImport behaviour.
-			safeDecls := safeDeclsOnly(prog)
-			subChunk, err := Compile(safeDecls)
+			// StructDecl, typed declarations, error type declarations), skipping
+			// any top-level side-effectful statements (Print, Call, etc.). This
+			// mirrors the tree-walk evalSafeImport behaviour. They run in a scope
+			// of their own so public functions can still call private ones.
+			prog, err := parseFile(resolved)
 			if err != nil {
 				return err
 			}
-			subMachine := newMachine(builtin)
-			subMachine.importHandler = m.importHandler
-			subMachine.cur = &callFrame{
-				chunk: subChunk,
-				ip:    0,
-				stack: []interface{}{},
-				env:   env, // define directly in caller's env
+			file = &importedFile{env: env.newChild(), private: project.PrivateNames(prog)}
+			if err := runIn(caller, safeDeclsOnly(prog), resolved, file.env); err != nil {
+				return err
 			}
-			_, execErr := subMachine.execute(env)
-			return execErr
+		} else if loaded, ok := modules.Lookup(resolved); ok {
+			file = loaded.(*importedFile)
+		} else {
+			prog, err := parseFile(resolved)
+			if err != nil {
+				return err
+			}
+			if err := modules.Begin(resolved, line); err != nil {
+				return err
+			}
+			file = &importedFile{env: env.newChild(), private: project.PrivateNames(prog)}
+			err = runIn(caller, prog, resolved, file.env)
+			modules.End()
+			if err != nil {
+				return err
+			}
+			modules.Store(resolved, file)
 		}
+		subEnv, private := file.env, file.private
 
-		subChunk, err := Compile(prog)
-		if err != nil {
-			return err
-		}
-		subEnv := env.newChild()
-		subMachine := newMachine(builtin)
-		subMachine.importHandler = m.importHandler
-		subMachine.cur = &callFrame{
-			chunk: subChunk,
-			ip:    0,
-			stack: []interface{}{},
-			env:   subEnv,
-		}
-		_, execErr := subMachine.execute(subEnv)
-		if execErr != nil {
-			return execErr
+		// Namespaced import: bind the alias to a module of the file's public
+		// names
+		if alias != "" {
+			mod := types.NewModule(alias, path)
+			for k, v := range subEnv.vars {
+				if !private[k] {
+					mod.Members[k] = v.value
+				}
+			}
+			for k, v := range subEnv.funcs {
+				if !private[k] {
+					mod.Members[k] = v
+				}
+			}
+			mod.Current = func(name string) (interface{}, bool) {
+				if en, ok := subEnv.vars[name]; ok {
+					return en.value, true
+				}
+				if fn, ok := subEnv.funcs[name]; ok {
+					return fn, true
+				}
+				return nil, false
+			}
+			if err := env.defineVar(alias, mod, true); err != nil {
+				return fmt.Errorf("failed to import '%s' as '%s': %v", path, alias, err)
+			}
+			return nil
 		}
 		// Import specific items or all
 		if !importAll && len(items) > 0 {
@@ -95,6 +161,9 @@ func Execute(chunk *Chunk, builtin BuiltinFunc, predefined map[string]interface{
 				if name == "" {
 					continue
 				}
+				if private[name] {
+					return &project.PrivateError{Name: name, File: path}
+				}
 				val, ok := subEnv.getVar(name)
 				if ok {
 					env.vars[name] = &envEntry{value: val}
@@ -107,10 +176,14 @@ func Execute(chunk *Chunk, builtin BuiltinFunc, predefined map[string]interface{
 		} else {
 			// Import everything (importAll == true or no explicit items list)
 			for k, v := range subEnv.vars {
-				env.vars[k] = v
+				if !private[k] {
+					env.vars[k] = v
+				}
 			}
 			for k, v := range subEnv.funcs {
-				env.funcs[k] = v
+				if !private[k] {
+					env.funcs[k] = v
+				}
 			}
 			for k, v := range subEnv.structDefs {
 				env.structDefs[k] = v
@@ -128,7 +201,28 @@ func Execute(chunk *Chunk, builtin BuiltinFunc, predefined map[string]interface{
 		stack: []interface{}{},
 		env:   root,
 	}
-	return m.execute(root)
+	result, err := m.execute(root)
+	if err == nil && m.sched != nil {
+		// The program is not over until its background tasks are.
+		err = m.sched.Finish(m.task)
+	}
+	return result, err
+}
+
+// importedFile is an imported file once it has run: the scope it ran in and
+// the names it declared privately, which importers cannot see.
+type importedFile struct {
+	env     *ivmEnv
+	private map[string]bool
+}
+
+// parseFile reads and parses the English file at path.
+func parseFile(path string) (*ast.Program, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewParser(parser.NewLexer(string(src)).TokenizeAll()).Parse()
 }
 
 // compileProgram is a helper used internally.
@@ -137,7 +231,8 @@ func compileProgram(prog *ast.Program) (*Chunk, error) {
 }
 
 // safeDeclsOnly returns a new Program containing only declaration statements
-// (VariableDecl, TypedVariableDecl, FunctionDecl, StructDecl, ErrorTypeDecl).
+// (VariableDecl, TypedVariableDecl, FunctionDecl, StructDecl, ErrorTypeDecl, EnumDecl,
+// CapabilityDecl).
 // Used by the safe-import path to skip side-effectful top-level code (Print,
 // Call, etc.), matching the tree-walk evalSafeImport.
 func safeDeclsOnly(prog *ast.Program) *ast.Program {
@@ -148,7 +243,9 @@ func safeDeclsOnly(prog *ast.Program) *ast.Program {
 			*ast.TypedVariableDecl,
 			*ast.FunctionDecl,
 			*ast.StructDecl,
-			*ast.ErrorTypeDecl:
+			*ast.ErrorTypeDecl,
+			*ast.EnumDecl,
+			*ast.CapabilityDecl:
 			filtered.Statements = append(filtered.Statements, stmt)
 		}
 	}
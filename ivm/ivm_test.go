@@ -62,6 +62,9 @@ tests := []string{
 `Declare x to be 4 * 5.`,
 `Declare x to be 10 / 2.`,
 `Declare x to be the remainder of 10 divided by 3.`,
+`Declare x to be 2 to the power of 8.`,
+`Declare x to be 7 divided evenly by 2.`,
+`Declare x to be 12 bitwise and 10 bitwise or 1 shifted left by 2.`,
 }
 for _, src := range tests {
 _, err := run(src)
@@ -71,6 +74,24 @@ t.Fatalf("%q: error: %v", src, err)
 }
 }
 
+func TestPowerFloorAndBitwise(t *testing.T) {
+	out := captureOutput(func() {
+		_, err := run(`Print 2 to the power of 10, 7 divided evenly by 2, -7 divided evenly by 2.
+Print 12 bitwise and 10, 12 bitwise or 3, 12 bitwise xor 10, 1 shifted left by 4, 256 shifted right by 2.`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if out != "1024 3 -4\n8 15 6 16 64\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+	for _, src := range []string{`Print 1.5 bitwise and 1.`, `Print 1 shifted left by -1.`, `Print 1 divided evenly by 0.`} {
+		if _, err := run(src); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
+
 // ─── Variables ────────────────────────────────────────────────────────────────
 
 func TestVariableDecl(t *testing.T) {
@@ -921,3 +942,843 @@ if !strings.Contains(py, "def __init__(self,") {
 t.Errorf("missing __init__ in:\n%s", py)
 }
 }
+
+func TestDecompileFunctionLiteral(t *testing.T) {
+	py, err := decompileSource(`Declare double to be a function that takes x and returns x * 2.
+Declare ops to be [a function that takes n and returns n + 1, double].`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(py, "def double(x):") {
+		t.Errorf("missing def for named function literal in:\n%s", py)
+	}
+	if !strings.Contains(py, "ops = [lambda n: ") {
+		t.Errorf("missing lambda in:\n%s", py)
+	}
+}
+
+func TestFunctionLiteralValue(t *testing.T) {
+	out := captureOutput(func() {
+		_, err := run(`Declare double to be a function that takes x and returns x * 2.
+Declare f to be double.
+Print f(4).
+Print f.`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if out != "8\n<function double>\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestHigherOrderListFunctions(t *testing.T) {
+	out := captureOutput(func() {
+		_, err := run(`Declare numbers to be [1, 2, 3, 4].
+Declare is_even to be a function that takes n and returns the remainder of n divided by 2 is equal to 0.
+Print count_where(numbers, is_even).
+Print combine(numbers, a function that takes total and n and returns total + n, 0).`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if out != "2\n10\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestDecompileHigherOrder(t *testing.T) {
+	py, err := decompileSource(`Declare nums to be [1, 2, 3].
+Declare add to be a function that takes a and b and returns a + b.
+Declare total to be combine(nums, add, 0).`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(py, "_combine(nums, add, 0)") || !strings.Contains(py, "def _combine") {
+		t.Errorf("missing _combine call or helper in:\n%s", py)
+	}
+}
+
+func TestInterpolatedString(t *testing.T) {
+	out := captureOutput(func() {
+		_, err := run(`Declare name to be "Bo".
+Declare scores to be [3, 4].
+Print "{name} scored {(the item at position 1 in scores) + 1} in {scores}, \{ok\}".`)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if out != "Bo scored 5 in [3 4], {ok}\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestDecompileInterpolatedString(t *testing.T) {
+	py, err := decompileSource(`Declare name to be "Bo".
+Declare greeting to be "Hi {name}, {} {'x' + name}".`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(py, `greeting = ("Hi " + str(name) + ", {} " + str(("x" + name)))`) {
+		t.Errorf("missing concatenation fallback in:\n%s", py)
+	}
+	py, err = decompileSource(`Declare name to be "Bo".
+Declare greeting to be "Hi {name}! {}".`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(py, `greeting = f"Hi {name}! {{}}"`) {
+		t.Errorf("missing f-string in:\n%s", py)
+	}
+}
+
+const whenSource = `Declare function describe that takes thing and does the following:
+    When thing is "start" or "go":
+        Return "starting".
+    When it is 0:
+        Return "zero".
+    When it is between 1 and 10:
+        Return "small".
+    When it is a number:
+        Return "number".
+    Otherwise:
+        Return "other".
+    thats it.
+thats it.
+Print describe("go").
+Print describe(0).
+Print describe(4).
+Print describe(40).
+Print describe(true).`
+
+func TestWhenStatement(t *testing.T) {
+	out := captureOutput(func() {
+		if _, err := run(whenSource); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	if out != "starting\nzero\nsmall\nnumber\nother\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestWhenJumpTable(t *testing.T) {
+	chunk, err := compileSource(whenSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunk.Funcs) != 1 || len(chunk.Funcs[0].Body.JumpTables) != 1 {
+		t.Fatalf("expected one jump table in describe")
+	}
+	if got := len(chunk.Funcs[0].Body.JumpTables[0].Keys); got != 3 {
+		t.Errorf("expected 3 table keys, got %d", got)
+	}
+}
+
+func TestDecompileWhenStatement(t *testing.T) {
+	py, err := decompileSource(whenSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`case "start" | "go":`, "case 0 if not isinstance(__hidden_1, bool):", "case _:", "and 1 <= __hidden_1 <= 10:", "isinstance("} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+const meterSource = `declare Meter as a structure with the following fields:
+    reading is an unsigned integer.
+    label is a string.
+thats it.
+Declare m to be a new instance of Meter with the following fields:
+    label is "gas".
+    reading is 5.
+thats it.
+Print m's reading.`
+
+func TestIntegerFieldOverflow(t *testing.T) {
+	_, err := run(meterSource + `
+Set m's reading to be (m's reading) - 6.`)
+	if err == nil || !strings.Contains(err.Error(), "does not fit in an unsigned integer") {
+		t.Errorf("expected an overflow error, got %v", err)
+	}
+}
+
+func TestDecompileStructInstantiation(t *testing.T) {
+	py, err := decompileSource(meterSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`Meter(label="gas", reading=5)`, "m.reading"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeDecimalConstants(t *testing.T) {
+	chunk, err := compileSource(`Declare price to be the decimal 0.10.
+Declare big to be the whole number 123456789012345678901234567890.
+Print price * 3.
+Print big + 1.`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "0.30\n123456789012345678901234567891\n"; out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func TestDecompileDecimal(t *testing.T) {
+	py, err := decompileSource(`Declare price to be the decimal 0.10.
+Print price cast to decimal.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"from decimal import Decimal", `Decimal("0.10")`, "Decimal(str(price))"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeEnum(t *testing.T) {
+	chunk, err := compileSource(`Declare Color as one of red, green and blue.
+Declare c to be Color's green.
+For each m in Color, do the following:
+    Print m.
+thats it.
+If c is equal to Color's green, then
+    Print "same".
+thats it.`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(decoded.EnumDefs) != 1 || decoded.EnumDefs[0].Name != "Color" || len(decoded.EnumDefs[0].Members) != 3 {
+		t.Fatalf("enum definition not preserved: %+v", decoded.EnumDefs)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "red\ngreen\nblue\nsame\n"; out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func TestDecompileEnum(t *testing.T) {
+	py, err := decompileSource(`Declare Color as one of red, green and blue.
+Declare c to be Color's green.
+Print c.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"import enum", "class Color(enum.Enum):", "    blue = 3", "c = Color.green"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeStructParent(t *testing.T) {
+	chunk, err := compileSource(`declare Animal as a structure with the following fields:
+    name is a string with "Rex" being the default.
+thats it.
+declare Dog as a kind of Animal with the following fields:
+    breed is a string with "collie" being the default.
+thats it.
+Declare d to be a new instance of Dog.
+Print d's name, d's breed.`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if got := decoded.StructDefs[1].Parent; got != "Animal" {
+		t.Fatalf("expected parent Animal, got %q", got)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "Rex collie\n"; out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func TestEncodeDecodeCapability(t *testing.T) {
+	chunk, err := compileSource(`Declare Speaker as a capability requiring a function speak.
+declare Dog as a structure that can Speaker with the following fields:
+    name is a string with "Rex" being the default.
+    let speak be a function that does the following:
+        Print name, "says woof".
+    thats it.
+thats it.
+Declare function greet that takes pet as anything that can Speaker and does the following:
+    Call pet's speak.
+thats it.
+Declare d to be a new instance of Dog.
+Call greet with d.
+Try doing the following:
+    Call greet with 5.
+on TypeError:
+    Print error.
+thats it.`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if len(decoded.CapabilityDefs) != 1 || decoded.CapabilityDefs[0].Methods[0] != "speak" {
+		t.Fatalf("capability definition not preserved: %+v", decoded.CapabilityDefs)
+	}
+	if got := decoded.StructDefs[0].Capabilities; len(got) != 1 || got[0] != "Speaker" {
+		t.Fatalf("struct capabilities not preserved: %v", got)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	want := "Rex says woof\n<error: function 'greet' needs 'pet' to be something that can Speaker, but got number>\n"
+	if out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func TestDecompileCapability(t *testing.T) {
+	py, err := decompileSource(`Declare Speaker as a capability requiring a function speak.
+declare Dog as a structure that can Speaker with the following fields:
+    let speak be a function that does the following:
+        Print "woof".
+    thats it.
+thats it.
+Declare function greet that takes pet as anything that can Speaker and does the following:
+    Call pet's speak.
+thats it.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"from typing import Protocol", "class Speaker(Protocol):", "    def speak(self): ...", "class Dog(Speaker):", "def greet(pet: Speaker):"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeTypedSignature(t *testing.T) {
+	chunk, err := compileSource(`Declare function area that takes width as number and height as number and returns a number and does the following:
+    Return width * height.
+thats it.
+Declare function label that takes n and returns text and does the following:
+    Return n.
+thats it.
+Print area(2, 3).
+Try doing the following:
+    Print area("2", 3).
+on TypeError:
+    Print error.
+thats it.
+Try doing the following:
+    Print label(1).
+on TypeError:
+    Print error.
+thats it.`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if fn := decoded.Funcs[0]; len(fn.ParamTypes) != 2 || fn.ParamTypes[1] != "number" || fn.ReturnType != "number" {
+		t.Fatalf("signature not preserved: %v returning %q", fn.ParamTypes, fn.ReturnType)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	want := "6\n<error: function 'area' needs 'width' to be a number, but got text>\n" +
+		"<error: function 'label' should return text, but returned a number>\n"
+	if out != want {
+		t.Errorf("expected %q, got %q", want, out)
+	}
+}
+
+func TestDecompileTypedSignature(t *testing.T) {
+	py, err := decompileSource(`declare Point as a structure with the following fields:
+    x is a number.
+thats it.
+Declare function area that takes width as number and height as number (defaulting to 1) and returns a number and does the following:
+    Return width * height.
+thats it.
+Declare function show that takes p as a Point and f as a function and returns nothing and does the following:
+    Print p's x.
+thats it.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"from typing import Callable", "def area(width: float, height: float = 1) -> float:", `def show(p: "Point", f: Callable) -> None:`} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestDecompileElementTypes(t *testing.T) {
+	py, err := decompileSource(`Declare scores as a list of numbers to be [1, 2].
+Declare ages as a lookup table from text to number.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"scores: list[float] = [1, 2]", "ages: dict[str, float] = {}"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestDecompileNamespacedImport(t *testing.T) {
+	py, err := decompileSource(`Import "examples/math_library.abc" as ml.
+Declare s to be the result of calling ml's square with 3.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"import math_library as ml", "s = ml.square(3)"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeDefaultsAndNamedArguments(t *testing.T) {
+	chunk, err := compileSource(`Declare function greet that takes name and greeting (defaulting to "Hello") and does the following:
+    Print greeting, name.
+thats it.
+Call greet with "Ann".
+Call greet with greeting as "Yo" and name as "Bo".`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if got := decoded.Funcs[0].Defaults; len(got) != 2 || got[0] != nil || got[1] == nil {
+		t.Fatalf("parameter defaults not preserved: %v", got)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "Hello Ann\nYo Bo\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestDecompileDefaultsAndNamedArguments(t *testing.T) {
+	py, err := decompileSource(`Declare function greet that takes name and greeting (defaulting to "Hello") and does the following:
+    Print greeting, name.
+thats it.
+Call greet with "Ann" and greeting as "Hi".`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`def greet(name, greeting="Hello"):`, `greet("Ann", greeting="Hi")`} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestDecompileDefaultWorkedOutEachCall(t *testing.T) {
+	py, err := decompileSource(`Declare function pair that takes a and b (defaulting to a * 2) and items (defaulting to []) and does the following:
+    Print a, b, items.
+thats it.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`def pair(a, b=None, items=None):`, "    if b is None:\n        b = (a * 2)\n", "    if items is None:\n        items = []\n"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestDecompileLoopLabels(t *testing.T) {
+	py, err := decompileSource(`Declare i to be 0.
+Repeat the following while i is less than 3 and call this loop outer:
+    Set i to be i + 1.
+    For each x in [1, 2], do the following:
+        Repeat the following 2 times:
+            If x is equal to 1, then
+                Continue outer.
+            thats it.
+            Break out of outer.
+        thats it.
+    thats it.
+    Print i.
+thats it.
+Repeat the following 3 times:
+    If i is equal to 3, then
+        Break out of this loop.
+    thats it.
+    Continue.
+thats it.
+Print "done".`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Python has no labeled break: each loop left on the way out breaks
+	// again on a flag, and the loop directly inside outer continues it.
+	want := `while i < 3:
+    i = (i + 1)
+    _continue_1 = False
+    _break_1 = False
+    for x in [1, 2]:
+        for _ in range(int(2)):
+            if x == 1:
+                _continue_1 = True
+                break
+            _break_1 = True
+            break
+        if _continue_1:
+            break
+        if _break_1:
+            break
+    if _continue_1:
+        continue
+    if _break_1:
+        break
+    print(i)
+for _ in range(int(3)):
+    if i == 3:
+        break
+    continue
+print("done")
+`
+	if !strings.Contains(py, want) {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, py)
+	}
+
+	if _, err := compileSource(`Repeat the following 2 times:
+    Break out of outer.
+thats it.`); err == nil || !strings.Contains(err.Error(), "no loop called 'outer'") {
+		t.Errorf("expected an error for a break naming no loop, got %v", err)
+	}
+}
+
+func TestEncodeDecodeRestParameter(t *testing.T) {
+	chunk, err := compileSource(`Declare function label that takes prefix and any number of items and does the following:
+    Print prefix, items.
+thats it.
+Call label with "a".
+Call label with "b" and 1 and 2.`)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !decoded.Funcs[0].Variadic {
+		t.Fatal("rest parameter not preserved")
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "a []\nb [1 2]\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	py, err := decompileSource(`Declare function label that takes prefix and any number of items and does the following:
+    Print prefix, items.
+thats it.`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `def label(prefix, *items):`; !strings.Contains(py, want) {
+		t.Errorf("missing %q in:\n%s", want, py)
+	}
+	if want := `items = list(items)`; !strings.Contains(py, want) {
+		t.Errorf("missing %q in:\n%s", want, py)
+	}
+}
+
+func TestEncodeDecodeDestructuring(t *testing.T) {
+	const src = `Declare function divide that takes a and b and does the following:
+    Return a divided evenly by b and the remainder of a divided by b.
+thats it.
+Declare q and r to be the result of calling divide with 7 and 2.
+Print q, r.
+Set q and r to [r, q].
+Print q, r.
+Declare first and the rest as rest to be [1, 2, 3].
+Print first, rest.`
+	chunk, err := compileSource(src)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "3 1\n1 3\n1 [2 3]\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	py, err := decompileSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`q, r = _unpack(divide(7, 2), "q", "r")`, `q, r = [r, q]`, `first, *rest = [1, 2, 3]`} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeGenerator(t *testing.T) {
+	const src = `Declare function evens that takes limit and does the following:
+    Declare i to be 0.
+    repeat the following while i is less than limit:
+        Give back i.
+        Set i to i + 2.
+    thats it.
+thats it.
+For each n in evens(5), do the following:
+    Print n.
+thats it.`
+	chunk, err := compileSource(src)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if !decoded.Funcs[0].Generator {
+		t.Errorf("expected evens to decode as a generator")
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "0\n2\n4\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	py, err := decompileSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"yield i", "for n in evens(5):"} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeBackgroundTasks(t *testing.T) {
+	const src = `Declare results to be a channel.
+Do the following in the background and call it producer:
+    Send 10 to results.
+    Send 20 to results.
+thats it.
+Receive from results into a.
+Receive from results into b.
+Wait for producer.
+Print a + b.`
+	chunk, err := compileSource(src)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	out := captureOutput(func() {
+		if _, err := ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if want := "30\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	py, err := decompileSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"import threading", "import queue", "results = queue.Queue()",
+		"def _background_producer():", "results.put(10)",
+		"producer = _Task(_background_producer)", "a = results.get()", "producer.wait()",
+	} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestEncodeDecodeAssertions(t *testing.T) {
+	const src = `Declare balance to be -5.
+Make sure that balance is at most 10.
+Make sure that balance is at least 0, otherwise say "negative balance".`
+	chunk, err := compileSource(src)
+	if err != nil {
+		t.Fatalf("compile error: %v", err)
+	}
+	data, err := ivm.EncodeFile(chunk)
+	if err != nil {
+		t.Fatalf("encode error: %v", err)
+	}
+	decoded, err := ivm.DecodeFile(data)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	_, err = ivm.Execute(decoded, stdlib.Eval, stdlib.PredefinedValues())
+	if err == nil || !strings.Contains(err.Error(), "negative balance") || !strings.Contains(err.Error(), "balance was -5") {
+		t.Errorf("expected the assertion on line 3 to fail, got %v", err)
+	}
+	if _, err := ivm.ExecuteWithoutAssertions(decoded, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+		t.Errorf("expected no error with assertions off, got %v", err)
+	}
+
+	py, err := decompileSource(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`assert (balance <= 10)`, `assert (balance >= 0), "negative balance"`} {
+		if !strings.Contains(py, want) {
+			t.Errorf("missing %q in:\n%s", want, py)
+		}
+	}
+}
+
+func TestRunTestBlocks(t *testing.T) {
+	const src = `Declare counter to be 0.
+Declare function bump that does the following:
+    Set counter to counter + 1.
+thats it.
+Print "top level".
+Test "first" by doing the following:
+    Call bump.
+    Make sure that counter is equal to 1.
+thats it.
+Test "second" by doing the following:
+    Call bump.
+    Make sure that counter is equal to 1, otherwise say "state leaked between tests".
+thats it.
+Test "failing" by doing the following:
+    Make sure that counter is greater than 0.
+thats it.`
+	prog, err := parser.NewParser(parser.NewLexer(src).TokenizeAll()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := ivm.Tests(prog)
+	if len(tests) != 3 || tests[0].Name != "first" || tests[2].Name != "failing" {
+		t.Fatalf("expected the three tests in order, got %v", tests)
+	}
+
+	var errs []error
+	out := captureOutput(func() {
+		for _, test := range tests {
+			errs = append(errs, ivm.RunTest(prog, test, stdlib.Eval, stdlib.PredefinedValues()))
+		}
+	})
+	if out != "" {
+		t.Errorf("expected the file's top-level code not to run, got %q", out)
+	}
+	if errs[0] != nil || errs[1] != nil {
+		t.Errorf("expected the first two tests to pass, got %v and %v", errs[0], errs[1])
+	}
+	if errs[2] == nil || !strings.Contains(errs[2].Error(), "AssertionError") {
+		t.Errorf("expected the last test to fail with an AssertionError, got %v", errs[2])
+	}
+
+	// Running the program skips its tests.
+	chunk, err := ivm.Compile(prog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = captureOutput(func() {
+		if _, err := ivm.Execute(chunk, stdlib.Eval, stdlib.PredefinedValues()); err != nil {
+			t.Errorf("execute error: %v", err)
+		}
+	})
+	if out != "top level\n" {
+		t.Errorf("got %q, want %q", out, "top level\n")
+	}
+}
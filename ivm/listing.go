@@ -14,9 +14,11 @@ package ivm
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"os"
 	"strings"
 
+	"github.com/Advik-B/english/astvm/types"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/termenv"
 )
@@ -120,17 +122,41 @@ func printChunk(sb *strings.Builder, chunk *Chunk, title string, color bool, dep
 	}
 
 	// ── Function sub-chunks ───────────────────────────────────────────────────
-	for _, fc := range chunk.Funcs {
-		params := strings.Join(fc.Params, ", ")
+	spawned := make(map[uint32]bool)
+	for _, instr := range chunk.Code {
+		if instr.Op == OP_SPAWN {
+			spawned[instr.Operand] = true
+		}
+	}
+	for i, fc := range chunk.Funcs {
+		signature := listedSignature(fc)
+		kind := "function"
+		if fc.Generator {
+			kind = "generator"
+		} else if spawned[uint32(i)] {
+			kind = "background"
+		}
 		sb.WriteString("\n")
-		printChunk(sb, fc.Body, fmt.Sprintf("function %s(%s)", fc.Name, params), color, depth+1)
+		printChunk(sb, fc.Body, fmt.Sprintf("%s %s%s", kind, fc.Name, signature), color, depth+1)
+		printParamDefaults(sb, fc, fc.Name, color, depth+1)
 	}
 
 	// ── Struct sub-chunks ─────────────────────────────────────────────────────
 	for _, sd := range chunk.StructDefs {
 		sb.WriteString("\n")
 		sb.WriteString(indent)
-		sb.WriteString(applyStyle(color, lsComment, fmt.Sprintf("struct %s:", sd.Name)))
+		var notes []string
+		if sd.Parent != "" {
+			notes = append(notes, "kind of "+sd.Parent)
+		}
+		if len(sd.Capabilities) > 0 {
+			notes = append(notes, "can "+strings.Join(sd.Capabilities, ", "))
+		}
+		header := fmt.Sprintf("struct %s:", sd.Name)
+		if len(notes) > 0 {
+			header = fmt.Sprintf("struct %s (%s):", sd.Name, strings.Join(notes, "; "))
+		}
+		sb.WriteString(applyStyle(color, lsComment, header))
 		sb.WriteString("\n")
 		for _, f := range sd.Fields {
 			line := fmt.Sprintf("%s  field %s : %s", indent, f.Name, f.TypeName)
@@ -142,10 +168,40 @@ func printChunk(sb *strings.Builder, chunk *Chunk, title string, color bool, dep
 			}
 		}
 		for _, m := range sd.Methods {
-			params := strings.Join(m.Params, ", ")
+			signature := listedSignature(m)
 			sb.WriteString("\n")
 			printChunk(sb, m.Body,
-				fmt.Sprintf("method %s.%s(%s)", sd.Name, m.Name, params), color, depth+2)
+				fmt.Sprintf("method %s.%s%s", sd.Name, m.Name, signature), color, depth+2)
+			printParamDefaults(sb, m, sd.Name+"."+m.Name, color, depth+2)
+		}
+	}
+}
+
+// listedSignature renders fc's parameters for a chunk header, with the type
+// of each typed one, marking a rest parameter with "*" and ending with the
+// type fc returns, if it says.
+func listedSignature(fc *FuncChunk) string {
+	params := append([]string(nil), fc.Params...)
+	for i, typeName := range fc.ParamTypes {
+		if typeName != "" && i < len(params) {
+			params[i] += " : " + typeName
+		}
+	}
+	if fc.Variadic && len(params) > 0 {
+		params[len(params)-1] = "*" + params[len(params)-1]
+	}
+	signature := "(" + strings.Join(params, ", ") + ")"
+	if fc.ReturnType != "" {
+		signature += " -> " + fc.ReturnType
+	}
+	return signature
+}
+
+// printParamDefaults lists the mini-chunks computing fc's parameter defaults.
+func printParamDefaults(sb *strings.Builder, fc *FuncChunk, label string, color bool, depth int) {
+	for i, def := range fc.Defaults {
+		if def != nil {
+			printChunk(sb, def, fmt.Sprintf("default(%s.%s)", label, fc.Params[i]), color, depth)
 		}
 	}
 }
@@ -179,8 +235,25 @@ func formatOperand(chunk *Chunk, instr Instruction) string {
 		return name(operand)
 	case OP_JUMP:
 		return fmt.Sprintf("-> %d", operand)
-	case OP_JUMP_IF_FALSE, OP_JUMP_IF_TRUE:
+	case OP_JUMP_IF_FALSE, OP_JUMP_IF_TRUE, OP_ITER_NEXT, OP_ASSERT:
 		return fmt.Sprintf("-> %d", operand)
+	case OP_ASSERT_CHECK:
+		if kind := operand >> 24; kind > 0 {
+			return fmt.Sprintf("%s -> %d", BinOp(kind-1), operand&0xFFFFFF)
+		}
+		return fmt.Sprintf("-> %d", operand&0xFFFFFF)
+	case OP_JUMP_TABLE:
+		if int(operand) < len(chunk.JumpTables) {
+			jt := chunk.JumpTables[operand]
+			cases := make([]string, len(jt.Keys))
+			for i, k := range jt.Keys {
+				cases[i] = fmt.Sprintf("%s -> %d", formatConst(k), jt.Targets[i])
+			}
+			return fmt.Sprintf("{%s} (tables[%d])", strings.Join(cases, ", "), operand)
+		}
+		return fmt.Sprintf("tables[%d]", operand)
+	case OP_IS_TYPE:
+		return name(operand)
 	case OP_BINARY_OP:
 		return BinOp(operand).String()
 	case OP_UNARY_OP:
@@ -190,15 +263,15 @@ func formatOperand(chunk *Chunk, instr Instruction) string {
 		case UnaryNot:
 			return "not"
 		}
-	case OP_CALL:
-		argc := operand >> 16
+	case OP_CALL, OP_CALL_METHOD:
+		argc := operand >> 16 & 0xFF
+		kwc := operand >> 24
 		nameIdx := operand & 0xFFFF
+		if kwc > 0 {
+			return fmt.Sprintf("%s argc=%d kwc=%d", name(nameIdx), argc, kwc)
+		}
 		return fmt.Sprintf("%s argc=%d", name(nameIdx), argc)
-	case OP_CALL_METHOD:
-		argc := operand >> 16
-		methIdx := operand & 0xFFFF
-		return fmt.Sprintf("%s argc=%d", name(methIdx), argc)
-	case OP_DEFINE_FUNC:
+	case OP_DEFINE_FUNC, OP_MAKE_FUNC, OP_SPAWN:
 		if int(operand) < len(chunk.Funcs) {
 			fc := chunk.Funcs[operand]
 			return fmt.Sprintf("%q (funcs[%d])", fc.Name, operand)
@@ -213,6 +286,18 @@ func formatOperand(chunk *Chunk, instr Instruction) string {
 		fieldCount := operand >> 16
 		snIdx := operand & 0xFFFF
 		return fmt.Sprintf("%s fields=%d", name(snIdx), fieldCount)
+	case OP_DEFINE_ENUM:
+		if int(operand) < len(chunk.EnumDefs) {
+			ed := chunk.EnumDefs[operand]
+			return fmt.Sprintf("%q (enums[%d]) members=%s", ed.Name, operand, strings.Join(ed.Members, ","))
+		}
+		return fmt.Sprintf("enums[%d]", operand)
+	case OP_DEFINE_CAPABILITY:
+		if int(operand) < len(chunk.CapabilityDefs) {
+			cd := chunk.CapabilityDefs[operand]
+			return fmt.Sprintf("%q (capabilities[%d]) requires=%s", cd.Name, operand, strings.Join(cd.Methods, ","))
+		}
+		return fmt.Sprintf("capabilities[%d]", operand)
 	case OP_GET_FIELD, OP_SET_FIELD:
 		return name(operand)
 	case OP_CAST:
@@ -233,8 +318,10 @@ func formatOperand(chunk *Chunk, instr Instruction) string {
 		count := operand >> 1
 		newline := (operand & 1) == 1
 		return fmt.Sprintf("count=%d newline=%v", count, newline)
-	case OP_BUILD_LIST, OP_BUILD_ARRAY:
+	case OP_BUILD_LIST, OP_BUILD_ARRAY, OP_BUILD_STRING:
 		return fmt.Sprintf("count=%d", operand)
+	case OP_UNPACK:
+		return fmt.Sprintf("count=%d rest=%v", operand&^unpackRest, operand&unpackRest != 0)
 	case OP_INDEX_SET:
 		return name(operand)
 	case OP_LOOKUP_SET:
@@ -290,9 +377,17 @@ func formatOperand(chunk *Chunk, instr Instruction) string {
 		if flags&1 != 0 {
 			parts = append(parts, "selective")
 		}
+		if flags&8 != 0 {
+			parts = append(parts, "as")
+		}
 		return strings.Join(parts, " ")
 	case OP_SET_LINE:
 		return fmt.Sprintf("line=%d", operand)
+	case OP_SET_OVERFLOW_MODE:
+		if operand == 1 {
+			return "wrapping"
+		}
+		return "checked"
 	}
 	return ""
 }
@@ -324,6 +419,20 @@ func (b BinOp) String() string {
 		return ">"
 	case BinGte:
 		return ">="
+	case BinPow:
+		return "**"
+	case BinFloorDiv:
+		return "//"
+	case BinBitAnd:
+		return "&"
+	case BinBitOr:
+		return "|"
+	case BinBitXor:
+		return "^"
+	case BinShl:
+		return "<<"
+	case BinShr:
+		return ">>"
 	default:
 		return fmt.Sprintf("binop(%d)", int(b))
 	}
@@ -351,6 +460,10 @@ func formatConst(v interface{}) string {
 		return "false"
 	case nil:
 		return "nothing"
+	case *types.Decimal:
+		return "decimal " + val.String()
+	case *big.Int:
+		return "whole " + val.String()
 	case []interface{}:
 		parts := make([]string, len(val))
 		for i, item := range val {
@@ -365,17 +478,19 @@ func formatConst(v interface{}) string {
 func opStyle(op Opcode) lipgloss.Style {
 	switch op {
 	case OP_DEFINE_VAR, OP_DEFINE_CONST, OP_DEFINE_TYPED, OP_DEFINE_TYPED_CONST,
-		OP_DEFINE_FUNC, OP_DEFINE_STRUCT, OP_DEFINE_ERROR_TYPE,
+		OP_DEFINE_FUNC, OP_MAKE_FUNC, OP_DEFINE_STRUCT, OP_DEFINE_ERROR_TYPE, OP_DEFINE_ENUM,
+		OP_DEFINE_CAPABILITY,
 		OP_LOAD_CONST, OP_LOAD_NOTHING, OP_LOAD_VAR,
-		OP_BUILD_LIST, OP_BUILD_ARRAY, OP_BUILD_LOOKUP,
-		OP_NEW_STRUCT, OP_IMPORT:
+		OP_BUILD_LIST, OP_BUILD_ARRAY, OP_BUILD_LOOKUP, OP_BUILD_STRING,
+		OP_NEW_STRUCT, OP_IMPORT, OP_NEW_CHANNEL:
 		return lsOpData
-	case OP_JUMP, OP_JUMP_IF_FALSE, OP_JUMP_IF_TRUE, OP_RETURN,
+	case OP_JUMP, OP_JUMP_IF_FALSE, OP_JUMP_IF_TRUE, OP_JUMP_TABLE, OP_RETURN,
 		OP_TRY_BEGIN, OP_TRY_END, OP_CATCH, OP_RAISE,
-		OP_TRY_SET_ERRORTYPE, OP_TRY_SET_FINALLY, OP_RERAISE_PENDING,
-		OP_PUSH_SCOPE, OP_POP_SCOPE:
+		OP_TRY_SET_ERRORTYPE, OP_TRY_SET_FINALLY, OP_RERAISE_PENDING, OP_TRY_POP,
+		OP_PUSH_SCOPE, OP_POP_SCOPE, OP_ITER, OP_ITER_NEXT, OP_YIELD,
+		OP_SPAWN, OP_WAIT, OP_ASSERT, OP_ASSERT_CHECK, OP_ASSERT_FAILED:
 		return lsOpCtrl
-	case OP_PRINT, OP_ASK:
+	case OP_PRINT, OP_ASK, OP_SEND, OP_RECEIVE:
 		return lsOpIO
 	case OP_CALL, OP_CALL_METHOD:
 		return lsOpCall
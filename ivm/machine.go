@@ -2,7 +2,9 @@ package ivm
 
 import (
 "bufio"
+"errors"
 "github.com/Advik-B/english/astvm/types"
+"github.com/Advik-B/english/project"
 "fmt"
 "os"
 "strings"
@@ -39,8 +41,22 @@ type Machine struct {
 frames  []*callFrame
 cur     *callFrame
 builtin BuiltinFunc
-// importHandler is called for OP_IMPORT; if nil, imports are silently skipped.
-importHandler func(path string, items []interface{}, importAll, isSafe bool, env *ivmEnv) error
+// importHandler is called for OP_IMPORT with the importing machine; if nil,
+// imports are silently skipped.
+importHandler func(caller *Machine, path string, items []interface{}, importAll, isSafe bool, alias string, line int, env *ivmEnv) error
+// wrapIntegers is set by OP_SET_OVERFLOW_MODE; integer overflow wraps instead of raising.
+wrapIntegers bool
+// yielding is set by OP_YIELD so runFrame can tell a suspension from a return.
+yielding bool
+// sched runs the program's background tasks; it is nil until the first one
+// starts or a channel is used. task is the one this machine runs.
+sched *types.Scheduler
+task  *types.Task
+// noAssertions skips "Make sure that" checks (see ExecuteWithoutAssertions).
+noAssertions bool
+// file is the source file of the code this machine runs, or "" when that is
+// not a file; the files it imports are looked for from its directory.
+file string
 }
 
 func newMachine(builtin BuiltinFunc) *Machine {
@@ -95,6 +111,32 @@ return fmt.Sprintf("Runtime Error at line %d: %s", e.line, e.message)
 return fmt.Sprintf("Runtime Error: %s", e.message)
 }
 
+// raiseErr returns err unchanged when it is an error value, which an
+// on-clause can catch by type, and as a runtime error otherwise.
+func (m *Machine) raiseErr(err error) error {
+if _, ok := err.(*types.ErrorValue); ok {
+return err
+}
+return m.runtimeErr(err.Error())
+}
+
+// binaryOp applies op to left and right the way OP_BINARY_OP does.
+func (m *Machine) binaryOp(op BinOp, left, right interface{}) (interface{}, error) {
+// Arithmetic on an integer struct field keeps its kind and is range checked.
+if res, handled, err := types.IntegerArithmetic(op.String(), left, right, m.wrapIntegers); handled {
+return res, err
+}
+// Decimals and whole numbers are exact; see types.ExactArithmetic.
+if res, handled, err := types.ExactArithmetic(op.String(), left, right); handled {
+return res, err
+}
+res, err := doBinaryOp(op, left, right)
+if err != nil {
+return nil, m.raiseErr(err)
+}
+return res, nil
+}
+
 // execute runs the machine until the outermost frame returns.
 func (m *Machine) execute(env *ivmEnv) (interface{}, error) {
 for {
@@ -209,15 +251,42 @@ case OP_LOAD_VAR:
 name := chunk.Names[operand]
 val, ok := m.env().getVar(name)
 if !ok {
+// A declared function used by name is a function value.
+fn, isFunc := m.env().getFunc(name)
+if !isFunc {
 return nil, false, m.runtimeErr(fmt.Sprintf("undefined variable '%s'", name))
 }
+val = fn
+}
 m.push(val)
 
+case OP_UNPACK:
+count := int(operand &^ unpackRest)
+rest := operand&unpackRest != 0
+names := make([]string, count)
+for i := count - 1; i >= 0; i-- {
+names[i], _ = m.pop().(string)
+}
+value := m.pop()
+var values []interface{}
+var err error
+if si, ok := value.(*StructInstance); ok {
+values, err = types.Unpack(value, names, rest, si.DefName, si.Fields)
+} else {
+values, err = types.Unpack(value, names, rest, "", nil)
+}
+if err != nil {
+return nil, false, err
+}
+for i := count - 1; i >= 0; i-- {
+m.push(values[i])
+}
+
 case OP_STORE_VAR:
 name := chunk.Names[operand]
 val := m.pop()
 if err := m.env().setVar(name, val); err != nil {
-return nil, false, m.runtimeErr(err.Error())
+return nil, false, m.raiseErr(err)
 }
 
 case OP_DEFINE_VAR:
@@ -242,7 +311,7 @@ if !ok {
 return nil, false, m.runtimeErr("DEFINE_TYPED: expected type name string on stack")
 }
 if err := m.env().defineTypedVar(name, typeName, val, false); err != nil {
-return nil, false, m.runtimeErr(err.Error())
+return nil, false, m.raiseErr(err)
 }
 
 case OP_DEFINE_TYPED_CONST:
@@ -253,7 +322,7 @@ if !ok {
 return nil, false, m.runtimeErr("DEFINE_TYPED_CONST: expected type name string on stack")
 }
 if err := m.env().defineTypedVar(name, typeName, val, true); err != nil {
-return nil, false, m.runtimeErr(err.Error())
+return nil, false, m.raiseErr(err)
 }
 
 case OP_TOGGLE_VAR:
@@ -273,14 +342,70 @@ return nil, false, m.runtimeErr(err.Error())
 case OP_BINARY_OP:
 right := m.pop()
 left := m.pop()
-res, err := doBinaryOp(BinOp(operand), left, right)
+res, err := m.binaryOp(BinOp(operand), left, right)
 if err != nil {
-return nil, false, m.runtimeErr(err.Error())
+return nil, false, err
 }
 m.push(res)
 
+case OP_ASSERT:
+if m.noAssertions {
+m.cur.ip = int(operand)
+}
+
+case OP_ASSERT_CHECK:
+check := &types.Assertion{Line: m.cur.line}
+var holds interface{}
+if kind := operand >> 24; kind == 0 {
+holds = m.pop()
+} else {
+right, rightText := m.pop(), m.pop()
+left, leftText := m.pop(), m.pop()
+res, err := m.binaryOp(BinOp(kind-1), left, right)
+if err != nil {
+return nil, false, err
+}
+holds = res
+check.Operands = []types.Operand{
+{Text: fmt.Sprint(leftText), Value: ivmToString(left)},
+{Text: fmt.Sprint(rightText), Value: ivmToString(right)},
+}
+}
+check.Condition = fmt.Sprint(m.pop())
+ok, err := ivmToBool(holds)
+if err != nil {
+return nil, false, m.runtimeErr(err.Error())
+}
+if ok {
+m.cur.ip = int(operand & 0xFFFFFF)
+break
+}
+m.push(check)
+
+case OP_ASSERT_FAILED:
+msg := m.pop()
+check := m.pop().(*types.Assertion)
+text := ""
+if msg != nil {
+text = ivmToString(msg)
+}
+return nil, false, types.NewAssertionError(text, check)
+
 case OP_UNARY_OP:
 val := m.pop()
+if UnaryOp(operand) == UnaryNeg {
+if res, handled, err := types.IntegerNegate(val, m.wrapIntegers); handled {
+if err != nil {
+return nil, false, err
+}
+m.push(res)
+break
+}
+if res, handled := types.ExactNegate(val); handled {
+m.push(res)
+break
+}
+}
 res, err := doUnaryOp(UnaryOp(operand), val)
 if err != nil {
 return nil, false, m.runtimeErr(err.Error())
@@ -288,8 +413,30 @@ return nil, false, m.runtimeErr(err.Error())
 m.push(res)
 
 case OP_JUMP:
+// A backward jump ends a loop iteration: a statement boundary where
+// another task may take a turn.
+if m.sched != nil && int(operand) < m.cur.ip {
+m.sched.Checkpoint()
+}
 m.cur.ip = int(operand)
 
+case OP_JUMP_TABLE:
+if target, ok := chunk.JumpTables[operand].Lookup(m.pop()); ok {
+m.cur.ip = int(target)
+}
+
+case OP_IS_TYPE:
+m.push(m.env().IsOfType(m.pop(), chunk.Names[operand]))
+
+case OP_IN_RANGE:
+high := m.pop()
+low := m.pop()
+ok, err := ivmInRange(m.pop(), low, high)
+if err != nil {
+return nil, false, m.runtimeErr(err.Error())
+}
+m.push(ok)
+
 case OP_JUMP_IF_FALSE:
 val := m.pop()
 b, err := ivmToBool(val)
@@ -318,32 +465,24 @@ m.popEnv()
 
 case OP_DEFINE_FUNC:
 fc := chunk.Funcs[operand]
-m.env().defineFunc(fc.Name, fc)
+m.env().defineFunc(fc.Name, fc.withEnv(m.env()))
 
 case OP_CALL:
-argc := int(operand >> 16)
 nameIdx := operand & 0xFFFF
 name := chunk.Names[nameIdx]
-args := make([]interface{}, argc)
-for i := argc - 1; i >= 0; i-- {
-args[i] = m.pop()
-}
-res, err := m.callFunction(name, args, chunk)
+args, named := m.popArguments(operand)
+res, err := m.callFunction(name, args, named, chunk)
 if err != nil {
 return nil, false, err
 }
 m.push(res)
 
 case OP_CALL_METHOD:
-argc := int(operand >> 16)
 methodNameIdx := operand & 0xFFFF
 methodName := chunk.Names[methodNameIdx]
-args := make([]interface{}, argc)
-for i := argc - 1; i >= 0; i-- {
-args[i] = m.pop()
-}
+args, named := m.popArguments(operand)
 obj := m.pop()
-res, err := m.callMethod(obj, methodName, args, chunk)
+res, err := m.callMethod(obj, methodName, args, named, chunk)
 if err != nil {
 return nil, false, err
 }
@@ -357,6 +496,76 @@ retVal = m.pop()
 // Always signal stop; each loop (execute/callFuncChunk) handles frame restoration
 return retVal, true, nil
 
+case OP_YIELD:
+// Stop like a return; runFrame sees m.yielding and leaves the frame suspended.
+m.yielding = true
+return m.pop(), true, nil
+
+case OP_SPAWN:
+m.push(m.spawn(chunk.Funcs[operand]))
+
+case OP_WAIT:
+value := m.pop()
+task, ok := value.(*types.Task)
+if !ok {
+return nil, false, m.runtimeErr(fmt.Sprintf("TypeError: 'Wait for' needs a task started in the background; got %s", ivmGetTypeName(value)))
+}
+if err := m.scheduler().Wait(m.task, task); err != nil {
+return nil, false, err
+}
+
+case OP_NEW_CHANNEL:
+m.push(&types.Channel{})
+
+case OP_SEND:
+ch, err := m.popChannel("Send")
+if err != nil {
+return nil, false, err
+}
+if err := m.scheduler().Send(m.task, ch, m.pop()); err != nil {
+return nil, false, err
+}
+
+case OP_RECEIVE:
+ch, err := m.popChannel("Receive")
+if err != nil {
+return nil, false, err
+}
+value, err := m.scheduler().Receive(m.task, ch)
+if err != nil {
+return nil, false, err
+}
+m.push(value)
+
+case OP_ITER:
+value := m.pop()
+it, ok := types.Iterate(value)
+if !ok {
+return nil, false, m.runtimeErr(fmt.Sprintf("'for each' requires list, array, or lookup table; got %s", ivmGetTypeName(value)))
+}
+m.push(it)
+
+case OP_ITER_NEXT:
+var item interface{}
+var ok bool
+var err error
+switch it := m.pop().(type) {
+case *Generator:
+item, ok, err = m.resume(it)
+case types.Iterator:
+item, ok, err = it.Next()
+default:
+return nil, false, m.runtimeErr("'for each' lost track of what it was walking")
+}
+if err != nil {
+return nil, false, err
+}
+if !ok {
+m.cur.ip = int(operand)
+} else {
+m.push(item)
+}
+
 case OP_PRINT:
 newline := operand & 1
 count := int(operand >> 1)
@@ -379,6 +588,14 @@ elems[i] = m.pop()
 }
 m.push(elems)
 
+case OP_BUILD_STRING:
+count := int(operand)
+parts := make([]string, count)
+for i := count - 1; i >= 0; i-- {
+parts[i] = ivmToString(m.pop())
+}
+m.push(strings.Join(parts, ""))
+
 case OP_BUILD_RANGE:
 	hasCustomStep := operand == 1
 	var stepVal interface{}
@@ -444,6 +661,9 @@ container, ok := m.env().getVar(name)
 if !ok {
 return nil, false, m.runtimeErr(fmt.Sprintf("undefined variable '%s'", name))
 }
+if err := types.CheckElement(name, m.env().varType(name), val, m.env()); err != nil {
+return nil, false, m.raiseErr(err)
+}
 if err := doIndexSet(container, index, val); err != nil {
 return nil, false, m.runtimeErr(err.Error())
 }
@@ -481,6 +701,13 @@ k, err := types.SerializeKey(key)
 if err != nil {
 return nil, false, m.runtimeErr(err.Error())
 }
+typeName := m.env().varType(name)
+if err := types.CheckKey(name, typeName, key, m.env()); err != nil {
+return nil, false, m.raiseErr(err)
+}
+if err := types.CheckElement(name, typeName, val, m.env()); err != nil {
+return nil, false, m.raiseErr(err)
+}
 if _, exists := lt.Entries[k]; !exists {
 lt.KeyOrder = append(lt.KeyOrder, k)
 }
@@ -531,6 +758,10 @@ m.push(val == nil)
 case OP_ERROR_TYPE_CHECK:
 typeName := chunk.Names[operand]
 val := m.pop()
+if si, ok := val.(*StructInstance); ok {
+m.push(m.env().IsOfType(si, typeName))
+break
+}
 ev, ok := val.(*types.ErrorValue)
 if !ok {
 m.push(false)
@@ -560,6 +791,28 @@ m.push(fmt.Sprintf("%p", &val))
 
 case OP_DEFINE_STRUCT:
 sd := chunk.StructDefs[operand]
+if sd.Parent != "" {
+parent, ok := m.env().getStructDef(sd.Parent)
+if !ok {
+return nil, false, m.runtimeErr(fmt.Sprintf("undefined struct '%s'", sd.Parent))
+}
+sd = sd.inherit(parent)
+}
+// A structure that says it "can" something must have every function the
+// capability requires, whether declared here or inherited.
+for _, name := range sd.Capabilities {
+val, _ := m.env().getVar(name)
+capability, ok := val.(*types.Capability)
+if !ok {
+return nil, false, m.runtimeErr(fmt.Sprintf("undefined capability '%s'", name))
+}
+missing := capability.Missing(func(method string) bool {
+return indexOfMethod(sd.Methods, method) >= 0
+})
+if missing != "" {
+return nil, false, m.runtimeErr(fmt.Sprintf("%s says it can %s, but it has no function '%s'", sd.Name, name, missing))
+}
+}
 m.env().defineStructDef(sd.Name, sd)
 
 case OP_NEW_STRUCT:
@@ -571,10 +824,15 @@ if !ok {
 return nil, false, m.runtimeErr(fmt.Sprintf("undefined struct '%s'", structName))
 }
 
-// Pop field values in reverse order (last field pushed = top of stack)
-fieldVals := make([]interface{}, fieldCount)
-for i := fieldCount - 1; i >= 0; i-- {
-fieldVals[i] = m.pop()
+// Pop the name/value pairs pushed by compileStructInstantiation.
+given := make(map[string]interface{}, fieldCount)
+for i := 0; i < fieldCount; i++ {
+val := m.pop()
+name, _ := m.pop().(string)
+if sd.field(name) == nil {
+return nil, false, m.runtimeErr(fmt.Sprintf("struct '%s' has no field '%s'", structName, name))
+}
+given[name] = val
 }
 
 inst := &StructInstance{
@@ -583,36 +841,25 @@ DefRef:  sd,
 Fields:  make(map[string]interface{}),
 }
 
-// Assign fields in FieldOrder from StructInstantiation (same order as compiled)
-// The fieldCount fields were compiled from FieldOrder, so we use sd.Fields for defaults
-// and the compiled values for specified ones.
-// Since we compile in FieldOrder (from StructInstantiation), we need to map them back.
-// However, the compiler pushes them in the FieldOrder from the instantiation,
-// not necessarily the struct definition order.
-// We store them positionally, so we need the same order.
-// For simplicity: if fieldCount > 0, use fieldVals as-is in the order they were compiled.
-// The struct definition's field order is in sd.Fields (slice).
-// The instantiation's field order is what was compiled. We don't have that info here.
-// Solution: compile ALL struct fields in struct definition order (see compileStructInstantiation).
-
-// We reconstruct by matching against sd.Fields order
-for i, fd := range sd.Fields {
-var fval interface{}
-if i < fieldCount {
-fval = fieldVals[i]
-}
-if fval == nil && fd.DefaultExprChunk != nil {
-// Execute default expression chunk
+// Fill every field in declaration order: the given value if there is one,
+// otherwise the declared default, otherwise the zero value of its type.
+for _, fd := range sd.Fields {
+fval, ok := given[fd.Name]
+if !ok && fd.DefaultExprChunk != nil {
 var defErr error
 fval, defErr = m.executeDefaultChunk(fd.DefaultExprChunk)
 if defErr != nil {
 return nil, false, m.runtimeErr(defErr.Error())
 }
+ok = true
 }
-if fval == nil {
-// Use type default
+if !ok {
 fval = typeDefault(fd.TypeName)
 }
+fval, err := checkField(fd, fval)
+if err != nil {
+return nil, false, err
+}
 inst.Fields[fd.Name] = fval
 }
 m.push(inst)
@@ -620,6 +867,22 @@ m.push(inst)
 case OP_GET_FIELD:
 fieldName := chunk.Names[operand]
 obj := m.pop()
+if mod, ok := obj.(*types.Module); ok {
+member, err := m.moduleMember(mod, fieldName, nil, nil)
+if err != nil {
+return nil, false, err
+}
+m.push(member)
+break
+}
+if et, ok := obj.(*types.EnumType); ok {
+member, err := et.Get(fieldName)
+if err != nil {
+return nil, false, m.runtimeErr(err.Error())
+}
+m.push(member)
+break
+}
 si, ok := obj.(*StructInstance)
 if !ok {
 return nil, false, m.runtimeErr(fmt.Sprintf("GET_FIELD: not a struct instance (got %T)", obj))
@@ -638,6 +901,12 @@ si, ok := obj.(*StructInstance)
 if !ok {
 return nil, false, m.runtimeErr(fmt.Sprintf("SET_FIELD: not a struct instance (got %T)", obj))
 }
+if fd := si.DefRef.field(fieldName); fd != nil {
+var err error
+if newVal, err = checkField(fd, newVal); err != nil {
+return nil, false, err
+}
+}
 si.Fields[fieldName] = newVal
 
 case OP_RAISE:
@@ -666,6 +935,10 @@ m.cur.tryStack = m.cur.tryStack[:len(m.cur.tryStack)-1]
 // Jump past the catch body to the finally/end section
 m.cur.ip = int(operand)
 
+case OP_TRY_POP:
+// A break or continue is leaving operand Try bodies.
+m.cur.tryStack = m.cur.tryStack[:len(m.cur.tryStack)-int(operand)]
+
 case OP_TRY_SET_ERRORTYPE:
 // Set the error-type filter on the top try frame.
 // operand = nameIdx+1 (0 means catch-all / no filter).
@@ -763,6 +1036,10 @@ hasItems := flags&1 != 0
 isSafe := flags&2 != 0
 importAll := flags&4 != 0
 
+var alias string
+if flags&8 != 0 {
+alias, _ = m.pop().(string)
+}
 var items []interface{}
 if hasItems {
 itemsVal := m.pop()
@@ -774,7 +1051,12 @@ return nil, false, m.runtimeErr("IMPORT: expected path string on stack")
 }
 
 if m.importHandler != nil {
-if err := m.importHandler(path, items, importAll, isSafe, m.env()); err != nil {
+if err := m.importHandler(m, path, items, importAll, isSafe, alias, m.cur.line, m.env()); err != nil {
+// A circular import keeps its chain of files all the way out.
+var cycle *project.ImportCycleError
+if errors.As(err, &cycle) {
+return nil, false, cycle
+}
 return nil, false, m.runtimeErr(err.Error())
 }
 }
@@ -783,11 +1065,29 @@ return nil, false, m.runtimeErr(err.Error())
 case OP_SET_LINE:
 m.cur.line = int(operand)
 
+case OP_SET_OVERFLOW_MODE:
+m.wrapIntegers = operand == 1
+
+case OP_DEFINE_ENUM:
+ed := chunk.EnumDefs[operand]
+if err := m.env().defineVar(ed.Name, types.NewEnumType(ed.Name, ed.Members), true); err != nil {
+return nil, false, m.runtimeErr(err.Error())
+}
+
+case OP_DEFINE_CAPABILITY:
+cd := chunk.CapabilityDefs[operand]
+if err := m.env().defineVar(cd.Name, &types.Capability{Name: cd.Name, Methods: cd.Methods}, true); err != nil {
+return nil, false, m.runtimeErr(err.Error())
+}
+
 case OP_POP:
 if len(m.cur.stack) > 0 {
 m.pop()
 }
 
+case OP_MAKE_FUNC:
+m.push(chunk.Funcs[operand].withEnv(m.env()))
+
 default:
 return nil, false, m.runtimeErr(fmt.Sprintf("unknown opcode: %d (%s)", op, OpName(op)))
 }
@@ -795,15 +1095,59 @@ return nil, false, m.runtimeErr(fmt.Sprintf("unknown opcode: %d (%s)", op, OpNam
 return nil, false, nil
 }
 
-func (m *Machine) callFunction(name string, args []interface{}, callerChunk *Chunk) (interface{}, error) {
+// namedArgs holds the "name as value" arguments of one call, in the order
+// they were written.
+type namedArgs struct {
+names  []string
+values []interface{}
+}
+
+// popArguments pops the arguments of an OP_CALL or OP_CALL_METHOD whose
+// operand is kwc<<24 | argc<<16 | name: argc positional values, then kwc
+// pairs of a name constant and a value. named is nil when there are none.
+func (m *Machine) popArguments(operand uint32) (args []interface{}, named *namedArgs) {
+argc := int(operand >> 16 & 0xFF)
+kwc := int(operand >> 24)
+if kwc > 0 {
+named = &namedArgs{names: make([]string, kwc), values: make([]interface{}, kwc)}
+for i := kwc - 1; i >= 0; i-- {
+named.values[i] = m.pop()
+named.names[i], _ = m.pop().(string)
+}
+}
+args = make([]interface{}, argc)
+for i := argc - 1; i >= 0; i-- {
+args[i] = m.pop()
+}
+return args, named
+}
+
+func (m *Machine) callFunction(name string, args []interface{}, named *namedArgs, callerChunk *Chunk) (interface{}, error) {
 // Look up user-defined function
-fn, ok := m.env().getFunc(name)
+fn, ok := m.env().getCallable(name)
 if ok {
-return m.callFuncChunk(fn, args, nil)
+return m.callFuncChunk(fn, args, named, nil)
 }
 // Fall back to builtin
 if m.builtin != nil {
-res, err := m.builtin(name, args)
+if named != nil {
+return nil, m.runtimeErr(types.NoNamedArguments(name).Error())
+}
+// Function arguments become callbacks so higher-order built-ins such as
+// transform can run them on this machine.
+for i, arg := range args {
+if fc, ok := arg.(*FuncChunk); ok {
+args[i] = m.callbackFor(fc)
+}
+}
+var res interface{}
+var err error
+if name == "sleep" && m.sched != nil {
+// Let the other tasks run while this one sleeps.
+m.sched.Release(func() { res, err = m.builtin(name, args) })
+} else {
+res, err = m.builtin(name, args)
+}
 if err != nil {
 // stdlib.Eval returns "unknown built-in function: X" for names it
 // doesn't recognise. That message is misleading when the function
@@ -820,6 +1164,17 @@ return res, nil
 return nil, m.runtimeErr(fmt.Sprintf("undefined function '%s'", name))
 }
 
+// callbackFor wraps fn so the standard library can call it.
+func (m *Machine) callbackFor(fn *FuncChunk) *types.Callback {
+return &types.Callback{
+Name: fn.Name,
+Fn:   fn,
+Call: func(args []interface{}) (interface{}, error) {
+return m.callFuncChunk(fn, args, nil, nil)
+},
+}
+}
+
 // errCaughtByParent is a sentinel returned by callFuncChunk when an error
 // escapes the function and is caught by a try block in a *parent* frame.
 // handleError has already rewound the frame stack and set m.cur to the parent
@@ -830,17 +1185,32 @@ type errCaughtByParent struct{}
 
 func (errCaughtByParent) Error() string { return "caught by parent frame" }
 
-func (m *Machine) callFuncChunk(fn *FuncChunk, args []interface{}, selfEnv *ivmEnv) (interface{}, error) {
-if len(args) != len(fn.Params) {
+func (m *Machine) callFuncChunk(fn *FuncChunk, args []interface{}, named *namedArgs, selfEnv *ivmEnv) (interface{}, error) {
+if fn.Defaults != nil || fn.Variadic || named != nil {
+var err error
+if args, err = m.bindArguments(fn, args, named); err != nil {
+return nil, err
+}
+} else if len(args) != len(fn.Params) {
 return nil, m.runtimeErr(fmt.Sprintf("function '%s' expects %d argument(s), got %d", fn.Name, len(fn.Params), len(args)))
 }
+if err := checkParamCapabilities(fn, args); err != nil {
+return nil, err
+}
+if err := m.checkParamTypes(fn, args); err != nil {
+return nil, err
+}
 
-// Create a new environment for the function call
+// Create a new environment for the function call. Methods run in their
+// instance scope; functions run in the scope they were defined in, so
+// nested functions keep seeing the enclosing call's variables.
 var parentEnv *ivmEnv
-if selfEnv != nil {
+switch {
+case selfEnv != nil:
 parentEnv = selfEnv
-} else {
-// Use the current env's root as the closure env (simple lexical scoping)
+case fn.env != nil:
+parentEnv = fn.env
+default:
 parentEnv = m.env()
 }
 funcEnv := parentEnv.newChild()
@@ -848,10 +1218,6 @@ for i, param := range fn.Params {
 funcEnv.defineVar(param, args[i], false)
 }
 
-// Push current frame, start new frame.
-// Remember which frame belongs to this function so we can detect if
-// handleError() has swapped m.cur to a parent frame.
-m.frames = append(m.frames, m.cur)
 funcFrame := &callFrame{
 chunk: fn.Body,
 ip:    0,
@@ -859,22 +1225,43 @@ stack: []interface{}{},
 env:   funcEnv,
 name:  fn.Name,
 }
+// A generator's body does not run until something asks for its first value.
+if fn.Generator {
+return &Generator{Name: fn.Name, frame: funcFrame, m: m}, nil
+}
+
+// Push current frame, start new frame.
+m.frames = append(m.frames, m.cur)
 m.cur = funcFrame
+result, _, err := m.runFrame(funcFrame)
+if err != nil {
+return nil, err
+}
+if err := m.checkReturnType(fn, result); err != nil {
+return nil, err
+}
+return result, nil
+}
 
-// Run until RETURN or end of code
+// runFrame runs funcFrame, which the caller has just made m.cur, until it
+// returns, gives back a value or runs off the end of its code, then restores
+// the caller's frame. yielded reports whether the frame stopped at a "Give
+// back" and can be resumed.
+func (m *Machine) runFrame(funcFrame *callFrame) (result interface{}, yielded bool, err error) {
+// Run until RETURN, YIELD or end of code
 for {
 frame := m.cur
 if frame != funcFrame {
 // m.cur was changed to a parent frame by handleError (catch handler
 // found in a parent frame).  Return the sentinel; execute() will
 // continue at the catch handler.
-return nil, errCaughtByParent{}
+return nil, false, errCaughtByParent{}
 }
 if frame.ip >= len(frame.chunk.Code) {
 // Implicit nil return at end of function; restore caller frame.
 m.cur = m.frames[len(m.frames)-1]
 m.frames = m.frames[:len(m.frames)-1]
-return nil, nil
+return nil, false, nil
 }
 
 instr := frame.chunk.Code[frame.ip]
@@ -882,13 +1269,17 @@ frame.ip++
 
 result, stop, err := m.step(instr, frame.chunk)
 if err != nil {
-// Propagate the sentinel without calling handleError again.
+// Propagate the sentinel without calling handleError again, unless
+// the catch handler it found is in this very frame.
 if _, ok := err.(errCaughtByParent); ok {
-return nil, err
+if m.cur == funcFrame {
+continue
+}
+return nil, false, err
 }
 caught, jumpErr := m.handleError(err)
 if jumpErr != nil {
-return nil, jumpErr
+return nil, false, jumpErr
 }
 if caught {
 // If the handler is in a parent frame, m.cur has been updated.
@@ -897,18 +1288,125 @@ continue
 }
 // Error was not caught anywhere.  handleError has already unwound
 // m.cur and m.frames; do NOT touch them again.
-return nil, err
+return nil, false, err
 }
 if stop {
-// OP_RETURN: restore caller frame, return the value.
+// OP_RETURN or OP_YIELD: restore caller frame, return the value.
+yielded = m.yielding
+m.yielding = false
 m.cur = m.frames[len(m.frames)-1]
 m.frames = m.frames[:len(m.frames)-1]
-return result, nil
+return result, yielded, nil
+}
+}
+}
+
+// resume runs g's suspended frame on top of the current one until it gives
+// back its next value. ok is false once the body has returned or failed.
+func (m *Machine) resume(g *Generator) (item interface{}, ok bool, err error) {
+if g.done {
+return nil, false, nil
+}
+m.frames = append(m.frames, m.cur)
+m.cur = g.frame
+item, yielded, err := m.runFrame(g.frame)
+if err != nil || !yielded {
+g.done = true
+return nil, false, err
 }
+return item, true, nil
 }
+
+// bindArguments lines a call's arguments up with fn's parameters when the
+// call names some of them or fn has defaults or a rest parameter. A
+// parameter the call leaves out gets its default, computed afresh in the
+// scope fn was defined in, where the parameters before it are visible.
+func (m *Machine) bindArguments(fn *FuncChunk, args []interface{}, named *namedArgs) ([]interface{}, error) {
+if named == nil {
+named = &namedArgs{}
+}
+hasDefault := func(i int) bool { return i < len(fn.Defaults) && fn.Defaults[i] != nil }
+slots, given, err := types.BindArguments(fn.Name, fn.Params, fn.Variadic, hasDefault, args, named.names, named.values)
+if err != nil {
+return nil, m.runtimeErr(err.Error())
+}
+env := fn.env
+if env == nil {
+env = m.env()
+}
+scope := env.newChild()
+for i := range slots {
+if !given[i] {
+if slots[i], err = m.executeDefaultChunkIn(fn.Defaults[i], scope); err != nil {
+return nil, err
+}
+}
+scope.defineVar(fn.Params[i], slots[i], false)
+}
+return slots, nil
+}
+
+// checkParamCapabilities returns a TypeError when an argument passed for a
+// parameter declared "as anything that can X" is not an instance of a
+// structure that declared X.
+func checkParamCapabilities(fn *FuncChunk, args []interface{}) error {
+for i, capability := range fn.ParamCapabilities {
+if capability == "" || i >= len(args) {
+continue
+}
+got := types.Name(types.Infer(args[i]))
+if si, ok := args[i].(*StructInstance); ok {
+if si.DefRef != nil && si.DefRef.can(capability) {
+continue
+}
+got = si.DefName
+}
+return types.CapabilityMismatch(fn.Name, fn.Params[i], capability, got)
+}
+return nil
+}
+
+// checkParamTypes returns a TypeError when an argument is not of the type
+// its parameter was declared as ("takes width as number").
+func (m *Machine) checkParamTypes(fn *FuncChunk, args []interface{}) error {
+for i, typeName := range fn.ParamTypes {
+if typeName == "" || i >= len(args) || m.env().IsOfType(args[i], typeName) {
+continue
+}
+return types.ArgumentTypeMismatch(fn.Name, fn.Params[i], typeName, signatureTypeName(args[i]))
+}
+return nil
+}
+
+// checkReturnType returns a TypeError when a function that says what it
+// returns ("and returns a number") gives back something else.
+func (m *Machine) checkReturnType(fn *FuncChunk, result interface{}) error {
+if fn.ReturnType == "" || m.env().IsOfType(result, fn.ReturnType) {
+return nil
+}
+return types.ReturnTypeMismatch(fn.Name, fn.ReturnType, signatureTypeName(result))
+}
+
+// signatureTypeName describes a value's type for a signature TypeError:
+// instances and enum members by the name of their structure or enum.
+func signatureTypeName(v interface{}) string {
+switch val := v.(type) {
+case *StructInstance:
+return val.DefName
+case *types.EnumValue:
+return val.Type.Name
+case *types.ErrorValue:
+return val.ErrorType
+}
+return types.Name(ivmKind(v))
+}
+
+func (m *Machine) callMethod(obj interface{}, methodName string, args []interface{}, named *namedArgs, callerChunk *Chunk) (interface{}, error) {
+// "geo's area with 3" calls a member of a module; "geo's pi" reads one.
+if mod, ok := obj.(*types.Module); ok {
+return m.moduleMember(mod, methodName, args, named)
 }
 
-func (m *Machine) callMethod(obj interface{}, methodName string, args []interface{}, callerChunk *Chunk) (interface{}, error) {
 // Check if it's a struct instance
 si, ok := obj.(*StructInstance)
 if ok {
@@ -921,31 +1419,71 @@ structFieldEnv := m.env().newChild()
 for k, v := range si.Fields {
 structFieldEnv.defineVar(k, v, false)
 }
-res, err := m.callFuncChunk(method, args, structFieldEnv)
+res, err := m.callFuncChunk(method, args, named, structFieldEnv)
 if err != nil {
 return nil, err
 }
 // Update struct fields from method execution
-for k := range si.Fields {
-if val, exists := structFieldEnv.vars[k]; exists {
-si.Fields[k] = val.value
+for _, fd := range si.DefRef.Fields {
+if val, exists := structFieldEnv.vars[fd.Name]; exists {
+fval, err := checkField(fd, val.value)
+if err != nil {
+return nil, err
+}
+si.Fields[fd.Name] = fval
 }
 }
 return res, nil
 }
 }
 }
+// "p's x" with no method named x reads the field x.
+if val, exists := si.Fields[methodName]; exists && len(args) == 0 {
+return val, nil
+}
 return nil, m.runtimeErr(fmt.Sprintf("struct '%s' has no method '%s'", si.DefName, methodName))
 }
 
+// "Color's red" reads a member of an enum.
+if et, ok := obj.(*types.EnumType); ok && len(args) == 0 {
+member, err := et.Get(methodName)
+if err != nil {
+return nil, m.runtimeErr(err.Error())
+}
+return member, nil
+}
+
 // Non-struct: fall back to calling function with obj as first argument
 allArgs := append([]interface{}{obj}, args...)
-return m.callFunction(methodName, allArgs, callerChunk)
+return m.callFunction(methodName, allArgs, named, callerChunk)
+}
+
+// moduleMember reads the member called name from mod, or calls it with args
+// when it is a function.
+func (m *Machine) moduleMember(mod *types.Module, name string, args []interface{}, named *namedArgs) (interface{}, error) {
+member, err := mod.Get(name)
+if err != nil {
+return nil, m.runtimeErr(err.Error())
+}
+fn, isFunc := member.(*FuncChunk)
+if !isFunc {
+if len(args) > 0 || named != nil {
+return nil, m.runtimeErr(fmt.Sprintf("'%s' in module '%s' is not a function", name, mod.Name))
+}
+return member, nil
+}
+return m.callFuncChunk(fn, args, named, nil)
 }
 
 func (m *Machine) executeDefaultChunk(chunk *Chunk) (interface{}, error) {
+return m.executeDefaultChunkIn(chunk, m.env())
+}
+
+// executeDefaultChunkIn runs a default-value mini-chunk in a fresh child of
+// parent.
+func (m *Machine) executeDefaultChunkIn(chunk *Chunk, parent *ivmEnv) (interface{}, error) {
 subMachine := &Machine{builtin: m.builtin}
-env := m.env().newChild()
+env := parent.newChild()
 subMachine.cur = &callFrame{
 chunk: chunk,
 ip:    0,
@@ -28,7 +28,7 @@ const (
 
 	// ── Scope ─────────────────────────────────────────────────────────────
 	OP_PUSH_SCOPE // push a new child environment
-	OP_POP_SCOPE  // restore parent environment
+	OP_POP_SCOPE  // restore parent environment; operand 1 marks a break or continue leaving the scope early
 
 	// ── Functions ─────────────────────────────────────────────────────────
 	OP_DEFINE_FUNC  // define function; operand = func chunk index in chunk.Funcs
@@ -57,7 +57,7 @@ const (
 	OP_TYPEOF          // pop value; push type name string
 	OP_CAST            // operand = type name index; pop value; push cast(value, type)
 	OP_NIL_CHECK       // operand: 1=is_something, 0=is_nothing; pop value; push bool
-	OP_ERROR_TYPE_CHECK // operand = type name index; pop value; push bool
+	OP_ERROR_TYPE_CHECK // operand = type name index; pop error or struct instance; push bool
 
 	// ── Input ─────────────────────────────────────────────────────────────
 	OP_ASK // operand: 1=has_prompt, 0=no_prompt; [pop prompt;] push input line
@@ -93,6 +93,10 @@ const (
 	// re-propagated after the finally block finishes.
 	OP_RERAISE_PENDING
 
+	// OP_TRY_POP drops the top operand try frames without running their
+	// handlers. Emitted before a break or continue that jumps out of Try bodies.
+	OP_TRY_POP
+
 	// ── Error type declaration ────────────────────────────────────────────
 	OP_DEFINE_ERROR_TYPE // operand = name_idx<<16 | parent_name_idx (0 = no parent)
 
@@ -104,13 +108,53 @@ const (
 	OP_SWAP_VARS // operand = name1_idx<<16 | name2_idx
 
 	// ── Import ────────────────────────────────────────────────────────────
-	OP_IMPORT // operand = flags (importAll<<2 | isSafe<<1 | hasItems); top of stack = path string
+	OP_IMPORT // operand = flags (hasAlias<<3 | importAll<<2 | isSafe<<1 | hasItems); stack = path [, items] [, alias]
 
 	// ── Line tracking ─────────────────────────────────────────────────────
 	OP_SET_LINE // operand = line number
 
 	// ── Stack management ──────────────────────────────────────────────────
 	OP_POP // discard top of stack
+
+	// ── Function values ───────────────────────────────────────────────────
+	OP_MAKE_FUNC // operand = func chunk index in chunk.Funcs; push the function as a value
+
+	// ── Text ──────────────────────────────────────────────────────────────
+	OP_BUILD_STRING // operand = part count; pop N values; push their text joined together
+
+	// ── Pattern matching ──────────────────────────────────────────────────
+	OP_JUMP_TABLE // operand = jump table index in chunk.JumpTables; pop subject; jump to its case or fall through
+	OP_IS_TYPE    // operand = type name index; pop value; push whether it is of that type
+	OP_IN_RANGE   // pop high, pop low, pop value; push low <= value <= high (false for non-numbers)
+
+	// ── Integer overflow ──────────────────────────────────────────────────
+	OP_SET_OVERFLOW_MODE // operand = 1 to wrap integer overflow, 0 to raise OverflowError
+
+	// ── Enumerations ──────────────────────────────────────────────────────
+	OP_DEFINE_ENUM // operand = enum def index in chunk.EnumDefs; define the enum as a constant
+
+	// ── Capabilities ──────────────────────────────────────────────────────
+	OP_DEFINE_CAPABILITY // operand = capability def index in chunk.CapabilityDefs; define it as a constant
+
+	// ── Destructuring ─────────────────────────────────────────────────────
+	OP_UNPACK // operand = count | unpackRest when the last name takes the leftovers; pop count name strings, then a list or struct; push one value per name, the first on top
+
+	// ── Iteration ─────────────────────────────────────────────────────────
+	OP_ITER      // pop value; push an iterator over it (see types.Iterate)
+	OP_ITER_NEXT // operand = jump target; pop iterator; push its next item, or jump to operand when it has none
+	OP_YIELD     // pop value; suspend the generator's frame and hand the value to the loop consuming it
+
+	// ── Background tasks and channels ─────────────────────────────────────
+	OP_SPAWN       // operand = func index in chunk.Funcs; start its body as a background task; push the task
+	OP_WAIT        // pop task; wait until it finishes and raise the error that ended it, if any
+	OP_NEW_CHANNEL // push a new channel
+	OP_SEND        // pop channel, pop value; wait until another task receives the value
+	OP_RECEIVE     // pop channel; wait for the next value sent through it and push it
+
+	// ── Assertions ("Make sure that ...") ─────────────────────────────────
+	OP_ASSERT        // operand = jump target; skip the assertion when assertions are off
+	OP_ASSERT_CHECK  // operand = (BinOp+1)<<24 for a comparison, 0 otherwise | jump target; see compileAssert
+	OP_ASSERT_FAILED // pop message (nothing for none), pop the failed check; raise AssertionError
 )
 
 // BinOp encodes a binary operator.
@@ -128,6 +172,13 @@ const (
 	BinLte
 	BinGt
 	BinGte
+	BinPow      // to the power of
+	BinFloorDiv // divided evenly by
+	BinBitAnd
+	BinBitOr
+	BinBitXor
+	BinShl
+	BinShr
 )
 
 // UnaryOp encodes a unary operator.
@@ -237,6 +288,8 @@ func OpName(op Opcode) string {
 		return "TRY_SET_FINALLY"
 	case OP_RERAISE_PENDING:
 		return "RERAISE_PENDING"
+	case OP_TRY_POP:
+		return "TRY_POP"
 	case OP_DEFINE_ERROR_TYPE:
 		return "DEFINE_ERROR_TYPE"
 	case OP_MAKE_REFERENCE:
@@ -251,6 +304,46 @@ func OpName(op Opcode) string {
 		return "SET_LINE"
 	case OP_POP:
 		return "POP"
+	case OP_MAKE_FUNC:
+		return "MAKE_FUNC"
+	case OP_BUILD_STRING:
+		return "BUILD_STRING"
+	case OP_JUMP_TABLE:
+		return "JUMP_TABLE"
+	case OP_IS_TYPE:
+		return "IS_TYPE"
+	case OP_IN_RANGE:
+		return "IN_RANGE"
+	case OP_SET_OVERFLOW_MODE:
+		return "SET_OVERFLOW_MODE"
+	case OP_DEFINE_ENUM:
+		return "DEFINE_ENUM"
+	case OP_DEFINE_CAPABILITY:
+		return "DEFINE_CAPABILITY"
+	case OP_UNPACK:
+		return "UNPACK"
+	case OP_ITER:
+		return "ITER"
+	case OP_ITER_NEXT:
+		return "ITER_NEXT"
+	case OP_YIELD:
+		return "YIELD"
+	case OP_SPAWN:
+		return "SPAWN"
+	case OP_WAIT:
+		return "WAIT"
+	case OP_NEW_CHANNEL:
+		return "NEW_CHANNEL"
+	case OP_SEND:
+		return "SEND"
+	case OP_RECEIVE:
+		return "RECEIVE"
+	case OP_ASSERT:
+		return "ASSERT"
+	case OP_ASSERT_CHECK:
+		return "ASSERT_CHECK"
+	case OP_ASSERT_FAILED:
+		return "ASSERT_FAILED"
 	default:
 		return "UNKNOWN"
 	}
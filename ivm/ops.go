@@ -4,6 +4,7 @@ import (
 	"github.com/Advik-B/english/astvm/types"
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 )
@@ -60,10 +61,71 @@ func doBinaryOp(op BinOp, left, right interface{}) (interface{}, error) {
 		return ivmOrderCompare(left, right, func(a, b float64) bool { return a > b })
 	case BinGte:
 		return ivmOrderCompare(left, right, func(a, b float64) bool { return a >= b })
+	case BinPow:
+		return requireNumberBinary(left, right, "to the power of", func(a, b float64) interface{} { return math.Pow(a, b) })
+	case BinFloorDiv:
+		l, err := ivmToFloat(left, "divided evenly by")
+		if err != nil {
+			return nil, err
+		}
+		r, err := ivmToFloat(right, "divided evenly by")
+		if err != nil {
+			return nil, err
+		}
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Floor(l / r), nil
+	case BinBitAnd, BinBitOr, BinBitXor, BinShl, BinShr:
+		return ivmBitwise(op, left, right)
 	}
 	return nil, fmt.Errorf("unknown binary op: %d", op)
 }
 
+// ivmBitwise applies a bitwise operator to two whole numbers.
+func ivmBitwise(op BinOp, left, right interface{}) (interface{}, error) {
+	name := bitwiseOpNames[op]
+	l, err := ivmToWhole(left, name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := ivmToWhole(right, name)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case BinBitAnd:
+		return float64(l & r), nil
+	case BinBitOr:
+		return float64(l | r), nil
+	case BinBitXor:
+		return float64(l ^ r), nil
+	}
+	if r < 0 {
+		return nil, fmt.Errorf("ValueError: cannot shift by a negative amount (%d)", r)
+	}
+	if op == BinShl {
+		if r >= 63 || (l<<uint64(r))>>uint64(r) != l {
+			return nil, &types.ErrorValue{
+				ErrorType: "OverflowError",
+				Message:   fmt.Sprintf("%d shifted left by %d is too large for a number; shift a whole number instead", l, r),
+			}
+		}
+		return float64(l << uint64(r)), nil
+	}
+	return float64(l >> uint64(r)), nil
+}
+
+// bitwiseOpNames gives the English phrase for each bitwise BinOp, for use in
+// error messages.
+var bitwiseOpNames = map[BinOp]string{
+	BinBitAnd: "bitwise and",
+	BinBitOr:  "bitwise or",
+	BinBitXor: "bitwise xor",
+	BinShl:    "shifted left by",
+	BinShr:    "shifted right by",
+}
+
 func doUnaryOp(op UnaryOp, val interface{}) (interface{}, error) {
 	switch op {
 	case UnaryNeg:
@@ -83,6 +145,13 @@ func doUnaryOp(op UnaryOp, val interface{}) (interface{}, error) {
 }
 
 func ivmAdd(left, right interface{}) (interface{}, error) {
+	if types.IsNumeric(types.Infer(left)) && types.IsNumeric(types.Infer(right)) {
+		// Any mix of number kinds; integer-only sums never get here because
+		// types.IntegerArithmetic handles them first.
+		l, _ := ivmToFloat(left, "+")
+		r, _ := ivmToFloat(right, "+")
+		return l + r, nil
+	}
 	switch l := left.(type) {
 	case float64:
 		r, ok := right.(float64)
@@ -139,11 +208,28 @@ func ivmToFloat(v interface{}, op string) (float64, error) {
 		return float64(val), nil
 	case float32:
 		return float64(val), nil
+	case *types.Decimal:
+		return val.Float64(), nil
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(val).Float64()
+		return f, nil
 	default:
 		return 0, fmt.Errorf("TypeError: '%s' requires number, got %s", op, ivmGetTypeName(v))
 	}
 }
 
+// ivmToWhole converts a numeric value with no fractional part to int64.
+func ivmToWhole(v interface{}, op string) (int64, error) {
+	n, err := ivmToFloat(v, op)
+	if err != nil {
+		return 0, err
+	}
+	if n != math.Trunc(n) || math.IsInf(n, 0) {
+		return 0, fmt.Errorf("TypeError: '%s' requires whole numbers, got %v", op, n)
+	}
+	return int64(n), nil
+}
+
 func ivmToBool(v interface{}) (bool, error) {
 	switch val := v.(type) {
 	case bool:
@@ -162,6 +248,9 @@ func ivmStrictEquals(left, right interface{}) (bool, error) {
 	if left == nil || right == nil {
 		return false, nil
 	}
+	if eq, ok := types.NumbersEqual(left, right); ok {
+		return eq, nil
+	}
 	lk := types.Canonical(types.Infer(left))
 	rk := types.Canonical(types.Infer(right))
 	if lk != rk {
@@ -180,11 +269,53 @@ func ivmStrictEquals(left, right interface{}) (bool, error) {
 		if r, ok := right.(bool); ok {
 			return l == r, nil
 		}
+	case *types.EnumValue:
+		if r, ok := right.(*types.EnumValue); ok {
+			return l.Equal(r), nil
+		}
 	}
 	return false, nil
 }
 
+// ivmInRange reports whether v lies between low and high inclusive. A v that
+// is not a number is never in range; the bounds must be numbers.
+func ivmInRange(v, low, high interface{}) (bool, error) {
+	lo, err := ivmToFloat(low, "between")
+	if err != nil {
+		return false, err
+	}
+	hi, err := ivmToFloat(high, "between")
+	if err != nil {
+		return false, err
+	}
+	n, err := ivmToFloat(v, "between")
+	if err != nil {
+		return false, nil
+	}
+	return n >= lo && n <= hi, nil
+}
+
+// ivmKind returns the TypeKind of a runtime value, including the kinds that
+// only exist in the VM (functions and struct instances).
+func ivmKind(v interface{}) types.TypeKind {
+	switch v.(type) {
+	case *FuncChunk:
+		return types.TypeFunction
+	case *StructInstance:
+		return types.TypeStruct
+	case *ReferenceValue:
+		return types.TypeRef
+	case *Generator:
+		return types.TypeGenerator
+	default:
+		return types.Infer(v)
+	}
+}
+
 func ivmOrderCompare(left, right interface{}, pred func(float64, float64) bool) (bool, error) {
+	if cmp, ok := types.CompareNumbers(left, right); ok {
+		return pred(float64(cmp), 0), nil
+	}
 	l, err := ivmToFloat(left, "comparison")
 	if err != nil {
 		return false, err
@@ -218,6 +349,17 @@ func doIndexGet(container, index interface{}) (interface{}, error) {
 			return nil, fmt.Errorf("index %d out of range for array of length %d", i, len(c.Elements))
 		}
 		return c.Elements[i], nil
+	case *types.EnumType:
+		// For-each over an enum walks its members by position.
+		idx, err := ivmToFloat(index, "index")
+		if err != nil {
+			return nil, err
+		}
+		i := int(idx)
+		if i < 0 || i >= len(c.Members) {
+			return nil, fmt.Errorf("index %d out of range for enum of length %d", i, len(c.Members))
+		}
+		return c.Members[i], nil
 	case *types.RangeValue:
 		idx, err := ivmToFloat(index, "index")
 		if err != nil {
@@ -310,6 +452,8 @@ func doLength(val interface{}) (float64, error) {
 		return float64(len([]rune(v))), nil
 	case *types.LookupTableValue:
 		return float64(len(v.KeyOrder)), nil
+	case *types.EnumType:
+		return float64(len(v.Members)), nil
 	default:
 		return 0, fmt.Errorf("cannot get length of %s", ivmGetTypeName(val))
 	}
@@ -318,7 +462,7 @@ func doLength(val interface{}) (float64, error) {
 func doLookupGet(table, key interface{}) (interface{}, error) {
 	lt, ok := table.(*types.LookupTableValue)
 	if !ok {
-		return nil, fmt.Errorf("LOOKUP_GET: not a lookup table")
+		return nil, fmt.Errorf("TypeError: cannot index %s with a key; expected lookup table", types.Name(ivmKind(table)))
 	}
 	k, err := types.SerializeKey(key)
 	if err != nil {
@@ -345,6 +489,10 @@ func ivmGetTypeName(v interface{}) string {
 		return "u64"
 	case float32:
 		return "f32"
+	case *types.Decimal:
+		return "decimal"
+	case *big.Int:
+		return "whole number"
 	case string:
 		return "text"
 	case bool:
@@ -362,10 +510,22 @@ func ivmGetTypeName(v interface{}) string {
 		return "error"
 	case *StructInstance:
 		return val.DefName
+	case *types.EnumValue:
+		return val.Type.Name
+	case *types.EnumType:
+		return "enum"
+	case *types.Module:
+		return "module"
 	case *ReferenceValue:
 		return "reference"
 	case *FuncChunk:
 		return "function"
+	case *Generator:
+		return "generator"
+	case *types.Task:
+		return "task"
+	case *types.Channel:
+		return "channel"
 	case nil:
 		return "nothing"
 	default:
@@ -394,6 +554,10 @@ func ivmToString(v interface{}) string {
 		return strconv.FormatUint(val, 10)
 	case float32:
 		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case *types.Decimal:
+		return val.String()
+	case *big.Int:
+		return val.String()
 	case string:
 		return val
 	case bool:
@@ -429,12 +593,24 @@ func ivmToString(v interface{}) string {
 		return "{" + strings.Join(parts, ", ") + "}"
 	case *StructInstance:
 		return fmt.Sprintf("<%s instance>", val.DefName)
+	case *types.EnumValue:
+		return val.Name
+	case *types.EnumType:
+		return val.String()
+	case *types.Module:
+		return val.String()
 	case *types.ErrorValue:
 		return fmt.Sprintf("<error: %s>", val.Message)
 	case *ReferenceValue:
 		return fmt.Sprintf("<ref: %s>", val.Name)
 	case *FuncChunk:
 		return fmt.Sprintf("<function %s>", val.Name)
+	case *Generator:
+		return val.String()
+	case *types.Task:
+		return val.String()
+	case *types.Channel:
+		return val.String()
 	case nil:
 		return "nothing"
 	default:
@@ -471,17 +647,42 @@ func deepCopyValue(val interface{}) interface{} {
 	}
 }
 
+// typeDefault is the value of a struct field that has neither a given value
+// nor a declared default.
 func typeDefault(typeName string) interface{} {
-	switch typeName {
-	case "number", "f64", "f32", "i32", "i64", "u32", "u64":
+	switch types.Parse(typeName) {
+	case types.TypeI32:
+		return int32(0)
+	case types.TypeI64:
+		return int64(0)
+	case types.TypeU32:
+		return uint32(0)
+	case types.TypeU64:
+		return uint64(0)
+	case types.TypeF32, types.TypeF64:
 		return float64(0)
-	case "text":
+	case types.TypeDecimal:
+		return types.DecimalFromInt(big.NewInt(0))
+	case types.TypeWhole:
+		return big.NewInt(0)
+	case types.TypeString:
 		return ""
-	case "boolean":
+	case types.TypeBool:
 		return false
-	case "list":
+	case types.TypeList:
 		return []interface{}{}
 	default:
 		return nil
 	}
 }
+
+// checkField checks a value being stored in a struct field against the
+// field's declared type. Integer fields are the only ones enforced at runtime:
+// the value must be a whole number within the field's range.
+func checkField(fd *FieldDef, v interface{}) (interface{}, error) {
+	kind := types.Parse(fd.TypeName)
+	if !types.IsInteger(kind) {
+		return v, nil
+	}
+	return types.CheckIntegerField(fd.Name, v, kind)
+}
@@ -0,0 +1,54 @@
+package ivm
+
+import (
+	"fmt"
+
+	"github.com/Advik-B/english/astvm/types"
+)
+
+// scheduler returns the scheduler running the program's background tasks,
+// making it on first use with this machine as the main program.
+func (m *Machine) scheduler() *types.Scheduler {
+	if m.sched == nil {
+		m.sched, m.task = types.NewScheduler()
+	}
+	return m.sched
+}
+
+// spawn starts fn's body as a background task on a machine of its own. The
+// body runs in a child of the current scope, so it shares the variables the
+// statement could see.
+func (m *Machine) spawn(fn *FuncChunk) *types.Task {
+	sched := m.scheduler()
+	env := m.env().newChild()
+	return sched.Start(fn.Name, func(t *types.Task) error {
+		tm := &Machine{
+			builtin:       m.builtin,
+			importHandler: m.importHandler,
+			wrapIntegers:  m.wrapIntegers,
+			noAssertions:  m.noAssertions,
+			file:          m.file,
+			sched:         sched,
+			task:          t,
+		}
+		tm.cur = &callFrame{
+			chunk: fn.Body,
+			ip:    0,
+			stack: []interface{}{},
+			env:   env,
+			name:  fn.Name,
+		}
+		_, err := tm.execute(env)
+		return err
+	})
+}
+
+// popChannel pops the channel a Send or Receive uses.
+func (m *Machine) popChannel(verb string) (*types.Channel, error) {
+	value := m.pop()
+	ch, ok := value.(*types.Channel)
+	if !ok {
+		return nil, m.runtimeErr(fmt.Sprintf("TypeError: '%s' needs a channel; got %s", verb, ivmGetTypeName(value)))
+	}
+	return ch, nil
+}
@@ -0,0 +1,51 @@
+package ivm
+
+import "github.com/Advik-B/english/ast"
+
+// Tests returns the test blocks written at the top level of prog, in the
+// order they appear.
+func Tests(prog *ast.Program) []*ast.TestBlock {
+	var tests []*ast.TestBlock
+	for _, stmt := range prog.Statements {
+		if tb, ok := stmt.(*ast.TestBlock); ok {
+			tests = append(tests, tb)
+		}
+	}
+	return tests
+}
+
+// RunTest runs one of prog's test blocks on a machine and in an environment
+// of its own, so no test sees what another has changed. The program's
+// declarations and imports run first, so the test can use them; the rest of
+// its top-level code does not run.
+func RunTest(prog *ast.Program, test *ast.TestBlock, builtin BuiltinFunc, predefined map[string]interface{}) error {
+	return RunTestFile(prog, "", test, builtin, predefined)
+}
+
+// RunTestFile is RunTest for the program read from file, whose imports are
+// looked for from the file's directory.
+func RunTestFile(prog *ast.Program, file string, test *ast.TestBlock, builtin BuiltinFunc, predefined map[string]interface{}) error {
+	setup := &ast.Program{}
+	for _, stmt := range prog.Statements {
+		switch stmt.(type) {
+		case *ast.VariableDecl,
+			*ast.TypedVariableDecl,
+			*ast.FunctionDecl,
+			*ast.StructDecl,
+			*ast.ErrorTypeDecl,
+			*ast.EnumDecl,
+			*ast.CapabilityDecl,
+			*ast.ImportStatement,
+			*ast.OverflowModeStatement:
+			setup.Statements = append(setup.Statements, stmt)
+		}
+	}
+	setup.Statements = append(setup.Statements, test.Body...)
+
+	chunk, err := Compile(setup)
+	if err != nil {
+		return err
+	}
+	_, err = execute(chunk, file, builtin, predefined, false)
+	return err
+}
@@ -47,12 +47,16 @@ type AnalysisResult struct {
 	Diagnostics []Diagnostic
 	Functions   map[string]*FunctionInfo
 	Variables   map[string]*VariableInfo
+	Modules     map[string]*ModuleInfo // by the name given after "as"
+	Imports     []*ModuleInfo          // files imported without "as"
 }
 
 // FunctionInfo contains information about a function
 type FunctionInfo struct {
 	Name          string
 	Parameters    []string
+	Defaults      []string // per parameter, the default as written or "" if required; nil if none has one
+	Variadic      bool     // the last parameter collects any extra arguments
 	Range         Range
 	DefRange      Range
 	Body          []ast.Statement
@@ -69,7 +73,11 @@ type VariableInfo struct {
 }
 
 // Analyzer analyzes English language documents
-type Analyzer struct{}
+type Analyzer struct {
+	// importing holds the files whose imports are being analyzed on the
+	// way to this document, so a cycle of them stops.
+	importing map[string]bool
+}
 
 // NewAnalyzer creates a new analyzer
 func NewAnalyzer() *Analyzer {
@@ -84,6 +92,7 @@ func (a *Analyzer) Analyze(doc *Document) *AnalysisResult {
 		Diagnostics: make([]Diagnostic, 0),
 		Functions:   make(map[string]*FunctionInfo),
 		Variables:   make(map[string]*VariableInfo),
+		Modules:     make(map[string]*ModuleInfo),
 	}
 
 	// Tokenize
@@ -183,28 +192,36 @@ func (a *Analyzer) extractSymbols(program *ast.Program, result *AnalysisResult,
 func (a *Analyzer) extractFromStatement(stmt ast.Statement, result *AnalysisResult, doc *Document, parent *Symbol) {
 	switch s := stmt.(type) {
 	case *ast.VariableDecl:
-		sym := a.createVariableSymbol(s, doc)
-		if parent != nil {
-			parent.Children = append(parent.Children, sym)
-		} else {
-			result.Symbols = append(result.Symbols, sym)
+		// A destructuring declaration ("Declare q and r to be ...") declares
+		// every one of its names.
+		names := s.Names
+		if len(names) == 0 {
+			names = []string{s.Name}
 		}
+		for _, name := range names {
+			sym := a.createVariableSymbol(name, s, doc)
+			if parent != nil {
+				parent.Children = append(parent.Children, sym)
+			} else {
+				result.Symbols = append(result.Symbols, sym)
+			}
 
-		// Add to variables map
-		result.Variables[s.Name] = &VariableInfo{
-			Name:       s.Name,
-			IsConstant: s.IsConstant,
-			Range:      sym.Range,
-			DefRange:   sym.DefRange,
-			Value:      a.exprToString(s.Value),
-		}
+			// Add to variables map
+			result.Variables[name] = &VariableInfo{
+				Name:       name,
+				IsConstant: s.IsConstant,
+				Range:      sym.Range,
+				DefRange:   sym.DefRange,
+				Value:      a.exprToString(s.Value),
+			}
 
-		// Add reference for the definition
-		result.References = append(result.References, &Reference{
-			Name:         s.Name,
-			Range:        sym.DefRange,
-			IsDefinition: true,
-		})
+			// Add reference for the definition
+			result.References = append(result.References, &Reference{
+				Name:         name,
+				Range:        sym.DefRange,
+				IsDefinition: true,
+			})
+		}
 
 		// Extract references from value expression
 		a.extractReferencesFromExpr(s.Value, result, doc)
@@ -221,6 +238,8 @@ func (a *Analyzer) extractFromStatement(stmt ast.Statement, result *AnalysisResu
 		result.Functions[s.Name] = &FunctionInfo{
 			Name:          s.Name,
 			Parameters:    s.Parameters,
+			Defaults:      a.paramDefaults(s),
+			Variadic:      s.Variadic,
 			Range:         sym.Range,
 			DefRange:      sym.DefRange,
 			Body:          s.Body,
@@ -240,12 +259,17 @@ func (a *Analyzer) extractFromStatement(stmt ast.Statement, result *AnalysisResu
 		}
 
 	case *ast.Assignment:
-		// Add reference for the variable being assigned
-		varRange := a.findIdentifierRange(s.Name, doc)
-		result.References = append(result.References, &Reference{
-			Name:  s.Name,
-			Range: varRange,
-		})
+		// Add a reference for each variable being assigned
+		names := s.Names
+		if len(names) == 0 {
+			names = []string{s.Name}
+		}
+		for _, name := range names {
+			result.References = append(result.References, &Reference{
+				Name:  name,
+				Range: a.findIdentifierRange(name, doc),
+			})
+		}
 		// Extract references from value
 		a.extractReferencesFromExpr(s.Value, result, doc)
 
@@ -264,6 +288,26 @@ func (a *Analyzer) extractFromStatement(stmt ast.Statement, result *AnalysisResu
 			a.extractFromStatement(elseStmt, result, doc, parent)
 		}
 
+	case *ast.WhenStatement:
+		a.extractReferencesFromExpr(s.Subject, result, doc)
+		for _, wc := range s.Cases {
+			for _, pattern := range wc.Patterns {
+				switch p := pattern.(type) {
+				case *ast.ValuePattern:
+					a.extractReferencesFromExpr(p.Value, result, doc)
+				case *ast.RangePattern:
+					a.extractReferencesFromExpr(p.Low, result, doc)
+					a.extractReferencesFromExpr(p.High, result, doc)
+				}
+			}
+			for _, stmt := range wc.Body {
+				a.extractFromStatement(stmt, result, doc, parent)
+			}
+		}
+		for _, stmt := range s.Otherwise {
+			a.extractFromStatement(stmt, result, doc, parent)
+		}
+
 	case *ast.WhileLoop:
 		a.extractReferencesFromExpr(s.Condition, result, doc)
 		for _, bodyStmt := range s.Body {
@@ -287,14 +331,53 @@ func (a *Analyzer) extractFromStatement(stmt ast.Statement, result *AnalysisResu
 			a.extractReferencesFromExpr(value, result, doc)
 		}
 
+	case *ast.YieldStatement:
+		a.extractReferencesFromExpr(s.Value, result, doc)
+
+	case *ast.BackgroundStatement:
+		// The task's name is set like a variable when the task starts.
+		result.References = append(result.References, &Reference{
+			Name:  s.Name,
+			Range: a.findIdentifierRange(s.Name, doc),
+		})
+		for _, bodyStmt := range s.Body {
+			a.extractFromStatement(bodyStmt, result, doc, parent)
+		}
+
+	case *ast.TestBlock:
+		for _, bodyStmt := range s.Body {
+			a.extractFromStatement(bodyStmt, result, doc, parent)
+		}
+
+	case *ast.WaitStatement:
+		a.extractReferencesFromExpr(s.Task, result, doc)
+
+	case *ast.SendStatement:
+		a.extractReferencesFromExpr(s.Value, result, doc)
+		a.extractReferencesFromExpr(s.Channel, result, doc)
+
+	case *ast.AssertStatement:
+		a.extractReferencesFromExpr(s.Condition, result, doc)
+		a.extractReferencesFromExpr(s.Message, result, doc)
+
 	case *ast.ReturnStatement:
 		a.extractReferencesFromExpr(s.Value, result, doc)
+		for _, value := range s.Values {
+			a.extractReferencesFromExpr(value, result, doc)
+		}
 
 	case *ast.CallStatement:
 		if s.FunctionCall != nil {
 			a.extractReferencesFromExpr(s.FunctionCall, result, doc)
 		}
 
+	case *ast.ImportStatement:
+		if s.Alias != "" {
+			a.extractModule(s, result, doc)
+		} else {
+			a.extractImport(s, result, doc)
+		}
+
 	case *ast.IndexAssignment:
 		varRange := a.findIdentifierRange(s.ListName, doc)
 		result.References = append(result.References, &Reference{
@@ -353,6 +436,9 @@ func (a *Analyzer) extractReferencesFromExpr(expr ast.Expression, result *Analys
 	case *ast.LengthExpression:
 		a.extractReferencesFromExpr(e.List, result, doc)
 
+	case *ast.ReceiveExpression:
+		a.extractReferencesFromExpr(e.Channel, result, doc)
+
 	case *ast.ListLiteral:
 		for _, elem := range e.Elements {
 			a.extractReferencesFromExpr(elem, result, doc)
@@ -368,7 +454,7 @@ func (a *Analyzer) extractReferencesFromExpr(expr ast.Expression, result *Analys
 }
 
 // createVariableSymbol creates a symbol for a variable declaration
-func (a *Analyzer) createVariableSymbol(v *ast.VariableDecl, doc *Document) *Symbol {
+func (a *Analyzer) createVariableSymbol(name string, v *ast.VariableDecl, doc *Document) *Symbol {
 	symType := SymbolTypeVariable
 	detail := "variable"
 	if v.IsConstant {
@@ -377,10 +463,10 @@ func (a *Analyzer) createVariableSymbol(v *ast.VariableDecl, doc *Document) *Sym
 	}
 
 	// Find the range of the declaration in the document
-	nameRange := a.findIdentifierRange(v.Name, doc)
+	nameRange := a.findIdentifierRange(name, doc)
 
 	return &Symbol{
-		Name:     v.Name,
+		Name:     name,
 		Type:     symType,
 		Range:    nameRange, // For simple cases, use the name range
 		DefRange: nameRange,
@@ -392,7 +478,7 @@ func (a *Analyzer) createVariableSymbol(v *ast.VariableDecl, doc *Document) *Sym
 func (a *Analyzer) createFunctionSymbol(f *ast.FunctionDecl, doc *Document) *Symbol {
 	nameRange := a.findIdentifierRange(f.Name, doc)
 
-	params := strings.Join(f.Parameters, ", ")
+	params := strings.Join(paramLabels(f.Parameters, a.paramDefaults(f), f.Variadic), ", ")
 	detail := "function"
 	if len(f.Parameters) > 0 {
 		detail = "function(" + params + ")"
@@ -450,6 +536,11 @@ func (a *Analyzer) exprToString(expr ast.Expression) string {
 			return fmt.Sprintf("%d", int64(e.Value))
 		}
 		return fmt.Sprintf("%g", e.Value)
+	case *ast.DecimalLiteral:
+		if e.Whole {
+			return "the whole number " + e.Digits
+		}
+		return "the decimal " + e.Digits
 	case *ast.StringLiteral:
 		return `"` + e.Value + `"`
 	case *ast.BooleanLiteral:
@@ -470,6 +561,37 @@ func (a *Analyzer) exprToString(expr ast.Expression) string {
 	}
 }
 
+// paramDefaults renders the default value of each of f's parameters, or ""
+// for a required one. It returns nil when no parameter has a default.
+func (a *Analyzer) paramDefaults(f *ast.FunctionDecl) []string {
+	if f.Defaults == nil {
+		return nil
+	}
+	defaults := make([]string, len(f.Defaults))
+	for i, d := range f.Defaults {
+		if d != nil {
+			defaults[i] = a.exprToString(d)
+		}
+	}
+	return defaults
+}
+
+// paramLabels labels each parameter for a signature, showing its default
+// as "greeting = \"Hello\"" and a rest parameter as "any number of values".
+func paramLabels(params, defaults []string, variadic bool) []string {
+	labels := make([]string, len(params))
+	for i, p := range params {
+		labels[i] = p
+		if i < len(defaults) && defaults[i] != "" {
+			labels[i] += " = " + defaults[i]
+		}
+	}
+	if variadic && len(labels) > 0 {
+		labels[len(labels)-1] = "any number of " + labels[len(labels)-1]
+	}
+	return labels
+}
+
 // generateFunctionDoc generates documentation for a function
 func (a *Analyzer) generateFunctionDoc(f *ast.FunctionDecl) string {
 	var doc strings.Builder
@@ -479,14 +601,33 @@ func (a *Analyzer) generateFunctionDoc(f *ast.FunctionDecl) string {
 
 	if len(f.Parameters) > 0 {
 		doc.WriteString("Parameters:\n")
-		for _, param := range f.Parameters {
+		defaults := a.paramDefaults(f)
+		for i, param := range f.Parameters {
 			doc.WriteString("- `")
 			doc.WriteString(param)
-			doc.WriteString("`\n")
+			doc.WriteString("`")
+			if i < len(f.ParamTypes) && f.ParamTypes[i] != "" {
+				doc.WriteString(" as ")
+				doc.WriteString(f.ParamTypes[i])
+			}
+			if i < len(defaults) && defaults[i] != "" {
+				doc.WriteString(" (defaulting to `")
+				doc.WriteString(defaults[i])
+				doc.WriteString("`)")
+			}
+			if f.Variadic && i == len(f.Parameters)-1 {
+				doc.WriteString(" (any number of arguments, collected into a list)")
+			}
+			doc.WriteString("\n")
 		}
 	} else {
 		doc.WriteString("Takes no parameters.\n")
 	}
+	if f.ReturnType != "" {
+		doc.WriteString("\nReturns: `")
+		doc.WriteString(f.ReturnType)
+		doc.WriteString("`\n")
+	}
 
 	return doc.String()
 }
@@ -495,7 +636,13 @@ func (a *Analyzer) generateFunctionDoc(f *ast.FunctionDecl) string {
 func (a *Analyzer) GetCompletions(doc *Document, pos Position, result *AnalysisResult) []CompletionItem {
 	items := make([]CompletionItem, 0)
 
-	prefix := strings.ToLower(completionPrefixAtPosition(doc, pos))
+	rawPrefix := completionPrefixAtPosition(doc, pos)
+	prefix := strings.ToLower(rawPrefix)
+
+	// After "geo's " only the members of the module make sense
+	if mod := moduleBefore(doc.GetLine(pos.Line), pos.Character-len(rawPrefix), result); mod != nil {
+		return normalizeCompletionItems(moduleCompletions(mod, prefix))
+	}
 
 	// Add keyword completions
 	items = append(items, a.getKeywordCompletions(prefix)...)
@@ -525,7 +672,7 @@ func (a *Analyzer) GetCompletions(doc *Document, pos Position, result *AnalysisR
 			items = append(items, CompletionItem{
 				Label:  name,
 				Kind:   CompletionItemKindFunction,
-				Detail: "function(" + strings.Join(info.Parameters, ", ") + ")",
+				Detail: "function(" + strings.Join(paramLabels(info.Parameters, info.Defaults, info.Variadic), ", ") + ")",
 				Documentation: MarkupContent{
 					Kind:  MarkupKindMarkdown,
 					Value: info.Documentation,
@@ -534,6 +681,11 @@ func (a *Analyzer) GetCompletions(doc *Document, pos Position, result *AnalysisR
 		}
 	}
 
+	// Add the names imports without "as" bring in
+	for _, mod := range result.Imports {
+		items = append(items, moduleCompletions(mod, prefix)...)
+	}
+
 	return normalizeCompletionItems(items)
 }
 
@@ -599,12 +751,25 @@ func (a *Analyzer) getKeywordCompletions(prefix string) []CompletionItem {
 		{"Return", "Return from function", "Return ${1:value}."},
 		{"Break", "Break out of loop", "Break out of the loop."},
 		{"Toggle", "Toggle boolean", "Toggle ${1:variable}."},
+		{"Make sure that", "Check a condition", "Make sure that ${1:condition}, otherwise say \"${2:message}\"."},
+		{"Test", "Write a test", "Test \"${1:name}\" by doing the following:\n\t${2:statements}\nThats it."},
 		{"Declare function", "Declare a function", "Declare function ${1:name} that does the following:\n\t${2:statements}\nThats it."},
+		{"Do the following in the background", "Start a background task", "Do the following in the background and call it ${1:job}:\n\t${2:statements}\nThats it."},
+		{"Wait for", "Wait for a background task", "Wait for ${1:job}."},
+		{"Send", "Send a value to a channel", "Send ${1:value} to ${2:channel}."},
+		{"Receive", "Receive a value from a channel", "Receive from ${1:channel} into ${2:name}."},
 		{"true", "Boolean true", "true"},
 		{"false", "Boolean false", "false"},
 		{"the item at position", "Access list element", "the item at position ${1:index} in ${2:list}"},
 		{"the length of", "Get length", "the length of ${1:list}"},
 		{"the remainder of", "Modulo operation", "the remainder of ${1:a} divided by ${2:b}"},
+		{"to the power of", "Exponent", "to the power of"},
+		{"divided evenly by", "Floor division", "divided evenly by"},
+		{"bitwise and", "Bitwise AND", "bitwise and"},
+		{"bitwise or", "Bitwise OR", "bitwise or"},
+		{"bitwise xor", "Bitwise XOR", "bitwise xor"},
+		{"shifted left by", "Left shift", "shifted left by"},
+		{"shifted right by", "Right shift", "shifted right by"},
 		{"is equal to", "Equality comparison", "is equal to"},
 		{"is not equal to", "Inequality comparison", "is not equal to"},
 		{"is less than", "Less than comparison", "is less than"},
@@ -637,6 +802,29 @@ func (a *Analyzer) GetHover(doc *Document, pos Position, result *AnalysisResult)
 		return nil
 	}
 
+	// "geo's area" names a member of the module, not anything declared here
+	if mod := moduleMemberAt(doc, wordRange, result); mod != nil {
+		if info, ok := mod.Variables[word]; ok {
+			return &Hover{
+				Contents: MarkupContent{
+					Kind:  MarkupKindMarkdown,
+					Value: fmt.Sprintf("**variable** `%s` %s\n\nValue: `%s`", word, mod.origin(), info.Value),
+				},
+				Range: &wordRange,
+			}
+		}
+		if info, ok := mod.Functions[word]; ok {
+			return &Hover{
+				Contents: MarkupContent{
+					Kind:  MarkupKindMarkdown,
+					Value: info.Documentation,
+				},
+				Range: &wordRange,
+			}
+		}
+		return nil
+	}
+
 	// Check if it's a variable
 	if info, ok := result.Variables[word]; ok {
 		kind := "variable"
@@ -663,6 +851,28 @@ func (a *Analyzer) GetHover(doc *Document, pos Position, result *AnalysisResult)
 		}
 	}
 
+	// Check the names imports without "as" bring in
+	for _, mod := range result.Imports {
+		if info, ok := mod.Variables[word]; ok {
+			return &Hover{
+				Contents: MarkupContent{
+					Kind:  MarkupKindMarkdown,
+					Value: fmt.Sprintf("**variable** `%s` %s\n\nValue: `%s`", word, mod.origin(), info.Value),
+				},
+				Range: &wordRange,
+			}
+		}
+		if info, ok := mod.Functions[word]; ok {
+			return &Hover{
+				Contents: MarkupContent{
+					Kind:  MarkupKindMarkdown,
+					Value: info.Documentation,
+				},
+				Range: &wordRange,
+			}
+		}
+	}
+
 	// Check if it's a keyword
 	if doc := a.getKeywordDocumentation(word); doc != "" {
 		return &Hover{
@@ -705,11 +915,22 @@ func (a *Analyzer) getKeywordDocumentation(word string) string {
 
 // GetDefinition returns the definition location for a symbol at the given position
 func (a *Analyzer) GetDefinition(doc *Document, pos Position, result *AnalysisResult) *Location {
-	word, _ := doc.GetWordAtPosition(pos)
+	word, wordRange := doc.GetWordAtPosition(pos)
 	if word == "" {
 		return nil
 	}
 
+	// A member of a module is defined in the module's file
+	if mod := moduleMemberAt(doc, wordRange, result); mod != nil {
+		if info, ok := mod.Variables[word]; ok {
+			return &Location{URI: mod.URI, Range: info.DefRange}
+		}
+		if info, ok := mod.Functions[word]; ok {
+			return &Location{URI: mod.URI, Range: info.DefRange}
+		}
+		return nil
+	}
+
 	// Check variables
 	if info, ok := result.Variables[word]; ok {
 		return &Location{
@@ -726,6 +947,16 @@ func (a *Analyzer) GetDefinition(doc *Document, pos Position, result *AnalysisRe
 		}
 	}
 
+	// Names imports without "as" bring in are defined in the imported file
+	for _, mod := range result.Imports {
+		if info, ok := mod.Variables[word]; ok {
+			return &Location{URI: mod.URI, Range: info.DefRange}
+		}
+		if info, ok := mod.Functions[word]; ok {
+			return &Location{URI: mod.URI, Range: info.DefRange}
+		}
+	}
+
 	return nil
 }
 
@@ -807,18 +1038,25 @@ func (a *Analyzer) GetSignatureHelp(doc *Document, pos Position, result *Analysi
 		return nil
 	}
 
-	// Find the opening parenthesis or function call context
-	// In English, function calls look like: "the result of calling FuncName with arg1 and arg2"
+	// Find the function call context. In English, function calls look like
+	// "Call FuncName with arg1 and arg2" or "the result of calling FuncName
+	// with arg1 and arg2".
 	lineBeforeCursor := line[:pos.Character]
+	lower := strings.ToLower(lineBeforeCursor)
 
-	// Look for "calling " pattern
-	callingIdx := strings.LastIndex(strings.ToLower(lineBeforeCursor), "calling ")
-	if callingIdx == -1 {
+	nameStart := -1
+	if idx := strings.LastIndex(lower, "calling "); idx != -1 {
+		nameStart = idx + len("calling ")
+	}
+	if idx := strings.LastIndex(lower, "call "); idx != -1 && idx+len("call ") > nameStart {
+		nameStart = idx + len("call ")
+	}
+	if nameStart == -1 {
 		return nil
 	}
 
-	// Extract function name after "calling "
-	afterCalling := lineBeforeCursor[callingIdx+8:]
+	// Extract function name
+	afterCalling := lineBeforeCursor[nameStart:]
 	funcName := ""
 	for _, c := range afterCalling {
 		if isWordChar(byte(c)) {
@@ -832,38 +1070,65 @@ func (a *Analyzer) GetSignatureHelp(doc *Document, pos Position, result *Analysi
 		return nil
 	}
 
-	// Look up the function
-	funcInfo, ok := result.Functions[funcName]
+	// Look up the function, which "geo's area" finds in the module geo
+	functions := result.Functions
+	if mod, ok := result.Modules[funcName]; ok && strings.HasPrefix(afterCalling[len(funcName):], "'s ") {
+		afterCalling = afterCalling[len(funcName)+len("'s "):]
+		funcName = ""
+		for _, c := range afterCalling {
+			if !isWordChar(byte(c)) {
+				break
+			}
+			funcName += string(c)
+		}
+		functions = mod.Functions
+	}
+	funcInfo, ok := functions[funcName]
 	if !ok {
 		return nil
 	}
 
-	// Count "and" to determine which parameter we're on
+	// Count "and" to determine which parameter we're on; a named argument
+	// ("greeting as ...") is on the parameter it names.
 	withIdx := strings.Index(strings.ToLower(afterCalling), " with ")
 	activeParam := 0
 	if withIdx != -1 {
 		// Count "and" occurrences after "with"
-		afterWith := afterCalling[withIdx+6:]
-		activeParam = strings.Count(strings.ToLower(afterWith), " and ")
+		afterWith := strings.ToLower(afterCalling[withIdx+6:])
+		activeParam = strings.Count(afterWith, " and ")
+		current := afterWith[strings.LastIndex(afterWith, " and ")+1:]
+		current = strings.TrimPrefix(current, "and ")
+		if fields := strings.Fields(current); len(fields) >= 2 && fields[1] == "as" {
+			for i, param := range funcInfo.Parameters {
+				if strings.EqualFold(param, fields[0]) {
+					activeParam = i
+				}
+			}
+		}
 	}
 
 	// Build signature
-	paramLabels := make([]ParameterInformation, 0, len(funcInfo.Parameters))
-	for _, param := range funcInfo.Parameters {
-		paramLabels = append(paramLabels, ParameterInformation{
-			Label: param,
+	labels := paramLabels(funcInfo.Parameters, funcInfo.Defaults, funcInfo.Variadic)
+	paramLabelInfo := make([]ParameterInformation, 0, len(labels))
+	for _, label := range labels {
+		paramLabelInfo = append(paramLabelInfo, ParameterInformation{
+			Label: label,
 		})
 	}
 
 	sig := SignatureInformation{
-		Label: funcName + "(" + strings.Join(funcInfo.Parameters, ", ") + ")",
+		Label: funcName + "(" + strings.Join(labels, ", ") + ")",
 		Documentation: MarkupContent{
 			Kind:  MarkupKindMarkdown,
 			Value: funcInfo.Documentation,
 		},
-		Parameters: paramLabels,
+		Parameters: paramLabelInfo,
 	}
 
+	// Every argument past the fixed parameters goes to a rest parameter.
+	if funcInfo.Variadic && activeParam >= len(funcInfo.Parameters) {
+		activeParam = len(funcInfo.Parameters) - 1
+	}
 	if activeParam < len(funcInfo.Parameters) {
 		sig.ActiveParameter = &activeParam
 	}
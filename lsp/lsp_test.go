@@ -1,6 +1,11 @@
 package lsp
 
 import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -328,6 +333,150 @@ Thats it.`)
 			t.Error("Expected variable symbol")
 		}
 	})
+
+	t.Run("GetSignatureHelp_Defaults", func(t *testing.T) {
+		doc := NewDocument("test", "english", 1, `Declare function greet that takes name and greeting (defaulting to "Hello") and does the following:
+    Print greeting, name.
+Thats it.
+Call greet with "Bo" and greeting as "Hi".`)
+		result := analyzer.Analyze(doc)
+
+		cursor := strings.Index(doc.GetLine(3), `"Hi"`)
+		help := analyzer.GetSignatureHelp(doc, Position{Line: 3, Character: cursor}, result)
+		if help == nil || len(help.Signatures) != 1 {
+			t.Fatal("Expected a signature")
+		}
+		sig := help.Signatures[0]
+		if want := `greet(name, greeting = "Hello")`; sig.Label != want {
+			t.Errorf("Expected label %q, got %q", want, sig.Label)
+		}
+		if help.ActiveParameter == nil || *help.ActiveParameter != 1 {
+			t.Errorf("Expected the named argument to make greeting active, got %v", help.ActiveParameter)
+		}
+	})
+
+	t.Run("NamespacedImport", func(t *testing.T) {
+		dir := t.TempDir()
+		lib := `Declare tau to always be 6.28.
+Declare function area that takes r and does the following:
+    Return r * r.
+Thats it.`
+		if err := os.WriteFile(filepath.Join(dir, "geometry.abc"), []byte(lib), 0644); err != nil {
+			t.Fatal(err)
+		}
+		uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "main.abc"))}).String()
+		doc := NewDocument(uri, "english", 1, `Import "geometry.abc" as geo.
+Print geo's area.`)
+		result := analyzer.Analyze(doc)
+		if result.Modules["geo"] == nil {
+			t.Fatal("Expected module 'geo'")
+		}
+
+		completions := analyzer.GetCompletions(doc, Position{Line: 1, Character: 12}, result)
+		labels := map[string]bool{}
+		for _, c := range completions {
+			labels[c.Label] = true
+		}
+		if len(completions) != 2 || !labels["area"] || !labels["tau"] {
+			t.Errorf("Expected only the module's members, got %v", labels)
+		}
+
+		if hover := analyzer.GetHover(doc, Position{Line: 1, Character: 13}, result); hover == nil {
+			t.Error("Expected hover for a module member")
+		}
+		def := analyzer.GetDefinition(doc, Position{Line: 1, Character: 13}, result)
+		if def == nil || !strings.HasSuffix(def.URI, "/geometry.abc") || def.Range.Start.Line != 1 {
+			t.Errorf("Expected the definition in geometry.abc on line 1, got %+v", def)
+		}
+	})
+
+	t.Run("NamespacedImport_HidesPrivate", func(t *testing.T) {
+		dir := t.TempDir()
+		lib := `Declare privately scale to be 2.
+Declare private function helper that takes r and does the following:
+    Return r * scale.
+Thats it.
+Declare function area that takes r and does the following:
+    Return helper(r) * r.
+Thats it.`
+		if err := os.WriteFile(filepath.Join(dir, "geometry.abc"), []byte(lib), 0644); err != nil {
+			t.Fatal(err)
+		}
+		uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "main.abc"))}).String()
+		doc := NewDocument(uri, "english", 1, `Import "geometry.abc" as geo.
+Print geo's area.`)
+		result := analyzer.Analyze(doc)
+		completions := analyzer.GetCompletions(doc, Position{Line: 1, Character: 12}, result)
+		if len(completions) != 1 || completions[0].Label != "area" {
+			t.Errorf("Expected only the public 'area', got %v", completions)
+		}
+	})
+
+	t.Run("Import_HidesPrivate", func(t *testing.T) {
+		dir := t.TempDir()
+		lib := `Declare privately scale to be 2.
+Declare tau to always be 6.28.
+Declare private function helper that takes r and does the following:
+    Return r * scale.
+Thats it.
+Declare function area that takes r and does the following:
+    Return helper(r) * r.
+Thats it.`
+		if err := os.WriteFile(filepath.Join(dir, "geometry.abc"), []byte(lib), 0644); err != nil {
+			t.Fatal(err)
+		}
+		uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "main.abc"))}).String()
+		for _, tt := range []struct {
+			imp  string
+			want []string
+		}{
+			{`Import everything from "geometry.abc".`, []string{"area", "tau"}},
+			{`Import area from "geometry.abc".`, []string{"area"}},
+		} {
+			doc := NewDocument(uri, "english", 1, tt.imp+"\nPrint 1.")
+			result := analyzer.Analyze(doc)
+			labels := map[string]bool{}
+			for _, c := range analyzer.GetCompletions(doc, Position{Line: 1, Character: 6}, result) {
+				labels[c.Label] = true
+			}
+			for _, name := range []string{"area", "tau", "helper", "scale"} {
+				if want := slices.Contains(tt.want, name); labels[name] != want {
+					t.Errorf("%s: completion of %q = %v, want %v", tt.imp, name, labels[name], want)
+				}
+			}
+		}
+
+		doc := NewDocument(uri, "english", 1, `Import everything from "geometry.abc".
+Print area(2).`)
+		result := analyzer.Analyze(doc)
+		def := analyzer.GetDefinition(doc, Position{Line: 1, Character: 7}, result)
+		if def == nil || !strings.HasSuffix(def.URI, "/geometry.abc") || def.Range.Start.Line != 5 {
+			t.Errorf("Expected the definition in geometry.abc on line 5, got %+v", def)
+		}
+	})
+
+	t.Run("NamespacedImport_ProjectLibraries", func(t *testing.T) {
+		root := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(root, "libraries", "strings"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "english.project"), []byte("name: shop\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		lib := "Declare function slugify that takes s and does the following:\n    Return s.\nThats it."
+		if err := os.WriteFile(filepath.Join(root, "libraries", "strings", "slugify.abc"), []byte(lib), 0644); err != nil {
+			t.Fatal(err)
+		}
+		uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(root, "src", "main.abc"))}).String()
+		doc := NewDocument(uri, "english", 1, `Import "strings/slugify" as slug.`)
+		result := analyzer.Analyze(doc)
+		if mod := result.Modules["slug"]; mod == nil || mod.Functions["slugify"] == nil {
+			t.Errorf("Expected the library in libraries/ to be found, got %+v", mod)
+		}
+	})
 }
 
 func TestServerCapabilities(t *testing.T) {
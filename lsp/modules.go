@@ -0,0 +1,210 @@
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/project"
+)
+
+// ModuleInfo describes a file imported with "Import "geometry.abc" as geo.":
+// the functions and variables reached as "geo's area" and "geo's pi". For a
+// file imported without "as" it holds the names the import brings in, and
+// Name is empty.
+type ModuleInfo struct {
+	Name      string // the name given after "as"
+	Path      string // the path as written in the import
+	URI       string // the file's URI, for go-to-definition
+	Functions map[string]*FunctionInfo
+	Variables map[string]*VariableInfo
+}
+
+// extractModule analyzes the file behind an import with "as" and records it
+// under its alias. The alias itself is recorded as a constant, so it can be
+// completed, hovered and found like any other name.
+func (a *Analyzer) extractModule(s *ast.ImportStatement, result *AnalysisResult, doc *Document) {
+	aliasRange := a.findIdentifierRange(s.Alias, doc)
+	result.Symbols = append(result.Symbols, &Symbol{
+		Name:     s.Alias,
+		Type:     SymbolTypeConstant,
+		Range:    aliasRange,
+		DefRange: aliasRange,
+		Detail:   fmt.Sprintf("module %q", s.Path),
+	})
+	result.Variables[s.Alias] = &VariableInfo{
+		Name:       s.Alias,
+		IsConstant: true,
+		Range:      aliasRange,
+		DefRange:   aliasRange,
+		Value:      fmt.Sprintf("module %q", s.Path),
+	}
+	result.References = append(result.References, &Reference{
+		Name:         s.Alias,
+		Range:        aliasRange,
+		IsDefinition: true,
+	})
+	if mod := a.analyzeImport(s, doc); mod != nil {
+		result.Modules[s.Alias] = mod
+	}
+}
+
+// extractImport records the names an import without "as" brings in: every
+// public name of the file, or just the ones "Import a and b from …" lists.
+func (a *Analyzer) extractImport(s *ast.ImportStatement, result *AnalysisResult, doc *Document) {
+	mod := a.analyzeImport(s, doc)
+	if mod == nil {
+		return
+	}
+	if !s.ImportAll && len(s.Items) > 0 {
+		listed := make(map[string]bool, len(s.Items))
+		for _, item := range s.Items {
+			listed[item] = true
+		}
+		for name := range mod.Functions {
+			if !listed[name] {
+				delete(mod.Functions, name)
+			}
+		}
+		for name := range mod.Variables {
+			if !listed[name] {
+				delete(mod.Variables, name)
+			}
+		}
+	}
+	result.Imports = append(result.Imports, mod)
+}
+
+// analyzeImport analyzes the file behind an import and returns the names
+// it lets other files see, or nil when the file cannot be read or is already
+// being imported on the way to this document.
+func (a *Analyzer) analyzeImport(s *ast.ImportStatement, doc *Document) *ModuleInfo {
+	path := resolveImportPath(doc.URI, s.Path)
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if a.importing[path] {
+		return nil // a module importing itself, directly or not
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	// The module is analyzed by an analyzer of its own, which knows the
+	// files being imported on the way to it.
+	sub := &Analyzer{importing: map[string]bool{path: true}}
+	for p := range a.importing {
+		sub.importing[p] = true
+	}
+	modURI := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	modResult := sub.Analyze(NewDocument(modURI, "english", 0, string(content)))
+	mod := &ModuleInfo{
+		Name:      s.Alias,
+		Path:      s.Path,
+		URI:       modURI,
+		Functions: make(map[string]*FunctionInfo),
+		Variables: make(map[string]*VariableInfo),
+	}
+	// What the file declares privately is not seen by the files importing it.
+	private := project.PrivateNames(modResult.Program)
+	for name, fn := range modResult.Functions {
+		if !private[name] {
+			mod.Functions[name] = fn
+		}
+	}
+	for name, v := range modResult.Variables {
+		if !private[name] {
+			mod.Variables[name] = v
+		}
+	}
+	return mod
+}
+
+// resolveImportPath finds the file an import names, searching from the
+// document's directory when the document is a file — so the project and
+// libraries it belongs to are found — and then from the current directory,
+// as run does.
+func resolveImportPath(docURI, importPath string) string {
+	importer := ""
+	if u, err := url.Parse(docURI); err == nil && u.Scheme == "file" {
+		importer = filepath.FromSlash(u.Path)
+	}
+	if path, err := project.ResolveImport(importer, importPath); err == nil {
+		return path
+	}
+	return importPath
+}
+
+// moduleBefore returns the module whose possessive, as in "geo's ", comes
+// right before column col of line.
+func moduleBefore(line string, col int, result *AnalysisResult) *ModuleInfo {
+	if col > len(line) {
+		col = len(line)
+	}
+	text := strings.TrimRight(line[:col], " \t")
+	if len(text) == col {
+		return nil // no space between the possessive and the word
+	}
+	before, ok := strings.CutSuffix(text, "'s")
+	if !ok {
+		return nil
+	}
+	start := len(before)
+	for start > 0 && isWordChar(before[start-1]) {
+		start--
+	}
+	return result.Modules[before[start:]]
+}
+
+// moduleMemberAt returns the module the word at wordRange is a member of,
+// when it is written "geo's word".
+func moduleMemberAt(doc *Document, wordRange Range, result *AnalysisResult) *ModuleInfo {
+	return moduleBefore(doc.GetLine(wordRange.Start.Line), wordRange.Start.Character, result)
+}
+
+// moduleCompletions offers the members of mod.
+func moduleCompletions(mod *ModuleInfo, prefix string) []CompletionItem {
+	items := make([]CompletionItem, 0)
+	for name, info := range mod.Variables {
+		if prefix == "" || strings.HasPrefix(strings.ToLower(name), prefix) {
+			kind := CompletionItemKindVariable
+			if info.IsConstant {
+				kind = CompletionItemKindConstant
+			}
+			items = append(items, CompletionItem{
+				Label:  name,
+				Kind:   kind,
+				Detail: info.Value,
+				Documentation: MarkupContent{
+					Kind:  MarkupKindMarkdown,
+					Value: fmt.Sprintf("Variable `%s` %s", name, mod.origin()),
+				},
+			})
+		}
+	}
+	for name, info := range mod.Functions {
+		if prefix == "" || strings.HasPrefix(strings.ToLower(name), prefix) {
+			items = append(items, CompletionItem{
+				Label:  name,
+				Kind:   CompletionItemKindFunction,
+				Detail: "function(" + strings.Join(paramLabels(info.Parameters, info.Defaults, info.Variadic), ", ") + ")",
+				Documentation: MarkupContent{
+					Kind:  MarkupKindMarkdown,
+					Value: info.Documentation,
+				},
+			})
+		}
+	}
+	return items
+}
+
+// origin says where a member of mod comes from, for completions and hovers.
+func (mod *ModuleInfo) origin() string {
+	if mod.Name == "" {
+		return fmt.Sprintf("from `%s`", mod.Path)
+	}
+	return fmt.Sprintf("of module `%s`", mod.Name)
+}
@@ -185,6 +185,114 @@ func (p *Parser) parseRaiseStatement() (ast.Statement, error) {
 	}, nil
 }
 
+// parseMakeSure parses an assertion:
+//
+//	Make sure that balance is at least 0, otherwise say "negative balance".
+//
+// "make", "sure" and "say" are matched as plain identifiers. The condition's
+// source text is kept for the AssertionError raised when it does not hold.
+func (p *Parser) parseMakeSure() (ast.Statement, error) {
+	startLine := p.curToken.Line
+	p.nextToken() // consume "make"
+	p.nextToken() // consume "sure"
+
+	if p.curToken.Type != token.THAT {
+		return nil, p.syntaxErr(msgMakeSureThat, hintMakeSure)
+	}
+	p.nextToken()
+
+	// curToken is tokens[p.position-2]; see nextToken.
+	start := p.position - 2
+	condition, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	condTokens := p.tokens[start : p.position-2]
+
+	stmt := &ast.AssertStatement{
+		Condition: condition,
+		Text:      sourceText(condTokens),
+		Line:      startLine,
+	}
+	if be, ok := condition.(*ast.BinaryExpression); ok && strings.HasPrefix(be.Operator, "is ") {
+		depth := 0
+		for i, tok := range condTokens {
+			switch {
+			case tok.Type == token.LPAREN:
+				depth++
+			case tok.Type == token.RPAREN:
+				depth--
+			case depth == 0 && isComparisonToken(tok.Type):
+				// parseRelational reads the left side with parseCast, which
+				// stops at the first comparison outside parentheses.
+				if i+1 < len(condTokens) {
+					stmt.Operands = []string{sourceText(condTokens[:i]), sourceText(condTokens[i+1:])}
+				}
+			}
+			if stmt.Operands != nil {
+				break
+			}
+		}
+	}
+
+	if p.curToken.Type == token.COMMA {
+		p.nextToken()
+		if p.curToken.Type != token.OTHERWISE || !isWord(p.peekToken, "say") {
+			return nil, p.syntaxErr(msgMakeSureOtherwise, hintMakeSure)
+		}
+		p.nextToken()
+		p.nextToken()
+		stmt.Message, err = p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	return stmt, nil
+}
+
+// isComparisonToken reports whether t is one of the comparison operators
+// that parseRelational turns into a BinaryExpression.
+func isComparisonToken(t token.Type) bool {
+	switch t {
+	case token.IS_EQUAL_TO, token.IS_LESS_THAN, token.IS_GREATER_THAN,
+		token.IS_LESS_EQUAL, token.IS_GREATER_EQUAL, token.IS_NOT_EQUAL:
+		return true
+	}
+	return false
+}
+
+// sourceText rebuilds the source of a run of tokens: one space apart, but
+// none inside brackets, before a comma or 's, or before the bracket of a call
+// or index written straight after its name.
+func sourceText(toks []token.Token) string {
+	var sb strings.Builder
+	for i, tok := range toks {
+		if i > 0 {
+			prev := toks[i-1]
+			switch {
+			case prev.Type == token.LPAREN, prev.Type == token.LBRACKET:
+			case tok.Type == token.RPAREN, tok.Type == token.RBRACKET,
+				tok.Type == token.COMMA, tok.Type == token.POSSESSIVE:
+			case (tok.Type == token.LPAREN || tok.Type == token.LBRACKET) &&
+				prev.Type == token.IDENTIFIER && tok.Pos == prev.Pos+len(prev.Value):
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+		if tok.Type == token.STRING || tok.Type == token.INTERPOLATED_STRING {
+			sb.WriteString(`"` + tok.Value + `"`)
+		} else {
+			sb.WriteString(tok.Value)
+		}
+	}
+	return sb.String()
+}
+
 // parseSwapStatement parses a swap statement
 // Syntax: swap a and b.
 func (p *Parser) parseSwapStatement() (ast.Statement, error) {
@@ -283,6 +391,78 @@ func (p *Parser) parseErrorTypeDecl() (ast.Statement, error) {
 	}, nil
 }
 
+// parseEnumDecl parses an enumeration declaration.
+// Syntax: Declare Color as one of red, green and blue.
+// Members are separated by commas and/or "and". This is called from
+// parseDeclareAs when "one of" is detected.
+func (p *Parser) parseEnumDecl() (ast.Statement, error) {
+	nameToken := p.curToken
+	if nameToken.Type != token.IDENTIFIER {
+		return nil, p.syntaxErr(
+			msgEnumName,
+			hintEnumDecl,
+		)
+	}
+	p.nextToken() // consume name
+
+	// Consume "as one of"
+	if err := p.expectToken(token.AS); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	p.nextToken() // "one"
+	if err := p.expectToken(token.OF); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	var members []string
+	seen := make(map[string]bool)
+	for {
+		if !isPossessiveMethodNameToken(p.curToken.Type) || p.curToken.Type == token.AND {
+			return nil, p.syntaxErr(
+				fmt.Sprintf(msgFmtEnumMember, p.curToken.Value),
+				hintEnumDecl,
+			)
+		}
+		member := p.curToken.Value
+		if seen[member] {
+			return nil, p.syntaxErr(
+				fmt.Sprintf(msgFmtEnumDuplicate, member, nameToken.Value),
+				hintEnumDecl,
+			)
+		}
+		seen[member] = true
+		members = append(members, member)
+		p.nextToken()
+
+		if p.curToken.Type == token.PERIOD {
+			break
+		}
+		switch p.curToken.Type {
+		case token.COMMA:
+			p.nextToken()
+			if p.curToken.Type == token.AND { // "red, green, and blue"
+				p.nextToken()
+			}
+		case token.AND:
+			p.nextToken()
+		default:
+			return nil, p.syntaxErr(
+				fmt.Sprintf(msgFmtEnumMember, p.curToken.Value),
+				hintEnumDecl,
+			)
+		}
+	}
+	p.nextToken() // consume period
+
+	return &ast.EnumDecl{
+		Name:    nameToken.Value,
+		Members: members,
+		Line:    nameToken.Line,
+	}, nil
+}
+
 // parseErrorSubtypeDecl parses an error subtype declaration.
 // Syntax: Declare CustomErr1 as a type of CustomLibError.
 // This is called from parseDeclareAs when "a type of" is detected.
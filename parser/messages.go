@@ -36,6 +36,8 @@ const (
 	hintFunctionName        = "Function names must start with a letter. For example: 'Declare function greet that does the following:'"
 	hintParameterName       = "Parameter names must start with a letter. For example: 'Declare function add that takes x and y and does the following:'"
 	hintImportPath          = "For example: 'Import \"myfile.abc\".' or 'Import everything from \"utils.abc\".'"
+	hintImportAlias         = "For example: 'Import \"geometry.abc\" as geo.' then 'Set a to be the result of calling geo's area with 3.'"
+	hintPrivateDecl         = "For example: 'Declare privately cache to be 0.' or 'Declare private function helper that does the following:'"
 
 	// Assignment / Set statements.
 	hintSetVarName    = "For example: 'Set score to be 10.' or 'Set name to be \"Alice\".'"
@@ -43,13 +45,15 @@ const (
 	hintSetCallResult = "For example: 'Set result to be the result of calling square of x.'"
 	hintSetListName   = "For example: 'Set the item at position 1 in myList to be 99.'"
 	hintSetTableFull  = "The full form is: 'Set tableName at key to be value.'"
+	hintSetFieldFull  = "The full form is: 'Set person's age to be 31.'"
 
 	// Call statements.
 	hintCallName = "For example: 'Call greet.' or 'Call add with 3 and 5.'"
 
 	// Control-flow statements.
 	hintForEachVar = "For example: 'For each item in myList:' or 'For each number in scores:'"
-	hintBreakLoop  = "For example: 'Break out of the loop.' or 'Break out of this loop.'"
+	hintBreakLoop  = "For example: 'Break out of the loop.', 'Break out of this loop.' or, for a loop called outer, 'Break out of outer.'"
+	hintLoopLabel  = "For example: 'Repeat the following while i is less than 10 and call this loop outer:' then 'Break out of outer.' or 'Continue outer.' inside it."
 
 	// Output.
 	hintPrintOrWrite = "To show output, use 'Print \"Hello\".' or 'Write \"No newline\".'"
@@ -60,9 +64,10 @@ const (
 	hintAskFull = "For example: 'Ask \"What is your name?\" as myName.' or 'Ask \"Enter a number:\" and store it in num.'"
 
 	// Type cast / possessive / error-type check.
-	hintCastType       = "Valid type names are: number, text, boolean, integer. For example: 'x cast to number'."
+	hintCastType       = "Valid type names are: number, decimal, whole number, text, boolean, integer. For example: 'x cast to number'."
+	hintDecimalLiteral = "For example: 'the decimal 19.99' or 'the whole number 12345678901234567890'."
 	hintPossessive     = "For example: 'myText's length' or 'myText's upper'."
-	hintErrorTypeCheck = "For example: 'error is NetworkError' or 'error is RuntimeError'."
+	hintErrorTypeCheck = "For example: 'error is NetworkError' or 'pet is a Animal'."
 
 	// Expressions.
 	hintTheExpression     = "After 'the' you can use: 'the value of x', 'the length of myList', 'the remainder of a divided by b', 'the result of calling myFunction', or a field name like 'the age of person'."
@@ -72,6 +77,9 @@ const (
 	hintLocationOf        = "For example: 'the location of myVariable'."
 	hintReferenceTo       = "For example: 'a reference to myVariable'."
 	hintToggle            = "For example: 'Toggle isRunning.' or 'Toggle the value of isActive.'"
+	hintFunctionLiteral   = "For example: 'a function that takes x and returns x * 2' or 'a function that takes x and does the following: ... thats it'."
+	hintWhenStatement     = "For example: 'When command is \"start\": ... When it is \"stop\" or \"halt\": ... Otherwise: ... thats it.' Cases can also be 'between 1 and 10' or 'a number'."
+	hintInterpolation     = "Put an expression inside braces to include its value, for example: \"Hello, {name}!\". Write \\{ for a literal brace, and use the other kind of quote for text inside the braces."
 
 	// Arrays and lookup tables.
 	hintArrayLiteral         = "For example: 'an array of [1, 2, 3]' or 'an array of number [1, 2, 3]'."
@@ -84,13 +92,54 @@ const (
 	hintOnError      = "For example: 'on error:' to catch all errors, or 'on NetworkError:' to catch a specific type."
 	hintRaiseAs      = "For example: 'raise \"Something went wrong\" as NetworkError.'"
 	hintSwapVars     = "For example: 'swap a and b.' swaps the values of a and b."
+	hintMakeSure     = "For example: 'Make sure that balance is at least 0, otherwise say \"negative balance\".'"
+
+	// Integer overflow mode.
+	hintOverflowMode = "For example: 'Use wrapping arithmetic.' or 'Use checked arithmetic.'"
 
 	// Custom error type declarations.
 	hintErrorTypeDecl    = "For example: 'Declare NetworkError as an error type.'"
 	hintErrorSubtypeDecl = "For example: 'Declare TimeoutError as a type of NetworkError.'"
 
+	// Enumeration declarations.
+	hintEnumDecl = "For example: 'Declare Color as one of red, green and blue.'"
+
+	// Capability declarations.
+	hintCapabilityDecl   = "For example: 'Declare Speaker as a capability requiring a function speak.'"
+	hintStructCapability = "For example: 'Declare Dog as a structure that can Speaker with the following fields:'"
+	hintParamCapability  = "For example: 'Declare function greet that takes pet as anything that can Speaker and does the following:'"
+
+	// Typed function signatures.
+	hintSignatureType = "For example: 'Declare function area that takes width as number and height as number and returns a number and does the following:'"
+
+	// Element types of lists and lookup tables.
+	hintElementType = "For example: 'Declare scores as a list of numbers.' or 'Declare ages as a lookup table from text to number.'"
+
+	// Default parameter values and named arguments.
+	hintParamDefault  = "For example: 'Declare function greet that takes name and greeting (defaulting to \"Hello\") and does the following:'"
+	hintNamedArgument = "Named arguments come after the others. For example: 'Call greet with \"Bo\" and greeting as \"Hi\".'"
+
+	// Rest parameters.
+	hintRestParam = "For example: 'Declare function total that takes any number of values and does the following:'"
+
+	// Generators.
+	hintGiveBack = "For example: 'Declare function evens that takes n and does the following:' with 'Give back i.' in its body."
+
+	// Background tasks and channels.
+	hintBackground = "For example: 'Do the following in the background and call it job:' followed by the statements and 'thats it.'"
+	hintSend       = "For example: 'Send 5 to results.'"
+	hintReceive    = "For example: 'Receive from results into answer.'"
+
+	// Test blocks.
+	hintTestBlock = "For example: 'Test \"adding works\" by doing the following:' followed by the statements and 'thats it.'"
+
+	// Destructuring declarations and assignments.
+	hintDestructure     = "For example: 'Declare q and r to be the result of calling divide with 7 and 2.'"
+	hintDestructureRest = "For example: 'Declare first and the rest as others to be [1, 2, 3].'"
+
 	// Structure declarations.
 	hintStructName         = "For example: 'Declare Person as a structure with the following fields:'"
+	hintStructParent       = "For example: 'Declare Dog as a kind of Animal with the following fields:'"
 	hintFieldName          = "Field names must start with a letter. For example: 'name is a text.'"
 	hintFieldType          = "Valid types are: text, number, boolean, integer. For example: 'name is a text.' or 'age is an integer.'"
 	hintMethodName         = "Method names must start with a letter. For example: 'let greet be a function that does the following:'"
@@ -111,23 +160,45 @@ const (
 	msgFunctionNameExpected = "I expected a function name here."
 	msgParameterName        = "I expected a parameter name here."
 	msgImportPath           = "The file path after 'Import' must be in quotes."
+	msgImportAlias          = "I expected a module name after 'as'."
+	msgImportAliasItems     = "An import with 'as' brings in the whole file, so it cannot name items to import."
 	msgDeclareVarName       = "I expected a variable name after 'Declare'."
+	msgPrivateDecl          = "Only variables and functions can be declared privately."
 	msgSetVarName           = "I expected a variable name after 'Set'."
 	msgSetCallFuncName      = "I expected the name of a function to call here."
 	msgSetListName          = "I expected the name of the list here."
 	msgCallName             = "I expected a function or method name after 'Call'."
 	msgForEachVar           = "I expected a loop variable name here."
+	msgLoopLabel            = "I expected 'this loop' and a name after 'and call'."
 	msgPrintOrWrite         = "I expected 'Print' or 'Write' here."
 	msgAskVarAs             = "I expected a variable name after 'as' to store the answer."
 	msgAskVarAnd            = "I expected a variable name to store the answer in."
 	msgErrorTypeOnName      = "I expected an error type name or 'error' after 'on'."
 	msgRaiseErrorType       = "I expected an error type name after 'as'."
+	msgMakeSureThat         = "I expected 'sure that' and a condition after 'Make'."
+	msgMakeSureOtherwise    = "I expected 'otherwise say' and a message after the condition."
 	msgSwapFirstVar         = "I expected the first variable name after 'swap'."
 	msgSwapSecondVar        = "I expected the second variable name after 'and'."
 	msgErrorTypeName        = "I expected the name of the new error type."
 	msgErrorSubtypeName     = "I expected the name of the error subtype."
 	msgErrorParentType      = "I expected the parent error type name after 'of'."
+	msgEnumName             = "I expected the name of the new enumeration."
+	msgCapabilityName       = "I expected the name of the new capability."
+	msgCapabilityAfterCan   = "I expected the name of a capability after 'can'."
+	msgDefaultClose         = "I expected ')' after the default value."
+	msgDestructureName      = "I expected another variable name after 'and'."
+	msgDestructureRestAs    = "I expected 'as' and a variable name after 'the rest'."
+	msgDestructureRestLast  = "'the rest' must be the last of the names to unpack into."
+	msgGiveBackOutside      = "'Give back' can only be used in the body of a function declared with 'Declare function'."
+	msgBackgroundForm       = "I expected 'in the background and call it' and a name after 'Do the following'."
+	msgTaskName             = "I expected a name for the task after 'call it'."
+	msgSendTo               = "I expected 'to' and a channel after the value to send."
+	msgReceiveFrom          = "I expected 'from' and a channel after 'Receive'."
+	msgReceiveInto          = "I expected 'into' and a variable name after the channel."
+	msgTestForm             = "I expected 'by doing the following:' after the name of the test."
+	msgTestNested           = "A test can only be written at the top level of a file, not inside another block."
 	msgStructName           = "I expected the name of the structure after 'Declare'."
+	msgStructParentName     = "I expected the name of the parent structure after 'a kind of'."
 	msgFieldName            = "I expected the name of the field."
 	msgMethodName           = "I expected the method name."
 	msgMethodParam          = "I expected a parameter name."
@@ -143,6 +214,8 @@ const (
 	msgArrayNeedsOf         = "I expected 'of' after 'array'."
 	msgArrayNeedsCloseBrkt  = "I expected ']' to close the array, but reached the end of the file."
 	msgStructMethodParam    = "I expected a parameter name."
+	msgFunctionLiteralBody  = "I expected 'returns' or 'does the following' to describe what the function does."
+	msgOverflowMode         = "I expected 'wrapping arithmetic' or 'checked arithmetic' after 'Use'."
 )
 
 // ─── Format-string messages ───────────────────────────────────────────────────
@@ -165,7 +238,7 @@ const (
 	msgFmtCallFromOn = "I expected the object name after 'Call %s from/on'."
 
 	// "I expected 'the' or 'this' here, but found '<tok>'."
-	msgFmtBreakTheThis = "I expected 'the' or 'this' here, but found '%s'."
+	msgFmtBreakTheThis = "I expected 'the', 'this' or the name of a loop here, but found '%s'."
 
 	// "I expected 'as' or 'and' after the question text, but found '<tok>'."
 	msgFmtAskAfter = "I expected 'as' or 'and' after the question text, but found '%s'."
@@ -173,6 +246,9 @@ const (
 	// "I expected a type name after 'cast to', but found '<tok>'."
 	msgFmtCastTypeName = "I expected a type name after 'cast to', but found '%s'."
 
+	// "I expected a number after 'the decimal'."
+	msgFmtDecimalLiteral = "I expected a number after 'the %s'."
+
 	// "I do not understand 'the <tok>' here."
 	msgFmtTheUnknown = "I do not understand 'the %s' here."
 
@@ -206,6 +282,48 @@ const (
 	// "I expected 'a' or 'an' after 'as', but found '<tok>'."
 	msgFmtArticleAfterAs = "I expected 'a' or 'an' after 'as', but found '%s'."
 
+	// "I expected a member name after 'one of', but found '<tok>'."
+	msgFmtEnumMember = "I expected a member name after 'one of', but found '%s'."
+
+	// "'<member>' is listed twice in the enumeration '<name>'."
+	msgFmtEnumDuplicate = "'%s' is listed twice in the enumeration '%s'."
+
+	// "I expected a function name after 'requiring', but found '<tok>'."
+	msgFmtCapabilityMethod = "I expected a function name after 'requiring', but found '%s'."
+
+	// "I expected 'anything that can' after 'as', but found '<tok>'."
+	msgFmtParamCapability = "I expected 'anything that can' after 'as', but found '%s'."
+
+	// "I expected a type such as 'number' or 'text' after '<word>', but found '<tok>'."
+	msgFmtSignatureType = "I expected a type such as 'number' or 'text' after '%s', but found '%s'."
+
+	// "I expected 'to' after the type of the lookup table's keys, but found '<tok>'."
+	msgFmtLookupKeyTypeTo = "I expected 'to' after the type of the lookup table's keys, but found '%s'."
+
+	// "I expected 'to' after 'defaulting', but found '<tok>'."
+	msgFmtDefaultTo = "I expected 'to' after 'defaulting', but found '%s'."
+
+	// "Parameter '<name>' comes after one with a default, so it needs a default too."
+	msgFmtParamNeedsDefault = "Parameter '%s' comes after one with a default, so it needs a default too."
+
+	// "I found a plain argument after the named argument '<name>'."
+	msgFmtPositionalAfterNamed = "I found a plain argument after the named argument '%s'."
+
+	// "'<name>' is listed twice in the names to unpack into."
+	msgFmtDestructureTwice = "'%s' is listed twice in the names to unpack into."
+
+	// "I expected 'of' and a parameter name after 'any number', but found '<tok>'."
+	msgFmtRestParamName = "I expected 'of' and a parameter name after 'any number', but found '%s'."
+
+	// "'<name>' collects the remaining arguments, so it has to be the last parameter."
+	msgFmtRestParamLast = "'%s' collects the remaining arguments, so it has to be the last parameter."
+
+	// "'<name>' collects the remaining arguments into a list, so it cannot have a default."
+	msgFmtRestParamDefault = "'%s' collects the remaining arguments into a list, so it cannot have a default."
+
+	// "'<name>' collects the remaining arguments into a list, so it cannot be given a type or a capability."
+	msgFmtRestParamAs = "'%s' collects the remaining arguments into a list, so it cannot be given a type or a capability."
+
 	// "I expected the word 'error' here, but found '<tok>'."
 	msgFmtExpectedErrorWord = "I expected the word 'error' here, but found '%s'."
 
@@ -222,6 +340,15 @@ const (
 	// "The text <s> cannot appear here on its own."
 	msgFmtStringStatement = "The text %q cannot appear here on its own."
 
+	// "I expected 'is' after the value being matched, but found '<tok>'."
+	msgFmtWhenIs = "I expected 'is' after the value being matched, but found '%s'."
+
+	// "I could not read the text: <reason>."
+	msgFmtInterpolation = "I could not read the text: %s."
+
+	// "I could not understand '{<expr>}' inside the text."
+	msgFmtInterpolatedExpr = "I could not understand '{%s}' inside the text."
+
 	// "I do not know what to do with '<tok>' here."
 	msgFmtUnknownToken = "I do not know what to do with '%s' here."
 )
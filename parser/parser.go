@@ -3,6 +3,7 @@ package parser
 import (
 	"github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/token"
+	"github.com/Advik-B/english/tokeniser"
 	"fmt"
 	"strconv"
 	"strings"
@@ -11,6 +12,7 @@ import (
 // Magic string constants used in parsing
 const (
 	resultKeyword = "result"
+	restKeyword   = "rest"
 )
 
 // Parser transforms tokens into an AST
@@ -19,6 +21,10 @@ type Parser struct {
 	position  int
 	curToken  token.Token
 	peekToken token.Token
+	// generator points at the Generator flag of the function whose body is
+	// being parsed, which "Give back" sets. It is nil outside a function and
+	// in method and function literal bodies, where "Give back" is an error.
+	generator *bool
 }
 
 // NewParser creates a new parser for the given tokens
@@ -102,8 +108,15 @@ func (p *Parser) Parse() (*ast.Program, error) {
 		stmtStartLine := p.curToken.Line
 
 		// parseStatement() also handles PLEASE (for inner blocks), but since we
-		// already consumed it above, curToken is no longer PLEASE here.
-		stmt, err := p.parseStatement()
+		// already consumed it above, curToken is no longer PLEASE here. Test
+		// blocks are only allowed here, at the top level.
+		var stmt ast.Statement
+		var err error
+		if isTestBlock(p.curToken, p.peekToken) {
+			stmt, err = p.parseTestBlock()
+		} else {
+			stmt, err = p.parseStatement()
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -162,6 +175,8 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 		return p.parseCall()
 	case token.IF:
 		return p.parseIfStatement()
+	case token.WHEN:
+		return p.parseWhenStatement()
 	case token.REPEAT:
 		return p.parseRepeat()
 	case token.FOR:
@@ -182,10 +197,30 @@ func (p *Parser) parseStatement() (ast.Statement, error) {
 		return p.parseSwapStatement()
 	case token.SLEEP:
 		return p.parseSleepStatement()
+	case token.DO:
+		return p.parseBackground()
 	default:
 		switch p.curToken.Type {
 		case token.IDENTIFIER:
 			name := p.curToken.Value
+			if strings.EqualFold(name, "use") {
+				return p.parseOverflowMode()
+			}
+			if strings.EqualFold(name, "give") && isWord(p.peekToken, "back") {
+				return p.parseGiveBack()
+			}
+			if strings.EqualFold(name, "make") && isWord(p.peekToken, "sure") {
+				return p.parseMakeSure()
+			}
+			if strings.EqualFold(name, "send") {
+				return p.parseSend()
+			}
+			if strings.EqualFold(name, "receive") && p.peekToken.Type == token.FROM {
+				return p.parseReceive()
+			}
+			if isTestBlock(p.curToken, p.peekToken) {
+				return nil, p.syntaxErr(msgTestNested, hintTestBlock)
+			}
 			return nil, &SyntaxError{
 				Msg:  fmt.Sprintf(msgFmtIdentifierStatement, name),
 				Line: p.curToken.Line,
@@ -311,10 +346,12 @@ func (p *Parser) parseLetDeclaration() (ast.Statement, error) {
 // - Import everything from "file.abc".
 // - Import all from "file.abc".
 // - Import all from "file.abc" safely.
+// - Import "file.abc" as m.
 func (p *Parser) parseImport() (ast.Statement, error) {
 	if err := p.expectToken(token.IMPORT); err != nil {
 		return nil, err
 	}
+	importLine := p.curToken.Line
 	p.nextToken()
 
 	var items []string
@@ -380,6 +417,26 @@ func (p *Parser) parseImport() (ast.Statement, error) {
 		p.nextToken()
 	}
 
+	// Check for "as <name>": the file's names go into a module object
+	// instead of the importer's environment.
+	var alias string
+	if p.curToken.Type == token.AS {
+		p.nextToken()
+		if p.curToken.Type != token.IDENTIFIER {
+			return nil, p.syntaxErr(msgImportAlias, hintImportAlias)
+		}
+		if len(items) > 0 {
+			return nil, p.syntaxErr(msgImportAliasItems, hintImportAlias)
+		}
+		alias = p.curToken.Value
+		p.nextToken()
+		// "Import "file.abc" as m safely." reads as well as "safely as m"
+		if p.curToken.Type == token.SAFELY && !isSafe {
+			isSafe = true
+			p.nextToken()
+		}
+	}
+
 	// Expect period to end the statement
 	if err := p.expectToken(token.PERIOD); err != nil {
 		return nil, err
@@ -391,6 +448,8 @@ func (p *Parser) parseImport() (ast.Statement, error) {
 		Items:     items,
 		ImportAll: importAll,
 		IsSafe:    isSafe,
+		Alias:     alias,
+		Line:      importLine,
 	}, nil
 }
 
@@ -400,6 +459,40 @@ func (p *Parser) parseDeclaration() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	// "Declare privately x …" and "Declare private function f …" hide the
+	// declaration from files that import this one. A name must follow, so
+	// "Declare private to be 1." still declares a variable called private.
+	if p.curToken.Type == token.IDENTIFIER && isPrivateWord(p.curToken.Value) &&
+		(p.peekToken.Type == token.IDENTIFIER || p.peekToken.Type == token.FUNCTION) {
+		p.nextToken()
+		startTok := p.curToken
+		stmt, err := p.parseDeclared()
+		if err != nil {
+			return nil, err
+		}
+		switch d := stmt.(type) {
+		case *ast.VariableDecl:
+			d.IsPrivate = true
+		case *ast.TypedVariableDecl:
+			d.IsPrivate = true
+		case *ast.FunctionDecl:
+			d.IsPrivate = true
+		default:
+			return nil, &SyntaxError{Msg: msgPrivateDecl, Line: startTok.Line, Col: startTok.Col, Hint: hintPrivateDecl}
+		}
+		return stmt, nil
+	}
+	return p.parseDeclared()
+}
+
+// isPrivateWord reports whether word is "private" or "privately".
+func isPrivateWord(word string) bool {
+	return strings.EqualFold(word, "private") || strings.EqualFold(word, "privately")
+}
+
+// parseDeclared parses what follows "Declare": a function, a declaration
+// with "as", or a variable.
+func (p *Parser) parseDeclared() (ast.Statement, error) {
 	// Check if it's a function declaration
 	if p.curToken.Type == token.FUNCTION {
 		return p.parseFunctionDeclaration()
@@ -421,6 +514,16 @@ func (p *Parser) parseDeclaration() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	// "Declare q and r to be …" — destructuring declaration
+	var names []string
+	var rest bool
+	if p.curToken.Type == token.AND || p.curToken.Type == token.COMMA {
+		var err error
+		if names, rest, err = p.parseMoreNames(nameToken.Value); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := p.expectToken(token.TO); err != nil {
 		return nil, err
 	}
@@ -444,7 +547,7 @@ func (p *Parser) parseDeclaration() (ast.Statement, error) {
 		p.nextToken()
 	}
 
-	value, err := p.parseExpression()
+	value, err := p.parseAssignedValue()
 	if err != nil {
 		return nil, err
 	}
@@ -454,6 +557,9 @@ func (p *Parser) parseDeclaration() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	if names != nil {
+		return &ast.VariableDecl{Names: names, Rest: rest, IsConstant: isConstant, Value: value, Line: nameToken.Line}, nil
+	}
 	return &ast.VariableDecl{
 		Name:       nameToken.Value,
 		IsConstant: isConstant,
@@ -464,8 +570,11 @@ func (p *Parser) parseDeclaration() (ast.Statement, error) {
 
 // parseDeclareAs dispatches "Declare X as ..." to the correct parser:
 //   - "Declare X as a structure ..."       → struct declaration
+//   - "Declare X as a kind of Y ..."       → struct declaration inheriting Y
+//   - "Declare X as a capability ..."      → capability declaration
 //   - "Declare X as an error type."        → custom error type declaration
 //   - "Declare X as a type of Y."          → error subtype declaration
+//   - "Declare X as one of a, b and c."    → enumeration declaration
 //   - "Declare X as <typename> to be ..."  → typed variable declaration
 func (p *Parser) parseDeclareAs() (ast.Statement, error) {
 	// curToken is IDENTIFIER (name), peekToken is AS.
@@ -482,6 +591,15 @@ func (p *Parser) parseDeclareAs() (ast.Statement, error) {
 		switch {
 		case tok2AfterAs.Type == token.STRUCTURE || tok2AfterAs.Type == token.STRUCT:
 			return p.parseStructDeclaration()
+		case strings.ToLower(tok2AfterAs.Value) == "capability":
+			// "Declare Speaker as a capability requiring a function speak."
+			return p.parseCapabilityDecl()
+		case strings.ToLower(tok2AfterAs.Value) == "kind":
+			// Might be "Declare Dog as a kind of Animal with the following fields:"
+			tok3AfterAs := p.tokenAt(p.position + 2)
+			if tok3AfterAs.Type == token.OF {
+				return p.parseStructDeclaration()
+			}
 		case strings.ToLower(tok2AfterAs.Value) == "error":
 			// Might be "Declare X as an error type."
 			tok3AfterAs := p.tokenAt(p.position + 2)
@@ -497,6 +615,12 @@ func (p *Parser) parseDeclareAs() (ast.Statement, error) {
 		}
 	}
 
+	// "Declare X as one of red, green and blue."
+	if tokAfterAs.Type == token.IDENTIFIER && strings.ToLower(tokAfterAs.Value) == "one" &&
+		tok2AfterAs.Type == token.OF {
+		return p.parseEnumDecl()
+	}
+
 	// Fall through: typed variable declaration "Declare X as typename to be value."
 	return p.parseTypedVariableDecl()
 }
@@ -525,7 +649,9 @@ func (p *Parser) parseFunctionDeclaration() (ast.Statement, error) {
 	}
 	p.nextToken()
 
-	var parameters []string
+	var parameters, capabilities, paramTypes []string
+	var defaults []ast.Expression
+	variadic := false
 
 	// Skip optional "that" before "takes" or "does"
 	if p.curToken.Type == token.THAT {
@@ -535,6 +661,16 @@ func (p *Parser) parseFunctionDeclaration() (ast.Statement, error) {
 	if p.curToken.Type == token.TAKES {
 		p.nextToken()
 		for {
+			// "takes any number of values" ends the parameter list
+			if isWord(p.curToken, "any") && isWord(p.peekToken, "number") {
+				rest, err := p.parseRestParameter()
+				if err != nil {
+					return nil, err
+				}
+				parameters = append(parameters, rest)
+				variadic = true
+				break
+			}
 			paramToken := p.curToken
 			if p.curToken.Type != token.IDENTIFIER {
 				return nil, p.syntaxErr(
@@ -545,17 +681,43 @@ func (p *Parser) parseFunctionDeclaration() (ast.Statement, error) {
 			parameters = append(parameters, paramToken.Value)
 			p.nextToken()
 
+			// "takes width as number", "takes pet as anything that can Speaker"
+			if p.curToken.Type == token.AS {
+				capability, typeName, err := p.parseParamAnnotation()
+				if err != nil {
+					return nil, err
+				}
+				if capability != "" {
+					capabilities = setParamAnnotation(capabilities, len(parameters)-1, capability)
+				} else {
+					paramTypes = setParamAnnotation(paramTypes, len(parameters)-1, typeName)
+				}
+			}
+
+			// "takes name and greeting (defaulting to "Hello")"
+			var err error
+			if defaults, err = p.parseParamDefault(defaults, parameters, len(parameters)-1); err != nil {
+				return nil, err
+			}
+
 			if p.curToken.Type != token.AND {
 				break
 			}
-			// Check if "and" is followed by "does" (end of params) or another param
-			if p.peekToken.Type == token.DOES {
+			// Check if "and" is followed by "does" or "returns" (end of
+			// params) or another param
+			if p.peekToken.Type == token.DOES || isReturnsWord(p.peekToken) {
 				break
 			}
 			p.nextToken()
 		}
 	}
 
+	// "and returns a number"
+	returnType, err := p.parseReturnType()
+	if err != nil {
+		return nil, err
+	}
+
 	// Support "and does" syntax after parameters
 	if p.curToken.Type == token.AND {
 		p.nextToken()
@@ -581,7 +743,8 @@ func (p *Parser) parseFunctionDeclaration() (ast.Statement, error) {
 	}
 	p.nextToken()
 
-	body, err := p.parseBlock()
+	generator := false
+	body, err := p.parseBody(&generator)
 	if err != nil {
 		return nil, err
 	}
@@ -598,8 +761,124 @@ func (p *Parser) parseFunctionDeclaration() (ast.Statement, error) {
 		p.nextToken()
 	}
 
+	if capabilities != nil {
+		capabilities = padParamAnnotations(capabilities, len(parameters))
+	}
+	if paramTypes != nil {
+		paramTypes = padParamAnnotations(paramTypes, len(parameters))
+	}
+
 	return &ast.FunctionDecl{
-		Name:       nameToken.Value,
+		Name:              nameToken.Value,
+		Parameters:        parameters,
+		ParamCapabilities: capabilities,
+		ParamTypes:        paramTypes,
+		ReturnType:        returnType,
+		Defaults:          defaults,
+		Variadic:          variadic,
+		Generator:         generator,
+		Body:              body,
+		Line:              funcLine,
+	}, nil
+}
+
+// isReturnsWord reports whether tok is the word "returns", which introduces the
+// result expression of a short-form function literal.
+func isReturnsWord(tok token.Token) bool {
+	return tok.Type == token.IDENTIFIER && strings.ToLower(tok.Value) == "returns"
+}
+
+// parseFunctionLiteral parses an anonymous function used as a value. It is
+// called from parsePrimary with curToken on FUNCTION (after "a"/"an").
+//
+//	a function that takes x and returns x * 2
+//	a function that takes x and y and does the following: ... thats it
+//
+// The block form consumes "thats it" but leaves the period to the enclosing
+// statement, so "Declare f to be a function ... thats it." reads naturally.
+func (p *Parser) parseFunctionLiteral() (ast.Expression, error) {
+	funcLine := p.curToken.Line
+	p.nextToken() // consume FUNCTION
+
+	if p.curToken.Type == token.THAT {
+		p.nextToken()
+	}
+
+	var parameters []string
+	if p.curToken.Type == token.TAKES {
+		p.nextToken()
+		for {
+			if p.curToken.Type != token.IDENTIFIER {
+				return nil, p.syntaxErr(msgParameterName, hintFunctionLiteral)
+			}
+			parameters = append(parameters, p.curToken.Value)
+			p.nextToken()
+
+			if p.curToken.Type != token.AND {
+				break
+			}
+			if p.peekToken.Type == token.DOES || isReturnsWord(p.peekToken) {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
+	if p.curToken.Type == token.AND {
+		p.nextToken()
+	}
+
+	// Short form: "returns <expression>"
+	if isReturnsWord(p.curToken) {
+		retLine := p.curToken.Line
+		p.nextToken()
+		value, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.FunctionLiteral{
+			Parameters: parameters,
+			Body:       []ast.Statement{&ast.ReturnStatement{Value: value, Line: retLine}},
+			Line:       funcLine,
+		}, nil
+	}
+
+	// Block form: "does the following: ... thats it"
+	if p.curToken.Type != token.DOES {
+		return nil, p.syntaxErr(msgFunctionLiteralBody, hintFunctionLiteral)
+	}
+	p.nextToken()
+
+	if err := p.expectToken(token.THE); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	if err := p.expectToken(token.FOLLOWING); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	if err := p.expectToken(token.COLON); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	body, err := p.parseBody(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectToken(token.THATS); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectToken(token.IT); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &ast.FunctionLiteral{
 		Parameters: parameters,
 		Body:       body,
 		Line:       funcLine,
@@ -638,6 +917,32 @@ func (p *Parser) parseAssignment() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	// "Set OBJECT's FIELD to be VALUE." — struct field write. The lexer keeps
+	// the 's on the identifier, as in "p2's".
+	if objectName, ok := strings.CutSuffix(nameToken.Value, "'s"); ok && objectName != "" {
+		if p.curToken.Type != token.IDENTIFIER {
+			return nil, p.syntaxErr(msgPossessive, hintSetFieldFull)
+		}
+		field := p.curToken.Value
+		p.nextToken()
+		if err := p.expectToken(token.TO); err != nil {
+			return nil, err
+		}
+		p.nextToken()
+		if p.curToken.Type == token.BE {
+			p.nextToken()
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectToken(token.PERIOD); err != nil {
+			return nil, err
+		}
+		p.nextToken()
+		return &ast.FieldAssignment{ObjectName: objectName, Field: field, Value: value}, nil
+	}
+
 	// "Set TABLE at KEY to be VALUE." — lookup table shorthand write
 	if p.curToken.Type == token.AT {
 		p.nextToken() // consume AT
@@ -666,6 +971,16 @@ func (p *Parser) parseAssignment() (ast.Statement, error) {
 		return &ast.LookupKeyAssignment{TableName: nameToken.Value, Key: key, Value: value, Line: setLine}, nil
 	}
 
+	// "Set q and r to …" — destructuring assignment
+	var names []string
+	var rest bool
+	if p.curToken.Type == token.AND || p.curToken.Type == token.COMMA {
+		var err error
+		if names, rest, err = p.parseMoreNames(nameToken.Value); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := p.expectToken(token.TO); err != nil {
 		return nil, err
 	}
@@ -676,46 +991,7 @@ func (p *Parser) parseAssignment() (ast.Statement, error) {
 		p.nextToken()
 	}
 
-	// Check for function call result: "the result of calling ..."
-	if p.curToken.Type == token.THE && p.peekToken.Type == token.IDENTIFIER && strings.EqualFold(p.peekToken.Value, resultKeyword) {
-		p.nextToken() // consume THE
-		p.nextToken() // consume "result"
-		if p.curToken.Type == token.OF {
-			p.nextToken()
-			if p.curToken.Type == token.CALLING {
-				p.nextToken()
-				funcName := p.curToken.Value
-				if p.curToken.Type != token.IDENTIFIER {
-					return nil, p.syntaxErr(
-						msgSetCallFuncName,
-						hintSetCallResult,
-					)
-				}
-				p.nextToken()
-
-				args, err := p.parseFunctionArguments()
-				if err != nil {
-					return nil, err
-				}
-
-				if err := p.expectToken(token.PERIOD); err != nil {
-					return nil, err
-				}
-				p.nextToken()
-
-				return &ast.Assignment{
-					Name: nameToken.Value,
-					Value: &ast.FunctionCall{
-						Name:      funcName,
-						Arguments: args,
-					},
-					Line: setLine,
-				}, nil
-			}
-		}
-	}
-
-	value, err := p.parseExpression()
+	value, err := p.parseAssignedValue()
 	if err != nil {
 		return nil, err
 	}
@@ -725,6 +1001,9 @@ func (p *Parser) parseAssignment() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	if names != nil {
+		return &ast.Assignment{Names: names, Rest: rest, Value: value, Line: setLine}, nil
+	}
 	return &ast.Assignment{
 		Name:  nameToken.Value,
 		Value: value,
@@ -732,6 +1011,96 @@ func (p *Parser) parseAssignment() (ast.Statement, error) {
 	}, nil
 }
 
+// parseAssignedValue parses the value given to a variable by a declaration
+// or assignment: "the result of calling f with …" or any expression.
+func (p *Parser) parseAssignedValue() (ast.Expression, error) {
+	if p.curToken.Type != token.THE || !strings.EqualFold(p.peekToken.Value, resultKeyword) ||
+		p.tokenAt(p.position).Type != token.OF || p.tokenAt(p.position+1).Type != token.CALLING {
+		return p.parseExpression()
+	}
+	for i := 0; i < 4; i++ {
+		p.nextToken() // consume "the result of calling"
+	}
+	if p.curToken.Type != token.IDENTIFIER {
+		return nil, p.syntaxErr(
+			msgSetCallFuncName,
+			hintSetCallResult,
+		)
+	}
+	funcName := p.curToken.Value
+	p.nextToken()
+
+	// "the result of calling geo's area with 3" calls a member of a module
+	// or a method of a structure.
+	if objectName, ok := strings.CutSuffix(funcName, "'s"); ok && objectName != "" {
+		if p.curToken.Type != token.IDENTIFIER {
+			return nil, p.syntaxErr(
+				fmt.Sprintf(msgFmtCallPossessive, objectName),
+				hintSetCallResult,
+			)
+		}
+		methodName := p.curToken.Value
+		p.nextToken()
+		args, named, err := p.parseFunctionArguments()
+		if err != nil {
+			return nil, err
+		}
+		return &ast.MethodCall{
+			Object:         &ast.Identifier{Name: objectName},
+			MethodName:     methodName,
+			Arguments:      args,
+			NamedArguments: named,
+		}, nil
+	}
+
+	args, named, err := p.parseFunctionArguments()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.FunctionCall{
+		Name:           funcName,
+		Arguments:      args,
+		NamedArguments: named,
+	}, nil
+}
+
+// parseMoreNames reads the names after the first in "q and r" or "a, b and
+// c", the targets of a destructuring declaration or assignment. The last may
+// be written "the rest as r", which rest reports, so that r collects the
+// items left over once the names before it have theirs.
+func (p *Parser) parseMoreNames(first string) (names []string, rest bool, err error) {
+	names = []string{first}
+	for p.curToken.Type == token.AND || p.curToken.Type == token.COMMA {
+		if rest {
+			return nil, false, p.syntaxErr(msgDestructureRestLast, hintDestructureRest)
+		}
+		p.nextToken()
+		if p.curToken.Type == token.AND {
+			p.nextToken() // "a, b, and c"
+		}
+		if p.curToken.Type == token.THE && strings.EqualFold(p.peekToken.Value, restKeyword) {
+			p.nextToken() // consume THE
+			p.nextToken() // consume "rest"
+			if p.curToken.Type != token.AS {
+				return nil, false, p.syntaxErr(msgDestructureRestAs, hintDestructureRest)
+			}
+			p.nextToken()
+			rest = true
+		}
+		if p.curToken.Type != token.IDENTIFIER {
+			return nil, false, p.syntaxErr(msgDestructureName, hintDestructure)
+		}
+		for _, name := range names {
+			if name == p.curToken.Value {
+				return nil, false, p.syntaxErr(fmt.Sprintf(msgFmtDestructureTwice, name), hintDestructure)
+			}
+		}
+		names = append(names, p.curToken.Value)
+		p.nextToken()
+	}
+	return names, rest, nil
+}
+
 // parseIndexAssignment parses "the item at position X in Y to be Z"
 func (p *Parser) parseIndexAssignment(setLine int) (ast.Statement, error) {
 	// Already consumed "Set the", now at "item"
@@ -835,9 +1204,13 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 
 		// Parse optional arguments
 		var args []ast.Expression
+		var named []*ast.NamedArgument
 		if p.curToken.Type == token.WITH {
 			p.nextToken()
-			args = p.parseCallArguments()
+			var err error
+			if args, named, err = p.parseCallArguments(); err != nil {
+				return nil, err
+			}
 		}
 
 		if err := p.expectToken(token.PERIOD); err != nil {
@@ -850,7 +1223,8 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 			MethodCall: &ast.MethodCall{
 				Object:     &ast.Identifier{Name: objectName},
 				MethodName: methodName,
-				Arguments:  args,
+				Arguments:      args,
+				NamedArguments: named,
 			},
 			Line: callLine,
 		}, nil
@@ -873,9 +1247,13 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 
 		// Parse optional arguments
 		var args []ast.Expression
+		var named []*ast.NamedArgument
 		if p.curToken.Type == token.WITH {
 			p.nextToken()
-			args = p.parseCallArguments()
+			var err error
+			if args, named, err = p.parseCallArguments(); err != nil {
+				return nil, err
+			}
 		}
 
 		if err := p.expectToken(token.PERIOD); err != nil {
@@ -888,7 +1266,8 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 			MethodCall: &ast.MethodCall{
 				Object:     &ast.Identifier{Name: objectName},
 				MethodName: methodName,
-				Arguments:  args,
+				Arguments:      args,
+				NamedArguments: named,
 			},
 			Line: callLine,
 		}, nil
@@ -897,10 +1276,14 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 	// Regular function call: "call greet with args."
 	funcName := firstIdent
 	var args []ast.Expression
+	var named []*ast.NamedArgument
 
 	if p.curToken.Type == token.WITH {
 		p.nextToken()
-		args = p.parseCallArguments()
+		var err error
+		if args, named, err = p.parseCallArguments(); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := p.expectToken(token.PERIOD); err != nil {
@@ -910,23 +1293,33 @@ func (p *Parser) parseCall() (ast.Statement, error) {
 
 	return &ast.CallStatement{
 		FunctionCall: &ast.FunctionCall{
-			Name:      funcName,
-			Arguments: args,
+			Name:           funcName,
+			Arguments:      args,
+			NamedArguments: named,
 		},
 		Line: callLine,
 	}, nil
 }
 
-// parseCallArguments parses comma-separated call arguments
-func (p *Parser) parseCallArguments() []ast.Expression {
+// parseCallArguments parses comma-separated call arguments. Arguments
+// written "name as value" are named, and come after the positional ones.
+func (p *Parser) parseCallArguments() ([]ast.Expression, []*ast.NamedArgument, error) {
 	var args []ast.Expression
+	var named []*ast.NamedArgument
 
 	for {
-		arg, err := p.parseExpression()
+		arg, namedArg, err := p.parseArgument(named)
 		if err != nil {
+			if namedArg != nil {
+				return nil, nil, err
+			}
 			break
 		}
-		args = append(args, arg)
+		if namedArg != nil {
+			named = append(named, namedArg)
+		} else {
+			args = append(args, arg)
+		}
 
 		if p.curToken.Type != token.AND && p.curToken.Type != token.COMMA {
 			break
@@ -934,7 +1327,31 @@ func (p *Parser) parseCallArguments() []ast.Expression {
 		p.nextToken()
 	}
 
-	return args
+	return args, named, nil
+}
+
+// parseArgument parses one call argument, either a plain expression or a
+// named "greeting as \"Hi\"" one. named holds the named arguments already
+// parsed; a plain argument after them is an error, which is returned together
+// with the last named argument so callers can tell it from an ordinary parse
+// failure.
+func (p *Parser) parseArgument(named []*ast.NamedArgument) (ast.Expression, *ast.NamedArgument, error) {
+	if p.curToken.Type == token.IDENTIFIER && p.peekToken.Type == token.AS {
+		name := p.curToken.Value
+		p.nextToken()
+		p.nextToken()
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &ast.NamedArgument{Name: name, Value: value}, nil
+	}
+	if len(named) > 0 {
+		last := named[len(named)-1]
+		return nil, last, p.syntaxErr(fmt.Sprintf(msgFmtPositionalAfterNamed, last.Name), hintNamedArgument)
+	}
+	arg, err := p.parseExpression()
+	return arg, nil, err
 }
 
 func (p *Parser) parseIfStatement() (ast.Statement, error) {
@@ -949,76 +1366,262 @@ func (p *Parser) parseIfStatement() (ast.Statement, error) {
 		return nil, err
 	}
 
-	if err := p.expectToken(token.COMMA); err != nil {
-		return nil, err
+	if err := p.expectToken(token.COMMA); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	if err := p.expectToken(token.THEN); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	thenBody, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var elseIfParts []*ast.ElseIfPart
+	var elseBody []ast.Statement
+
+	for p.curToken.Type == token.OTHERWISE {
+		p.nextToken()
+		if p.curToken.Type == token.IF {
+			p.nextToken()
+			eifCond, err := p.parseComparison()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectToken(token.COMMA); err != nil {
+				return nil, err
+			}
+			p.nextToken()
+			if err := p.expectToken(token.THEN); err != nil {
+				return nil, err
+			}
+			p.nextToken()
+			eifBody, err := p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+			elseIfParts = append(elseIfParts, &ast.ElseIfPart{
+				Condition: eifCond,
+				Body:      eifBody,
+			})
+		} else {
+			elseBody, err = p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if p.curToken.Type == token.THATS {
+		p.nextToken()
+		if err := p.expectToken(token.IT); err != nil {
+			return nil, err
+		}
+		p.nextToken()
+		if err := p.expectToken(token.PERIOD); err != nil {
+			return nil, err
+		}
+		p.nextToken()
+	}
+
+	return &ast.IfStatement{
+		Condition: condition,
+		Then:      thenBody,
+		ElseIf:    elseIfParts,
+		Else:      elseBody,
+		Line:      startLine,
+	}, nil
+}
+
+// parseWhenStatement parses a multi-way branch:
+//
+//	When <subject> is <patterns>:
+//	    …
+//	When it is <patterns>:
+//	    …
+//	Otherwise:
+//	    …
+//	thats it.
+func (p *Parser) parseWhenStatement() (ast.Statement, error) {
+	startLine := p.curToken.Line
+	p.nextToken() // consume WHEN
+
+	subject, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []*ast.WhenCase
+	for {
+		patterns, err := p.parseWhenPatterns()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectToken(token.COLON); err != nil {
+			return nil, err
+		}
+		p.nextToken()
+		body, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, &ast.WhenCase{Patterns: patterns, Body: body})
+
+		if p.curToken.Type != token.WHEN {
+			break
+		}
+		p.nextToken() // consume WHEN
+		p.nextToken() // consume IT
+	}
+
+	var otherwise []ast.Statement
+	if p.curToken.Type == token.OTHERWISE {
+		p.nextToken()
+		if p.curToken.Type == token.COLON {
+			p.nextToken()
+		}
+		otherwise, err = p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.expectToken(token.THATS); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectToken(token.IT); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &ast.WhenStatement{
+		Subject:   subject,
+		Cases:     cases,
+		Otherwise: otherwise,
+		Line:      startLine,
+	}, nil
+}
+
+// parseWhenPatterns parses the "is …" part of a When case: one or more
+// patterns separated by commas or "or", optionally introduced by "one of".
+// "is nothing", "is true" and "is false" arrive as single tokens.
+func (p *Parser) parseWhenPatterns() ([]ast.WhenPattern, error) {
+	switch p.curToken.Type {
+	case token.IS_NOTHING_OP, token.IS_TRUE, token.IS_FALSE:
+		var value ast.Expression = &ast.NothingLiteral{}
+		if p.curToken.Type != token.IS_NOTHING_OP {
+			value = &ast.BooleanLiteral{Value: p.curToken.Type == token.IS_TRUE}
+		}
+		p.nextToken()
+		patterns := []ast.WhenPattern{&ast.ValuePattern{Value: value}}
+		if p.curToken.Type != token.COMMA && p.curToken.Type != token.OR {
+			return patterns, nil
+		}
+		p.skipPatternSeparator()
+		more, err := p.parsePatternList()
+		if err != nil {
+			return nil, err
+		}
+		return append(patterns, more...), nil
+	case token.IS:
+		p.nextToken()
+	default:
+		return nil, p.syntaxErr(
+			fmt.Sprintf(msgFmtWhenIs, p.curToken.Value),
+			hintWhenStatement,
+		)
+	}
+
+	if p.curToken.Type == token.IDENTIFIER && strings.ToLower(p.curToken.Value) == "one" &&
+		p.peekToken.Type == token.OF {
+		p.nextToken() // consume "one"
+		p.nextToken() // consume OF
 	}
-	p.nextToken()
+	return p.parsePatternList()
+}
 
-	if err := p.expectToken(token.THEN); err != nil {
-		return nil, err
+// parsePatternList parses patterns separated by ",", "or" or ", or".
+func (p *Parser) parsePatternList() ([]ast.WhenPattern, error) {
+	var patterns []ast.WhenPattern
+	for {
+		pattern, err := p.parseWhenPattern()
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+		if p.curToken.Type != token.COMMA && p.curToken.Type != token.OR {
+			return patterns, nil
+		}
+		p.skipPatternSeparator()
 	}
-	p.nextToken()
+}
 
-	thenBody, err := p.parseBlock()
-	if err != nil {
-		return nil, err
+func (p *Parser) skipPatternSeparator() {
+	if p.curToken.Type == token.COMMA {
+		p.nextToken()
 	}
-
-	var elseIfParts []*ast.ElseIfPart
-	var elseBody []ast.Statement
-
-	for p.curToken.Type == token.OTHERWISE {
+	if p.curToken.Type == token.OR {
 		p.nextToken()
-		if p.curToken.Type == token.IF {
+	}
+}
+
+// parseWhenPattern parses a single pattern: "between <low> and <high>",
+// "a <type name>", or a value.
+func (p *Parser) parseWhenPattern() (ast.WhenPattern, error) {
+	if p.curToken.Type == token.IDENTIFIER {
+		switch strings.ToLower(p.curToken.Value) {
+		case "between":
 			p.nextToken()
-			eifCond, err := p.parseComparison()
+			low, err := p.parseExpression()
 			if err != nil {
 				return nil, err
 			}
-			if err := p.expectToken(token.COMMA); err != nil {
+			if err := p.expectToken(token.AND); err != nil {
 				return nil, err
 			}
 			p.nextToken()
-			if err := p.expectToken(token.THEN); err != nil {
+			high, err := p.parseExpression()
+			if err != nil {
 				return nil, err
 			}
+			return &ast.RangePattern{Low: low, High: high}, nil
+		case "a", "an":
+			if p.peekToken.Type != token.IDENTIFIER && !token.IsKeyword(p.peekToken.Type) {
+				break
+			}
 			p.nextToken()
-			eifBody, err := p.parseBlock()
-			if err != nil {
-				return nil, err
+			if p.curToken.Type == token.IDENTIFIER {
+				name := p.curToken.Value
+				p.nextToken()
+				if strings.EqualFold(name, "whole") && strings.EqualFold(p.curToken.Value, "number") {
+					name = "whole number"
+					p.nextToken()
+				}
+				return &ast.TypePattern{TypeName: name}, nil
 			}
-			elseIfParts = append(elseIfParts, &ast.ElseIfPart{
-				Condition: eifCond,
-				Body:      eifBody,
-			})
-		} else {
-			elseBody, err = p.parseBlock()
-			if err != nil {
-				return nil, err
+			name := p.parseTypeName()
+			if name == "lookup" && p.curToken.Type == token.TABLE {
+				p.nextToken()
+				name = "lookup table"
 			}
-			break
+			return &ast.TypePattern{TypeName: name}, nil
 		}
 	}
-
-	if p.curToken.Type == token.THATS {
-		p.nextToken()
-		if err := p.expectToken(token.IT); err != nil {
-			return nil, err
-		}
-		p.nextToken()
-		if err := p.expectToken(token.PERIOD); err != nil {
-			return nil, err
-		}
-		p.nextToken()
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
 	}
-
-	return &ast.IfStatement{
-		Condition: condition,
-		Then:      thenBody,
-		ElseIf:    elseIfParts,
-		Else:      elseBody,
-		Line:      startLine,
-	}, nil
+	return &ast.ValuePattern{Value: value}, nil
 }
 
 func (p *Parser) parseRepeat() (ast.Statement, error) {
@@ -1032,6 +1635,10 @@ func (p *Parser) parseRepeat() (ast.Statement, error) {
 	if p.curToken.Type == token.FOREVER {
 		p.nextToken()
 
+		label, err := p.parseLoopLabel()
+		if err != nil {
+			return nil, err
+		}
 		if err := p.expectToken(token.COLON); err != nil {
 			return nil, err
 		}
@@ -1057,6 +1664,7 @@ func (p *Parser) parseRepeat() (ast.Statement, error) {
 		return &ast.WhileLoop{
 			Condition: &ast.BooleanLiteral{Value: true},
 			Body:      body,
+			Label:     label,
 			Line:      startLine,
 		}, nil
 	}
@@ -1078,6 +1686,10 @@ func (p *Parser) parseRepeat() (ast.Statement, error) {
 		if err != nil {
 			return nil, err
 		}
+		label, err := p.parseLoopLabel()
+		if err != nil {
+			return nil, err
+		}
 
 		if err := p.expectToken(token.COLON); err != nil {
 			return nil, err
@@ -1104,6 +1716,7 @@ func (p *Parser) parseRepeat() (ast.Statement, error) {
 		return &ast.WhileLoop{
 			Condition: condition,
 			Body:      body,
+			Label:     label,
 			Line:      startLine,
 		}, nil
 	}
@@ -1118,6 +1731,10 @@ func (p *Parser) parseRepeat() (ast.Statement, error) {
 		return nil, err
 	}
 	p.nextToken()
+	label, err := p.parseLoopLabel()
+	if err != nil {
+		return nil, err
+	}
 
 	if err := p.expectToken(token.COLON); err != nil {
 		return nil, err
@@ -1144,10 +1761,36 @@ func (p *Parser) parseRepeat() (ast.Statement, error) {
 	return &ast.ForLoop{
 		Count: countExpr,
 		Body:  body,
+		Label: label,
 		Line:  startLine,
 	}, nil
 }
 
+// parseLoopLabel parses the name a loop may be given just before its colon,
+// "and call this loop outer", and returns it; it returns "" when the loop
+// has no name.
+func (p *Parser) parseLoopLabel() (string, error) {
+	if p.curToken.Type != token.AND || p.peekToken.Type != token.CALL {
+		return "", nil
+	}
+	p.nextToken() // consume AND
+	p.nextToken() // consume CALL
+	if p.curToken.Type != token.IDENTIFIER || !strings.EqualFold(p.curToken.Value, "this") {
+		return "", p.syntaxErr(msgLoopLabel, hintLoopLabel)
+	}
+	p.nextToken()
+	if p.curToken.Type != token.LOOP {
+		return "", p.syntaxErr(msgLoopLabel, hintLoopLabel)
+	}
+	p.nextToken()
+	if p.curToken.Type != token.IDENTIFIER {
+		return "", p.syntaxErr(msgLoopLabel, hintLoopLabel)
+	}
+	label := p.curToken.Value
+	p.nextToken()
+	return label, nil
+}
+
 func (p *Parser) parseForEach() (ast.Statement, error) {
 	if err := p.expectToken(token.FOR); err != nil {
 		return nil, err
@@ -1203,6 +1846,10 @@ func (p *Parser) parseForEach() (ast.Statement, error) {
 		return nil, err
 	}
 	p.nextToken()
+	label, err := p.parseLoopLabel()
+	if err != nil {
+		return nil, err
+	}
 
 	if err := p.expectToken(token.COLON); err != nil {
 		return nil, err
@@ -1227,10 +1874,11 @@ func (p *Parser) parseForEach() (ast.Statement, error) {
 	}
 
 	return &ast.ForEachLoop{
-		Item: itemName,
-		List: listExpr,
-		Body: body,
-		Line: itemToken.Line,
+		Item:  itemName,
+		List:  listExpr,
+		Body:  body,
+		Label: label,
+		Line:  itemToken.Line,
 	}, nil
 }
 
@@ -1288,11 +1936,31 @@ func (p *Parser) parseReturn() (ast.Statement, error) {
 		return nil, err
 	}
 
+	// "Return q and r." returns both values together
+	var values []ast.Expression
+	for p.curToken.Type == token.AND || p.curToken.Type == token.COMMA {
+		p.nextToken()
+		if p.curToken.Type == token.AND {
+			p.nextToken() // "a, b, and c"
+		}
+		next, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			values = []ast.Expression{value}
+		}
+		values = append(values, next)
+	}
+
 	if err := p.expectToken(token.PERIOD); err != nil {
 		return nil, err
 	}
 	p.nextToken()
 
+	if values != nil {
+		return &ast.ReturnStatement{Values: values, Line: startLine}, nil
+	}
 	return &ast.ReturnStatement{
 		Value: value,
 		Line:  startLine,
@@ -1303,6 +1971,7 @@ func (p *Parser) parseBreak() (ast.Statement, error) {
 	if err := p.expectToken(token.BREAK); err != nil {
 		return nil, err
 	}
+	line := p.curToken.Line
 	p.nextToken()
 
 	if err := p.expectToken(token.OUT); err != nil {
@@ -1315,6 +1984,14 @@ func (p *Parser) parseBreak() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	// "Break out of outer." leaves the loop called outer
+	if p.curToken.Type == token.IDENTIFIER && p.peekToken.Type == token.PERIOD {
+		label := p.curToken.Value
+		p.nextToken()
+		p.nextToken()
+		return &ast.BreakStatement{Label: label, Line: line}, nil
+	}
+
 	// Accept "the" or "this" (as IDENTIFIER)
 	if p.curToken.Type == token.THE {
 		p.nextToken()
@@ -1337,15 +2014,24 @@ func (p *Parser) parseBreak() (ast.Statement, error) {
 	}
 	p.nextToken()
 
-	return &ast.BreakStatement{}, nil
+	return &ast.BreakStatement{Line: line}, nil
 }
 
 // parseContinue parses a continue statement:
 //   - "Continue." or "Skip."
 //   - "Continue the loop." or "Skip the loop."
+//   - "Continue outer." to go on with the next iteration of the loop called outer
 func (p *Parser) parseContinue() (ast.Statement, error) {
+	line := p.curToken.Line
 	p.nextToken() // consume CONTINUE or SKIP
 
+	if p.curToken.Type == token.IDENTIFIER && p.peekToken.Type == token.PERIOD {
+		label := p.curToken.Value
+		p.nextToken()
+		p.nextToken()
+		return &ast.ContinueStatement{Label: label, Line: line}, nil
+	}
+
 	// Optional "the loop"
 	if p.curToken.Type == token.THE {
 		p.nextToken() // consume THE
@@ -1359,7 +2045,7 @@ func (p *Parser) parseContinue() (ast.Statement, error) {
 	}
 	p.nextToken()
 
-	return &ast.ContinueStatement{}, nil
+	return &ast.ContinueStatement{Line: line}, nil
 }
 
 // parseAskStatement parses an ask statement for user input:
@@ -1441,6 +2127,7 @@ func (p *Parser) parseBlock() ([]ast.Statement, error) {
 		p.curToken.Type != token.OTHERWISE &&
 		p.curToken.Type != token.ON &&
 		p.curToken.Type != token.BUT &&
+		!(p.curToken.Type == token.WHEN && p.peekToken.Type == token.IT) &&
 		p.curToken.Type != token.EOF {
 		stmt, err := p.parseStatement()
 		if err != nil {
@@ -1466,6 +2153,9 @@ func (p *Parser) parseLogical() (ast.Expression, error) {
 	}
 
 	for p.curToken.Type == token.AND || p.curToken.Type == token.OR {
+		if p.curToken.Type == token.AND && p.peekToken.Type == token.CALL {
+			break // "and call this loop …" names the loop being parsed
+		}
 		op := "and"
 		if p.curToken.Type == token.OR {
 			op = "or"
@@ -1496,6 +2186,13 @@ func (p *Parser) parseRelational() (ast.Expression, error) {
 	case token.IS_EQUAL_TO, token.IS_LESS_THAN, token.IS_GREATER_THAN,
 		token.IS_LESS_EQUAL, token.IS_GREATER_EQUAL, token.IS_NOT_EQUAL:
 		op := p.curToken.Value
+		// "is at least" and "is at most" are other ways to say these two.
+		switch p.curToken.Type {
+		case token.IS_LESS_EQUAL:
+			op = "is less than or equal to"
+		case token.IS_GREATER_EQUAL:
+			op = "is greater than or equal to"
+		}
 		p.nextToken()
 		right, err := p.parseCast()
 		if err != nil {
@@ -1554,8 +2251,13 @@ func (p *Parser) parseRelational() (ast.Expression, error) {
 		}, nil
 
 	case token.IS:
-		// "error is TypeName" — error type check (exact or inherited match)
+		// "error is TypeName" — error type check (exact or inherited match),
+		// or "pet is a Animal" for a structure and the ones it inherits from
 		p.nextToken()
+		if p.curToken.Type == token.IDENTIFIER && p.peekToken.Type == token.IDENTIFIER &&
+			(strings.ToLower(p.curToken.Value) == "a" || strings.ToLower(p.curToken.Value) == "an") {
+			p.nextToken()
+		}
 		if p.curToken.Type != token.IDENTIFIER {
 			return nil, p.syntaxErr(
 				msgErrorTypeIsName,
@@ -1585,7 +2287,7 @@ func isPossessiveMethodNameToken(t token.Type) bool {
 //   - "cast to <type>" / "casted to <type>" — explicit type conversion
 //   - "has <key>"                            — lookup table key check
 func (p *Parser) parseCast() (ast.Expression, error) {
-	expr, err := p.parseAdditive()
+	expr, err := p.parseBitwise()
 	if err != nil {
 		return nil, err
 	}
@@ -1643,11 +2345,15 @@ func (p *Parser) parseCast() (ast.Expression, error) {
 		methodName := p.curToken.Value
 		p.nextToken()
 		var args []ast.Expression
+		var named []*ast.NamedArgument
 		if p.curToken.Type == token.WITH {
 			p.nextToken()
-			args = p.parseCallArguments()
+			var err error
+			if args, named, err = p.parseCallArguments(); err != nil {
+				return nil, err
+			}
 		}
-		return &ast.MethodCall{Object: expr, MethodName: methodName, Arguments: args}, nil
+		return &ast.MethodCall{Object: expr, MethodName: methodName, Arguments: args, NamedArguments: named}, nil
 	}
 
 	return expr, nil
@@ -1680,6 +2386,11 @@ func (p *Parser) parseTypeName() string {
 	if name != "" {
 		p.nextToken()
 	}
+	// Handle "whole number"
+	if name == "whole" && strings.EqualFold(p.curToken.Value, "number") {
+		p.nextToken()
+		name = "whole number"
+	}
 	return name
 }
 
@@ -1709,19 +2420,82 @@ func (p *Parser) parseAdditive() (ast.Expression, error) {
 	return left, nil
 }
 
+// parseBitwise parses "bitwise and", "bitwise or" and "bitwise xor", which
+// share one precedence level and bind more loosely than shifts.
+func (p *Parser) parseBitwise() (ast.Expression, error) {
+	left, err := p.parseShift()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		var op string
+		switch p.curToken.Type {
+		case token.BIT_AND:
+			op = "&"
+		case token.BIT_OR:
+			op = "|"
+		case token.BIT_XOR:
+			op = "^"
+		default:
+			return left, nil
+		}
+		p.nextToken()
+		right, err := p.parseShift()
+		if err != nil {
+			return nil, err
+		}
+		left = &ast.BinaryExpression{
+			Left:     left,
+			Operator: op,
+			Right:    right,
+		}
+	}
+}
+
+// parseShift parses "shifted left by" and "shifted right by".
+func (p *Parser) parseShift() (ast.Expression, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.curToken.Type == token.SHIFT_LEFT || p.curToken.Type == token.SHIFT_RIGHT {
+		op := "<<"
+		if p.curToken.Type == token.SHIFT_RIGHT {
+			op = ">>"
+		}
+		p.nextToken()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &ast.BinaryExpression{
+			Left:     left,
+			Operator: op,
+			Right:    right,
+		}
+	}
+
+	return left, nil
+}
+
 func (p *Parser) parseMultiplicative() (ast.Expression, error) {
-	left, err := p.parsePrimary()
+	left, err := p.parsePower()
 	if err != nil {
 		return nil, err
 	}
 
-	for p.curToken.Type == token.STAR || p.curToken.Type == token.SLASH {
+	for p.curToken.Type == token.STAR || p.curToken.Type == token.SLASH || p.curToken.Type == token.FLOOR_DIVIDE {
 		op := "*"
-		if p.curToken.Type == token.SLASH {
+		switch p.curToken.Type {
+		case token.SLASH:
 			op = "/"
+		case token.FLOOR_DIVIDE:
+			op = "//"
 		}
 		p.nextToken()
-		right, err := p.parsePrimary()
+		right, err := p.parsePower()
 		if err != nil {
 			return nil, err
 		}
@@ -1735,6 +2509,97 @@ func (p *Parser) parseMultiplicative() (ast.Expression, error) {
 	return left, nil
 }
 
+// parsePower parses "to the power of". It binds tighter than multiplication
+// and is right-associative: 2 to the power of 3 to the power of 2 is 2^9.
+func (p *Parser) parsePower() (ast.Expression, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if p.curToken.Type != token.POWER {
+		return base, nil
+	}
+	p.nextToken()
+	exponent, err := p.parsePower()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.BinaryExpression{
+		Left:     base,
+		Operator: "**",
+		Right:    exponent,
+	}, nil
+}
+
+// parseDecimalLiteral parses the rest of "the decimal 19.99" or "the whole
+// number 42" once "the" has been consumed. ok is false, with nothing
+// consumed, when the words that follow are not one of those forms.
+func (p *Parser) parseDecimalLiteral() (ast.Expression, bool, error) {
+	if p.curToken.Type != token.IDENTIFIER {
+		return nil, false, nil
+	}
+	next := p.peekToken
+	whole := false
+	switch strings.ToLower(p.curToken.Value) {
+	case "decimal":
+	case "whole":
+		if next.Type != token.IDENTIFIER || strings.ToLower(next.Value) != "number" {
+			return nil, false, nil
+		}
+		whole = true
+		next = p.tokenAt(p.position)
+	default:
+		return nil, false, nil
+	}
+	if next.Type != token.NUMBER && next.Type != token.MINUS {
+		return nil, false, nil
+	}
+	p.nextToken() // consume "decimal" / "whole"
+	if whole {
+		p.nextToken() // consume "number"
+	}
+	sign := ""
+	if p.curToken.Type == token.MINUS {
+		sign = "-"
+		p.nextToken()
+	}
+	if p.curToken.Type != token.NUMBER || (whole && strings.Contains(p.curToken.Value, ".")) {
+		kind := "decimal"
+		if whole {
+			kind = "whole number"
+		}
+		return nil, true, p.syntaxErr(fmt.Sprintf(msgFmtDecimalLiteral, kind), hintDecimalLiteral)
+	}
+	digits := sign + p.curToken.Value
+	p.nextToken()
+	return &ast.DecimalLiteral{Digits: digits, Whole: whole}, true, nil
+}
+
+// parseInterpolatedString parses text containing "{expression}" parts. Each
+// embedded expression is lexed and parsed on its own, and must be a single
+// complete expression.
+func (p *Parser) parseInterpolatedString() (ast.Expression, error) {
+	pieces, err := tokeniser.SplitInterpolation(p.curToken.Value)
+	if err != nil {
+		return nil, p.syntaxErr(fmt.Sprintf(msgFmtInterpolation, err), hintInterpolation)
+	}
+	parts := make([]ast.Expression, 0, len(pieces))
+	for _, piece := range pieces {
+		if !piece.IsExpr {
+			parts = append(parts, &ast.StringLiteral{Value: piece.Text})
+			continue
+		}
+		sub := NewParser(NewLexer(piece.Text).TokenizeAll())
+		expr, err := sub.parseExpression()
+		if err != nil || sub.curToken.Type != token.EOF {
+			return nil, p.syntaxErr(fmt.Sprintf(msgFmtInterpolatedExpr, strings.TrimSpace(piece.Text)), hintInterpolation)
+		}
+		parts = append(parts, expr)
+	}
+	p.nextToken()
+	return &ast.InterpolatedString{Parts: parts}, nil
+}
+
 func (p *Parser) parsePrimary() (ast.Expression, error) {
 	switch p.curToken.Type {
 	case token.NUMBER:
@@ -1747,6 +2612,9 @@ func (p *Parser) parsePrimary() (ast.Expression, error) {
 		p.nextToken()
 		return &ast.StringLiteral{Value: value}, nil
 
+	case token.INTERPOLATED_STRING:
+		return p.parseInterpolatedString()
+
 	case token.TRUE:
 		p.nextToken()
 		return &ast.BooleanLiteral{Value: true}, nil
@@ -1822,6 +2690,10 @@ func (p *Parser) parsePrimary() (ast.Expression, error) {
 		if p.curToken.Type == token.ENTRY {
 			return p.parseLookupKeyAccess()
 		}
+		// "the decimal 19.99" / "the whole number 12345678901234567890"
+		if lit, ok, err := p.parseDecimalLiteral(); ok {
+			return lit, err
+		}
 		// Check for field access: "the name of person"
 		if p.curToken.Type == token.IDENTIFIER {
 			fieldName := p.curToken.Value
@@ -1893,6 +2765,11 @@ func (p *Parser) parsePrimary() (ast.Expression, error) {
 				}
 				return &ast.LookupTableLiteral{}, nil
 			}
+			if isWord(p.curToken, "channel") {
+				// "a channel"
+				p.nextToken()
+				return &ast.ChannelLiteral{}, nil
+			}
 			if p.curToken.Type == token.ARRAY {
 				// "an array of [elements]" or "an array of number [elements]"
 				return p.parseArrayLiteral()
@@ -1901,6 +2778,10 @@ func (p *Parser) parsePrimary() (ast.Expression, error) {
 				// "a range from X to Y"
 				return p.parseRangeExpression()
 			}
+			if p.curToken.Type == token.FUNCTION {
+				// "a function that takes x and returns x * 2"
+				return p.parseFunctionLiteral()
+			}
 			// Not a special phrase, treat "a"/"an" as identifier
 			return &ast.Identifier{Name: name}, nil
 		}
@@ -1915,14 +2796,19 @@ func (p *Parser) parsePrimary() (ast.Expression, error) {
 				methodName := p.curToken.Value
 				p.nextToken()
 				var args []ast.Expression
+				var named []*ast.NamedArgument
 				if p.curToken.Type == token.WITH {
 					p.nextToken()
-					args = p.parseCallArguments()
+					var err error
+					if args, named, err = p.parseCallArguments(); err != nil {
+						return nil, err
+					}
 				}
 				return &ast.MethodCall{
-					Object:     &ast.Identifier{Name: objectName},
-					MethodName: methodName,
-					Arguments:  args,
+					Object:         &ast.Identifier{Name: objectName},
+					MethodName:     methodName,
+					Arguments:      args,
+					NamedArguments: named,
 				}, nil
 			}
 			// No method name after possessive — treat as plain identifier
@@ -2371,17 +3257,22 @@ func (p *Parser) parseRangeExpression() (ast.Expression, error) {
 	return &ast.RangeLiteral{Start: startExpr, End: endExpr, Step: stepExpr}, nil
 }
 
-func (p *Parser) parseFunctionArguments() ([]ast.Expression, error) {
+func (p *Parser) parseFunctionArguments() ([]ast.Expression, []*ast.NamedArgument, error) {
 	var args []ast.Expression
+	var named []*ast.NamedArgument
 
 	if p.curToken.Type == token.WITH {
 		p.nextToken()
 		for {
-			arg, err := p.parseExpression()
+			arg, namedArg, err := p.parseArgument(named)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
+			}
+			if namedArg != nil {
+				named = append(named, namedArg)
+			} else {
+				args = append(args, arg)
 			}
-			args = append(args, arg)
 
 			if p.curToken.Type != token.AND {
 				break
@@ -2390,7 +3281,7 @@ func (p *Parser) parseFunctionArguments() ([]ast.Expression, error) {
 		}
 	}
 
-	return args, nil
+	return args, named, nil
 }
 
 func (p *Parser) parseFunctionCallArgs() ([]ast.Expression, error) {
@@ -2544,6 +3435,77 @@ func (p *Parser) parseLookupKeyAssignment(setLine int) (ast.Statement, error) {
 	return &ast.LookupKeyAssignment{TableName: tableName, Key: key, Value: value, Line: setLine}, nil
 }
 
+// parseOverflowMode parses the statement that chooses how integer arithmetic
+// handles results that do not fit their kind:
+//
+//	Use wrapping arithmetic.
+//	Use checked integer arithmetic.
+//
+// "use", "wrapping", "checked" and "arithmetic" are matched as plain
+// identifiers so that none of them becomes a reserved word.
+func (p *Parser) parseOverflowMode() (ast.Statement, error) {
+	line := p.curToken.Line
+	p.nextToken() // consume "use"
+
+	var wrap bool
+	switch strings.ToLower(p.curToken.Value) {
+	case "wrapping":
+		wrap = true
+	case "checked":
+	default:
+		return nil, p.syntaxErr(msgOverflowMode, hintOverflowMode)
+	}
+	p.nextToken()
+
+	if p.curToken.Type == token.INTEGER {
+		p.nextToken()
+	}
+	if p.curToken.Type != token.IDENTIFIER || !strings.EqualFold(p.curToken.Value, "arithmetic") {
+		return nil, p.syntaxErr(msgOverflowMode, hintOverflowMode)
+	}
+	p.nextToken()
+
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &ast.OverflowModeStatement{Wrap: wrap, Line: line}, nil
+}
+
+// parseBody parses a function or method body. generator is the flag a
+// "Give back" in the body sets, or nil where one is not allowed.
+func (p *Parser) parseBody(generator *bool) ([]ast.Statement, error) {
+	outer := p.generator
+	p.generator = generator
+	defer func() { p.generator = outer }()
+	return p.parseBlock()
+}
+
+// parseGiveBack parses "Give back <expression>.", which makes the function
+// it appears in a generator. "give" and "back" are matched as plain
+// identifiers so that neither becomes a reserved word.
+func (p *Parser) parseGiveBack() (ast.Statement, error) {
+	line := p.curToken.Line
+	if p.generator == nil {
+		return nil, p.syntaxErr(msgGiveBackOutside, hintGiveBack)
+	}
+	p.nextToken() // consume "give"
+	p.nextToken() // consume "back"
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	*p.generator = true
+	return &ast.YieldStatement{Value: value, Line: line}, nil
+}
+
 // parseSleepStatement parses "Sleep for <duration>." and "Wait for <duration>."
 // where duration is either:
 //   - <number><unit>  e.g. 500ms, 2s, 1m, 1h
@@ -2567,6 +3529,7 @@ func (p *Parser) parseLookupKeyAssignment(setLine int) (ast.Statement, error) {
 //	Would you kindly wait for a second.
 func (p *Parser) parseSleepStatement() (ast.Statement, error) {
 	line := p.curToken.Line
+	isWait := strings.EqualFold(p.curToken.Value, "wait")
 	p.nextToken() // consume SLEEP / WAIT
 
 	if p.curToken.Type != token.FOR {
@@ -2579,6 +3542,11 @@ func (p *Parser) parseSleepStatement() (ast.Statement, error) {
 	}
 	p.nextToken() // consume FOR
 
+	// "Wait for job." waits for a background task rather than a duration.
+	if isWait && p.curToken.Type != token.NUMBER && !isWord(p.curToken, "a") && !isWord(p.curToken, "an") {
+		return p.parseWaitForTask(line)
+	}
+
 	var seconds float64
 
 	// Handle the natural-English shorthands: "a second", "an hour", etc.
@@ -2693,6 +3661,8 @@ func stmtLine(stmt ast.Statement) int {
 		return s.Line
 	case *ast.IfStatement:
 		return s.Line
+	case *ast.WhenStatement:
+		return s.Line
 	case *ast.WhileLoop:
 		return s.Line
 	case *ast.ForLoop:
@@ -2703,14 +3673,32 @@ func stmtLine(stmt ast.Statement) int {
 		return s.Line
 	case *ast.ReturnStatement:
 		return s.Line
+	case *ast.YieldStatement:
+		return s.Line
+	case *ast.BackgroundStatement:
+		return s.Line
+	case *ast.WaitStatement:
+		return s.Line
+	case *ast.SendStatement:
+		return s.Line
 	case *ast.RaiseStatement:
 		return s.Line
+	case *ast.TestBlock:
+		return s.Line
+	case *ast.AssertStatement:
+		return s.Line
 	case *ast.TryStatement:
 		return s.Line
 	case *ast.SwapStatement:
 		return s.Line
+	case *ast.OverflowModeStatement:
+		return s.Line
 	case *ast.ToggleStatement:
 		return s.Line
+	case *ast.BreakStatement:
+		return s.Line
+	case *ast.ContinueStatement:
+		return s.Line
 	}
 	return 0
 }
@@ -3,6 +3,8 @@ package parser
 import (
 	"github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/token"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -785,6 +787,42 @@ func TestParserArithmeticExpressions(t *testing.T) {
 	}
 }
 
+func TestParserPowerAndBitwisePrecedence(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Declare x to be 2 * 3 to the power of 2.", "(2 * (3 ** 2))"},
+		{"Declare x to be 2 to the power of 3 to the power of 2.", "(2 ** (3 ** 2))"},
+		{"Declare x to be 7 divided evenly by 2 * 3.", "((7 // 2) * 3)"},
+		{"Declare x to be 1 + 2 shifted left by 3.", "((1 + 2) << 3)"},
+		{"Declare x to be 1 bitwise or 2 shifted left by 3.", "(1 | (2 << 3))"},
+		{"Declare x to be 6 bitwise and 3 bitwise xor 1.", "((6 & 3) ^ 1)"},
+	}
+
+	var render func(e ast.Expression) string
+	render = func(e ast.Expression) string {
+		switch n := e.(type) {
+		case *ast.BinaryExpression:
+			return "(" + render(n.Left) + " " + n.Operator + " " + render(n.Right) + ")"
+		case *ast.NumberLiteral:
+			return strconv.FormatFloat(n.Value, 'f', -1, 64)
+		}
+		return "?"
+	}
+	for _, tt := range tests {
+		program, err := parse(tt.input)
+		if err != nil {
+			t.Errorf("Input %q: parse error: %v", tt.input, err)
+			continue
+		}
+		got := render(program.Statements[0].(*ast.VariableDecl).Value)
+		if got != tt.want {
+			t.Errorf("Input %q: want %s, got %s", tt.input, tt.want, got)
+		}
+	}
+}
+
 func TestParserUnaryExpression(t *testing.T) {
 	input := "Declare x to be -5."
 	program, err := parse(input)
@@ -1146,6 +1184,116 @@ func TestParserFunctionCallWithParens(t *testing.T) {
 	}
 }
 
+func TestParserFunctionLiteral(t *testing.T) {
+	tests := []struct {
+		input     string
+		params    int
+		bodyStmts int
+	}{
+		{"Declare double to be a function that takes x and returns x * 2.", 1, 1},
+		{"Declare add to be a function that takes a and b and returns a + b.", 2, 1},
+		{"Declare hello to be a function that does the following:\n    Print \"hi\".\n    Print \"there\".\nthats it.", 0, 2},
+	}
+
+	for _, test := range tests {
+		program, err := parse(test.input)
+		if err != nil {
+			t.Errorf("Input %q: parse error: %v", test.input, err)
+			continue
+		}
+		decl, ok := program.Statements[0].(*ast.VariableDecl)
+		if !ok {
+			t.Errorf("Input %q: expected *ast.VariableDecl, got %T", test.input, program.Statements[0])
+			continue
+		}
+		fl, ok := decl.Value.(*ast.FunctionLiteral)
+		if !ok {
+			t.Errorf("Input %q: expected *ast.FunctionLiteral, got %T", test.input, decl.Value)
+			continue
+		}
+		if len(fl.Parameters) != test.params {
+			t.Errorf("Input %q: expected %d parameters, got %d", test.input, test.params, len(fl.Parameters))
+		}
+		if len(fl.Body) != test.bodyStmts {
+			t.Errorf("Input %q: expected %d body statements, got %d", test.input, test.bodyStmts, len(fl.Body))
+		}
+	}
+}
+
+func TestParserInterpolatedString(t *testing.T) {
+	program, err := parse(`Print "Hi {name}, you are {age + 1}".`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	output := program.Statements[0].(*ast.OutputStatement)
+	is, ok := output.Values[0].(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("expected *ast.InterpolatedString, got %T", output.Values[0])
+	}
+	if len(is.Parts) != 4 {
+		t.Fatalf("expected 4 parts, got %d", len(is.Parts))
+	}
+	if _, ok := is.Parts[1].(*ast.Identifier); !ok {
+		t.Errorf("expected part 1 to be *ast.Identifier, got %T", is.Parts[1])
+	}
+	if _, ok := is.Parts[3].(*ast.BinaryExpression); !ok {
+		t.Errorf("expected part 3 to be *ast.BinaryExpression, got %T", is.Parts[3])
+	}
+
+	for _, bad := range []string{`Print "Hi {name".`, `Print "Hi {1 +}".`, `Print "Hi {x y}".`} {
+		if _, err := parse(bad); err == nil {
+			t.Errorf("Input %q: expected a syntax error", bad)
+		}
+	}
+}
+
+func TestParserWhenStatement(t *testing.T) {
+	program, err := parse(`When command is "start":
+    Print "go".
+When it is one of "stop", "halt" or "quit":
+    Print "stop".
+When it is between 1 and 10:
+    Print "small".
+When it is a number or nothing:
+    Print "other".
+Otherwise:
+    Print "unknown".
+thats it.`)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	when, ok := program.Statements[0].(*ast.WhenStatement)
+	if !ok {
+		t.Fatalf("expected *ast.WhenStatement, got %T", program.Statements[0])
+	}
+	if len(when.Cases) != 4 || len(when.Otherwise) != 1 {
+		t.Fatalf("expected 4 cases and an otherwise, got %d and %d", len(when.Cases), len(when.Otherwise))
+	}
+	if n := len(when.Cases[1].Patterns); n != 3 {
+		t.Errorf("expected 3 patterns in case 2, got %d", n)
+	}
+	if _, ok := when.Cases[2].Patterns[0].(*ast.RangePattern); !ok {
+		t.Errorf("expected *ast.RangePattern, got %T", when.Cases[2].Patterns[0])
+	}
+	if tp, ok := when.Cases[3].Patterns[0].(*ast.TypePattern); !ok || tp.TypeName != "number" {
+		t.Errorf("expected type pattern 'number', got %#v", when.Cases[3].Patterns[0])
+	}
+	if vp, ok := when.Cases[3].Patterns[1].(*ast.ValuePattern); !ok {
+		t.Errorf("expected *ast.ValuePattern, got %T", when.Cases[3].Patterns[1])
+	} else if _, ok := vp.Value.(*ast.NothingLiteral); !ok {
+		t.Errorf("expected nothing literal, got %T", vp.Value)
+	}
+
+	for _, bad := range []string{
+		"When x:\n    Print 1.\nthats it.",
+		"When x is 1:\n    Print 1.\n",
+	} {
+		if _, err := parse(bad); err == nil {
+			t.Errorf("Input %q: expected a syntax error", bad)
+		}
+	}
+}
+
 func TestParserAskStatement(t *testing.T) {
 tests := []struct {
 input   string
@@ -1184,3 +1332,748 @@ t.Errorf("Input %q: expected *ast.AskExpression as value, got %T", test.input, a
 }
 }
 }
+
+func TestParserFieldAssignment(t *testing.T) {
+	program, err := parse(`Set person's age to be 31.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	assign, ok := program.Statements[0].(*ast.FieldAssignment)
+	if !ok {
+		t.Fatalf("Expected FieldAssignment, got %T", program.Statements[0])
+	}
+	if assign.ObjectName != "person" || assign.Field != "age" {
+		t.Errorf("Expected person's age, got %s's %s", assign.ObjectName, assign.Field)
+	}
+}
+
+func TestParserOverflowMode(t *testing.T) {
+	tests := []struct {
+		input string
+		wrap  bool
+	}{
+		{`Use wrapping arithmetic.`, true},
+		{`Use checked integer arithmetic.`, false},
+	}
+
+	for _, test := range tests {
+		program, err := parse(test.input)
+		if err != nil {
+			t.Errorf("Input %q: parse error: %v", test.input, err)
+			continue
+		}
+		mode, ok := program.Statements[0].(*ast.OverflowModeStatement)
+		if !ok {
+			t.Errorf("Input %q: expected *ast.OverflowModeStatement, got %T", test.input, program.Statements[0])
+			continue
+		}
+		if mode.Wrap != test.wrap {
+			t.Errorf("Input %q: expected Wrap=%v, got %v", test.input, test.wrap, mode.Wrap)
+		}
+	}
+
+	if _, err := parse(`Use fast arithmetic.`); err == nil {
+		t.Error("expected an error for an unknown overflow mode")
+	}
+}
+
+func TestParserUnsignedIntegerField(t *testing.T) {
+	program, err := parse(`declare Meter as a structure with the following fields:
+    reading is an unsigned integer.
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	decl, ok := program.Statements[0].(*ast.StructDecl)
+	if !ok {
+		t.Fatalf("Expected StructDecl, got %T", program.Statements[0])
+	}
+	if got := decl.Fields[0].TypeName; got != "unsigned integer" {
+		t.Errorf("Expected type 'unsigned integer', got %q", got)
+	}
+}
+
+func TestParserDecimalLiteral(t *testing.T) {
+	program, err := parse(`Declare price to be the decimal -19.990.
+Declare big to be the whole number 12345678901234567890.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	tests := []struct {
+		digits string
+		whole  bool
+	}{
+		{"-19.990", false},
+		{"12345678901234567890", true},
+	}
+	for i, tt := range tests {
+		decl := program.Statements[i].(*ast.VariableDecl)
+		lit, ok := decl.Value.(*ast.DecimalLiteral)
+		if !ok {
+			t.Fatalf("Expected DecimalLiteral, got %T", decl.Value)
+		}
+		if lit.Digits != tt.digits || lit.Whole != tt.whole {
+			t.Errorf("Expected %q (whole=%v), got %q (whole=%v)", tt.digits, tt.whole, lit.Digits, lit.Whole)
+		}
+	}
+}
+
+func TestParserStructKindOf(t *testing.T) {
+	program, err := parse(`declare Dog as a kind of Animal with the following fields:
+    breed is a string.
+thats it.
+If pet is a Dog, then
+    Print "dog".
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	decl, ok := program.Statements[0].(*ast.StructDecl)
+	if !ok {
+		t.Fatalf("Expected StructDecl, got %T", program.Statements[0])
+	}
+	if decl.Name != "Dog" || decl.Parent != "Animal" {
+		t.Errorf("Expected Dog as a kind of Animal, got %q of %q", decl.Name, decl.Parent)
+	}
+	if len(decl.Fields) != 1 || decl.Fields[0].Name != "breed" {
+		t.Errorf("Expected one field 'breed', got %v", decl.Fields)
+	}
+
+	cond := program.Statements[1].(*ast.IfStatement).Condition
+	if check, ok := cond.(*ast.ErrorTypeCheckExpression); !ok || check.TypeName != "Dog" {
+		t.Errorf("Expected a type check against Dog, got %#v", cond)
+	}
+}
+
+func TestParserCapability(t *testing.T) {
+	program, err := parse(`Declare Speaker as a capability requiring a function speak, a function listen and function wave.
+declare Dog as a kind of Animal that can Speaker and Walker with the following fields:
+    breed is a string.
+thats it.
+Declare function greet that takes pet as anything that can Speaker and count and does the following:
+    Call pet's speak.
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	cd, ok := program.Statements[0].(*ast.CapabilityDecl)
+	if !ok {
+		t.Fatalf("Expected CapabilityDecl, got %T", program.Statements[0])
+	}
+	if cd.Name != "Speaker" || len(cd.Methods) != 3 || cd.Methods[0] != "speak" || cd.Methods[2] != "wave" {
+		t.Errorf("Expected Speaker requiring speak, listen and wave, got %q requiring %v", cd.Name, cd.Methods)
+	}
+
+	decl, ok := program.Statements[1].(*ast.StructDecl)
+	if !ok {
+		t.Fatalf("Expected StructDecl, got %T", program.Statements[1])
+	}
+	if decl.Parent != "Animal" || len(decl.Capabilities) != 2 || decl.Capabilities[1] != "Walker" {
+		t.Errorf("Expected a kind of Animal that can Speaker and Walker, got %q can %v", decl.Parent, decl.Capabilities)
+	}
+
+	fn, ok := program.Statements[2].(*ast.FunctionDecl)
+	if !ok {
+		t.Fatalf("Expected FunctionDecl, got %T", program.Statements[2])
+	}
+	if len(fn.Parameters) != 2 || len(fn.ParamCapabilities) != 2 ||
+		fn.ParamCapabilities[0] != "Speaker" || fn.ParamCapabilities[1] != "" {
+		t.Errorf("Expected pet to need Speaker and count nothing, got %v / %v", fn.Parameters, fn.ParamCapabilities)
+	}
+}
+
+func TestParserTypedSignature(t *testing.T) {
+	program, err := parse(`Declare function area that takes width as number and height as a whole number and returns a number and does the following:
+    Return width * height.
+thats it.
+Declare function pi that returns a number and does the following:
+    Return 3.
+thats it.
+Declare function show that takes p as a Point and pet as anything that can Speaker and does the following:
+    Print p.
+thats it.
+declare Shape as a structure with the following fields:
+    let scaled be a function that takes factor as number and returns a lookup table and does the following:
+        Return factor.
+    thats it.
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	area := program.Statements[0].(*ast.FunctionDecl)
+	if len(area.ParamTypes) != 2 || area.ParamTypes[0] != "number" || area.ParamTypes[1] != "whole number" || area.ReturnType != "number" {
+		t.Errorf("Expected (number, whole number) returning number, got %v returning %q", area.ParamTypes, area.ReturnType)
+	}
+
+	pi := program.Statements[1].(*ast.FunctionDecl)
+	if len(pi.Parameters) != 0 || pi.ReturnType != "number" {
+		t.Errorf("Expected no parameters returning number, got %v returning %q", pi.Parameters, pi.ReturnType)
+	}
+
+	show := program.Statements[2].(*ast.FunctionDecl)
+	if len(show.ParamTypes) != 2 || show.ParamTypes[0] != "Point" || show.ParamTypes[1] != "" ||
+		len(show.ParamCapabilities) != 2 || show.ParamCapabilities[1] != "Speaker" || show.ReturnType != "" {
+		t.Errorf("Expected p as Point and pet as a Speaker, got %v / %v", show.ParamTypes, show.ParamCapabilities)
+	}
+
+	method := program.Statements[3].(*ast.StructDecl).Methods[0]
+	if len(method.ParamTypes) != 1 || method.ParamTypes[0] != "number" || method.ReturnType != "lookup table" {
+		t.Errorf("Expected the method to take a number and return a lookup table, got %v returning %q", method.ParamTypes, method.ReturnType)
+	}
+
+	if _, err := parse(`Declare function f that takes x as 5 and does the following:
+    Print x.
+thats it.`); err == nil {
+		t.Error("Expected a syntax error for a parameter typed as 5")
+	}
+}
+
+func TestParserElementTypes(t *testing.T) {
+	program, err := parse(`Declare scores as a list of numbers to be [1, 2].
+Declare ages as a lookup table from text to number.
+Declare flags as list of boolean.
+Declare big as a list of whole numbers.
+Declare nested as a lookup table from number to lists.
+Declare plain as a lookup table.
+Declare points as a list of Point.
+Declare shades as a lookup table from text to Color.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	want := []string{
+		"list of number",
+		"lookup table from text to number",
+		"list of boolean",
+		"list of whole number",
+		"lookup table from number to list",
+		"lookup table",
+		"list of Point",
+		"lookup table from text to Color",
+	}
+	for i, w := range want {
+		decl := program.Statements[i].(*ast.TypedVariableDecl)
+		if decl.TypeName != w {
+			t.Errorf("statement %d: expected type %q, got %q", i, w, decl.TypeName)
+		}
+	}
+	if decl := program.Statements[0].(*ast.TypedVariableDecl); decl.Value == nil {
+		t.Error("Expected scores to have an initial value")
+	}
+	if decl := program.Statements[1].(*ast.TypedVariableDecl); decl.Value == nil {
+		t.Error("Expected ages to start as an empty lookup table")
+	}
+	if decl := program.Statements[2].(*ast.TypedVariableDecl); decl.Value == nil {
+		t.Error("Expected flags to start as an empty list")
+	}
+
+	for _, src := range []string{
+		`Declare ages as a lookup table from text.`,
+		`Declare scores as a list of.`,
+	} {
+		if _, err := parse(src); err == nil {
+			t.Errorf("%s: expected a syntax error", src)
+		}
+	}
+}
+
+func TestParserNamespacedImport(t *testing.T) {
+	program, err := parse(`Import "geometry.abc" as geo.
+Import "text.abc" as txt safely.
+Import from "util.abc" safely as util.
+Declare a to be the result of calling geo's area with 3.
+Set a to be the result of calling geo's helper.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	for i, want := range []struct {
+		alias string
+		safe  bool
+	}{{"geo", false}, {"txt", true}, {"util", true}} {
+		imp := program.Statements[i].(*ast.ImportStatement)
+		if imp.Alias != want.alias || imp.IsSafe != want.safe {
+			t.Errorf("import %d: expected alias %q (safe %v), got %q (safe %v)", i, want.alias, want.safe, imp.Alias, imp.IsSafe)
+		}
+	}
+	if imp := program.Statements[0].(*ast.ImportStatement); imp.Line != 1 {
+		t.Errorf("Expected the import on line 1, got %d", imp.Line)
+	}
+
+	decl := program.Statements[3].(*ast.VariableDecl)
+	mc, ok := decl.Value.(*ast.MethodCall)
+	if !ok {
+		t.Fatalf("Expected a MethodCall, got %T", decl.Value)
+	}
+	if obj, _ := mc.Object.(*ast.Identifier); obj == nil || obj.Name != "geo" || mc.MethodName != "area" || len(mc.Arguments) != 1 {
+		t.Errorf("Expected geo's area with one argument, got %+v", mc)
+	}
+	if mc, ok := program.Statements[4].(*ast.Assignment).Value.(*ast.MethodCall); !ok || len(mc.Arguments) != 0 {
+		t.Errorf("Expected geo's helper with no arguments, got %+v", program.Statements[4].(*ast.Assignment).Value)
+	}
+
+	for _, src := range []string{
+		`Import "geometry.abc" as.`,
+		`Import area from "geometry.abc" as geo.`,
+		`Declare a to be the result of calling geo's.`,
+	} {
+		if _, err := parse(src); err == nil {
+			t.Errorf("%s: expected a syntax error", src)
+		}
+	}
+}
+
+func TestParserDefaultsAndNamedArguments(t *testing.T) {
+	program, err := parse(`Declare function greet that takes name and greeting (defaulting to "Hello") and does the following:
+    Print greeting, name.
+thats it.
+Call greet with "Bo" and greeting as "Hi".
+Set x to be the result of calling greet with name as "Cy".`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	fn, ok := program.Statements[0].(*ast.FunctionDecl)
+	if !ok {
+		t.Fatalf("Expected FunctionDecl, got %T", program.Statements[0])
+	}
+	if len(fn.Defaults) != 2 || fn.Defaults[0] != nil {
+		t.Fatalf("Expected name to be required and greeting to have a default, got %v", fn.Defaults)
+	}
+	if lit, ok := fn.Defaults[1].(*ast.StringLiteral); !ok || lit.Value != "Hello" {
+		t.Errorf("Expected greeting to default to \"Hello\", got %#v", fn.Defaults[1])
+	}
+
+	call := program.Statements[1].(*ast.CallStatement).FunctionCall
+	if len(call.Arguments) != 1 || len(call.NamedArguments) != 1 || call.NamedArguments[0].Name != "greeting" {
+		t.Errorf("Expected one positional and one named argument, got %d and %v", len(call.Arguments), call.NamedArguments)
+	}
+
+	set := program.Statements[2].(*ast.Assignment).Value.(*ast.FunctionCall)
+	if len(set.Arguments) != 0 || len(set.NamedArguments) != 1 || set.NamedArguments[0].Name != "name" {
+		t.Errorf("Expected only the named argument name, got %d and %v", len(set.Arguments), set.NamedArguments)
+	}
+
+	for _, input := range []string{
+		// A required parameter cannot follow one with a default.
+		`Declare function f that takes a (defaulting to 1) and b and does the following:
+    Print a.
+thats it.`,
+		// Named arguments come after the positional ones.
+		`Call f with a as 1 and 2.`,
+	} {
+		if _, err := parse(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestParserRestParameter(t *testing.T) {
+	program, err := parse(`Declare function label that takes prefix and any number of items and does the following:
+    Print prefix, items.
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	fn, ok := program.Statements[0].(*ast.FunctionDecl)
+	if !ok {
+		t.Fatalf("Expected FunctionDecl, got %T", program.Statements[0])
+	}
+	if !fn.Variadic || len(fn.Parameters) != 2 || fn.Parameters[1] != "items" {
+		t.Errorf("Expected prefix and the rest parameter items, got %v (variadic %v)", fn.Parameters, fn.Variadic)
+	}
+
+	for _, input := range []string{
+		// The rest parameter has to come last.
+		`Declare function f that takes any number of items and b and does the following:
+    Print b.
+thats it.`,
+		// It collects a list, so it takes no default.
+		`Declare function f that takes any number of items (defaulting to []) and does the following:
+    Print items.
+thats it.`,
+		`Declare function f that takes any number items and does the following:
+    Print items.
+thats it.`,
+	} {
+		if _, err := parse(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+
+	_, err = parse(`Declare function f that takes any number of xs as number and does the following:
+    Print xs.
+thats it.`)
+	if err == nil || !strings.Contains(err.Error(), "cannot be given a type") {
+		t.Errorf("Expected the error to say a rest parameter takes no type, got %v", err)
+	}
+}
+
+func TestParserEnumDecl(t *testing.T) {
+	tests := []struct {
+		input   string
+		members []string
+	}{
+		{`Declare Color as one of red, green and blue.`, []string{"red", "green", "blue"}},
+		{`Declare Size as one of small, medium, and large.`, []string{"small", "medium", "large"}},
+		{`Declare Power as one of on and off.`, []string{"on", "off"}},
+	}
+	for _, tt := range tests {
+		program, err := parse(tt.input)
+		if err != nil {
+			t.Fatalf("Input %q: parse error: %v", tt.input, err)
+		}
+		decl, ok := program.Statements[0].(*ast.EnumDecl)
+		if !ok {
+			t.Fatalf("Input %q: expected EnumDecl, got %T", tt.input, program.Statements[0])
+		}
+		if len(decl.Members) != len(tt.members) {
+			t.Fatalf("Input %q: expected members %v, got %v", tt.input, tt.members, decl.Members)
+		}
+		for i, m := range tt.members {
+			if decl.Members[i] != m {
+				t.Errorf("Input %q: expected member %d to be %q, got %q", tt.input, i, m, decl.Members[i])
+			}
+		}
+	}
+
+	if _, err := parse(`Declare Color as one of red, green and red.`); err == nil {
+		t.Error("expected an error for a duplicate enum member")
+	}
+}
+
+func TestParserWholeNumberField(t *testing.T) {
+	program, err := parse(`declare Ledger as a structure with the following fields:
+    total is a whole number.
+    rate is a decimal.
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+
+	decl := program.Statements[0].(*ast.StructDecl)
+	if got := decl.Fields[0].TypeName; got != "whole number" {
+		t.Errorf("Expected type 'whole number', got %q", got)
+	}
+	if got := decl.Fields[1].TypeName; got != "decimal" {
+		t.Errorf("Expected type 'decimal', got %q", got)
+	}
+}
+
+func TestParserDestructuring(t *testing.T) {
+	program, err := parse(`Declare function divide that takes a and b and does the following:
+    Return a divided evenly by b and the remainder of a divided by b.
+thats it.
+Declare q and r to be the result of calling divide with 7 and 2.
+Set a, b, and c to [1, 2, 3].
+Declare head and the rest as tail to be [1, 2, 3].`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	fn := program.Statements[0].(*ast.FunctionDecl)
+	ret, ok := fn.Body[0].(*ast.ReturnStatement)
+	if !ok || len(ret.Values) != 2 {
+		t.Fatalf("Expected a return of two values, got %#v", fn.Body[0])
+	}
+	decl, ok := program.Statements[1].(*ast.VariableDecl)
+	if !ok || len(decl.Names) != 2 || decl.Names[0] != "q" || decl.Names[1] != "r" {
+		t.Fatalf("Expected a declaration of q and r, got %#v", program.Statements[1])
+	}
+	if call, ok := decl.Value.(*ast.FunctionCall); !ok || call.Name != "divide" || len(call.Arguments) != 2 {
+		t.Errorf("Expected a call to divide with 2 arguments, got %#v", decl.Value)
+	}
+	assign, ok := program.Statements[2].(*ast.Assignment)
+	if !ok || len(assign.Names) != 3 || assign.Names[2] != "c" {
+		t.Fatalf("Expected an assignment to a, b and c, got %#v", program.Statements[2])
+	}
+	if assign.Rest {
+		t.Errorf("Expected c to take one item, not the rest")
+	}
+	rest, ok := program.Statements[3].(*ast.VariableDecl)
+	if !ok || !rest.Rest || len(rest.Names) != 2 || rest.Names[1] != "tail" {
+		t.Fatalf("Expected tail to collect the rest, got %#v", program.Statements[3])
+	}
+
+	for _, input := range []string{
+		`Declare q and q to be [1, 2].`,
+		`Declare q and to be [1, 2].`,
+		`Declare q and the rest to be [1, 2].`,
+		`Declare q, the rest as r and s to be [1, 2].`,
+	} {
+		if _, err := parse(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestParserPrivateDeclarations(t *testing.T) {
+	program, err := parse(`Declare privately cache to be 0.
+Declare private function helper that does the following:
+    Return 1.
+thats it.
+Declare privately limit as number to be 3.
+Declare privately q and r to be [1, 2].
+Declare private to be "still a name".`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if decl := program.Statements[0].(*ast.VariableDecl); !decl.IsPrivate || decl.Name != "cache" {
+		t.Errorf("Expected cache to be private, got %#v", decl)
+	}
+	if fn := program.Statements[1].(*ast.FunctionDecl); !fn.IsPrivate || fn.Name != "helper" {
+		t.Errorf("Expected helper to be private, got %#v", fn)
+	}
+	if decl := program.Statements[2].(*ast.TypedVariableDecl); !decl.IsPrivate || decl.Name != "limit" {
+		t.Errorf("Expected limit to be private, got %#v", decl)
+	}
+	if decl := program.Statements[3].(*ast.VariableDecl); !decl.IsPrivate || len(decl.Names) != 2 {
+		t.Errorf("Expected q and r to be private, got %#v", decl)
+	}
+	if decl := program.Statements[4].(*ast.VariableDecl); decl.IsPrivate || decl.Name != "private" {
+		t.Errorf("Expected a public variable called private, got %#v", decl)
+	}
+
+	if _, err := parse(`Declare private Color as one of red and green.`); err == nil {
+		t.Errorf("Expected a syntax error for a private enum")
+	}
+}
+
+func TestParserGiveBack(t *testing.T) {
+	program, err := parse(`Declare function evens that takes limit and does the following:
+    Declare i to be 0.
+    repeat the following while i is less than limit:
+        Give back i.
+        Set i to i + 2.
+    thats it.
+thats it.
+Declare function double that takes x and does the following:
+    Return x * 2.
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	evens := program.Statements[0].(*ast.FunctionDecl)
+	if !evens.Generator {
+		t.Errorf("Expected evens to be a generator")
+	}
+	loop := evens.Body[1].(*ast.WhileLoop)
+	if _, ok := loop.Body[0].(*ast.YieldStatement); !ok {
+		t.Errorf("Expected a YieldStatement, got %#v", loop.Body[0])
+	}
+	if program.Statements[1].(*ast.FunctionDecl).Generator {
+		t.Errorf("Expected double not to be a generator")
+	}
+
+	for _, input := range []string{
+		`Give back 1.`,
+		`Declare f to be a function that takes x and does the following:
+    Give back x.
+thats it.`,
+	} {
+		if _, err := parse(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestParserBackgroundTasks(t *testing.T) {
+	program, err := parse(`Declare results to be a channel.
+Do the following in the background and call it producer:
+    Send 10 to results.
+thats it.
+Receive from results into answer.
+Wait for producer.
+Wait for 2 seconds.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if _, ok := program.Statements[0].(*ast.VariableDecl).Value.(*ast.ChannelLiteral); !ok {
+		t.Errorf("Expected a ChannelLiteral, got %#v", program.Statements[0])
+	}
+	bg, ok := program.Statements[1].(*ast.BackgroundStatement)
+	if !ok || bg.Name != "producer" || len(bg.Body) != 1 {
+		t.Fatalf("Expected background task producer, got %#v", program.Statements[1])
+	}
+	if send, ok := bg.Body[0].(*ast.SendStatement); !ok || send.Channel.(*ast.Identifier).Name != "results" {
+		t.Errorf("Expected a send to results, got %#v", bg.Body[0])
+	}
+	recv := program.Statements[2].(*ast.Assignment)
+	if _, ok := recv.Value.(*ast.ReceiveExpression); !ok || recv.Name != "answer" {
+		t.Errorf("Expected a receive into answer, got %#v", recv)
+	}
+	if wait, ok := program.Statements[3].(*ast.WaitStatement); !ok || wait.Task.(*ast.Identifier).Name != "producer" {
+		t.Errorf("Expected a wait for producer, got %#v", program.Statements[3])
+	}
+	if _, ok := program.Statements[4].(*ast.CallStatement); !ok {
+		t.Errorf("Expected 'Wait for 2 seconds.' to still sleep, got %#v", program.Statements[4])
+	}
+
+	for _, input := range []string{
+		`Do the following in the garden and call it job:
+    Print 1.
+thats it.`,
+		`Do the following in the background:
+    Print 1.
+thats it.`,
+		`Send 1 into ch.`,
+		`Receive ch into x.`,
+		`Receive from ch to x.`,
+	} {
+		if _, err := parse(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestParserMakeSure(t *testing.T) {
+	program, err := parse(`Make sure that balance is at least 0, otherwise say "negative balance".
+Make sure that total - 1 is at most (limit * 2).
+Make sure that ready.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	check, ok := program.Statements[0].(*ast.AssertStatement)
+	if !ok {
+		t.Fatalf("Expected an AssertStatement, got %#v", program.Statements[0])
+	}
+	if check.Text != "balance is at least 0" {
+		t.Errorf("Text = %q", check.Text)
+	}
+	if len(check.Operands) != 2 || check.Operands[0] != "balance" || check.Operands[1] != "0" {
+		t.Errorf("Operands = %q", check.Operands)
+	}
+	if be, ok := check.Condition.(*ast.BinaryExpression); !ok || be.Operator != "is greater than or equal to" {
+		t.Errorf("Expected 'is at least' to read as 'is greater than or equal to', got %#v", check.Condition)
+	}
+	if msg, ok := check.Message.(*ast.StringLiteral); !ok || msg.Value != "negative balance" {
+		t.Errorf("Expected the message \"negative balance\", got %#v", check.Message)
+	}
+
+	check = program.Statements[1].(*ast.AssertStatement)
+	if be, ok := check.Condition.(*ast.BinaryExpression); !ok || be.Operator != "is less than or equal to" {
+		t.Errorf("Expected 'is at most' to read as 'is less than or equal to', got %#v", check.Condition)
+	}
+	if len(check.Operands) != 2 || check.Operands[0] != "total - 1" || check.Operands[1] != "(limit * 2)" {
+		t.Errorf("Operands = %q", check.Operands)
+	}
+	if check.Message != nil {
+		t.Errorf("Expected no message, got %#v", check.Message)
+	}
+
+	check = program.Statements[2].(*ast.AssertStatement)
+	if check.Text != "ready" || len(check.Operands) != 0 {
+		t.Errorf("Expected a plain condition, got %#v", check)
+	}
+
+	for _, input := range []string{
+		`Make sure balance is at least 0.`,
+		`Make sure that balance is at least 0, otherwise "negative".`,
+		`Make sure that balance is at least 0 otherwise say "negative"`,
+	} {
+		if _, err := parse(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestParserTestBlock(t *testing.T) {
+	program, err := parse(`Declare total to be 0.
+Test "adding works" by doing the following:
+    Make sure that 1 + 1 is equal to 2.
+thats it.
+Declare test to be 5.
+Print test.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	tb, ok := program.Statements[1].(*ast.TestBlock)
+	if !ok {
+		t.Fatalf("Expected a TestBlock, got %#v", program.Statements[1])
+	}
+	if tb.Name != "adding works" || tb.Line != 2 || len(tb.Body) != 1 {
+		t.Errorf("Expected test \"adding works\" on line 2 with one statement, got %#v", tb)
+	}
+	if _, ok := tb.Body[0].(*ast.AssertStatement); !ok {
+		t.Errorf("Expected an AssertStatement, got %#v", tb.Body[0])
+	}
+	if len(program.Statements) != 4 {
+		t.Errorf("Expected 'test' to still work as a variable name, got %d statements", len(program.Statements))
+	}
+
+	for _, input := range []string{
+		`Test "x" doing the following:
+    Print 1.
+thats it.`,
+		`Test "x" by doing the following
+    Print 1.
+thats it.`,
+		`If true, then
+    Test "nested" by doing the following:
+        Print 1.
+    thats it.
+thats it.`,
+	} {
+		if _, err := parse(input); err == nil {
+			t.Errorf("Expected a syntax error for %q", input)
+		}
+	}
+}
+
+func TestParserLoopLabels(t *testing.T) {
+	program, err := parse(`Repeat the following while i is less than 3 and j is less than 3 and call this loop outer:
+    For each x in items, do the following and call this loop inner:
+        Break out of outer.
+        Continue inner.
+        Break out of this loop.
+        Continue.
+    thats it.
+thats it.
+Repeat the following 2 times and call this loop twice:
+    Print 1.
+thats it.
+Repeat forever and call this loop spin:
+    Break out of spin.
+thats it.`)
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	outer := program.Statements[0].(*ast.WhileLoop)
+	if outer.Label != "outer" {
+		t.Errorf("Expected the while loop to be called outer, got %q", outer.Label)
+	}
+	if _, ok := outer.Condition.(*ast.BinaryExpression); !ok {
+		t.Errorf("Expected 'and' inside the condition to stay part of it, got %#v", outer.Condition)
+	}
+	inner := outer.Body[0].(*ast.ForEachLoop)
+	if inner.Label != "inner" {
+		t.Errorf("Expected the for-each loop to be called inner, got %q", inner.Label)
+	}
+	if brk := inner.Body[0].(*ast.BreakStatement); brk.Label != "outer" || brk.Line != 3 {
+		t.Errorf("Expected a break out of outer on line 3, got %#v", brk)
+	}
+	if cont := inner.Body[1].(*ast.ContinueStatement); cont.Label != "inner" {
+		t.Errorf("Expected a continue of inner, got %#v", cont)
+	}
+	if brk := inner.Body[2].(*ast.BreakStatement); brk.Label != "" {
+		t.Errorf("Expected a plain break, got %#v", brk)
+	}
+	if cont := inner.Body[3].(*ast.ContinueStatement); cont.Label != "" {
+		t.Errorf("Expected a plain continue, got %#v", cont)
+	}
+	if loop := program.Statements[1].(*ast.ForLoop); loop.Label != "twice" {
+		t.Errorf("Expected the counted loop to be called twice, got %q", loop.Label)
+	}
+	if loop := program.Statements[2].(*ast.WhileLoop); loop.Label != "spin" {
+		t.Errorf("Expected the forever loop to be called spin, got %q", loop.Label)
+	}
+
+	if _, err := parse(`Repeat forever and call outer:
+    Break out of this loop.
+thats it.`); err == nil {
+		t.Errorf("Expected a syntax error for a label without 'this loop'")
+	}
+}
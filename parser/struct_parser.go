@@ -4,6 +4,7 @@ import (
 	"github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/token"
 	"fmt"
+	"strings"
 )
 
 // parseStructDeclaration parses a struct declaration
@@ -15,6 +16,10 @@ import (
 //	      print "hello, my name is", name.
 //	  thats it.
 //	thats it.
+//
+// A structure declared as "a kind of Animal" instead of "a structure"
+// inherits Animal's fields, defaults and methods. Either form may be followed
+// by "that can Speaker" to promise the functions a capability requires.
 func (p *Parser) parseStructDeclaration() (ast.Statement, error) {
 	nameToken := p.curToken
 	if p.curToken.Type != token.IDENTIFIER {
@@ -36,8 +41,17 @@ func (p *Parser) parseStructDeclaration() (ast.Statement, error) {
 		p.nextToken()
 	}
 
-	// Expect "structure" or "struct"
-	if p.curToken.Type != token.STRUCTURE && p.curToken.Type != token.STRUCT {
+	// Expect "structure" or "struct", or "kind of <Parent>" for a structure
+	// that inherits from another
+	parent := ""
+	if p.curToken.Type == token.IDENTIFIER && strings.ToLower(p.curToken.Value) == "kind" && p.peekToken.Type == token.OF {
+		p.nextToken()
+		p.nextToken()
+		if p.curToken.Type != token.IDENTIFIER {
+			return nil, p.syntaxErr(msgStructParentName, hintStructParent)
+		}
+		parent = p.curToken.Value
+	} else if p.curToken.Type != token.STRUCTURE && p.curToken.Type != token.STRUCT {
 		return nil, p.syntaxErr(
 			fmt.Sprintf(msgFmtStructOrStruct, p.curToken.Value),
 			hintStructName,
@@ -45,6 +59,24 @@ func (p *Parser) parseStructDeclaration() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	// Optional "that can Speaker and Walker"
+	var capabilities []string
+	if p.curToken.Type == token.THAT && isWord(p.peekToken, "can") {
+		p.nextToken()
+		p.nextToken()
+		for {
+			if p.curToken.Type != token.IDENTIFIER {
+				return nil, p.syntaxErr(msgCapabilityAfterCan, hintStructCapability)
+			}
+			capabilities = append(capabilities, p.curToken.Value)
+			p.nextToken()
+			if p.curToken.Type != token.AND {
+				break
+			}
+			p.nextToken()
+		}
+	}
+
 	// Expect "with"
 	if err := p.expectToken(token.WITH); err != nil {
 		return nil, err
@@ -130,9 +162,11 @@ func (p *Parser) parseStructDeclaration() (ast.Statement, error) {
 	p.nextToken()
 
 	return &ast.StructDecl{
-		Name:    nameToken.Value,
-		Fields:  fields,
-		Methods: methods,
+		Name:         nameToken.Value,
+		Parent:       parent,
+		Capabilities: capabilities,
+		Fields:       fields,
+		Methods:      methods,
 	}, nil
 }
 
@@ -179,8 +213,15 @@ func (p *Parser) parseStructField() (*ast.StructField, error) {
 	typeName := typeToken.Value
 	if p.curToken.Type == token.INTEGER {
 		typeName = "integer"
+		if isUnsigned {
+			typeName = "unsigned integer"
+		}
 	}
 	p.nextToken()
+	if strings.EqualFold(typeName, "whole") && strings.EqualFold(p.curToken.Value, "number") {
+		typeName = "whole number"
+		p.nextToken()
+	}
 
 	var defaultValue ast.Expression
 
@@ -261,7 +302,9 @@ func (p *Parser) parseStructMethod() (*ast.FunctionDecl, error) {
 	}
 	p.nextToken()
 
-	var parameters []string
+	var parameters, capabilities, paramTypes []string
+	var defaults []ast.Expression
+	variadic := false
 
 	// Check for "that takes" for parameters
 	if p.curToken.Type == token.THAT {
@@ -269,6 +312,16 @@ func (p *Parser) parseStructMethod() (*ast.FunctionDecl, error) {
 		if p.curToken.Type == token.TAKES {
 			p.nextToken()
 			for {
+				// "takes any number of values" ends the parameter list
+				if isWord(p.curToken, "any") && isWord(p.peekToken, "number") {
+					rest, err := p.parseRestParameter()
+					if err != nil {
+						return nil, err
+					}
+					parameters = append(parameters, rest)
+					variadic = true
+					break
+				}
 				paramToken := p.curToken
 				if p.curToken.Type != token.IDENTIFIER {
 					return nil, p.syntaxErr(
@@ -279,11 +332,30 @@ func (p *Parser) parseStructMethod() (*ast.FunctionDecl, error) {
 				parameters = append(parameters, paramToken.Value)
 				p.nextToken()
 
+				if p.curToken.Type == token.AS {
+					capability, typeName, err := p.parseParamAnnotation()
+					if err != nil {
+						return nil, err
+					}
+					if capability != "" {
+						capabilities = setParamAnnotation(capabilities, len(parameters)-1, capability)
+					} else {
+						paramTypes = setParamAnnotation(paramTypes, len(parameters)-1, typeName)
+					}
+				}
+
+				// "takes name and greeting (defaulting to "Hello")"
+				var err error
+				if defaults, err = p.parseParamDefault(defaults, parameters, len(parameters)-1); err != nil {
+					return nil, err
+				}
+
 				if p.curToken.Type != token.AND {
 					break
 				}
-				// Check if "and" is followed by "does" (end of params) or another param
-				if p.peekToken.Type == token.DOES {
+				// Check if "and" is followed by "does" or "returns" (end of
+				// params) or another param
+				if p.peekToken.Type == token.DOES || isReturnsWord(p.peekToken) {
 					break
 				}
 				p.nextToken()
@@ -291,6 +363,12 @@ func (p *Parser) parseStructMethod() (*ast.FunctionDecl, error) {
 		}
 	}
 
+	// "and returns a number"
+	returnType, err := p.parseReturnType()
+	if err != nil {
+		return nil, err
+	}
+
 	// Support "and does" syntax after parameters
 	if p.curToken.Type == token.AND {
 		p.nextToken()
@@ -324,7 +402,7 @@ func (p *Parser) parseStructMethod() (*ast.FunctionDecl, error) {
 	p.nextToken()
 
 	// Parse function body
-	body, err := p.parseBlock()
+	body, err := p.parseBody(nil)
 	if err != nil {
 		return nil, err
 	}
@@ -345,10 +423,22 @@ func (p *Parser) parseStructMethod() (*ast.FunctionDecl, error) {
 	}
 	p.nextToken()
 
+	if capabilities != nil {
+		capabilities = padParamAnnotations(capabilities, len(parameters))
+	}
+	if paramTypes != nil {
+		paramTypes = padParamAnnotations(paramTypes, len(parameters))
+	}
+
 	return &ast.FunctionDecl{
-		Name:       nameToken.Value,
-		Parameters: parameters,
-		Body:       body,
+		Name:              nameToken.Value,
+		Parameters:        parameters,
+		ParamCapabilities: capabilities,
+		ParamTypes:        paramTypes,
+		ReturnType:        returnType,
+		Defaults:          defaults,
+		Variadic:          variadic,
+		Body:              body,
 	}, nil
 }
 
@@ -504,15 +594,11 @@ func (p *Parser) parseTypedVariableDecl() (ast.Statement, error) {
 	}
 	p.nextToken()
 
-	// Read the type name (e.g. "number", "text", "boolean")
-	if p.curToken.Type != token.IDENTIFIER {
-		return nil, p.syntaxErr(
-			fmt.Sprintf(msgFmtTypedVarType, p.curToken.Value),
-			hintTypedVarType,
-		)
+	// Read the type name (e.g. "number", "text", "a list of numbers")
+	typeName, err := p.parseDeclaredType()
+	if err != nil {
+		return nil, err
 	}
-	typeName := p.curToken.Value
-	p.nextToken()
 
 	isConstant := false
 	var value ast.Expression
@@ -536,7 +622,6 @@ func (p *Parser) parseTypedVariableDecl() (ast.Statement, error) {
 			p.nextToken()
 		}
 
-		var err error
 		value, err = p.parseExpression()
 		if err != nil {
 			return nil, err
@@ -548,6 +633,17 @@ func (p *Parser) parseTypedVariableDecl() (ast.Statement, error) {
 	}
 	p.nextToken()
 
+	// A list or lookup table that says what it holds starts out empty, ready
+	// for "Set ages at "bo" to be 3." or append.
+	if value == nil {
+		switch {
+		case strings.HasPrefix(typeName, "list of "):
+			value = &ast.ListLiteral{}
+		case strings.HasPrefix(typeName, "lookup table"):
+			value = &ast.LookupTableLiteral{}
+		}
+	}
+
 	return &ast.TypedVariableDecl{
 		Name:       nameToken.Value,
 		TypeName:   typeName,
@@ -556,3 +652,321 @@ func (p *Parser) parseTypedVariableDecl() (ast.Statement, error) {
 		Line:       nameToken.Line,
 	}, nil
 }
+
+// parseDeclaredType parses the type in a typed variable declaration: a type
+// name such as "number" or "whole number", or a list or lookup table together
+// with the types of what it holds:
+//
+//	Declare scores as a list of numbers.
+//	Declare ages as a lookup table from text to number.
+//
+// These come back as "list of number" and "lookup table from text to number".
+func (p *Parser) parseDeclaredType() (string, error) {
+	if isWord(p.curToken, "a") || isWord(p.curToken, "an") {
+		p.nextToken()
+	}
+	switch {
+	case isWord(p.curToken, "list") && p.peekToken.Type == token.OF:
+		p.nextToken()
+		p.nextToken()
+		elemType, err := p.parseElementType("of")
+		if err != nil {
+			return "", err
+		}
+		return "list of " + elemType, nil
+	case p.curToken.Type == token.LOOKUP && p.peekToken.Type == token.TABLE:
+		p.nextToken()
+		p.nextToken()
+		if p.curToken.Type != token.FROM {
+			return "lookup table", nil
+		}
+		p.nextToken()
+		keyType, err := p.parseElementType("from")
+		if err != nil {
+			return "", err
+		}
+		if p.curToken.Type != token.TO {
+			return "", p.syntaxErr(fmt.Sprintf(msgFmtLookupKeyTypeTo, p.curToken.Value), hintElementType)
+		}
+		p.nextToken()
+		elemType, err := p.parseElementType("to")
+		if err != nil {
+			return "", err
+		}
+		return "lookup table from " + keyType + " to " + elemType, nil
+	}
+
+	if p.curToken.Type != token.IDENTIFIER {
+		return "", p.syntaxErr(
+			fmt.Sprintf(msgFmtTypedVarType, p.curToken.Value),
+			hintTypedVarType,
+		)
+	}
+	typeName := p.curToken.Value
+	p.nextToken()
+	if strings.EqualFold(typeName, "whole") && strings.EqualFold(p.curToken.Value, "number") {
+		typeName += " " + p.curToken.Value
+		p.nextToken()
+	}
+	return typeName, nil
+}
+
+// pluralTypeNames maps the plural of a built-in type name, as written in
+// "a list of numbers", to the type name itself.
+var pluralTypeNames = map[string]string{
+	"numbers":       "number",
+	"texts":         "text",
+	"booleans":      "boolean",
+	"decimals":      "decimal",
+	"integers":      "integer",
+	"lists":         "list",
+	"arrays":        "array",
+	"whole numbers": "whole number",
+	"lookup tables": "lookup table",
+}
+
+// parseElementType parses the type of a list's elements or of a lookup
+// table's keys or values, after the word given by after. A built-in type name
+// may be written in the plural, and is returned in the singular; any other
+// name keeps its case, as a structure, enum or error type needs.
+func (p *Parser) parseElementType(after string) (string, error) {
+	switch p.curToken.Type {
+	case token.PERIOD, token.EOF, token.TO, token.NEWLINE:
+		return "", p.syntaxErr(fmt.Sprintf(msgFmtSignatureType, after, p.curToken.Value), hintElementType)
+	}
+	written := p.curToken
+	name := p.parseTypeName()
+	if (name == "whole" && isWord(p.curToken, "numbers")) ||
+		(name == "lookup" && (p.curToken.Type == token.TABLE || isWord(p.curToken, "tables"))) {
+		name += " " + strings.ToLower(p.curToken.Value)
+		p.nextToken()
+	}
+	if singular, ok := pluralTypeNames[name]; ok {
+		return singular, nil
+	}
+	if written.Type == token.IDENTIFIER && strings.EqualFold(name, written.Value) {
+		return written.Value, nil
+	}
+	return name, nil
+}
+
+// parseCapabilityDecl parses a capability declaration.
+// Syntax: Declare Speaker as a capability requiring a function speak.
+//
+//	Declare Pet as a capability requiring a function speak and a function fetch.
+//
+// This is called from parseDeclareAs when "a capability" is detected.
+func (p *Parser) parseCapabilityDecl() (ast.Statement, error) {
+	nameToken := p.curToken
+	if nameToken.Type != token.IDENTIFIER {
+		return nil, p.syntaxErr(msgCapabilityName, hintCapabilityDecl)
+	}
+	p.nextToken() // consume name
+
+	// Consume "as a capability requiring"
+	if err := p.expectToken(token.AS); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	p.nextToken() // "a"
+	p.nextToken() // "capability"
+	if !isWord(p.curToken, "requiring") {
+		return nil, p.syntaxErr(fmt.Sprintf(msgFmtCapabilityMethod, p.curToken.Value), hintCapabilityDecl)
+	}
+	p.nextToken()
+
+	var methods []string
+	for {
+		// Each entry reads "a function speak"; the article and the word
+		// "function" are optional after the first.
+		if isWord(p.curToken, "a") || isWord(p.curToken, "an") {
+			p.nextToken()
+		}
+		if p.curToken.Type == token.FUNCTION {
+			p.nextToken()
+		}
+		if p.curToken.Type != token.IDENTIFIER {
+			return nil, p.syntaxErr(fmt.Sprintf(msgFmtCapabilityMethod, p.curToken.Value), hintCapabilityDecl)
+		}
+		methods = append(methods, p.curToken.Value)
+		p.nextToken()
+
+		if p.curToken.Type == token.PERIOD {
+			break
+		}
+		switch p.curToken.Type {
+		case token.COMMA:
+			p.nextToken()
+			if p.curToken.Type == token.AND {
+				p.nextToken()
+			}
+		case token.AND:
+			p.nextToken()
+		default:
+			return nil, p.syntaxErr(fmt.Sprintf(msgFmtCapabilityMethod, p.curToken.Value), hintCapabilityDecl)
+		}
+	}
+	p.nextToken() // consume period
+
+	return &ast.CapabilityDecl{
+		Name:    nameToken.Value,
+		Methods: methods,
+		Line:    nameToken.Line,
+	}, nil
+}
+
+// parseParamAnnotation parses what follows a parameter name's "as": either
+// a capability, "as anything that can Speaker", or a type, "as number" or
+// "as a Point". It returns whichever of the two was given.
+func (p *Parser) parseParamAnnotation() (capability, typeName string, err error) {
+	if isWord(p.peekToken, "anything") {
+		capability, err = p.parseParamCapability()
+		return capability, "", err
+	}
+	p.nextToken() // consume "as"
+	typeName, err = p.parseSignatureType("as")
+	return "", typeName, err
+}
+
+// parseReturnType parses an optional "returns a number" after a function's
+// parameters, with or without the "and" before it, and returns the type, or
+// "" when the function does not say what it returns.
+func (p *Parser) parseReturnType() (string, error) {
+	if p.curToken.Type == token.AND && isReturnsWord(p.peekToken) {
+		p.nextToken()
+	}
+	if !isReturnsWord(p.curToken) {
+		return "", nil
+	}
+	p.nextToken() // consume "returns"
+	return p.parseSignatureType("returns")
+}
+
+// parseSignatureType parses a type in a function's signature, after the word
+// given by after: an optional "a" or "an", then a built-in type such as
+// "number", "whole number" or "lookup table", or the name of a structure,
+// enum or error type, which keeps its case.
+func (p *Parser) parseSignatureType(after string) (string, error) {
+	if isWord(p.curToken, "a") || isWord(p.curToken, "an") {
+		p.nextToken()
+	}
+	switch p.curToken.Type {
+	case token.IDENTIFIER:
+		name := p.curToken.Value
+		p.nextToken()
+		if strings.EqualFold(name, "whole") && isWord(p.curToken, "number") {
+			name = "whole number"
+			p.nextToken()
+		}
+		return name, nil
+	case token.LOOKUP, token.ARRAY, token.NOTHING, token.FUNCTION, token.INTEGER, token.UNSIGNED:
+		name := p.parseTypeName()
+		if name == "lookup" {
+			if p.curToken.Type != token.TABLE {
+				return "", p.syntaxErr(fmt.Sprintf(msgFmtSignatureType, after, p.curToken.Value), hintSignatureType)
+			}
+			p.nextToken()
+			name = "lookup table"
+		}
+		return name, nil
+	}
+	return "", p.syntaxErr(fmt.Sprintf(msgFmtSignatureType, after, p.curToken.Value), hintSignatureType)
+}
+
+// parseParamCapability parses "as anything that can Speaker" after a
+// parameter name and returns the capability's name.
+func (p *Parser) parseParamCapability() (string, error) {
+	p.nextToken() // consume "as"
+	if !isWord(p.curToken, "anything") || p.peekToken.Type != token.THAT {
+		return "", p.syntaxErr(fmt.Sprintf(msgFmtParamCapability, p.curToken.Value), hintParamCapability)
+	}
+	p.nextToken()
+	p.nextToken()
+	if !isWord(p.curToken, "can") {
+		return "", p.syntaxErr(fmt.Sprintf(msgFmtParamCapability, p.curToken.Value), hintParamCapability)
+	}
+	p.nextToken()
+	if p.curToken.Type != token.IDENTIFIER {
+		return "", p.syntaxErr(msgCapabilityAfterCan, hintParamCapability)
+	}
+	capability := p.curToken.Value
+	p.nextToken()
+	return capability, nil
+}
+
+// setParamAnnotation records what parameter i is annotated with, a
+// capability or a type, in a per-parameter list that is only created once
+// some parameter has one.
+func setParamAnnotation(annotations []string, i int, annotation string) []string {
+	annotations = padParamAnnotations(annotations, i+1)
+	annotations[i] = annotation
+	return annotations
+}
+
+// padParamAnnotations extends a non-nil per-parameter list to n entries, so
+// it lines up with the parameter list.
+func padParamAnnotations(annotations []string, n int) []string {
+	for len(annotations) < n {
+		annotations = append(annotations, "")
+	}
+	return annotations
+}
+
+// parseParamDefault parses an optional "(defaulting to EXPR)" after the
+// parameter at index i and records it in defaults. Once one parameter has a
+// default, every later one needs one too, so calls can always fill the
+// parameters from the left.
+func (p *Parser) parseParamDefault(defaults []ast.Expression, parameters []string, i int) ([]ast.Expression, error) {
+	if p.curToken.Type != token.LPAREN || !isWord(p.peekToken, "defaulting") {
+		if defaults != nil {
+			return nil, p.syntaxErr(fmt.Sprintf(msgFmtParamNeedsDefault, parameters[i]), hintParamDefault)
+		}
+		return nil, nil
+	}
+	p.nextToken() // consume "("
+	p.nextToken() // consume "defaulting"
+	if p.curToken.Type != token.TO {
+		return nil, p.syntaxErr(fmt.Sprintf(msgFmtDefaultTo, p.curToken.Value), hintParamDefault)
+	}
+	p.nextToken()
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.curToken.Type != token.RPAREN {
+		return nil, p.syntaxErr(msgDefaultClose, hintParamDefault)
+	}
+	p.nextToken()
+	for len(defaults) < i {
+		defaults = append(defaults, nil)
+	}
+	return append(defaults, value), nil
+}
+
+// parseRestParameter parses "any number of values" in a parameter list and
+// returns the parameter's name. It has to be the last parameter, and it
+// takes no default, type or capability.
+func (p *Parser) parseRestParameter() (string, error) {
+	p.nextToken() // consume "any"
+	p.nextToken() // consume "number"
+	if p.curToken.Type != token.OF || p.peekToken.Type != token.IDENTIFIER {
+		return "", p.syntaxErr(fmt.Sprintf(msgFmtRestParamName, p.curToken.Value), hintRestParam)
+	}
+	p.nextToken()
+	name := p.curToken.Value
+	p.nextToken()
+	switch {
+	case p.curToken.Type == token.AS:
+		return "", p.syntaxErr(fmt.Sprintf(msgFmtRestParamAs, name), hintRestParam)
+	case p.curToken.Type == token.LPAREN:
+		return "", p.syntaxErr(fmt.Sprintf(msgFmtRestParamDefault, name), hintRestParam)
+	case p.curToken.Type == token.AND && p.peekToken.Type != token.DOES:
+		return "", p.syntaxErr(fmt.Sprintf(msgFmtRestParamLast, name), hintRestParam)
+	}
+	return name, nil
+}
+
+// isWord reports whether tok is the plain word w, ignoring case.
+func isWord(tok token.Token, w string) bool {
+	return tok.Type == token.IDENTIFIER && strings.ToLower(tok.Value) == w
+}
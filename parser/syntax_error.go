@@ -62,7 +62,7 @@ func tokenFriendlyName(t token.Type) string {
 		return "a name"
 	case token.NUMBER:
 		return "a number"
-	case token.STRING:
+	case token.STRING, token.INTERPOLATED_STRING:
 		return "some text (in quotes)"
 	case token.BE:
 		return "the word 'be'"
@@ -136,7 +136,7 @@ func tokenFriendlyValue(t token.Type, value string) string {
 		return fmt.Sprintf("the name '%s'", value)
 	case token.NUMBER:
 		return fmt.Sprintf("the number %s", value)
-	case token.STRING:
+	case token.STRING, token.INTERPOLATED_STRING:
 		return fmt.Sprintf("the text %q", value)
 	case token.EOF:
 		return "the end of the file"
@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/token"
+)
+
+// parseBackground parses a background task:
+//
+//	Do the following in the background and call it job:
+//	    Print "working".
+//	thats it.
+//
+// "background" is matched as a plain identifier so that it does not become a
+// reserved word.
+func (p *Parser) parseBackground() (ast.Statement, error) {
+	line := p.curToken.Line
+	p.nextToken() // consume DO
+
+	if p.curToken.Type != token.THE || p.peekToken.Type != token.FOLLOWING {
+		return nil, p.syntaxErr(msgBackgroundForm, hintBackground)
+	}
+	p.nextToken()
+	p.nextToken()
+
+	if p.curToken.Type != token.IN || p.peekToken.Type != token.THE {
+		return nil, p.syntaxErr(msgBackgroundForm, hintBackground)
+	}
+	p.nextToken()
+	p.nextToken()
+	if !isWord(p.curToken, "background") {
+		return nil, p.syntaxErr(msgBackgroundForm, hintBackground)
+	}
+	p.nextToken()
+
+	if p.curToken.Type != token.AND || p.peekToken.Type != token.CALL {
+		return nil, p.syntaxErr(msgBackgroundForm, hintBackground)
+	}
+	p.nextToken()
+	p.nextToken()
+
+	if err := p.expectToken(token.IT); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	if p.curToken.Type != token.IDENTIFIER {
+		return nil, p.syntaxErr(msgTaskName, hintBackground)
+	}
+	name := p.curToken.Value
+	p.nextToken()
+
+	if err := p.expectToken(token.COLON); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	// The task runs on its own, so "Give back" inside it cannot reach the
+	// loop consuming an enclosing generator.
+	body, err := p.parseBody(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectToken(token.THATS); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectToken(token.IT); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &ast.BackgroundStatement{Name: name, Body: body, Line: line}, nil
+}
+
+// parseWaitForTask parses the rest of "Wait for job." once parseSleepStatement
+// has consumed "wait for" and found no duration.
+func (p *Parser) parseWaitForTask(line int) (ast.Statement, error) {
+	task, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	return &ast.WaitStatement{Task: task, Line: line}, nil
+}
+
+// parseSend parses "Send <value> to <channel>.". "send" is matched as a
+// plain identifier.
+func (p *Parser) parseSend() (ast.Statement, error) {
+	line := p.curToken.Line
+	p.nextToken() // consume "send"
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.curToken.Type != token.TO {
+		return nil, p.syntaxErr(msgSendTo, hintSend)
+	}
+	p.nextToken()
+
+	channel, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &ast.SendStatement{Value: value, Channel: channel, Line: line}, nil
+}
+
+// parseReceive parses "Receive from <channel> into <name>.". Like Ask, it
+// becomes an assignment, so the variable need not be declared first.
+func (p *Parser) parseReceive() (ast.Statement, error) {
+	line := p.curToken.Line
+	p.nextToken() // consume "receive"
+
+	if p.curToken.Type != token.FROM {
+		return nil, p.syntaxErr(msgReceiveFrom, hintReceive)
+	}
+	p.nextToken()
+
+	channel, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !isWord(p.curToken, "into") || p.peekToken.Type != token.IDENTIFIER {
+		return nil, p.syntaxErr(msgReceiveInto, hintReceive)
+	}
+	p.nextToken()
+	name := p.curToken.Value
+	p.nextToken()
+
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &ast.Assignment{
+		Name:  name,
+		Value: &ast.ReceiveExpression{Channel: channel},
+		Line:  line,
+	}, nil
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/token"
+	"strings"
+)
+
+// isTestBlock reports whether cur and peek start a test block: "Test"
+// followed by the test's name. "test" is matched as a plain identifier so
+// that it does not become a reserved word.
+func isTestBlock(cur, peek token.Token) bool {
+	return cur.Type == token.IDENTIFIER && strings.EqualFold(cur.Value, "test") && peek.Type == token.STRING
+}
+
+// parseTestBlock parses a test block:
+//
+//	Test "adding works" by doing the following:
+//	    Make sure that 1 + 1 is equal to 2.
+//	thats it.
+func (p *Parser) parseTestBlock() (ast.Statement, error) {
+	line := p.curToken.Line
+	p.nextToken() // consume "test"
+	name := p.curToken.Value
+	p.nextToken()
+
+	if p.curToken.Type != token.BY || p.peekToken.Type != token.DOING {
+		return nil, p.syntaxErr(msgTestForm, hintTestBlock)
+	}
+	p.nextToken()
+	p.nextToken()
+	if p.curToken.Type == token.THE {
+		p.nextToken()
+	}
+	if p.curToken.Type != token.FOLLOWING || p.peekToken.Type != token.COLON {
+		return nil, p.syntaxErr(msgTestForm, hintTestBlock)
+	}
+	p.nextToken()
+	p.nextToken()
+
+	body, err := p.parseBody(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expectToken(token.THATS); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectToken(token.IT); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+	if err := p.expectToken(token.PERIOD); err != nil {
+		return nil, err
+	}
+	p.nextToken()
+
+	return &ast.TestBlock{Name: name, Body: body, Line: line}, nil
+}
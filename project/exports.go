@@ -0,0 +1,54 @@
+package project
+
+import (
+	"fmt"
+
+	"github.com/Advik-B/english/ast"
+)
+
+// PrivateNames returns the names prog declares privately at its top level,
+// with "Declare privately x …" or "Declare private function f …". Files
+// that import prog cannot see them: importing everything skips them, a
+// module made with "as" leaves them out, and naming one in a selective
+// import is an error.
+func PrivateNames(prog *ast.Program) map[string]bool {
+	private := make(map[string]bool)
+	if prog == nil {
+		return private
+	}
+	for _, stmt := range prog.Statements {
+		switch s := stmt.(type) {
+		case *ast.VariableDecl:
+			if !s.IsPrivate {
+				continue
+			}
+			if s.Names != nil {
+				for _, name := range s.Names {
+					private[name] = true
+				}
+			} else {
+				private[s.Name] = true
+			}
+		case *ast.TypedVariableDecl:
+			if s.IsPrivate {
+				private[s.Name] = true
+			}
+		case *ast.FunctionDecl:
+			if s.IsPrivate {
+				private[s.Name] = true
+			}
+		}
+	}
+	return private
+}
+
+// PrivateError is the error for a selective import that names a private
+// declaration of the file it imports from.
+type PrivateError struct {
+	Name string // the name imported
+	File string // the import path as written
+}
+
+func (e *PrivateError) Error() string {
+	return fmt.Sprintf("'%s' is private to '%s' and cannot be imported", e.Name, e.File)
+}
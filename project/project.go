@@ -0,0 +1,220 @@
+// Package project finds the files English programs import.
+//
+// An import names a file as written, "geometry.abc", or a library without
+// its extension, "strings/slugify". ResolveImport looks for it in these
+// places, in order, and uses the first file it finds:
+//
+//  1. the directory of the file making the import;
+//  2. the project root: the nearest directory, that one or one above it,
+//     holding an english.project manifest;
+//  3. the root's libraries/ folder, where a project vendors its libraries;
+//  4. the library directories the manifest lists;
+//  5. the directories in the ENGLISH_PATH environment variable, separated as
+//     in PATH;
+//  6. the same places again starting from the current directory, as imports
+//     always have been.
+//
+// So "english run shop/main.abc" finds shop/geometry.abc and the project
+// shop/ belongs to from any directory, and an imported file's own imports
+// are looked for next to it.
+//
+// The manifest is a plain text file of "key: value" lines; blank lines and
+// lines starting with # are ignored:
+//
+//	# english.project
+//	name: shop
+//	libraries: vendor, ../shared
+//
+// Library directories are relative to the root. run, compile, transpile
+// --inline, the checker and the language server all resolve imports here, so
+// they always agree on which file an import means.
+package project
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ManifestName is the file that marks a project's root.
+const ManifestName = "english.project"
+
+// LibrariesDir is the folder under the root that holds vendored libraries.
+const LibrariesDir = "libraries"
+
+// PathVariable is the environment variable listing extra library directories.
+const PathVariable = "ENGLISH_PATH"
+
+// Project is a directory holding an english.project manifest.
+type Project struct {
+	Root      string   // the directory holding the manifest
+	Name      string   // the manifest's name, if it gives one
+	Libraries []string // the library directories it lists, relative to Root
+}
+
+// Find returns the project dir belongs to, looking in dir and then in each
+// directory above it. It returns nil, and no error, when there is none.
+func Find(dir string) (*Project, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		manifest := filepath.Join(abs, ManifestName)
+		if info, err := os.Stat(manifest); err == nil && !info.IsDir() {
+			return Load(manifest)
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return nil, nil
+		}
+		abs = parent
+	}
+}
+
+// Load reads the manifest at path.
+func Load(path string) (*Project, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := &Project{Root: filepath.Dir(path)}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected 'key: value', got %q", path, lineNum, line)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "name":
+			p.Name = value
+		case "libraries":
+			for _, dir := range strings.Split(value, ",") {
+				if dir = strings.TrimSpace(dir); dir != "" {
+					p.Libraries = append(p.Libraries, dir)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown setting '%s' (settings are name and libraries)",
+				path, lineNum, strings.TrimSpace(key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SearchPath returns the directories an import made from dir is looked for
+// in, in order, without duplicates.
+func SearchPath(dir string) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]bool)
+	add := func(d string) {
+		key := d
+		if abs, err := filepath.Abs(d); err == nil {
+			key = abs
+		}
+		if !seen[key] {
+			seen[key] = true
+			dirs = append(dirs, d)
+		}
+	}
+
+	add(dir)
+	proj, err := Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	if proj != nil {
+		add(proj.Root)
+		add(filepath.Join(proj.Root, LibrariesDir))
+		for _, lib := range proj.Libraries {
+			if !filepath.IsAbs(lib) {
+				lib = filepath.Join(proj.Root, lib)
+			}
+			add(lib)
+		}
+	}
+	for _, d := range filepath.SplitList(os.Getenv(PathVariable)) {
+		if d != "" {
+			add(d)
+		}
+	}
+	return dirs, nil
+}
+
+// Resolve returns the file importPath names, looked for from the current
+// directory. A path found there is returned as written.
+func Resolve(importPath string) (string, error) {
+	return ResolveFrom(".", importPath)
+}
+
+// ResolveImport returns the file importPath names when the file importer
+// imports it: it is looked for from importer's directory and then from the
+// current directory. An importer of "", a program that is not a file, looks
+// from the current directory only.
+func ResolveImport(importer, importPath string) (string, error) {
+	if importer == "" {
+		return Resolve(importPath)
+	}
+	file, err := ResolveFrom(filepath.Dir(importer), importPath)
+	if err == nil {
+		return file, nil
+	}
+	if file, cwdErr := Resolve(importPath); cwdErr == nil {
+		return file, nil
+	}
+	return "", err
+}
+
+// ResolveFrom returns the file importPath names, looked for from dir as
+// described in the package comment. A path without an extension has .abc
+// added, so "strings/slugify" finds strings/slugify.abc.
+func ResolveFrom(dir, importPath string) (string, error) {
+	names := []string{importPath}
+	if filepath.Ext(importPath) == "" {
+		names = append(names, importPath+".abc")
+	}
+	if filepath.IsAbs(importPath) {
+		for _, name := range names {
+			if isFile(name) {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("cannot find '%s'", importPath)
+	}
+
+	dirs, err := SearchPath(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range dirs {
+		for _, name := range names {
+			candidate := name
+			if d != "." {
+				candidate = filepath.Join(d, name)
+			}
+			if isFile(candidate) {
+				return candidate, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("cannot find '%s' (looked in %s)", importPath, strings.Join(dirs, ", "))
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,170 @@
+package project_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Advik-B/english/project"
+)
+
+// writeFiles creates each file under dir, with its parent directories.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindWalksUp(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		project.ManifestName: "# the shop\nname: shop\nlibraries: vendor, ../shared\n",
+		"src/app/main.abc":   "",
+	})
+
+	proj, err := project.Find(filepath.Join(root, "src", "app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proj == nil {
+		t.Fatal("expected to find the project")
+	}
+	if proj.Root != root || proj.Name != "shop" {
+		t.Errorf("got root %q and name %q", proj.Root, proj.Name)
+	}
+	if strings.Join(proj.Libraries, "|") != "vendor|../shared" {
+		t.Errorf("got libraries %v", proj.Libraries)
+	}
+}
+
+func TestFindWithoutManifest(t *testing.T) {
+	proj, err := project.Find(t.TempDir())
+	if err != nil || proj != nil {
+		t.Errorf("expected no project, got %v, %v", proj, err)
+	}
+}
+
+func TestLoadRejectsUnknownSettings(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{project.ManifestName: "name: shop\nlibrary: vendor\n"})
+	_, err := project.Load(filepath.Join(root, project.ManifestName))
+	if err == nil || !strings.Contains(err.Error(), ":2: unknown setting 'library'") {
+		t.Errorf("expected an unknown setting error on line 2, got %v", err)
+	}
+}
+
+func TestResolveFromSearchOrder(t *testing.T) {
+	root := t.TempDir()
+	extra := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		project.ManifestName:            "libraries: vendor\n",
+		"src/local.abc":                 "",
+		"shared.abc":                    "",
+		"libraries/strings/slugify.abc": "",
+		"vendor/money.abc":              "",
+		"libraries/both.abc":            "",
+		"vendor/both.abc":               "",
+	})
+	writeFiles(t, extra, map[string]string{"greet.abc": ""})
+	t.Setenv(project.PathVariable, extra)
+
+	src := filepath.Join(root, "src")
+	for _, tc := range []struct{ importPath, want string }{
+		{"local.abc", filepath.Join(src, "local.abc")},
+		{"shared.abc", filepath.Join(root, "shared.abc")},
+		{"strings/slugify", filepath.Join(root, "libraries", "strings", "slugify.abc")},
+		{"money", filepath.Join(root, "vendor", "money.abc")},
+		{"both.abc", filepath.Join(root, "libraries", "both.abc")},
+		{"greet", filepath.Join(extra, "greet.abc")},
+	} {
+		got, err := project.ResolveFrom(src, tc.importPath)
+		if err != nil {
+			t.Errorf("%s: %v", tc.importPath, err)
+		} else if got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.importPath, got, tc.want)
+		}
+	}
+
+	_, err := project.ResolveFrom(src, "missing")
+	if err == nil || !strings.Contains(err.Error(), "cannot find 'missing' (looked in ") {
+		t.Errorf("expected a cannot find error, got %v", err)
+	}
+}
+
+func TestResolveKeepsPathsFoundInCurrentDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"lib/util.abc": ""})
+	t.Chdir(dir)
+
+	got, err := project.Resolve("lib/util")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != filepath.Join("lib", "util.abc") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestResolveImportStartsFromImporter(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"shop/main.abc":     "",
+		"shop/geometry.abc": "",
+		"geometry.abc":      "",
+		"only_here.abc":     "",
+	})
+	t.Chdir(dir)
+
+	main := filepath.Join("shop", "main.abc")
+	for _, tc := range []struct{ importer, importPath, want string }{
+		{main, "geometry.abc", filepath.Join("shop", "geometry.abc")},
+		{main, "only_here.abc", "only_here.abc"},
+		{"", "geometry.abc", "geometry.abc"},
+	} {
+		got, err := project.ResolveImport(tc.importer, tc.importPath)
+		if err != nil {
+			t.Errorf("%s from %q: %v", tc.importPath, tc.importer, err)
+		} else if got != tc.want {
+			t.Errorf("%s from %q: got %q, want %q", tc.importPath, tc.importer, got, tc.want)
+		}
+	}
+}
+
+func TestRegistryReportsCycles(t *testing.T) {
+	r := project.NewRegistry("a.abc")
+	if err := r.Begin("b.abc", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Begin("c.abc", 5); err != nil {
+		t.Fatal(err)
+	}
+	err := r.Begin("./a.abc", 1)
+	cycle, ok := err.(*project.ImportCycleError)
+	if !ok {
+		t.Fatalf("expected an ImportCycleError, got %v", err)
+	}
+	if want := "circular import: a.abc → b.abc → c.abc → ./a.abc"; cycle.ImportCycleMessage() != want {
+		t.Errorf("got %q, want %q", cycle.ImportCycleMessage(), want)
+	}
+	want := "a.abc, line 2 imports b.abc|b.abc, line 5 imports c.abc|c.abc, line 1 imports ./a.abc"
+	if got := strings.Join(cycle.ImportChain(), "|"); got != want {
+		t.Errorf("got chain %q, want %q", got, want)
+	}
+
+	r.End()
+	r.Store("c.abc", 42)
+	if v, ok := r.Lookup("./c.abc"); !ok || v != 42 {
+		t.Errorf("expected c.abc to be found once stored, got %v, %v", v, ok)
+	}
+	if err := r.Begin("c.abc", 3); err != nil {
+		t.Errorf("a file that has finished is not a cycle, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package project
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Registry keeps track of the modules of one run, so each file runs at most
+// once however many files import it, and a file that ends up importing
+// itself is reported instead of recursing forever.
+//
+// A VM calls Begin before running an imported file and End when it is done,
+// then Store to keep what the file declared; Lookup finds it again for the
+// next import of the same file. What is stored is up to the VM.
+type Registry struct {
+	loading []loadingFile
+	loaded  map[string]interface{}
+}
+
+// loadingFile is a file being run: the program itself or a file it imports,
+// directly or not. line is the line of the file before it on the stack that
+// imports it.
+type loadingFile struct {
+	key  string
+	name string
+	line int
+}
+
+// NewRegistry returns the registry for a run of the program in main, or of
+// a program that is not a file when main is "".
+func NewRegistry(main string) *Registry {
+	r := &Registry{loaded: make(map[string]interface{})}
+	if main != "" {
+		r.loading = append(r.loading, loadingFile{key: fileKey(main), name: main})
+	}
+	return r
+}
+
+// Lookup returns what was stored for file by an earlier import.
+func (r *Registry) Lookup(file string) (interface{}, bool) {
+	v, ok := r.loaded[fileKey(file)]
+	return v, ok
+}
+
+// Store records what running file declared.
+func (r *Registry) Store(file string, module interface{}) {
+	r.loaded[fileKey(file)] = module
+}
+
+// Begin records that file is about to run because the file currently
+// running imports it at line. It returns an *ImportCycleError when file is
+// already running, that is when it imports itself, directly or not.
+func (r *Registry) Begin(file string, line int) error {
+	key := fileKey(file)
+	for i, f := range r.loading {
+		if f.key != key {
+			continue
+		}
+		cycle := &ImportCycleError{}
+		for _, g := range r.loading[i:] {
+			cycle.Files = append(cycle.Files, g.name)
+		}
+		for _, g := range r.loading[i+1:] {
+			cycle.Lines = append(cycle.Lines, g.line)
+		}
+		cycle.Files = append(cycle.Files, file)
+		cycle.Lines = append(cycle.Lines, line)
+		return cycle
+	}
+	r.loading = append(r.loading, loadingFile{key: key, name: file, line: line})
+	return nil
+}
+
+// End records that the file passed to the matching Begin has finished.
+func (r *Registry) End() {
+	r.loading = r.loading[:len(r.loading)-1]
+}
+
+// fileKey identifies a file however the path to it is written.
+func fileKey(file string) string {
+	if abs, err := filepath.Abs(file); err == nil {
+		return abs
+	}
+	return filepath.Clean(file)
+}
+
+// ImportCycleError is raised when files import each other in a circle.
+// Files is the chain of imports, starting and ending with the same file, and
+// Lines[i] is the line of Files[i] that imports Files[i+1].
+type ImportCycleError struct {
+	Files []string
+	Lines []int
+}
+
+func (e *ImportCycleError) Error() string {
+	return e.ImportCycleMessage() + " (" + strings.Join(e.ImportChain(), ", ") + ")"
+}
+
+// ImportCycleMessage returns the chain of files, as in
+// "circular import: a.abc → b.abc → a.abc".
+func (e *ImportCycleError) ImportCycleMessage() string {
+	return "circular import: " + strings.Join(e.Files, " → ")
+}
+
+// ImportChain returns each import of the chain, as in
+// "a.abc, line 1 imports b.abc".
+func (e *ImportCycleError) ImportChain() []string {
+	steps := make([]string, len(e.Lines))
+	for i, line := range e.Lines {
+		steps[i] = fmt.Sprintf("%s, line %d imports %s", e.Files[i], line, e.Files[i+1])
+	}
+	return steps
+}
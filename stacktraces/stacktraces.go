@@ -155,6 +155,28 @@ type SyntaxError interface {
 	SyntaxHint() string
 }
 
+// AssertionError is the interface satisfied by the error value a failed
+// "Make sure that" raises. It is rendered with the condition that did not
+// hold and what each side of it was. AssertionCondition returns "" for error
+// values that are not failed assertions; they render as generic errors.
+type AssertionError interface {
+	error
+	AssertionMessage() string
+	AssertionCondition() string
+	AssertionOperands() []string
+	AssertionLine() int
+	AssertionCallStack() []string
+}
+
+// ImportCycleError is the interface satisfied by the error raised when files
+// import each other in a circle. ImportCycleMessage names the chain of files,
+// and ImportChain describes each import in it, with its line.
+type ImportCycleError interface {
+	error
+	ImportCycleMessage() string
+	ImportChain() []string
+}
+
 // ─── Public API ──────────────────────────────────────────────────────────────
 
 // Render formats err as a pretty, colour-aware string.
@@ -188,6 +210,39 @@ func Print(err error) {
 func renderPlain(err error) string {
 	var sb strings.Builder
 
+	if ae, ok := err.(AssertionError); ok && ae.AssertionCondition() != "" {
+		if line := ae.AssertionLine(); line > 0 {
+			sb.WriteString(fmt.Sprintf("AssertionError at line %d: %s\n", line, ae.AssertionMessage()))
+		} else {
+			sb.WriteString("AssertionError: ")
+			sb.WriteString(ae.AssertionMessage())
+			sb.WriteString("\n")
+		}
+		sb.WriteString(fmt.Sprintf("  Make sure that %s\n", ae.AssertionCondition()))
+		for _, operand := range ae.AssertionOperands() {
+			sb.WriteString(fmt.Sprintf("    %s\n", operand))
+		}
+
+		stack := ae.AssertionCallStack()
+		if len(stack) > 0 {
+			sb.WriteString("\nCall Stack (most recent first):\n")
+			for i, frame := range stack {
+				sb.WriteString(fmt.Sprintf("  %d. %s\n", i+1, frame))
+			}
+		}
+		return sb.String()
+	}
+
+	if ie, ok := err.(ImportCycleError); ok {
+		sb.WriteString("Import Error: ")
+		sb.WriteString(ie.ImportCycleMessage())
+		sb.WriteString("\n")
+		for _, step := range ie.ImportChain() {
+			sb.WriteString(fmt.Sprintf("  %s\n", step))
+		}
+		return sb.String()
+	}
+
 	if re, ok := err.(RuntimeError); ok {
 		if line := re.RuntimeLine(); line > 0 {
 			sb.WriteString(fmt.Sprintf("Runtime Error at line %d: %s\n", line, re.RuntimeMessage()))
@@ -256,6 +311,16 @@ func renderPlain(err error) string {
 func renderColored(err error) string {
 	var sb strings.Builder
 
+	if ae, ok := err.(AssertionError); ok && ae.AssertionCondition() != "" {
+		renderAssertionError(&sb, ae)
+		return sb.String()
+	}
+
+	if ie, ok := err.(ImportCycleError); ok {
+		renderImportCycleError(&sb, ie)
+		return sb.String()
+	}
+
 	if re, ok := err.(RuntimeError); ok {
 		renderRuntimeError(&sb, re)
 		return sb.String()
@@ -317,6 +382,90 @@ func renderRuntimeError(sb *strings.Builder, re RuntimeError) {
 	sb.WriteString("\n\n")
 }
 
+func renderImportCycleError(sb *strings.Builder, ie ImportCycleError) {
+	sep := separatorStyle.Render(strings.Repeat("-", 50))
+
+	sb.WriteString("\n")
+	sb.WriteString(headerStyle.Render(" Import Error "))
+	sb.WriteString("\n")
+	sb.WriteString(sep)
+	sb.WriteString("\n\n")
+
+	sb.WriteString("  ")
+	sb.WriteString(labelStyle.Render("Message: "))
+	sb.WriteString(messageStyle.Render(ie.ImportCycleMessage()))
+	sb.WriteString("\n")
+
+	sb.WriteString("\n")
+	sb.WriteString("  ")
+	sb.WriteString(stackHeaderStyle.Render("Import Chain"))
+	sb.WriteString("\n")
+	sb.WriteString(sep)
+	sb.WriteString("\n")
+	for i, step := range ie.ImportChain() {
+		sb.WriteString("  ")
+		sb.WriteString(frameNumberStyle.Render(fmt.Sprintf("%2d.", i+1)))
+		sb.WriteString("  ")
+		sb.WriteString(frameNameStyle.Render(step))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(sep)
+	sb.WriteString("\n\n")
+}
+
+func renderAssertionError(sb *strings.Builder, ae AssertionError) {
+	sep := separatorStyle.Render(strings.Repeat("-", 50))
+
+	sb.WriteString("\n")
+	sb.WriteString(headerStyle.Render(" Assertion Failed "))
+	sb.WriteString("\n")
+	sb.WriteString(sep)
+	sb.WriteString("\n\n")
+
+	sb.WriteString("  ")
+	sb.WriteString(labelStyle.Render("Message: "))
+	sb.WriteString(messageStyle.Render(ae.AssertionMessage()))
+	sb.WriteString("\n")
+
+	if line := ae.AssertionLine(); line > 0 {
+		sb.WriteString("  ")
+		sb.WriteString(labelStyle.Render("Line:    "))
+		sb.WriteString(messageStyle.Render(fmt.Sprintf("%d", line)))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("  ")
+	sb.WriteString(labelStyle.Render("Check:   "))
+	sb.WriteString(highlight.HighlightInline("Make sure that "+ae.AssertionCondition(), true))
+	sb.WriteString("\n")
+	for _, operand := range ae.AssertionOperands() {
+		sb.WriteString("           ")
+		sb.WriteString(hintStyle.Render(operand))
+		sb.WriteString("\n")
+	}
+
+	stack := ae.AssertionCallStack()
+	if len(stack) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString("  ")
+		sb.WriteString(stackHeaderStyle.Render("Call Stack") + separatorStyle.Render(" (most recent first)"))
+		sb.WriteString("\n")
+		sb.WriteString(sep)
+		sb.WriteString("\n")
+		for i, frame := range stack {
+			sb.WriteString("  ")
+			sb.WriteString(frameNumberStyle.Render(fmt.Sprintf("%2d.", i+1)))
+			sb.WriteString("  ")
+			sb.WriteString(frameNameStyle.Render(frame))
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString(sep)
+	sb.WriteString("\n\n")
+}
+
 func renderCompileError(sb *strings.Builder, ce CompileError) {
 	sep := separatorStyle.Render(strings.Repeat("-", 50))
 
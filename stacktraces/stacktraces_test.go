@@ -395,3 +395,77 @@ func TestRender_SyntaxVsRuntime_DifferentHeaders(t *testing.T) {
 		t.Errorf("runtime output should not contain 'Syntax Error', got:\n%s", runtimeOut)
 	}
 }
+
+// fakeAssertionError is a test double that satisfies stacktraces.AssertionError.
+type fakeAssertionError struct {
+	msg      string
+	cond     string
+	operands []string
+	line     int
+}
+
+func (e *fakeAssertionError) Error() string                { return "AssertionError: " + e.msg }
+func (e *fakeAssertionError) AssertionMessage() string     { return e.msg }
+func (e *fakeAssertionError) AssertionCondition() string   { return e.cond }
+func (e *fakeAssertionError) AssertionOperands() []string  { return e.operands }
+func (e *fakeAssertionError) AssertionLine() int           { return e.line }
+func (e *fakeAssertionError) AssertionCallStack() []string { return []string{"<main>", "withdraw"} }
+
+func TestRender_AssertionError(t *testing.T) {
+	ae := &fakeAssertionError{
+		msg:      "negative balance",
+		cond:     "balance is at least 0",
+		operands: []string{"balance was -5"},
+		line:     4,
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	plain := stacktraces.Render(ae)
+	colored := stripANSI(stacktraces.RenderWithColor(ae, true))
+
+	for _, got := range []string{plain, colored} {
+		for _, want := range []string{"negative balance", "balance is at least 0", "balance was -5", "4", "withdraw"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected %q in output, got:\n%s", want, got)
+			}
+		}
+	}
+	if !strings.Contains(plain, "AssertionError at line 4: negative balance") {
+		t.Errorf("expected the AssertionError header in plain output, got:\n%s", plain)
+	}
+	if !strings.Contains(plain, "Make sure that balance is at least 0") {
+		t.Errorf("expected the failing check in plain output, got:\n%s", plain)
+	}
+}
+
+// ─── ImportCycleError ────────────────────────────────────────────────────────
+
+// fakeImportCycleError is a test double that satisfies stacktraces.ImportCycleError.
+type fakeImportCycleError struct{}
+
+func (e *fakeImportCycleError) Error() string { return e.ImportCycleMessage() }
+func (e *fakeImportCycleError) ImportCycleMessage() string {
+	return "circular import: a.abc → b.abc → a.abc"
+}
+func (e *fakeImportCycleError) ImportChain() []string {
+	return []string{"a.abc, line 1 imports b.abc", "b.abc, line 3 imports a.abc"}
+}
+
+func TestRender_ImportCycleError(t *testing.T) {
+	ie := &fakeImportCycleError{}
+
+	t.Setenv("NO_COLOR", "1")
+	plain := stacktraces.Render(ie)
+	colored := stripANSI(stacktraces.RenderWithColor(ie, true))
+
+	for _, got := range []string{plain, colored} {
+		for _, want := range []string{"a.abc → b.abc → a.abc", "a.abc, line 1 imports b.abc", "b.abc, line 3 imports a.abc"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected %q in output, got:\n%s", want, got)
+			}
+		}
+	}
+	if !strings.HasPrefix(plain, "Import Error: circular import:") {
+		t.Errorf("expected the Import Error header in plain output, got:\n%s", plain)
+	}
+}
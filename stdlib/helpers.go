@@ -30,6 +30,36 @@ func requireList(fn string, arg vm.Value) ([]interface{}, error) {
 	return lst, nil
 }
 
+func requireCallback(fn string, arg vm.Value) (*types.Callback, error) {
+	cb, ok := arg.(*types.Callback)
+	if !ok {
+		return nil, fmt.Errorf("TypeError: %s expects a function, got %s", fn, kindName(arg))
+	}
+	return cb, nil
+}
+
+// higherOrderFunctions are the built-ins that receive function arguments as
+// *types.Callback. Every other built-in gets the VM's plain function value.
+var higherOrderFunctions = map[string]bool{
+	"transform":   true,
+	"keep":        true,
+	"combine":     true,
+	"find_first":  true,
+	"count_where": true,
+}
+
+// unwrapCallbacks replaces each *types.Callback in args with the function
+// value it wraps, so built-ins that merely store or inspect a function (such
+// as append) never leak the wrapper back into user code.
+func unwrapCallbacks(args []vm.Value) []vm.Value {
+	for i, arg := range args {
+		if cb, ok := arg.(*types.Callback); ok {
+			args[i] = cb.Fn
+		}
+	}
+	return args
+}
+
 func requireLookupTable(fn string, arg vm.Value) (*types.LookupTableValue, error) {
 	lt, ok := arg.(*types.LookupTableValue)
 	if !ok {
@@ -40,7 +70,7 @@ func requireLookupTable(fn string, arg vm.Value) (*types.LookupTableValue, error
 
 func kindName(v vm.Value) string {
 	switch v.(type) {
-	case *vm.FunctionValue:
+	case *vm.FunctionValue, *types.Callback:
 		return "function"
 	case *vm.StructInstance:
 		return "struct"
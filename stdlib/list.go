@@ -369,10 +369,116 @@ func evalList(name string, args []vm.Value) (vm.Value, error) {
 			result[i] = []interface{}{lst[i], other[i]}
 		}
 		return result, nil
+	case "transform":
+		lst, fn, err := listAndCallback(name, args)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]interface{}, len(lst))
+		for i, item := range lst {
+			if result[i], err = fn.Call([]interface{}{item}); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case "keep":
+		lst, fn, err := listAndCallback(name, args)
+		if err != nil {
+			return nil, err
+		}
+		result := []interface{}{}
+		for _, item := range lst {
+			ok, err := callPredicate(name, fn, item)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				result = append(result, item)
+			}
+		}
+		return result, nil
+	case "combine":
+		lst, fn, err := listAndCallback(name, args)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 3 {
+			return nil, fmt.Errorf("combine expects a list, a function and a starting value")
+		}
+		acc := args[2]
+		for _, item := range lst {
+			if acc, err = fn.Call([]interface{}{acc, item}); err != nil {
+				return nil, err
+			}
+		}
+		return acc, nil
+	case "find_first":
+		lst, fn, err := listAndCallback(name, args)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range lst {
+			ok, err := callPredicate(name, fn, item)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return item, nil
+			}
+		}
+		return nil, nil
+	case "count_where":
+		lst, fn, err := listAndCallback(name, args)
+		if err != nil {
+			return nil, err
+		}
+		count := 0
+		for _, item := range lst {
+			ok, err := callPredicate(name, fn, item)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				count++
+			}
+		}
+		return float64(count), nil
 	}
 	return nil, vm.NewRuntimeError("unknown list function: " + name)
 }
 
+// listAndCallback validates the (list, function) arguments shared by the
+// higher-order list functions.
+func listAndCallback(name string, args []vm.Value) ([]interface{}, *types.Callback, error) {
+	if len(args) < 2 {
+		return nil, nil, fmt.Errorf("%s expects a list and a function", name)
+	}
+	lst, err := requireList(name, args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	fn, err := requireCallback(name, args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return lst, fn, nil
+}
+
+// callPredicate calls fn with item and requires a boolean answer. Errors
+// raised inside fn are returned unchanged so the VM can route them to the
+// right error handler.
+func callPredicate(name string, fn *types.Callback, item interface{}) (bool, error) {
+	res, err := fn.Call([]interface{}{item})
+	if err != nil {
+		return false, err
+	}
+	b, ok := res.(bool)
+	if !ok {
+		return false, fmt.Errorf("TypeError: %s expects '%s' to return a boolean, got %s", name, fn.Name, kindName(res))
+	}
+	return b, nil
+}
+
 func registerListFunctions(env *vm.Environment) {
 	env.DefineFunction("append", &vm.FunctionValue{Name: "append", Parameters: []string{"list", "item"}, Body: nil, Closure: env})
 	env.DefineFunction("remove", &vm.FunctionValue{Name: "remove", Parameters: []string{"list", "index"}, Body: nil, Closure: env})
@@ -394,4 +500,9 @@ func registerListFunctions(env *vm.Environment) {
 	env.DefineFunction("product", &vm.FunctionValue{Name: "product", Parameters: []string{"list"}, Body: nil, Closure: env})
 	env.DefineFunction("sorted_desc", &vm.FunctionValue{Name: "sorted_desc", Parameters: []string{"list"}, Body: nil, Closure: env})
 	env.DefineFunction("zip_with", &vm.FunctionValue{Name: "zip_with", Parameters: []string{"list", "other"}, Body: nil, Closure: env})
+	env.DefineFunction("transform", &vm.FunctionValue{Name: "transform", Parameters: []string{"list", "function"}, Body: nil, Closure: env})
+	env.DefineFunction("keep", &vm.FunctionValue{Name: "keep", Parameters: []string{"list", "function"}, Body: nil, Closure: env})
+	env.DefineFunction("combine", &vm.FunctionValue{Name: "combine", Parameters: []string{"list", "function", "start"}, Body: nil, Closure: env})
+	env.DefineFunction("find_first", &vm.FunctionValue{Name: "find_first", Parameters: []string{"list", "function"}, Body: nil, Closure: env})
+	env.DefineFunction("count_where", &vm.FunctionValue{Name: "count_where", Parameters: []string{"list", "function"}, Body: nil, Closure: env})
 }
@@ -20,7 +20,13 @@ func Register(env *vm.Environment) {
 }
 
 // Eval evaluates a built-in function by name with the provided arguments.
+//
+// Function arguments arrive wrapped in *types.Callback; only the higher-order
+// list functions keep the wrapper, all others see the plain function value.
 func Eval(name string, args []vm.Value) (vm.Value, error) {
+	if !higherOrderFunctions[name] {
+		args = unwrapCallbacks(args)
+	}
 	switch name {
 	// ── Math ──────────────────────────────────────────────────────────────────
 	case "sqrt", "pow", "abs", "floor", "ceil", "round", "min", "max",
@@ -46,7 +52,8 @@ func Eval(name string, args []vm.Value) (vm.Value, error) {
 	case "append", "remove", "insert", "sort", "reverse", "sum", "unique",
 		"first", "last", "flatten", "count", "slice",
 		"average", "min_value", "max_value", "any_true", "all_true",
-		"product", "sorted_desc", "zip_with":
+		"product", "sorted_desc", "zip_with",
+		"transform", "keep", "combine", "find_first", "count_where":
 		return evalList(name, args)
 
 	// ── I/O ───────────────────────────────────────────────────────────────────
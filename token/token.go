@@ -14,6 +14,7 @@ const (
 	// Literals
 	NUMBER
 	STRING
+	INTERPOLATED_STRING // text with embedded "{expression}" parts; Value holds the raw contents
 	IDENTIFIER
 
 	// Keywords
@@ -109,6 +110,7 @@ const (
 	HAS
 	ENTRY
 	RANGE
+	WHEN
 
 	// PLEASE is emitted for politeness prefixes: "please", "kindly",
 	// "could you", and "would you kindly". When --minimum-politeness is
@@ -141,6 +143,15 @@ const (
 	ASSIGN
 	DOTDOT // ".." range operator
 
+	// Arithmetic and bitwise operators (multi-word)
+	POWER        // "to the power of"
+	FLOOR_DIVIDE // "divided evenly by" — floor division
+	BIT_AND      // "bitwise and"
+	BIT_OR       // "bitwise or"
+	BIT_XOR      // "bitwise xor"
+	SHIFT_LEFT   // "shifted left by"
+	SHIFT_RIGHT  // "shifted right by"
+
 	// Comparison operators (multi-word)
 	IS_EQUAL_TO
 	IS_LESS_THAN
@@ -185,6 +196,8 @@ func (t Type) String() string {
 		return "NUMBER"
 	case STRING:
 		return "STRING"
+	case INTERPOLATED_STRING:
+		return "INTERPOLATED_STRING"
 	case IDENTIFIER:
 		return "IDENTIFIER"
 	case DECLARE:
@@ -367,6 +380,8 @@ func (t Type) String() string {
 		return "ENTRY"
 	case RANGE:
 		return "RANGE"
+	case WHEN:
+		return "WHEN"
 	case PLEASE:
 		return "PLEASE"
 	case SLEEP:
@@ -399,6 +414,20 @@ func (t Type) String() string {
 		return "ASSIGN"
 	case DOTDOT:
 		return "DOTDOT"
+	case POWER:
+		return "POWER"
+	case FLOOR_DIVIDE:
+		return "FLOOR_DIVIDE"
+	case BIT_AND:
+		return "BIT_AND"
+	case BIT_OR:
+		return "BIT_OR"
+	case BIT_XOR:
+		return "BIT_XOR"
+	case SHIFT_LEFT:
+		return "SHIFT_LEFT"
+	case SHIFT_RIGHT:
+		return "SHIFT_RIGHT"
 	case IS_EQUAL_TO:
 		return "IS_EQUAL_TO"
 	case IS_LESS_THAN:
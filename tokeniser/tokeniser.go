@@ -6,6 +6,7 @@
 package tokeniser
 
 import (
+	"fmt"
 	"github.com/Advik-B/english/token"
 	"strings"
 	"unicode"
@@ -71,7 +72,7 @@ func isIdentChar(b byte) bool {
 // allow a following 's to be treated as the possessive marker.
 func isPossessiveContext(t token.Type) bool {
 	switch t {
-	case token.IDENTIFIER, token.STRING, token.NUMBER,
+	case token.IDENTIFIER, token.STRING, token.INTERPOLATED_STRING, token.NUMBER,
 		token.RPAREN, token.RBRACKET, token.TRUE, token.FALSE:
 		return true
 	}
@@ -101,41 +102,144 @@ func (l *Lexer) readComment() (token.Token, bool) {
 	return token.Token{Type: token.COMMENT, Value: text, Line: line, Col: col, Pos: pos}, true
 }
 
-func (l *Lexer) readString(quote byte) string {
+// readString reads a quoted text literal. It returns the literal's contents
+// with escapes decoded, or — when the literal embeds "{expression}" parts —
+// the raw contents and interpolated == true. Raw contents are split later
+// with SplitInterpolation.
+func (l *Lexer) readString(quote byte) (value string, interpolated bool) {
 	l.readChar() // skip opening quote
-	var result strings.Builder
+	start := l.position
 	for l.ch != quote && l.ch != 0 {
-		if l.ch == '\\' {
-			l.readChar() // skip backslash
-			switch l.ch {
-			case 'n':
-				result.WriteByte('\n')
-			case 't':
-				result.WriteByte('\t')
-			case 'r':
-				result.WriteByte('\r')
-			case '\\':
-				result.WriteByte('\\')
-			case '"':
-				result.WriteByte('"')
-			case '\'':
-				result.WriteByte('\'')
-			default:
-				// For unrecognized escape sequences (e.g., \x), preserve the backslash
-				// and the character as-is. This allows unknown sequences to pass through
-				// without error, though they won't have special meaning.
-				result.WriteByte('\\')
-				result.WriteByte(l.ch)
-			}
-		} else {
-			result.WriteByte(l.ch)
+		switch {
+		case l.ch == '\\':
+			l.readChar() // skip backslash; the escaped character is never special
+		case l.ch == '{' && l.peekChar() != '}':
+			interpolated = true
 		}
 		l.readChar()
 	}
+	end := l.position
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	raw := l.input[start:end]
 	l.readChar() // skip closing quote
+	if interpolated {
+		return raw, true
+	}
+	return decodeEscapes(raw), false
+}
+
+// decodeEscapes expands the backslash escapes of a text literal.
+func decodeEscapes(raw string) string {
+	if !strings.Contains(raw, "\\") {
+		return raw
+	}
+	var result strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' || i+1 >= len(raw) {
+			result.WriteByte(raw[i])
+			continue
+		}
+		i++ // skip backslash
+		switch raw[i] {
+		case 'n':
+			result.WriteByte('\n')
+		case 't':
+			result.WriteByte('\t')
+		case 'r':
+			result.WriteByte('\r')
+		case '\\', '"', '\'', '{', '}':
+			result.WriteByte(raw[i])
+		default:
+			// For unrecognized escape sequences (e.g., \x), preserve the backslash
+			// and the character as-is. This allows unknown sequences to pass through
+			// without error, though they won't have special meaning.
+			result.WriteByte('\\')
+			result.WriteByte(raw[i])
+		}
+	}
 	return result.String()
 }
 
+// StringPart is one piece of an interpolated text literal: either literal
+// text or the source of an embedded "{expression}".
+type StringPart struct {
+	Text   string // literal text with escapes decoded, or the expression source
+	Raw    string // the piece exactly as written (expressions exclude the braces)
+	IsExpr bool
+}
+
+// SplitInterpolation splits the raw contents of an INTERPOLATED_STRING token
+// into literal text and embedded expressions. "{}" and "\{" stay literal.
+func SplitInterpolation(raw string) ([]StringPart, error) {
+	var parts []StringPart
+	litStart := 0
+	flush := func(end int) {
+		if end > litStart {
+			text := raw[litStart:end]
+			parts = append(parts, StringPart{Text: decodeEscapes(text), Raw: text})
+		}
+	}
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\':
+			i++
+		case raw[i] == '{' && i+1 < len(raw) && raw[i+1] == '}':
+			i++
+		case raw[i] == '{':
+			end := matchingBrace(raw, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '{' in text")
+			}
+			expr := raw[i+1 : end]
+			if strings.TrimSpace(expr) == "" {
+				return nil, fmt.Errorf("empty '{ }' in text")
+			}
+			flush(i)
+			parts = append(parts, StringPart{Text: expr, Raw: expr, IsExpr: true})
+			litStart = end + 1
+			i = end
+		}
+	}
+	flush(len(raw))
+	return parts, nil
+}
+
+// matchingBrace returns the index of the '}' closing the '{' at open, or -1.
+// Braces inside quoted text within the expression are ignored; an apostrophe
+// directly after a word is a possessive, not a quote.
+func matchingBrace(raw string, open int) int {
+	depth := 0
+	for i := open; i < len(raw); i++ {
+		switch c := raw[i]; {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case c == '"' || (c == '\'' && (i == 0 || !isIdentChar(raw[i-1]))):
+			for i++; i < len(raw) && raw[i] != c; i++ {
+				if raw[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// stringToken reads the text literal starting at the current quote.
+func (l *Lexer) stringToken(line, col, pos int) token.Token {
+	str, interpolated := l.readString(l.ch)
+	if interpolated {
+		return token.Token{Type: token.INTERPOLATED_STRING, Value: str, Line: line, Col: col, Pos: pos}
+	}
+	return token.Token{Type: token.STRING, Value: str, Line: line, Col: col, Pos: pos}
+}
+
 func (l *Lexer) readNumber() string {
 	start := l.position
 	for unicode.IsDigit(rune(l.ch)) {
@@ -261,6 +365,7 @@ var keywords = map[string]token.Type{
 	"has":        token.HAS,
 	"entry":      token.ENTRY,
 	"range":      token.RANGE,
+	"when":       token.WHEN,
 	// Politeness prefixes – consumed by the parser before any statement.
 	"please": token.PLEASE,
 	"kindly": token.PLEASE,
@@ -305,6 +410,11 @@ func (l *Lexer) NextToken() token.Token {
 		strings.ToLower(l.input[l.position:l.position+3]) == "has" {
 		return l.tryMultiWordComparison(line, col, pos)
 	}
+	// Arithmetic and bitwise phrases: "to the power of", "divided evenly by",
+	// "bitwise and", "shifted left by", …
+	if arithmeticPhraseStarts[l.peekWord()] {
+		return l.tryMultiWordComparison(line, col, pos)
+	}
 
 	var tok token.Token
 
@@ -366,12 +476,10 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar() // consume s
 			tok = token.Token{Type: token.POSSESSIVE, Value: "'s", Line: line, Col: col, Pos: pos}
 		} else {
-			str := l.readString(l.ch)
-			tok = token.Token{Type: token.STRING, Value: str, Line: line, Col: col, Pos: pos}
+			tok = l.stringToken(line, col, pos)
 		}
 	case '"':
-		str := l.readString(l.ch)
-		tok = token.Token{Type: token.STRING, Value: str, Line: line, Col: col, Pos: pos}
+		tok = l.stringToken(line, col, pos)
 	case '\n':
 		tok = token.Token{Type: token.NEWLINE, Value: "\n", Line: line, Col: col, Pos: pos}
 		l.readChar()
@@ -434,7 +542,27 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-// tryMultiWordComparison handles multi-word operators like "is equal to".
+// arithmeticPhraseStarts holds the first words of the multi-word arithmetic
+// and bitwise operators recognised by tryMultiWordComparison.
+var arithmeticPhraseStarts = map[string]bool{
+	"to":      true,
+	"divided": true,
+	"bitwise": true,
+	"shifted": true,
+}
+
+// peekWord returns the lower-cased identifier starting at the current
+// position without consuming it.
+func (l *Lexer) peekWord() string {
+	end := l.position
+	for end < len(l.input) && isIdentChar(l.input[end]) {
+		end++
+	}
+	return strings.ToLower(l.input[l.position:end])
+}
+
+// tryMultiWordComparison handles multi-word operators like "is equal to" and
+// "to the power of".
 // line, col, and pos are the position of the first character of the phrase,
 // already captured by the caller.
 func (l *Lexer) tryMultiWordComparison(line, col, pos int) token.Token {
@@ -482,9 +610,9 @@ func (l *Lexer) tryMultiWordComparison(line, col, pos int) token.Token {
 			tokenType = token.IS_LESS_THAN
 		case "is greater than":
 			tokenType = token.IS_GREATER_THAN
-		case "is less than or equal to":
+		case "is less than or equal to", "is at most":
 			tokenType = token.IS_LESS_EQUAL
-		case "is greater than or equal to":
+		case "is greater than or equal to", "is at least":
 			tokenType = token.IS_GREATER_EQUAL
 		case "is not equal to":
 			tokenType = token.IS_NOT_EQUAL
@@ -500,6 +628,20 @@ func (l *Lexer) tryMultiWordComparison(line, col, pos int) token.Token {
 			tokenType = token.ISNT_TRUE
 		case "isn't false", "is not false":
 			tokenType = token.ISNT_FALSE
+		case "to the power of":
+			tokenType = token.POWER
+		case "divided evenly by":
+			tokenType = token.FLOOR_DIVIDE
+		case "bitwise and":
+			tokenType = token.BIT_AND
+		case "bitwise or":
+			tokenType = token.BIT_OR
+		case "bitwise xor":
+			tokenType = token.BIT_XOR
+		case "shifted left by":
+			tokenType = token.SHIFT_LEFT
+		case "shifted right by":
+			tokenType = token.SHIFT_RIGHT
 		default:
 			tokenType = token.ERROR
 		}
@@ -190,3 +190,85 @@ func TestTokenizeForHighlight_UnterminatedString(t *testing.T) {
 		})
 	}
 }
+
+// TestNewLexer_ArithmeticPhrases checks the multi-word arithmetic and
+// bitwise operators, and that their leading words still lex normally on
+// their own.
+func TestNewLexer_ArithmeticPhrases(t *testing.T) {
+	cases := []struct {
+		src  string
+		want []token.Type
+	}{
+		{"x to the power of 2", []token.Type{token.IDENTIFIER, token.POWER, token.NUMBER, token.EOF}},
+		{"x Divided Evenly By 2", []token.Type{token.IDENTIFIER, token.FLOOR_DIVIDE, token.NUMBER, token.EOF}},
+		{"x bitwise and y", []token.Type{token.IDENTIFIER, token.BIT_AND, token.IDENTIFIER, token.EOF}},
+		{"x bitwise or y", []token.Type{token.IDENTIFIER, token.BIT_OR, token.IDENTIFIER, token.EOF}},
+		{"x bitwise xor y", []token.Type{token.IDENTIFIER, token.BIT_XOR, token.IDENTIFIER, token.EOF}},
+		{"x shifted left by 1", []token.Type{token.IDENTIFIER, token.SHIFT_LEFT, token.NUMBER, token.EOF}},
+		{"x shifted right by 1", []token.Type{token.IDENTIFIER, token.SHIFT_RIGHT, token.NUMBER, token.EOF}},
+		{"Set x to the result", []token.Type{token.SET, token.IDENTIFIER, token.TO, token.THE, token.IDENTIFIER, token.EOF}},
+		{"the remainder of x divided by 2", []token.Type{token.THE, token.REMAINDER, token.OF, token.IDENTIFIER, token.DIVIDED, token.BY, token.NUMBER, token.EOF}},
+	}
+	for _, c := range cases {
+		toks := tokeniser.NewLexer(c.src).TokenizeAll()
+		if len(toks) != len(c.want) {
+			t.Errorf("%q: want %d tokens, got %d: %v", c.src, len(c.want), len(toks), toks)
+			continue
+		}
+		for i, tt := range c.want {
+			if toks[i].Type != tt {
+				t.Errorf("%q token[%d]: want %s, got %s", c.src, i, tt, toks[i].Type)
+			}
+		}
+	}
+}
+
+// ─── String interpolation ─────────────────────────────────────────────────────
+
+func TestNewLexer_InterpolatedString(t *testing.T) {
+	cases := []struct {
+		src   string
+		typ   token.Type
+		value string
+	}{
+		{`"plain"`, token.STRING, "plain"},
+		{`"Hi {name}"`, token.INTERPOLATED_STRING, "Hi {name}"},
+		{`"empty {} braces"`, token.STRING, "empty {} braces"},
+		{`"escaped \{name\}"`, token.STRING, "escaped {name}"},
+		{`'Hi {"x"}'`, token.INTERPOLATED_STRING, `Hi {"x"}`},
+	}
+	for _, c := range cases {
+		toks := tokeniser.NewLexer(c.src).TokenizeAll()
+		if toks[0].Type != c.typ || toks[0].Value != c.value {
+			t.Errorf("%s: want %s %q, got %s %q", c.src, c.typ, c.value, toks[0].Type, toks[0].Value)
+		}
+	}
+}
+
+func TestSplitInterpolation(t *testing.T) {
+	parts, err := tokeniser.SplitInterpolation(`Hi {name}\n{add(1, "}")}!`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []tokeniser.StringPart{
+		{Text: "Hi ", Raw: "Hi "},
+		{Text: "name", Raw: "name", IsExpr: true},
+		{Text: "\n", Raw: `\n`},
+		{Text: `add(1, "}")`, Raw: `add(1, "}")`, IsExpr: true},
+		{Text: "!", Raw: "!"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("want %d parts, got %d: %+v", len(want), len(parts), parts)
+	}
+	for i := range want {
+		if parts[i] != want[i] {
+			t.Errorf("part[%d]: want %+v, got %+v", i, want[i], parts[i])
+		}
+	}
+
+	for _, bad := range []string{"Hi {name", "Hi {  }"} {
+		if _, err := tokeniser.SplitInterpolation(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
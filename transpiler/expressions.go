@@ -16,6 +16,12 @@ func (t *Transpiler) transpileExpr(expr ast.Expression) string {
 	switch e := expr.(type) {
 	case *ast.NumberLiteral:
 		return formatNumber(e.Value)
+	case *ast.DecimalLiteral:
+		if e.Whole {
+			return e.Digits
+		}
+		t.needsDecimal = true
+		return fmt.Sprintf("Decimal(%q)", e.Digits)
 	case *ast.StringLiteral:
 		return fmt.Sprintf("%q", e.Value)
 	case *ast.BooleanLiteral:
@@ -39,12 +45,20 @@ func (t *Transpiler) transpileExpr(expr ast.Expression) string {
 		return sanitizeIdent(e.Name)
 	case *ast.ListLiteral:
 		return t.transpileListLit(e.Elements)
+	case *ast.FunctionLiteral:
+		return t.transpileFunctionLit(e)
+	case *ast.InterpolatedString:
+		return t.transpileInterpolatedString(e)
 	case *ast.RangeLiteral:
 		return t.transpileRangeLit(e)
 	case *ast.ArrayLiteral:
 		return t.transpileListLit(e.Elements)
 	case *ast.LookupTableLiteral:
 		return "{}"
+	case *ast.ChannelLiteral:
+		return "_Channel()"
+	case *ast.ReceiveExpression:
+		return t.transpileExpr(e.Channel) + ".get()"
 	case *ast.BinaryExpression:
 		return t.transpileBinaryExpr(e)
 	case *ast.UnaryExpression:
@@ -104,6 +118,60 @@ func (t *Transpiler) transpileListLit(elements []ast.Expression) string {
 	return "[" + strings.Join(parts, ", ") + "]"
 }
 
+// transpileFunctionLit emits a lambda when the literal's body is a single
+// Return. Longer bodies cannot be a Python expression, so they are hoisted
+// into a helper def written just before the current line, and the helper's
+// name is used in place of the literal.
+func (t *Transpiler) transpileFunctionLit(e *ast.FunctionLiteral) string {
+	params := make([]string, len(e.Parameters))
+	for i, p := range e.Parameters {
+		params[i] = sanitizeIdent(p)
+	}
+	if len(e.Body) == 1 {
+		if ret, ok := e.Body[0].(*ast.ReturnStatement); ok && ret.Value != nil {
+			if len(params) == 0 {
+				return "lambda: " + t.transpileExpr(ret.Value)
+			}
+			return fmt.Sprintf("lambda %s: %s", strings.Join(params, ", "), t.transpileExpr(ret.Value))
+		}
+	}
+	t.anonCount++
+	name := fmt.Sprintf("_anonymous_%d", t.anonCount)
+	if t.indent == 0 {
+		t.ensureBlankLines(2)
+	}
+	t.transpileFunctionDef(name, e.Parameters, e.Body)
+	return name
+}
+
+// transpileInterpolatedString emits an f-string. Embedded expressions that
+// cannot appear inside an f-string (quotes, backslashes, a leading brace)
+// make the whole literal fall back to concatenation with str().
+func (t *Transpiler) transpileInterpolatedString(e *ast.InterpolatedString) string {
+	var sb strings.Builder
+	terms := make([]string, len(e.Parts))
+	fallback := false
+	for i, part := range e.Parts {
+		if lit, ok := part.(*ast.StringLiteral); ok {
+			terms[i] = fmt.Sprintf("%q", lit.Value)
+			text := terms[i][1 : len(terms[i])-1]
+			text = strings.ReplaceAll(text, "{", "{{")
+			sb.WriteString(strings.ReplaceAll(text, "}", "}}"))
+			continue
+		}
+		expr := t.transpileExpr(part)
+		terms[i] = "str(" + expr + ")"
+		if strings.ContainsAny(expr, "\"\\") || strings.HasPrefix(expr, "{") {
+			fallback = true
+		}
+		sb.WriteString("{" + expr + "}")
+	}
+	if fallback {
+		return "(" + strings.Join(terms, " + ") + ")"
+	}
+	return `f"` + sb.String() + `"`
+}
+
 func (t *Transpiler) transpileRangeLit(e *ast.RangeLiteral) string {
 	// Transpile range literals to Python range(...)
 	// Python's range is exclusive on the end, but English ranges are inclusive
@@ -148,6 +216,10 @@ func (t *Transpiler) transpileBinaryExpr(e *ast.BinaryExpression) string {
 	if _, ok := e.Right.(*ast.BinaryExpression); ok {
 		right = "(" + right + ")"
 	}
+	if _, ok := e.Left.(*ast.UnaryExpression); ok && e.Operator == "**" {
+		// Python's ** binds tighter than unary minus: (-2) ** 2, not -2 ** 2.
+		left = "(" + left + ")"
+	}
 	return fmt.Sprintf("%s %s %s", left, op, right)
 }
 
@@ -165,20 +237,61 @@ func (t *Transpiler) transpileUnaryExpr(e *ast.UnaryExpression) string {
 
 func (t *Transpiler) transpileMethodCallExpr(e *ast.MethodCall) string {
 	obj := t.transpileExpr(e.Object)
+	if members, ok := t.moduleOf(e.Object); ok && len(e.Arguments) == 0 && len(e.NamedArguments) == 0 && !members[e.MethodName] {
+		return fmt.Sprintf("%s.%s", obj, sanitizeIdent(e.MethodName))
+	}
+	if len(e.Arguments) == 0 && len(e.NamedArguments) == 0 && t.isEnumName(e.Object) {
+		return fmt.Sprintf("%s.%s", obj, e.MethodName)
+	}
+	if len(e.Arguments) == 0 && len(e.NamedArguments) == 0 && t.structFields[e.MethodName] && !t.structMethods[e.MethodName] {
+		return fmt.Sprintf("%s.%s", obj, sanitizeIdent(e.MethodName))
+	}
 	args := make([]string, len(e.Arguments))
 	for i, a := range e.Arguments {
 		args[i] = t.transpileExpr(a)
 	}
+	args = append(args, t.keywordArgs(e.NamedArguments)...)
 	return fmt.Sprintf("%s.%s(%s)", obj, e.MethodName, strings.Join(args, ", "))
 }
 
+// keywordArgs renders "name as value" arguments as Python keyword arguments.
+func (t *Transpiler) keywordArgs(named []*ast.NamedArgument) []string {
+	kwargs := make([]string, len(named))
+	for i, na := range named {
+		kwargs[i] = sanitizeIdent(na.Name) + "=" + t.transpileExpr(na.Value)
+	}
+	return kwargs
+}
+
+// moduleOf returns the members of the module e names, when e is a name bound
+// by "Import "file.abc" as m".
+func (t *Transpiler) moduleOf(e ast.Expression) (map[string]bool, bool) {
+	id, ok := e.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	members, ok := t.modules[id.Name]
+	return members, ok
+}
+
+// isEnumName reports whether e names an enum declared in the program.
+func (t *Transpiler) isEnumName(e ast.Expression) bool {
+	id, ok := e.(*ast.Identifier)
+	return ok && t.enums[id.Name]
+}
+
 func (t *Transpiler) transpileCast(e *ast.CastExpression) string {
 	inner := t.transpileExpr(e.Value)
 	switch strings.ToLower(e.TypeName) {
 	case "number", "float":
 		return fmt.Sprintf("float(%s)", inner)
-	case "integer", "int":
+	case "integer", "int", "whole number":
 		return fmt.Sprintf("int(%s)", inner)
+	case "decimal":
+		// str() first so a number converts through its shortest form, as it
+		// does in the VMs.
+		t.needsDecimal = true
+		return fmt.Sprintf("Decimal(str(%s))", inner)
 	case "text", "string", "str":
 		return fmt.Sprintf("str(%s)", inner)
 	case "boolean", "bool":
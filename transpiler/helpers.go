@@ -98,6 +98,62 @@ var helperDefs = map[string]string{
 
 	"_zip_with": `def _zip_with(a, b):
     return [[x, y] for x, y in zip(a, b)]`,
+	"_combine": `def _combine(lst, fn, start):
+    result = start
+    for item in lst:
+        result = fn(result, item)
+    return result`,
+	"_unpack": `def _unpack(value, *names):
+    if isinstance(value, (list, tuple)):
+        return value
+    return tuple(getattr(value, name) for name in names)`,
+	// Modules imported with "as" more than once, by file, so that each
+	// later alias gets the module the first import built.
+	"_modules": `_modules = {}`,
+	// A module's variables can change after it has loaded, so its namespace
+	// reads each one through a closure instead of keeping a copy.
+	"_Module": `class _Module:
+    def __init__(self, **members):
+        self._members = members
+
+    def __getattr__(self, name):
+        try:
+            return self._members[name]()
+        except KeyError:
+            raise AttributeError(name) from None`,
+	"_Task": `class _Task(threading.Thread):
+    def __init__(self, body):
+        super().__init__()
+        self.body = body
+        self.error = None
+        self.start()
+
+    def run(self):
+        try:
+            self.body()
+        except Exception as e:
+            self.error = e
+
+    def wait(self):
+        self.join()
+        if self.error is not None:
+            raise self.error`,
+	// Sending on an English channel waits until another task receives the
+	// value, which queue.Queue.put does not, so each value carries an event
+	// its receiver sets.
+	"_Channel": `class _Channel:
+    def __init__(self):
+        self.items = queue.Queue()
+
+    def put(self, value):
+        received = threading.Event()
+        self.items.put((value, received))
+        received.wait()
+
+    def get(self):
+        value, received = self.items.get()
+        received.set()
+        return value`,
 }
 
 // helperOrder defines the deterministic emission order for helper functions.
@@ -113,6 +169,12 @@ var helperOrder = []string{
 	"_unique",
 	"_product",
 	"_zip_with",
+	"_combine",
+	"_unpack",
+	"_modules",
+	"_Module",
+	"_Task",
+	"_Channel",
 }
 
 // ─── Numeric literal formatting ───────────────────────────────────────────────
@@ -170,6 +232,8 @@ func mapOperator(op string) string {
 		return "%"
 	case "**":
 		return "**"
+	case "//", "&", "|", "^", "<<", ">>":
+		return op
 	case "is equal to", "==":
 		return "=="
 	case "is not equal to", "!=":
@@ -198,18 +262,37 @@ func mapTypeName(name string) string {
 	switch strings.ToLower(name) {
 	case "number", "float":
 		return "float"
-	case "integer", "int", "unsigned integer":
+	case "integer", "int", "unsigned integer", "whole number":
 		// Python int is arbitrarily large and signed; it is the closest equivalent.
 		return "int"
+	case "decimal":
+		return "Decimal"
 	case "text", "string":
 		return "str"
 	case "boolean", "bool":
 		return "bool"
 	case "list", "array":
 		return "list"
-	default:
-		return name
+	case "lookup table", "lookup", "table":
+		return "dict"
+	case "nothing":
+		return "None"
+	case "function":
+		return "Callable"
+	case "error":
+		return "Exception"
+	}
+	// A structure or enum element type keeps its case: it is a class.
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "list of ") {
+		return "list[" + mapTypeName(name[len("list of "):]) + "]"
 	}
+	if strings.HasPrefix(lower, "lookup table from ") {
+		if key, value, ok := strings.Cut(name[len("lookup table from "):], " to "); ok {
+			return fmt.Sprintf("dict[%s, %s]", mapTypeName(key), mapTypeName(value))
+		}
+	}
+	return name
 }
 
 // typeZeroValue returns the Python zero/default value literal for a given
@@ -217,8 +300,10 @@ func mapTypeName(name string) string {
 // struct instances can be created with no arguments.
 func typeZeroValue(typeName string) string {
 	switch strings.ToLower(typeName) {
-	case "number", "float", "integer", "int", "unsigned integer":
+	case "number", "float", "integer", "int", "unsigned integer", "i32", "i64", "u32", "u64", "whole number":
 		return "0"
+	case "decimal":
+		return "Decimal()"
 	case "text", "string":
 		return `""`
 	case "boolean", "bool":
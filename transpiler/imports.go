@@ -3,7 +3,10 @@ package transpiler
 import (
 	"github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 	"os"
+	"path/filepath"
+	"sort"
 )
 
 // inlineImports walks the program's statement list and replaces every
@@ -15,13 +18,23 @@ import (
 //   - IsSafe: inline only function/variable declarations (skip output/call
 //     statements), so top-level side-effects in the library are not executed.
 //   - Selective (Items non-empty): inline only the named declarations.
+//   - Alias ("as m"): keep the ImportStatement and record the file's program
+//     in modules; transpileImport() wraps it in a function that builds the
+//     module object.
 //
-// 'seen' tracks which file paths have already been inlined; a second import
-// of the same file is silently skipped to avoid duplicate definitions.
+// 'seen' holds the resolved path of every file already inlined into the
+// current scope; a second import of the same file is silently skipped to
+// avoid duplicate definitions. A file imported with "as" a second time is not
+// inlined again: like the VMs, which run each file once, the later alias is
+// bound to the module the first one built (see sharedModules). 'modules' is
+// the project.Registry of the whole transpilation, seeded with the main file,
+// so files that import each other in a circle, through aliases or not, give
+// the same *project.ImportCycleError the VMs raise instead of recursing
+// forever.
 //
 // If the referenced file cannot be read or parsed, the ImportStatement is kept
 // in place so that transpileImport() can emit it as an informational comment.
-func inlineImports(program *ast.Program, seen map[string]bool) *ast.Program {
+func (t *Transpiler) inlineImports(program *ast.Program, importer string, seen map[string]bool, modules *project.Registry) (*ast.Program, error) {
 	var newStmts []ast.Statement
 	for _, stmt := range program.Statements {
 		imp, ok := stmt.(*ast.ImportStatement)
@@ -30,34 +43,83 @@ func inlineImports(program *ast.Program, seen map[string]bool) *ast.Program {
 			continue
 		}
 
-		// Second import of the same file → skip (no duplicate definitions).
-		if seen[imp.Path] {
+		file, err := project.ResolveImport(importer, imp.Path)
+		if err != nil {
+			newStmts = append(newStmts, stmt)
 			continue
 		}
 
-		// Try to read and parse the referenced file.
-		content, err := os.ReadFile(imp.Path)
-		if err != nil {
-			// File not found or unreadable; keep the ImportStatement so that
-			// transpileImport() can emit it as a comment.
+		// A module gets a scope of its own, so what it imports is inlined
+		// into that scope however often the main program imports it.
+		if imp.Alias != "" {
+			key := t.moduleKey(file)
+			if first, ok := t.aliasedFiles[key]; ok && !imp.IsSafe {
+				if err := modules.Begin(file, imp.Line); err != nil {
+					return nil, err
+				}
+				modules.End()
+				t.sharedModules[first] = key
+				t.sharedModules[imp] = key
+				newStmts = append(newStmts, stmt)
+				continue
+			}
+			if modProg, err := parseImportFile(importer, imp.Path); err == nil {
+				if err := modules.Begin(file, imp.Line); err != nil {
+					return nil, err
+				}
+				modProg, err = t.inlineImports(modProg, file, make(map[string]bool), modules)
+				modules.End()
+				if err != nil {
+					return nil, err
+				}
+				if imp.IsSafe {
+					// Importing safely runs none of the file, so there is
+					// nothing to share.
+					modProg = &ast.Program{Statements: filterDecls(modProg.Statements)}
+				} else {
+					t.aliasedFiles[key] = imp
+				}
+				t.inlinedModules[imp] = modProg
+			}
 			newStmts = append(newStmts, stmt)
 			continue
 		}
 
-		seen[imp.Path] = true
+		// A file importing one of the files that import it is a cycle, even
+		// when that file has been inlined already.
+		if err := modules.Begin(file, imp.Line); err != nil {
+			return nil, err
+		}
+
+		// Second import of the same file → skip (no duplicate definitions).
+		key, err := filepath.Abs(file)
+		if err != nil {
+			key = file
+		}
+		if seen[key] {
+			modules.End()
+			continue
+		}
 
-		lx := parser.NewLexer(string(content))
-		tokens := lx.TokenizeAll()
-		p := parser.NewParser(tokens)
-		importedProg, err := p.Parse()
+		// Try to read and parse the referenced file.
+		importedProg, err := parseImportFile(importer, imp.Path)
 		if err != nil {
-			// Parse error in the imported file; keep ImportStatement as comment.
+			// File not found, unreadable or unparsable; keep the
+			// ImportStatement so that transpileImport() can emit it as a
+			// comment.
+			modules.End()
 			newStmts = append(newStmts, stmt)
 			continue
 		}
 
+		seen[key] = true
+
 		// Recursively resolve imports in the imported file.
-		importedProg = inlineImports(importedProg, seen)
+		importedProg, err = t.inlineImports(importedProg, file, seen, modules)
+		modules.End()
+		if err != nil {
+			return nil, err
+		}
 
 		// Select which statements to inline based on import mode.
 		var toInline []ast.Statement
@@ -72,7 +134,96 @@ func inlineImports(program *ast.Program, seen map[string]bool) *ast.Program {
 
 		newStmts = append(newStmts, toInline...)
 	}
-	return &ast.Program{Statements: newStmts}
+	return &ast.Program{Statements: newStmts}, nil
+}
+
+// moduleKey names the module built from file in the output's _modules: its
+// path from the main file's directory, so it is the same whichever file
+// imports it and says nothing about the machine it was transpiled on.
+func (t *Transpiler) moduleKey(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	dir := t.sourceDir
+	if dir == "" {
+		dir = "."
+	}
+	if absDir, err := filepath.Abs(dir); err == nil {
+		if rel, err := filepath.Rel(absDir, abs); err == nil {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(abs)
+}
+
+// parseImportFile reads and parses the English file an import of path in the
+// file importer names.
+func parseImportFile(importer, path string) (*ast.Program, error) {
+	file, err := project.ResolveImport(importer, path)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewParser(parser.NewLexer(string(content)).TokenizeAll()).Parse()
+}
+
+// moduleMembers returns the public names a module file declares at its top
+// level, each mapped to whether it is a function: these are what "m's name"
+// can reach after "Import "file.abc" as m".
+func moduleMembers(stmts []ast.Statement) map[string]bool {
+	private := project.PrivateNames(&ast.Program{Statements: stmts})
+	members := make(map[string]bool)
+	for _, s := range stmts {
+		switch decl := s.(type) {
+		case *ast.FunctionDecl:
+			members[decl.Name] = true
+		case *ast.VariableDecl:
+			if decl.Name != "" {
+				members[decl.Name] = false
+			}
+			for _, name := range decl.Names {
+				members[name] = false
+			}
+		case *ast.TypedVariableDecl:
+			members[decl.Name] = false
+		case *ast.EnumDecl:
+			members[decl.Name] = false
+		case *ast.CapabilityDecl:
+			members[decl.Name] = false
+		}
+	}
+	for name := range private {
+		delete(members, name)
+	}
+	return members
+}
+
+// exportedNames returns the __all__ list of a file with private
+// declarations: every top-level name it declares publicly, sorted, so
+// "from file import *" leaves the private ones out as the English import
+// does. It returns nil when nothing is private.
+func exportedNames(stmts []ast.Statement) []string {
+	if len(project.PrivateNames(&ast.Program{Statements: stmts})) == 0 {
+		return nil
+	}
+	var names []string
+	for name := range moduleMembers(stmts) {
+		names = append(names, sanitizeIdent(name))
+	}
+	for _, s := range stmts {
+		switch decl := s.(type) {
+		case *ast.StructDecl:
+			names = append(names, sanitizeIdent(decl.Name))
+		case *ast.ErrorTypeDecl:
+			names = append(names, sanitizeIdent(decl.Name))
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
 // filterDecls retains only function/variable/struct declarations, discarding
@@ -83,7 +234,8 @@ func filterDecls(stmts []ast.Statement) []ast.Statement {
 	for _, s := range stmts {
 		switch s.(type) {
 		case *ast.FunctionDecl, *ast.VariableDecl, *ast.TypedVariableDecl,
-			*ast.StructDecl, *ast.ErrorTypeDecl, *ast.CommentStatement:
+			*ast.StructDecl, *ast.ErrorTypeDecl, *ast.EnumDecl, *ast.CapabilityDecl,
+			*ast.CommentStatement:
 			result = append(result, s)
 		}
 	}
@@ -107,6 +259,14 @@ func selectNamedDecls(stmts []ast.Statement, items []string) []ast.Statement {
 		case *ast.VariableDecl:
 			if want[decl.Name] {
 				result = append(result, s)
+				continue
+			}
+			// A destructuring declaration is kept if it declares any wanted name.
+			for _, name := range decl.Names {
+				if want[name] {
+					result = append(result, s)
+					break
+				}
 			}
 		case *ast.TypedVariableDecl:
 			if want[decl.Name] {
@@ -116,6 +276,14 @@ func selectNamedDecls(stmts []ast.Statement, items []string) []ast.Statement {
 			if want[decl.Name] {
 				result = append(result, s)
 			}
+		case *ast.EnumDecl:
+			if want[decl.Name] {
+				result = append(result, s)
+			}
+		case *ast.CapabilityDecl:
+			if want[decl.Name] {
+				result = append(result, s)
+			}
 		}
 	}
 	return result
@@ -2,9 +2,12 @@ package transpiler
 
 import (
 	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/project"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 // ─── Statements ───────────────────────────────────────────────────────────────
@@ -35,8 +38,20 @@ func (t *Transpiler) transpileStatement(stmt ast.Statement) {
 		t.transpileOutput(s)
 	case *ast.ReturnStatement:
 		t.transpileReturn(s)
+	case *ast.YieldStatement:
+		t.writeLine("yield " + t.transpileExpr(s.Value))
+	case *ast.BackgroundStatement:
+		t.transpileBackground(s)
+	case *ast.TestBlock:
+		t.writeFunctionDef(testFunctionName(s.Name), nil, nil, "", s.Body)
+	case *ast.WaitStatement:
+		t.writeLine(t.transpileExpr(s.Task) + ".wait()")
+	case *ast.SendStatement:
+		t.writeLine(fmt.Sprintf("%s.put(%s)", t.transpileExpr(s.Channel), t.transpileExpr(s.Value)))
 	case *ast.IfStatement:
 		t.transpileIf(s)
+	case *ast.WhenStatement:
+		t.transpileWhen(s)
 	case *ast.WhileLoop:
 		t.transpileWhile(s)
 	case *ast.ForLoop:
@@ -47,20 +62,45 @@ func (t *Transpiler) transpileStatement(stmt ast.Statement) {
 		n := sanitizeIdent(s.Name)
 		t.writeLine(fmt.Sprintf("%s = not %s", n, n))
 	case *ast.BreakStatement:
+		if flag, ok := t.escapeFlags[s]; ok {
+			t.writeLine(flag + " = True")
+		}
 		t.writeLine("break")
 	case *ast.ContinueStatement:
-		t.writeLine("continue")
+		if flag, ok := t.escapeFlags[s]; ok {
+			t.writeLine(flag + " = True")
+			t.writeLine("break")
+		} else {
+			t.writeLine("continue")
+		}
 	case *ast.TryStatement:
 		t.transpileTry(s)
 	case *ast.RaiseStatement:
 		t.transpileRaise(s)
+	case *ast.AssertStatement:
+		if s.Message != nil {
+			t.writeLine(fmt.Sprintf("assert %s, %s", t.transpileExpr(s.Condition), t.transpileExpr(s.Message)))
+		} else {
+			t.writeLine("assert " + t.transpileExpr(s.Condition))
+		}
 	case *ast.ErrorTypeDecl:
 		t.transpileErrorTypeDecl(s)
+	case *ast.EnumDecl:
+		t.transpileEnumDecl(s)
+	case *ast.CapabilityDecl:
+		t.transpileCapabilityDecl(s)
 	case *ast.SwapStatement:
 		n1, n2 := sanitizeIdent(s.Name1), sanitizeIdent(s.Name2)
 		t.writeLine(fmt.Sprintf("%s, %s = %s, %s", n1, n2, n2, n1))
 	case *ast.StructDecl:
 		t.transpileStructDecl(s)
+	case *ast.OverflowModeStatement:
+		// Python integers never overflow, so the mode only survives as a note.
+		if s.Wrap {
+			t.writeLine("# Use wrapping arithmetic.")
+		} else {
+			t.writeLine("# Use checked arithmetic.")
+		}
 	case *ast.CommentStatement:
 		t.transpileComment(s)
 	default:
@@ -71,6 +111,20 @@ func (t *Transpiler) transpileStatement(stmt ast.Statement) {
 // ─── Individual statement translators ────────────────────────────────────────
 
 func (t *Transpiler) transpileImport(s *ast.ImportStatement) {
+	key, shared := t.sharedModules[s]
+	if modProg, ok := t.inlinedModules[s]; ok {
+		t.transpileInlinedModule(s.Alias, modProg)
+		if shared {
+			t.helpers["_modules"] = true
+			t.writeLine(fmt.Sprintf("_modules[%q] = %s", key, sanitizeIdent(s.Alias)))
+		}
+		return
+	}
+	if shared {
+		// The file was imported with "as" before; it runs only once.
+		t.writeLine(fmt.Sprintf("%s = _modules[%q]", sanitizeIdent(s.Alias), key))
+		return
+	}
 	if t.inlineMode {
 		// In inline mode, ImportStatements are replaced before code generation.
 		// This code path is only reached if the referenced file couldn't be resolved.
@@ -79,6 +133,8 @@ func (t *Transpiler) transpileImport(s *ast.ImportStatement) {
 		}
 		if len(s.Items) > 0 {
 			t.writeLine(fmt.Sprintf("# from %q import %s", s.Path, strings.Join(s.Items, ", ")))
+		} else if s.Alias != "" {
+			t.writeLine(fmt.Sprintf("# import %q as %s", s.Path, s.Alias))
 		} else {
 			t.writeLine(fmt.Sprintf("# import %q", s.Path))
 		}
@@ -97,10 +153,21 @@ func (t *Transpiler) transpileImport(s *ast.ImportStatement) {
 	// If the library lives in a different directory from the main file, emit a
 	// one-line sys.path insert so Python can find the module.
 	importedDir := filepath.Dir(s.Path)
+	if file, err := project.ResolveImport(t.sourceFile, s.Path); err == nil {
+		importedDir = filepath.Dir(file)
+	}
 	mainDir := t.sourceDir
 	if mainDir == "" {
 		mainDir = "."
 	}
+	// A library found through the project can be an absolute path while the
+	// main file's is relative; Rel needs both of one kind.
+	if abs, err := filepath.Abs(importedDir); err == nil {
+		importedDir = abs
+	}
+	if abs, err := filepath.Abs(mainDir); err == nil {
+		mainDir = abs
+	}
 	if rel, err := filepath.Rel(mainDir, importedDir); err == nil {
 		rel = filepath.ToSlash(rel)
 		if rel != "." {
@@ -111,7 +178,10 @@ func (t *Transpiler) transpileImport(s *ast.ImportStatement) {
 	}
 
 	// Emit the Python import.
-	if len(s.Items) > 0 {
+	if s.Alias != "" {
+		// Namespaced: "import geometry as geo"
+		t.writeLine(fmt.Sprintf("import %s as %s", module, sanitizeIdent(s.Alias)))
+	} else if len(s.Items) > 0 {
 		// Selective: "from math_library import square, cube"
 		sanitized := make([]string, len(s.Items))
 		for i, item := range s.Items {
@@ -127,6 +197,40 @@ func (t *Transpiler) transpileImport(s *ast.ImportStatement) {
 	}
 }
 
+// transpileInlinedModule writes the program of a file imported "as alias"
+// into a function of its own, so its names stay out of the importer's, and
+// binds alias to the _Module of its top-level names that the function
+// returns. The module's own functions set its variables as nonlocals, and
+// the _Module reads them through closures, so the importer sees each change.
+func (t *Transpiler) transpileInlinedModule(alias string, prog *ast.Program) {
+	name := sanitizeIdent(alias)
+	members := moduleMembers(prog.Statements)
+	fields := make([]string, 0, len(members))
+	for member := range members {
+		fields = append(fields, fmt.Sprintf("%s=lambda: %s", sanitizeIdent(member), sanitizeIdent(member)))
+	}
+	sort.Strings(fields)
+
+	scope := make(map[string]string)
+	eachScopeStatement(prog.Statements, func(stmt ast.Statement) {
+		for _, name := range declaredNames(stmt) {
+			scope[sanitizeIdent(name)] = "local"
+		}
+	})
+
+	t.helpers["_Module"] = true
+	t.ensureBlankLines(2)
+	t.writeLine(fmt.Sprintf("def _module_%s():", name))
+	t.indent++
+	t.funcScopes = append(t.funcScopes, scope)
+	t.transpileBody(prog.Statements)
+	t.funcScopes = t.funcScopes[:len(t.funcScopes)-1]
+	t.writeLine(fmt.Sprintf("return _Module(%s)", strings.Join(fields, ", ")))
+	t.indent--
+	t.write("\n\n")
+	t.writeLine(fmt.Sprintf("%s = _module_%s()", name, name))
+}
+
 func (t *Transpiler) transpileComment(s *ast.CommentStatement) {
 	// Comments are only emitted when keepComments is true (i.e. .abc source files).
 	// .101 bytecode files never contain CommentStatement nodes, but this guard
@@ -142,7 +246,22 @@ func (t *Transpiler) transpileComment(s *ast.CommentStatement) {
 }
 
 func (t *Transpiler) transpileVariableDecl(s *ast.VariableDecl) {
+	if len(s.Names) > 0 {
+		targets := make([]string, len(s.Names))
+		for i, n := range s.Names {
+			targets[i] = sanitizeIdent(n)
+		}
+		if s.Rest {
+			targets[len(targets)-1] = "*" + targets[len(targets)-1]
+		}
+		t.writeLine(fmt.Sprintf("%s = %s", strings.Join(targets, ", "), t.unpackedValue(s.Names, s.Rest, s.Value)))
+		return
+	}
 	name := sanitizeIdent(s.Name)
+	if fl, ok := s.Value.(*ast.FunctionLiteral); ok {
+		t.transpileFunctionDef(name, fl.Parameters, fl.Body)
+		return
+	}
 	val := t.transpileExpr(s.Value)
 	if s.IsConstant {
 		// Emit a typing.Final annotation so type-checkers treat this as constant.
@@ -156,6 +275,9 @@ func (t *Transpiler) transpileTypedVariableDecl(s *ast.TypedVariableDecl) {
 	name := sanitizeIdent(s.Name)
 	val := t.transpileExpr(s.Value)
 	typeName := mapTypeName(s.TypeName)
+	if strings.Contains(typeName, "Decimal") {
+		t.needsDecimal = true
+	}
 	if s.IsConstant {
 		t.writeLine(fmt.Sprintf("%s: Final[%s] = %s", name, typeName, val))
 	} else {
@@ -164,13 +286,59 @@ func (t *Transpiler) transpileTypedVariableDecl(s *ast.TypedVariableDecl) {
 }
 
 func (t *Transpiler) transpileAssignment(s *ast.Assignment) {
+	if len(s.Names) > 0 {
+		targets := make([]string, len(s.Names))
+		for i, n := range s.Names {
+			targets[i] = sanitizeIdent(n)
+			if t.methodFields[n] {
+				targets[i] = "self." + targets[i]
+			}
+		}
+		if s.Rest {
+			targets[len(targets)-1] = "*" + targets[len(targets)-1]
+		}
+		t.writeLine(fmt.Sprintf("%s = %s", strings.Join(targets, ", "), t.unpackedValue(s.Names, s.Rest, s.Value)))
+		return
+	}
 	target := sanitizeIdent(s.Name)
 	if t.methodFields[s.Name] {
 		target = "self." + sanitizeIdent(s.Name)
 	}
+	if fl, ok := s.Value.(*ast.FunctionLiteral); ok && !t.methodFields[s.Name] {
+		t.transpileFunctionDef(target, fl.Parameters, fl.Body)
+		return
+	}
 	t.writeLine(fmt.Sprintf("%s = %s", target, t.transpileExpr(s.Value)))
 }
 
+// unpackedValue renders the value of a destructuring declaration or
+// assignment. A list literal with one item per name, or a call to a function
+// that returns one value per name, unpacks as a plain Python tuple, as does
+// anything whose rest is collected, since only a list has a rest. Anything
+// else might be a structure, so it goes through the _unpack helper.
+func (t *Transpiler) unpackedValue(names []string, rest bool, value ast.Expression) string {
+	val := t.transpileExpr(value)
+	if rest {
+		return val
+	}
+	switch v := value.(type) {
+	case *ast.ListLiteral:
+		if len(v.Elements) == len(names) {
+			return val
+		}
+	case *ast.FunctionCall:
+		if t.tupleFunctions[v.Name] == len(names) {
+			return val
+		}
+	}
+	t.helpers["_unpack"] = true
+	args := []string{val}
+	for _, n := range names {
+		args = append(args, fmt.Sprintf("%q", n))
+	}
+	return fmt.Sprintf("_unpack(%s)", strings.Join(args, ", "))
+}
+
 func (t *Transpiler) transpileIndexAssignment(s *ast.IndexAssignment) {
 	target := sanitizeIdent(s.ListName)
 	if t.methodFields[s.ListName] {
@@ -192,17 +360,281 @@ func (t *Transpiler) transpileLookupKeyAssignment(s *ast.LookupKeyAssignment) {
 }
 
 func (t *Transpiler) transpileFunctionDecl(s *ast.FunctionDecl) {
-	params := make([]string, len(s.Parameters))
-	for i, p := range s.Parameters {
+	params := t.paramList(s.Parameters, s.ParamCapabilities, s.ParamTypes, s.Defaults, s.Variadic)
+	prelude := t.paramPrelude(s.Parameters, s.Defaults, s.Variadic)
+	t.writeFunctionDef(sanitizeIdent(s.Name), params, prelude, t.returnAnnotation(s.ReturnType), s.Body)
+}
+
+// transpileFunctionDef writes "def name(params):" followed by the body. It is
+// shared by function declarations and function literals bound to a name.
+func (t *Transpiler) transpileFunctionDef(name string, parameters []string, body []ast.Statement) {
+	t.writeFunctionDef(name, t.paramList(parameters, nil, nil, nil, false), nil, "", body)
+}
+
+// paramList sanitizes parameter names, annotates each one declared "as
+// anything that can X" with the Protocol class X transpiles to and each one
+// declared with a type with the matching Python type, gives each one
+// declared "(defaulting to ...)" its default (or None, see paramPrelude),
+// and turns a rest parameter into "*values".
+func (t *Transpiler) paramList(parameters, capabilities, paramTypes []string, defaults []ast.Expression, variadic bool) []string {
+	params := make([]string, len(parameters))
+	for i, p := range parameters {
 		params[i] = sanitizeIdent(p)
+		if variadic && i == len(parameters)-1 {
+			params[i] = "*" + params[i]
+			continue
+		}
+		annotated := true
+		switch {
+		case i < len(capabilities) && capabilities[i] != "":
+			params[i] += ": " + capabilities[i]
+		case i < len(paramTypes) && paramTypes[i] != "":
+			params[i] += ": " + t.typeAnnotation(paramTypes[i])
+		default:
+			annotated = false
+		}
+		if i < len(defaults) && defaults[i] != nil {
+			value := "None" // filled in by paramPrelude
+			if isConstantDefault(defaults[i]) {
+				value = t.transpileExpr(defaults[i])
+			}
+			if annotated {
+				params[i] += " = " + value
+			} else {
+				params[i] += "=" + value
+			}
+		}
 	}
-	t.writeLine(fmt.Sprintf("def %s(%s):", sanitizeIdent(s.Name), strings.Join(params, ", ")))
+	return params
+}
+
+// paramPrelude returns the lines a function body starts with to give its
+// parameters their defaults. English works a default out on every call
+// without one, and it may use the parameters before it, so unless it is a
+// constant paramList leaves None in the def line and the body fills it in.
+// Python collects a rest parameter into a tuple, so it is made a list, as
+// English gives it.
+func (t *Transpiler) paramPrelude(parameters []string, defaults []ast.Expression, variadic bool) []string {
+	var lines []string
+	for i, p := range parameters {
+		name := sanitizeIdent(p)
+		if variadic && i == len(parameters)-1 {
+			lines = append(lines, fmt.Sprintf("%s = list(%s)", name, name))
+			continue
+		}
+		if i >= len(defaults) || defaults[i] == nil || isConstantDefault(defaults[i]) {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("if %s is None:", name), fmt.Sprintf("    %s = %s", name, t.transpileExpr(defaults[i])))
+	}
+	return lines
+}
+
+// isConstantDefault reports whether a default is a literal Python can
+// safely evaluate once, in the def line, and share between calls.
+func isConstantDefault(expr ast.Expression) bool {
+	switch expr.(type) {
+	case *ast.NumberLiteral, *ast.DecimalLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NothingLiteral:
+		return true
+	}
+	return false
+}
+
+// returnAnnotation renders a function's declared return type as " -> T",
+// or "" when it does not declare one.
+func (t *Transpiler) returnAnnotation(returnType string) string {
+	if returnType == "" {
+		return ""
+	}
+	return " -> " + t.typeAnnotation(returnType)
+}
+
+// typeAnnotation converts a type named in a function's signature to a Python
+// annotation. Structures, enums and error types are quoted, since their
+// classes may be defined after the function.
+func (t *Transpiler) typeAnnotation(typeName string) string {
+	annotation := mapTypeName(typeName)
+	if annotation == typeName {
+		return fmt.Sprintf("%q", typeName)
+	}
+	switch annotation {
+	case "Decimal":
+		t.needsDecimal = true
+	case "Callable":
+		t.needsCallable = true
+	}
+	return annotation
+}
+
+func (t *Transpiler) writeFunctionDef(name string, params, prelude []string, returns string, body []ast.Statement) {
+	t.writeLine(fmt.Sprintf("def %s(%s)%s:", name, strings.Join(params, ", "), returns))
 	t.indent++
-	t.transpileBody(s.Body)
+	t.transpileFunctionBody(params, prelude, body)
 	t.indent--
 	t.write("\n")
 }
 
+// transpileFunctionBody writes the body of a def taking params. English lets
+// a function set the variables of the code around it, which Python only
+// allows after a global or nonlocal statement, so the body starts with one
+// for each variable it sets without declaring, followed by the prelude lines.
+func (t *Transpiler) transpileFunctionBody(params, prelude []string, body []ast.Statement) {
+	scope := make(map[string]string)
+	for _, p := range params {
+		name, _, _ := strings.Cut(strings.TrimLeft(p, "*"), "=")
+		name, _, _ = strings.Cut(name, ":")
+		scope[strings.TrimSpace(name)] = "local"
+	}
+	eachScopeStatement(body, func(stmt ast.Statement) {
+		for _, name := range declaredNames(stmt) {
+			scope[sanitizeIdent(name)] = "local"
+		}
+	})
+	var globals, nonlocals []string
+	eachScopeStatement(body, func(stmt ast.Statement) {
+		for _, name := range assignedNames(stmt) {
+			if t.methodFields[name] {
+				continue // becomes self.<field>
+			}
+			name = sanitizeIdent(name)
+			if scope[name] != "" {
+				continue
+			}
+			scope[name] = t.outerScope(name)
+			if scope[name] == "global" {
+				globals = append(globals, name)
+			} else {
+				nonlocals = append(nonlocals, name)
+			}
+		}
+	})
+	if len(globals) > 0 {
+		t.writeLine("global " + strings.Join(globals, ", "))
+	}
+	if len(nonlocals) > 0 {
+		t.writeLine("nonlocal " + strings.Join(nonlocals, ", "))
+	}
+	for _, line := range prelude {
+		t.writeLine(line)
+	}
+	t.funcScopes = append(t.funcScopes, scope)
+	t.transpileBody(body)
+	t.funcScopes = t.funcScopes[:len(t.funcScopes)-1]
+}
+
+// outerScope reports whether a variable set inside a function belongs to
+// one of the functions around it ("nonlocal") or to the module ("global").
+func (t *Transpiler) outerScope(name string) string {
+	for i := len(t.funcScopes) - 1; i >= 0; i-- {
+		switch t.funcScopes[i][name] {
+		case "local", "nonlocal":
+			return "nonlocal"
+		case "global":
+			return "global"
+		}
+	}
+	return "global"
+}
+
+// eachScopeStatement calls visit for every statement of a function body,
+// including those in its branches and loops but not those in the functions
+// and background tasks it defines, which have scopes of their own.
+func eachScopeStatement(stmts []ast.Statement, visit func(ast.Statement)) {
+	for _, stmt := range stmts {
+		visit(stmt)
+		switch s := stmt.(type) {
+		case *ast.IfStatement:
+			eachScopeStatement(s.Then, visit)
+			for _, elif := range s.ElseIf {
+				eachScopeStatement(elif.Body, visit)
+			}
+			eachScopeStatement(s.Else, visit)
+		case *ast.WhenStatement:
+			for _, wc := range s.Cases {
+				eachScopeStatement(wc.Body, visit)
+			}
+			eachScopeStatement(s.Otherwise, visit)
+		case *ast.WhileLoop:
+			eachScopeStatement(s.Body, visit)
+		case *ast.ForLoop:
+			eachScopeStatement(s.Body, visit)
+		case *ast.ForEachLoop:
+			eachScopeStatement(s.Body, visit)
+		case *ast.TryStatement:
+			eachScopeStatement(s.TryBody, visit)
+			eachScopeStatement(s.ErrorBody, visit)
+			eachScopeStatement(s.FinallyBody, visit)
+		}
+	}
+}
+
+// declaredNames returns the variables a statement makes in the scope it is
+// in.
+func declaredNames(stmt ast.Statement) []string {
+	switch s := stmt.(type) {
+	case *ast.VariableDecl:
+		if s.Names != nil {
+			return s.Names
+		}
+		return []string{s.Name}
+	case *ast.TypedVariableDecl:
+		return []string{s.Name}
+	case *ast.FunctionDecl:
+		return []string{s.Name}
+	case *ast.BackgroundStatement:
+		return []string{s.Name}
+	case *ast.ForEachLoop:
+		return []string{s.Item}
+	case *ast.TryStatement:
+		if s.ErrorVar != "" {
+			return []string{s.ErrorVar}
+		}
+	}
+	return nil
+}
+
+// assignedNames returns the variables a statement sets.
+func assignedNames(stmt ast.Statement) []string {
+	switch s := stmt.(type) {
+	case *ast.Assignment:
+		if s.Names != nil {
+			return s.Names
+		}
+		return []string{s.Name}
+	case *ast.ToggleStatement:
+		return []string{s.Name}
+	case *ast.SwapStatement:
+		return []string{s.Name1, s.Name2}
+	}
+	return nil
+}
+
+// transpileBackground defines the task's body as a function and starts it on
+// a thread of its own.
+func (t *Transpiler) transpileBackground(s *ast.BackgroundStatement) {
+	name := sanitizeIdent(s.Name)
+	t.writeFunctionDef("_background_"+s.Name, nil, nil, "", s.Body)
+	t.writeLine(fmt.Sprintf("%s = _Task(_background_%s)", name, s.Name))
+}
+
+// testFunctionName turns the name of a test block into the name of a function
+// pytest will collect: "adding works" becomes test_adding_works.
+func testFunctionName(name string) string {
+	var sb strings.Builder
+	sb.WriteString("test_")
+	underscore := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			underscore = false
+		} else if !underscore {
+			sb.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimRight(sb.String(), "_")
+}
+
 func (t *Transpiler) transpileCallStatement(s *ast.CallStatement) {
 	if s.FunctionCall != nil {
 		t.writeLine(t.transpileFuncCallExpr(s.FunctionCall))
@@ -225,7 +657,13 @@ func (t *Transpiler) transpileOutput(s *ast.OutputStatement) {
 }
 
 func (t *Transpiler) transpileReturn(s *ast.ReturnStatement) {
-	if s.Value == nil {
+	if len(s.Values) > 0 {
+		vals := make([]string, len(s.Values))
+		for i, v := range s.Values {
+			vals[i] = t.transpileExpr(v)
+		}
+		t.writeLine("return " + strings.Join(vals, ", "))
+	} else if s.Value == nil {
 		t.writeLine("return")
 	} else {
 		t.writeLine(fmt.Sprintf("return %s", t.transpileExpr(s.Value)))
@@ -253,26 +691,249 @@ func (t *Transpiler) transpileIf(s *ast.IfStatement) {
 	}
 }
 
+// transpileWhen emits a Python match statement. Cases made only of literals
+// and type patterns become plain case patterns; anything else captures the
+// subject and tests it in a guard.
+func (t *Transpiler) transpileWhen(s *ast.WhenStatement) {
+	capture := "_subject"
+	if id, ok := s.Subject.(*ast.Identifier); ok {
+		capture = sanitizeIdent(id.Name)
+	}
+	t.writeLine(fmt.Sprintf("match %s:", t.transpileExpr(s.Subject)))
+	t.indent++
+	for _, wc := range s.Cases {
+		if patterns, ok := t.casePatterns(wc.Patterns); ok {
+			pattern := strings.Join(patterns, " | ")
+			if numericPatterns(wc.Patterns) {
+				// Python's True is the int 1, so number patterns would
+				// match a boolean that no VM would.
+				if _, ok := s.Subject.(*ast.Identifier); !ok {
+					pattern = fmt.Sprintf("(%s) as %s", pattern, capture)
+				}
+				pattern += fmt.Sprintf(" if not isinstance(%s, bool)", capture)
+			}
+			t.writeLine(fmt.Sprintf("case %s:", pattern))
+		} else {
+			guards := make([]string, len(wc.Patterns))
+			for i, pattern := range wc.Patterns {
+				guards[i] = t.patternGuard(capture, pattern)
+			}
+			t.writeLine(fmt.Sprintf("case %s if %s:", capture, strings.Join(guards, " or ")))
+		}
+		t.indent++
+		t.transpileBody(wc.Body)
+		t.indent--
+	}
+	if len(s.Otherwise) > 0 {
+		t.writeLine("case _:")
+		t.indent++
+		t.transpileBody(s.Otherwise)
+		t.indent--
+	}
+	t.indent--
+}
+
+// casePatterns renders patterns as Python case patterns, or reports false
+// when one of them can only be expressed as a guard. Number patterns that
+// sit alongside boolean ones are left to guards, since only a guard can
+// keep a boolean from matching the numbers.
+func (t *Transpiler) casePatterns(patterns []ast.WhenPattern) ([]string, bool) {
+	if numericPatterns(patterns) && booleanPatterns(patterns) {
+		return nil, false
+	}
+	var out []string
+	for _, pattern := range patterns {
+		switch p := pattern.(type) {
+		case *ast.ValuePattern:
+			switch p.Value.(type) {
+			case *ast.NumberLiteral, *ast.StringLiteral, *ast.BooleanLiteral, *ast.NothingLiteral:
+				out = append(out, t.transpileExpr(p.Value))
+			default:
+				return nil, false
+			}
+		case *ast.TypePattern:
+			switch strings.ToLower(p.TypeName) {
+			case "number":
+				out = append(out, "int()", "float()")
+			case "decimal":
+				t.needsDecimal = true
+				out = append(out, "Decimal()")
+			case "whole number":
+				out = append(out, "int()")
+			case "text":
+				out = append(out, "str()")
+			case "boolean":
+				out = append(out, "bool()")
+			case "list":
+				out = append(out, "list()")
+			case "lookup table":
+				out = append(out, "dict()")
+			case "nothing":
+				out = append(out, "None")
+			case "function":
+				return nil, false
+			default:
+				out = append(out, p.TypeName+"()")
+			}
+		default:
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+// numericPatterns reports whether any pattern matches numbers.
+func numericPatterns(patterns []ast.WhenPattern) bool {
+	for _, pattern := range patterns {
+		switch p := pattern.(type) {
+		case *ast.ValuePattern:
+			if _, ok := p.Value.(*ast.NumberLiteral); ok {
+				return true
+			}
+		case *ast.TypePattern:
+			switch strings.ToLower(p.TypeName) {
+			case "number", "whole number":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// booleanPatterns reports whether any pattern matches booleans.
+func booleanPatterns(patterns []ast.WhenPattern) bool {
+	for _, pattern := range patterns {
+		switch p := pattern.(type) {
+		case *ast.ValuePattern:
+			if _, ok := p.Value.(*ast.BooleanLiteral); ok {
+				return true
+			}
+		case *ast.TypePattern:
+			if strings.EqualFold(p.TypeName, "boolean") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// patternGuard renders a single pattern as a boolean test on name. Number
+// tests rule out booleans, which Python counts as ints.
+func (t *Transpiler) patternGuard(name string, pattern ast.WhenPattern) string {
+	switch p := pattern.(type) {
+	case *ast.ValuePattern:
+		if _, ok := p.Value.(*ast.NumberLiteral); ok {
+			return fmt.Sprintf("(%s == %s and not isinstance(%s, bool))", name, t.transpileExpr(p.Value), name)
+		}
+		if _, ok := p.Value.(*ast.BooleanLiteral); ok {
+			return fmt.Sprintf("%s is %s", name, t.transpileExpr(p.Value))
+		}
+		return fmt.Sprintf("%s == %s", name, t.transpileExpr(p.Value))
+	case *ast.RangePattern:
+		// Like the VMs, a non-number subject is simply out of range.
+		return fmt.Sprintf("(isinstance(%s, (int, float)) and not isinstance(%s, bool) and %s <= %s <= %s)", name, name, t.transpileExpr(p.Low), name, t.transpileExpr(p.High))
+	case *ast.TypePattern:
+		switch strings.ToLower(p.TypeName) {
+		case "number":
+			return fmt.Sprintf("(isinstance(%s, (int, float)) and not isinstance(%s, bool))", name, name)
+		case "decimal":
+			t.needsDecimal = true
+			return fmt.Sprintf("isinstance(%s, Decimal)", name)
+		case "whole number":
+			return fmt.Sprintf("(isinstance(%s, int) and not isinstance(%s, bool))", name, name)
+		case "text":
+			return fmt.Sprintf("isinstance(%s, str)", name)
+		case "boolean":
+			return fmt.Sprintf("isinstance(%s, bool)", name)
+		case "list":
+			return fmt.Sprintf("isinstance(%s, list)", name)
+		case "lookup table":
+			return fmt.Sprintf("isinstance(%s, dict)", name)
+		case "nothing":
+			return name + " is None"
+		case "function":
+			return fmt.Sprintf("callable(%s)", name)
+		default:
+			return fmt.Sprintf("isinstance(%s, %s)", name, p.TypeName)
+		}
+	}
+	return "False"
+}
+
 func (t *Transpiler) transpileWhile(s *ast.WhileLoop) {
+	t.writeEscapeFlags(s)
 	t.writeLine(fmt.Sprintf("while %s:", t.transpileExpr(s.Condition)))
 	t.indent++
 	t.transpileBody(s.Body)
 	t.indent--
+	t.writeEscapeChecks(s)
 }
 
 func (t *Transpiler) transpileForLoop(s *ast.ForLoop) {
 	count := t.transpileExpr(s.Count)
+	t.writeEscapeFlags(s)
 	t.writeLine(fmt.Sprintf("for _ in range(%s):", maybeInt(count)))
 	t.indent++
 	t.transpileBody(s.Body)
 	t.indent--
+	t.writeEscapeChecks(s)
 }
 
 func (t *Transpiler) transpileForEach(s *ast.ForEachLoop) {
+	t.writeEscapeFlags(s)
 	t.writeLine(fmt.Sprintf("for %s in %s:", sanitizeIdent(s.Item), t.transpileExpr(s.List)))
 	t.indent++
 	t.transpileBody(s.Body)
 	t.indent--
+	t.writeEscapeChecks(s)
+}
+
+// loopEscape is a flag set by a break or continue that leaves a loop for
+// one around it. direct is true for the loop directly inside the one the
+// break or continue names.
+type loopEscape struct {
+	flag   string
+	cont   bool
+	direct bool
+}
+
+// loopLabel returns the name a loop was given with "and call this loop".
+func loopLabel(loop ast.Statement) string {
+	switch l := loop.(type) {
+	case *ast.WhileLoop:
+		return l.Label
+	case *ast.ForLoop:
+		return l.Label
+	case *ast.ForEachLoop:
+		return l.Label
+	}
+	return ""
+}
+
+// writeEscapeFlags clears, before a loop directly inside a labeled loop,
+// the flags of the breaks and continues in it naming that loop.
+func (t *Transpiler) writeEscapeFlags(loop ast.Statement) {
+	for _, e := range t.loopEscapes[loop] {
+		if e.direct {
+			t.writeLine(e.flag + " = False")
+		}
+	}
+}
+
+// writeEscapeChecks follows a loop with a check of each flag passing
+// through it: a continue of the loop around it continues, and anything
+// else breaks again.
+func (t *Transpiler) writeEscapeChecks(loop ast.Statement) {
+	for _, e := range t.loopEscapes[loop] {
+		t.writeLine("if " + e.flag + ":")
+		t.indent++
+		if e.cont && e.direct {
+			t.writeLine("continue")
+		} else {
+			t.writeLine("break")
+		}
+		t.indent--
+	}
 }
 
 func (t *Transpiler) transpileTry(s *ast.TryStatement) {
@@ -328,8 +989,53 @@ func (t *Transpiler) transpileErrorTypeDecl(s *ast.ErrorTypeDecl) {
 	t.write("\n")
 }
 
+// transpileEnumDecl emits an enum.Enum subclass. Members are numbered from 1
+// as Python's enum module does, and __str__ returns the bare member name so
+// that printing a member matches the English output.
+func (t *Transpiler) transpileEnumDecl(s *ast.EnumDecl) {
+	t.writeLine(fmt.Sprintf("class %s(enum.Enum):", s.Name))
+	t.indent++
+	for i, m := range s.Members {
+		t.writeLine(fmt.Sprintf("%s = %d", m, i+1))
+	}
+	t.write("\n")
+	t.writeLine("def __str__(self):")
+	t.indent++
+	t.writeLine("return self.name")
+	t.indent -= 2
+	t.write("\n")
+}
+
+// transpileCapabilityDecl emits a typing.Protocol class with a stub for each
+// function the capability requires.
+func (t *Transpiler) transpileCapabilityDecl(s *ast.CapabilityDecl) {
+	t.writeLine(fmt.Sprintf("class %s(Protocol):", s.Name))
+	t.indent++
+	for i, m := range s.Methods {
+		if i > 0 {
+			t.write("\n")
+		}
+		t.writeLine(fmt.Sprintf("def %s(self): ...", sanitizeIdent(m)))
+	}
+	if len(s.Methods) == 0 {
+		t.writeLine("pass")
+	}
+	t.indent--
+	t.write("\n")
+}
+
 func (t *Transpiler) transpileStructDecl(s *ast.StructDecl) {
-	t.writeLine(fmt.Sprintf("class %s:", s.Name))
+	// The parent comes first, then each capability's Protocol class.
+	var bases []string
+	if s.Parent != "" {
+		bases = append(bases, s.Parent)
+	}
+	bases = append(bases, s.Capabilities...)
+	if len(bases) > 0 {
+		t.writeLine(fmt.Sprintf("class %s(%s):", s.Name, strings.Join(bases, ", ")))
+	} else {
+		t.writeLine(fmt.Sprintf("class %s:", s.Name))
+	}
 	t.indent++
 
 	if len(s.Fields) > 0 {
@@ -345,11 +1051,24 @@ func (t *Transpiler) transpileStructDecl(s *ast.StructDecl) {
 				defVal := t.transpileExpr(field.DefaultValue)
 				params = append(params, fmt.Sprintf("%s=%s", fname, defVal))
 			} else {
-				params = append(params, fmt.Sprintf("%s=%s", fname, typeZeroValue(field.TypeName)))
+				zero := typeZeroValue(field.TypeName)
+				if zero == "Decimal()" {
+					t.needsDecimal = true
+				}
+				params = append(params, fmt.Sprintf("%s=%s", fname, zero))
 			}
 		}
+		// A subclass passes the fields it does not declare itself on to the
+		// parent's __init__, then sets its own, so a redeclared field's
+		// default replaces the parent's.
+		if s.Parent != "" {
+			params = append(params, "**inherited")
+		}
 		t.writeLine(fmt.Sprintf("def __init__(%s):", strings.Join(params, ", ")))
 		t.indent++
+		if s.Parent != "" {
+			t.writeLine("super().__init__(**inherited)")
+		}
 		for _, field := range s.Fields {
 			fname := sanitizeIdent(field.Name)
 			t.writeLine(fmt.Sprintf("self.%s = %s", fname, fname))
@@ -359,10 +1078,13 @@ func (t *Transpiler) transpileStructDecl(s *ast.StructDecl) {
 		t.writeLine("pass")
 	}
 
-	// Activate self.<field> rewriting for method bodies.
+	// Activate self.<field> rewriting for method bodies, including the
+	// fields inherited from any parent declared in this program.
 	fields := make(map[string]bool, len(s.Fields))
-	for _, field := range s.Fields {
-		fields[field.Name] = true
+	for decl := s; decl != nil; decl = t.structDecls[decl.Parent] {
+		for _, field := range decl.Fields {
+			fields[field.Name] = true
+		}
 	}
 	savedFields := t.methodFields
 	t.methodFields = fields
@@ -372,12 +1094,10 @@ func (t *Transpiler) transpileStructDecl(s *ast.StructDecl) {
 		t.write("\n")
 		mparams := make([]string, 0, len(method.Parameters)+1)
 		mparams = append(mparams, "self")
-		for _, p := range method.Parameters {
-			mparams = append(mparams, sanitizeIdent(p))
-		}
-		t.writeLine(fmt.Sprintf("def %s(%s):", sanitizeIdent(method.Name), strings.Join(mparams, ", ")))
+		mparams = append(mparams, t.paramList(method.Parameters, method.ParamCapabilities, method.ParamTypes, method.Defaults, method.Variadic)...)
+		t.writeLine(fmt.Sprintf("def %s(%s)%s:", sanitizeIdent(method.Name), strings.Join(mparams, ", "), t.returnAnnotation(method.ReturnType)))
 		t.indent++
-		t.transpileBody(method.Body)
+		t.transpileFunctionBody(mparams, t.paramPrelude(method.Parameters, method.Defaults, method.Variadic), method.Body)
 		t.indent--
 	}
 
@@ -406,8 +1126,13 @@ func (t *Transpiler) transpileBody(stmts []ast.Statement) {
 	}
 	for i, stmt := range stmts {
 		if t.indent == 0 {
+			if isFunctionLiteralDef(stmt) {
+				if i == 0 || !isCommentStmt(stmts[i-1]) {
+					t.ensureBlankLines(2)
+				}
+			}
 			switch stmt.(type) {
-			case *ast.FunctionDecl, *ast.StructDecl, *ast.ErrorTypeDecl:
+			case *ast.FunctionDecl, *ast.StructDecl, *ast.ErrorTypeDecl, *ast.EnumDecl, *ast.CapabilityDecl:
 				// Add two blank lines before this definition unless it is
 				// immediately preceded by a comment (in which case the blank
 				// lines were already inserted before that comment block).
@@ -432,6 +1157,20 @@ func isCommentStmt(s ast.Statement) bool {
 	return ok
 }
 
+// isFunctionLiteralDef reports whether s binds a function literal to a name,
+// which is emitted as a def and so needs the same spacing as one.
+func isFunctionLiteralDef(s ast.Statement) bool {
+	switch st := s.(type) {
+	case *ast.VariableDecl:
+		_, ok := st.Value.(*ast.FunctionLiteral)
+		return ok
+	case *ast.Assignment:
+		_, ok := st.Value.(*ast.FunctionLiteral)
+		return ok
+	}
+	return false
+}
+
 // isFirstCommentBeforeDef reports whether stmts[i] is the first comment in a
 // contiguous run of CommentStatements that is immediately followed by a
 // top-level FunctionDecl, StructDecl, or ErrorTypeDecl.
@@ -448,8 +1187,11 @@ func isFirstCommentBeforeDef(stmts []ast.Statement, i int) bool {
 		if _, ok := stmts[j].(*ast.CommentStatement); ok {
 			continue
 		}
+		if isFunctionLiteralDef(stmts[j]) {
+			return true
+		}
 		switch stmts[j].(type) {
-		case *ast.FunctionDecl, *ast.StructDecl, *ast.ErrorTypeDecl:
+		case *ast.FunctionDecl, *ast.StructDecl, *ast.ErrorTypeDecl, *ast.EnumDecl, *ast.CapabilityDecl:
 			return true
 		default:
 			return false
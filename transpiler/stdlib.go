@@ -28,7 +28,9 @@ func (t *Transpiler) transpileFuncCallExpr(e *ast.FunctionCall) string {
 	// User-defined functions take priority over any stdlib mapping with the
 	// same name (e.g. a user can define their own "average" that takes numbers
 	// rather than a list).
-	if t.userFunctions[e.Name] {
+	// Named arguments only reach user-defined functions.
+	if t.userFunctions[e.Name] || len(e.NamedArguments) > 0 {
+		args = append(args, t.keywordArgs(e.NamedArguments)...)
 		return fmt.Sprintf("%s(%s)", sanitizeIdent(e.Name), strings.Join(args, ", "))
 	}
 
@@ -207,6 +209,16 @@ func (t *Transpiler) transpileFuncCallExpr(e *ast.FunctionCall) string {
 		return fmt.Sprintf("%s[%s:%s]", a(0), maybeInt(a(1)), maybeInt(a(2)))
 	case "zip_with":
 		return fmt.Sprintf("_zip_with(%s, %s)", a(0), a(1))
+	case "transform":
+		return fmt.Sprintf("list(map(%s, %s))", a(1), a(0))
+	case "keep":
+		return fmt.Sprintf("list(filter(%s, %s))", a(1), a(0))
+	case "combine":
+		return fmt.Sprintf("_combine(%s, %s, %s)", a(0), a(1), a(2))
+	case "find_first":
+		return fmt.Sprintf("next(filter(%s, %s), None)", a(1), a(0))
+	case "count_where":
+		return fmt.Sprintf("len(list(filter(%s, %s)))", a(1), a(0))
 
 	// ── Lookup table ──────────────────────────────────────────────────────────
 	case "keys":
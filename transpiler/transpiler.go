@@ -11,6 +11,9 @@ package transpiler
 
 import (
 	"github.com/Advik-B/english/ast"
+	"github.com/Advik-B/english/project"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -43,6 +46,16 @@ type Transpiler struct {
 	// to each sibling library .py file in the generated importlib statements.
 	sourceDir string
 
+	// sourceFile is the main source file, when known. Its imports are looked
+	// for from its directory, and inline mode starts the chain of imports
+	// from it, so a file that imports it back is reported as a circular
+	// import.
+	sourceFile string
+
+	// err is the error that stopped Transpile from inlining the imports,
+	// such as a circular import; see Err.
+	err error
+
 	// userFunctions is the set of user-defined function names collected during
 	// the scan pass. Functions in this set take priority over any stdlib mapping
 	// with the same name, so "Declare function average ..." is emitted as a plain
@@ -50,11 +63,23 @@ type Transpiler struct {
 	userFunctions map[string]bool
 
 	// Python module imports required by the generated code.
-	needsMath   bool
-	needsCopy   bool
-	needsRandom bool
-	needsTyping bool // typing.Final for constants
-	needsTime   bool
+	needsMath    bool
+	needsCopy    bool
+	needsRandom  bool
+	needsTyping  bool // typing.Final for constants
+	needsTime    bool
+	needsDecimal bool // decimal.Decimal for decimal literals and casts
+	needsEnum    bool // enum.Enum for "Declare Color as one of ..."
+	// needsProtocol is set by "Declare Speaker as a capability ...", which
+	// becomes a typing.Protocol class.
+	needsProtocol bool
+	// needsCallable is set by a function signature naming the type
+	// "function", annotated as typing.Callable.
+	needsCallable bool
+	// needsThreading and needsQueue are set by background tasks and by
+	// channels, which become threads and _Channel objects.
+	needsThreading bool
+	needsQueue     bool
 
 	// Python helper functions to inject at the top of the output.
 	helpers map[string]bool
@@ -63,6 +88,63 @@ type Transpiler struct {
 	// Bare identifier references to field names inside method bodies are
 	// rewritten to self.<field>.
 	methodFields map[string]bool
+
+	// structFields and structMethods collect the field and method names of
+	// every struct declared in the program, so that "p's x" can be emitted as
+	// the attribute p.x when x is a field rather than a method.
+	structFields  map[string]bool
+	structMethods map[string]bool
+
+	// structDecls maps each struct declared in the program to its
+	// declaration, so a subclass can find the fields it inherits.
+	structDecls map[string]*ast.StructDecl
+
+	// enums holds the name of every enum declared in the program, so that
+	// "Color's red" is emitted as the member access Color.red.
+	enums map[string]bool
+
+	// modules maps each name bound by "Import "file.abc" as m" to the
+	// file's top-level names, each marked true when it is a function, so
+	// that "m's f" is emitted as the call m.f() and "m's x" as m.x.
+	modules map[string]map[string]bool
+
+	// inlinedModules holds, in inline mode, the program of the file behind
+	// each import with "as"; transpileImport() writes it out in full.
+	inlinedModules map[*ast.ImportStatement]*ast.Program
+
+	// aliasedFiles holds, in inline mode, the first import with "as" of
+	// each file, by moduleKey. A later import of the file with "as" builds
+	// no module of its own: sharedModules maps it, and that first import, to
+	// the key under which the first module is kept in the output's _modules.
+	aliasedFiles  map[string]*ast.ImportStatement
+	sharedModules map[*ast.ImportStatement]string
+
+	// tupleFunctions maps every function with a "Return a and b." statement
+	// to the number of values it returns, or -1 when its Returns disagree.
+	// Its result is a Python tuple, so destructuring a call to one into as
+	// many names needs no _unpack helper. scanFunction is the function the
+	// scan pass is inside.
+	tupleFunctions map[string]int
+	scanFunction   string
+
+	// funcScopes holds, for each def being written, innermost last, how each
+	// variable its body uses is bound: "local", "global" or "nonlocal". A
+	// nested def uses it to tell which of its outer variables are globals.
+	funcScopes []map[string]string
+
+	// anonCount numbers the helper defs hoisted out of multi-statement
+	// function literals (_anonymous_1, _anonymous_2, ...).
+	anonCount int
+
+	// Python has no labeled break, so "Break out of outer." inside another
+	// loop sets the flag _break_outer and breaks, and each loop it passes
+	// through breaks again when the flag is set. escapeFlags maps each such
+	// break or continue to its flag, and loopEscapes each loop to the flags
+	// passing through it. scanLoops holds the loops around the statement
+	// the scan pass is at, innermost last.
+	escapeFlags map[ast.Statement]string
+	loopEscapes map[ast.Statement][]loopEscape
+	scanLoops   []ast.Statement
 }
 
 // NewTranspiler creates a Transpiler for .abc source files.
@@ -111,6 +193,21 @@ func (t *Transpiler) WithSourceDir(dir string) *Transpiler {
 	return t
 }
 
+// WithSourceFile sets the main source file, and with it the source
+// directory (see WithSourceDir).
+func (t *Transpiler) WithSourceFile(file string) *Transpiler {
+	t.sourceFile = file
+	t.sourceDir = filepath.Dir(file)
+	return t
+}
+
+// Err returns the error that stopped the last Transpile from inlining the
+// program's imports, such as a *project.ImportCycleError, or nil. The
+// program is then transpiled with its imports left in place.
+func (t *Transpiler) Err() error {
+	return t.err
+}
+
 // Transpile converts a parsed English program to a Python source string.
 //
 // It runs two passes:
@@ -126,8 +223,17 @@ func (t *Transpiler) Transpile(program *ast.Program) string {
 	// files so that the generated Python is self-contained. In non-inline mode
 	// ImportStatements are kept in the AST and transpileImport() emits Python
 	// importlib code that loads the sibling .py files produced by the CLI.
+	t.err = nil
 	if t.inlineMode {
-		program = inlineImports(program, make(map[string]bool))
+		t.inlinedModules = make(map[*ast.ImportStatement]*ast.Program)
+		t.aliasedFiles = make(map[string]*ast.ImportStatement)
+		t.sharedModules = make(map[*ast.ImportStatement]string)
+		inlined, err := t.inlineImports(program, t.sourceFile, make(map[string]bool), project.NewRegistry(t.sourceFile))
+		if err != nil {
+			t.err = err
+		} else {
+			program = inlined
+		}
 	}
 
 	// Pass 1 – collect import and helper requirements.
@@ -163,13 +269,47 @@ func (t *Transpiler) Transpile(program *ast.Program) string {
 	if t.needsTime {
 		out.WriteString("import time\n")
 	}
+	if t.needsEnum {
+		out.WriteString("import enum\n")
+	}
+	if t.needsThreading {
+		out.WriteString("import threading\n")
+	}
+	if t.needsQueue {
+		out.WriteString("import queue\n")
+	}
+	if t.needsDecimal {
+		out.WriteString("from decimal import Decimal\n")
+	}
+	var typingNames []string
 	if t.needsTyping {
-		out.WriteString("from typing import Final\n")
+		typingNames = append(typingNames, "Final")
+	}
+	if t.needsCallable {
+		typingNames = append(typingNames, "Callable")
 	}
-	if t.needsMath || t.needsCopy || t.needsRandom || t.needsTime || t.needsTyping {
+	if t.needsProtocol {
+		typingNames = append(typingNames, "Protocol")
+	}
+	if len(typingNames) > 0 {
+		out.WriteString("from typing import " + strings.Join(typingNames, ", ") + "\n")
+	}
+	if t.needsMath || t.needsCopy || t.needsRandom || t.needsTime || t.needsEnum || t.needsThreading || t.needsQueue || t.needsDecimal || len(typingNames) > 0 {
 		out.WriteString("\n")
 	}
 
+	// A library with private declarations lists its public names, so the
+	// importer's "from library import *" cannot reach the private ones.
+	if !t.inlineMode {
+		if names := exportedNames(program.Statements); names != nil {
+			quoted := make([]string, len(names))
+			for i, name := range names {
+				quoted[i] = strconv.Quote(name)
+			}
+			out.WriteString("__all__ = [" + strings.Join(quoted, ", ") + "]\n\n")
+		}
+	}
+
 	// Emit helper functions in a deterministic order.
 	// Each helper is followed by two newlines (\n\n) so the body that follows
 	// is separated by one blank line.
@@ -201,9 +341,15 @@ func (t *Transpiler) scanStmt(stmt ast.Statement) {
 		// Register this as a user-defined function so that transpileFuncCallExpr
 		// prefers it over any stdlib mapping with the same name.
 		t.userFunctions[s.Name] = true
+		for _, d := range s.Defaults {
+			t.scanExpr(d)
+		}
+		outer, outerLoops := t.scanFunction, t.scanLoops
+		t.scanFunction, t.scanLoops = s.Name, nil
 		for _, c := range s.Body {
 			t.scanStmt(c)
 		}
+		t.scanFunction, t.scanLoops = outer, outerLoops
 	case *ast.IfStatement:
 		for _, c := range s.Then {
 			t.scanStmt(c)
@@ -216,18 +362,26 @@ func (t *Transpiler) scanStmt(stmt ast.Statement) {
 		for _, c := range s.Else {
 			t.scanStmt(c)
 		}
-	case *ast.WhileLoop:
-		for _, c := range s.Body {
-			t.scanStmt(c)
+	case *ast.WhenStatement:
+		t.scanExpr(s.Subject)
+		for _, wc := range s.Cases {
+			for _, c := range wc.Body {
+				t.scanStmt(c)
+			}
 		}
-	case *ast.ForLoop:
-		for _, c := range s.Body {
+		for _, c := range s.Otherwise {
 			t.scanStmt(c)
 		}
+	case *ast.WhileLoop:
+		t.scanLoop(s, s.Body)
+	case *ast.ForLoop:
+		t.scanLoop(s, s.Body)
 	case *ast.ForEachLoop:
-		for _, c := range s.Body {
-			t.scanStmt(c)
-		}
+		t.scanLoop(s, s.Body)
+	case *ast.BreakStatement:
+		t.scanLoopEscape(s, s.Label, false)
+	case *ast.ContinueStatement:
+		t.scanLoopEscape(s, s.Label, true)
 	case *ast.TryStatement:
 		for _, c := range s.TryBody {
 			t.scanStmt(c)
@@ -238,13 +392,43 @@ func (t *Transpiler) scanStmt(stmt ast.Statement) {
 		for _, c := range s.FinallyBody {
 			t.scanStmt(c)
 		}
+	case *ast.ImportStatement:
+		if s.Alias != "" {
+			t.scanModuleImport(s)
+		}
+	case *ast.EnumDecl:
+		if t.enums == nil {
+			t.enums = make(map[string]bool)
+		}
+		t.enums[s.Name] = true
+		t.needsEnum = true
+	case *ast.CapabilityDecl:
+		t.needsProtocol = true
 	case *ast.StructDecl:
+		if t.structFields == nil {
+			t.structFields = make(map[string]bool)
+			t.structMethods = make(map[string]bool)
+			t.structDecls = make(map[string]*ast.StructDecl)
+		}
+		t.structDecls[s.Name] = s
+		for _, f := range s.Fields {
+			t.structFields[f.Name] = true
+		}
 		for _, m := range s.Methods {
+			t.structMethods[m.Name] = true
+			for _, d := range m.Defaults {
+				t.scanExpr(d)
+			}
 			for _, c := range m.Body {
 				t.scanStmt(c)
 			}
 		}
 	case *ast.VariableDecl:
+		if _, ok := s.Value.(*ast.FunctionLiteral); ok {
+			// Emitted as "def name(...)", so calls through the variable are
+			// user calls rather than stdlib ones.
+			t.userFunctions[s.Name] = true
+		}
 		t.scanExpr(s.Value)
 		if s.IsConstant {
 			t.needsTyping = true
@@ -268,20 +452,123 @@ func (t *Transpiler) scanStmt(stmt ast.Statement) {
 		for _, v := range s.Values {
 			t.scanExpr(v)
 		}
+	case *ast.YieldStatement:
+		t.scanExpr(s.Value)
+	case *ast.TestBlock:
+		for _, c := range s.Body {
+			t.scanStmt(c)
+		}
+	case *ast.BackgroundStatement:
+		t.needsThreading = true
+		t.helpers["_Task"] = true
+		for _, c := range s.Body {
+			t.scanStmt(c)
+		}
+	case *ast.WaitStatement:
+		t.scanExpr(s.Task)
+	case *ast.SendStatement:
+		t.scanExpr(s.Value)
+		t.scanExpr(s.Channel)
 	case *ast.ReturnStatement:
 		t.scanExpr(s.Value)
+		for _, v := range s.Values {
+			t.scanExpr(v)
+		}
+		if len(s.Values) > 0 && t.scanFunction != "" {
+			if t.tupleFunctions == nil {
+				t.tupleFunctions = make(map[string]int)
+			}
+			if count, seen := t.tupleFunctions[t.scanFunction]; seen && count != len(s.Values) {
+				t.tupleFunctions[t.scanFunction] = -1
+			} else {
+				t.tupleFunctions[t.scanFunction] = len(s.Values)
+			}
+		}
 	case *ast.CallStatement:
 		if s.FunctionCall != nil {
 			t.scanFuncCall(s.FunctionCall.Name)
 			for _, a := range s.FunctionCall.Arguments {
 				t.scanExpr(a)
 			}
+			for _, na := range s.FunctionCall.NamedArguments {
+				t.scanExpr(na.Value)
+			}
 		}
 		if s.MethodCall != nil {
 			t.scanExpr(s.MethodCall.Object)
 		}
 	case *ast.RaiseStatement:
 		t.scanExpr(s.Message)
+	case *ast.AssertStatement:
+		t.scanExpr(s.Condition)
+		t.scanExpr(s.Message)
+	}
+}
+
+// scanLoop scans the body of a loop.
+func (t *Transpiler) scanLoop(loop ast.Statement, body []ast.Statement) {
+	t.scanLoops = append(t.scanLoops, loop)
+	for _, c := range body {
+		t.scanStmt(c)
+	}
+	t.scanLoops = t.scanLoops[:len(t.scanLoops)-1]
+}
+
+// scanLoopEscape records a break or continue of the loop called label when
+// it is not the innermost loop, giving it a flag and marking the loops it
+// leaves through.
+func (t *Transpiler) scanLoopEscape(stmt ast.Statement, label string, cont bool) {
+	if label == "" {
+		return
+	}
+	top := len(t.scanLoops) - 1
+	for i := top; i >= 0; i-- {
+		if loopLabel(t.scanLoops[i]) != label {
+			continue
+		}
+		if i == top {
+			return // a plain break or continue
+		}
+		flag := "_break_" + sanitizeIdent(label)
+		if cont {
+			flag = "_continue_" + sanitizeIdent(label)
+		}
+		if t.escapeFlags == nil {
+			t.escapeFlags = make(map[ast.Statement]string)
+			t.loopEscapes = make(map[ast.Statement][]loopEscape)
+		}
+		t.escapeFlags[stmt] = flag
+	loops:
+		for j := i + 1; j <= top; j++ {
+			loop := t.scanLoops[j]
+			for _, e := range t.loopEscapes[loop] {
+				if e.flag == flag {
+					continue loops
+				}
+			}
+			t.loopEscapes[loop] = append(t.loopEscapes[loop], loopEscape{flag: flag, cont: cont, direct: j == i+1})
+		}
+		return
+	}
+}
+
+// scanModuleImport records the names the file behind "Import "file.abc" as
+// m" declares, and in inline mode scans the file's code, which is written out
+// in full.
+func (t *Transpiler) scanModuleImport(s *ast.ImportStatement) {
+	prog, inlined := t.inlinedModules[s]
+	if !inlined {
+		var err error
+		if prog, err = parseImportFile(t.sourceFile, s.Path); err != nil {
+			prog = &ast.Program{}
+		}
+	}
+	if t.modules == nil {
+		t.modules = make(map[string]map[string]bool)
+	}
+	t.modules[s.Alias] = moduleMembers(prog.Statements)
+	if inlined {
+		t.scanProgram(prog)
 	}
 }
 
@@ -295,11 +582,17 @@ func (t *Transpiler) scanExpr(expr ast.Expression) {
 		for _, a := range e.Arguments {
 			t.scanExpr(a)
 		}
+		for _, na := range e.NamedArguments {
+			t.scanExpr(na.Value)
+		}
 	case *ast.MethodCall:
 		t.scanExpr(e.Object)
 		for _, a := range e.Arguments {
 			t.scanExpr(a)
 		}
+		for _, na := range e.NamedArguments {
+			t.scanExpr(na.Value)
+		}
 	case *ast.BinaryExpression:
 		t.scanExpr(e.Left)
 		t.scanExpr(e.Right)
@@ -335,6 +628,12 @@ func (t *Transpiler) scanExpr(expr ast.Expression) {
 		}
 	case *ast.ErrorTypeCheckExpression:
 		t.scanExpr(e.Value)
+	case *ast.ChannelLiteral:
+		t.needsQueue = true
+		t.needsThreading = true
+		t.helpers["_Channel"] = true
+	case *ast.ReceiveExpression:
+		t.scanExpr(e.Channel)
 	case *ast.ListLiteral:
 		for _, el := range e.Elements {
 			t.scanExpr(el)
@@ -349,6 +648,17 @@ func (t *Transpiler) scanExpr(expr ast.Expression) {
 		for _, el := range e.Elements {
 			t.scanExpr(el)
 		}
+	case *ast.FunctionLiteral:
+		outerLoops := t.scanLoops
+		t.scanLoops = nil
+		for _, c := range e.Body {
+			t.scanStmt(c)
+		}
+		t.scanLoops = outerLoops
+	case *ast.InterpolatedString:
+		for _, p := range e.Parts {
+			t.scanExpr(p)
+		}
 	}
 }
 
@@ -376,6 +686,8 @@ func (t *Transpiler) scanFuncCall(name string) {
 		t.helpers["_unique"] = true
 	case "zip_with":
 		t.helpers["_zip_with"] = true
+	case "combine":
+		t.helpers["_combine"] = true
 	case "sign":
 		t.helpers["_sign"] = true
 	case "read_file":
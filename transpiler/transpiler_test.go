@@ -1,10 +1,13 @@
 package transpiler_test
 
 import (
+	"errors"
 	ast_pkg "github.com/Advik-B/english/ast"
 	"github.com/Advik-B/english/parser"
+	"github.com/Advik-B/english/project"
 	"github.com/Advik-B/english/transpiler"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -136,6 +139,155 @@ func TestModulo(t *testing.T) {
 	assertContainsLine(t, out, `r = 10 % 3`)
 }
 
+func TestPowerAndFloorDivision(t *testing.T) {
+	out := transpile(t, `Declare p to be 2 to the power of 3 to the power of 2.
+Declare q to be 7 divided evenly by 2.`)
+	assertContainsLine(t, out, `p = 2 ** (3 ** 2)`)
+	assertContainsLine(t, out, `q = 7 // 2`)
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	out := transpile(t, `Declare x to be 5.
+Declare m to be x bitwise and 3.
+Declare s to be 1 shifted left by 4 bitwise or x.`)
+	assertContainsLine(t, out, `m = x & 3`)
+	assertContainsLine(t, out, `s = (1 << 4) | x`)
+}
+
+func TestDecimalAndWholeNumbers(t *testing.T) {
+	out := transpile(t, `Declare price to be the decimal 0.10.
+Declare big to be the whole number 12345678901234567890.
+Declare d to be 0.5 cast to decimal.`)
+	assertContains(t, out, "from decimal import Decimal")
+	assertContainsLine(t, out, `price = Decimal("0.10")`)
+	assertContainsLine(t, out, `big = 12345678901234567890`)
+	assertContainsLine(t, out, `d = Decimal(str(0.5))`)
+}
+
+func TestNoDecimalImportWhenNotNeeded(t *testing.T) {
+	out := transpile(t, `Declare big to be the whole number 7.`)
+	if strings.Contains(out, "from decimal import Decimal") {
+		t.Errorf("unexpected decimal import in:\n%s", out)
+	}
+}
+
+func TestStructKindOf(t *testing.T) {
+	out := transpile(t, `declare Animal as a structure with the following fields:
+    name is a string.
+thats it.
+declare Dog as a kind of Animal with the following fields:
+    breed is a string.
+
+    let describe be a function that does the following:
+        Print name, breed.
+    thats it.
+thats it.`)
+	assertContainsLine(t, out, "class Dog(Animal):")
+	assertContainsLine(t, out, `def __init__(self, breed="", **inherited):`)
+	assertContainsLine(t, out, "super().__init__(**inherited)")
+	assertContainsLine(t, out, "print(self.name, self.breed)")
+}
+
+func TestCapabilityDecl(t *testing.T) {
+	out := transpile(t, `Declare Speaker as a capability requiring a function speak.
+Declare limit to always be 3.
+declare Dog as a structure that can Speaker with the following fields:
+    let speak be a function that does the following:
+        Print "woof".
+    thats it.
+thats it.
+Declare function greet that takes pet as anything that can Speaker and does the following:
+    Call pet's speak.
+thats it.`)
+	assertContains(t, out, "from typing import Final, Protocol")
+	assertContainsLine(t, out, "class Speaker(Protocol):")
+	assertContainsLine(t, out, "def speak(self): ...")
+	assertContainsLine(t, out, "class Dog(Speaker):")
+	assertContainsLine(t, out, "def greet(pet: Speaker):")
+}
+
+func TestTypedSignature(t *testing.T) {
+	out := transpile(t, `Declare function area that takes width as number and height as number and returns a number and does the following:
+    Return width * height.
+thats it.
+Declare function show that takes p as a Point and scale as number (defaulting to 1) and returns nothing and does the following:
+    Print p's x * scale.
+thats it.
+Declare function apply that takes f as a function and returns a lookup table and does the following:
+    Return f(1).
+thats it.`)
+	assertContains(t, out, "from typing import Callable")
+	assertContainsLine(t, out, "def area(width: float, height: float) -> float:")
+	assertContainsLine(t, out, `def show(p: "Point", scale: float = 1) -> None:`)
+	assertContainsLine(t, out, "def apply(f: Callable) -> dict:")
+}
+
+func TestElementTypes(t *testing.T) {
+	out := transpile(t, `Declare scores as a list of numbers to be [1, 2].
+Declare ages as a lookup table from text to decimal.`)
+	assertContains(t, out, "from decimal import Decimal")
+	assertContainsLine(t, out, "scores: list[float] = [1, 2]")
+	assertContainsLine(t, out, "ages: dict[str, Decimal] = {}")
+}
+
+func TestElementTypesUserDeclared(t *testing.T) {
+	out := transpile(t, `Declare Point as a structure with the following fields:
+    x is a number with 0 being the default.
+thats it.
+Declare Color as one of red and green.
+Declare points as a list of Point.
+Declare shades as a lookup table from text to Color.`)
+	assertContainsLine(t, out, "points: list[Point] = []")
+	assertContainsLine(t, out, "shades: dict[str, Color] = {}")
+}
+
+func TestDefaultsAndNamedArguments(t *testing.T) {
+	out := transpile(t, `Declare function greet that takes name and greeting (defaulting to "Hello") and does the following:
+    Print greeting, name.
+thats it.
+Call greet with "Bo" and greeting as "Hi".
+Set x to be the result of calling greet with name as "Cy".`)
+	assertContainsLine(t, out, `def greet(name, greeting="Hello"):`)
+	assertContainsLine(t, out, `greet("Bo", greeting="Hi")`)
+	assertContainsLine(t, out, `x = greet(name="Cy")`)
+}
+
+func TestDefaultWorkedOutEachCall(t *testing.T) {
+	out := transpile(t, `Declare function pair that takes a and b (defaulting to a * 2) and items (defaulting to []) and does the following:
+    Print a, b.
+thats it.`)
+	assertContainsLine(t, out, `def pair(a, b=None, items=None):`)
+	assertContainsLine(t, out, `if b is None:`)
+	assertContainsLine(t, out, `b = a * 2`)
+	assertContainsLine(t, out, `if items is None:`)
+	assertContainsLine(t, out, `items = []`)
+}
+
+func TestRestParameter(t *testing.T) {
+	out := transpile(t, `Declare function label that takes prefix and any number of items and does the following:
+    Print prefix, items.
+thats it.
+Call label with "b" and 1 and 2.`)
+	assertContainsLine(t, out, `def label(prefix, *items):`)
+	assertContainsLine(t, out, `items = list(items)`)
+	assertContainsLine(t, out, `label("b", 1, 2)`)
+}
+
+func TestEnumDecl(t *testing.T) {
+	out := transpile(t, `Declare Color as one of red, green and blue.
+Declare c to be Color's green.
+For each m in Color, do the following:
+    Print m.
+thats it.`)
+	assertContains(t, out, "import enum")
+	assertContainsLine(t, out, "class Color(enum.Enum):")
+	assertContainsLine(t, out, "red = 1")
+	assertContainsLine(t, out, "blue = 3")
+	assertContainsLine(t, out, "return self.name")
+	assertContainsLine(t, out, "c = Color.green")
+	assertContainsLine(t, out, "for m in Color:")
+}
+
 // ─── Control flow ─────────────────────────────────────────────────────────────
 
 func TestIfElse(t *testing.T) {
@@ -151,6 +303,40 @@ thats it.`)
 	assertContains(t, out, `print("small")`)
 }
 
+func TestWhenMatch(t *testing.T) {
+	out := transpile(t, `Declare command to be "halt".
+When command is "start":
+    Print "starting".
+When it is "stop" or "halt":
+    Print "stopping".
+When it is between 1 and 10:
+    Print "small".
+When it is a number:
+    Print "number".
+Otherwise:
+    Print "unknown".
+thats it.`)
+	assertContains(t, out, "match command:")
+	assertContains(t, out, `case "stop" | "halt":`)
+	assertContains(t, out, "case command if (isinstance(command, (int, float)) and not isinstance(command, bool) and 1 <= command <= 10):")
+	assertContains(t, out, "case int() | float() if not isinstance(command, bool):")
+	assertContains(t, out, "case _:")
+}
+
+func TestWhenMatchBooleanSubject(t *testing.T) {
+	out := transpile(t, `Declare b to be true.
+When b is 1:
+    Print "one".
+When it is 2 or true:
+    Print "two or true".
+When it is a boolean:
+    Print "boolean".
+thats it.`)
+	assertContains(t, out, "case 1 if not isinstance(b, bool):")
+	assertContains(t, out, "case b if (b == 2 and not isinstance(b, bool)) or b is True:")
+	assertContains(t, out, "case bool():")
+}
+
 func TestWhileLoop(t *testing.T) {
 	out := transpile(t, `Declare i to be 0.
 repeat the following while i is less than 5:
@@ -190,6 +376,51 @@ thats it.`)
 	assertContains(t, out, "break")
 }
 
+func TestLoopLabels(t *testing.T) {
+	out := transpile(t, `Declare grid to be [[1, 2], [3, 4]].
+For each row in grid, do the following and call this loop rows:
+    Repeat the following while true and call this loop scan:
+        For each cell in row, do the following:
+            If cell is equal to 2, then
+                Continue rows.
+            thats it.
+            If cell is equal to 4, then
+                Break out of rows.
+            thats it.
+            Break out of scan.
+        thats it.
+    thats it.
+    Print row.
+thats it.`)
+	// Python has no labeled break, so the loops in between break again on
+	// a flag, and the loop directly inside rows continues it.
+	assertContains(t, out, `for row in grid:
+    _continue_rows = False
+    _break_rows = False
+    while True:
+        _break_scan = False
+        for cell in row:
+            if cell == 2:
+                _continue_rows = True
+                break
+            if cell == 4:
+                _break_rows = True
+                break
+            _break_scan = True
+            break
+        if _continue_rows:
+            break
+        if _break_rows:
+            break
+        if _break_scan:
+            break
+    if _continue_rows:
+        continue
+    if _break_rows:
+        break
+    print(row)`)
+}
+
 // ─── Functions ────────────────────────────────────────────────────────────────
 
 func TestFunctionDecl(t *testing.T) {
@@ -208,6 +439,38 @@ Call greet with "Alice".`)
 	assertContains(t, out, `greet("Alice")`)
 }
 
+func TestFunctionLiteralDeclaredAsDef(t *testing.T) {
+	out := transpile(t, `Declare double to be a function that takes x and returns x * 2.`)
+	assertContains(t, out, "def double(x):")
+	assertContains(t, out, "return x * 2")
+}
+
+func TestFunctionLiteralLambda(t *testing.T) {
+	out := transpile(t, `Declare ops to be [a function that takes n and returns n + 1].`)
+	assertContainsLine(t, out, "ops = [lambda n: n + 1]")
+}
+
+func TestFunctionLiteralHoisted(t *testing.T) {
+	out := transpile(t, `Declare ops to be [a function that takes n and does the following:
+    Print n.
+    Return n.
+thats it].`)
+	assertContains(t, out, "def _anonymous_1(n):")
+	assertContainsLine(t, out, "ops = [_anonymous_1]")
+}
+
+func TestInterpolatedStringFString(t *testing.T) {
+	out := transpile(t, `Declare name to be "Bo".
+Print "Hi {name}, {} next is {2 + 3}".`)
+	assertContainsLine(t, out, `print(f"Hi {name}, {{}} next is {2 + 3}")`)
+}
+
+func TestInterpolatedStringConcatFallback(t *testing.T) {
+	out := transpile(t, `Declare name to be "Bo".
+Print "Hi {'dear ' + name}!".`)
+	assertContainsLine(t, out, `print(("Hi " + str("dear " + name) + "!"))`)
+}
+
 // ─── Booleans / nil ──────────────────────────────────────────────────────────
 
 func TestBooleanLiterals(t *testing.T) {
@@ -683,6 +946,23 @@ Print zip_with(a, b).`)
 	assertContains(t, out, "def _zip_with")
 }
 
+func TestStdlibHigherOrder(t *testing.T) {
+	out := transpile(t, `Declare nums to be [1, 2, 3].
+Declare double to be a function that takes x and returns x * 2.
+Declare add to be a function that takes a and b and returns a + b.
+Print transform(nums, double).
+Print keep(nums, a function that takes n and returns n is greater than 1).
+Print combine(nums, add, 0).
+Print find_first(nums, double).
+Print count_where(nums, double).`)
+	assertContains(t, out, "list(map(double, nums))")
+	assertContains(t, out, "list(filter(lambda n: n > 1, nums))")
+	assertContains(t, out, "_combine(nums, add, 0)")
+	assertContains(t, out, "def _combine")
+	assertContains(t, out, "next(filter(double, nums), None)")
+	assertContains(t, out, "len(list(filter(double, nums)))")
+}
+
 // ─── stdlib – Lookup table ───────────────────────────────────────────────────
 
 func TestStdlibKeys(t *testing.T) {
@@ -960,6 +1240,134 @@ Print square(3).`
 	}
 }
 
+func TestNamespacedImportInlining(t *testing.T) {
+	dir := t.TempDir()
+	libPath := dir + "/geometry.abc"
+	libSrc := `Declare tau to always be 6.28.
+Declare function area that takes r and does the following:
+    Return r * r.
+thats it.
+`
+	if err := os.WriteFile(libPath, []byte(libSrc), 0644); err != nil {
+		t.Fatalf("write lib: %v", err)
+	}
+
+	mainSrc := `Import "` + libPath + `" as geo.
+Declare a to be the result of calling geo's area with 2.
+Print geo's tau.`
+
+	// The module's code runs inside a function whose names are handed back
+	// as a namespace, so they do not leak into the importer.
+	out := transpileInlined(t, mainSrc)
+	assertContainsLine(t, out, "class _Module:")
+	assertContainsLine(t, out, "def _module_geo():")
+	assertContainsLine(t, out, "return _Module(area=lambda: area, tau=lambda: tau)")
+	assertContainsLine(t, out, "geo = _module_geo()")
+	assertContainsLine(t, out, "a = geo.area(2)")
+	assertContainsLine(t, out, "print(geo.tau)")
+}
+
+func TestPrivateNamesLeftOutOfModule(t *testing.T) {
+	dir := t.TempDir()
+	libPath := dir + "/geometry.abc"
+	libSrc := `Declare privately scale to be 2.
+Declare function area that takes r and does the following:
+    Return r * r * scale.
+thats it.
+`
+	if err := os.WriteFile(libPath, []byte(libSrc), 0644); err != nil {
+		t.Fatalf("write lib: %v", err)
+	}
+
+	out := transpileInlined(t, `Import "`+libPath+`" as geo.
+Declare a to be the result of calling geo's area with 2.`)
+	assertContainsLine(t, out, "scale = 2")
+	assertContainsLine(t, out, "return _Module(area=lambda: area)")
+}
+
+func TestModuleFunctionSetsModuleVariable(t *testing.T) {
+	dir := t.TempDir()
+	libPath := dir + "/counter.abc"
+	libSrc := `Declare count to be 0.
+Declare function bump that does the following:
+    Set count to count + 1.
+thats it.
+`
+	if err := os.WriteFile(libPath, []byte(libSrc), 0644); err != nil {
+		t.Fatalf("write lib: %v", err)
+	}
+
+	// count lives in _module_c, not at the top of the output.
+	out := transpileInlined(t, `Import "`+libPath+`" as c.
+Call c's bump.
+Print c's count.`)
+	assertContainsLine(t, out, "nonlocal count")
+	if strings.Contains(out, "global count") {
+		t.Errorf("count should not be a global:\n%s", out)
+	}
+	assertContainsLine(t, out, "print(c.count)")
+}
+
+func TestRepeatedAliasedImportBuildsModuleOnce(t *testing.T) {
+	dir := t.TempDir()
+	libSrc := `Print "geometry loaded".
+Declare tau to always be 6.28.
+`
+	if err := os.WriteFile(filepath.Join(dir, "geometry.abc"), []byte(libSrc), 0644); err != nil {
+		t.Fatalf("write lib: %v", err)
+	}
+
+	tr := transpiler.NewTranspilerInlined().WithSourceFile(filepath.Join(dir, "main.abc"))
+	out := tr.Transpile(parse(t, `Import "geometry.abc" as geo.
+Import "geometry.abc" as g2.
+Print g2's tau.`))
+	if err := tr.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The file's top level runs once; the second alias shares the module.
+	if n := strings.Count(out, `print("geometry loaded")`); n != 1 {
+		t.Errorf("expected the module to be built once, found it %d times:\n%s", n, out)
+	}
+	assertContainsLine(t, out, "geo = _module_geo()")
+	assertContainsLine(t, out, `_modules["geometry.abc"] = geo`)
+	assertContainsLine(t, out, `g2 = _modules["geometry.abc"]`)
+	assertContainsLine(t, out, "print(g2.tau)")
+}
+
+func TestCircularImportInlining(t *testing.T) {
+	dir := t.TempDir()
+	aPath, bPath := dir+"/a.abc", dir+"/b.abc"
+	xPath, yPath := dir+"/x.abc", dir+"/y.abc"
+	files := map[string]string{
+		aPath: `Import "` + bPath + `".
+Print "a".`,
+		bPath: `Import "` + aPath + `".
+Print "b".`,
+		xPath: `Import "` + yPath + `" as y.
+Print "x".`,
+		yPath: `Import "` + xPath + `" as x.
+Print "y".`,
+	}
+	for path, src := range files {
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	for _, tc := range []struct{ main, other string }{{aPath, bPath}, {xPath, yPath}} {
+		tr := transpiler.NewTranspilerInlined().WithSourceFile(tc.main)
+		tr.Transpile(parse(t, files[tc.main]))
+		var cycle *project.ImportCycleError
+		if !errors.As(tr.Err(), &cycle) {
+			t.Fatalf("%s: expected a circular import, got %v", tc.main, tr.Err())
+		}
+		want := []string{tc.main, tc.other, tc.main}
+		if strings.Join(cycle.Files, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: expected chain %v, got %v", tc.main, want, cycle.Files)
+		}
+	}
+}
+
 // ─── Non-inline import (default mode) ────────────────────────────────────────
 
 func TestNonInlineImportAll(t *testing.T) {
@@ -980,6 +1388,36 @@ Print square(3).`)
 	assertContains(t, out, "from math_library import square, cube")
 }
 
+func TestNonInlineNamespacedImport(t *testing.T) {
+	// "as" emits "import module as alias"; members become attributes.
+	out := transpile(t, `Import "examples/math_library.abc" as ml.
+Declare s to be the result of calling ml's square with 3.`)
+	assertContainsLine(t, out, "import math_library as ml")
+	assertContainsLine(t, out, "s = ml.square(3)")
+}
+
+func TestPrivateDeclarationsSetAll(t *testing.T) {
+	// A library with private names lists its public ones in __all__, so
+	// "from library import *" leaves the private ones out.
+	out := transpile(t, `Declare private function helper that does the following:
+    Return 1.
+thats it.
+Declare function run that does the following:
+    Return helper().
+thats it.
+Declare privately cache to be 0.
+Declare limit to always be 3.
+declare Point as a structure with the following fields:
+    x is a number.
+thats it.`)
+	assertContainsLine(t, out, `__all__ = ["Point", "limit", "run"]`)
+	assertContainsLine(t, out, "def helper():")
+
+	if out := transpile(t, `Declare limit to always be 3.`); strings.Contains(out, "__all__") {
+		t.Errorf("a file without private declarations should not set __all__:\n%s", out)
+	}
+}
+
 func TestNonInlineCrossDirectoryImport(t *testing.T) {
 	// When the library is in a subdirectory relative to the main file, a
 	// sys.path.insert line is emitted before the from-import.
@@ -991,6 +1429,31 @@ Print "hello".`)
 	assertContains(t, result, "from utils import *")
 }
 
+func TestNonInlineProjectLibraryImport(t *testing.T) {
+	// A library found in the project's libraries/ folder, named without its
+	// extension, is put on sys.path even when the main file is given
+	// relative to the current directory.
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "libraries", "strings"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, src := range map[string]string{
+		"english.project":               "name: shop\n",
+		"libraries/strings/slugify.abc": "Declare function slugify that takes s and does the following:\n    Return s.\nthats it.\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	prog := parse(t, `Import "strings/slugify".
+Print slugify("a").`)
+	result := transpiler.NewTranspiler().WithSourceFile("main.abc").Transpile(prog)
+	assertContains(t, result, `sys.path.insert(0, os.path.join(os.path.dirname(__file__), "libraries/strings"))`)
+	assertContains(t, result, "from slugify import *")
+}
+
 func TestNonInlineSameDirectoryImport(t *testing.T) {
 	// When library and main file are in the same directory, no sys.path line needed.
 	prog := parse(t, `Import "examples/lib.abc".
@@ -1154,3 +1617,108 @@ func TestSleepNoBareFunctionCall(t *testing.T) {
 		t.Errorf("sleep should emit time.sleep(), not bare sleep(); got:\n%s", out)
 	}
 }
+
+func TestDestructuring(t *testing.T) {
+	out := transpile(t, `Declare function divide that takes a and b and does the following:
+    Return a divided evenly by b and the remainder of a divided by b.
+thats it.
+Declare q and r to be the result of calling divide with 7 and 2.
+Declare pair to be [1, 2].
+Declare x and y to be pair.
+Declare first and the rest as rest to be [1, 2, 3].`)
+	assertContainsLine(t, out, `return a // b, a % b`)
+	assertContainsLine(t, out, `q, r = divide(7, 2)`)
+	assertContainsLine(t, out, `x, y = _unpack(pair, "x", "y")`)
+	assertContainsLine(t, out, `first, *rest = [1, 2, 3]`)
+}
+
+func TestGiveBack(t *testing.T) {
+	out := transpile(t, `Declare function evens that takes limit and does the following:
+    Declare i to be 0.
+    repeat the following while i is less than limit:
+        Give back i.
+        Set i to i + 2.
+    thats it.
+thats it.
+For each n in evens(7), do the following:
+    Print n.
+thats it.`)
+	assertContainsLine(t, out, `yield i`)
+	assertContainsLine(t, out, `for n in evens(7):`)
+}
+
+func TestBackgroundTasks(t *testing.T) {
+	out := transpile(t, `Declare results to be a channel.
+Do the following in the background and call it producer:
+    Send 10 to results.
+thats it.
+Receive from results into answer.
+Wait for producer.`)
+	assertContains(t, out, "import threading")
+	assertContains(t, out, "import queue")
+	assertContains(t, out, "class _Task(threading.Thread):")
+	assertContains(t, out, "class _Channel:")
+	assertContainsLine(t, out, `results = _Channel()`)
+	assertContainsLine(t, out, `def _background_producer():`)
+	assertContainsLine(t, out, `results.put(10)`)
+	assertContainsLine(t, out, `producer = _Task(_background_producer)`)
+	assertContainsLine(t, out, `answer = results.get()`)
+	assertContainsLine(t, out, `producer.wait()`)
+}
+
+func TestBackgroundTaskSetsOuterVariables(t *testing.T) {
+	out := transpile(t, `Declare counter to be 0.
+Do the following in the background and call it job:
+    Set counter to be counter + 1.
+thats it.
+Wait for job.
+Declare function run that does the following:
+    Declare total to be 0.
+    Do the following in the background and call it adder:
+        Declare step to be 5.
+        Set total to be total + step.
+        Set counter to be counter + 1.
+    thats it.
+    Wait for adder.
+    Return total.
+thats it.`)
+	assertContainsLine(t, out, `def _background_job():`)
+	assertContainsLine(t, out, `global counter`)
+	assertContainsLine(t, out, `nonlocal total`)
+	if strings.Contains(out, "nonlocal step") || strings.Contains(out, "global step") {
+		t.Errorf("a variable the task declares must stay local, got:\n%s", out)
+	}
+}
+
+func TestClosureSetsCapturedVariables(t *testing.T) {
+	out := transpile(t, `Declare function makeCounter that takes step and does the following:
+    Declare count to be 0.
+    Declare calls to be 0.
+    Declare function inc that does the following:
+        Set count to be count + step.
+        Set calls to be calls + 1.
+        Return count.
+    thats it.
+    Return inc.
+thats it.`)
+	assertContainsLine(t, out, `nonlocal count, calls`)
+	if strings.Contains(out, "nonlocal step") {
+		t.Errorf("a captured variable that is only read needs no nonlocal, got:\n%s", out)
+	}
+}
+
+func TestMakeSure(t *testing.T) {
+	out := transpile(t, `Declare balance to be 5.
+Make sure that balance is at least 0, otherwise say "negative balance".
+Make sure that balance is at most 10.`)
+	assertContainsLine(t, out, `assert balance >= 0, "negative balance"`)
+	assertContainsLine(t, out, `assert balance <= 10`)
+}
+
+func TestTestBlock(t *testing.T) {
+	out := transpile(t, `Test "adding works, mostly!" by doing the following:
+    Make sure that 1 + 1 is equal to 2.
+thats it.`)
+	assertContainsLine(t, out, `def test_adding_works_mostly():`)
+	assertContainsLine(t, out, `assert (1 + 1) == 2`)
+}